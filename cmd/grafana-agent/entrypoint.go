@@ -18,6 +18,9 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/gorilla/mux"
 	"github.com/grafana/agent/internal/agentseed"
+	"github.com/grafana/agent/internal/converter"
+	"github.com/grafana/agent/internal/converter/convertapi"
+	"github.com/grafana/agent/internal/flow/riverfmt"
 	"github.com/grafana/agent/internal/static/config"
 	"github.com/grafana/agent/internal/static/logs"
 	"github.com/grafana/agent/internal/static/metrics"
@@ -237,6 +240,9 @@ func (ep *Entrypoint) wire(mux *mux.Router, grpc *grpc.Server) {
 
 	mux.HandleFunc("/-/reload", ep.reloadHandler).Methods("GET", "POST")
 
+	mux.Handle(convertapi.Path, converter.APIHandler()).Methods("POST")
+	mux.Handle(riverfmt.APIPath, riverfmt.APIHandler(nil)).Methods("POST")
+
 	mux.HandleFunc("/-/support", ep.supportHandler).Methods("GET")
 }
 
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gopkg.in/yaml.v3"
+)
+
+// chaosFile is the name of the optional file of a test directory which lists
+// the faults to inject while the test runs.
+const chaosFile = "chaos.yaml"
+
+// netemImage is the image used to run tc in the network namespace of a
+// service container, as the service images don't ship it.
+const netemImage = "nicolaka/netshoot"
+
+// Chaos actions.
+const (
+	actionRestartAgent   = "restart_agent"   // Kill the agent and start it again.
+	actionPauseService   = "pause_service"   // Pause a service for Duration.
+	actionPacketLoss     = "packet_loss"     // Drop Percent of the packets of a service for Duration.
+	actionNetworkLatency = "network_latency" // Delay the packets of a service by Latency for Duration.
+)
+
+type chaosConfig struct {
+	Steps []chaosStep `yaml:"steps"`
+}
+
+// chaosStep is a fault injected at a given time after the agent started.
+type chaosStep struct {
+	At       time.Duration `yaml:"at"`
+	Action   string        `yaml:"action"`
+	Service  string        `yaml:"service"`
+	Duration time.Duration `yaml:"duration"`
+	Percent  float64       `yaml:"percent"`
+	Latency  time.Duration `yaml:"latency"`
+}
+
+func (s chaosStep) validate() error {
+	switch s.Action {
+	case actionRestartAgent:
+		return nil
+	case actionPauseService, actionPacketLoss, actionNetworkLatency:
+		if s.Service == "" {
+			return fmt.Errorf("%s requires a service", s.Action)
+		}
+		if s.Duration <= 0 {
+			return fmt.Errorf("%s requires a positive duration", s.Action)
+		}
+	default:
+		return fmt.Errorf("unknown action %q", s.Action)
+	}
+
+	switch {
+	case s.Action == actionPacketLoss && (s.Percent <= 0 || s.Percent > 100):
+		return fmt.Errorf("%s requires a percent between 0 and 100", s.Action)
+	case s.Action == actionNetworkLatency && s.Latency <= 0:
+		return fmt.Errorf("%s requires a positive latency", s.Action)
+	}
+	return nil
+}
+
+// loadChaosSteps reads the chaos steps of the test in testDir. It returns no
+// steps if the test doesn't define any.
+func loadChaosSteps(testDir string) ([]chaosStep, error) {
+	bb, err := os.ReadFile(filepath.Join(testDir, chaosFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var cfg chaosConfig
+	if err := yaml.Unmarshal(bb, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", chaosFile, err)
+	}
+	for i, step := range cfg.Steps {
+		if err := step.validate(); err != nil {
+			return nil, fmt.Errorf("step %d of %s: %w", i, chaosFile, err)
+		}
+	}
+	return cfg.Steps, nil
+}
+
+// runChaos injects the faults of steps until ctx is canceled. Faults which
+// are still active when ctx is canceled are reverted. The returned log
+// describes the injected faults and their errors.
+func runChaos(ctx context.Context, steps []chaosStep, agent *agentProcess, env *testEnvironment) string {
+	var (
+		wg     sync.WaitGroup
+		logMut sync.Mutex
+		log    strings.Builder
+	)
+	logf := func(format string, args ...any) {
+		logMut.Lock()
+		defer logMut.Unlock()
+		fmt.Fprintf(&log, format+"\n", args...)
+	}
+
+	start := time.Now()
+	for _, step := range steps {
+		wg.Add(1)
+		go func(step chaosStep) {
+			defer wg.Done()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(step.At - time.Since(start)):
+			}
+
+			logf("T+%s: %s %s", step.At, step.Action, step.Service)
+			if err := injectFault(ctx, step, agent, env); err != nil {
+				logf("T+%s: %s %s failed: %v", step.At, step.Action, step.Service, err)
+			}
+		}(step)
+	}
+	wg.Wait()
+	return log.String()
+}
+
+// injectFault injects the fault of step, returning once it's reverted.
+func injectFault(ctx context.Context, step chaosStep, agent *agentProcess, env *testEnvironment) error {
+	if step.Action == actionRestartAgent {
+		return agent.Restart()
+	}
+
+	c, err := env.Container(step.Service)
+	if err != nil {
+		return err
+	}
+
+	var inject, revert func() error
+	switch step.Action {
+	case actionPauseService:
+		cli, err := testcontainers.NewDockerClientWithOpts(ctx)
+		if err != nil {
+			return err
+		}
+		defer cli.Close()
+
+		// The fault is reverted even if ctx is canceled.
+		inject = func() error { return cli.ContainerPause(ctx, c.GetContainerID()) }
+		revert = func() error { return cli.ContainerUnpause(context.Background(), c.GetContainerID()) }
+
+	case actionPacketLoss, actionNetworkLatency:
+		netem := []string{"loss", fmt.Sprintf("%g%%", step.Percent)}
+		if step.Action == actionNetworkLatency {
+			netem = []string{"delay", fmt.Sprintf("%dms", step.Latency.Milliseconds())}
+		}
+		inject = func() error {
+			return runTC(ctx, c.GetContainerID(), append([]string{"add", "dev", "eth0", "root", "netem"}, netem...)...)
+		}
+		revert = func() error {
+			return runTC(context.Background(), c.GetContainerID(), "del", "dev", "eth0", "root")
+		}
+	}
+
+	if err := inject(); err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(step.Duration):
+	}
+	return revert()
+}
+
+// runTC runs tc qdisc with args in the network namespace of the container
+// with the given ID.
+func runTC(ctx context.Context, containerID string, args ...string) error {
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image: netemImage,
+			Cmd:   append([]string{"tc", "qdisc"}, args...),
+			HostConfigModifier: func(hc *container.HostConfig) {
+				hc.NetworkMode = container.NetworkMode("container:" + containerID)
+				hc.CapAdd = append(hc.CapAdd, "NET_ADMIN")
+			},
+			WaitingFor: wait.ForExit(),
+		},
+		Started: true,
+	})
+	if c != nil {
+		defer func() { _ = c.Terminate(context.Background()) }()
+	}
+	if err != nil {
+		return fmt.Errorf("tc qdisc %s: %w", strings.Join(args, " "), err)
+	}
+
+	state, err := c.State(ctx)
+	if err != nil {
+		return err
+	}
+	if state.ExitCode != 0 {
+		return fmt.Errorf("tc qdisc %s exited with code %d", strings.Join(args, " "), state.ExitCode)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosStep_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		step   chaosStep
+		expect string
+	}{
+		{
+			name: "restart agent",
+			step: chaosStep{Action: actionRestartAgent},
+		},
+		{
+			name: "pause service",
+			step: chaosStep{Action: actionPauseService, Service: "loki", Duration: time.Second},
+		},
+		{
+			name: "packet loss",
+			step: chaosStep{Action: actionPacketLoss, Service: "mimir", Duration: time.Second, Percent: 100},
+		},
+		{
+			name: "network latency",
+			step: chaosStep{Action: actionNetworkLatency, Service: "mimir", Duration: time.Second, Latency: time.Millisecond},
+		},
+		{
+			name:   "unknown action",
+			step:   chaosStep{Action: "flood"},
+			expect: `unknown action "flood"`,
+		},
+		{
+			name:   "no service",
+			step:   chaosStep{Action: actionPauseService, Duration: time.Second},
+			expect: "pause_service requires a service",
+		},
+		{
+			name:   "no duration",
+			step:   chaosStep{Action: actionPacketLoss, Service: "mimir", Percent: 10},
+			expect: "packet_loss requires a positive duration",
+		},
+		{
+			name:   "no percent",
+			step:   chaosStep{Action: actionPacketLoss, Service: "mimir", Duration: time.Second},
+			expect: "packet_loss requires a percent between 0 and 100",
+		},
+		{
+			name:   "percent too high",
+			step:   chaosStep{Action: actionPacketLoss, Service: "mimir", Duration: time.Second, Percent: 101},
+			expect: "packet_loss requires a percent between 0 and 100",
+		},
+		{
+			name:   "no latency",
+			step:   chaosStep{Action: actionNetworkLatency, Service: "mimir", Duration: time.Second},
+			expect: "network_latency requires a positive latency",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.step.validate()
+			if tc.expect == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expect)
+			}
+		})
+	}
+}
+
+func TestLoadChaosSteps(t *testing.T) {
+	t.Run("no file", func(t *testing.T) {
+		steps, err := loadChaosSteps(t.TempDir())
+		require.NoError(t, err)
+		require.Empty(t, steps)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		dir := writeChaosFile(t, `
+steps:
+  - at: 10s
+    action: restart_agent
+  - at: 20s
+    action: network_latency
+    service: mimir
+    latency: 500ms
+    duration: 20s
+`)
+		steps, err := loadChaosSteps(dir)
+		require.NoError(t, err)
+		require.Equal(t, []chaosStep{
+			{At: 10 * time.Second, Action: actionRestartAgent},
+			{At: 20 * time.Second, Action: actionNetworkLatency, Service: "mimir", Latency: 500 * time.Millisecond, Duration: 20 * time.Second},
+		}, steps)
+	})
+
+	t.Run("invalid step", func(t *testing.T) {
+		dir := writeChaosFile(t, `
+steps:
+  - at: 10s
+    action: restart_agent
+  - at: 20s
+    action: pause_service
+    duration: 5s
+`)
+		_, err := loadChaosSteps(dir)
+		require.EqualError(t, err, "step 1 of chaos.yaml: pause_service requires a service")
+	})
+
+	t.Run("invalid yaml", func(t *testing.T) {
+		dir := writeChaosFile(t, "steps: {")
+		_, err := loadChaosSteps(dir)
+		require.ErrorContains(t, err, "parsing chaos.yaml")
+	})
+}
+
+func TestRunChaos(t *testing.T) {
+	steps := []chaosStep{
+		{At: 0, Action: actionPauseService, Service: "loki", Duration: time.Second},
+		{At: time.Hour, Action: actionRestartAgent},
+	}
+	env := &testEnvironment{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	// The step of a service which isn't running fails, and the step which
+	// isn't due before ctx is canceled doesn't run.
+	out := runChaos(ctx, steps, &agentProcess{}, env)
+	require.Equal(t, "T+0s: pause_service loki\nT+0s: pause_service loki failed: service loki isn't running for this test\n", out)
+}
+
+func writeChaosFile(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, chaosFile), []byte(content), 0644))
+	return dir
+}
@@ -0,0 +1,33 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Eventually retries condition every DefaultRetryInterval until it passes,
+// failing t if it doesn't pass within DefaultTimeout. Assertions which fail
+// on the last attempt are reported.
+func Eventually(t *testing.T, condition func(c *assert.CollectT), msgAndArgs ...interface{}) bool {
+	t.Helper()
+	return assert.EventuallyWithT(t, condition, DefaultTimeout, DefaultRetryInterval, msgAndArgs...)
+}
+
+// WithinTolerance reports whether actual is within a relative tolerance of
+// expected. A tolerance of 0.1 accepts values up to 10% away from expected.
+func WithinTolerance(actual, expected, tolerance float64) bool {
+	if expected == 0 {
+		return actual == 0
+	}
+	return math.Abs(actual-expected) <= math.Abs(expected)*tolerance
+}
+
+// assertWithinTolerance asserts that actual is within a relative tolerance of
+// expected.
+func assertWithinTolerance(c *assert.CollectT, actual, expected, tolerance float64) bool {
+	return assert.True(c, WithinTolerance(actual, expected, tolerance),
+		fmt.Sprintf("expected %v to be within %v%% of %v", actual, tolerance*100, expected))
+}
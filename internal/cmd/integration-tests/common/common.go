@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -14,6 +15,16 @@ type Unmarshaler interface {
 const DefaultRetryInterval = 100 * time.Millisecond
 const DefaultTimeout = time.Minute
 
+// serviceURL returns the URL of a service started for the test, read from the
+// environment variable env set by the test runner. def is returned when the
+// variable isn't set, such as when the services were started manually.
+func serviceURL(env, def string) string {
+	if u, ok := os.LookupEnv(env); ok {
+		return u
+	}
+	return def
+}
+
 func FetchDataFromURL(url string, target Unmarshaler) error {
 	resp, err := http.Get(url)
 	if err != nil {
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var lokiURL = serviceURL("LOKI_URL", "http://localhost:3100") + "/loki/api/v1/"
+
+// LogQuery returns the URL of a Loki query for the given LogQL expression.
+func LogQuery(query string) string {
+	return fmt.Sprintf("%squery?query=%s", lokiURL, url.QueryEscape(query))
+}
+
+// AssertLogsAvailable performs a Loki query and expects the result to
+// eventually contain every expected log line.
+func AssertLogsAvailable(t *testing.T, query string, expected ...string) {
+	Eventually(t, func(c *assert.CollectT) {
+		var logResponse LogResponse
+		err := FetchDataFromURL(LogQuery(query), &logResponse)
+		if !assert.NoError(c, err) || !assert.NotEmpty(c, logResponse.Data.Result) {
+			return
+		}
+		lines := logLines(logResponse.Data.Result)
+		for _, line := range expected {
+			assert.Contains(c, lines, line)
+		}
+	}, "Logs did not satisfy the conditions within the time limit")
+}
+
+// AssertLogStreamLabels performs a Loki query and expects the result to
+// eventually contain a stream which has all the given labels.
+func AssertLogStreamLabels(t *testing.T, query string, labels map[string]string) {
+	Eventually(t, func(c *assert.CollectT) {
+		var logResponse LogResponse
+		err := FetchDataFromURL(LogQuery(query), &logResponse)
+		if !assert.NoError(c, err) || !assert.NotEmpty(c, logResponse.Data.Result) {
+			return
+		}
+		for _, result := range logResponse.Data.Result {
+			if hasLabels(result.Stream, labels) {
+				return
+			}
+		}
+		assert.Fail(c, fmt.Sprintf("no stream matching %s has the labels %v", query, labels))
+	}, "Log streams did not satisfy the conditions within the time limit")
+}
+
+// AssertLogCount performs a Loki query and expects the number of log lines in
+// the result to eventually be within a relative tolerance of expected.
+func AssertLogCount(t *testing.T, query string, expected int, tolerance float64) {
+	Eventually(t, func(c *assert.CollectT) {
+		var logResponse LogResponse
+		err := FetchDataFromURL(LogQuery(query), &logResponse)
+		if assert.NoError(c, err) {
+			count := len(logLines(logResponse.Data.Result))
+			assertWithinTolerance(c, float64(count), float64(expected), tolerance)
+		}
+	}, "Log count did not satisfy the conditions within the time limit")
+}
+
+// logLines returns the log lines of every stream of results.
+func logLines(results []LogData) []string {
+	var lines []string
+	for _, result := range results {
+		for _, valuePair := range result.Values {
+			lines = append(lines, valuePair[1])
+		}
+	}
+	return lines
+}
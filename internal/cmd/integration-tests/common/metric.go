@@ -10,6 +10,12 @@ type MetricsResponse struct {
 	Data   []Metric `json:"data"`
 }
 
+// SeriesResponse is the response of the Prometheus series API.
+type SeriesResponse struct {
+	Status string              `json:"status"`
+	Data   []map[string]string `json:"data"`
+}
+
 type MetricResponse struct {
 	Status string     `json:"status"`
 	Data   MetricData `json:"data"`
@@ -62,6 +68,10 @@ func (m *MetricsResponse) Unmarshal(data []byte) error {
 	return json.Unmarshal(data, m)
 }
 
+func (m *SeriesResponse) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, m)
+}
+
 func (h *HistogramRawData) UnmarshalJSON(b []byte) error {
 	var arr []json.RawMessage
 	if err := json.Unmarshal(b, &arr); err != nil {
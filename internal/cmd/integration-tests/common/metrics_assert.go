@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmt"
+	"net/url"
 	"strconv"
 	"testing"
 
@@ -9,7 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-const promURL = "http://localhost:9009/prometheus/api/v1/"
+var promURL = serviceURL("MIMIR_URL", "http://localhost:9009") + "/prometheus/api/v1/"
 
 // Default metrics list according to what the prom-gen app is generating.
 var PromDefaultMetrics = []string{
@@ -50,6 +51,18 @@ func MetricsQuery(testName string) string {
 	return fmt.Sprintf("%sseries?match[]={test_name='%s'}", promURL, testName)
 }
 
+// PromQuery returns the URL of an instant Prometheus query for the given
+// PromQL expression.
+func PromQuery(query string) string {
+	return fmt.Sprintf("%squery?query=%s", promURL, url.QueryEscape(query))
+}
+
+// SeriesQuery returns the URL listing the series which match the given
+// selector.
+func SeriesQuery(selector string) string {
+	return fmt.Sprintf("%sseries?match[]=%s", promURL, url.QueryEscape(selector))
+}
+
 // MimirMetricsTest checks that all given metrics are stored in Mimir.
 func MimirMetricsTest(t *testing.T, metrics []string, histogramMetrics []string, testName string) {
 	AssertMetricsAvailable(t, metrics, histogramMetrics, testName)
@@ -144,3 +157,52 @@ func AssertMetricData(t *testing.T, query, expectedMetric string, testName strin
 		}
 	}, DefaultTimeout, DefaultRetryInterval, "Data did not satisfy the conditions within the time limit")
 }
+
+// AssertSeriesWithLabels expects the series matching selector to eventually
+// include a series which has all the given labels.
+func AssertSeriesWithLabels(t *testing.T, selector string, labels map[string]string) {
+	Eventually(t, func(c *assert.CollectT) {
+		var seriesResponse SeriesResponse
+		err := FetchDataFromURL(SeriesQuery(selector), &seriesResponse)
+		if !assert.NoError(c, err) || !assert.NotEmpty(c, seriesResponse.Data, "no series match %s", selector) {
+			return
+		}
+		for _, series := range seriesResponse.Data {
+			if hasLabels(series, labels) {
+				return
+			}
+		}
+		assert.Fail(c, fmt.Sprintf("no series matching %s has the labels %v", selector, labels))
+	}, "Series did not satisfy the conditions within the time limit")
+}
+
+// AssertMetricValue performs the given PromQL query and expects the value of
+// the first result to eventually be within a relative tolerance of expected.
+// Use an aggregation such as sum() to compare the value of several series.
+func AssertMetricValue(t *testing.T, query string, expected, tolerance float64) {
+	Eventually(t, func(c *assert.CollectT) {
+		var metricResponse MetricResponse
+		err := FetchDataFromURL(PromQuery(query), &metricResponse)
+		if !assert.NoError(c, err) || !assert.NotEmpty(c, metricResponse.Data.Result) {
+			return
+		}
+		result := metricResponse.Data.Result[0]
+		if !assert.NotNil(c, result.Value, "query %s didn't return a sample", query) {
+			return
+		}
+		value, err := strconv.ParseFloat(result.Value.Value, 64)
+		if assert.NoError(c, err) {
+			assertWithinTolerance(c, value, expected, tolerance)
+		}
+	}, "Metric value did not satisfy the conditions within the time limit")
+}
+
+// hasLabels reports whether labels contains every label of expected.
+func hasLabels(labels, expected map[string]string) bool {
+	for name, value := range expected {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
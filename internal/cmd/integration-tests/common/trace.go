@@ -0,0 +1,18 @@
+package common
+
+import "encoding/json"
+
+// TraceSearchResponse is the response of the Tempo search API.
+type TraceSearchResponse struct {
+	Traces []TraceData `json:"traces"`
+}
+
+type TraceData struct {
+	TraceID         string `json:"traceID"`
+	RootServiceName string `json:"rootServiceName"`
+	RootTraceName   string `json:"rootTraceName"`
+}
+
+func (m *TraceSearchResponse) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, m)
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var tempoURL = serviceURL("TEMPO_URL", "http://localhost:3200") + "/api/"
+
+// traceSearchLimit is the maximum number of traces returned by a search.
+const traceSearchLimit = 1000
+
+// TraceSearchQuery returns the URL of a Tempo search for traces which have
+// all the given tags.
+func TraceSearchQuery(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for name, value := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, value))
+	}
+	sort.Strings(pairs)
+
+	params := url.Values{}
+	params.Set("tags", strings.Join(pairs, " "))
+	params.Set("limit", fmt.Sprint(traceSearchLimit))
+	return fmt.Sprintf("%ssearch?%s", tempoURL, params.Encode())
+}
+
+// AssertTracesAvailable expects Tempo to eventually store traces which have
+// all the given tags.
+func AssertTracesAvailable(t *testing.T, tags map[string]string) {
+	Eventually(t, func(c *assert.CollectT) {
+		var traceResponse TraceSearchResponse
+		err := FetchDataFromURL(TraceSearchQuery(tags), &traceResponse)
+		if assert.NoError(c, err) {
+			assert.NotEmpty(c, traceResponse.Traces, "no traces have the tags %v", tags)
+		}
+	}, "Traces did not satisfy the conditions within the time limit")
+}
+
+// AssertTraceCount expects the number of traces which have all the given
+// tags to eventually be within a relative tolerance of expected. At most
+// 1000 traces are counted.
+func AssertTraceCount(t *testing.T, tags map[string]string, expected int, tolerance float64) {
+	Eventually(t, func(c *assert.CollectT) {
+		var traceResponse TraceSearchResponse
+		err := FetchDataFromURL(TraceSearchQuery(tags), &traceResponse)
+		if assert.NoError(c, err) {
+			assertWithinTolerance(c, float64(len(traceResponse.Traces)), float64(expected), tolerance)
+		}
+	}, "Trace count did not satisfy the conditions within the time limit")
+}
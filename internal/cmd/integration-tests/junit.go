@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+	SystemErr string        `xml:"system-err,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Output  string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnitReport writes the results of the tests to path in the JUnit XML
+// format, with a test case for every test directory.
+func writeJUnitReport(path string, results []TestLog) error {
+	suite := junitTestSuite{Name: "integration-tests"}
+
+	var total time.Duration
+	for _, r := range results {
+		tc := junitTestCase{
+			Name:      r.TestDir,
+			Classname: "integration-tests",
+			Time:      junitSeconds(r.Duration),
+		}
+		switch {
+		case r.Skipped:
+			tc.Skipped = &junitSkipped{Message: "not applicable for this OS"}
+			suite.Skipped++
+		case r.Failed:
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("failed after %d attempts", r.Attempts),
+				Output:  r.TestOutput,
+			}
+			tc.SystemErr = r.AgentLog
+			suite.Failures++
+		default:
+			tc.SystemOut = r.TestOutput
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+		suite.Tests++
+		total += r.Duration
+	}
+	suite.Time = junitSeconds(total)
+
+	out, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(out, '\n')...), 0644)
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
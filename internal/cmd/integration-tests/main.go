@@ -3,14 +3,18 @@ package main
 import (
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
-var specificTest string
-var skipBuild bool
+var (
+	specificTest string
+	skipBuild    bool
+	retries      int
+	testTimeout  time.Duration
+	junitFile    string
+)
 
 func main() {
 	rootCmd := &cobra.Command{
@@ -21,6 +25,9 @@ func main() {
 
 	rootCmd.PersistentFlags().StringVar(&specificTest, "test", "", "Specific test directory to run")
 	rootCmd.PersistentFlags().BoolVar(&skipBuild, "skip-build", false, "Skip building the agent")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 0, "Number of times to rerun a failed test")
+	rootCmd.PersistentFlags().DurationVar(&testTimeout, "timeout", 10*time.Minute, "Maximum duration of a single run of a test, including starting its services")
+	rootCmd.PersistentFlags().StringVar(&junitFile, "junit-file", "", "Path of a file to write the results to in the JUnit XML format")
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -29,27 +36,23 @@ func main() {
 }
 
 func runIntegrationTests(cmd *cobra.Command, args []string) {
+	if retries < 0 {
+		fmt.Println("--retries must not be negative")
+		os.Exit(1)
+	}
+
+	dirs, err := testDirs()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	defer reportResults()
-	defer cleanUpEnvironment()
 
 	if !skipBuild {
 		buildAgent()
 	}
-	setupEnvironment()
-
-	if specificTest != "" {
-		fmt.Println("Running", specificTest)
-		if !filepath.IsAbs(specificTest) && !strings.HasPrefix(specificTest, "./tests/") {
-			specificTest = "./tests/" + specificTest
-		}
-		logChan = make(chan TestLog, 1)
-		runSingleTest(specificTest, 12345)
-	} else {
-		testDirs, err := filepath.Glob("./tests/*")
-		if err != nil {
-			panic(err)
-		}
-		logChan = make(chan TestLog, len(testDirs))
-		runAllTests()
-	}
+
+	logChan = make(chan TestLog, len(dirs))
+	runAllTests(dirs)
 }
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gopkg.in/yaml.v3"
+)
+
+// servicesFile is the name of the optional file of a test directory which
+// lists the services the test needs.
+const servicesFile = "services.yaml"
+
+// containerLogsDir is the directory of a test directory where the logs of the
+// containers of the test are written.
+const containerLogsDir = "container-logs"
+
+// servicePort is a port of a service which the agent and the tests connect
+// to. Its address on the host is passed to them in an environment variable.
+type servicePort struct {
+	port   nat.Port
+	env    string // Environment variable holding the address of the port.
+	scheme string // Scheme prepended to the address, if any.
+}
+
+// service is an external dependency of the tests, run as a container.
+type service struct {
+	request testcontainers.ContainerRequest
+	ports   []servicePort
+}
+
+// services are the services tests can use, by name.
+var services = map[string]service{
+	"mimir": {
+		request: testcontainers.ContainerRequest{
+			Image:      "grafana/mimir:2.10.4",
+			Entrypoint: []string{"/bin/mimir", "-config.file=/etc/mimir-config/mimir.yaml"},
+			Files: []testcontainers.ContainerFile{{
+				HostFilePath:      "./configs/mimir/mimir.yaml",
+				ContainerFilePath: "/etc/mimir-config/mimir.yaml",
+				FileMode:          0644,
+			}},
+		},
+		ports: []servicePort{{port: "9009/tcp", env: "MIMIR_URL", scheme: "http://"}},
+	},
+	"loki": {
+		request: testcontainers.ContainerRequest{
+			Image: "grafana/loki:latest",
+			Cmd:   []string{"-config.file=/etc/loki/local-config.yaml"},
+		},
+		ports: []servicePort{{port: "3100/tcp", env: "LOKI_URL", scheme: "http://"}},
+	},
+	"tempo": {
+		request: testcontainers.ContainerRequest{
+			Image: "grafana/tempo:latest",
+			Cmd:   []string{"-config.file=/etc/tempo-config/tempo.yaml"},
+			Files: []testcontainers.ContainerFile{{
+				HostFilePath:      "./configs/tempo/tempo.yaml",
+				ContainerFilePath: "/etc/tempo-config/tempo.yaml",
+				FileMode:          0644,
+			}},
+		},
+		ports: []servicePort{
+			{port: "3200/tcp", env: "TEMPO_URL", scheme: "http://"},
+			{port: "4318/tcp", env: "TEMPO_OTLP_HTTP_URL", scheme: "http://"},
+		},
+	},
+	"otel-metrics-gen": {
+		request: testcontainers.ContainerRequest{
+			FromDockerfile: testcontainers.FromDockerfile{
+				Context:    "../../..",
+				Dockerfile: "./internal/cmd/integration-tests/configs/otel-metrics-gen/Dockerfile",
+			},
+			Env: map[string]string{
+				// The agent runs on the host.
+				"OTEL_EXPORTER_ENDPOINT": envOrDefault("OTEL_EXPORTER_ENDPOINT", "host.docker.internal:4318"),
+			},
+			HostConfigModifier: func(hc *container.HostConfig) {
+				hc.ExtraHosts = append(hc.ExtraHosts, "host.docker.internal:host-gateway")
+			},
+		},
+	},
+	"prom-gen": {
+		request: testcontainers.ContainerRequest{
+			FromDockerfile: testcontainers.FromDockerfile{
+				Context:    "../../..",
+				Dockerfile: "./internal/cmd/integration-tests/configs/prom-gen/Dockerfile",
+			},
+		},
+		ports: []servicePort{{port: "9001/tcp", env: "PROM_GEN_ADDRESS"}},
+	},
+	"redis": {
+		request: testcontainers.ContainerRequest{
+			Image: "redis:6.0.9-alpine",
+		},
+		ports: []servicePort{{port: "6379/tcp", env: "REDIS_ADDRESS"}},
+	},
+}
+
+func envOrDefault(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return def
+}
+
+type servicesConfig struct {
+	Services []string `yaml:"services"`
+}
+
+// loadServices reads the names of the services the test in testDir needs.
+// It returns no services if the test doesn't need any.
+func loadServices(testDir string) ([]string, error) {
+	bb, err := os.ReadFile(filepath.Join(testDir, servicesFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var cfg servicesConfig
+	if err := yaml.Unmarshal(bb, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", servicesFile, err)
+	}
+	for _, name := range cfg.Services {
+		if _, ok := services[name]; !ok {
+			return nil, fmt.Errorf("unknown service %q in %s, known services are %s", name, servicesFile, serviceNames())
+		}
+	}
+	return cfg.Services, nil
+}
+
+// testEnvironment holds the containers of the services of a single test,
+// which run in a network of their own.
+type testEnvironment struct {
+	network    testcontainers.Network
+	containers map[string]testcontainers.Container
+	env        []string
+}
+
+// startEnvironment starts the services named by names in a new network named
+// after testName. The returned environment must be terminated even if an
+// error is returned.
+func startEnvironment(ctx context.Context, testName string, names []string) (*testEnvironment, error) {
+	e := &testEnvironment{containers: make(map[string]testcontainers.Container)}
+	if len(names) == 0 {
+		return e, nil
+	}
+
+	networkName := "integration-tests-" + testName
+	network, err := testcontainers.GenericNetwork(ctx, testcontainers.GenericNetworkRequest{
+		NetworkRequest: testcontainers.NetworkRequest{
+			Name:           networkName,
+			CheckDuplicate: true,
+		},
+	})
+	if err != nil {
+		return e, fmt.Errorf("creating network %s: %w", networkName, err)
+	}
+	e.network = network
+
+	for _, name := range names {
+		svc := services[name]
+
+		req := svc.request
+		req.Name = networkName + "-" + name
+		req.Networks = []string{networkName}
+		req.NetworkAliases = map[string][]string{networkName: {name}}
+		var waits []wait.Strategy
+		for _, p := range svc.ports {
+			req.ExposedPorts = append(req.ExposedPorts, string(p.port))
+			waits = append(waits, wait.ForListeningPort(p.port))
+		}
+		if len(waits) > 0 {
+			req.WaitingFor = wait.ForAll(waits...)
+		}
+
+		c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+			ContainerRequest: req,
+			Started:          true,
+		})
+		if c != nil {
+			e.containers[name] = c
+		}
+		if err != nil {
+			return e, fmt.Errorf("starting %s: %w", name, err)
+		}
+
+		host, err := c.Host(ctx)
+		if err != nil {
+			return e, err
+		}
+		for _, p := range svc.ports {
+			mapped, err := c.MappedPort(ctx, p.port)
+			if err != nil {
+				return e, err
+			}
+			e.env = append(e.env, fmt.Sprintf("%s=%s%s:%s", p.env, p.scheme, host, mapped.Port()))
+		}
+	}
+	return e, nil
+}
+
+// Env returns the environment variables holding the addresses of the
+// services, to pass to the agent and the tests.
+func (e *testEnvironment) Env() []string {
+	return e.env
+}
+
+// Container returns the container of the service named name.
+func (e *testEnvironment) Container(name string) (testcontainers.Container, error) {
+	c, ok := e.containers[name]
+	if !ok {
+		return nil, fmt.Errorf("service %s isn't running for this test", name)
+	}
+	return c, nil
+}
+
+// WriteLogs writes the logs of every container to a file named after its
+// service in the container logs directory of testDir. It returns the path of
+// the directory.
+func (e *testEnvironment) WriteLogs(ctx context.Context, testDir string) (string, error) {
+	dir := filepath.Join(testDir, containerLogsDir)
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if len(e.containers) == 0 {
+		return "", nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	var errs []error
+	for name, c := range e.containers {
+		if err := writeContainerLogs(ctx, c, filepath.Join(dir, name+".log")); err != nil {
+			errs = append(errs, fmt.Errorf("writing logs of %s: %w", name, err))
+		}
+	}
+	return dir, errors.Join(errs...)
+}
+
+func writeContainerLogs(ctx context.Context, c testcontainers.Container, path string) error {
+	logs, err := c.Logs(ctx)
+	if err != nil {
+		return err
+	}
+	defer logs.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, logs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Terminate removes the containers and the network of the environment.
+func (e *testEnvironment) Terminate(ctx context.Context) error {
+	names := make([]string, 0, len(e.containers))
+	for name := range e.containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := e.containers[name].Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("terminating %s: %w", name, err))
+		}
+	}
+	if e.network != nil {
+		if err := e.network.Remove(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("removing network: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// serviceNames returns the names of the services tests can use.
+func serviceNames() string {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -9,16 +10,22 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
 	agentBinaryPath = "../../../../../build/grafana-agent-flow"
 )
 
+// TestLog is the result of running a test.
 type TestLog struct {
 	TestDir    string
 	AgentLog   string
 	TestOutput string
+	Failed     bool
+	Skipped    bool          // The test doesn't apply to this OS.
+	Attempts   int           // Number of times the test ran.
+	Duration   time.Duration // Duration of the last run.
 }
 
 var logChan chan TestLog
@@ -37,65 +44,227 @@ func buildAgent() {
 	executeCommand("make", []string{"-C", "../../..", "agent-flow"}, "Building agent")
 }
 
-func setupEnvironment() {
-	executeCommand("docker-compose", []string{"up", "-d"}, "Setting up environment with Docker Compose")
+// agentProcess is the agent run for a test. The agent can be restarted while
+// the test runs; the logs of every run are kept.
+type agentProcess struct {
+	dir  string
+	port int
+	env  []string // Environment variables added to the ones of the runner.
+
+	mut sync.Mutex
+	cmd *exec.Cmd
+	log bytes.Buffer
 }
 
-func runSingleTest(testDir string, port int) {
-	info, err := os.Stat(testDir)
-	if err != nil {
-		panic(err)
+func (a *agentProcess) Start() error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.start()
+}
+
+func (a *agentProcess) start() error {
+	cmd := exec.Command(agentBinaryPath, "run", "config.river", "--server.http.listen-addr", fmt.Sprintf("0.0.0.0:%d", a.port))
+	cmd.Dir = a.dir
+	cmd.Env = append(os.Environ(), a.env...)
+	cmd.Stdout = &a.log
+	cmd.Stderr = &a.log
+	if err := cmd.Start(); err != nil {
+		return err
 	}
-	if !info.IsDir() {
+	a.cmd = cmd
+	return nil
+}
+
+// Restart kills the agent without letting it shut down gracefully and starts
+// it again with the same data directory.
+func (a *agentProcess) Restart() error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.stop()
+	fmt.Fprintf(&a.log, "\n--- agent restarted by chaos step ---\n\n")
+	return a.start()
+}
+
+func (a *agentProcess) Stop() {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.stop()
+}
+
+func (a *agentProcess) stop() {
+	if a.cmd == nil {
 		return
 	}
+	if err := a.cmd.Process.Kill(); err != nil {
+		panic(err)
+	}
+	// The agent exits with an error when it's killed.
+	_ = a.cmd.Wait()
+	a.cmd = nil
+}
+
+// Log returns the logs of every run of the agent.
+func (a *agentProcess) Log() string {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return a.log.String()
+}
 
+// runTest runs the test in testDir, running it again up to retries times
+// while it fails, and reports the result of the last run.
+func runTest(testDir string, port int) {
+	var result TestLog
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		if attempt > 1 {
+			fmt.Printf("Retrying %s (attempt %d of %d)\n", testDir, attempt, retries+1)
+		}
+		start := time.Now()
+		result = runSingleTest(testDir, port)
+		result.Attempts = attempt
+		result.Duration = time.Since(start)
+		if !result.Failed {
+			break
+		}
+	}
+	logChan <- result
+}
+
+func runSingleTest(testDir string, port int) TestLog {
 	dirName := filepath.Base(testDir)
 
-	var agentLogBuffer bytes.Buffer
-	cmd := exec.Command(agentBinaryPath, "run", "config.river", "--server.http.listen-addr", fmt.Sprintf("0.0.0.0:%d", port))
-	cmd.Dir = testDir
-	cmd.Stdout = &agentLogBuffer
-	cmd.Stderr = &agentLogBuffer
+	chaosSteps, err := loadChaosSteps(testDir)
+	if err != nil {
+		return TestLog{
+			TestDir:  dirName,
+			AgentLog: fmt.Sprintf("Failed to load chaos steps: %v", err),
+			Failed:   true,
+		}
+	}
 
-	if err := cmd.Start(); err != nil {
-		logChan <- TestLog{
+	serviceNames, err := loadServices(testDir)
+	if err != nil {
+		return TestLog{
+			TestDir:  dirName,
+			AgentLog: fmt.Sprintf("Failed to load services: %v", err),
+			Failed:   true,
+		}
+	}
+
+	// The timeout covers starting the services too, since pulling images is
+	// the most likely reason for a test to hang.
+	ctx := context.Background()
+	testCtx, cancel := context.WithTimeout(ctx, testTimeout)
+	defer cancel()
+
+	env, err := startEnvironment(testCtx, dirName, serviceNames)
+	defer func() {
+		if err := env.Terminate(ctx); err != nil {
+			fmt.Printf("Failed to clean up the services of %s: %v\n", dirName, err)
+		}
+	}()
+	if err != nil {
+		return TestLog{
+			TestDir:  dirName,
+			AgentLog: fmt.Sprintf("Failed to start services: %v", err),
+			Failed:   true,
+		}
+	}
+
+	agent := &agentProcess{dir: testDir, port: port, env: env.Env()}
+	if err := agent.Start(); err != nil {
+		return TestLog{
 			TestDir:  dirName,
 			AgentLog: fmt.Sprintf("Failed to start agent: %v", err),
+			Failed:   true,
 		}
-		return
 	}
 
-	testCmd := exec.Command("go", "test")
+	chaosCtx, stopChaos := context.WithCancel(testCtx)
+	chaosLog := make(chan string, 1)
+	go func() {
+		chaosLog <- runChaos(chaosCtx, chaosSteps, agent, env)
+	}()
+
+	testCmd := exec.CommandContext(testCtx, "go", "test")
 	testCmd.Dir = testDir
+	testCmd.Env = append(os.Environ(), env.Env()...)
+	// Don't wait forever for the test binary to close its output once go
+	// test is killed on timeout.
+	testCmd.WaitDelay = 10 * time.Second
 	testOutput, errTest := testCmd.CombinedOutput()
+	timedOut := testCtx.Err() == context.DeadlineExceeded
+
+	stopChaos()
+	chaosOutput := <-chaosLog
+	agent.Stop()
+
+	// The logs are written for every test so that they can be inspected
+	// after tests which passed too.
+	logsDir, errLogs := env.WriteLogs(ctx, testDir)
 
-	err = cmd.Process.Kill()
+	err = os.RemoveAll(filepath.Join(testDir, "data-agent"))
 	if err != nil {
 		panic(err)
 	}
 
-	agentLog := agentLogBuffer.String()
+	if errTest == nil {
+		return TestLog{TestDir: dirName, TestOutput: string(testOutput)}
+	}
 
-	if errTest != nil {
-		logChan <- TestLog{
-			TestDir:    dirName,
-			AgentLog:   agentLog,
-			TestOutput: string(testOutput),
+	output := string(testOutput)
+	if timedOut {
+		output += fmt.Sprintf("\nTest timed out after %s", testTimeout)
+	}
+	if chaosOutput != "" {
+		output += "\nChaos steps:\n" + chaosOutput
+	}
+	switch {
+	case errLogs != nil:
+		output += fmt.Sprintf("\nFailed to write container logs: %v", errLogs)
+	case logsDir != "":
+		output += "\nContainer logs: " + logsDir
+	}
+	return TestLog{
+		TestDir:    dirName,
+		AgentLog:   agent.Log(),
+		TestOutput: output,
+		Failed:     true,
+	}
+}
+
+// testDirs returns the test directories to run: the one passed with the
+// --test flag, or every directory in ./tests.
+func testDirs() ([]string, error) {
+	if specificTest != "" {
+		testDir := specificTest
+		if !filepath.IsAbs(testDir) && !strings.HasPrefix(testDir, "./tests/") {
+			testDir = "./tests/" + testDir
+		}
+		info, err := os.Stat(testDir)
+		if err != nil {
+			return nil, err
 		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", testDir)
+		}
+		return []string{testDir}, nil
 	}
 
-	err = os.RemoveAll(filepath.Join(testDir, "data-agent"))
+	matches, err := filepath.Glob("./tests/*")
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	var dirs []string
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			dirs = append(dirs, m)
+		}
 	}
+	return dirs, nil
 }
 
-func runAllTests() {
-	testDirs, err := filepath.Glob("./tests/*")
-	if err != nil {
-		panic(err)
-	}
+func runAllTests(testDirs []string) {
 	var wg sync.WaitGroup
 	port := 12345
 	for i, testDir := range testDirs {
@@ -103,36 +272,43 @@ func runAllTests() {
 		wg.Add(1)
 		go func(td string, offset int) {
 			defer wg.Done()
-			runSingleTest(td, port+offset)
+			runTest(td, port+offset)
 		}(testDir, i)
 	}
 	wg.Wait()
 }
 
-func cleanUpEnvironment() {
-	fmt.Println("Cleaning up Docker environment...")
-	err := exec.Command("docker-compose", "down", "--volumes", "--rmi", "all").Run()
-	if err != nil {
-		panic(err)
-	}
-}
-
 func reportResults() {
 	testsFailed := 0
 	// It's ok to close the channel here because all tests are finished.
 	// If the channel would not be closed, the for loop would wait forever.
 	close(logChan)
+	var results []TestLog
 	for log := range logChan {
-		if strings.Contains(log.TestOutput, "build constraints exclude all Go files") {
+		if log.Failed && strings.Contains(log.TestOutput, "build constraints exclude all Go files") {
 			fmt.Printf("Test %q is not applicable for this OS, ignoring\n", log.TestDir)
+			log.Failed, log.Skipped = false, true
+		}
+		results = append(results, log)
+		if !log.Failed {
+			if log.Attempts > 1 {
+				fmt.Printf("Test %q passed after %d attempts\n", log.TestDir, log.Attempts)
+			}
 			continue
 		}
-		fmt.Printf("Failure detected in %s:\n", log.TestDir)
+		fmt.Printf("Failure detected in %s after %d attempts:\n", log.TestDir, log.Attempts)
 		fmt.Println("Test output:", log.TestOutput)
 		fmt.Println("Agent logs:", log.AgentLog)
 		testsFailed++
 	}
 
+	if junitFile != "" {
+		if err := writeJUnitReport(junitFile, results); err != nil {
+			fmt.Printf("Failed to write JUnit report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	if testsFailed > 0 {
 		fmt.Printf("%d tests failed!\n", testsFailed)
 		os.Exit(1)
@@ -0,0 +1,147 @@
+package pipelinetests
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow"
+	"github.com/grafana/agent/internal/flow/logging"
+	cluster_service "github.com/grafana/agent/internal/service/cluster"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// AgentOptions configures an Agent.
+type AgentOptions struct {
+	// River config to load when the agent starts.
+	Config string
+
+	// Where to write the logs of the agent. Logs are discarded if nil.
+	Logs io.Writer
+}
+
+// Agent is a single Flow controller running in the test process without
+// clustering. Tests can read the exports of its components and change its
+// config while it runs.
+type Agent struct {
+	Addr string // Address of the HTTP server of the agent.
+
+	t      *testing.T
+	flow   *flow.Flow
+	cancel context.CancelFunc
+	exited chan struct{}
+
+	mut    sync.Mutex
+	config string // Config loaded last.
+}
+
+// NewAgent starts an Agent with opts.Config loaded. The agent is stopped when
+// the test finishes.
+func NewAgent(t *testing.T, opts AgentOptions) *Agent {
+	t.Helper()
+	if opts.Logs == nil {
+		opts.Logs = io.Discard
+	}
+
+	l, err := logging.New(opts.Logs, logging.DefaultOptions)
+	require.NoError(t, err)
+
+	addr := freeAddr(t)
+	reg := prometheus.NewRegistry()
+
+	clusterService, err := cluster_service.New(cluster_service.Options{
+		Log:              l,
+		Metrics:          reg,
+		EnableClustering: false,
+		NodeName:         "agent",
+		AdvertiseAddress: addr,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &Agent{
+		Addr:   addr,
+		t:      t,
+		flow:   newFlow(t, l, reg, addr, clusterService),
+		cancel: cancel,
+		exited: make(chan struct{}),
+	}
+	go func() {
+		defer close(a.exited)
+		a.flow.Run(ctx)
+	}()
+	t.Cleanup(a.Stop)
+
+	a.UpdateConfig(opts.Config)
+	return a
+}
+
+// UpdateConfig loads config in place of the current config of the agent.
+// Components which are in both configs keep running and are updated with
+// their new arguments.
+func (a *Agent) UpdateConfig(config string) {
+	a.t.Helper()
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	source, err := flow.ParseSource(a.t.Name(), []byte(config))
+	require.NoError(a.t, err)
+	require.NoError(a.t, a.flow.LoadSource(source, nil))
+	a.config = config
+}
+
+// Reevaluate loads the current config again, evaluating every component.
+// Components whose arguments didn't change aren't updated.
+func (a *Agent) Reevaluate() {
+	a.t.Helper()
+
+	a.mut.Lock()
+	config := a.config
+	a.mut.Unlock()
+	a.UpdateConfig(config)
+}
+
+// Exports returns the current exports of the component with the given ID,
+// such as "prometheus.exporter.self.default".
+func (a *Agent) Exports(id string) component.Exports {
+	a.t.Helper()
+
+	info, err := a.flow.GetComponent(component.ParseID(id), component.InfoOptions{GetExports: true})
+	require.NoError(a.t, err)
+	return info.Exports
+}
+
+// Eval evaluates a River expression, such as
+// "discovery.relabel.default.output", against the current exports of the
+// components of the agent.
+func (a *Agent) Eval(expr string) (any, error) {
+	return a.flow.EvaluateExpression(expr)
+}
+
+// EventuallyEval expects the River expression expr to eventually evaluate to
+// expected before DefaultTimeout elapses.
+func (a *Agent) EventuallyEval(t *testing.T, expr string, expected any) {
+	t.Helper()
+
+	require.EventuallyWithT(t, func(ct *assert.CollectT) {
+		v, err := a.Eval(expr)
+		if assert.NoError(ct, err) {
+			assert.Equal(ct, expected, v, "value of %s", expr)
+		}
+	}, DefaultTimeout, 100*time.Millisecond)
+}
+
+// Flow returns the Flow controller of the agent.
+func (a *Agent) Flow() *flow.Flow { return a.flow }
+
+// Stop stops the agent. It's safe to call Stop more than once.
+func (a *Agent) Stop() {
+	a.cancel()
+	<-a.exited
+}
@@ -0,0 +1,35 @@
+package pipelinetests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/grafana/agent/internal/component/discovery/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_UpdateConfig(t *testing.T) {
+	config := func(label string) string {
+		return fmt.Sprintf(`
+discovery.relabel "test" {
+	targets = [{"value" = env("PIPELINETESTS_VALUE"), "label" = %q}]
+}
+`, label)
+	}
+
+	t.Setenv("PIPELINETESTS_VALUE", "a")
+	agent := NewAgent(t, AgentOptions{Config: config("first")})
+	agent.EventuallyEval(t, `discovery.relabel.test.output[0]["value"]`, "a")
+
+	// env is only called when the config is evaluated.
+	t.Setenv("PIPELINETESTS_VALUE", "b")
+	agent.Reevaluate()
+	agent.EventuallyEval(t, `discovery.relabel.test.output[0]["value"]`, "b")
+
+	agent.UpdateConfig(config("second"))
+	agent.EventuallyEval(t, `discovery.relabel.test.output[0]["label"]`, "second")
+
+	exports, ok := agent.Exports("discovery.relabel.test").(relabel.Exports)
+	require.True(t, ok)
+	require.Equal(t, "second", exports.Output[0]["label"])
+}
@@ -0,0 +1,132 @@
+package pipelinetests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NodeLabel is the label which pipelines must add to the series they send to
+// a Backend, set to the name of the node sending them.
+const NodeLabel = "agent_node"
+
+// Backend is a fake Prometheus remote_write backend which records which node
+// sent the series of each target.
+type Backend struct {
+	srv *httptest.Server
+
+	mut      sync.Mutex
+	received map[string]map[string]time.Time // Target instance -> node -> last time a series was received
+}
+
+// NewBackend starts a Backend, which is closed when the test finishes.
+func NewBackend(t *testing.T) *Backend {
+	b := &Backend{received: make(map[string]map[string]time.Time)}
+	b.srv = httptest.NewServer(http.HandlerFunc(b.handleWrite))
+	t.Cleanup(b.srv.Close)
+	return b
+}
+
+// WriteURL returns the URL pipelines should send remote_write requests to.
+func (b *Backend) WriteURL() string {
+	return b.srv.URL + "/api/v1/write"
+}
+
+func (b *Backend) handleWrite(w http.ResponseWriter, r *http.Request) {
+	req, err := remote.DecodeWriteRequest(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	for _, ts := range req.Timeseries {
+		var instance, node string
+		for _, l := range ts.Labels {
+			switch l.Name {
+			case "instance":
+				instance = l.Value
+			case NodeLabel:
+				node = l.Value
+			}
+		}
+		if instance == "" || node == "" {
+			continue
+		}
+		if b.received[instance] == nil {
+			b.received[instance] = make(map[string]time.Time)
+		}
+		b.received[instance][node] = now
+	}
+}
+
+// Owners returns, for each target instance, the sorted names of the nodes
+// which sent series for it after since.
+func (b *Backend) Owners(since time.Time) map[string][]string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	owners := make(map[string][]string)
+	for instance, nodes := range b.received {
+		for node, last := range nodes {
+			if last.After(since) {
+				owners[instance] = append(owners[instance], node)
+			}
+		}
+		sort.Strings(owners[instance])
+	}
+	return owners
+}
+
+// AssertTargetDistribution expects every target to eventually be scraped by
+// exactly one running node of c after since, and every running node to
+// scrape at least one target when there are enough targets. Use since to
+// ignore series sent before a change to the cluster, such as stopping a node.
+func (b *Backend) AssertTargetDistribution(t *testing.T, c *Cluster, targets []string, since time.Time) {
+	t.Helper()
+
+	require.EventuallyWithT(t, func(ct *assert.CollectT) {
+		running := make(map[string]int)
+		for _, n := range c.RunningNodes() {
+			running[n.Name] = 0
+		}
+
+		owners := b.Owners(since)
+		for _, target := range targets {
+			nodes := owners[target]
+			if !assert.Len(ct, nodes, 1, "target %s must be scraped by exactly one node, scraped by %v", target, nodes) {
+				continue
+			}
+			if _, ok := running[nodes[0]]; assert.True(ct, ok, "target %s is scraped by stopped node %s", target, nodes[0]) {
+				running[nodes[0]]++
+			}
+		}
+
+		if len(targets) >= len(running) {
+			for node, count := range running {
+				assert.Positive(ct, count, "node %s doesn't scrape any target", node)
+			}
+		}
+	}, DefaultTimeout, 500*time.Millisecond, "targets aren't distributed across the cluster; owners: %s", formatOwners(b.Owners(since)))
+}
+
+func formatOwners(owners map[string][]string) string {
+	pairs := make([]string, 0, len(owners))
+	for target, nodes := range owners {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", target, strings.Join(nodes, ",")))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,266 @@
+// Package pipelinetests runs Flow pipelines in-process for end-to-end tests.
+//
+// An Agent runs a single Flow controller. Tests can read the exports of its
+// components, evaluate it again, and change its config while it runs.
+//
+// A Cluster starts several Flow controllers which join one cluster, each with
+// its own HTTP server, cluster node, and data directory. Pipelines send data
+// to a shared fake Backend, which records which node sent which series so
+// tests can check how work is distributed across the cluster.
+//
+// Log and trace pipelines can send data to a LokiSink or an OTLPSink, whose
+// Eventually* helpers wait for specific log lines or spans to arrive.
+package pipelinetests
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/agent/internal/service"
+	cluster_service "github.com/grafana/agent/internal/service/cluster"
+	http_service "github.com/grafana/agent/internal/service/http"
+	"github.com/grafana/agent/internal/service/labelstore"
+	otel_service "github.com/grafana/agent/internal/service/otel"
+	"github.com/grafana/ckit/peer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// DefaultTimeout is how long assertions wait for the cluster to reach the
+// expected state.
+const DefaultTimeout = time.Minute
+
+// ClusterOptions configures a Cluster.
+type ClusterOptions struct {
+	// Number of nodes to start.
+	Nodes int
+
+	// Config returns the River config to load on the node with the given
+	// name.
+	Config func(nodeName string) string
+
+	// Where to write the logs of the nodes. Logs are discarded if nil.
+	Logs io.Writer
+}
+
+// Cluster is a set of in-process Flow controllers joined into one cluster.
+type Cluster struct {
+	t    *testing.T
+	opts ClusterOptions
+
+	mut   sync.Mutex
+	nodes []*Node
+}
+
+// Node is a Flow controller running as part of a Cluster.
+type Node struct {
+	Name string // Name of the node in the cluster.
+	Addr string // Address of the HTTP server of the node.
+
+	flow    *flow.Flow
+	cluster *cluster_service.Service
+	cancel  context.CancelFunc
+	exited  chan struct{}
+}
+
+// NewCluster starts a cluster of opts.Nodes nodes and waits for every node to
+// see the others as participants. The cluster is stopped when the test
+// finishes.
+func NewCluster(t *testing.T, opts ClusterOptions) *Cluster {
+	t.Helper()
+	require.Positive(t, opts.Nodes, "a cluster needs at least one node")
+	require.NotNil(t, opts.Config, "a cluster needs a config")
+	if opts.Logs == nil {
+		opts.Logs = io.Discard
+	}
+
+	c := &Cluster{t: t, opts: opts}
+	t.Cleanup(c.Stop)
+
+	for i := 0; i < opts.Nodes; i++ {
+		c.StartNode(fmt.Sprintf("node-%d", i))
+	}
+	c.AssertConverged(t)
+	return c
+}
+
+// StartNode starts a new node which joins the running nodes of the cluster.
+func (c *Cluster) StartNode(name string) *Node {
+	c.t.Helper()
+
+	l, err := logging.New(c.opts.Logs, logging.DefaultOptions)
+	require.NoError(c.t, err)
+
+	addr := freeAddr(c.t)
+	reg := prometheus.NewRegistry()
+
+	clusterService, err := cluster_service.New(cluster_service.Options{
+		Log:              l,
+		Metrics:          reg,
+		EnableClustering: true,
+		NodeName:         name,
+		AdvertiseAddress: addr,
+		RejoinInterval:   5 * time.Second,
+		DiscoverPeers: func() ([]string, error) {
+			var peers []string
+			for _, n := range c.RunningNodes() {
+				if n.Name != name {
+					peers = append(peers, n.Addr)
+				}
+			}
+			return peers, nil
+		},
+	})
+	require.NoError(c.t, err)
+
+	f := newFlow(c.t, l, reg, addr, clusterService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := &Node{
+		Name:    name,
+		Addr:    addr,
+		flow:    f,
+		cluster: clusterService,
+		cancel:  cancel,
+		exited:  make(chan struct{}),
+	}
+	go func() {
+		defer close(n.exited)
+		f.Run(ctx)
+	}()
+
+	source, err := flow.ParseSource(name, []byte(c.opts.Config(name)))
+	require.NoError(c.t, err)
+	require.NoError(c.t, f.LoadSource(source, nil))
+
+	// Like the agent, nodes only participate in the cluster once their config
+	// is loaded.
+	stateCtx, stateCancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer stateCancel()
+	require.NoError(c.t, clusterService.ChangeState(stateCtx, peer.StateParticipant))
+
+	c.mut.Lock()
+	c.nodes = append(c.nodes, n)
+	c.mut.Unlock()
+	return n
+}
+
+// StopNode stops the node with the given name, which leaves the cluster.
+func (c *Cluster) StopNode(name string) {
+	c.t.Helper()
+
+	c.mut.Lock()
+	var stopped *Node
+	for i, n := range c.nodes {
+		if n.Name == name {
+			stopped = n
+			c.nodes = append(c.nodes[:i], c.nodes[i+1:]...)
+			break
+		}
+	}
+	c.mut.Unlock()
+
+	require.NotNil(c.t, stopped, "node %s isn't running", name)
+	stopped.stop()
+}
+
+// Stop stops every node of the cluster.
+func (c *Cluster) Stop() {
+	c.mut.Lock()
+	nodes := c.nodes
+	c.nodes = nil
+	c.mut.Unlock()
+
+	for _, n := range nodes {
+		n.stop()
+	}
+}
+
+// RunningNodes returns the nodes which are running.
+func (c *Cluster) RunningNodes() []*Node {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return append([]*Node(nil), c.nodes...)
+}
+
+// AssertConverged expects every running node to eventually see exactly the
+// running nodes as participants of the cluster.
+func (c *Cluster) AssertConverged(t *testing.T) {
+	t.Helper()
+
+	require.EventuallyWithT(t, func(ct *assert.CollectT) {
+		nodes := c.RunningNodes()
+
+		expected := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			expected = append(expected, n.Name)
+		}
+		for _, n := range nodes {
+			var participants []string
+			for _, p := range n.Peers() {
+				if p.State == peer.StateParticipant {
+					participants = append(participants, p.Name)
+				}
+			}
+			assert.ElementsMatch(ct, expected, participants, "participants seen by %s", n.Name)
+		}
+	}, DefaultTimeout, 100*time.Millisecond, "cluster didn't converge")
+}
+
+// Peers returns the peers of the cluster as seen by n.
+func (n *Node) Peers() []peer.Peer {
+	return n.cluster.Data().(cluster_service.Cluster).Peers()
+}
+
+// Flow returns the Flow controller of n.
+func (n *Node) Flow() *flow.Flow { return n.flow }
+
+func (n *Node) stop() {
+	n.cancel()
+	<-n.exited
+}
+
+// newFlow returns a Flow controller with the services components expect,
+// whose HTTP server listens on addr.
+func newFlow(t *testing.T, l *logging.Logger, reg *prometheus.Registry, addr string, clusterService *cluster_service.Service) *flow.Flow {
+	t.Helper()
+
+	otelService := otel_service.New(l)
+	require.NotNil(t, otelService)
+
+	return flow.New(flow.Options{
+		Logger:       l,
+		DataPath:     t.TempDir(),
+		Reg:          reg,
+		MinStability: featuregate.StabilityBeta,
+		Services: []service.Service{
+			http_service.New(http_service.Options{
+				Logger:         l,
+				Gatherer:       reg,
+				HTTPListenAddr: addr,
+			}),
+			clusterService,
+			otelService,
+			labelstore.New(l, reg),
+		},
+	})
+}
+
+// freeAddr returns a local address which is free to listen on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	return lis.Addr().String()
+}
@@ -0,0 +1,29 @@
+package pipelinetests
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/grafana/agent/internal/component/prometheus/remotewrite"
+	_ "github.com/grafana/agent/internal/component/prometheus/scrape"
+)
+
+func TestCluster_Failover(t *testing.T) {
+	var (
+		backend = NewBackend(t)
+		targets = NewTargets(t, 9)
+	)
+
+	c := NewCluster(t, ClusterOptions{
+		Nodes: 3,
+		Config: func(nodeName string) string {
+			return ClusteredScrapeConfig(nodeName, targets, backend)
+		},
+	})
+	backend.AssertTargetDistribution(t, c, targets, time.Now())
+
+	// The targets of a stopped node are taken over by the remaining nodes.
+	c.StopNode("node-1")
+	c.AssertConverged(t)
+	backend.AssertTargetDistribution(t, c, targets, time.Now())
+}
@@ -0,0 +1,116 @@
+package pipelinetests
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/logproto"
+	loki_util "github.com/grafana/loki/pkg/util"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// LogLine is a log line received by a LokiSink.
+type LogLine struct {
+	Labels    map[string]string
+	Timestamp time.Time
+	Line      string
+}
+
+// LokiSink is a fake Loki push API which records the log lines it receives.
+type LokiSink struct {
+	srv *httptest.Server
+
+	mut   sync.Mutex
+	lines []LogLine
+}
+
+// NewLokiSink starts a LokiSink, which is closed when the test finishes.
+func NewLokiSink(t *testing.T) *LokiSink {
+	s := &LokiSink{}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handlePush))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+// PushURL returns the URL pipelines should send log lines to, for example
+// from the endpoint block of loki.write.
+func (s *LokiSink) PushURL() string {
+	return s.srv.URL + "/loki/api/v1/push"
+}
+
+func (s *LokiSink) handlePush(w http.ResponseWriter, r *http.Request) {
+	var req logproto.PushRequest
+	err := loki_util.ParseProtoReader(context.Background(), r.Body, int(r.ContentLength), math.MaxInt32, &req, loki_util.RawSnappy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var lines []LogLine
+	for _, stream := range req.Streams {
+		lset, err := parser.ParseMetric(stream.Labels)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, entry := range stream.Entries {
+			lines = append(lines, LogLine{
+				Labels:    lset.Map(),
+				Timestamp: entry.Timestamp,
+				Line:      entry.Line,
+			})
+		}
+	}
+
+	s.mut.Lock()
+	s.lines = append(s.lines, lines...)
+	s.mut.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Lines returns the log lines received so far.
+func (s *LokiSink) Lines() []LogLine {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]LogLine(nil), s.lines...)
+}
+
+// FindLines returns the log lines received so far which have every label of
+// selector and contain substr.
+func (s *LokiSink) FindLines(selector map[string]string, substr string) []LogLine {
+	var found []LogLine
+	for _, l := range s.Lines() {
+		if hasLabels(l.Labels, selector) && strings.Contains(l.Line, substr) {
+			found = append(found, l)
+		}
+	}
+	return found
+}
+
+// EventuallyLogLine expects a log line which has every label of selector and
+// contains substr to be received before DefaultTimeout elapses.
+func (s *LokiSink) EventuallyLogLine(t *testing.T, selector map[string]string, substr string) {
+	t.Helper()
+
+	require.EventuallyWithT(t, func(ct *assert.CollectT) {
+		assert.NotEmpty(ct, s.FindLines(selector, substr), "no log line with labels %v containing %q among %d received lines", selector, substr, len(s.Lines()))
+	}, DefaultTimeout, 100*time.Millisecond)
+}
+
+// hasLabels reports whether labels holds every label of selector.
+func hasLabels(labels, selector map[string]string) bool {
+	for name, value := range selector {
+		if v, ok := labels[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
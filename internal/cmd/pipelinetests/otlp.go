@@ -0,0 +1,174 @@
+package pipelinetests
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"google.golang.org/grpc"
+)
+
+// Span is a span received by an OTLPSink. Attribute values are converted to
+// strings.
+type Span struct {
+	TraceID            string
+	Name               string
+	Attributes         map[string]string
+	ResourceAttributes map[string]string
+}
+
+// OTLPSink is a fake OTLP backend which records the spans it receives over
+// gRPC and HTTP.
+type OTLPSink struct {
+	ptraceotlp.UnimplementedGRPCServer
+
+	httpSrv  *httptest.Server
+	grpcSrv  *grpc.Server
+	grpcAddr string
+
+	mut   sync.Mutex
+	spans []Span
+}
+
+var _ ptraceotlp.GRPCServer = (*OTLPSink)(nil)
+
+// NewOTLPSink starts an OTLPSink, which is closed when the test finishes.
+func NewOTLPSink(t *testing.T) *OTLPSink {
+	s := &OTLPSink{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/traces", s.handleTraces)
+	s.httpSrv = httptest.NewServer(mux)
+	t.Cleanup(s.httpSrv.Close)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s.grpcAddr = lis.Addr().String()
+	s.grpcSrv = grpc.NewServer()
+	ptraceotlp.RegisterGRPCServer(s.grpcSrv, s)
+	go func() { _ = s.grpcSrv.Serve(lis) }()
+	t.Cleanup(s.grpcSrv.Stop)
+
+	return s
+}
+
+// GRPCAddr returns the host:port pipelines should send traces to over
+// insecure gRPC, for example from otelcol.exporter.otlp.
+func (s *OTLPSink) GRPCAddr() string { return s.grpcAddr }
+
+// HTTPURL returns the base URL pipelines should send traces to over HTTP,
+// for example from otelcol.exporter.otlphttp. Traces are accepted at the
+// /v1/traces path, encoded as protobuf or JSON.
+func (s *OTLPSink) HTTPURL() string { return s.httpSrv.URL }
+
+// Export implements ptraceotlp.GRPCServer.
+func (s *OTLPSink) Export(_ context.Context, req ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
+	s.record(req.Traces())
+	return ptraceotlp.NewExportResponse(), nil
+}
+
+func (s *OTLPSink) handleTraces(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := ptraceotlp.NewExportRequest()
+	if r.Header.Get("Content-Type") == "application/json" {
+		err = req.UnmarshalJSON(body)
+	} else {
+		err = req.UnmarshalProto(body)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.record(req.Traces())
+
+	resp, err := ptraceotlp.NewExportResponse().MarshalProto()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(resp)
+}
+
+func (s *OTLPSink) record(traces ptrace.Traces) {
+	var spans []Span
+	rss := traces.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		resourceAttrs := attributesMap(rs.Resource().Attributes())
+
+		sss := rs.ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			ss := sss.At(j).Spans()
+			for k := 0; k < ss.Len(); k++ {
+				span := ss.At(k)
+				spans = append(spans, Span{
+					TraceID:            span.TraceID().String(),
+					Name:               span.Name(),
+					Attributes:         attributesMap(span.Attributes()),
+					ResourceAttributes: resourceAttrs,
+				})
+			}
+		}
+	}
+
+	s.mut.Lock()
+	s.spans = append(s.spans, spans...)
+	s.mut.Unlock()
+}
+
+func attributesMap(attrs pcommon.Map) map[string]string {
+	res := make(map[string]string, attrs.Len())
+	attrs.Range(func(k string, v pcommon.Value) bool {
+		res[k] = v.AsString()
+		return true
+	})
+	return res
+}
+
+// Spans returns the spans received so far.
+func (s *OTLPSink) Spans() []Span {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]Span(nil), s.spans...)
+}
+
+// FindSpansWithAttr returns the spans received so far which have the
+// attribute key set to value. Resource attributes are checked too.
+func (s *OTLPSink) FindSpansWithAttr(key, value string) []Span {
+	var found []Span
+	for _, span := range s.Spans() {
+		if v, ok := span.Attributes[key]; ok && v == value {
+			found = append(found, span)
+		} else if v, ok := span.ResourceAttributes[key]; ok && v == value {
+			found = append(found, span)
+		}
+	}
+	return found
+}
+
+// EventuallySpanWithAttr expects a span which has the attribute key set to
+// value to be received before DefaultTimeout elapses. Resource attributes are
+// checked too.
+func (s *OTLPSink) EventuallySpanWithAttr(t *testing.T, key, value string) {
+	t.Helper()
+
+	require.EventuallyWithT(t, func(ct *assert.CollectT) {
+		assert.NotEmpty(ct, s.FindSpansWithAttr(key, value), "no span with attribute %s=%q among %d received spans", key, value, len(s.Spans()))
+	}, DefaultTimeout, 100*time.Millisecond)
+}
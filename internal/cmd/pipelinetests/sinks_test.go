@@ -0,0 +1,127 @@
+package pipelinetests
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/grafana/agent/internal/component/local/file_match"
+	_ "github.com/grafana/agent/internal/component/loki/source/file"
+	_ "github.com/grafana/agent/internal/component/loki/write"
+	_ "github.com/grafana/agent/internal/component/otelcol/exporter/otlp"
+	_ "github.com/grafana/agent/internal/component/otelcol/receiver/otlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLokiSink_Pipeline(t *testing.T) {
+	sink := NewLokiSink(t)
+
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(logFile, []byte("level=info msg=\"hello from the pipeline\"\n"), 0644))
+
+	NewCluster(t, ClusterOptions{
+		Nodes: 1,
+		Config: func(string) string {
+			return fmt.Sprintf(`
+local.file_match "app" {
+	path_targets = [{"__path__" = %q, "app" = "pipelinetests"}]
+}
+
+loki.source.file "app" {
+	targets    = local.file_match.app.targets
+	forward_to = [loki.write.default.receiver]
+}
+
+loki.write "default" {
+	endpoint {
+		url        = %q
+		batch_wait = "100ms"
+	}
+}
+`, logFile, sink.PushURL())
+		},
+	})
+
+	sink.EventuallyLogLine(t, map[string]string{"app": "pipelinetests"}, "hello from the pipeline")
+}
+
+func TestOTLPSink_Pipeline(t *testing.T) {
+	sink := NewOTLPSink(t)
+	receiverAddr := freeAddr(t)
+
+	NewCluster(t, ClusterOptions{
+		Nodes: 1,
+		Config: func(string) string {
+			return fmt.Sprintf(`
+otelcol.receiver.otlp "default" {
+	http {
+		endpoint = %q
+	}
+
+	output {
+		traces = [otelcol.exporter.otlp.default.input]
+	}
+}
+
+otelcol.exporter.otlp "default" {
+	client {
+		endpoint = %q
+
+		tls {
+			insecure = true
+		}
+	}
+}
+`, receiverAddr, sink.GRPCAddr())
+		},
+	})
+
+	span := `{"resourceSpans": [{
+		"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "pipelinetests"}}]},
+		"scopeSpans": [{"spans": [{
+			"traceId": "5b8efff798038103d269b633813fc60c",
+			"spanId": "eee19b7ec3c1b174",
+			"name": "test-span",
+			"kind": 1,
+			"attributes": [{"key": "http.method", "value": {"stringValue": "GET"}}]
+		}]}]
+	}]}`
+
+	// The receiver may not be listening yet right after the config is loaded.
+	require.Eventually(t, func() bool {
+		resp, err := http.Post("http://"+receiverAddr+"/v1/traces", "application/json", strings.NewReader(span))
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, DefaultTimeout, 100*time.Millisecond)
+
+	sink.EventuallySpanWithAttr(t, "http.method", "GET")
+	sink.EventuallySpanWithAttr(t, "service.name", "pipelinetests")
+}
+
+func TestOTLPSink_HTTP(t *testing.T) {
+	sink := NewOTLPSink(t)
+
+	span := `{"resourceSpans": [{"scopeSpans": [{"spans": [{
+		"traceId": "5b8efff798038103d269b633813fc60c",
+		"spanId": "eee19b7ec3c1b174",
+		"name": "test-span",
+		"attributes": [{"key": "http.status_code", "value": {"intValue": "200"}}]
+	}]}]}]}`
+
+	resp, err := http.Post(sink.HTTPURL()+"/v1/traces", "application/json", strings.NewReader(span))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	spans := sink.FindSpansWithAttr("http.status_code", "200")
+	require.Len(t, spans, 1)
+	require.Equal(t, "test-span", spans[0].Name)
+	require.Equal(t, "5b8efff798038103d269b633813fc60c", spans[0].TraceID)
+}
@@ -0,0 +1,61 @@
+package pipelinetests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// NewTargets starts n scrape targets which expose a single metric, returning
+// their addresses. The targets are closed when the test finishes.
+func NewTargets(t *testing.T, n int) []string {
+	addrs := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			_, _ = fmt.Fprintln(w, "pipelinetests_target_up 1")
+		}))
+		t.Cleanup(srv.Close)
+		addrs = append(addrs, srv.Listener.Addr().String())
+	}
+	return addrs
+}
+
+// ClusteredScrapeConfig returns a config which scrapes targets with
+// clustering enabled and sends the series to b, labeled with the name of the
+// node.
+func ClusteredScrapeConfig(nodeName string, targets []string, b *Backend) string {
+	entries := make([]string, 0, len(targets))
+	for _, target := range targets {
+		entries = append(entries, fmt.Sprintf(`{"__address__" = %q}`, target))
+	}
+
+	return fmt.Sprintf(`
+prometheus.scrape "default" {
+	targets         = [%s]
+	forward_to      = [prometheus.remote_write.default.receiver]
+	scrape_interval = "1s"
+	scrape_timeout  = "500ms"
+
+	clustering {
+		enabled = true
+	}
+}
+
+prometheus.remote_write "default" {
+	external_labels = {
+		%s = %q,
+	}
+
+	endpoint {
+		url = %q
+
+		queue_config {
+			batch_send_deadline = "100ms"
+		}
+	}
+}
+`, strings.Join(entries, ", "), NodeLabel, nodeName, b.WriteURL())
+}
@@ -3,6 +3,7 @@ package all
 
 import (
 	_ "github.com/grafana/agent/internal/component/beyla/ebpf"                               // Import beyla.ebpf
+	_ "github.com/grafana/agent/internal/component/cluster/distribute"                       // Import cluster.distribute
 	_ "github.com/grafana/agent/internal/component/discovery/aws"                            // Import discovery.aws.ec2 and discovery.aws.lightsail
 	_ "github.com/grafana/agent/internal/component/discovery/azure"                          // Import discovery.azure
 	_ "github.com/grafana/agent/internal/component/discovery/consul"                         // Import discovery.consul
@@ -33,13 +34,16 @@ import (
 	_ "github.com/grafana/agent/internal/component/discovery/serverset"                      // Import discovery.serverset
 	_ "github.com/grafana/agent/internal/component/discovery/triton"                         // Import discovery.triton
 	_ "github.com/grafana/agent/internal/component/discovery/uyuni"                          // Import discovery.uyuni
+	_ "github.com/grafana/agent/internal/component/discovery/write_file"                     // Import discovery.write_file
 	_ "github.com/grafana/agent/internal/component/faro/receiver"                            // Import faro.receiver
 	_ "github.com/grafana/agent/internal/component/local/file"                               // Import local.file
 	_ "github.com/grafana/agent/internal/component/local/file_match"                         // Import local.file_match
 	_ "github.com/grafana/agent/internal/component/loki/echo"                                // Import loki.echo
 	_ "github.com/grafana/agent/internal/component/loki/process"                             // Import loki.process
 	_ "github.com/grafana/agent/internal/component/loki/relabel"                             // Import loki.relabel
+	_ "github.com/grafana/agent/internal/component/loki/route"                               // Import loki.route
 	_ "github.com/grafana/agent/internal/component/loki/rules/kubernetes"                    // Import loki.rules.kubernetes
+	_ "github.com/grafana/agent/internal/component/loki/sample"                              // Import loki.sample
 	_ "github.com/grafana/agent/internal/component/loki/source/api"                          // Import loki.source.api
 	_ "github.com/grafana/agent/internal/component/loki/source/aws_firehose"                 // Import loki.source.awsfirehose
 	_ "github.com/grafana/agent/internal/component/loki/source/azure_event_hubs"             // Import loki.source.azure_event_hubs
@@ -56,7 +60,9 @@ import (
 	_ "github.com/grafana/agent/internal/component/loki/source/podlogs"                      // Import loki.source.podlogs
 	_ "github.com/grafana/agent/internal/component/loki/source/syslog"                       // Import loki.source.syslog
 	_ "github.com/grafana/agent/internal/component/loki/source/windowsevent"                 // Import loki.source.windowsevent
+	_ "github.com/grafana/agent/internal/component/loki/tenant_router"                       // Import loki.tenant_router
 	_ "github.com/grafana/agent/internal/component/loki/write"                               // Import loki.write
+	_ "github.com/grafana/agent/internal/component/loki/write/kafka"                         // Import loki.write.kafka
 	_ "github.com/grafana/agent/internal/component/mimir/rules/kubernetes"                   // Import mimir.rules.kubernetes
 	_ "github.com/grafana/agent/internal/component/module/file"                              // Import module.file
 	_ "github.com/grafana/agent/internal/component/module/git"                               // Import module.git
@@ -97,6 +103,9 @@ import (
 	_ "github.com/grafana/agent/internal/component/otelcol/receiver/prometheus"              // Import otelcol.receiver.prometheus
 	_ "github.com/grafana/agent/internal/component/otelcol/receiver/vcenter"                 // Import otelcol.receiver.vcenter
 	_ "github.com/grafana/agent/internal/component/otelcol/receiver/zipkin"                  // Import otelcol.receiver.zipkin
+	_ "github.com/grafana/agent/internal/component/prometheus/adaptive_metrics"              // Import prometheus.adaptive_metrics
+	_ "github.com/grafana/agent/internal/component/prometheus/aggregate"                     // Import prometheus.aggregate
+	_ "github.com/grafana/agent/internal/component/prometheus/dedup"                         // Import prometheus.dedup
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/apache"               // Import prometheus.exporter.apache
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/azure"                // Import prometheus.exporter.azure
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/blackbox"             // Import prometheus.exporter.blackbox
@@ -124,20 +133,29 @@ import (
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/unix"                 // Import prometheus.exporter.unix
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/vsphere"              // Import prometheus.exporter.vsphere
 	_ "github.com/grafana/agent/internal/component/prometheus/exporter/windows"              // Import prometheus.exporter.windows
+	_ "github.com/grafana/agent/internal/component/prometheus/expose"                        // Import prometheus.expose
+	_ "github.com/grafana/agent/internal/component/prometheus/filter"                        // Import prometheus.filter
 	_ "github.com/grafana/agent/internal/component/prometheus/operator/podmonitors"          // Import prometheus.operator.podmonitors
 	_ "github.com/grafana/agent/internal/component/prometheus/operator/probes"               // Import prometheus.operator.probes
 	_ "github.com/grafana/agent/internal/component/prometheus/operator/servicemonitors"      // Import prometheus.operator.servicemonitors
+	_ "github.com/grafana/agent/internal/component/prometheus/rate_limit"                    // Import prometheus.rate_limit
 	_ "github.com/grafana/agent/internal/component/prometheus/receive_http"                  // Import prometheus.receive_http
 	_ "github.com/grafana/agent/internal/component/prometheus/relabel"                       // Import prometheus.relabel
 	_ "github.com/grafana/agent/internal/component/prometheus/remotewrite"                   // Import prometheus.remote_write
+	_ "github.com/grafana/agent/internal/component/prometheus/route"                         // Import prometheus.route
+	_ "github.com/grafana/agent/internal/component/prometheus/rules/file"                    // Import prometheus.rules.file
 	_ "github.com/grafana/agent/internal/component/prometheus/scrape"                        // Import prometheus.scrape
+	_ "github.com/grafana/agent/internal/component/prometheus/tenant_router"                 // Import prometheus.tenant_router
 	_ "github.com/grafana/agent/internal/component/pyroscope/ebpf"                           // Import pyroscope.ebpf
 	_ "github.com/grafana/agent/internal/component/pyroscope/java"                           // Import pyroscope.java
 	_ "github.com/grafana/agent/internal/component/pyroscope/scrape"                         // Import pyroscope.scrape
 	_ "github.com/grafana/agent/internal/component/pyroscope/write"                          // Import pyroscope.write
+	_ "github.com/grafana/agent/internal/component/remote/endpointcheck"                     // Import remote.endpoint_check
+	_ "github.com/grafana/agent/internal/component/remote/heartbeat"                         // Import remote.heartbeat
 	_ "github.com/grafana/agent/internal/component/remote/http"                              // Import remote.http
 	_ "github.com/grafana/agent/internal/component/remote/kubernetes/configmap"              // Import remote.kubernetes.configmap
 	_ "github.com/grafana/agent/internal/component/remote/kubernetes/secret"                 // Import remote.kubernetes.secret
 	_ "github.com/grafana/agent/internal/component/remote/s3"                                // Import remote.s3
 	_ "github.com/grafana/agent/internal/component/remote/vault"                             // Import remote.vault
+	_ "github.com/grafana/agent/internal/component/trigger/cron"                             // Import trigger.cron
 )
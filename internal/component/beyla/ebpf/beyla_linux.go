@@ -33,6 +33,13 @@ func init() {
 		Stability: featuregate.StabilityBeta,
 		Args:      Arguments{},
 		Exports:   Exports{},
+		Sandbox: component.SandboxExceptions{
+			// Executables of instrumented processes, which can be anywhere on
+			// the host, are read to find the functions to instrument.
+			ReadPaths:      []string{"/"},
+			Syscalls:       []string{"bpf", "perf_event_open"},
+			SocketFamilies: []string{"packet"},
+		},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
@@ -0,0 +1,152 @@
+package distribute
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/cluster"
+	"github.com/grafana/ckit/shard"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "cluster.distribute",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the cluster.distribute
+// component.
+type Arguments struct {
+	// Targets to distribute. Targets are distributed like the targets of
+	// prometheus.scrape, by their labels without the __meta_ labels.
+	Targets []discovery.Target `river:"targets,attr,optional"`
+
+	// Values of any type to distribute, by their string representation.
+	Values []any `river:"values,attr,optional"`
+}
+
+// Exports holds values which are exported by the cluster.distribute
+// component.
+type Exports struct {
+	Targets []discovery.Target `river:"targets,attr"`
+	Values  []any              `river:"values,attr"`
+}
+
+// Component implements the cluster.distribute component.
+type Component struct {
+	opts    component.Options
+	cluster cluster.Cluster
+
+	ownedTargets prometheus.Gauge
+	ownedValues  prometheus.Gauge
+
+	mut     sync.Mutex
+	args    Arguments
+	exports Exports
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+	_ cluster.Component   = (*Component)(nil)
+)
+
+// New creates a new cluster.distribute component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(cluster.ServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get information about cluster: %w", err)
+	}
+
+	c := &Component{
+		opts:    o,
+		cluster: data.(cluster.Cluster),
+		ownedTargets: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_cluster_distribute_owned_targets",
+			Help: "Number of targets owned by this cluster node",
+		}),
+		ownedValues: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_cluster_distribute_owned_values",
+			Help: "Number of values owned by this cluster node",
+		}),
+	}
+	for _, metric := range []prometheus.Collector{c.ownedTargets, c.ownedValues} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.args = args.(Arguments)
+	c.distribute(true)
+	return nil
+}
+
+// NotifyClusterChange implements cluster.Component.
+func (c *Component) NotifyClusterChange() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.distribute(false)
+}
+
+// distribute exports the targets and values owned by the local node. Unless
+// force is set, exports are only updated if ownership changed. distribute
+// must be called with c.mut held.
+func (c *Component) distribute(force bool) {
+	dt := discovery.NewDistributedTargets(true, c.cluster, c.args.Targets)
+	exports := Exports{
+		Targets: dt.Get(),
+		Values:  make([]any, 0, len(c.args.Values)),
+	}
+	for _, v := range c.args.Values {
+		if c.owns(fmt.Sprint(v)) {
+			exports.Values = append(exports.Values, v)
+		}
+	}
+
+	c.ownedTargets.Set(float64(len(exports.Targets)))
+	c.ownedValues.Set(float64(len(exports.Values)))
+
+	if !force && reflect.DeepEqual(c.exports, exports) {
+		return
+	}
+	c.exports = exports
+	c.opts.OnStateChange(exports)
+}
+
+// owns returns whether the local node owns key.
+func (c *Component) owns(key string) bool {
+	peers, err := c.cluster.Lookup(shard.StringKey(key), 1, shard.OpReadWrite)
+	// Lookup can only fail when asking for more owners than there are peers.
+	// In that case, fall back to owning the key.
+	return err != nil || len(peers) == 0 || peers[0].Self
+}
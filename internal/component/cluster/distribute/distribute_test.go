@@ -0,0 +1,99 @@
+package distribute
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/service/cluster"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/ckit/peer"
+	"github.com/grafana/ckit/shard"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+// ownerCluster is a cluster where the local node owns the keys in owned.
+type ownerCluster struct {
+	mut   sync.Mutex
+	owned map[string]bool
+}
+
+func (c *ownerCluster) Lookup(key shard.Key, _ int, _ shard.Op) ([]peer.Peer, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	for k := range c.owned {
+		if shard.StringKey(k) == key {
+			return []peer.Peer{{Name: "local", Self: true}}, nil
+		}
+	}
+	return []peer.Peer{{Name: "remote"}}, nil
+}
+
+func (c *ownerCluster) Peers() []peer.Peer {
+	return []peer.Peer{{Name: "local", Self: true}, {Name: "remote"}}
+}
+
+func (c *ownerCluster) setOwned(keys ...string) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.owned = make(map[string]bool)
+	for _, k := range keys {
+		c.owned[k] = true
+	}
+}
+
+func TestDistribute(t *testing.T) {
+	var (
+		cl      = &ownerCluster{}
+		updates int
+		exports Exports
+	)
+	// Targets are owned by their labels without the __meta_ labels.
+	cl.setOwned(`{__address__="a:80"}`, "foo")
+
+	c, err := New(component.Options{
+		ID:     "cluster.distribute.test",
+		Logger: util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {
+			updates++
+			exports = e.(Exports)
+		},
+		Registerer: prometheus.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return cluster.Cluster(cl), nil
+		},
+	}, Arguments{
+		Targets: []discovery.Target{
+			{"__address__": "a:80", "__meta_kubernetes_pod_name": "a"},
+			{"__address__": "b:80"},
+		},
+		Values: []any{"foo", "bar"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, updates)
+	require.Equal(t, []discovery.Target{{"__address__": "a:80", "__meta_kubernetes_pod_name": "a"}}, exports.Targets)
+	require.Equal(t, []any{"foo"}, exports.Values)
+
+	// Exports are only updated when ownership changes.
+	c.NotifyClusterChange()
+	require.Equal(t, 1, updates)
+
+	cl.setOwned(`{__address__="b:80"}`, "foo", "bar")
+	c.NotifyClusterChange()
+	require.Equal(t, 2, updates)
+	require.Equal(t, []discovery.Target{{"__address__": "b:80"}}, exports.Targets)
+	require.Equal(t, []any{"foo", "bar"}, exports.Values)
+}
+
+func TestRiverArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		targets = [{"__address__" = "a:80"}]
+		values  = ["foo", 1, {"key" = "value"}]
+	`), &args))
+	require.Len(t, args.Targets, 1)
+	require.Len(t, args.Values, 3)
+}
@@ -0,0 +1,334 @@
+// Package dlq implements a dead-letter queue for write components. Payloads
+// which an endpoint rejected with a permanent error are stored in bounded
+// on-disk storage instead of being dropped, so that they can be inspected,
+// re-driven, or purged later.
+package dlq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// recordExt is the file extension of records stored on disk.
+const recordExt = ".json"
+
+// ErrNotFound is returned when a record doesn't exist.
+var ErrNotFound = errors.New("record not found")
+
+// Arguments configures the dead-letter queue of a component.
+type Arguments struct {
+	MaxSize units.Base2Bytes `river:"max_size,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	MaxSize: 100 * units.MiB,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.MaxSize <= 0 {
+		return fmt.Errorf("max_size must be greater than 0")
+	}
+	return nil
+}
+
+// Record is a payload which was rejected by an endpoint.
+type Record struct {
+	ID       uint64    `json:"id"`
+	Time     time.Time `json:"time"`
+	Endpoint string    `json:"endpoint"`
+	Tenant   string    `json:"tenant,omitempty"`
+	Status   int       `json:"status"`
+	Error    string    `json:"error"`
+	Entries  int       `json:"entries"`
+	Size     int       `json:"size"`
+
+	// Payload is the request body which was rejected. It's only set on
+	// records returned by Get.
+	Payload []byte `json:"-"`
+}
+
+// diskRecord is the representation of a record on disk.
+type diskRecord struct {
+	Record
+	Payload []byte `json:"payload"`
+}
+
+// Queue is a dead-letter queue stored in a directory. Queue implements
+// [prometheus.Collector]. A Queue without Arguments stores nothing.
+type Queue struct {
+	dir string
+
+	recordsDesc *prometheus.Desc
+	bytesDesc   *prometheus.Desc
+	evictedDesc *prometheus.Desc
+
+	mut     sync.Mutex
+	args    *Arguments
+	loaded  bool
+	records []Record // Ordered by ID, without payloads.
+	size    int64
+	nextID  uint64
+	evicted uint64
+}
+
+var _ prometheus.Collector = (*Queue)(nil)
+
+// NewQueue returns a new Queue which stores records in dir. Call Update to
+// enable it.
+func NewQueue(dir string) *Queue {
+	return &Queue{
+		dir: dir,
+
+		recordsDesc: prometheus.NewDesc(
+			"agent_write_dead_letter_records",
+			"Number of payloads stored in the dead-letter queue.",
+			nil, nil,
+		),
+		bytesDesc: prometheus.NewDesc(
+			"agent_write_dead_letter_bytes",
+			"Size in bytes of the payloads stored in the dead-letter queue.",
+			nil, nil,
+		),
+		evictedDesc: prometheus.NewDesc(
+			"agent_write_dead_letter_evicted_total",
+			"Total number of payloads evicted from the dead-letter queue to stay within its maximum size.",
+			nil, nil,
+		),
+	}
+}
+
+// Update applies new arguments to q. Passing nil disables q; records already
+// stored are kept on disk.
+func (q *Queue) Update(args *Arguments) error {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	q.args = args
+	if args == nil {
+		return nil
+	}
+	if !q.loaded {
+		if err := q.load(); err != nil {
+			return err
+		}
+		q.loaded = true
+	}
+	return q.evict(0)
+}
+
+// Enabled reports whether q stores records.
+func (q *Queue) Enabled() bool {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return q.args != nil
+}
+
+// load reads the records stored in q.dir. q.mut must be held.
+func (q *Queue) load() error {
+	if err := os.MkdirAll(q.dir, 0750); err != nil {
+		return fmt.Errorf("creating dead-letter queue directory: %w", err)
+	}
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return fmt.Errorf("reading dead-letter queue directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), recordExt) {
+			continue
+		}
+		rec, err := q.read(entry.Name())
+		if err != nil {
+			// A record may be partially written if the process exited while
+			// writing it; it's skipped rather than failing the component.
+			continue
+		}
+		rec.Payload = nil
+		q.records = append(q.records, rec)
+		q.size += int64(rec.Size)
+		if rec.ID >= q.nextID {
+			q.nextID = rec.ID + 1
+		}
+	}
+	sort.Slice(q.records, func(i, j int) bool { return q.records[i].ID < q.records[j].ID })
+	return nil
+}
+
+// Add stores a record. The ID, time, and size of rec are set by Add. The
+// oldest records are evicted to stay within the maximum size. Add reports
+// whether the record was stored, which isn't the case if q is disabled.
+func (q *Queue) Add(rec Record) (bool, error) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	if q.args == nil {
+		return false, nil
+	}
+	if int64(len(rec.Payload)) > int64(q.args.MaxSize) {
+		return false, fmt.Errorf("payload of %d bytes is larger than the dead-letter queue", len(rec.Payload))
+	}
+	if err := q.evict(int64(len(rec.Payload))); err != nil {
+		return false, err
+	}
+
+	rec.ID = q.nextID
+	rec.Time = time.Now().UTC()
+	rec.Size = len(rec.Payload)
+	if err := q.write(rec); err != nil {
+		return false, err
+	}
+
+	q.nextID++
+	q.size += int64(rec.Size)
+	rec.Payload = nil
+	q.records = append(q.records, rec)
+	return true, nil
+}
+
+// evict removes the oldest records until a payload of size bytes can be
+// added. q.mut must be held.
+func (q *Queue) evict(size int64) error {
+	for len(q.records) > 0 && q.size+size > int64(q.args.MaxSize) {
+		if err := q.remove(0); err != nil {
+			return err
+		}
+		q.evicted++
+	}
+	return nil
+}
+
+// List returns the stored records, oldest first, without their payloads.
+func (q *Queue) List() []Record {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return append([]Record(nil), q.records...)
+}
+
+// Get returns the record with the given ID, including its payload.
+func (q *Queue) Get(id uint64) (Record, error) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	if _, ok := q.index(id); !ok {
+		return Record{}, ErrNotFound
+	}
+	return q.read(filename(id))
+}
+
+// Remove deletes the record with the given ID.
+func (q *Queue) Remove(id uint64) error {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	i, ok := q.index(id)
+	if !ok {
+		return ErrNotFound
+	}
+	return q.remove(i)
+}
+
+// Purge deletes all records.
+func (q *Queue) Purge() error {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	for len(q.records) > 0 {
+		if err := q.remove(len(q.records) - 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// index returns the index of the record with the given ID in q.records.
+// q.mut must be held.
+func (q *Queue) index(id uint64) (int, bool) {
+	i := sort.Search(len(q.records), func(i int) bool { return q.records[i].ID >= id })
+	return i, i < len(q.records) && q.records[i].ID == id
+}
+
+// remove deletes the record at index i of q.records. q.mut must be held.
+func (q *Queue) remove(i int) error {
+	rec := q.records[i]
+	if err := os.Remove(filepath.Join(q.dir, filename(rec.ID))); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing dead-letter record: %w", err)
+	}
+	q.records = append(q.records[:i], q.records[i+1:]...)
+	q.size -= int64(rec.Size)
+	return nil
+}
+
+// write stores rec on disk. The record is written to a temporary file first
+// so that partially written records are never loaded.
+func (q *Queue) write(rec Record) error {
+	bb, err := json.Marshal(diskRecord{Record: rec, Payload: rec.Payload})
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(q.dir, filename(rec.ID))
+	if err := os.WriteFile(path+".tmp", bb, 0640); err != nil {
+		return fmt.Errorf("writing dead-letter record: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("writing dead-letter record: %w", err)
+	}
+	return nil
+}
+
+// read loads the record stored in the named file.
+func (q *Queue) read(name string) (Record, error) {
+	bb, err := os.ReadFile(filepath.Join(q.dir, name))
+	if err != nil {
+		return Record{}, fmt.Errorf("reading dead-letter record: %w", err)
+	}
+	var dr diskRecord
+	if err := json.Unmarshal(bb, &dr); err != nil {
+		return Record{}, fmt.Errorf("decoding dead-letter record %s: %w", name, err)
+	}
+	dr.Record.Payload = dr.Payload
+	return dr.Record, nil
+}
+
+// filename returns the name of the file storing the record with the given ID.
+// IDs are zero-padded so that files sort in the order they were added.
+func filename(id uint64) string {
+	return fmt.Sprintf("%020d%s", id, recordExt)
+}
+
+// Describe implements [prometheus.Collector].
+func (q *Queue) Describe(ch chan<- *prometheus.Desc) {
+	ch <- q.recordsDesc
+	ch <- q.bytesDesc
+	ch <- q.evictedDesc
+}
+
+// Collect implements [prometheus.Collector].
+func (q *Queue) Collect(ch chan<- prometheus.Metric) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	if q.args == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(q.recordsDesc, prometheus.GaugeValue, float64(len(q.records)))
+	ch <- prometheus.MustNewConstMetric(q.bytesDesc, prometheus.GaugeValue, float64(q.size))
+	ch <- prometheus.MustNewConstMetric(q.evictedDesc, prometheus.CounterValue, float64(q.evicted))
+}
@@ -0,0 +1,126 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/units"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestQueue(t *testing.T, dir string, maxSize units.Base2Bytes) *Queue {
+	t.Helper()
+	q := NewQueue(dir)
+	require.NoError(t, q.Update(&Arguments{MaxSize: maxSize}))
+	return q
+}
+
+func TestQueue(t *testing.T) {
+	dir := t.TempDir()
+	q := newTestQueue(t, dir, 8)
+
+	for i := 0; i < 3; i++ {
+		stored, err := q.Add(Record{Endpoint: "default", Status: 400, Payload: []byte(fmt.Sprintf("abcd%d", i))})
+		require.NoError(t, err)
+		require.True(t, stored)
+	}
+
+	// The oldest record was evicted to stay within 8 bytes.
+	records := q.List()
+	require.Len(t, records, 1)
+	require.Equal(t, uint64(2), records[0].ID)
+	require.Nil(t, records[0].Payload)
+	require.Equal(t, uint64(2), q.evicted)
+
+	rec, err := q.Get(2)
+	require.NoError(t, err)
+	require.Equal(t, "abcd2", string(rec.Payload))
+	require.Equal(t, 400, rec.Status)
+
+	_, err = q.Get(0)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = q.Add(Record{Payload: []byte("more than eight bytes")})
+	require.Error(t, err)
+
+	// Records are loaded again from disk.
+	reopened := newTestQueue(t, dir, 8)
+	require.Equal(t, records, reopened.List())
+	stored, err := reopened.Add(Record{Payload: []byte("x")})
+	require.NoError(t, err)
+	require.True(t, stored)
+	require.Equal(t, uint64(3), reopened.List()[1].ID)
+
+	require.NoError(t, reopened.Purge())
+	require.Empty(t, reopened.List())
+	require.Empty(t, newTestQueue(t, dir, 8).List())
+}
+
+func TestQueue_Disabled(t *testing.T) {
+	q := NewQueue(t.TempDir())
+	stored, err := q.Add(Record{Payload: []byte("payload")})
+	require.NoError(t, err)
+	require.False(t, stored)
+	require.Equal(t, 0, testutil.CollectAndCount(q))
+}
+
+func TestHandler(t *testing.T) {
+	q := newTestQueue(t, t.TempDir(), units.MiB)
+	for _, payload := range []string{"ok", "fail"} {
+		_, err := q.Add(Record{Endpoint: "default", Status: 400, Payload: []byte(payload)})
+		require.NoError(t, err)
+	}
+
+	redrive := func(_ context.Context, rec Record) error {
+		if string(rec.Payload) == "fail" {
+			return fmt.Errorf("rejected again")
+		}
+		return nil
+	}
+	decode := func(payload []byte) (any, error) { return strings.ToUpper(string(payload)), nil }
+	srv := httptest.NewServer(q.Handler(redrive, decode))
+	defer srv.Close()
+
+	do := func(method, path string) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	var records []Record
+	resp := do(http.MethodGet, "/dead_letter/records")
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&records))
+	require.Len(t, records, 2)
+
+	var details struct {
+		ID      uint64 `json:"id"`
+		Payload string `json:"payload"`
+	}
+	resp = do(http.MethodGet, "/dead_letter/records/0")
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&details))
+	require.Equal(t, "OK", details.Payload)
+
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/dead_letter/records/5").StatusCode)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodGet, "/dead_letter/records/abc").StatusCode)
+
+	// Only records which were re-driven successfully are removed.
+	var res redriveResult
+	resp = do(http.MethodPost, "/dead_letter/records/redrive")
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	require.Equal(t, 1, res.Redriven)
+	require.Equal(t, 1, res.Failed)
+	require.Len(t, q.List(), 1)
+
+	require.Equal(t, http.StatusBadGateway, do(http.MethodPost, "/dead_letter/records/1/redrive").StatusCode)
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/dead_letter/records/1").StatusCode)
+	require.Empty(t, q.List())
+}
@@ -0,0 +1,153 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// RedriveFunc sends the payload of rec to its endpoint again.
+type RedriveFunc func(ctx context.Context, rec Record) error
+
+// DecodeFunc decodes a payload into a value which can be marshaled as JSON
+// for inspection.
+type DecodeFunc func(payload []byte) (any, error)
+
+// recordDetails is the response of the endpoint returning a single record.
+type recordDetails struct {
+	Record
+	Payload any `json:"payload,omitempty"`
+}
+
+// redriveResult is the response of the endpoint re-driving all records.
+type redriveResult struct {
+	Redriven int      `json:"redriven"`
+	Failed   int      `json:"failed"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// Handler returns an HTTP handler to inspect, re-drive, and purge the records
+// of q. Records are removed once they're re-driven successfully. Payloads of
+// single records are decoded with decode for inspection.
+//
+// The following routes are served:
+//
+//   - GET /dead_letter/records: lists the records.
+//   - DELETE /dead_letter/records: purges all records.
+//   - POST /dead_letter/records/redrive: re-drives all records.
+//   - GET /dead_letter/records/{id}: returns a record and its decoded payload.
+//   - DELETE /dead_letter/records/{id}: deletes a record.
+//   - POST /dead_letter/records/{id}/redrive: re-drives a record.
+func (q *Queue) Handler(redrive RedriveFunc, decode DecodeFunc) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /dead_letter/records", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, q.List())
+	})
+	mux.HandleFunc("DELETE /dead_letter/records", func(w http.ResponseWriter, r *http.Request) {
+		if err := q.Purge(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /dead_letter/records/redrive", func(w http.ResponseWriter, r *http.Request) {
+		var res redriveResult
+		for _, rec := range q.List() {
+			if err := q.redrive(r.Context(), rec.ID, redrive); err != nil {
+				res.Failed++
+				res.Errors = append(res.Errors, err.Error())
+				continue
+			}
+			res.Redriven++
+		}
+		writeJSON(w, http.StatusOK, res)
+	})
+
+	mux.HandleFunc("GET /dead_letter/records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseID(w, r)
+		if !ok {
+			return
+		}
+		rec, err := q.Get(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		details := recordDetails{Record: rec}
+		if decode != nil {
+			if details.Payload, err = decode(rec.Payload); err != nil {
+				http.Error(w, "decoding payload: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, details)
+	})
+	mux.HandleFunc("DELETE /dead_letter/records/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseID(w, r)
+		if !ok {
+			return
+		}
+		if err := q.Remove(id); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /dead_letter/records/{id}/redrive", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseID(w, r)
+		if !ok {
+			return
+		}
+		if err := q.redrive(r.Context(), id, redrive); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				writeError(w, err)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// redrive re-drives the record with the given ID, removing it if it was sent
+// successfully.
+func (q *Queue) redrive(ctx context.Context, id uint64, redrive RedriveFunc) error {
+	rec, err := q.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := redrive(ctx, rec); err != nil {
+		return err
+	}
+	return q.Remove(id)
+}
+
+func parseID(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid record ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
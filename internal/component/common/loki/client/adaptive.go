@@ -0,0 +1,94 @@
+package client
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/atomic"
+)
+
+// AdaptiveBatchConfig configures adaptive batching. When enabled, the batch
+// size of a client starts at Config.BatchSize and is adjusted between
+// MinBatchSize and MaxBatchSize based on the latency of send requests and on
+// rate limiting by the endpoint.
+type AdaptiveBatchConfig struct {
+	Enabled       bool
+	MinBatchSize  int
+	MaxBatchSize  int
+	TargetLatency time.Duration
+}
+
+// maxBatchSize returns the largest size a batch of the client can grow to.
+func (c Config) maxBatchSize() int {
+	if c.AdaptiveBatching.Enabled && c.AdaptiveBatching.MaxBatchSize > c.BatchSize {
+		return c.AdaptiveBatching.MaxBatchSize
+	}
+	return c.BatchSize
+}
+
+// batchSizer tracks the size at which batches of a client are sent.
+//
+// With adaptive batching, the size grows by a quarter after every send
+// request faster than half the target latency, shrinks by a quarter after
+// every request slower than the target latency, and is halved when the
+// endpoint rate limits a request. Other failed requests don't change the
+// size, as they're unlikely to be caused by it.
+type batchSizer struct {
+	cfg   AdaptiveBatchConfig
+	size  *atomic.Int64
+	gauge prometheus.Gauge
+}
+
+func newBatchSizer(metrics *Metrics, cfg Config) *batchSizer {
+	s := &batchSizer{
+		cfg:   cfg.AdaptiveBatching,
+		size:  atomic.NewInt64(int64(cfg.BatchSize)),
+		gauge: metrics.batchSizeBytes.WithLabelValues(cfg.URL.Host),
+	}
+	if s.cfg.Enabled {
+		s.size.Store(int64(s.clamp(cfg.BatchSize)))
+	}
+	s.gauge.Set(float64(s.size.Load()))
+	metrics.batchWaitSeconds.WithLabelValues(cfg.URL.Host).Set(cfg.BatchWait.Seconds())
+	return s
+}
+
+// Size returns the size in bytes at which batches should be sent.
+func (s *batchSizer) Size() int {
+	return int(s.size.Load())
+}
+
+// observe adjusts the batch size based on the outcome of a send request.
+func (s *batchSizer) observe(latency time.Duration, status int, err error) {
+	if !s.cfg.Enabled {
+		return
+	}
+
+	size := s.Size()
+	switch {
+	case batchIsRateLimited(status):
+		size /= 2
+	case err != nil:
+		return
+	case latency < s.cfg.TargetLatency/2:
+		size += size / 4
+	case latency > s.cfg.TargetLatency:
+		size -= size / 4
+	default:
+		return
+	}
+
+	size = s.clamp(size)
+	s.size.Store(int64(size))
+	s.gauge.Set(float64(size))
+}
+
+func (s *batchSizer) clamp(size int) int {
+	if size < s.cfg.MinBatchSize {
+		return s.cfg.MinBatchSize
+	}
+	if size > s.cfg.MaxBatchSize {
+		return s.cfg.MaxBatchSize
+	}
+	return size
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/flagext"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchSizer(t *testing.T) {
+	u, err := url.Parse("http://localhost:3100/loki/api/v1/push")
+	require.NoError(t, err)
+
+	cfg := Config{
+		URL:       flagext.URLValue{URL: u},
+		BatchSize: 1000,
+		AdaptiveBatching: AdaptiveBatchConfig{
+			Enabled:       true,
+			MinBatchSize:  500,
+			MaxBatchSize:  2000,
+			TargetLatency: time.Second,
+		},
+	}
+	s := newBatchSizer(NewMetrics(nil), cfg)
+	require.Equal(t, 1000, s.Size())
+
+	// Fast requests grow the batch size up to the maximum.
+	s.observe(100*time.Millisecond, 204, nil)
+	require.Equal(t, 1250, s.Size())
+	for i := 0; i < 10; i++ {
+		s.observe(100*time.Millisecond, 204, nil)
+	}
+	require.Equal(t, 2000, s.Size())
+
+	// Slow requests shrink the batch size.
+	s.observe(2*time.Second, 204, nil)
+	require.Equal(t, 1500, s.Size())
+
+	// Requests close to the target latency and failed requests keep it.
+	s.observe(750*time.Millisecond, 204, nil)
+	s.observe(100*time.Millisecond, 500, errors.New("server error"))
+	require.Equal(t, 1500, s.Size())
+
+	// Rate limited requests halve it, down to the minimum.
+	s.observe(100*time.Millisecond, 429, errors.New("rate limited"))
+	require.Equal(t, 750, s.Size())
+	s.observe(100*time.Millisecond, 429, errors.New("rate limited"))
+	require.Equal(t, 500, s.Size())
+
+	// Without adaptive batching the batch size never changes.
+	cfg.AdaptiveBatching.Enabled = false
+	s = newBatchSizer(NewMetrics(nil), cfg)
+	s.observe(100*time.Millisecond, 429, errors.New("rate limited"))
+	require.Equal(t, 1000, s.Size())
+}
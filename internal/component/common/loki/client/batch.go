@@ -39,6 +39,10 @@ type batch struct {
 
 	// segmentCounter tracks the amount of entries for each segment present in this batch.
 	segmentCounter map[int]int
+
+	// tokens holds the delivery tokens of the entries in this batch, which are
+	// released once the batch is acknowledged.
+	tokens []*loki.DeliveryToken
 }
 
 func newBatch(maxStreams int, entries ...loki.Entry) *batch {
@@ -67,6 +71,7 @@ func (b *batch) add(entry loki.Entry) error {
 	labels := labelsMapToString(entry.Labels, ReservedLabelTenantID)
 	if stream, ok := b.streams[labels]; ok {
 		stream.Entries = append(stream.Entries, entry.Entry)
+		b.addToken(entry.Token)
 		return nil
 	}
 
@@ -79,9 +84,26 @@ func (b *batch) add(entry loki.Entry) error {
 		Labels:  labels,
 		Entries: []logproto.Entry{entry.Entry},
 	}
+	b.addToken(entry.Token)
 	return nil
 }
 
+func (b *batch) addToken(t *loki.DeliveryToken) {
+	if t != nil {
+		b.tokens = append(b.tokens, t)
+	}
+}
+
+// ack releases the delivery tokens of all entries in the batch. It's called
+// once the batch has either been accepted by the endpoint or permanently
+// dropped.
+func (b *batch) ack() {
+	for _, t := range b.tokens {
+		t.Done()
+	}
+	b.tokens = nil
+}
+
 // addFromWAL adds an entry to the batch, tracking that the data being added comes from segment segmentNum read from the
 // WAL.
 func (b *batch) addFromWAL(lbs model.LabelSet, entry logproto.Entry, segmentNum int) error {
@@ -20,7 +20,9 @@ import (
 	"github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 
+	"github.com/grafana/agent/internal/component/common/dlq"
 	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/common/slo"
 	"github.com/grafana/agent/internal/util"
 	lokiutil "github.com/grafana/loki/pkg/util"
 )
@@ -59,9 +61,18 @@ type Metrics struct {
 	mutatedBytes                 *prometheus.CounterVec
 	requestDuration              *prometheus.HistogramVec
 	batchRetries                 *prometheus.CounterVec
+	batchSizeBytes               *prometheus.GaugeVec
+	batchWaitSeconds             *prometheus.GaugeVec
 	countersWithHost             []*prometheus.CounterVec
 	countersWithHostTenant       []*prometheus.CounterVec
 	countersWithHostTenantReason []*prometheus.CounterVec
+
+	// SLO records the outcome of every send request when non-nil.
+	SLO *slo.Tracker
+
+	// DeadLetters stores batches rejected with a permanent error when
+	// non-nil.
+	DeadLetters *dlq.Queue
 }
 
 func NewMetrics(reg prometheus.Registerer) *Metrics {
@@ -103,6 +114,14 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 		Name: "loki_write_batch_retries_total",
 		Help: "Number of times batches has had to be retried.",
 	}, []string{HostLabel, TenantLabel})
+	m.batchSizeBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loki_write_batch_size_bytes",
+		Help: "Size in bytes at which batches are currently sent.",
+	}, []string{HostLabel})
+	m.batchWaitSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loki_write_batch_wait_seconds",
+		Help: "Maximum amount of time batches currently wait before being sent.",
+	}, []string{HostLabel})
 
 	m.countersWithHost = []*prometheus.CounterVec{
 		m.encodedBytes, m.sentBytes, m.sentEntries,
@@ -126,6 +145,8 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 		m.mutatedBytes = util.MustRegisterOrGet(reg, m.mutatedBytes).(*prometheus.CounterVec)
 		m.requestDuration = util.MustRegisterOrGet(reg, m.requestDuration).(*prometheus.HistogramVec)
 		m.batchRetries = util.MustRegisterOrGet(reg, m.batchRetries).(*prometheus.CounterVec)
+		m.batchSizeBytes = util.MustRegisterOrGet(reg, m.batchSizeBytes).(*prometheus.GaugeVec)
+		m.batchWaitSeconds = util.MustRegisterOrGet(reg, m.batchWaitSeconds).(*prometheus.GaugeVec)
 	}
 
 	return &m
@@ -147,6 +168,7 @@ type client struct {
 	cfg     Config
 	client  *http.Client
 	entries chan loki.Entry
+	sizer   *batchSizer
 
 	once sync.Once
 	wg   sync.WaitGroup
@@ -208,6 +230,7 @@ func newClient(metrics *Metrics, cfg Config, maxStreams, maxLineSize int, maxLin
 	}
 
 	c.client.Timeout = cfg.Timeout
+	c.sizer = newBatchSizer(metrics, cfg)
 
 	// Initialize counters to 0 so the metrics are exported before the first
 	// occurrence of incrementing to avoid missing metrics.
@@ -289,6 +312,7 @@ func (c *client) run() {
 				if !c.maxLineSizeTruncate {
 					c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonLineTooLong).Inc()
 					c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonLineTooLong).Add(float64(len(e.Line)))
+					e.Token.Done()
 					break
 				}
 
@@ -308,7 +332,7 @@ func (c *client) run() {
 
 			// If adding the entry to the batch will increase the size over the max
 			// size allowed, we do send the current batch and then create a new one
-			if batch.sizeBytesAfter(e.Entry) > c.cfg.BatchSize {
+			if batch.sizeBytesAfter(e.Entry) > c.sizer.Size() {
 				c.sendBatch(tenantID, batch)
 
 				batches[tenantID] = newBatch(c.maxStreams, e)
@@ -325,6 +349,7 @@ func (c *client) run() {
 				}
 				c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(float64(len(e.Line)))
 				c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Inc()
+				e.Token.Done()
 				return
 			}
 		case <-maxWaitCheck.C:
@@ -350,6 +375,14 @@ func batchIsRateLimited(status int) bool {
 }
 
 func (c *client) sendBatch(tenantID string, batch *batch) {
+	defer func() {
+		// Entries of batches which couldn't be sent because the client was
+		// stopped aren't acknowledged, so that their sources read them again.
+		if c.ctx.Err() == nil {
+			batch.ack()
+		}
+	}()
+
 	buf, entriesCount, err := batch.encode()
 	if err != nil {
 		level.Error(c.logger).Log("msg", "error encoding batch", "error", err)
@@ -364,8 +397,12 @@ func (c *client) sendBatch(tenantID string, batch *batch) {
 		start := time.Now()
 		// send uses `timeout` internally, so `context.Background` is good enough.
 		status, err = c.send(context.Background(), tenantID, buf)
+		c.sizer.observe(time.Since(start), status, err)
 
 		c.metrics.requestDuration.WithLabelValues(strconv.Itoa(status), c.cfg.URL.Host).Observe(time.Since(start).Seconds())
+		if c.metrics.SLO != nil {
+			c.metrics.SLO.Observe(c.cfg.URL.Redacted(), time.Since(start), err == nil)
+		}
 
 		// Immediately drop rate limited batches to avoid HOL blocking for other tenants not experiencing throttling
 		if c.cfg.DropRateLimitedBatches && batchIsRateLimited(status) {
@@ -399,6 +436,7 @@ func (c *client) sendBatch(tenantID string, batch *batch) {
 
 	if err != nil {
 		level.Error(c.logger).Log("msg", "final error sending batch", "status", status, "tenant", tenantID, "error", err)
+		storeDeadLetter(c.logger, c.metrics, c.cfg, tenantID, status, err, buf, entriesCount)
 		// If the reason for the last retry error was rate limiting, count the drops as such, even if the previous errors
 		// were for a different reason
 		dropReason := ReasonGeneric
@@ -47,6 +47,9 @@ type Config struct {
 
 	// Queue controls configuration parameters specific to the queue client
 	Queue QueueConfig
+
+	// AdaptiveBatching controls whether the batch size is adjusted at runtime.
+	AdaptiveBatching AdaptiveBatchConfig
 }
 
 // QueueConfig holds configurations for the queue-based remote-write client.
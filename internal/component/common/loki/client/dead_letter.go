@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component/common/dlq"
+	"github.com/grafana/agent/internal/flow/logging/level"
+)
+
+// isPermanentError reports whether a batch which failed to be sent with the
+// given status will never be accepted by retrying it. Batches which failed
+// with connection-level errors, 429s, or 5xxs aren't permanent errors.
+func isPermanentError(status int) bool {
+	return status/100 == 4 && !batchIsRateLimited(status)
+}
+
+// storeDeadLetter stores a batch which failed to be sent in the dead-letter
+// queue of m, if the batch was rejected with a permanent error.
+func storeDeadLetter(logger log.Logger, m *Metrics, cfg Config, tenantID string, status int, sendErr error, buf []byte, entries int) {
+	if m.DeadLetters == nil || !isPermanentError(status) {
+		return
+	}
+
+	stored, err := m.DeadLetters.Add(dlq.Record{
+		Endpoint: GetClientName(cfg),
+		Tenant:   tenantID,
+		Status:   status,
+		Error:    sendErr.Error(),
+		Entries:  entries,
+		Payload:  buf,
+	})
+	switch {
+	case err != nil:
+		level.Error(logger).Log("msg", "failed to store batch in dead-letter queue", "tenant", tenantID, "error", err)
+	case stored:
+		level.Info(logger).Log("msg", "stored batch in dead-letter queue", "tenant", tenantID, "entries", entries)
+	}
+}
+
+// Redrive sends the payload of a dead-letter record to the endpoint it was
+// rejected by again.
+func (m *Manager) Redrive(ctx context.Context, rec dlq.Record) error {
+	for _, pair := range m.pairs {
+		if pair.name != rec.Endpoint {
+			continue
+		}
+		s, ok := pair.client.(interface {
+			send(ctx context.Context, tenantID string, buf []byte) (int, error)
+		})
+		if !ok {
+			break
+		}
+		if _, err := s.send(ctx, rec.Tenant, rec.Payload); err != nil {
+			return fmt.Errorf("redriving record %d to %s: %w", rec.ID, rec.Endpoint, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("redriving record %d: endpoint %s isn't configured", rec.ID, rec.Endpoint)
+}
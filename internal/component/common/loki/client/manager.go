@@ -47,6 +47,7 @@ type StoppableClient interface {
 
 // watcherClientPair represents a pair of watcher and client, which are coupled together, or just a single client.
 type watcherClientPair struct {
+	name    string
 	watcher StoppableWatcher
 	client  StoppableClient
 }
@@ -135,6 +136,7 @@ func NewManager(metrics *Metrics, logger log.Logger, limits limit.Config, reg pr
 			watcher.Start()
 
 			pairs = append(pairs, watcherClientPair{
+				name:    clientName,
 				watcher: watcher,
 				client:  queue,
 			})
@@ -147,6 +149,7 @@ func NewManager(metrics *Metrics, logger log.Logger, limits limit.Config, reg pr
 			clients = append(clients, client)
 
 			pairs = append(pairs, watcherClientPair{
+				name:   clientName,
 				client: client,
 			})
 		}
@@ -177,7 +180,8 @@ func (m *Manager) startWithConsume() {
 		defer m.wg.Done()
 		// discard read entries
 		//nolint:revive
-		for range m.entries {
+		for e := range m.entries {
+			e.Token.Done()
 		}
 	}()
 }
@@ -189,6 +193,11 @@ func (m *Manager) startWithForward() {
 	go func() {
 		defer m.wg.Done()
 		for e := range m.entries {
+			if len(m.clients) == 0 {
+				e.Token.Done()
+				continue
+			}
+			e.Token.Fork(len(m.clients) - 1)
 			for _, c := range m.clients {
 				c.Chan() <- e
 			}
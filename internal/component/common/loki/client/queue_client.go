@@ -155,6 +155,7 @@ type queueClient struct {
 	logger    log.Logger
 	cfg       Config
 	client    *http.Client
+	sizer     *batchSizer
 
 	batches      map[string]*batch
 	batchesMtx   sync.Mutex
@@ -216,7 +217,7 @@ func newQueueClient(metrics *Metrics, qcMetrics *QueueClientMetrics, cfg Config,
 
 	// The buffered channel size is calculated using the configured capacity, which is the worst case number of bytes
 	// the send queue can consume.
-	var queueBufferSize = cfg.Queue.Capacity / cfg.BatchSize
+	var queueBufferSize = cfg.Queue.Capacity / cfg.maxBatchSize()
 	c.sendQueue = newQueue(c, queueBufferSize, logger)
 
 	err := cfg.Client.Validate()
@@ -230,6 +231,7 @@ func newQueueClient(metrics *Metrics, qcMetrics *QueueClientMetrics, cfg Config,
 	}
 
 	c.client.Timeout = cfg.Timeout
+	c.sizer = newBatchSizer(metrics, cfg)
 
 	// Initialize counters to 0 so the metrics are exported before the first
 	// occurrence of incrementing to avoid missing metrics.
@@ -341,7 +343,7 @@ func (c *queueClient) appendSingleEntry(segmentNum int, lbs model.LabelSet, e lo
 
 	// If adding the entry to the batch will increase the size over the max
 	// size allowed, we do send the current batch and then create a new one
-	if batch.sizeBytesAfter(e) > c.cfg.BatchSize {
+	if batch.sizeBytesAfter(e) > c.sizer.Size() {
 		c.sendQueue.enqueue(queuedBatch{
 			TenantID: tenantID,
 			Batch:    batch,
@@ -462,8 +464,12 @@ func (c *queueClient) sendBatch(ctx context.Context, tenantID string, batch *bat
 		start := time.Now()
 		// send uses `timeout` internally, so `context.Background` is good enough.
 		status, err = c.send(ctx, tenantID, buf)
+		c.sizer.observe(time.Since(start), status, err)
 
 		c.metrics.requestDuration.WithLabelValues(strconv.Itoa(status), c.cfg.URL.Host).Observe(time.Since(start).Seconds())
+		if c.metrics.SLO != nil {
+			c.metrics.SLO.Observe(c.cfg.URL.Redacted(), time.Since(start), err == nil)
+		}
 
 		// Immediately drop rate limited batches to avoid HOL blocking for other tenants not experiencing throttling
 		if c.cfg.DropRateLimitedBatches && batchIsRateLimited(status) {
@@ -497,6 +503,7 @@ func (c *queueClient) sendBatch(ctx context.Context, tenantID string, batch *bat
 
 	if err != nil {
 		level.Error(c.logger).Log("msg", "final error sending batch", "status", status, "tenant", tenantID, "error", err)
+		storeDeadLetter(c.logger, c.metrics, c.cfg, tenantID, status, err, buf, entriesCount)
 		// If the reason for the last retry error was rate limiting, count the drops as such, even if the previous errors
 		// were for a different reason
 		dropReason := ReasonGeneric
@@ -0,0 +1,108 @@
+package loki
+
+import "go.uber.org/atomic"
+
+// DeliveryToken tracks the delivery of a log entry through a pipeline of
+// components. A source which wants to know when an entry has been delivered
+// attaches a token to the entry; the token invokes a callback once every
+// holder of the entry has released it.
+//
+// Components which forward an entry to more than one receiver must call Fork
+// before doing so, and components which drop an entry must call Done. loki.write
+// calls Done once the batch holding the entry has been accepted by the
+// endpoint, written to the WAL, or permanently dropped.
+//
+// Components which fail to hand an entry over, for example because they're
+// shutting down or the entry couldn't be sent, must call Fail instead of Done.
+// The source then never considers the entry delivered, so that it's read
+// again after a restart.
+//
+// All methods of DeliveryToken are safe to call on a nil token, so
+// components don't need to check whether an entry carries one.
+type DeliveryToken struct {
+	pending     *atomic.Int64
+	failed      *atomic.Bool
+	onDelivered func()
+	onFailed    func()
+}
+
+// NewDeliveryToken returns a new token with a single holder. onDelivered is
+// invoked once all holders released the token, unless any of them failed it.
+func NewDeliveryToken(onDelivered func()) *DeliveryToken {
+	return newDeliveryToken(onDelivered, nil)
+}
+
+func newDeliveryToken(onDelivered, onFailed func()) *DeliveryToken {
+	return &DeliveryToken{
+		pending:     atomic.NewInt64(1),
+		failed:      atomic.NewBool(false),
+		onDelivered: onDelivered,
+		onFailed:    onFailed,
+	}
+}
+
+// JoinDeliveryTokens returns a token which releases every token in tokens
+// once it's released. It's used when several entries are merged into one.
+// JoinDeliveryTokens returns nil if none of tokens are non-nil.
+func JoinDeliveryTokens(tokens ...*DeliveryToken) *DeliveryToken {
+	var joined []*DeliveryToken
+	for _, t := range tokens {
+		if t != nil {
+			joined = append(joined, t)
+		}
+	}
+	switch len(joined) {
+	case 0:
+		return nil
+	case 1:
+		return joined[0]
+	}
+
+	return newDeliveryToken(func() {
+		for _, t := range joined {
+			t.Done()
+		}
+	}, func() {
+		for _, t := range joined {
+			t.Fail()
+		}
+	})
+}
+
+// Fork adds n holders to the token. It must be called by an existing holder
+// before passing the entry to n additional receivers.
+func (t *DeliveryToken) Fork(n int) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.pending.Add(int64(n))
+}
+
+// Done releases one holder of the token.
+func (t *DeliveryToken) Done() {
+	if t == nil {
+		return
+	}
+	if t.pending.Dec() != 0 {
+		return
+	}
+	switch {
+	case t.failed.Load():
+		if t.onFailed != nil {
+			t.onFailed()
+		}
+	case t.onDelivered != nil:
+		t.onDelivered()
+	}
+}
+
+// Fail releases one holder of the token and marks the entry as not
+// delivered, so that the callback passed to NewDeliveryToken is never
+// invoked.
+func (t *DeliveryToken) Fail() {
+	if t == nil {
+		return
+	}
+	t.failed.Store(true)
+	t.Done()
+}
@@ -0,0 +1,102 @@
+package loki
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryToken(t *testing.T) {
+	var delivered int
+	token := NewDeliveryToken(func() { delivered++ })
+
+	token.Fork(2)
+	token.Done()
+	token.Done()
+	require.Equal(t, 0, delivered)
+	token.Done()
+	require.Equal(t, 1, delivered)
+
+	// Calls on a nil token are no-ops.
+	var nilToken *DeliveryToken
+	nilToken.Fork(1)
+	nilToken.Done()
+}
+
+func TestDeliveryToken_Fail(t *testing.T) {
+	var delivered bool
+	token := NewDeliveryToken(func() { delivered = true })
+
+	// A failed token is never delivered, even once every holder released it.
+	token.Fork(1)
+	token.Fail()
+	token.Done()
+	require.False(t, delivered)
+	require.Zero(t, token.pending.Load())
+
+	// Failing a joined token fails every token it joined.
+	var joinedDelivered int
+	a := NewDeliveryToken(func() { joinedDelivered++ })
+	b := NewDeliveryToken(func() { joinedDelivered++ })
+	JoinDeliveryTokens(a, b).Fail()
+	require.Zero(t, joinedDelivered)
+	require.True(t, a.failed.Load())
+	require.True(t, b.failed.Load())
+}
+
+func TestJoinDeliveryTokens(t *testing.T) {
+	var delivered int
+	a := NewDeliveryToken(func() { delivered++ })
+	b := NewDeliveryToken(func() { delivered++ })
+
+	require.Nil(t, JoinDeliveryTokens(nil, nil))
+	require.Same(t, a, JoinDeliveryTokens(nil, a))
+
+	JoinDeliveryTokens(a, nil, b).Done()
+	require.Equal(t, 2, delivered)
+}
+
+func TestSendTo_Delivery(t *testing.T) {
+	var delivered bool
+	token := NewDeliveryToken(func() { delivered = true })
+
+	// Every receiver must release the token.
+	out1, out2 := NewLogsReceiver(), NewLogsReceiver()
+	go func() {
+		_ = SendTo(context.Background(), Entry{Token: token}, []LogsReceiver{out1, out2})
+	}()
+	(<-out1.Chan()).Token.Done()
+	require.False(t, delivered)
+	(<-out2.Chan()).Token.Done()
+	require.True(t, delivered)
+
+	// Entries sent nowhere are delivered immediately.
+	delivered = false
+	token = NewDeliveryToken(func() { delivered = true })
+	require.NoError(t, SendTo(context.Background(), Entry{Token: token}, nil))
+	require.True(t, delivered)
+}
+
+func TestSendTo_Canceled(t *testing.T) {
+	var delivered bool
+	token := NewDeliveryToken(func() { delivered = true })
+
+	// Only the first receiver gets the entry before ctx is canceled.
+	out1, out2 := NewLogsReceiver(), NewLogsReceiver()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- SendTo(ctx, Entry{Token: token}, []LogsReceiver{out1, out2})
+	}()
+	entry := <-out1.Chan()
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	// The holder of the second receiver was released as failed, so the entry
+	// isn't delivered once the first receiver releases it.
+	require.Equal(t, int64(1), token.pending.Load())
+	entry.Token.Done()
+	require.Zero(t, token.pending.Load())
+	require.False(t, delivered)
+}
@@ -0,0 +1,69 @@
+package loki
+
+import (
+	"context"
+	"sync"
+)
+
+// Fanout sends log entries to a set of LogsReceivers. Sending blocks until
+// every receiver has accepted the entry, which propagates backpressure from
+// slow receivers to the sender.
+type Fanout struct {
+	mut      sync.RWMutex
+	children []LogsReceiver
+}
+
+// NewFanout creates a new Fanout which sends entries to children.
+func NewFanout(children []LogsReceiver) *Fanout {
+	return &Fanout{children: children}
+}
+
+// UpdateChildren changes the receivers entries are sent to.
+func (f *Fanout) UpdateChildren(children []LogsReceiver) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.children = children
+}
+
+// Send sends e to every receiver of f in order. When there's more than one
+// receiver, each one is given its own copy of e so that receivers can
+// safely mutate it. Send returns ctx.Err() if ctx is canceled before every
+// receiver accepted e.
+func (f *Fanout) Send(ctx context.Context, e Entry) error {
+	f.mut.RLock()
+	children := f.children
+	f.mut.RUnlock()
+
+	return SendTo(ctx, e, children)
+}
+
+// SendTo sends e to every receiver in children, like [Fanout.Send]. The
+// delivery token of e is forked or released as needed, and failed for the
+// receivers which didn't get e if ctx is canceled.
+func SendTo(ctx context.Context, e Entry, children []LogsReceiver) error {
+	if len(children) == 0 {
+		// There's nowhere to deliver the entry to.
+		e.Token.Done()
+		return nil
+	}
+	e.Token.Fork(len(children) - 1)
+
+	for i, child := range children {
+		entry := e
+		if len(children) > 1 && i < len(children)-1 {
+			entry = e.Clone()
+		}
+
+		select {
+		case <-ctx.Done():
+			// The receivers which didn't get the entry fail its delivery token,
+			// so that its source doesn't consider it delivered.
+			for range children[i:] {
+				e.Token.Fail()
+			}
+			return ctx.Err()
+		case child.Chan() <- entry:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package loki
+
+import (
+	"context"
+
+	"github.com/grafana/loki/pkg/logproto"
+)
+
+// EntryHook is called by an Interceptor for every received log entry. It
+// returns the entry to forward, which may be modified, and whether it should
+// be forwarded at all.
+type EntryHook func(e Entry) (Entry, bool)
+
+// Interceptor exposes a LogsReceiver and forwards the log entries it
+// receives to a set of LogsReceivers after passing them through hooks. It
+// implements the receive loop, fan-out, and backpressure shared by Loki
+// processing components, so that those components only need to provide
+// hooks.
+//
+// Hooks are called in the order they were provided, from a single goroutine.
+// Interceptor should not be modified once created, other than through
+// UpdateForwardTo.
+type Interceptor struct {
+	receiver LogsReceiver
+	fanout   *Fanout
+	hooks    []EntryHook
+}
+
+// NewInterceptor creates a new Interceptor which forwards entries to
+// forwardTo. Options can be provided to install hooks.
+func NewInterceptor(forwardTo []LogsReceiver, opts ...InterceptorOption) *Interceptor {
+	i := &Interceptor{
+		receiver: NewLogsReceiver(),
+		fanout:   NewFanout(forwardTo),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// InterceptorOption is an option argument passed to NewInterceptor.
+type InterceptorOption func(*Interceptor)
+
+// WithEntryHook returns an InterceptorOption which installs a hook that can
+// mutate or drop entries.
+func WithEntryHook(f EntryHook) InterceptorOption {
+	return func(i *Interceptor) {
+		i.hooks = append(i.hooks, f)
+	}
+}
+
+// WithMutateHook returns an InterceptorOption which installs a hook that
+// mutates every entry.
+func WithMutateHook(f EntryMutatorFunc) InterceptorOption {
+	return WithEntryHook(func(e Entry) (Entry, bool) {
+		return f(e), true
+	})
+}
+
+// WithDropHook returns an InterceptorOption which installs a hook that drops
+// every entry for which f returns true.
+func WithDropHook(f func(e Entry) bool) InterceptorOption {
+	return WithEntryHook(func(e Entry) (Entry, bool) {
+		return e, !f(e)
+	})
+}
+
+// WithMetadataHook returns an InterceptorOption which installs a hook that
+// appends the structured metadata returned by f to every entry.
+func WithMetadataHook(f func(e Entry) []logproto.LabelAdapter) InterceptorOption {
+	return WithEntryHook(func(e Entry) (Entry, bool) {
+		if md := f(e); len(md) > 0 {
+			// Copy the existing metadata so the entry received by the
+			// Interceptor isn't modified.
+			merged := make([]logproto.LabelAdapter, 0, len(e.StructuredMetadata)+len(md))
+			merged = append(merged, e.StructuredMetadata...)
+			e.StructuredMetadata = append(merged, md...)
+		}
+		return e, true
+	})
+}
+
+// Receiver returns the LogsReceiver entries are received from. The receiver
+// remains the same for the lifetime of the Interceptor.
+func (i *Interceptor) Receiver() LogsReceiver {
+	return i.receiver
+}
+
+// UpdateForwardTo changes the receivers entries are forwarded to.
+func (i *Interceptor) UpdateForwardTo(forwardTo []LogsReceiver) {
+	i.fanout.UpdateChildren(forwardTo)
+}
+
+// Run receives entries until ctx is canceled.
+func (i *Interceptor) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-i.receiver.Chan():
+			entry, ok := i.intercept(entry)
+			if !ok {
+				entry.Token.Done()
+				continue
+			}
+			if err := i.fanout.Send(ctx, entry); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func (i *Interceptor) intercept(e Entry) (Entry, bool) {
+	for _, hook := range i.hooks {
+		var ok bool
+		if e, ok = hook(e); !ok {
+			return e, false
+		}
+	}
+	return e, true
+}
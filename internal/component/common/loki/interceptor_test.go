@@ -0,0 +1,75 @@
+package loki
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptor(t *testing.T) {
+	var (
+		out1 = NewLogsReceiver()
+		out2 = NewLogsReceiver()
+	)
+
+	interceptor := NewInterceptor([]LogsReceiver{out1},
+		WithDropHook(func(e Entry) bool {
+			return e.Labels["drop"] == "true"
+		}),
+		WithMutateHook(func(e Entry) Entry {
+			e.Labels = e.Labels.Merge(model.LabelSet{"mutated": "true"})
+			return e
+		}),
+		WithMetadataHook(func(e Entry) []logproto.LabelAdapter {
+			return []logproto.LabelAdapter{{Name: "source", Value: "test"}}
+		}),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = interceptor.Run(ctx) }()
+
+	send := func(lbls model.LabelSet) {
+		select {
+		case interceptor.Receiver().Chan() <- Entry{Labels: lbls, Entry: logproto.Entry{Line: "hello"}}:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "failed to send entry")
+		}
+	}
+	receive := func(r LogsReceiver) Entry {
+		select {
+		case e := <-r.Chan():
+			return e
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "failed to receive entry")
+		}
+		return Entry{}
+	}
+
+	send(model.LabelSet{"drop": "true"})
+	send(model.LabelSet{"job": "a"})
+
+	e := receive(out1)
+	require.Equal(t, model.LabelSet{"job": "a", "mutated": "true"}, e.Labels)
+	require.Equal(t, []logproto.LabelAdapter{{Name: "source", Value: "test"}}, []logproto.LabelAdapter(e.StructuredMetadata))
+
+	// Entries are sent to every receiver after updating.
+	interceptor.UpdateForwardTo([]LogsReceiver{out1, out2})
+	send(model.LabelSet{"job": "b"})
+	require.Equal(t, model.LabelValue("b"), receive(out1).Labels["job"])
+	require.Equal(t, model.LabelValue("b"), receive(out2).Labels["job"])
+}
+
+func TestFanout_Backpressure(t *testing.T) {
+	fanout := NewFanout([]LogsReceiver{NewLogsReceiver()})
+
+	// Nobody reads from the receiver, so Send blocks until the context is
+	// canceled.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, fanout.Send(ctx, Entry{}), context.DeadlineExceeded)
+}
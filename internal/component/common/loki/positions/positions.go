@@ -68,6 +68,12 @@ type Entry struct {
 	Labels string `yaml:"labels"`
 }
 
+// IsCursor returns true if the entry holds a cursor, such as a journal cursor,
+// rather than the offset of a file on disk.
+func (e Entry) IsCursor() bool {
+	return strings.HasPrefix(e.Path, cursorKeyPrefix) || strings.HasPrefix(e.Path, journalKeyPrefix)
+}
+
 // File format for the positions data.
 type File struct {
 	Positions map[Entry]string `yaml:"positions"`
@@ -267,7 +273,7 @@ func (p *positions) cleanup() {
 		// If the position file is prefixed with cursor, it's a
 		// cursor and not a file on disk.
 		// We still have to support journal files, so we keep the previous check to avoid breaking change.
-		if strings.HasPrefix(k.Path, cursorKeyPrefix) || strings.HasPrefix(k.Path, journalKeyPrefix) {
+		if k.IsCursor() {
 			continue
 		}
 
@@ -287,6 +293,19 @@ func (p *positions) cleanup() {
 	}
 }
 
+// ReadFile reads the positions stored in the positions file at path. An
+// empty map is returned if the file doesn't exist.
+func ReadFile(path string) (map[Entry]string, error) {
+	return readPositionsFile(Config{PositionsFile: path}, log.NewNopLogger())
+}
+
+// WriteFile atomically replaces the positions file at path with positions.
+// The positions file must not be used by a running component, which would
+// overwrite it.
+func WriteFile(path string, positions map[Entry]string) error {
+	return writePositionFile(path, positions)
+}
+
 func readPositionsFile(cfg Config, logger log.Logger) (map[Entry]string, error) {
 	cleanfn := filepath.Clean(cfg.PositionsFile)
 	buf, err := os.ReadFile(cleanfn)
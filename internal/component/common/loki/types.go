@@ -51,13 +51,19 @@ func NewLogsReceiverWithChannel(c chan Entry) LogsReceiver {
 type Entry struct {
 	Labels model.LabelSet
 	logproto.Entry
+
+	// Token tracks the delivery of the entry when its source waits for
+	// delivery before advancing its position. May be nil.
+	Token *DeliveryToken
 }
 
-// Clone returns a copy of the entry so that it can be safely fanned out.
+// Clone returns a copy of the entry so that it can be safely fanned out. The
+// copy shares the delivery token of e; callers must Fork the token.
 func (e *Entry) Clone() Entry {
 	return Entry{
 		Labels: e.Labels.Clone(),
 		Entry:  e.Entry,
+		Token:  e.Token,
 	}
 }
 
@@ -31,6 +31,10 @@ type Config struct {
 	// Note that this functionality will likely be deprecated in favour of a programmatic cleanup mechanism.
 	MaxSegmentAge time.Duration
 
+	// MaxSize is the maximum size in bytes of the WAL on disk. When exceeded, the oldest segments are removed even if
+	// they're not old enough yet, dropping the data they hold. Zero means no limit.
+	MaxSize int64
+
 	// WatchConfig configures the backoff retry used by a WAL watcher when reading from segments not via
 	// the notification channel.
 	WatchConfig WatchConfig
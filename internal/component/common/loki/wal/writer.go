@@ -24,6 +24,7 @@ import (
 
 const (
 	minimumCleanSegmentsEvery = time.Second
+	maximumCheckSizeEvery     = 10 * time.Second
 )
 
 // CleanupEventSubscriber is an interface that objects that want to receive events from the wal Writer can implement. After
@@ -58,6 +59,7 @@ type Writer struct {
 	writeSubscribers     []WriteEventSubscriber
 
 	reclaimedOldSegmentsSpaceCounter *prometheus.CounterVec
+	truncatedSegmentsCounter         *prometheus.CounterVec
 	lastReclaimedSegment             *prometheus.GaugeVec
 	lastWrittenTimestamp             *prometheus.GaugeVec
 
@@ -91,6 +93,13 @@ func NewWriter(walCfg Config, logger log.Logger, reg prometheus.Registerer) (*Wr
 		Help:      "Number of bytes reclaimed from storage.",
 	}, []string{})
 
+	wrt.truncatedSegmentsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "loki_write",
+		Subsystem: "wal_writer",
+		Name:      "truncated_segments_total",
+		Help:      "Number of segments removed before reaching their max age because the WAL exceeded its max size.",
+	}, []string{})
+
 	wrt.lastReclaimedSegment = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "loki_write",
 		Subsystem: "wal_writer",
@@ -106,21 +115,26 @@ func NewWriter(walCfg Config, logger log.Logger, reg prometheus.Registerer) (*Wr
 
 	if reg != nil {
 		_ = reg.Register(wrt.reclaimedOldSegmentsSpaceCounter)
+		_ = reg.Register(wrt.truncatedSegmentsCounter)
 		_ = reg.Register(wrt.lastReclaimedSegment)
 		_ = reg.Register(wrt.lastWrittenTimestamp)
 	}
 
-	wrt.start(walCfg.MaxSegmentAge)
+	wrt.start(walCfg.MaxSegmentAge, walCfg.MaxSize)
 	return wrt, nil
 }
 
-func (wrt *Writer) start(maxSegmentAge time.Duration) {
+func (wrt *Writer) start(maxSegmentAge time.Duration, maxSize int64) {
 	wrt.wg.Add(1)
 	// main WAL writer routine
 	go func() {
 		defer wrt.wg.Done()
 		for e := range wrt.entries {
-			if err := wrt.entryWriter.WriteEntry(e, wrt.wal, wrt.log); err != nil {
+			err := wrt.entryWriter.WriteEntry(e, wrt.wal, wrt.log)
+			// Entries are delivered once they're in the WAL. Entries which failed
+			// to be written are dropped.
+			e.Token.Done()
+			if err != nil {
 				level.Error(wrt.log).Log("msg", "failed to write entry", "err", err)
 				// if an error occurred while writing the wal, go to next entry and don't notify write subscribers
 				continue
@@ -143,6 +157,10 @@ func (wrt *Writer) start(maxSegmentAge time.Duration) {
 		// By cleaning every 10th of the configured threshold for considering a segment old, we are allowing a maximum slip
 		// of 10%. If the configured time is 1 hour, that'd be 6 minutes.
 		triggerEvery := maxSegmentAge / 10
+		if maxSize > 0 && triggerEvery > maximumCheckSizeEvery {
+			// the size of the wal can grow quickly, so check it more often than the age of segments
+			triggerEvery = maximumCheckSizeEvery
+		}
 		if triggerEvery < minimumCleanSegmentsEvery {
 			triggerEvery = minimumCleanSegmentsEvery
 		}
@@ -151,7 +169,7 @@ func (wrt *Writer) start(maxSegmentAge time.Duration) {
 			select {
 			case <-trigger.C:
 				level.Debug(wrt.log).Log("msg", "Running wal old segments cleanup")
-				if err := wrt.cleanSegments(maxSegmentAge); err != nil {
+				if err := wrt.cleanSegments(maxSegmentAge, maxSize); err != nil {
 					level.Error(wrt.log).Log("msg", "Error cleaning old segments", "err", err)
 				}
 			case <-wrt.closeCleaner:
@@ -181,8 +199,9 @@ func (wrt *Writer) Stop() {
 // cleanSegments will remove segments older than maxAge from the WAL directory. If there's just one segment, none will be
 // deleted since it's likely there's active readers on it. In case there's multiple segments, each will be deleted if:
 // - It's not the last (highest numbered) segment
-// - It's last modified date is older than the max allowed age
-func (wrt *Writer) cleanSegments(maxAge time.Duration) error {
+// - It's last modified date is older than the max allowed age, or the total size of the WAL exceeds maxSize, in which
+// case the oldest segments are deleted first. A maxSize of zero disables the size limit.
+func (wrt *Writer) cleanSegments(maxAge time.Duration, maxSize int64) error {
 	maxModifiedAt := time.Now().Add(-maxAge)
 	walDir := wrt.wal.Dir()
 	segments, err := listSegments(walDir)
@@ -196,14 +215,27 @@ func (wrt *Writer) cleanSegments(maxAge time.Duration) error {
 	// find the most recent, or head segment to avoid cleaning it up
 	lastSegment := -1
 	maxReclaimed := -1
+	var totalSize int64
 	for _, segment := range segments {
 		if lastSegment < segment.number {
 			lastSegment = segment.number
 		}
+		totalSize += segment.size
 	}
+	// segments are sorted by number, hence the oldest are visited first
 	for _, segment := range segments {
-		if segment.lastModified.Before(maxModifiedAt) && segment.number != lastSegment {
-			// segment is older than allowed age, cleaning up
+		if segment.number == lastSegment {
+			continue
+		}
+		tooOld := segment.lastModified.Before(maxModifiedAt)
+		tooLarge := maxSize > 0 && totalSize > maxSize
+		if tooOld || tooLarge {
+			if !tooOld {
+				level.Warn(wrt.log).Log("msg", "Deleting wal segment because the wal exceeds its max size", "segmentNum", segment.number, "size", totalSize, "maxSize", maxSize)
+				wrt.truncatedSegmentsCounter.WithLabelValues().Inc()
+			}
+			totalSize -= segment.size
+			// segment is older than allowed age, or the wal is too large, cleaning up
 			if err := os.Remove(filepath.Join(walDir, segment.name)); err != nil {
 				level.Error(wrt.log).Log("msg", "Error old wal segment", "err", err, "segmentNum", segment.number)
 			}
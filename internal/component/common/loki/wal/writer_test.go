@@ -156,6 +156,47 @@ func TestWriter_OldSegmentsAreCleanedUp(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestWriter_SegmentsAreTruncatedOverMaxSize(t *testing.T) {
+	logger := level.NewFilter(log.NewLogfmtLogger(os.Stdout), level.AllowDebug())
+	dir := t.TempDir()
+
+	writer, err := NewWriter(Config{
+		Dir:           dir,
+		Enabled:       true,
+		MaxSegmentAge: time.Hour,
+	}, logger, prometheus.NewRegistry())
+	require.NoError(t, err)
+	defer func() {
+		writer.Stop()
+	}()
+
+	writer.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"testing": "log"},
+		Entry: logproto.Entry{
+			Timestamp: time.Now(),
+			Line:      "some line",
+		},
+	}
+
+	// accessing the WAL inside, just for testing!
+	require.NoError(t, writer.wal.Sync(), "failed to sync wal")
+	_, err = writer.wal.NextSegment()
+	require.NoError(t, err, "error closing current segment")
+
+	// No segment is old enough, and the wal doesn't exceed the max size.
+	require.NoError(t, writer.cleanSegments(time.Hour, 1<<30))
+	_, err = os.Stat(filepath.Join(dir, "00000000"))
+	require.NoError(t, err)
+
+	// The first segment is truncated once the wal exceeds the max size, but
+	// the head segment is kept.
+	require.NoError(t, writer.cleanSegments(time.Hour, 1))
+	_, err = os.Stat(filepath.Join(dir, "00000000"))
+	require.ErrorIs(t, err, os.ErrNotExist, "expected file not exists error")
+	_, err = os.Stat(filepath.Join(dir, "00000001"))
+	require.NoError(t, err)
+}
+
 func TestWriter_NoSegmentIsCleanedUpIfTheresOnlyOne(t *testing.T) {
 	logger := level.NewFilter(log.NewLogfmtLogger(os.Stdout), level.AllowDebug())
 	dir := t.TempDir()
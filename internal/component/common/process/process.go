@@ -0,0 +1,237 @@
+// Package process implements supervision of child processes spawned by
+// components, including running them as a different user, constraining them
+// with cgroup resource limits, restarting them on exit, and forwarding their
+// output to the component logger.
+package process
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/dskit/backoff"
+)
+
+// RestartPolicy determines whether a supervised process is restarted after it
+// exits.
+type RestartPolicy string
+
+// Supported restart policies.
+const (
+	RestartNever     RestartPolicy = "never"
+	RestartOnFailure RestartPolicy = "on_failure"
+	RestartAlways    RestartPolicy = "always"
+)
+
+// Config configures how child processes are run. It is intended to be
+// embedded as a block in component arguments.
+type Config struct {
+	// RunAsUser is the name or numeric ID of the user to run the process as.
+	// The process runs as the agent user when empty.
+	RunAsUser string `river:"run_as_user,attr,optional"`
+
+	// CPULimit is the maximum number of CPU cores the process may use. Zero
+	// means unlimited.
+	CPULimit float64 `river:"cpu_limit,attr,optional"`
+
+	// MemoryLimit is the maximum amount of memory the process may use. Zero
+	// means unlimited.
+	MemoryLimit units.Base2Bytes `river:"memory_limit,attr,optional"`
+
+	// CgroupParent is the cgroup v2 directory under which per-process cgroups
+	// are created when limits are configured.
+	CgroupParent string `river:"cgroup_parent,attr,optional"`
+
+	RestartPolicy     RestartPolicy `river:"restart_policy,attr,optional"`
+	MaxRestarts       int           `river:"max_restarts,attr,optional"`
+	MinRestartBackoff time.Duration `river:"min_restart_backoff,attr,optional"`
+	MaxRestartBackoff time.Duration `river:"max_restart_backoff,attr,optional"`
+}
+
+// DefaultConfig holds default settings for Config.
+var DefaultConfig = Config{
+	CgroupParent:      "/sys/fs/cgroup/grafana-agent",
+	RestartPolicy:     RestartOnFailure,
+	MinRestartBackoff: time.Second,
+	MaxRestartBackoff: time.Minute,
+}
+
+// SetToDefault implements river.Defaulter.
+func (c *Config) SetToDefault() {
+	*c = DefaultConfig
+}
+
+// Validate implements river.Validator.
+func (c *Config) Validate() error {
+	switch c.RestartPolicy {
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return fmt.Errorf("unknown restart_policy %q, must be one of %q, %q, or %q", c.RestartPolicy, RestartNever, RestartOnFailure, RestartAlways)
+	}
+	if c.CPULimit < 0 {
+		return fmt.Errorf("cpu_limit must not be negative")
+	}
+	if c.MemoryLimit < 0 {
+		return fmt.Errorf("memory_limit must not be negative")
+	}
+	if c.MaxRestarts < 0 {
+		return fmt.Errorf("max_restarts must not be negative")
+	}
+	if c.MinRestartBackoff > c.MaxRestartBackoff {
+		return fmt.Errorf("min_restart_backoff must not be greater than max_restart_backoff")
+	}
+	return nil
+}
+
+// hasLimits reports whether c requires a cgroup to be created.
+func (c *Config) hasLimits() bool {
+	return c.CPULimit > 0 || c.MemoryLimit > 0
+}
+
+// Supervisor runs child processes according to a Config.
+type Supervisor struct {
+	logger log.Logger
+
+	mut sync.RWMutex
+	cfg Config
+}
+
+// NewSupervisor creates a new Supervisor. Output of child processes is
+// written to logger.
+func NewSupervisor(logger log.Logger, cfg Config) *Supervisor {
+	return &Supervisor{logger: logger, cfg: cfg}
+}
+
+// Update changes the Config used for processes started after the call.
+func (s *Supervisor) Update(cfg Config) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.cfg = cfg
+}
+
+func (s *Supervisor) config() Config {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.cfg
+}
+
+// Exec runs a process to completion once and returns its stdout and stderr.
+// Output is additionally forwarded to the logger line by line. The restart
+// policy is not applied.
+func (s *Supervisor) Exec(ctx context.Context, name string, argv ...string) (stdout string, stderr string, err error) {
+	var outBuf, errBuf bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, argv...)
+	cmd.Stdout = io.MultiWriter(&outBuf, newLogWriter(s.logger, name, "stdout"))
+	cmd.Stderr = io.MultiWriter(&errBuf, newLogWriter(s.logger, name, "stderr"))
+
+	err = s.run(cmd, s.config())
+	return outBuf.String(), errBuf.String(), err
+}
+
+// Run runs a long-lived process until ctx is canceled, restarting it
+// according to the configured restart policy. Run returns the error of the
+// last run once the process is no longer restarted.
+func (s *Supervisor) Run(ctx context.Context, name string, argv ...string) error {
+	cfg := s.config()
+	bo := backoff.New(ctx, backoff.Config{
+		MinBackoff: cfg.MinRestartBackoff,
+		MaxBackoff: cfg.MaxRestartBackoff,
+		MaxRetries: 0,
+	})
+
+	var restarts int
+	for {
+		cfg = s.config()
+
+		cmd := exec.CommandContext(ctx, name, argv...)
+		cmd.Stdout = newLogWriter(s.logger, name, "stdout")
+		cmd.Stderr = newLogWriter(s.logger, name, "stderr")
+
+		start := time.Now()
+		err := s.run(cmd, cfg)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			level.Warn(s.logger).Log("msg", "process exited with error", "process", name, "err", err)
+		} else {
+			level.Info(s.logger).Log("msg", "process exited", "process", name)
+		}
+
+		switch {
+		case cfg.RestartPolicy == RestartNever:
+			return err
+		case cfg.RestartPolicy == RestartOnFailure && err == nil:
+			return nil
+		case cfg.MaxRestarts > 0 && restarts >= cfg.MaxRestarts:
+			return fmt.Errorf("process %s exceeded max_restarts (%d): %w", name, cfg.MaxRestarts, err)
+		}
+
+		// A process which ran for longer than the maximum backoff is considered
+		// to have recovered.
+		if time.Since(start) > cfg.MaxRestartBackoff {
+			bo.Reset()
+		}
+		bo.Wait()
+		if ctx.Err() != nil {
+			return nil
+		}
+		restarts++
+		level.Info(s.logger).Log("msg", "restarting process", "process", name, "restarts", restarts)
+	}
+}
+
+// run starts cmd with the credentials and cgroup limits from cfg and waits
+// for it to exit.
+func (s *Supervisor) run(cmd *exec.Cmd, cfg Config) error {
+	cleanup, err := configureCmd(cmd, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to configure process %s: %w", cmd.Path, err)
+	}
+	defer func() {
+		if err := cleanup(); err != nil {
+			level.Warn(s.logger).Log("msg", "failed to clean up process resources", "process", cmd.Path, "err", err)
+		}
+	}()
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", cmd.Path, err)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", cmd.Path, err)
+	}
+	return nil
+}
+
+// logWriter forwards every line written to it to a logger.
+type logWriter struct {
+	logger log.Logger
+	buf    []byte
+}
+
+func newLogWriter(l log.Logger, process, stream string) *logWriter {
+	return &logWriter{logger: log.With(l, "process", process, "stream", stream)}
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	// Incomplete trailing lines stay buffered until more data arrives.
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		level.Info(w.logger).Log("msg", string(bytes.TrimRight(w.buf[:idx], "\r")))
+		w.buf = w.buf[idx+1:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,115 @@
+//go:build linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// cpuPeriod is the cgroup v2 CPU accounting period in microseconds.
+const cpuPeriod = 100_000
+
+var cgroupCounter atomic.Uint64
+
+// configureCmd applies cfg to cmd. The returned function must be called once
+// the process has exited to release resources.
+func configureCmd(cmd *exec.Cmd, cfg Config) (func() error, error) {
+	noop := func() error { return nil }
+
+	attr := &syscall.SysProcAttr{
+		// Ensure children don't outlive the agent.
+		Pdeathsig: syscall.SIGKILL,
+	}
+	cmd.SysProcAttr = attr
+
+	if cfg.RunAsUser != "" {
+		cred, err := lookupCredential(cfg.RunAsUser)
+		if err != nil {
+			return noop, err
+		}
+		attr.Credential = cred
+	}
+
+	if !cfg.hasLimits() {
+		return noop, nil
+	}
+
+	dir := filepath.Join(cfg.CgroupParent, fmt.Sprintf("%s-%d-%d", filepath.Base(cmd.Path), os.Getpid(), cgroupCounter.Add(1)))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return noop, fmt.Errorf("creating cgroup: %w", err)
+	}
+	cleanup := func() error {
+		// The cgroup can only be removed once the kernel has reaped every task
+		// inside it, which may lag slightly behind Wait returning.
+		var err error
+		for i := 0; i < 10; i++ {
+			if err = os.Remove(dir); err == nil || os.IsNotExist(err) {
+				return nil
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return err
+	}
+
+	if cfg.CPULimit > 0 {
+		quota := int64(cfg.CPULimit * cpuPeriod)
+		if err := writeCgroupFile(dir, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)); err != nil {
+			return cleanup, err
+		}
+	}
+	if cfg.MemoryLimit > 0 {
+		if err := writeCgroupFile(dir, "memory.max", strconv.FormatInt(int64(cfg.MemoryLimit), 10)); err != nil {
+			return cleanup, err
+		}
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		return cleanup, fmt.Errorf("opening cgroup: %w", err)
+	}
+	attr.UseCgroupFD = true
+	attr.CgroupFD = int(f.Fd())
+
+	return func() error {
+		_ = f.Close()
+		return cleanup()
+	}, nil
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+		return fmt.Errorf("setting %s: %w", name, err)
+	}
+	return nil
+}
+
+// lookupCredential resolves a user name or numeric ID to the credential used
+// to start a process.
+func lookupCredential(name string) (*syscall.Credential, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		var idErr error
+		u, idErr = user.LookupId(name)
+		if idErr != nil {
+			return nil, fmt.Errorf("looking up user %q: %w", name, err)
+		}
+	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid %q for user %q", u.Uid, name)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gid %q for user %q", u.Gid, name)
+	}
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}, nil
+}
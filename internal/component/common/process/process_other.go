@@ -0,0 +1,19 @@
+//go:build !linux
+
+package process
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// configureCmd applies cfg to cmd. Running as a different user and resource
+// limits are only supported on Linux.
+func configureCmd(_ *exec.Cmd, cfg Config) (func() error, error) {
+	noop := func() error { return nil }
+	if cfg.RunAsUser != "" || cfg.hasLimits() {
+		return noop, fmt.Errorf("run_as_user, cpu_limit, and memory_limit are not supported on %s", runtime.GOOS)
+	}
+	return noop, nil
+}
@@ -0,0 +1,171 @@
+//go:build linux
+
+package process
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervisor_Exec(t *testing.T) {
+	s := NewSupervisor(log.NewNopLogger(), DefaultConfig)
+
+	stdout, stderr, err := s.Exec(context.Background(), "sh", "-c", "echo hello; echo world >&2")
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", stdout)
+	require.Equal(t, "world\n", stderr)
+}
+
+func TestSupervisor_Run(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.MinRestartBackoff = time.Millisecond
+	cfg.MaxRestartBackoff = 10 * time.Millisecond
+
+	t.Run("never", func(t *testing.T) {
+		cfg := cfg
+		cfg.RestartPolicy = RestartNever
+		err := NewSupervisor(log.NewNopLogger(), cfg).Run(context.Background(), "false")
+		require.Error(t, err)
+	})
+
+	t.Run("on_failure success", func(t *testing.T) {
+		err := NewSupervisor(log.NewNopLogger(), cfg).Run(context.Background(), "true")
+		require.NoError(t, err)
+	})
+
+	t.Run("max restarts", func(t *testing.T) {
+		cfg := cfg
+		cfg.MaxRestarts = 2
+		err := NewSupervisor(log.NewNopLogger(), cfg).Run(context.Background(), "false")
+		require.ErrorContains(t, err, "exceeded max_restarts (2)")
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		cfg := cfg
+		cfg.RestartPolicy = RestartAlways
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := NewSupervisor(log.NewNopLogger(), cfg).Run(ctx, "true")
+		require.NoError(t, err)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := DefaultConfig
+	require.NoError(t, cfg.Validate())
+
+	cfg.RestartPolicy = "sometimes"
+	require.ErrorContains(t, cfg.Validate(), "unknown restart_policy")
+}
+
+func TestLookupCredential(t *testing.T) {
+	cred, err := lookupCredential("root")
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), cred.Uid)
+	require.Equal(t, uint32(0), cred.Gid)
+
+	cred, err = lookupCredential("0")
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), cred.Uid)
+
+	_, err = lookupCredential("no-such-user-for-process-tests")
+	require.ErrorContains(t, err, `looking up user "no-such-user-for-process-tests"`)
+}
+
+func TestSupervisor_RunAsUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("running processes as a different user requires root")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("user nobody does not exist")
+	}
+
+	cfg := DefaultConfig
+	cfg.RunAsUser = "nobody"
+	stdout, _, err := NewSupervisor(log.NewNopLogger(), cfg).Exec(context.Background(), "id", "-u")
+	require.NoError(t, err)
+	require.Equal(t, nobody.Uid, strings.TrimSpace(stdout))
+}
+
+func TestConfigureCmd_CgroupLimits(t *testing.T) {
+	// The cgroup files are written to a regular directory, which is enough
+	// to check the limits without requiring cgroup v2 to be writable.
+	cfg := DefaultConfig
+	cfg.CgroupParent = t.TempDir()
+	cfg.CPULimit = 0.5
+	cfg.MemoryLimit = 64 * units.MiB
+
+	cmd := exec.Command("true")
+	cleanup, err := configureCmd(cmd, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cleanup() })
+	require.True(t, cmd.SysProcAttr.UseCgroupFD)
+
+	dirs, err := os.ReadDir(cfg.CgroupParent)
+	require.NoError(t, err)
+	require.Len(t, dirs, 1)
+	dir := filepath.Join(cfg.CgroupParent, dirs[0].Name())
+
+	cpuMax, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+	require.NoError(t, err)
+	require.Equal(t, "50000 100000", string(cpuMax))
+
+	memoryMax, err := os.ReadFile(filepath.Join(dir, "memory.max"))
+	require.NoError(t, err)
+	require.Equal(t, "67108864", string(memoryMax))
+}
+
+func TestConfigureCmd_NoLimits(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.CgroupParent = t.TempDir()
+
+	cmd := exec.Command("true")
+	_, err := configureCmd(cmd, cfg)
+	require.NoError(t, err)
+	require.False(t, cmd.SysProcAttr.UseCgroupFD)
+
+	dirs, err := os.ReadDir(cfg.CgroupParent)
+	require.NoError(t, err)
+	require.Empty(t, dirs, "no cgroup should be created without limits")
+}
+
+func TestSupervisor_CgroupLimits(t *testing.T) {
+	const parent = "/sys/fs/cgroup/grafana-agent-process-test"
+	if os.Geteuid() != 0 {
+		t.Skip("creating cgroups requires root")
+	}
+	if err := os.Mkdir(parent, 0755); err != nil {
+		t.Skipf("cgroup v2 is not writable: %s", err)
+	}
+	t.Cleanup(func() { _ = os.Remove(parent) })
+	if err := os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory"), 0644); err != nil {
+		t.Skipf("memory controller is not available: %s", err)
+	}
+
+	cfg := DefaultConfig
+	cfg.CgroupParent = parent
+	cfg.MemoryLimit = 64 * units.MiB
+
+	stdout, _, err := NewSupervisor(log.NewNopLogger(), cfg).Exec(context.Background(), "sh", "-c", "cat /sys/fs/cgroup$(cut -d: -f3 /proc/self/cgroup)/memory.max")
+	require.NoError(t, err)
+	require.Equal(t, "67108864", strings.TrimSpace(stdout))
+
+	// The per-process cgroup must be removed once the process exited.
+	dirs, err := os.ReadDir(parent)
+	require.NoError(t, err)
+	for _, d := range dirs {
+		require.False(t, d.IsDir(), "leftover cgroup %s", d.Name())
+	}
+}
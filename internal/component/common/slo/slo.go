@@ -0,0 +1,392 @@
+// Package slo computes rolling success ratio, latency quantile, and burn rate
+// metrics for the endpoints a component writes to.
+package slo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// minResolution is the smallest interval requests are grouped into.
+	minResolution = 10 * time.Second
+
+	// maxSlots is the maximum number of intervals kept per endpoint. The
+	// interval is widened for long windows to stay under this limit.
+	maxSlots = 720
+
+	// MaxWindow is the longest window which can be configured.
+	MaxWindow = 24 * time.Hour
+)
+
+var (
+	// latencyBuckets are the upper bounds used to estimate latency quantiles.
+	// They match the buckets of the prometheus.remote_write send duration
+	// histogram so that it can be mapped without loss.
+	latencyBuckets = append(append([]float64{}, prometheus.DefBuckets...), 25, 60, 120, 300)
+
+	// quantiles are the latency quantiles which are reported.
+	quantiles = []float64{0.5, 0.9, 0.99}
+)
+
+// Arguments configures the SLO metrics reported by a component.
+type Arguments struct {
+	Objective float64         `river:"objective,attr,optional"`
+	Windows   []time.Duration `river:"windows,attr,optional"`
+	BurnRate  bool            `river:"burn_rate,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Objective: 0.99,
+	Windows:   []time.Duration{5 * time.Minute, time.Hour},
+	BurnRate:  false,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+	args.Windows = append([]time.Duration{}, DefaultArguments.Windows...)
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Objective <= 0 || args.Objective >= 1 {
+		return fmt.Errorf("objective must be greater than 0 and less than 1")
+	}
+	if len(args.Windows) == 0 {
+		return fmt.Errorf("at least one window must be provided")
+	}
+	for _, w := range args.Windows {
+		if w < minResolution || w > MaxWindow {
+			return fmt.Errorf("window %s must be between %s and %s", w, minResolution, MaxWindow)
+		}
+	}
+	return nil
+}
+
+// resolution returns the interval requests are grouped into for args.
+func (args *Arguments) resolution() time.Duration {
+	var longest time.Duration
+	for _, w := range args.Windows {
+		if w > longest {
+			longest = w
+		}
+	}
+	res := minResolution
+	if perSlot := longest / maxSlots; perSlot > res {
+		res = perSlot.Truncate(time.Second) + time.Second
+	}
+	return res
+}
+
+// Tracker records the outcome of requests to endpoints and exposes rolling
+// SLO metrics for them. Tracker implements [prometheus.Collector]; metrics are
+// computed when collected. A Tracker without Arguments records nothing and
+// exposes no metrics.
+type Tracker struct {
+	now func() time.Time
+
+	successRatioDesc *prometheus.Desc
+	latencyDesc      *prometheus.Desc
+	burnRateDesc     *prometheus.Desc
+	objectiveDesc    *prometheus.Desc
+
+	mut        sync.Mutex
+	args       *Arguments
+	resolution time.Duration
+	slots      int
+	endpoints  map[string]*endpoint
+}
+
+var _ prometheus.Collector = (*Tracker)(nil)
+
+// NewTracker returns a new Tracker. Call Update to enable it.
+func NewTracker() *Tracker {
+	return &Tracker{
+		now: time.Now,
+
+		successRatioDesc: prometheus.NewDesc(
+			"agent_write_slo_success_ratio",
+			"Ratio of successful requests to an endpoint over a rolling window.",
+			[]string{"endpoint", "window"}, nil,
+		),
+		latencyDesc: prometheus.NewDesc(
+			"agent_write_slo_latency_seconds",
+			"Estimated quantile of request latency to an endpoint over a rolling window.",
+			[]string{"endpoint", "window", "quantile"}, nil,
+		),
+		burnRateDesc: prometheus.NewDesc(
+			"agent_write_slo_burn_rate",
+			"Rate at which the error budget of an endpoint is consumed over a rolling window.",
+			[]string{"endpoint", "window"}, nil,
+		),
+		objectiveDesc: prometheus.NewDesc(
+			"agent_write_slo_objective",
+			"Configured success ratio objective.",
+			nil, nil,
+		),
+
+		endpoints: make(map[string]*endpoint),
+	}
+}
+
+// Update changes the settings of t. Passing nil disables t. Recorded data is
+// discarded when the resolution changes, which happens when the longest
+// window changes.
+func (t *Tracker) Update(args *Arguments) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	t.args = args
+	if args == nil {
+		t.endpoints = make(map[string]*endpoint)
+		return
+	}
+
+	res := args.resolution()
+	var longest time.Duration
+	for _, w := range args.Windows {
+		if w > longest {
+			longest = w
+		}
+	}
+	slots := int(math.Ceil(float64(longest)/float64(res))) + 1
+
+	if res != t.resolution || slots != t.slots {
+		t.resolution, t.slots = res, slots
+		t.endpoints = make(map[string]*endpoint)
+	}
+}
+
+// Enabled returns true if t is recording requests.
+func (t *Tracker) Enabled() bool {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.args != nil
+}
+
+// Observe records a single request to endpoint.
+func (t *Tracker) Observe(endpoint string, latency time.Duration, success bool) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	s := t.currentSlot(endpoint)
+	if s == nil {
+		return
+	}
+	s.total++
+	if !success {
+		s.failed++
+	}
+	s.latency[bucketIndex(latency.Seconds())]++
+}
+
+// ObserveTotals records requests to endpoint from cumulative counters, such as
+// those exposed by an existing metric. The difference from the previous call
+// for the same endpoint is recorded. latency may be nil.
+func (t *Tracker) ObserveTotals(endpoint string, total, failed float64, latency *dto.Histogram) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	s := t.currentSlot(endpoint)
+	if s == nil {
+		return
+	}
+	e := t.endpoints[endpoint]
+
+	// Convert the cumulative buckets of latency into a count per bucket of
+	// latencyBuckets.
+	buckets := make([]float64, len(latencyBuckets)+1)
+	if latency != nil {
+		var prev float64
+		for _, b := range latency.GetBucket() {
+			buckets[bucketIndex(b.GetUpperBound())] += float64(b.GetCumulativeCount()) - prev
+			prev = float64(b.GetCumulativeCount())
+		}
+		buckets[len(latencyBuckets)] += float64(latency.GetSampleCount()) - prev
+	}
+
+	if e.seen {
+		// Counters which went backwards were reset; count their new value in
+		// full.
+		s.total += delta(e.lastTotal, total)
+		s.failed += delta(e.lastFailed, failed)
+		for i := range buckets {
+			s.latency[i] += delta(e.lastLatency[i], buckets[i])
+		}
+	}
+
+	e.seen = true
+	e.lastTotal, e.lastFailed, e.lastLatency = total, failed, buckets
+}
+
+func delta(prev, cur float64) float64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// currentSlot returns the slot of endpoint for the current interval. It
+// returns nil if t is disabled. t.mut must be held.
+func (t *Tracker) currentSlot(name string) *slot {
+	if t.args == nil {
+		return nil
+	}
+
+	e, ok := t.endpoints[name]
+	if !ok {
+		e = &endpoint{
+			slots:       make([]slot, t.slots),
+			lastLatency: make([]float64, len(latencyBuckets)+1),
+		}
+		t.endpoints[name] = e
+	}
+
+	id := t.now().UnixNano() / int64(t.resolution)
+	s := &e.slots[id%int64(len(e.slots))]
+	if s.id != id {
+		*s = slot{id: id, latency: make([]float64, len(latencyBuckets)+1)}
+	}
+	return s
+}
+
+// Describe implements [prometheus.Collector].
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- t.successRatioDesc
+	ch <- t.latencyDesc
+	ch <- t.burnRateDesc
+	ch <- t.objectiveDesc
+}
+
+// Collect implements [prometheus.Collector].
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if t.args == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(t.objectiveDesc, prometheus.GaugeValue, t.args.Objective)
+
+	names := make([]string, 0, len(t.endpoints))
+	for name := range t.endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	current := t.now().UnixNano() / int64(t.resolution)
+	for _, name := range names {
+		e := t.endpoints[name]
+
+		for _, w := range t.args.Windows {
+			window := model.Duration(w).String()
+			sum := e.sum(current, int64(math.Ceil(float64(w)/float64(t.resolution))))
+			if sum.total == 0 {
+				// No requests were made during the window; there's nothing to
+				// report.
+				continue
+			}
+
+			ratio := (sum.total - sum.failed) / sum.total
+			ch <- prometheus.MustNewConstMetric(t.successRatioDesc, prometheus.GaugeValue, ratio, name, window)
+
+			if t.args.BurnRate {
+				burn := (1 - ratio) / (1 - t.args.Objective)
+				ch <- prometheus.MustNewConstMetric(t.burnRateDesc, prometheus.GaugeValue, burn, name, window)
+			}
+
+			for _, q := range quantiles {
+				v := bucketQuantile(q, sum.latency)
+				if math.IsNaN(v) {
+					continue
+				}
+				ch <- prometheus.MustNewConstMetric(t.latencyDesc, prometheus.GaugeValue, v, name, window, strconv.FormatFloat(q, 'g', -1, 64))
+			}
+		}
+	}
+}
+
+// endpoint holds the recorded requests of a single endpoint.
+type endpoint struct {
+	slots []slot
+
+	// Previous values passed to ObserveTotals.
+	seen        bool
+	lastTotal   float64
+	lastFailed  float64
+	lastLatency []float64
+}
+
+// slot holds the requests of a single interval.
+type slot struct {
+	id      int64
+	total   float64
+	failed  float64
+	latency []float64 // Count per bucket of latencyBuckets, plus +Inf.
+}
+
+// sum adds up the slots in the n intervals up to and including current.
+func (e *endpoint) sum(current, n int64) slot {
+	res := slot{latency: make([]float64, len(latencyBuckets)+1)}
+	for _, s := range e.slots {
+		if s.id <= current-n || s.id > current || s.latency == nil {
+			continue
+		}
+		res.total += s.total
+		res.failed += s.failed
+		for i, v := range s.latency {
+			res.latency[i] += v
+		}
+	}
+	return res
+}
+
+// bucketIndex returns the index of the first bucket which v falls into.
+func bucketIndex(v float64) int {
+	return sort.SearchFloat64s(latencyBuckets, v)
+}
+
+// bucketQuantile estimates the q quantile from per-bucket counts by linear
+// interpolation within the bucket the quantile falls into, like PromQL's
+// histogram_quantile. It returns NaN if there are no observations.
+func bucketQuantile(q float64, counts []float64) float64 {
+	var total float64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return math.NaN()
+	}
+
+	rank := q * total
+	var seen float64
+	for i, c := range counts {
+		if seen+c < rank || c == 0 {
+			seen += c
+			continue
+		}
+		if i == len(latencyBuckets) {
+			// The quantile falls into the +Inf bucket; report the highest
+			// finite bound.
+			return latencyBuckets[len(latencyBuckets)-1]
+		}
+
+		var lower float64
+		if i > 0 {
+			lower = latencyBuckets[i-1]
+		}
+		upper := latencyBuckets[i]
+		return lower + (upper-lower)*((rank-seen)/c)
+	}
+	return latencyBuckets[len(latencyBuckets)-1]
+}
@@ -0,0 +1,134 @@
+package slo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRiverConfig(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`burn_rate = true`), &args))
+	require.Equal(t, Arguments{
+		Objective: 0.99,
+		Windows:   []time.Duration{5 * time.Minute, time.Hour},
+		BurnRate:  true,
+	}, args)
+
+	require.ErrorContains(t, river.Unmarshal([]byte(`objective = 1`), &args), "objective must be greater than 0 and less than 1")
+	require.ErrorContains(t, river.Unmarshal([]byte(`windows = ["48h"]`), &args), "window 48h0m0s must be between 10s and 24h0m0s")
+}
+
+func TestTracker(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	tracker := NewTracker()
+	tracker.now = func() time.Time { return now }
+	tracker.Update(&Arguments{
+		Objective: 0.5,
+		Windows:   []time.Duration{time.Minute, 5 * time.Minute},
+		BurnRate:  true,
+	})
+
+	// Four minutes ago: all requests failed.
+	now = now.Add(-4 * time.Minute)
+	for i := 0; i < 4; i++ {
+		tracker.Observe("http://loki/push", 2*time.Second, false)
+	}
+
+	// Now: 3 of 4 requests succeeded.
+	now = now.Add(4 * time.Minute)
+	for i := 0; i < 4; i++ {
+		tracker.Observe("http://loki/push", 50*time.Millisecond, i != 0)
+	}
+
+	expect := `
+		# HELP agent_write_slo_burn_rate Rate at which the error budget of an endpoint is consumed over a rolling window.
+		# TYPE agent_write_slo_burn_rate gauge
+		agent_write_slo_burn_rate{endpoint="http://loki/push",window="1m"} 0.5
+		agent_write_slo_burn_rate{endpoint="http://loki/push",window="5m"} 1.25
+		# HELP agent_write_slo_objective Configured success ratio objective.
+		# TYPE agent_write_slo_objective gauge
+		agent_write_slo_objective 0.5
+		# HELP agent_write_slo_success_ratio Ratio of successful requests to an endpoint over a rolling window.
+		# TYPE agent_write_slo_success_ratio gauge
+		agent_write_slo_success_ratio{endpoint="http://loki/push",window="1m"} 0.75
+		agent_write_slo_success_ratio{endpoint="http://loki/push",window="5m"} 0.375
+	`
+	require.NoError(t, testutil.CollectAndCompare(tracker, strings.NewReader(expect),
+		"agent_write_slo_burn_rate", "agent_write_slo_objective", "agent_write_slo_success_ratio"))
+
+	// Requests in the last minute all fell into the (0.025, 0.05] bucket.
+	var latency *dto.Metric
+	for _, m := range collect(tracker) {
+		pb := &dto.Metric{}
+		require.NoError(t, m.Write(pb))
+		if strings.Contains(m.Desc().String(), "agent_write_slo_latency_seconds") && hasLabels(pb, "1m", "0.5") {
+			latency = pb
+		}
+	}
+	require.InDelta(t, 0.0375, latency.GetGauge().GetValue(), 0.0001)
+
+	// Disabling the tracker removes all metrics.
+	tracker.Update(nil)
+	require.Empty(t, collect(tracker))
+}
+
+func TestTracker_ObserveTotals(t *testing.T) {
+	tracker := NewTracker()
+	args := DefaultArguments
+	tracker.Update(&args)
+
+	histogram := func(count uint64) *dto.Histogram {
+		return &dto.Histogram{
+			SampleCount: &count,
+			Bucket: []*dto.Bucket{
+				{UpperBound: ptr(0.1), CumulativeCount: &count},
+			},
+		}
+	}
+
+	// The first call only establishes the baseline.
+	tracker.ObserveTotals("http://prom/push", 100, 10, histogram(5))
+	tracker.ObserveTotals("http://prom/push", 300, 20, histogram(10))
+
+	expect := `
+		# HELP agent_write_slo_success_ratio Ratio of successful requests to an endpoint over a rolling window.
+		# TYPE agent_write_slo_success_ratio gauge
+		agent_write_slo_success_ratio{endpoint="http://prom/push",window="1h"} 0.95
+		agent_write_slo_success_ratio{endpoint="http://prom/push",window="5m"} 0.95
+	`
+	require.NoError(t, testutil.CollectAndCompare(tracker, strings.NewReader(expect), "agent_write_slo_success_ratio"))
+}
+
+func collect(c prometheus.Collector) []prometheus.Metric {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	var res []prometheus.Metric
+	for m := range ch {
+		res = append(res, m)
+	}
+	return res
+}
+
+func hasLabels(m *dto.Metric, window, quantile string) bool {
+	var matched int
+	for _, l := range m.GetLabel() {
+		if (l.GetName() == "window" && l.GetValue() == window) || (l.GetName() == "quantile" && l.GetValue() == quantile) {
+			matched++
+		}
+	}
+	return matched == 2
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,39 @@
+package component
+
+// ScrapeCostComponent is an extension interface for components which scrape
+// targets and can report the cost of the samples they produce.
+type ScrapeCostComponent interface {
+	Component
+
+	// ScrapeCost returns the cost of the last scrape of each active target.
+	ScrapeCost() ScrapeCost
+}
+
+// ScrapeCost is the cost of the samples produced by a scrape component.
+type ScrapeCost struct {
+	// ActiveSeries is the number of series produced by the last scrapes.
+	ActiveSeries int `json:"activeSeries"`
+
+	// SamplesPerSecond is the rate of samples produced, based on the interval
+	// between the last two scrapes of each target.
+	SamplesPerSecond float64 `json:"samplesPerSecond"`
+
+	// BytesPerSecond is the estimated rate of bytes sent to remote endpoints
+	// for the samples produced, before compression. The size of a sample is
+	// estimated from the size of its labels plus its timestamp and value.
+	BytesPerSecond float64 `json:"bytesPerSecond"`
+
+	// Targets holds the cost of each target, sorted by active series in
+	// descending order.
+	Targets []TargetCost `json:"targets"`
+}
+
+// TargetCost is the cost of the samples produced by scraping a single target.
+type TargetCost struct {
+	Job              string            `json:"job"`
+	URL              string            `json:"url"`
+	Labels           map[string]string `json:"labels"`
+	ActiveSeries     int               `json:"activeSeries"`
+	SamplesPerSecond float64           `json:"samplesPerSecond"`
+	BytesPerSecond   float64           `json:"bytesPerSecond"`
+}
@@ -1,6 +1,8 @@
 package component
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"strings"
@@ -63,6 +65,11 @@ type InfoOptions struct {
 	GetArguments bool // When true, sets the Arguments field of returned components.
 	GetExports   bool // When true, sets the Exports field of returned components.
 	GetDebugInfo bool // When true, sets the DebugInfo field of returned components.
+
+	// When true, sets the SensitiveBlocks field of returned components whose
+	// Arguments implement [SensitiveArguments], so that the values of those
+	// blocks are redacted when marshaling to JSON.
+	RedactSensitiveBlocks bool
 }
 
 // String returns the "<ModuleID>/<LocalID>" string representation of the id.
@@ -99,6 +106,11 @@ type Info struct {
 	Arguments Arguments   // Current arguments value of the component.
 	Exports   Exports     // Current exports value of the component.
 	DebugInfo interface{} // Current debug info of the component.
+
+	// SensitiveBlocks lists the blocks of Arguments whose values are replaced
+	// with "(secret)" when marshaling to JSON. Values typed as
+	// rivertypes.Secret are always replaced.
+	SensitiveBlocks []string
 }
 
 // MarshalJSON returns a JSON representation of cd. The format of the
@@ -147,6 +159,10 @@ func (info *Info) MarshalJSON() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	arguments, err = redactBlocks(arguments, info.SensitiveBlocks)
+	if err != nil {
+		return nil, err
+	}
 	exports, err = riverjson.MarshalBody(info.Exports)
 	if err != nil {
 		return nil, err
@@ -176,10 +192,24 @@ func (info *Info) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// TenantProvider is implemented by Providers which run tenants: isolated
+// controllers whose components are only reachable through their module ID.
+type TenantProvider interface {
+	// TenantModuleIDs returns the module IDs of the running tenants.
+	TenantModuleIDs() []string
+}
+
 // GetAllComponents enumerates over all of the modules in p and returns the set
-// of all components.
+// of all components. If p implements [TenantProvider], the components of its
+// tenants are included.
 func GetAllComponents(p Provider, opts InfoOptions) []*Info {
-	return getAllComponentsByModule("", p, opts)
+	components := getAllComponentsByModule("", p, opts)
+	if tp, ok := p.(TenantProvider); ok {
+		for _, id := range tp.TenantModuleIDs() {
+			components = append(components, getAllComponentsByModule(id, p, opts)...)
+		}
+	}
+	return components
 }
 
 func getAllComponentsByModule(moduleID string, p Provider, opts InfoOptions) []*Info {
@@ -203,3 +233,268 @@ func getAllComponentsByModule(moduleID string, p Provider, opts InfoOptions) []*
 
 	return components
 }
+
+// ModuleInfo is summary information about the components running in a
+// module.
+type ModuleInfo struct {
+	ID string // ID of the module. Empty for the root module.
+
+	// ComponentsByHealth counts the components of the module by their current
+	// health.
+	ComponentsByHealth map[HealthType]int
+
+	// ExportsSize is the total size in bytes of the exports of the components
+	// of the module, encoded as JSON.
+	ExportsSize int
+
+	// LastEvaluation and LastEvaluationDuration are the start time and duration
+	// of the most recent complete evaluation of the module. LastEvaluation is
+	// the zero time if the module hasn't been evaluated yet.
+	LastEvaluation         time.Time
+	LastEvaluationDuration time.Duration
+}
+
+// MarshalJSON returns a JSON representation of mi. The format of the
+// representation is not stable and is subject to change.
+func (mi *ModuleInfo) MarshalJSON() ([]byte, error) {
+	type moduleInfoJSON struct {
+		ModuleID               string             `json:"moduleID"`
+		ComponentsByHealth     map[HealthType]int `json:"componentsByHealth"`
+		ExportsSize            int                `json:"exportsSize"`
+		LastEvaluation         time.Time          `json:"lastEvaluation"`
+		LastEvaluationDuration float64            `json:"lastEvaluationDurationSeconds"`
+	}
+
+	componentsByHealth := mi.ComponentsByHealth
+	if componentsByHealth == nil {
+		componentsByHealth = map[HealthType]int{}
+	}
+
+	return json.Marshal(&moduleInfoJSON{
+		ModuleID:               mi.ID,
+		ComponentsByHealth:     componentsByHealth,
+		ExportsSize:            mi.ExportsSize,
+		LastEvaluation:         mi.LastEvaluation,
+		LastEvaluationDuration: mi.LastEvaluationDuration.Seconds(),
+	})
+}
+
+// ModuleInfoProvider is implemented by Providers which can report summary
+// information about their modules.
+type ModuleInfoProvider interface {
+	// ModuleIDs returns the IDs of all running modules in sorted order,
+	// including nested modules and tenants. The root module isn't included.
+	ModuleIDs() []string
+
+	// GetModuleInfo returns summary information about the module with the
+	// given ID. An empty moduleID refers to the root module.
+	//
+	// Returns ErrModuleNotFound if the provided moduleID doesn't exist.
+	GetModuleInfo(moduleID string) (*ModuleInfo, error)
+}
+
+// ModuleContent is the content currently loaded by a module or an import
+// block.
+type ModuleContent struct {
+	ID string // ID of the module or import block.
+
+	// Files holds the loaded content by file name. Literal values of
+	// attributes which look like they hold secrets are replaced with
+	// "(secret)".
+	Files map[string]string
+
+	// SHA256 is the checksum of the content before secrets were scrubbed.
+	SHA256 [sha256.Size]byte
+
+	// LastUpdate is the last time the content was loaded.
+	LastUpdate time.Time
+}
+
+// MarshalJSON returns a JSON representation of mc. The format of the
+// representation is not stable and is subject to change.
+func (mc *ModuleContent) MarshalJSON() ([]byte, error) {
+	type moduleContentJSON struct {
+		ID         string            `json:"id"`
+		Files      map[string]string `json:"files"`
+		SHA256     string            `json:"sha256"`
+		LastUpdate time.Time         `json:"lastUpdate"`
+	}
+
+	files := mc.Files
+	if files == nil {
+		files = map[string]string{}
+	}
+
+	return json.Marshal(&moduleContentJSON{
+		ID:         mc.ID,
+		Files:      files,
+		SHA256:     hex.EncodeToString(mc.SHA256[:]),
+		LastUpdate: mc.LastUpdate,
+	})
+}
+
+// ModuleContentProvider is implemented by Providers which can report the
+// content loaded by their modules and import blocks.
+type ModuleContentProvider interface {
+	// GetModuleContent returns the content loaded by the module or import
+	// block with the given ID. Import blocks are identified by their module ID
+	// and their block ID, such as "module.file.a/import.http.b", or only by
+	// their block ID for import blocks of the root module.
+	//
+	// Returns ErrModuleNotFound if the provided ID doesn't exist.
+	GetModuleContent(id string) (*ModuleContent, error)
+}
+
+// Kinds of nodes in a [Graph].
+const (
+	GraphNodeComponent       = "component"        // A builtin component.
+	GraphNodeCustomComponent = "custom_component" // An instance of a declare block.
+	GraphNodeImport          = "import"           // An import block.
+	GraphNodeDeclare         = "declare"          // A declare block.
+	GraphNodeService         = "service"          // A service block.
+	GraphNodeConfig          = "config"           // Any other config block, such as logging or argument.
+)
+
+// Graph is the dependency graph of the nodes of a module.
+type Graph struct {
+	ModuleID string      `json:"moduleID"` // ID of the module. Empty for the root module.
+	Nodes    []GraphNode `json:"nodes"`    // Nodes of the graph, sorted by ID.
+	Edges    []GraphEdge `json:"edges"`    // Edges of the graph, sorted by From and To.
+}
+
+// GraphNode is a node in a [Graph].
+type GraphNode struct {
+	ID   string `json:"id"`   // ID of the node within its module, such as "prometheus.scrape.default".
+	Kind string `json:"kind"` // Kind of the node, one of the GraphNode constants.
+	Name string `json:"name"` // Name of the block of the node, such as "prometheus.scrape".
+
+	// ModuleIDs lists the modules run by the node, such as the module of a
+	// custom component.
+	ModuleIDs []string `json:"moduleIDs,omitempty"`
+}
+
+// GraphEdge is an edge in a [Graph]. The node From references the node To,
+// so To is evaluated before From.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// GraphProvider is implemented by Providers which can report the dependency
+// graph of their modules.
+type GraphProvider interface {
+	// GetGraph returns the graph of the module with the given ID. An empty
+	// moduleID refers to the root module.
+	//
+	// Returns ErrModuleNotFound if the provided moduleID doesn't exist.
+	GetGraph(moduleID string) (*Graph, error)
+}
+
+// Profiling labels set on the goroutines of running components. Goroutines
+// started by a component inherit its labels, so CPU and goroutine profiles
+// can be grouped by component.
+const (
+	// ProfileLabelComponentID is the label holding the ID of the component
+	// within its module, such as "prometheus.scrape.default".
+	ProfileLabelComponentID = "component_id"
+
+	// ProfileLabelModule is the label holding the ID of the module the
+	// component belongs to. It's empty for components of the root module.
+	ProfileLabelModule = "module"
+)
+
+// ImportInfo is information about an import block of a module.
+type ImportInfo struct {
+	ModuleID   string // ID of the module the import block belongs to. Empty for the root module.
+	Label      string // Label of the import block.
+	SourceType string // Name of the import block, such as "import.file".
+
+	// LastUpdate is the last time the imported content was successfully
+	// loaded. It's the zero time if content hasn't been loaded yet.
+	LastUpdate time.Time
+
+	Health           Health // Overall health of the import.
+	EvaluationHealth Health // Health of the last evaluation of the import block.
+	RunHealth        Health // Health of running the import source.
+
+	// Declares lists the names of the declare blocks provided by the import, in
+	// sorted order.
+	Declares []string
+
+	// Imports lists the import blocks found in the imported content.
+	Imports []*ImportInfo
+
+	// DebugInfo holds the status of the nested import blocks, including the
+	// ones which failed to evaluate.
+	DebugInfo interface{}
+}
+
+// MarshalJSON returns a JSON representation of ii. The format of the
+// representation is not stable and is subject to change.
+func (ii *ImportInfo) MarshalJSON() ([]byte, error) {
+	type (
+		importHealthJSON struct {
+			State       string    `json:"state"`
+			Message     string    `json:"message"`
+			UpdatedTime time.Time `json:"updatedTime"`
+		}
+
+		importInfoJSON struct {
+			ModuleID         string           `json:"moduleID"`
+			Label            string           `json:"label"`
+			SourceType       string           `json:"sourceType"`
+			LastUpdate       time.Time        `json:"lastUpdate"`
+			Health           importHealthJSON `json:"health"`
+			EvaluationHealth importHealthJSON `json:"evaluationHealth"`
+			RunHealth        importHealthJSON `json:"runHealth"`
+			Declares         []string         `json:"declares"`
+			Imports          []*ImportInfo    `json:"imports"`
+			DebugInfo        json.RawMessage  `json:"debugInfo,omitempty"`
+		}
+	)
+
+	toHealthJSON := func(h Health) importHealthJSON {
+		return importHealthJSON{
+			State:       h.Health.String(),
+			Message:     h.Message,
+			UpdatedTime: h.UpdateTime,
+		}
+	}
+
+	declares := ii.Declares
+	if declares == nil {
+		declares = []string{}
+	}
+	imports := ii.Imports
+	if imports == nil {
+		imports = []*ImportInfo{}
+	}
+	debugInfo, err := riverjson.MarshalBody(ii.DebugInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&importInfoJSON{
+		ModuleID:         ii.ModuleID,
+		Label:            ii.Label,
+		SourceType:       ii.SourceType,
+		LastUpdate:       ii.LastUpdate,
+		Health:           toHealthJSON(ii.Health),
+		EvaluationHealth: toHealthJSON(ii.EvaluationHealth),
+		RunHealth:        toHealthJSON(ii.RunHealth),
+		Declares:         declares,
+		Imports:          imports,
+		DebugInfo:        debugInfo,
+	})
+}
+
+// ImportInfoProvider is implemented by Providers which can report information
+// about the import blocks of their modules.
+type ImportInfoProvider interface {
+	// ListImports returns the import blocks of the module with the given ID,
+	// sorted by label. Nested imports are reported by their parent import. An
+	// empty moduleID refers to the root module.
+	//
+	// Returns ErrModuleNotFound if the provided moduleID doesn't exist.
+	ListImports(moduleID string) ([]*ImportInfo, error)
+}
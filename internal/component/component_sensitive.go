@@ -0,0 +1,72 @@
+package component
+
+import (
+	"encoding/json"
+)
+
+// SensitiveArguments is an extension interface for Arguments which hold
+// sensitive values in blocks that don't use rivertypes.Secret, such as
+// credentials passed as plain strings to upstream libraries.
+type SensitiveArguments interface {
+	Arguments
+
+	// SensitiveBlocks returns the names of the blocks whose values are
+	// sensitive. Nested blocks are named by joining the names of their parent
+	// blocks with ".", such as "client.basic_auth".
+	SensitiveBlocks() []string
+}
+
+// redactedValue is the JSON representation of a redacted value, which
+// matches how River encodes rivertypes.Secret.
+var redactedValue = json.RawMessage(`{"type":"capsule","value":"(secret)"}`)
+
+// riverJSONStatement is a statement of a River body encoded as JSON by
+// riverjson.
+type riverJSONStatement struct {
+	Name  string                `json:"name"`
+	Type  string                `json:"type"`
+	Label string                `json:"label,omitempty"`
+	Body  *[]riverJSONStatement `json:"body,omitempty"`
+	Value json.RawMessage       `json:"value,omitempty"`
+}
+
+// redactBlocks replaces the values of every attribute of the blocks named by
+// blocks in body, a River body encoded as JSON by riverjson.
+func redactBlocks(body json.RawMessage, blocks []string) (json.RawMessage, error) {
+	if len(blocks) == 0 || len(body) == 0 {
+		return body, nil
+	}
+
+	var stmts []riverJSONStatement
+	if err := json.Unmarshal(body, &stmts); err != nil {
+		return nil, err
+	}
+
+	sensitive := make(map[string]struct{}, len(blocks))
+	for _, name := range blocks {
+		sensitive[name] = struct{}{}
+	}
+	redactStatements(stmts, "", sensitive, false)
+	return json.Marshal(stmts)
+}
+
+func redactStatements(stmts []riverJSONStatement, prefix string, sensitive map[string]struct{}, redact bool) {
+	for i := range stmts {
+		stmt := &stmts[i]
+		switch stmt.Type {
+		case "attr":
+			if redact {
+				stmt.Value = redactedValue
+			}
+		case "block":
+			name := stmt.Name
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			_, isSensitive := sensitive[name]
+			if stmt.Body != nil {
+				redactStatements(*stmt.Body, name, sensitive, redact || isSensitive)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package component_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/rivertypes"
+	"github.com/stretchr/testify/require"
+)
+
+type sensitiveArgs struct {
+	URL      string                       `river:"url,attr"`
+	Password rivertypes.Secret            `river:"password,attr"`
+	Headers  map[string]rivertypes.Secret `river:"headers,attr"`
+	Client   sensitiveClient              `river:"client,block"`
+}
+
+type sensitiveClient struct {
+	Timeout string        `river:"timeout,attr"`
+	Auth    sensitiveAuth `river:"auth,block"`
+}
+
+type sensitiveAuth struct {
+	Token string `river:"token,attr"`
+}
+
+func TestInfo_MarshalJSON_ScrubSecrets(t *testing.T) {
+	args := sensitiveArgs{
+		URL:      "http://localhost:9009",
+		Password: "SCRUBME",
+		Headers:  map[string]rivertypes.Secret{"X-Api-Key": "SCRUBME"},
+		Client: sensitiveClient{
+			Timeout: "10s",
+			Auth:    sensitiveAuth{Token: "REDACTME"},
+		},
+	}
+
+	t.Run("secrets", func(t *testing.T) {
+		bb, err := json.Marshal(&component.Info{Arguments: args})
+		require.NoError(t, err)
+		require.NotContains(t, string(bb), "SCRUBME")
+		require.Contains(t, string(bb), `"(secret)"`)
+		require.Contains(t, string(bb), "REDACTME")
+	})
+
+	t.Run("sensitive blocks", func(t *testing.T) {
+		bb, err := json.Marshal(&component.Info{
+			Arguments:       args,
+			SensitiveBlocks: []string{"client.auth"},
+		})
+		require.NoError(t, err)
+		require.NotContains(t, string(bb), "SCRUBME")
+		require.NotContains(t, string(bb), "REDACTME")
+
+		var actual struct {
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		require.NoError(t, json.Unmarshal(bb, &actual))
+
+		expect := `[
+			{"name": "url", "type": "attr", "value": {"type": "string", "value": "http://localhost:9009"}},
+			{"name": "password", "type": "attr", "value": {"type": "capsule", "value": "(secret)"}},
+			{"name": "headers", "type": "attr", "value": {"type": "object", "value": [
+				{"key": "X-Api-Key", "value": {"type": "capsule", "value": "(secret)"}}
+			]}},
+			{"name": "client", "type": "block", "body": [
+				{"name": "timeout", "type": "attr", "value": {"type": "string", "value": "10s"}},
+				{"name": "auth", "type": "block", "body": [
+					{"name": "token", "type": "attr", "value": {"type": "capsule", "value": "(secret)"}}
+				]}
+			]}
+		]`
+		require.JSONEq(t, expect, string(actual.Arguments))
+	})
+}
@@ -43,6 +43,7 @@ type Arguments struct {
 	NodeMeta     map[string]string `river:"node_meta,attr,optional"`
 
 	RefreshInterval  time.Duration           `river:"refresh_interval,attr,optional"`
+	SnapshotTargets  bool                    `river:"snapshot_targets,attr,optional"`
 	HTTPClientConfig config.HTTPClientConfig `river:",squash"`
 }
 
@@ -96,5 +97,7 @@ func New(opts component.Options, args Arguments) (*discovery.Component, error) {
 	return discovery.New(opts, args, func(args component.Arguments) (discovery.Discoverer, error) {
 		newArgs := args.(Arguments)
 		return prom_discovery.NewDiscovery(newArgs.Convert(), opts.Logger)
-	})
+	}, discovery.WithExportsSnapshot(discovery.DefaultSnapshotMaxAge, func(args component.Arguments) bool {
+		return args.(Arguments).SnapshotTargets
+	}))
 }
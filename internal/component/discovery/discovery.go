@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/logging/level"
 	"github.com/grafana/agent/internal/service/cluster"
 	"github.com/grafana/ckit/shard"
 	"github.com/prometheus/common/model"
@@ -110,16 +111,38 @@ type Component struct {
 	newDiscoverer chan struct{}
 
 	creator Creator
+
+	snapshotEnabled func(component.Arguments) bool // Nil if snapshots aren't supported.
+	snapshotMaxAge  time.Duration
+	snapshotting    bool                 // Whether the arguments enable snapshots. Guarded by discMut.
+	restored        []*targetgroup.Group // Restored groups until discovery converges. Guarded by discMut.
 }
 
 // New creates a discovery component given arguments and a concrete Discovery implementation function.
-func New(o component.Options, args component.Arguments, creator Creator) (*Component, error) {
+func New(o component.Options, args component.Arguments, creator Creator, opts ...Option) (*Component, error) {
 	c := &Component{
 		opts:    o,
 		creator: creator,
 		// buffered to avoid deadlock from the first immediate update
 		newDiscoverer: make(chan struct{}, 1),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.snapshotEnabled != nil && c.snapshotEnabled(args) {
+		// Failing to restore a snapshot only means that dependants wait for
+		// discovery, as they would without snapshots.
+		groups, err := c.restoreSnapshot(time.Now())
+		if err != nil {
+			level.Warn(o.Logger).Log("msg", "failed to restore targets snapshot", "err", err)
+		} else if len(groups) > 0 {
+			c.restored = groups
+			targets := groupsTargets(groups)
+			level.Info(o.Logger).Log("msg", "restored targets from snapshot until discovery converges", "targets", len(targets))
+			o.OnStateChange(Exports{Targets: targets})
+		}
+	}
 	return c, c.Update(args)
 }
 
@@ -156,8 +179,19 @@ func (c *Component) Update(args component.Arguments) error {
 	if err != nil {
 		return err
 	}
+	snapshotting := c.snapshotEnabled != nil && c.snapshotEnabled(args)
+	if c.snapshotEnabled != nil && !snapshotting {
+		if err := c.removeSnapshot(); err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to remove targets snapshot", "err", err)
+		}
+	}
+
 	c.discMut.Lock()
 	c.latestDisc = disc
+	c.snapshotting = snapshotting
+	if !snapshotting {
+		c.restored = nil
+	}
 	c.discMut.Unlock()
 
 	select {
@@ -175,8 +209,18 @@ var MaxUpdateFrequency = 5 * time.Second
 // runDiscovery is a utility for consuming and forwarding target groups from a discoverer.
 // It will handle collating targets (and clearing), as well as time based throttling of updates.
 func (c *Component) runDiscovery(ctx context.Context, d Discoverer) {
-	// all targets we have seen so far
+	c.discMut.Lock()
+	snapshotting, restored := c.snapshotting, c.restored
+	c.discMut.Unlock()
+
+	// all targets we have seen so far, starting with the restored ones
 	cache := map[string]*targetgroup.Group{}
+	// sources of restored groups which discovery didn't send again
+	restoredSources := map[string]struct{}{}
+	for _, group := range restored {
+		cache[group.Source] = group
+		restoredSources[group.Source] = struct{}{}
+	}
 
 	ch := make(chan []*targetgroup.Group)
 	go d.Run(ctx, ch)
@@ -185,37 +229,66 @@ func (c *Component) runDiscovery(ctx context.Context, d Discoverer) {
 	send := func() {
 		allTargets := []Target{}
 		for _, group := range cache {
-			for _, target := range group.Targets {
-				labels := map[string]string{}
-				// first add the group labels, and then the
-				// target labels, so that target labels take precedence.
-				for k, v := range group.Labels {
-					labels[string(k)] = string(v)
-				}
-				for k, v := range target {
-					labels[string(k)] = string(v)
-				}
-				allTargets = append(allTargets, labels)
-			}
+			allTargets = append(allTargets, groupTargets(group)...)
 		}
 		c.opts.OnStateChange(Exports{Targets: allTargets})
 	}
 
+	var (
+		start = time.Now()
+		// true once the discoverer sent target groups.
+		received = false
+		// true once discovery converged. Restored groups are dropped and
+		// snapshots are saved once discovery converged, so that a snapshot
+		// isn't overwritten by a discoverer stopped before converging.
+		converged = false
+		// true if the cache changed since the last snapshot.
+		dirty    = false
+		lastSave time.Time
+	)
+	save := func(now time.Time, force bool) {
+		if !snapshotting || !converged || !dirty || (!force && now.Sub(lastSave) < SnapshotFrequency) {
+			return
+		}
+		if err := c.saveSnapshot(cache, now); err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to save targets snapshot", "err", err)
+			return
+		}
+		lastSave, dirty = now, false
+	}
+
 	ticker := time.NewTicker(MaxUpdateFrequency)
 	// true if we have received new targets and need to send.
 	haveUpdates := false
 	for {
 		select {
-		case <-ticker.C:
+		case now := <-ticker.C:
+			// Discovery converged once a tick passes without updates after
+			// it sent target groups.
+			if !converged && ((received && !haveUpdates) || now.Sub(start) >= SnapshotConvergeTimeout) {
+				converged = true
+				for source := range restoredSources {
+					delete(cache, source)
+				}
+				c.discMut.Lock()
+				c.restored = nil
+				c.discMut.Unlock()
+				haveUpdates = haveUpdates || len(restoredSources) > 0
+				dirty = true
+			}
 			if haveUpdates {
 				send()
 				haveUpdates = false
 			}
+			save(now, false)
 		case <-ctx.Done():
 			send()
+			save(time.Now(), true)
 			return
 		case groups := <-ch:
+			received = true
 			for _, group := range groups {
+				delete(restoredSources, group.Source)
 				// Discoverer will send an empty target set to indicate the group (keyed by Source field)
 				// should be removed
 				if len(group.Targets) == 0 {
@@ -225,6 +298,34 @@ func (c *Component) runDiscovery(ctx context.Context, d Discoverer) {
 				}
 			}
 			haveUpdates = true
+			dirty = true
+		}
+	}
+}
+
+// groupsTargets returns the targets of groups.
+func groupsTargets(groups []*targetgroup.Group) []Target {
+	targets := []Target{}
+	for _, group := range groups {
+		targets = append(targets, groupTargets(group)...)
+	}
+	return targets
+}
+
+// groupTargets returns the targets of group, with the labels of the group.
+func groupTargets(group *targetgroup.Group) []Target {
+	targets := make([]Target, 0, len(group.Targets))
+	for _, target := range group.Targets {
+		labels := map[string]string{}
+		// first add the group labels, and then the
+		// target labels, so that target labels take precedence.
+		for k, v := range group.Labels {
+			labels[string(k)] = string(v)
+		}
+		for k, v := range target {
+			labels[string(k)] = string(v)
 		}
+		targets = append(targets, labels)
 	}
+	return targets
 }
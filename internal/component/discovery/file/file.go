@@ -16,6 +16,15 @@ func init() {
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
 		Exports:   discovery.Exports{},
+		Sandbox: component.SandboxExceptions{
+			ArgumentReadPaths: func(args component.Arguments) []string {
+				var paths []string
+				for _, pattern := range args.(Arguments).Files {
+					paths = append(paths, component.SandboxGlobDir(pattern))
+				}
+				return paths
+			},
+		},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
@@ -31,6 +31,7 @@ type Arguments struct {
 	NamespaceDiscovery NamespaceDiscovery      `river:"namespaces,block,optional"`
 	Selectors          []SelectorConfig        `river:"selectors,block,optional"`
 	AttachMetadata     AttachMetadataConfig    `river:"attach_metadata,block,optional"`
+	SnapshotTargets    bool                    `river:"snapshot_targets,attr,optional"`
 }
 
 // DefaultConfig holds defaults for SDConfig.
@@ -109,5 +110,7 @@ func New(opts component.Options, args Arguments) (*discovery.Component, error) {
 	return discovery.New(opts, args, func(args component.Arguments) (discovery.Discoverer, error) {
 		newArgs := args.(Arguments)
 		return promk8s.New(opts.Logger, newArgs.Convert())
-	})
+	}, discovery.WithExportsSnapshot(discovery.DefaultSnapshotMaxAge, func(args component.Arguments) bool {
+		return args.(Arguments).SnapshotTargets
+	}))
 }
@@ -3,6 +3,7 @@ package relabel
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/grafana/agent/internal/component"
 	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
@@ -19,6 +20,11 @@ func init() {
 		Args:      Arguments{},
 		Exports:   Exports{},
 
+		// Relabeling runs over every target whenever any upstream target
+		// changes, so churn from fast discovery is coalesced, like the
+		// Prometheus scrape manager does for its target updates.
+		MinEvaluationInterval: 5 * time.Second,
+
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
 		},
@@ -0,0 +1,148 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+)
+
+// SnapshotRestoredLabel is set to "true" on targets restored from a snapshot,
+// until discovery sends fresh targets. As a meta label, it's dropped before
+// targets are scraped.
+const SnapshotRestoredLabel = model.MetaLabelPrefix + "snapshot_restored"
+
+// DefaultSnapshotMaxAge is the maximum age of restored snapshots for
+// components which don't need a different one.
+const DefaultSnapshotMaxAge = 24 * time.Hour
+
+// SnapshotFrequency is the minimum time to wait between saving snapshots.
+// Snapshots are only saved when the targets changed since the last one.
+var SnapshotFrequency = time.Minute
+
+// SnapshotConvergeTimeout is the maximum time to wait for discovery to
+// converge before restored targets which discovery didn't send again are
+// dropped.
+var SnapshotConvergeTimeout = time.Minute
+
+// snapshotFilename is the name of the snapshot file in the data path of a
+// component.
+const snapshotFilename = "targets_snapshot.json"
+
+// snapshot is the content of a snapshot file.
+type snapshot struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Groups    []snapshotGroup `json:"groups"`
+}
+
+// snapshotGroup is a target group in a snapshot. Groups are saved rather
+// than targets so that restored groups are only replaced by fresh groups
+// from the same source.
+type snapshotGroup struct {
+	Source  string           `json:"source"`
+	Labels  model.LabelSet   `json:"labels,omitempty"`
+	Targets []model.LabelSet `json:"targets"`
+}
+
+// Option configures optional behaviour of a Component.
+type Option func(c *Component)
+
+// WithExportsSnapshot allows the component to persist its latest targets to
+// its data path, and to restore them when the component is created. This
+// allows components which depend on slowly-converging discovery to start
+// with the last known targets after a restart instead of none.
+//
+// enabled reports whether snapshots are enabled by the arguments of the
+// component. Restored targets are labeled with SnapshotRestoredLabel, and
+// are exported alongside fresh targets until discovery converges. Snapshots
+// older than maxAge aren't restored. Snapshots are disabled when the
+// component has no data path.
+func WithExportsSnapshot(maxAge time.Duration, enabled func(component.Arguments) bool) Option {
+	return func(c *Component) {
+		if c.opts.DataPath == "" {
+			return
+		}
+		c.snapshotEnabled = enabled
+		c.snapshotMaxAge = maxAge
+	}
+}
+
+// snapshotPath returns the path of the snapshot file of the component.
+func (c *Component) snapshotPath() string {
+	return filepath.Join(c.opts.DataPath, snapshotFilename)
+}
+
+// restoreSnapshot reads the snapshot of the component. It returns nil if
+// there's no snapshot or if it's older than maxAge.
+func (c *Component) restoreSnapshot(now time.Time) ([]*targetgroup.Group, error) {
+	buf, err := os.ReadFile(c.snapshotPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if now.Sub(s.Timestamp) > c.snapshotMaxAge {
+		return nil, nil
+	}
+
+	groups := make([]*targetgroup.Group, 0, len(s.Groups))
+	for _, g := range s.Groups {
+		labels := g.Labels.Clone()
+		if labels == nil {
+			labels = model.LabelSet{}
+		}
+		labels[SnapshotRestoredLabel] = "true"
+		groups = append(groups, &targetgroup.Group{Source: g.Source, Labels: labels, Targets: g.Targets})
+	}
+	return groups, nil
+}
+
+// saveSnapshot atomically writes the target groups of cache to the snapshot
+// file of the component.
+func (c *Component) saveSnapshot(cache map[string]*targetgroup.Group, now time.Time) error {
+	s := snapshot{Timestamp: now, Groups: make([]snapshotGroup, 0, len(cache))}
+	for _, g := range cache {
+		s.Groups = append(s.Groups, snapshotGroup{Source: g.Source, Labels: g.Labels, Targets: g.Targets})
+	}
+	buf, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.opts.DataPath, 0750); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.opts.DataPath, snapshotFilename+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.snapshotPath())
+}
+
+// removeSnapshot removes the snapshot file of the component, if any.
+func (c *Component) removeSnapshot() error {
+	err := os.Remove(c.snapshotPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
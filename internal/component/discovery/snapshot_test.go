@@ -0,0 +1,153 @@
+package discovery
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDiscoverer sends groups once, if any, and blocks until canceled.
+type fakeDiscoverer struct {
+	groups []*targetgroup.Group
+}
+
+func (d fakeDiscoverer) Run(ctx context.Context, ch chan<- []*targetgroup.Group) {
+	if len(d.groups) > 0 {
+		select {
+		case ch <- d.groups:
+		case <-ctx.Done():
+			return
+		}
+	}
+	<-ctx.Done()
+}
+
+// exportsRecorder records the latest exports of a component.
+type exportsRecorder struct {
+	mut     sync.Mutex
+	targets []Target
+	updates int
+}
+
+func (r *exportsRecorder) onStateChange(e component.Exports) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.targets = e.(Exports).Targets
+	r.updates++
+}
+
+func (r *exportsRecorder) get() ([]Target, int) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.targets, r.updates
+}
+
+func TestExportsSnapshot(t *testing.T) {
+	prevFrequency, prevTimeout := MaxUpdateFrequency, SnapshotConvergeTimeout
+	MaxUpdateFrequency, SnapshotConvergeTimeout = 10*time.Millisecond, time.Hour
+	defer func() { MaxUpdateFrequency, SnapshotConvergeTimeout = prevFrequency, prevTimeout }()
+
+	dataPath := t.TempDir()
+	newComponent := func(d fakeDiscoverer, r *exportsRecorder) *Component {
+		c, err := New(component.Options{
+			Logger:        util.TestFlowLogger(t),
+			DataPath:      dataPath,
+			OnStateChange: r.onStateChange,
+		}, nil, func(component.Arguments) (Discoverer, error) {
+			return d, nil
+		}, WithExportsSnapshot(time.Hour, func(component.Arguments) bool { return true }))
+		require.NoError(t, err)
+		return c
+	}
+
+	// There's nothing to restore on the first run.
+	var first exportsRecorder
+	c := newComponent(fakeDiscoverer{groups: []*targetgroup.Group{
+		{Source: "a", Targets: []model.LabelSet{{model.AddressLabel: "a:9090"}}},
+		{Source: "b", Targets: []model.LabelSet{{model.AddressLabel: "b:9090"}}},
+	}}, &first)
+	_, updates := first.get()
+	require.Equal(t, 0, updates)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { _ = c.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(c.snapshotPath())
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+
+	// The targets are restored on the next run, before discovery converges.
+	var second exportsRecorder
+	c = newComponent(fakeDiscoverer{groups: []*targetgroup.Group{
+		{Source: "a", Targets: []model.LabelSet{{model.AddressLabel: "a:9091"}}},
+	}}, &second)
+	targets, _ := second.get()
+	require.ElementsMatch(t, []Target{
+		{model.AddressLabel: "a:9090", SnapshotRestoredLabel: "true"},
+		{model.AddressLabel: "b:9090", SnapshotRestoredLabel: "true"},
+	}, targets)
+
+	// Restored groups are only replaced by fresh groups from the same source,
+	// until discovery converges.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = c.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		targets, _ := second.get()
+		return len(targets) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	targets, updates = second.get()
+	require.Equal(t, []Target{{model.AddressLabel: "a:9091"}}, targets)
+	require.Equal(t, 3, updates, "expected the restored, partial and converged targets to be exported")
+}
+
+func TestExportsSnapshot_Disabled(t *testing.T) {
+	dataPath := t.TempDir()
+	c := &Component{opts: component.Options{DataPath: dataPath}}
+	WithExportsSnapshot(time.Hour, func(component.Arguments) bool { return true })(c)
+	require.NoError(t, c.saveSnapshot(map[string]*targetgroup.Group{
+		"a": {Source: "a", Targets: []model.LabelSet{{model.AddressLabel: "a:9090"}}},
+	}, time.Now()))
+
+	// Disabling snapshots removes the existing snapshot, and no targets are
+	// restored.
+	var r exportsRecorder
+	c, err := New(component.Options{
+		Logger:        util.TestFlowLogger(t),
+		DataPath:      dataPath,
+		OnStateChange: r.onStateChange,
+	}, nil, func(component.Arguments) (Discoverer, error) {
+		return fakeDiscoverer{}, nil
+	}, WithExportsSnapshot(time.Hour, func(component.Arguments) bool { return false }))
+	require.NoError(t, err)
+	_, updates := r.get()
+	require.Equal(t, 0, updates)
+	require.NoFileExists(t, c.snapshotPath())
+}
+
+func TestExportsSnapshot_MaxAge(t *testing.T) {
+	c := &Component{opts: component.Options{DataPath: t.TempDir()}}
+	WithExportsSnapshot(time.Hour, func(component.Arguments) bool { return true })(c)
+
+	now := time.Now()
+	require.NoError(t, c.saveSnapshot(map[string]*targetgroup.Group{
+		"a": {Source: "a", Targets: []model.LabelSet{{model.AddressLabel: "a:9090"}}},
+	}, now))
+
+	groups, err := c.restoreSnapshot(now.Add(30 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	groups, err = c.restoreSnapshot(now.Add(2 * time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, groups)
+}
@@ -0,0 +1,167 @@
+// Package write_file implements the discovery.write_file component.
+package write_file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/prometheus/common/model"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "discovery.write_file",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   nil,
+		Sandbox: component.SandboxExceptions{
+			// The file is replaced by renaming a temporary file created next
+			// to it, so its whole directory must be writable.
+			ArgumentWritePaths: func(args component.Arguments) []string {
+				return []string{filepath.Dir(args.(Arguments).Filename)}
+			},
+		},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the discovery.write_file
+// component.
+type Arguments struct {
+	// Targets to write to the file.
+	Targets []discovery.Target `river:"targets,attr"`
+
+	// Path of the file to write.
+	Filename string `river:"filename,attr"`
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Filename == "" {
+		return fmt.Errorf("filename must not be empty")
+	}
+	return nil
+}
+
+// targetGroup is a target group in the Prometheus file_sd format.
+type targetGroup struct {
+	Targets []string       `json:"targets"`
+	Labels  model.LabelSet `json:"labels,omitempty"`
+}
+
+// Component implements the discovery.write_file component.
+type Component struct {
+	opts component.Options
+
+	mut      sync.Mutex
+	filename string
+	written  []byte // Content of the latest successful write to filename.
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new discovery.write_file component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{opts: o}
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements component.Component. The file is only written when its
+// content changes.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+
+	content, err := marshalTargets(newArgs.Targets)
+	if err != nil {
+		return err
+	}
+	if newArgs.Filename == c.filename && bytes.Equal(content, c.written) {
+		return nil
+	}
+
+	if err := writeFile(newArgs.Filename, content); err != nil {
+		return fmt.Errorf("failed to write targets to %s: %w", newArgs.Filename, err)
+	}
+	c.filename, c.written = newArgs.Filename, content
+	return nil
+}
+
+// marshalTargets encodes targets in the Prometheus file_sd JSON format, with
+// one target group per target. The address of the target is taken from its
+// __address__ label, and targets without one are skipped. Groups are sorted
+// so that the same targets are always encoded in the same way.
+func marshalTargets(targets []discovery.Target) ([]byte, error) {
+	groups := make([]targetGroup, 0, len(targets))
+	for _, t := range targets {
+		addr, ok := t[model.AddressLabel]
+		if !ok {
+			continue
+		}
+
+		group := targetGroup{Targets: []string{addr}}
+		for name, value := range t {
+			if name == model.AddressLabel {
+				continue
+			}
+			if group.Labels == nil {
+				group.Labels = make(model.LabelSet, len(t)-1)
+			}
+			group.Labels[model.LabelName(name)] = model.LabelValue(value)
+		}
+		groups = append(groups, group)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Targets[0] != groups[j].Targets[0] {
+			return groups[i].Targets[0] < groups[j].Targets[0]
+		}
+		return groups[i].Labels.Before(groups[j].Labels)
+	})
+	return json.MarshalIndent(groups, "", "  ")
+}
+
+// writeFile atomically writes content to filename, so that readers never see
+// a partially written file.
+func writeFile(filename string, content []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
@@ -0,0 +1,77 @@
+package write_file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshal(t *testing.T) {
+	cfg := `
+	targets  = [{"__address__" = "localhost:9090"}]
+	filename = "/tmp/targets.json"`
+
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+	require.Equal(t, "/tmp/targets.json", args.Filename)
+
+	cfg = `
+	targets  = []
+	filename = ""`
+	require.ErrorContains(t, river.Unmarshal([]byte(cfg), &args), "filename must not be empty")
+}
+
+func TestWriteFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "targets.json")
+
+	args := Arguments{
+		Targets: []discovery.Target{
+			{"__address__": "b:9090", "job": "b"},
+			{"__address__": "a:9090", "job": "a", "env": "prod"},
+			{"job": "no-address"},
+			{"__address__": "c:9090"},
+		},
+		Filename: filename,
+	}
+	c, err := New(component.Options{Logger: util.TestFlowLogger(t)}, args)
+	require.NoError(t, err)
+
+	expect := `[
+		{"targets": ["a:9090"], "labels": {"env": "prod", "job": "a"}},
+		{"targets": ["b:9090"], "labels": {"job": "b"}},
+		{"targets": ["c:9090"]}
+	]`
+	actual, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.JSONEq(t, expect, string(actual))
+
+	// The file isn't written again when the targets don't change.
+	require.NoError(t, os.Remove(filename))
+	require.NoError(t, c.Update(args))
+	_, err = os.Stat(filename)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	args.Targets = args.Targets[:1]
+	require.NoError(t, c.Update(args))
+	actual, err = os.ReadFile(filename)
+	require.NoError(t, err)
+	require.JSONEq(t, `[{"targets": ["b:9090"], "labels": {"job": "b"}}]`, string(actual))
+
+	// No temporary file is left behind.
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestWriteFile_MissingDirectory(t *testing.T) {
+	_, err := New(component.Options{Logger: util.TestFlowLogger(t)}, Arguments{
+		Filename: filepath.Join(t.TempDir(), "missing", "targets.json"),
+	})
+	require.ErrorContains(t, err, "failed to write targets")
+}
@@ -18,6 +18,15 @@ func init() {
 		Name:      "faro.receiver",
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
+		Sandbox: component.SandboxExceptions{
+			ArgumentReadPaths: func(args component.Arguments) []string {
+				var paths []string
+				for _, location := range args.(Arguments).SourceMaps.Locations {
+					paths = append(paths, location.Path)
+				}
+				return paths
+			},
+		},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
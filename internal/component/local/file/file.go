@@ -30,13 +30,30 @@ func init() {
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
 		Exports:   Exports{},
+		Sandbox: component.SandboxExceptions{
+			ArgumentReadPaths: func(args component.Arguments) []string {
+				return []string{args.(Arguments).Filename}
+			},
+		},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
 		},
+		CheckSecrets: func(_ context.Context, args component.Arguments) error {
+			return checkFile(args.(Arguments).Filename)
+		},
 	})
 }
 
+// checkFile ensures that filename exists and can be opened for reading.
+func checkFile(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 // Arguments holds values which are used to configure the local.file component.
 type Arguments struct {
 	// Filename indicates the file to watch.
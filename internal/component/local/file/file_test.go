@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/component/local/file"
 	filedetector "github.com/grafana/agent/internal/filedetector"
 	"github.com/grafana/agent/internal/flow/componenttest"
@@ -134,6 +135,22 @@ func TestFile_ExistOnLoad(t *testing.T) {
 	require.ErrorAs(t, err, &expectErr)
 }
 
+// TestFile_CheckSecrets ensures that the secret check of local.file fails
+// until the configured file exists.
+func TestFile_CheckSecrets(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "testfile")
+
+	reg, ok := component.Get("local.file")
+	require.True(t, ok)
+	require.NotNil(t, reg.CheckSecrets)
+
+	err := reg.CheckSecrets(context.Background(), file.Arguments{Filename: testFile})
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(testFile, []byte("secret"), 0600))
+	require.NoError(t, reg.CheckSecrets(context.Background(), file.Arguments{Filename: testFile}))
+}
+
 // canceledContext creates a context which is already canceled.
 func canceledContext() context.Context {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -17,6 +17,18 @@ func init() {
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
 		Exports:   discovery.Exports{},
+		Sandbox: component.SandboxExceptions{
+			ArgumentReadPaths: func(args component.Arguments) []string {
+				var paths []string
+				for _, target := range args.(Arguments).PathTargets {
+					if pattern := target["__path__"]; pattern != "" {
+						paths = append(paths, component.SandboxGlobDir(pattern))
+					}
+				}
+				return paths
+			},
+		},
+
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
 		},
@@ -0,0 +1,38 @@
+package echo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEcho_ReleasesDeliveryTokens(t *testing.T) {
+	c, err := New(component.Options{
+		ID:            "loki.echo.test",
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {},
+	}, DefaultArguments)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { require.NoError(t, c.Run(ctx)) }()
+
+	delivered := make(chan struct{})
+	c.receiver.Chan() <- loki.Entry{
+		Entry: logproto.Entry{Timestamp: time.Now(), Line: "hello"},
+		Token: loki.NewDeliveryToken(func() { close(delivered) }),
+	}
+
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "entry wasn't delivered")
+	}
+}
@@ -25,6 +25,18 @@ func init() {
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
 		Exports:   Exports{},
+		Sandbox: component.SandboxExceptions{
+			// Databases of geoip stages.
+			ArgumentReadPaths: func(args component.Arguments) []string {
+				var paths []string
+				for _, stage := range args.(Arguments).Stages {
+					if stage.GeoIPConfig != nil {
+						paths = append(paths, stage.GeoIPConfig.DB)
+					}
+				}
+				return paths
+			},
+		},
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
 		},
@@ -168,13 +180,8 @@ func (c *Component) handleOut(ctx context.Context, wg *sync.WaitGroup) {
 			c.fanoutMut.RLock()
 			fanout := c.fanout
 			c.fanoutMut.RUnlock()
-			for _, f := range fanout {
-				select {
-				case <-ctx.Done():
-					return
-				case f.Chan() <- entry:
-					// no-op
-				}
+			if err := loki.SendTo(ctx, entry, fanout); err != nil {
+				return
 			}
 		}
 	}
@@ -109,6 +109,7 @@ func (m *dropStage) Run(in chan Entry) chan Entry {
 				continue
 			}
 			m.dropCount.WithLabelValues(m.cfg.DropReason).Inc()
+			e.Token.Done()
 		}
 	}()
 	return out
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component/common/loki"
 	"github.com/grafana/agent/internal/flow/logging/level"
 	"github.com/grafana/river"
 	"github.com/prometheus/client_golang/prometheus"
@@ -131,6 +132,7 @@ func (c *cri) Run(entry chan Entry) chan Entry {
 				builder.WriteString(prev.Line)
 				builder.WriteString(e.Line)
 				e.Line = builder.String()
+				e.Token = loki.JoinDeliveryTokens(prev.Token, e.Token)
 			}
 			c.ensureTruncateIfRequired(&e)
 			c.partialLines[fingerprint] = e
@@ -147,6 +149,7 @@ func (c *cri) Run(entry chan Entry) chan Entry {
 			builder.WriteString(prev.Line)
 			builder.WriteString(e.Line)
 			e.Line = builder.String()
+			e.Token = loki.JoinDeliveryTokens(prev.Token, e.Token)
 			c.ensureTruncateIfRequired(&e)
 			delete(c.partialLines, fingerprint)
 		}
@@ -94,6 +94,7 @@ func (m *limitStage) Run(in chan Entry) chan Entry {
 				out <- e
 				continue
 			}
+			e.Token.Done()
 		}
 	}()
 	return out
@@ -174,6 +174,7 @@ func (m *matcherStage) runDrop(in chan Entry) chan Entry {
 				continue
 			}
 			m.dropCount.WithLabelValues(m.dropReason).Inc()
+			e.Token.Done()
 		}
 	}()
 	return out
@@ -75,6 +75,8 @@ type multilineState struct {
 	buffer         *bytes.Buffer // The lines of the current multiline block.
 	startLineEntry Entry         // The entry of the start line of a multiline block.
 	currentLines   uint64        // The number of lines of the current multiline block.
+
+	tokens []*loki.DeliveryToken // The delivery tokens of the lines of the current multiline block.
 }
 
 // newMultilineStage creates a MulitlineStage from config
@@ -167,6 +169,7 @@ func (m *multilineStage) runMultiline(in chan Entry, out chan Entry, wg *sync.Wa
 			}
 			state.buffer.WriteString(e.Line)
 			state.currentLines++
+			state.tokens = append(state.tokens, e.Token)
 
 			if state.currentLines == m.cfg.MaxLines {
 				m.flush(out, state)
@@ -193,10 +196,12 @@ func (m *multilineStage) flush(out chan Entry, s *multilineState) {
 				Timestamp: s.startLineEntry.Entry.Entry.Timestamp,
 				Line:      s.buffer.String(),
 			},
+			Token: loki.JoinDeliveryTokens(s.tokens...),
 		},
 	}
 	s.buffer.Reset()
 	s.currentLines = 0
+	s.tokens = nil
 
 	out <- collapsed
 }
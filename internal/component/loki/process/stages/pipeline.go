@@ -159,6 +159,7 @@ func (p *Pipeline) Wrap(next loki.EntryHandler) loki.EntryHandler {
 				if rateLimiterDrop {
 					if !rateLimiter.Allow() {
 						p.dropCount.WithLabelValues(rateLimiterDropReason).Inc()
+						e.Token.Done()
 						continue
 					}
 				} else {
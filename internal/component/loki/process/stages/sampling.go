@@ -74,6 +74,7 @@ func (m *samplingStage) Run(in chan Entry) chan Entry {
 				continue
 			}
 			m.dropCount.WithLabelValues(*m.cfg.DropReason).Inc()
+			e.Token.Done()
 		}
 	}()
 	return out
@@ -63,10 +63,10 @@ type Component struct {
 	opts    component.Options
 	metrics *metrics
 
-	mut      sync.RWMutex
-	rcs      []*relabel.Config
-	receiver loki.LogsReceiver
-	fanout   []loki.LogsReceiver
+	mut         sync.RWMutex
+	rcs         []*relabel.Config
+	receiver    loki.LogsReceiver
+	interceptor *loki.Interceptor
 
 	cache        *lru.Cache
 	maxCacheSize int
@@ -92,7 +92,8 @@ func New(o component.Options, args Arguments) (*Component, error) {
 
 	// Create and immediately export the receiver which remains the same for
 	// the component's lifetime.
-	c.receiver = loki.NewLogsReceiver()
+	c.interceptor = loki.NewInterceptor(args.ForwardTo, loki.WithEntryHook(c.intercept))
+	c.receiver = c.interceptor.Receiver()
 	o.OnStateChange(Exports{Receiver: c.receiver, Rules: args.RelabelConfigs})
 
 	// Call to Update() to set the relabelling rules once at the start.
@@ -105,29 +106,22 @@ func New(o component.Options, args Arguments) (*Component, error) {
 
 // Run implements component.Component.
 func (c *Component) Run(ctx context.Context) error {
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		case entry := <-c.receiver.Chan():
-			c.metrics.entriesProcessed.Inc()
-			lbls := c.relabel(entry)
-			if len(lbls) == 0 {
-				level.Debug(c.opts.Logger).Log("msg", "dropping entry after relabeling", "labels", entry.Labels.String())
-				continue
-			}
+	return c.interceptor.Run(ctx)
+}
 
-			c.metrics.entriesOutgoing.Inc()
-			entry.Labels = lbls
-			for _, f := range c.fanout {
-				select {
-				case <-ctx.Done():
-					return nil
-				case f.Chan() <- entry:
-				}
-			}
-		}
+// intercept relabels entry, dropping it if relabeling removed all of its
+// labels.
+func (c *Component) intercept(entry loki.Entry) (loki.Entry, bool) {
+	c.metrics.entriesProcessed.Inc()
+	lbls := c.relabel(entry)
+	if len(lbls) == 0 {
+		level.Debug(c.opts.Logger).Log("msg", "dropping entry after relabeling", "labels", entry.Labels.String())
+		return entry, false
 	}
+
+	c.metrics.entriesOutgoing.Inc()
+	entry.Labels = lbls
+	return entry, true
 }
 
 // Update implements component.Component.
@@ -149,7 +143,7 @@ func (c *Component) Update(args component.Arguments) error {
 		}
 	}
 	c.rcs = newRCS
-	c.fanout = newArgs.ForwardTo
+	c.interceptor.UpdateForwardTo(newArgs.ForwardTo)
 
 	c.opts.OnStateChange(Exports{Receiver: c.receiver, Rules: newArgs.RelabelConfigs})
 
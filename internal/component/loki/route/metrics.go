@@ -0,0 +1,34 @@
+package route
+
+import (
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	entriesProcessed prometheus_client.Counter
+	entriesRouted    *prometheus_client.CounterVec
+}
+
+// newMetrics creates a new set of metrics. If reg is non-nil, the metrics
+// will also be registered.
+func newMetrics(reg prometheus_client.Registerer) *metrics {
+	var m metrics
+
+	m.entriesProcessed = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "loki_route_entries_processed",
+		Help: "Total number of log entries processed",
+	})
+	m.entriesRouted = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+		Name: "loki_route_entries_routed_total",
+		Help: "Total number of log entries forwarded by each route",
+	}, []string{"route"})
+
+	if reg != nil {
+		reg.MustRegister(
+			m.entriesProcessed,
+			m.entriesRouted,
+		)
+	}
+
+	return &m
+}
@@ -0,0 +1,276 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.route",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Supported routing modes.
+const (
+	// ModeFirstMatch sends an entry only to the first matching route at each
+	// level of the routing tree.
+	ModeFirstMatch = "first_match"
+	// ModeAllMatches sends an entry to every matching route at each level of
+	// the routing tree.
+	ModeAllMatches = "all_matches"
+)
+
+// DefaultRouteName is the name used for the root of the routing tree in
+// metrics.
+const DefaultRouteName = "default"
+
+// Arguments holds values which are used to configure the loki.route
+// component.
+type Arguments struct {
+	// How sibling routes are evaluated.
+	Mode string `river:"mode,attr,optional"`
+
+	// Where log entries which don't match any route are forwarded to.
+	DefaultForwardTo []loki.LogsReceiver `river:"default_forward_to,attr,optional"`
+
+	Routes []Route `river:"route,block,optional"`
+}
+
+// Route is a node in the routing tree. An entry is sent to the first (or
+// every, depending on the mode) matching child route; if no child route
+// matches, the entry is sent to ForwardTo.
+type Route struct {
+	Name      string              `river:",label"`
+	Matchers  []string            `river:"matchers,attr,optional"`
+	LineRegex string              `river:"line_regex,attr,optional"`
+	ForwardTo []loki.LogsReceiver `river:"forward_to,attr,optional"`
+	Routes    []Route             `river:"route,block,optional"`
+}
+
+// DefaultArguments provides the default arguments for the loki.route
+// component.
+var DefaultArguments = Arguments{
+	Mode: ModeFirstMatch,
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	switch a.Mode {
+	case ModeFirstMatch, ModeAllMatches:
+	default:
+		return fmt.Errorf("unsupported mode %q, must be one of %q or %q", a.Mode, ModeFirstMatch, ModeAllMatches)
+	}
+
+	names := map[string]struct{}{DefaultRouteName: {}}
+	_, err := compile(a.Routes, names)
+	return err
+}
+
+// Exports holds values which are exported by the loki.route component.
+type Exports struct {
+	Receiver loki.LogsReceiver `river:"receiver,attr"`
+}
+
+// Component implements the loki.route component.
+type Component struct {
+	opts     component.Options
+	metrics  *metrics
+	receiver loki.LogsReceiver
+
+	mut        sync.RWMutex
+	root       *node
+	firstMatch bool
+}
+
+// node is a compiled node of the routing tree.
+type node struct {
+	name      string
+	matchers  []*labels.Matcher
+	lineRegex *regexp.Regexp
+	forwardTo []loki.LogsReceiver
+	children  []*node
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+)
+
+// New creates a new loki.route component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		metrics: newMetrics(o.Registerer),
+	}
+
+	// Create and immediately export the receiver which remains the same for
+	// the component's lifetime.
+	c.receiver = loki.NewLogsReceiver()
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.receiver.Chan():
+			c.metrics.entriesProcessed.Inc()
+
+			var forwardTo []loki.LogsReceiver
+			for _, n := range c.route(entry) {
+				c.metrics.entriesRouted.WithLabelValues(n.name).Inc()
+				forwardTo = append(forwardTo, n.forwardTo...)
+			}
+			if err := loki.SendTo(ctx, entry, forwardTo); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	names := map[string]struct{}{DefaultRouteName: {}}
+	children, err := compile(newArgs.Routes, names)
+	if err != nil {
+		return err
+	}
+	root := &node{
+		name:      DefaultRouteName,
+		forwardTo: newArgs.DefaultForwardTo,
+		children:  children,
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.root != nil {
+		c.root.walk(func(n *node) {
+			if _, ok := names[n.name]; !ok {
+				c.metrics.entriesRouted.DeleteLabelValues(n.name)
+			}
+		})
+	}
+	c.root = root
+	c.firstMatch = newArgs.Mode != ModeAllMatches
+	return nil
+}
+
+// route returns the routes the entry should be sent through.
+func (c *Component) route(e loki.Entry) []*node {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	return c.root.match(e, c.firstMatch, nil)
+}
+
+// compile builds the nodes of the routing tree for routes, recording the
+// names of all routes in names.
+func compile(routes []Route, names map[string]struct{}) ([]*node, error) {
+	nodes := make([]*node, 0, len(routes))
+	for _, r := range routes {
+		if _, ok := names[r.Name]; ok {
+			if r.Name == DefaultRouteName {
+				return nil, fmt.Errorf("route name %q is reserved", DefaultRouteName)
+			}
+			return nil, fmt.Errorf("route %q is defined more than once", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		if len(r.Matchers) == 0 && r.LineRegex == "" {
+			return nil, fmt.Errorf("route %q must have at least one matcher or a line_regex", r.Name)
+		}
+
+		n := &node{name: r.Name, forwardTo: r.ForwardTo}
+		for _, s := range r.Matchers {
+			ms, err := parser.ParseMetricSelector("{" + strings.TrimSpace(s) + "}")
+			if err != nil {
+				return nil, fmt.Errorf("route %q: invalid matcher %q: %w", r.Name, s, err)
+			}
+			n.matchers = append(n.matchers, ms...)
+		}
+		if r.LineRegex != "" {
+			re, err := regexp.Compile(r.LineRegex)
+			if err != nil {
+				return nil, fmt.Errorf("route %q: invalid line_regex: %w", r.Name, err)
+			}
+			n.lineRegex = re
+		}
+
+		children, err := compile(r.Routes, names)
+		if err != nil {
+			return nil, err
+		}
+		n.children = children
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// matches returns true if the entry satisfies all matchers and the line
+// regex of n.
+func (n *node) matches(e loki.Entry) bool {
+	for _, m := range n.matchers {
+		if !m.Matches(string(e.Labels[model.LabelName(m.Name)])) {
+			return false
+		}
+	}
+	return n.lineRegex == nil || n.lineRegex.MatchString(e.Line)
+}
+
+// match appends the routes the entry is sent through. The entry is assumed to
+// match n itself.
+func (n *node) match(e loki.Entry, firstMatch bool, res []*node) []*node {
+	matched := false
+	for _, child := range n.children {
+		if !child.matches(e) {
+			continue
+		}
+		matched = true
+		res = child.match(e, firstMatch, res)
+		if firstMatch {
+			break
+		}
+	}
+	if !matched {
+		res = append(res, n)
+	}
+	return res
+}
+
+// walk calls f for n and every node below it.
+func (n *node) walk(f func(*node)) {
+	f(n)
+	for _, child := range n.children {
+		child.walk(f)
+	}
+}
@@ -0,0 +1,128 @@
+package route
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouting(t *testing.T) {
+	entry := func(team, line string) loki.Entry {
+		return loki.Entry{
+			Labels: model.LabelSet{"team": model.LabelValue(team)},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: line},
+		}
+	}
+
+	tests := []struct {
+		mode   string
+		entry  loki.Entry
+		expect []string
+	}{
+		{ModeFirstMatch, entry("a", "level=error"), []string{"a_errors"}},
+		{ModeFirstMatch, entry("a", "level=info"), []string{"a"}},
+		{ModeFirstMatch, entry("b", "level=error"), []string{"errors"}},
+		{ModeFirstMatch, entry("b", "level=info"), []string{"default"}},
+		{ModeAllMatches, entry("a", "level=error"), []string{"a_errors", "errors"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.mode+"/"+tc.entry.Line, func(t *testing.T) {
+			c, err := New(component.Options{
+				Logger:        util.TestFlowLogger(t),
+				Registerer:    prometheus.NewRegistry(),
+				OnStateChange: func(e component.Exports) {},
+			}, Arguments{
+				Mode: tc.mode,
+				Routes: []Route{
+					{
+						Name:     "a",
+						Matchers: []string{`team="a"`},
+						Routes: []Route{
+							{Name: "a_errors", LineRegex: "level=error"},
+						},
+					},
+					{Name: "errors", LineRegex: "level=error"},
+				},
+			})
+			require.NoError(t, err)
+
+			var names []string
+			for _, n := range c.route(tc.entry) {
+				names = append(names, n.name)
+			}
+			require.Equal(t, tc.expect, names)
+		})
+	}
+}
+
+func TestForwarding(t *testing.T) {
+	errors, fallback := loki.NewLogsReceiver(), loki.NewLogsReceiver()
+
+	c, err := New(component.Options{
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}, Arguments{
+		Mode:             ModeFirstMatch,
+		DefaultForwardTo: []loki.LogsReceiver{fallback},
+		Routes: []Route{
+			{Name: "errors", LineRegex: "error", ForwardTo: []loki.LogsReceiver{errors}},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	for _, tc := range []struct {
+		line string
+		ch   loki.LogsReceiver
+	}{
+		{"an error happened", errors},
+		{"all good", fallback},
+	} {
+		c.receiver.Chan() <- loki.Entry{
+			Labels: model.LabelSet{"job": "test"},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: tc.line},
+		}
+		select {
+		case e := <-tc.ch.Chan():
+			require.Equal(t, tc.line, e.Line)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "failed waiting for log entry")
+		}
+	}
+}
+
+func TestRiverConfig(t *testing.T) {
+	cfg := `
+		default_forward_to = []
+
+		route "a" {
+			matchers   = ["team=\"a\""]
+			line_regex = "error"
+			forward_to = []
+		}
+	`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+	require.Equal(t, ModeFirstMatch, args.Mode)
+
+	cfg = `
+		route "a" {
+			forward_to = []
+		}
+	`
+	require.ErrorContains(t, river.Unmarshal([]byte(cfg), &args), `route "a" must have at least one matcher or a line_regex`)
+}
@@ -0,0 +1,46 @@
+package sample
+
+import (
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	entriesProcessed prometheus_client.Counter
+	entriesSampled   prometheus_client.Counter
+	entriesDropped   *prometheus_client.CounterVec
+	streams          prometheus_client.Gauge
+}
+
+// newMetrics creates a new set of metrics. If reg is non-nil, the metrics
+// will also be registered.
+func newMetrics(reg prometheus_client.Registerer) *metrics {
+	var m metrics
+
+	m.entriesProcessed = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "loki_sample_entries_processed",
+		Help: "Total number of log entries processed",
+	})
+	m.entriesSampled = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "loki_sample_entries_sampled_total",
+		Help: "Total number of log entries kept by sampling and forwarded",
+	})
+	m.entriesDropped = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+		Name: "loki_sample_entries_dropped_total",
+		Help: "Total number of log entries dropped by sampling",
+	}, []string{"reason"})
+	m.streams = prometheus_client.NewGauge(prometheus_client.GaugeOpts{
+		Name: "loki_sample_rate_limited_streams",
+		Help: "Number of streams currently tracked for rate limiting",
+	})
+
+	if reg != nil {
+		reg.MustRegister(
+			m.entriesProcessed,
+			m.entriesSampled,
+			m.entriesDropped,
+			m.streams,
+		)
+	}
+
+	return &m
+}
@@ -0,0 +1,264 @@
+package sample
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/featuregate"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/prometheus/common/model"
+	"golang.org/x/time/rate"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.sample",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Reasons an entry may be dropped, used as the value of the reason label of
+// the dropped entries metric.
+const (
+	reasonProbabilistic = "probabilistic"
+	reasonRateLimit     = "rate_limit"
+)
+
+// Arguments holds values which are used to configure the loki.sample
+// component.
+type Arguments struct {
+	// Where sampled log entries are forwarded to.
+	ForwardTo []loki.LogsReceiver `river:"forward_to,attr"`
+
+	// The fraction of log entries to keep, between 0 and 1.
+	Rate float64 `river:"rate,attr,optional"`
+
+	// If set, entries with the same value of this label are always either
+	// kept or dropped together.
+	HashLabel string `river:"hash_label,attr,optional"`
+
+	// If set, entries whose line has the same value for the first capture
+	// group of this regex are always either kept or dropped together.
+	HashRegex string `river:"hash_regex,attr,optional"`
+
+	// The maximum number of entries per second forwarded for each stream. 0
+	// disables rate-based sampling.
+	MaxPerSecond float64 `river:"max_per_second,attr,optional"`
+
+	// The number of entries which may exceed MaxPerSecond in a burst.
+	Burst int `river:"burst,attr,optional"`
+
+	// The maximum number of streams to track for rate-based sampling.
+	MaxStreams int `river:"max_streams,attr,optional"`
+}
+
+// DefaultArguments provides the default arguments for the loki.sample
+// component.
+var DefaultArguments = Arguments{
+	Rate:       1,
+	MaxStreams: 10_000,
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.Rate < 0 || a.Rate > 1 {
+		return fmt.Errorf("rate must be between 0 and 1, got %v", a.Rate)
+	}
+	if a.HashLabel != "" && a.HashRegex != "" {
+		return fmt.Errorf("only one of hash_label and hash_regex may be set")
+	}
+	if a.HashRegex != "" {
+		re, err := regexp.Compile(a.HashRegex)
+		if err != nil {
+			return fmt.Errorf("invalid hash_regex: %w", err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("hash_regex must have a capture group")
+		}
+	}
+	if a.MaxPerSecond < 0 {
+		return fmt.Errorf("max_per_second must not be negative")
+	}
+	if a.Burst < 0 {
+		return fmt.Errorf("burst must not be negative")
+	}
+	if a.MaxStreams <= 0 {
+		return fmt.Errorf("max_streams must be greater than 0")
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the loki.sample component.
+type Exports struct {
+	Receiver loki.LogsReceiver `river:"receiver,attr"`
+}
+
+// Component implements the loki.sample component.
+type Component struct {
+	opts     component.Options
+	metrics  *metrics
+	receiver loki.LogsReceiver
+
+	mut       sync.RWMutex
+	args      Arguments
+	hashRegex *regexp.Regexp
+	fanout    []loki.LogsReceiver
+
+	// limiters holds the rate limiter of each stream, keyed by the
+	// fingerprint of the stream's labels.
+	limiters *lru.Cache[model.Fingerprint, *rate.Limiter]
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+)
+
+// New creates a new loki.sample component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		metrics: newMetrics(o.Registerer),
+	}
+
+	// Create and immediately export the receiver which remains the same for
+	// the component's lifetime.
+	c.receiver = loki.NewLogsReceiver()
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.receiver.Chan():
+			c.metrics.entriesProcessed.Inc()
+
+			keep, fanout := c.sample(entry)
+			if !keep {
+				entry.Token.Done()
+				continue
+			}
+
+			c.metrics.entriesSampled.Inc()
+			if err := loki.SendTo(ctx, entry, fanout); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	var hashRegex *regexp.Regexp
+	if newArgs.HashRegex != "" {
+		var err error
+		if hashRegex, err = regexp.Compile(newArgs.HashRegex); err != nil {
+			return err
+		}
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	// Rate limiters are recreated whenever the rate-based settings change,
+	// as every existing limiter would otherwise need to be updated.
+	if c.limiters == nil || c.args.MaxPerSecond != newArgs.MaxPerSecond || c.args.Burst != newArgs.Burst || c.args.MaxStreams != newArgs.MaxStreams {
+		limiters, err := lru.New[model.Fingerprint, *rate.Limiter](newArgs.MaxStreams)
+		if err != nil {
+			return err
+		}
+		c.limiters = limiters
+		c.metrics.streams.Set(0)
+	}
+
+	c.args = newArgs
+	c.hashRegex = hashRegex
+	c.fanout = newArgs.ForwardTo
+	return nil
+}
+
+// sample reports whether the entry should be kept, along with the receivers
+// to forward it to.
+func (c *Component) sample(e loki.Entry) (bool, []loki.LogsReceiver) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if !c.keepProbabilistic(e) {
+		c.metrics.entriesDropped.WithLabelValues(reasonProbabilistic).Inc()
+		return false, nil
+	}
+	if c.args.MaxPerSecond > 0 && !c.limiter(e.Labels).Allow() {
+		c.metrics.entriesDropped.WithLabelValues(reasonRateLimit).Inc()
+		return false, nil
+	}
+	return true, c.fanout
+}
+
+// keepProbabilistic reports whether the entry is kept by probabilistic
+// sampling. When a hash key can be extracted from the entry, the decision is
+// deterministic for that key.
+func (c *Component) keepProbabilistic(e loki.Entry) bool {
+	switch c.args.Rate {
+	case 1:
+		return true
+	case 0:
+		return false
+	}
+
+	var key string
+	switch {
+	case c.args.HashLabel != "":
+		key = string(e.Labels[model.LabelName(c.args.HashLabel)])
+	case c.hashRegex != nil:
+		if m := c.hashRegex.FindStringSubmatch(e.Line); len(m) > 1 {
+			key = m[1]
+		}
+	}
+	if key == "" {
+		return rand.Float64() < c.args.Rate
+	}
+	return float64(xxhash.Sum64String(key)) < c.args.Rate*math.MaxUint64
+}
+
+// limiter returns the rate limiter for the stream with the given labels.
+func (c *Component) limiter(lbls model.LabelSet) *rate.Limiter {
+	fp := lbls.Fingerprint()
+	if l, ok := c.limiters.Get(fp); ok {
+		return l
+	}
+
+	burst := c.args.Burst
+	if burst == 0 {
+		burst = int(math.Max(1, math.Ceil(c.args.MaxPerSecond)))
+	}
+	l := rate.NewLimiter(rate.Limit(c.args.MaxPerSecond), burst)
+	c.limiters.Add(fp, l)
+	c.metrics.streams.Set(float64(c.limiters.Len()))
+	return l
+}
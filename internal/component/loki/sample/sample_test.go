@@ -0,0 +1,102 @@
+package sample
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestComponent(t *testing.T, args Arguments) *Component {
+	t.Helper()
+	c, err := New(component.Options{
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+	return c
+}
+
+func newEntry(lbls model.LabelSet, line string) loki.Entry {
+	return loki.Entry{
+		Labels: lbls,
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: line},
+	}
+}
+
+func TestProbabilistic(t *testing.T) {
+	args := DefaultArguments
+	args.Rate = 0.25
+	c := newTestComponent(t, args)
+
+	kept := 0
+	for i := 0; i < 10_000; i++ {
+		if keep, _ := c.sample(newEntry(model.LabelSet{"job": "test"}, "line")); keep {
+			kept++
+		}
+	}
+	require.InDelta(t, 2_500, kept, 500)
+	require.Equal(t, float64(10_000-kept), testutil.ToFloat64(c.metrics.entriesDropped.WithLabelValues(reasonProbabilistic)))
+}
+
+func TestDeterministic(t *testing.T) {
+	args := DefaultArguments
+	args.Rate = 0.5
+	args.HashRegex = `request_id=(\w+)`
+	c := newTestComponent(t, args)
+
+	for i := 0; i < 100; i++ {
+		line := fmt.Sprintf("request_id=req%d msg=hello", i)
+		first, _ := c.sample(newEntry(model.LabelSet{"job": "a"}, line))
+		for j := 0; j < 5; j++ {
+			again, _ := c.sample(newEntry(model.LabelSet{"job": "b"}, line+" more"))
+			require.Equal(t, first, again, "request %d sampled inconsistently", i)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	args := DefaultArguments
+	args.MaxPerSecond = 5
+	c := newTestComponent(t, args)
+
+	count := func(lbls model.LabelSet) int {
+		kept := 0
+		for i := 0; i < 20; i++ {
+			if keep, _ := c.sample(newEntry(lbls, "line")); keep {
+				kept++
+			}
+		}
+		return kept
+	}
+
+	// Each stream has its own limit.
+	require.Equal(t, 5, count(model.LabelSet{"job": "a"}))
+	require.Equal(t, 5, count(model.LabelSet{"job": "b"}))
+	require.Equal(t, 30.0, testutil.ToFloat64(c.metrics.entriesDropped.WithLabelValues(reasonRateLimit)))
+	require.Equal(t, 2.0, testutil.ToFloat64(c.metrics.streams))
+}
+
+func TestValidate(t *testing.T) {
+	args := DefaultArguments
+	args.Rate = 1.5
+	require.EqualError(t, args.Validate(), "rate must be between 0 and 1, got 1.5")
+
+	args = DefaultArguments
+	args.HashRegex = `request_id=\w+`
+	require.EqualError(t, args.Validate(), "hash_regex must have a capture group")
+
+	args = DefaultArguments
+	args.HashLabel = "request_id"
+	args.HashRegex = `request_id=(\w+)`
+	require.EqualError(t, args.Validate(), "only one of hash_label and hash_regex may be set")
+}
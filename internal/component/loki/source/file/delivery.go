@@ -0,0 +1,104 @@
+package file
+
+import (
+	"sync"
+
+	"github.com/grafana/agent/internal/component/common/loki"
+)
+
+// deliveryTracker tracks which entries read by a tailer have been delivered,
+// so that the tailer only stores positions of lines which were delivered.
+//
+// Every entry is assigned an increasing sequence number. A checkpoint records
+// the file position along with the sequence number of the next entry to be
+// read; the position of a checkpoint can be stored once every entry read
+// before it was delivered.
+type deliveryTracker struct {
+	mut sync.Mutex
+
+	next        uint64              // Sequence number of the next entry.
+	lowest      uint64              // Lowest sequence number not yet delivered.
+	delivered   map[uint64]struct{} // Delivered entries with a sequence number above lowest.
+	checkpoints []checkpoint
+
+	// Tail position and next sequence number as of the last call to inFlight.
+	lastPos  int64
+	lastNext uint64
+}
+
+type checkpoint struct {
+	seq uint64
+	pos int64
+}
+
+func newDeliveryTracker() *deliveryTracker {
+	return &deliveryTracker{delivered: make(map[uint64]struct{}), lastPos: -1}
+}
+
+// track returns a delivery token for the next entry read.
+func (t *deliveryTracker) track() *loki.DeliveryToken {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	seq := t.next
+	t.next++
+	return loki.NewDeliveryToken(func() { t.done(seq) })
+}
+
+func (t *deliveryTracker) done(seq uint64) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	t.delivered[seq] = struct{}{}
+	for {
+		if _, ok := t.delivered[t.lowest]; !ok {
+			break
+		}
+		delete(t.delivered, t.lowest)
+		t.lowest++
+	}
+}
+
+// inFlight returns how many lines read by the tail up to pos may not have
+// been tracked yet. The tail holds at most one line it read but didn't hand
+// over, which is only known to have been handed over once neither pos nor
+// the tracked entries changed since the previous call while every entry was
+// delivered: the line would have been tracked in between otherwise.
+func (t *deliveryTracker) inFlight(pos int64) uint64 {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	idle := pos == t.lastPos && t.next == t.lastNext && t.lowest == t.next
+	t.lastPos, t.lastNext = pos, t.next
+	if idle {
+		return 0
+	}
+	return 1
+}
+
+// checkpoint records pos as the position after the entries read so far, plus
+// inFlight entries which were read from the file but not tracked yet. It
+// returns the newest position whose entries have all been delivered, and
+// false if there's no such position yet.
+func (t *deliveryTracker) checkpoint(pos int64, inFlight uint64) (int64, bool) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	// Checkpoints needing as many or more entries to be delivered are
+	// superseded, as they have earlier positions.
+	seq := t.next + inFlight
+	for n := len(t.checkpoints); n > 0 && t.checkpoints[n-1].seq >= seq; n-- {
+		t.checkpoints = t.checkpoints[:n-1]
+	}
+	t.checkpoints = append(t.checkpoints, checkpoint{seq: seq, pos: pos})
+
+	var (
+		safe  int64
+		found bool
+	)
+	for len(t.checkpoints) > 0 && t.checkpoints[0].seq <= t.lowest {
+		safe, found = t.checkpoints[0].pos, true
+		t.checkpoints = t.checkpoints[1:]
+	}
+	return safe, found
+}
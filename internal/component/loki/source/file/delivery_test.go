@@ -0,0 +1,58 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryTracker(t *testing.T) {
+	tracker := newDeliveryTracker()
+
+	first, second := tracker.track(), tracker.track()
+
+	// Nothing has been delivered yet.
+	_, ok := tracker.checkpoint(100, 0)
+	require.False(t, ok)
+
+	// Positions can't be stored before every earlier entry was delivered.
+	second.Done()
+	_, ok = tracker.checkpoint(100, 0)
+	require.False(t, ok)
+
+	first.Done()
+	pos, ok := tracker.checkpoint(100, 0)
+	require.True(t, ok)
+	require.Equal(t, int64(100), pos)
+
+	// Entries in flight must be delivered before the position is stored.
+	_, ok = tracker.checkpoint(150, 1)
+	require.False(t, ok)
+	tracker.track().Done()
+	pos, ok = tracker.checkpoint(200, 0)
+	require.True(t, ok)
+	require.Equal(t, int64(200), pos)
+}
+
+func TestDeliveryTracker_InFlight(t *testing.T) {
+	tracker := newDeliveryTracker()
+	tracker.track().Done()
+
+	// A line read by the tail may still be waiting to be tracked until the
+	// tail is found idle with every entry delivered.
+	require.Equal(t, uint64(1), tracker.inFlight(100))
+	_, ok := tracker.checkpoint(100, 1)
+	require.False(t, ok)
+
+	require.Equal(t, uint64(0), tracker.inFlight(100))
+	pos, ok := tracker.checkpoint(100, 0)
+	require.True(t, ok)
+	require.Equal(t, int64(100), pos)
+
+	// Entries which aren't delivered yet keep the tail from being idle.
+	entry := tracker.track()
+	require.Equal(t, uint64(1), tracker.inFlight(150))
+	require.Equal(t, uint64(1), tracker.inFlight(150))
+	entry.Done()
+	require.Equal(t, uint64(0), tracker.inFlight(150))
+}
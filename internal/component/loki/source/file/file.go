@@ -24,6 +24,10 @@ func init() {
 		Name:      "loki.source.file",
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
+		Sandbox: component.SandboxExceptions{
+			ReadPaths:         []string{"/var/log"},
+			ArgumentReadPaths: sandboxReadPaths,
+		},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
@@ -31,6 +35,24 @@ func init() {
 	})
 }
 
+// sandboxReadPaths returns the paths of the targets of the component. The
+// path of a target containing glob patterns is replaced by the deepest
+// directory without any.
+func sandboxReadPaths(args component.Arguments) []string {
+	a := args.(Arguments)
+
+	var paths []string
+	for _, target := range a.Targets {
+		if path := target[pathLabel]; path != "" {
+			paths = append(paths, component.SandboxGlobDir(path))
+		}
+	}
+	if a.LegacyPositionsFile != "" {
+		paths = append(paths, a.LegacyPositionsFile)
+	}
+	return paths
+}
+
 const (
 	pathLabel     = "__path__"
 	filenameLabel = "filename"
@@ -46,6 +68,7 @@ type Arguments struct {
 	FileWatch           FileWatch           `river:"file_watch,block,optional"`
 	TailFromEnd         bool                `river:"tail_from_end,attr,optional"`
 	LegacyPositionsFile string              `river:"legacy_positions_file,attr,optional"`
+	WaitForDelivery     bool                `river:"wait_for_delivery,attr,optional"`
 }
 
 type FileWatch struct {
@@ -149,9 +172,7 @@ func (c *Component) Run(ctx context.Context) error {
 			return nil
 		case entry := <-c.handler.Chan():
 			c.mut.RLock()
-			for _, receiver := range c.receivers {
-				receiver.Chan() <- entry
-			}
+			_ = loki.SendTo(ctx, entry, c.receivers)
 			c.mut.RUnlock()
 		}
 	}
@@ -356,6 +377,7 @@ func (c *Component) startTailing(path string, labels model.LabelSet, handler lok
 			c.args.Encoding,
 			pollOptions,
 			c.args.TailFromEnd,
+			c.args.WaitForDelivery,
 		)
 		if err != nil {
 			level.Error(c.opts.Logger).Log("msg", "failed to start tailer", "error", err, "filename", path)
@@ -314,3 +314,15 @@ func TestEncoding(t *testing.T) {
 		"expected positions.yml file to be written eventually",
 	)
 }
+
+func TestSandboxReadPaths(t *testing.T) {
+	paths := sandboxReadPaths(Arguments{
+		Targets: []discovery.Target{
+			{"__path__": "/srv/app/app.log"},
+			{"__path__": "/srv/pods/*/logs/*.log"},
+			{"job": "no-path"},
+		},
+		LegacyPositionsFile: "/srv/positions.yaml",
+	})
+	require.Equal(t, []string{"/srv/app/app.log", "/srv/pods", "/srv/positions.yaml"}, paths)
+}
@@ -45,10 +45,13 @@ type tailer struct {
 	done    chan struct{}
 
 	decoder *encoding.Decoder
+
+	// delivery is set when positions are only stored for delivered entries.
+	delivery *deliveryTracker
 }
 
 func newTailer(metrics *metrics, logger log.Logger, handler loki.EntryHandler, positions positions.Positions, path string,
-	labels string, encoding string, pollOptions watch.PollingFileWatcherOptions, tailFromEnd bool) (*tailer, error) {
+	labels string, encoding string, pollOptions watch.PollingFileWatcherOptions, tailFromEnd bool, waitForDelivery bool) (*tailer, error) {
 	// Simple check to make sure the file we are tailing doesn't
 	// have a position already saved which is past the end of the file.
 	fi, err := os.Stat(path)
@@ -116,6 +119,10 @@ func newTailer(metrics *metrics, logger log.Logger, handler loki.EntryHandler, p
 		tailer.decoder = decoder
 	}
 
+	if waitForDelivery {
+		tailer.delivery = newDeliveryTracker()
+	}
+
 	go tailer.readLines()
 	go tailer.updatePosition()
 	metrics.filesActive.Add(1.)
@@ -260,13 +267,17 @@ func (t *tailer) readLines() {
 		}
 
 		t.metrics.readLines.WithLabelValues(t.path).Inc()
-		entries <- loki.Entry{
+		entry := loki.Entry{
 			Labels: model.LabelSet{},
 			Entry: logproto.Entry{
 				Timestamp: line.Time,
 				Line:      text,
 			},
 		}
+		if t.delivery != nil {
+			entry.Token = t.delivery.track()
+		}
+		entries <- entry
 	}
 }
 
@@ -293,6 +304,16 @@ func (t *tailer) MarkPositionAndSize() error {
 	// Update metrics and positions file all together to avoid race conditions when `t.tail` is stopped.
 	t.metrics.totalBytes.WithLabelValues(t.path).Set(float64(size))
 	t.metrics.readBytes.WithLabelValues(t.path).Set(float64(pos))
+
+	if t.delivery != nil {
+		// The position reported by the tail includes the line it may be
+		// waiting to hand over to readLines, so that line must be delivered
+		// as well before pos can be stored.
+		var ok bool
+		if pos, ok = t.delivery.checkpoint(pos, t.delivery.inFlight(pos)); !ok {
+			return nil
+		}
+	}
 	t.positions.Put(t.path, t.labels, pos)
 
 	return nil
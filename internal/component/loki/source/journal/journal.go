@@ -25,6 +25,16 @@ func init() {
 		Name:      "loki.source.journal",
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
+		Sandbox: component.SandboxExceptions{
+			// Persistent and volatile journals.
+			ReadPaths: []string{"/var/log/journal", "/run/log/journal"},
+			ArgumentReadPaths: func(args component.Arguments) []string {
+				if path := args.(Arguments).Path; path != "" {
+					return []string{path}
+				}
+				return nil
+			},
+		},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
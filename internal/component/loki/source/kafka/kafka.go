@@ -211,3 +211,9 @@ func (auth KafkaAuthentication) Convert() kt.Authentication {
 		},
 	}
 }
+
+// SaramaConfig returns a copy of cfg configured to authenticate to Kafka
+// brokers as described by auth.
+func (auth KafkaAuthentication) SaramaConfig(cfg *sarama.Config) (*sarama.Config, error) {
+	return kt.WithAuthentication(*cfg, auth.Convert())
+}
@@ -0,0 +1,199 @@
+package tenant_router
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/common/loki/client"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/prometheus/common/model"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.tenant_router",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// DefaultRouteName is the name used for the default route in metrics.
+const DefaultRouteName = "default"
+
+// Arguments holds values which are used to configure the loki.tenant_router
+// component.
+type Arguments struct {
+	// The label whose value selects the route for a log entry.
+	Label string `river:"label,attr"`
+
+	// Where log entries which don't match any route are forwarded to.
+	DefaultForwardTo []loki.LogsReceiver `river:"default_forward_to,attr,optional"`
+
+	// The tenant ID set on log entries which don't match any route.
+	DefaultTenantID string `river:"default_tenant_id,attr,optional"`
+
+	Routes []Route `river:"route,block,optional"`
+}
+
+// Route forwards log entries whose label value is one of Values.
+type Route struct {
+	Name      string              `river:",label"`
+	Values    []string            `river:"values,attr"`
+	TenantID  string              `river:"tenant_id,attr,optional"`
+	ForwardTo []loki.LogsReceiver `river:"forward_to,attr"`
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.Label == "" {
+		return fmt.Errorf("label must not be empty")
+	}
+
+	names := make(map[string]struct{}, len(a.Routes))
+	values := make(map[string]string)
+	for _, r := range a.Routes {
+		if r.Name == DefaultRouteName {
+			return fmt.Errorf("route name %q is reserved", DefaultRouteName)
+		}
+		if _, ok := names[r.Name]; ok {
+			return fmt.Errorf("route %q is defined more than once", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		if len(r.Values) == 0 {
+			return fmt.Errorf("route %q must have at least one value", r.Name)
+		}
+		for _, v := range r.Values {
+			if other, ok := values[v]; ok {
+				return fmt.Errorf("value %q is used by both route %q and route %q", v, other, r.Name)
+			}
+			values[v] = r.Name
+		}
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the loki.tenant_router
+// component.
+type Exports struct {
+	Receiver loki.LogsReceiver `river:"receiver,attr"`
+}
+
+// Component implements the loki.tenant_router component.
+type Component struct {
+	opts     component.Options
+	metrics  *metrics
+	receiver loki.LogsReceiver
+
+	mut          sync.RWMutex
+	label        model.LabelName
+	byValue      map[string]*route
+	defaultRoute *route
+}
+
+// route is a single destination of the router.
+type route struct {
+	name      string
+	tenantID  string
+	forwardTo []loki.LogsReceiver
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+)
+
+// New creates a new loki.tenant_router component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		metrics: newMetrics(o.Registerer),
+	}
+
+	// Create and immediately export the receiver which remains the same for
+	// the component's lifetime.
+	c.receiver = loki.NewLogsReceiver()
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.receiver.Chan():
+			c.metrics.entriesProcessed.Inc()
+
+			r := c.route(entry)
+			if r.tenantID != "" {
+				// Copy the label set so that entries shared with other
+				// components aren't modified.
+				lbls := entry.Labels.Clone()
+				lbls[client.ReservedLabelTenantID] = model.LabelValue(r.tenantID)
+				entry.Labels = lbls
+			}
+
+			c.metrics.entriesRouted.WithLabelValues(r.name).Inc()
+			if err := loki.SendTo(ctx, entry, r.forwardTo); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	var (
+		byValue = make(map[string]*route)
+		active  = make(map[string]struct{}, len(newArgs.Routes))
+	)
+	for _, r := range newArgs.Routes {
+		rt := &route{name: r.Name, tenantID: r.TenantID, forwardTo: r.ForwardTo}
+		active[r.Name] = struct{}{}
+		for _, v := range r.Values {
+			byValue[v] = rt
+		}
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for _, old := range c.byValue {
+		if _, ok := active[old.name]; !ok {
+			c.metrics.entriesRouted.DeleteLabelValues(old.name)
+		}
+	}
+	c.label = model.LabelName(newArgs.Label)
+	c.byValue = byValue
+	c.defaultRoute = &route{
+		name:      DefaultRouteName,
+		tenantID:  newArgs.DefaultTenantID,
+		forwardTo: newArgs.DefaultForwardTo,
+	}
+	return nil
+}
+
+// route returns the route the entry should be sent through.
+func (c *Component) route(e loki.Entry) *route {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if r, ok := c.byValue[string(e.Labels[c.label])]; ok {
+		return r
+	}
+	return c.defaultRoute
+}
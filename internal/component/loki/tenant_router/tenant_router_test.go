@@ -0,0 +1,73 @@
+package tenant_router
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/common/loki/client"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouting(t *testing.T) {
+	teamA, fallback := loki.NewLogsReceiver(), loki.NewLogsReceiver()
+
+	c, err := New(component.Options{
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}, Arguments{
+		Label:            "tenant",
+		DefaultForwardTo: []loki.LogsReceiver{fallback},
+		DefaultTenantID:  "shared",
+		Routes: []Route{{
+			Name:      "a",
+			Values:    []string{"team-a"},
+			TenantID:  "tenant-a",
+			ForwardTo: []loki.LogsReceiver{teamA},
+		}},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	send := func(tenant string) {
+		c.receiver.Chan() <- loki.Entry{
+			Labels: model.LabelSet{"tenant": model.LabelValue(tenant)},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: "hello"},
+		}
+	}
+	expect := func(ch loki.LogsReceiver, tenantID string) {
+		select {
+		case e := <-ch.Chan():
+			require.Equal(t, model.LabelValue(tenantID), e.Labels[client.ReservedLabelTenantID])
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "failed waiting for log entry")
+		}
+	}
+
+	send("team-a")
+	expect(teamA, "tenant-a")
+
+	send("team-b")
+	expect(fallback, "shared")
+}
+
+func TestValidate(t *testing.T) {
+	args := Arguments{Label: "tenant", Routes: []Route{
+		{Name: "a", Values: []string{"x"}},
+		{Name: "b", Values: []string{"x"}},
+	}}
+	require.EqualError(t, args.Validate(), `value "x" is used by both route "a" and route "b"`)
+
+	args = Arguments{Label: "tenant", Routes: []Route{{Name: "default", Values: []string{"x"}}}}
+	require.EqualError(t, args.Validate(), `route name "default" is reserved`)
+}
@@ -0,0 +1,43 @@
+package write
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/grafana/agent/internal/component/common/dlq"
+	"github.com/grafana/loki/pkg/logproto"
+)
+
+// Handler implements http_service.Component. It serves the API of the
+// dead-letter queue under /dead_letter/.
+func (c *Component) Handler() http.Handler {
+	return c.metrics.DeadLetters.Handler(c.redrive, decodePushRequest)
+}
+
+// redrive sends a batch stored in the dead-letter queue to its endpoint again.
+func (c *Component) redrive(ctx context.Context, rec dlq.Record) error {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if c.clientManger == nil {
+		return fmt.Errorf("no endpoints are configured")
+	}
+	return c.clientManger.Redrive(ctx, rec)
+}
+
+// decodePushRequest decodes a batch stored in the dead-letter queue so that
+// its streams can be inspected.
+func decodePushRequest(payload []byte) (any, error) {
+	buf, err := snappy.Decode(nil, payload)
+	if err != nil {
+		return nil, err
+	}
+	var req logproto.PushRequest
+	if err := proto.Unmarshal(buf, &req); err != nil {
+		return nil, err
+	}
+	return req.Streams, nil
+}
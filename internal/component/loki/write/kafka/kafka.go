@@ -0,0 +1,413 @@
+// Package kafka implements the loki.write.kafka component.
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	source_kafka "github.com/grafana/agent/internal/component/loki/source/kafka"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.write.kafka",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Formats of the messages produced to Kafka.
+const (
+	FormatJSON = "json"
+	FormatRaw  = "raw"
+)
+
+// Arguments holds values which are used to configure the loki.write.kafka
+// component.
+type Arguments struct {
+	Brokers []string `river:"brokers,attr"`
+
+	// Topic is a template for the topic of each entry, executed with the
+	// entry's labels.
+	Topic string `river:"topic,attr"`
+
+	Version        string                           `river:"version,attr,optional"`
+	Compression    string                           `river:"compression,attr,optional"`
+	Format         string                           `river:"format,attr,optional"`
+	Authentication source_kafka.KafkaAuthentication `river:"authentication,block,optional"`
+}
+
+// DefaultArguments provides the default arguments for the loki.write.kafka
+// component.
+var DefaultArguments = Arguments{
+	Version:        "2.2.1",
+	Compression:    "none",
+	Format:         FormatJSON,
+	Authentication: source_kafka.DefaultArguments.Authentication,
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.Brokers) == 0 {
+		return fmt.Errorf("at least one broker must be set")
+	}
+	if _, err := parseTopic(a.Topic); err != nil {
+		return err
+	}
+	if _, err := sarama.ParseKafkaVersion(a.Version); err != nil {
+		return fmt.Errorf("invalid version: %w", err)
+	}
+	var codec sarama.CompressionCodec
+	if err := codec.UnmarshalText([]byte(a.Compression)); err != nil {
+		return fmt.Errorf("invalid compression: %w", err)
+	}
+	switch a.Format {
+	case FormatJSON, FormatRaw:
+	default:
+		return fmt.Errorf("format must be one of %q or %q", FormatJSON, FormatRaw)
+	}
+	return nil
+}
+
+// parseTopic parses the topic template. Labels missing from an entry expand
+// to an empty string.
+func parseTopic(topic string) (*template.Template, error) {
+	if topic == "" {
+		return nil, fmt.Errorf("topic must not be empty")
+	}
+	tmpl, err := template.New("topic").Option("missingkey=zero").Parse(topic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// producerConfig returns the configuration of the Kafka producer for the
+// arguments.
+func (a *Arguments) producerConfig() (*sarama.Config, error) {
+	cfg := sarama.NewConfig()
+
+	version, err := sarama.ParseKafkaVersion(a.Version)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Version = version
+	if err := cfg.Producer.Compression.UnmarshalText([]byte(a.Compression)); err != nil {
+		return nil, err
+	}
+	cfg.Producer.Return.Successes = true
+	cfg.Producer.Return.Errors = true
+
+	return a.Authentication.SaramaConfig(cfg)
+}
+
+// Exports holds values which are exported by the loki.write.kafka component.
+type Exports struct {
+	Receiver loki.LogsReceiver `river:"receiver,attr"`
+}
+
+// newProducer creates the Kafka producer. It's a variable so tests can
+// replace it.
+var newProducer = func(brokers []string, cfg *sarama.Config) (sarama.AsyncProducer, error) {
+	return sarama.NewAsyncProducer(brokers, cfg)
+}
+
+// Component implements the loki.write.kafka component.
+type Component struct {
+	opts     component.Options
+	metrics  *metrics
+	receiver loki.LogsReceiver
+
+	mut      sync.RWMutex
+	args     Arguments
+	topic    *template.Template
+	producer *producer
+}
+
+// producer is a Kafka producer which can be replaced while entries are being
+// sent to it. It's only closed once the sends in progress have stopped, as
+// sending to a closed producer panics.
+type producer struct {
+	sarama.AsyncProducer
+
+	replaced chan struct{} // Closed when the producer is replaced.
+	sends    sync.WaitGroup
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+)
+
+// New creates a new loki.write.kafka component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		metrics: newMetrics(o.Registerer),
+	}
+
+	// Create and immediately export the receiver which remains the same for
+	// the component's lifetime.
+	c.receiver = loki.NewLogsReceiver()
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+		c.closeProducer(c.producer)
+		c.producer = nil
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case entry := <-c.receiver.Chan():
+			if !c.produce(ctx, entry) {
+				return nil
+			}
+		}
+	}
+}
+
+// produce sends the entry to the producer. It returns false if ctx was
+// canceled before the entry could be sent, in which case the delivery token
+// of the entry is failed. Entries which can't be encoded are dropped.
+//
+// The entry is sent without holding the lock, so a stalled producer doesn't
+// block Update. If the producer is replaced in the meantime, the entry is
+// sent to the new producer instead.
+func (c *Component) produce(ctx context.Context, e loki.Entry) bool {
+	c.mut.RLock()
+	msg, reason, err := c.message(e)
+	c.mut.RUnlock()
+	if err != nil {
+		level.Debug(c.opts.Logger).Log("msg", "dropping entry", "reason", reason, "err", err)
+		c.metrics.entriesDropped.WithLabelValues(reason).Inc()
+		e.Token.Done()
+		return true
+	}
+
+	for {
+		c.mut.RLock()
+		p := c.producer
+		if p != nil {
+			p.sends.Add(1)
+		}
+		c.mut.RUnlock()
+		if p == nil {
+			// The component is shutting down. The entry wasn't sent, so it
+			// must not be considered delivered.
+			e.Token.Fail()
+			return false
+		}
+
+		sent, replaced := p.send(ctx, msg)
+		switch {
+		case sent:
+			return true
+		case !replaced:
+			e.Token.Fail()
+			return false
+		}
+	}
+}
+
+// send sends msg to the producer. It stops waiting if ctx is canceled or the
+// producer is replaced. The caller must have added itself to p.sends.
+func (p *producer) send(ctx context.Context, msg *sarama.ProducerMessage) (sent, replaced bool) {
+	defer p.sends.Done()
+
+	select {
+	case <-ctx.Done():
+		return false, false
+	case <-p.replaced:
+		return false, true
+	case p.Input() <- msg:
+		return true, false
+	}
+}
+
+// message builds the Kafka message for the entry. When the entry can't be
+// produced, message returns the reason for dropping it.
+func (c *Component) message(e loki.Entry) (*sarama.ProducerMessage, string, error) {
+	labels := make(map[string]string, len(e.Labels))
+	for name, value := range e.Labels {
+		labels[string(name)] = string(value)
+	}
+
+	var topic strings.Builder
+	if err := c.topic.Execute(&topic, labels); err != nil {
+		return nil, reasonEmptyTopic, fmt.Errorf("executing topic template: %w", err)
+	}
+	if topic.Len() == 0 {
+		return nil, reasonEmptyTopic, fmt.Errorf("topic template expanded to an empty topic")
+	}
+
+	value, err := encodeEntry(c.args.Format, e, labels)
+	if err != nil {
+		return nil, reasonEncoding, err
+	}
+
+	return &sarama.ProducerMessage{
+		Topic:     topic.String(),
+		Key:       sarama.StringEncoder(e.Labels.Fingerprint().String()),
+		Value:     sarama.ByteEncoder(value),
+		Timestamp: e.Timestamp,
+		Metadata:  e.Token,
+	}, "", nil
+}
+
+// jsonEntry is the message value of an entry in the json format.
+type jsonEntry struct {
+	Timestamp          time.Time         `json:"timestamp"`
+	Line               string            `json:"line"`
+	Labels             map[string]string `json:"labels"`
+	StructuredMetadata map[string]string `json:"structured_metadata,omitempty"`
+}
+
+// encodeEntry encodes the entry in the given format.
+func encodeEntry(format string, e loki.Entry, labels map[string]string) ([]byte, error) {
+	if format == FormatRaw {
+		return []byte(e.Line), nil
+	}
+
+	je := jsonEntry{
+		Timestamp: e.Timestamp,
+		Line:      e.Line,
+		Labels:    labels,
+	}
+	if len(e.StructuredMetadata) > 0 {
+		je.StructuredMetadata = make(map[string]string, len(e.StructuredMetadata))
+		for _, md := range e.StructuredMetadata {
+			je.StructuredMetadata[md.Name] = md.Value
+		}
+	}
+	return json.Marshal(je)
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	topic, err := parseTopic(newArgs.Topic)
+	if err != nil {
+		return err
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	// The producer is only recreated when its settings change, so in-flight
+	// messages aren't flushed needlessly.
+	if c.producer == nil || producerChanged(c.args, newArgs) {
+		cfg, err := newArgs.producerConfig()
+		if err != nil {
+			return err
+		}
+		p, err := newProducer(newArgs.Brokers, cfg)
+		if err != nil {
+			return fmt.Errorf("creating kafka producer: %w", err)
+		}
+		go c.handleResults(p)
+
+		c.closeProducer(c.producer)
+		c.producer = &producer{AsyncProducer: p, replaced: make(chan struct{})}
+	}
+
+	c.args = newArgs
+	c.topic = topic
+	return nil
+}
+
+// producerChanged reports whether the producer must be recreated to apply
+// the new arguments.
+func producerChanged(prev, next Arguments) bool {
+	return !reflect.DeepEqual(prev.Brokers, next.Brokers) ||
+		prev.Version != next.Version ||
+		prev.Compression != next.Compression ||
+		!reflect.DeepEqual(prev.Authentication, next.Authentication)
+}
+
+// closeProducer flushes and closes the producer in the background once the
+// sends in progress have stopped. It's a no-op if p is nil. c.mut must be
+// held, so that no new sends to p are started.
+func (c *Component) closeProducer(p *producer) {
+	if p == nil {
+		return
+	}
+	close(p.replaced)
+	go func() {
+		p.sends.Wait()
+		if err := p.Close(); err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to close kafka producer", "err", err)
+		}
+	}()
+}
+
+// handleResults releases the delivery tokens of the messages produced by
+// producer once Kafka acknowledges them, and fails them when producing them
+// fails. It returns once the producer is closed.
+func (c *Component) handleResults(producer sarama.AsyncProducer) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for msg := range producer.Successes() {
+			c.metrics.entriesSent.Inc()
+			c.metrics.bytesSent.Add(float64(msg.Value.Length()))
+			releaseToken(msg, true)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for err := range producer.Errors() {
+			level.Error(c.opts.Logger).Log("msg", "failed to produce entry", "topic", err.Msg.Topic, "err", err.Err)
+			c.metrics.entriesFailed.Inc()
+			releaseToken(err.Msg, false)
+		}
+	}()
+
+	wg.Wait()
+}
+
+// releaseToken releases the delivery token of the entry msg was built from,
+// failing it if msg wasn't delivered.
+func releaseToken(msg *sarama.ProducerMessage, delivered bool) {
+	token, ok := msg.Metadata.(*loki.DeliveryToken)
+	switch {
+	case !ok:
+	case delivered:
+		token.Done()
+	default:
+		token.Fail()
+	}
+}
@@ -0,0 +1,304 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+// useMockProducer replaces the Kafka producer with a mock for the duration of
+// the test.
+func useMockProducer(t *testing.T, setup func(*mocks.AsyncProducer)) {
+	t.Helper()
+	prev := newProducer
+	t.Cleanup(func() { newProducer = prev })
+
+	newProducer = func(_ []string, cfg *sarama.Config) (sarama.AsyncProducer, error) {
+		p := mocks.NewAsyncProducer(t, cfg)
+		setup(p)
+		return p, nil
+	}
+}
+
+func newTestComponent(t *testing.T, args Arguments) *Component {
+	t.Helper()
+	c, err := New(component.Options{
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+	return c
+}
+
+func testArguments() Arguments {
+	args := DefaultArguments
+	args.Brokers = []string{"localhost:9092"}
+	args.Topic = "logs-{{ .namespace }}"
+	return args
+}
+
+func TestProduce(t *testing.T) {
+	received := make(chan *sarama.ProducerMessage, 1)
+	useMockProducer(t, func(p *mocks.AsyncProducer) {
+		p.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			received <- msg
+			return nil
+		})
+	})
+
+	c := newTestComponent(t, testArguments())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { require.NoError(t, c.Run(ctx)) }()
+
+	delivered := make(chan struct{})
+	lbls := model.LabelSet{"namespace": "default", "job": "test"}
+	ts := time.Unix(100, 0).UTC()
+	c.receiver.Chan() <- loki.Entry{
+		Labels: lbls,
+		Entry: logproto.Entry{
+			Timestamp:          ts,
+			Line:               "hello",
+			StructuredMetadata: []logproto.LabelAdapter{{Name: "trace_id", Value: "abc"}},
+		},
+		Token: loki.NewDeliveryToken(func() { close(delivered) }),
+	}
+
+	var msg *sarama.ProducerMessage
+	select {
+	case msg = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+	require.Equal(t, "logs-default", msg.Topic)
+
+	key, err := msg.Key.Encode()
+	require.NoError(t, err)
+	require.Equal(t, lbls.Fingerprint().String(), string(key))
+
+	value, err := msg.Value.Encode()
+	require.NoError(t, err)
+	var je jsonEntry
+	require.NoError(t, json.Unmarshal(value, &je))
+	require.Equal(t, jsonEntry{
+		Timestamp:          ts,
+		Line:               "hello",
+		Labels:             map[string]string{"namespace": "default", "job": "test"},
+		StructuredMetadata: map[string]string{"trace_id": "abc"},
+	}, je)
+
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery token wasn't released")
+	}
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(c.metrics.entriesSent) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestProduceFailure(t *testing.T) {
+	useMockProducer(t, func(p *mocks.AsyncProducer) {
+		p.ExpectInputAndFail(fmt.Errorf("broker unavailable"))
+	})
+
+	c := newTestComponent(t, testArguments())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { require.NoError(t, c.Run(ctx)) }()
+
+	// Entries which failed to be produced aren't delivered, so sources read
+	// them again after a restart.
+	delivered := make(chan struct{})
+	c.receiver.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"namespace": "default"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "hello"},
+		Token:  loki.NewDeliveryToken(func() { close(delivered) }),
+	}
+
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(c.metrics.entriesFailed) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	select {
+	case <-delivered:
+		t.Fatal("entry which failed to be produced was delivered")
+	default:
+	}
+}
+
+func TestUpdate_StalledProducer(t *testing.T) {
+	// The producers never accept messages, like when Kafka stalls.
+	var (
+		mut       sync.Mutex
+		producers []*stalledProducer
+	)
+	prev := newProducer
+	t.Cleanup(func() { newProducer = prev })
+	newProducer = func(_ []string, _ *sarama.Config) (sarama.AsyncProducer, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		p := newStalledProducer()
+		producers = append(producers, p)
+		return p, nil
+	}
+
+	c := newTestComponent(t, testArguments())
+	ctx, cancel := context.WithCancel(context.Background())
+	runDone := make(chan struct{})
+	go func() {
+		defer close(runDone)
+		require.NoError(t, c.Run(ctx))
+	}()
+
+	delivered := make(chan struct{})
+	c.receiver.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"namespace": "default"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "hello"},
+		Token:  loki.NewDeliveryToken(func() { close(delivered) }),
+	}
+
+	// Changing the brokers recreates the producer while the entry is being
+	// sent to the stalled one.
+	updated := make(chan error, 1)
+	go func() {
+		args := testArguments()
+		args.Brokers = []string{"localhost:9093"}
+		updated <- c.Update(args)
+	}()
+	select {
+	case err := <-updated:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Update blocked on the stalled producer")
+	}
+
+	// The replaced producer is closed once the entry moved to the new one.
+	mut.Lock()
+	first := producers[0]
+	mut.Unlock()
+	select {
+	case <-first.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("replaced producer wasn't closed")
+	}
+
+	// The entry was never sent, so it isn't delivered when shutting down.
+	cancel()
+	select {
+	case <-runDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return")
+	}
+	select {
+	case <-delivered:
+		t.Fatal("entry which was never sent was delivered")
+	default:
+	}
+}
+
+// stalledProducer is a sarama.AsyncProducer which never accepts messages.
+type stalledProducer struct {
+	sarama.AsyncProducer
+
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+	closed    chan struct{}
+}
+
+func newStalledProducer() *stalledProducer {
+	return &stalledProducer{
+		input:     make(chan *sarama.ProducerMessage),
+		successes: make(chan *sarama.ProducerMessage),
+		errors:    make(chan *sarama.ProducerError),
+		closed:    make(chan struct{}),
+	}
+}
+
+func (p *stalledProducer) Input() chan<- *sarama.ProducerMessage     { return p.input }
+func (p *stalledProducer) Successes() <-chan *sarama.ProducerMessage { return p.successes }
+func (p *stalledProducer) Errors() <-chan *sarama.ProducerError      { return p.errors }
+
+func (p *stalledProducer) Close() error {
+	// Like sarama, sending to the producer after closing it panics.
+	close(p.input)
+	close(p.successes)
+	close(p.errors)
+	close(p.closed)
+	return nil
+}
+
+func TestMessage(t *testing.T) {
+	useMockProducer(t, func(p *mocks.AsyncProducer) {})
+
+	args := testArguments()
+	args.Format = FormatRaw
+	c := newTestComponent(t, args)
+
+	msg, _, err := c.message(loki.Entry{
+		Labels: model.LabelSet{"namespace": "kube-system"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "raw line"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "logs-kube-system", msg.Topic)
+	value, err := msg.Value.Encode()
+	require.NoError(t, err)
+	require.Equal(t, "raw line", string(value))
+
+	// Labels missing from the entry expand to an empty string, and entries
+	// whose topic is empty are dropped.
+	args.Topic = "{{ .namespace }}"
+	require.NoError(t, c.Update(args))
+	_, reason, err := c.message(loki.Entry{
+		Labels: model.LabelSet{"job": "test"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "line"},
+	})
+	require.EqualError(t, err, "topic template expanded to an empty topic")
+	require.Equal(t, reasonEmptyTopic, reason)
+}
+
+func TestProducerConfig(t *testing.T) {
+	args := testArguments()
+	args.Compression = "zstd"
+	cfg, err := args.producerConfig()
+	require.NoError(t, err)
+	require.Equal(t, sarama.CompressionZSTD, cfg.Producer.Compression)
+
+	args.Compression = "snappy"
+	cfg, err = args.producerConfig()
+	require.NoError(t, err)
+	require.Equal(t, sarama.CompressionSnappy, cfg.Producer.Compression)
+}
+
+func TestValidate(t *testing.T) {
+	args := testArguments()
+	args.Topic = "{{ .namespace"
+	require.ErrorContains(t, args.Validate(), "invalid topic template")
+
+	args = testArguments()
+	args.Compression = "brotli"
+	require.ErrorContains(t, args.Validate(), "invalid compression")
+
+	args = testArguments()
+	args.Format = "protobuf"
+	require.EqualError(t, args.Validate(), `format must be one of "json" or "raw"`)
+
+	args = testArguments()
+	args.Brokers = nil
+	require.EqualError(t, args.Validate(), "at least one broker must be set")
+}
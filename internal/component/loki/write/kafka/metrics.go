@@ -0,0 +1,53 @@
+package kafka
+
+import (
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons an entry may be dropped, used as the value of the reason label of
+// the dropped entries metric.
+const (
+	reasonEmptyTopic = "empty_topic"
+	reasonEncoding   = "encoding"
+)
+
+type metrics struct {
+	entriesSent    prometheus_client.Counter
+	bytesSent      prometheus_client.Counter
+	entriesFailed  prometheus_client.Counter
+	entriesDropped *prometheus_client.CounterVec
+}
+
+// newMetrics creates a new set of metrics. If reg is non-nil, the metrics
+// will also be registered.
+func newMetrics(reg prometheus_client.Registerer) *metrics {
+	var m metrics
+
+	m.entriesSent = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "loki_write_kafka_entries_sent_total",
+		Help: "Total number of log entries acknowledged by Kafka",
+	})
+	m.bytesSent = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "loki_write_kafka_sent_bytes_total",
+		Help: "Total number of bytes of message values acknowledged by Kafka",
+	})
+	m.entriesFailed = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "loki_write_kafka_entries_failed_total",
+		Help: "Total number of log entries which failed to be produced to Kafka",
+	})
+	m.entriesDropped = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+		Name: "loki_write_kafka_entries_dropped_total",
+		Help: "Total number of log entries dropped before being produced to Kafka",
+	}, []string{"reason"})
+
+	if reg != nil {
+		reg.MustRegister(
+			m.entriesSent,
+			m.bytesSent,
+			m.entriesFailed,
+			m.entriesDropped,
+		)
+	}
+
+	return &m
+}
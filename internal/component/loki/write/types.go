@@ -30,6 +30,7 @@ type EndpointOptions struct {
 	RetryOnHTTP429    bool                    `river:"retry_on_http_429,attr,optional"`
 	HTTPClientConfig  *types.HTTPClientConfig `river:",squash"`
 	QueueConfig       QueueConfig             `river:"queue_config,block,optional"`
+	AdaptiveBatching  *AdaptiveBatching       `river:"adaptive_batching,block,optional"`
 }
 
 // GetDefaultEndpointOptions defines the default settings for sending logs to a
@@ -63,6 +64,11 @@ func (r *EndpointOptions) Validate() error {
 		return fmt.Errorf("failed to parse remote url %q: %w", r.URL, err)
 	}
 
+	if a := r.AdaptiveBatching; a != nil && (r.BatchSize < a.MinBatchSize || r.BatchSize > a.MaxBatchSize) {
+		return fmt.Errorf("batch_size %s must be between min_batch_size %s and max_batch_size %s when adaptive batching is enabled",
+			r.BatchSize, a.MinBatchSize, a.MaxBatchSize)
+	}
+
 	// We must explicitly Validate because HTTPClientConfig is squashed and it won't run otherwise
 	if r.HTTPClientConfig != nil {
 		return r.HTTPClientConfig.Validate()
@@ -86,6 +92,49 @@ func (q *QueueConfig) SetToDefault() {
 	}
 }
 
+// AdaptiveBatching configures an endpoint to adjust its batch size based on
+// the latency of send requests and on rate limiting.
+type AdaptiveBatching struct {
+	MinBatchSize  units.Base2Bytes `river:"min_batch_size,attr,optional"`
+	MaxBatchSize  units.Base2Bytes `river:"max_batch_size,attr,optional"`
+	TargetLatency time.Duration    `river:"target_latency,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *AdaptiveBatching) SetToDefault() {
+	*a = AdaptiveBatching{
+		MinBatchSize:  64 * units.KiB,
+		MaxBatchSize:  4 * units.MiB,
+		TargetLatency: 1 * time.Second,
+	}
+}
+
+// Validate implements river.Validator.
+func (a *AdaptiveBatching) Validate() error {
+	if a.MinBatchSize <= 0 {
+		return fmt.Errorf("min_batch_size must be greater than 0")
+	}
+	if a.MaxBatchSize < a.MinBatchSize {
+		return fmt.Errorf("max_batch_size must be greater than or equal to min_batch_size")
+	}
+	if a.TargetLatency <= 0 {
+		return fmt.Errorf("target_latency must be greater than 0")
+	}
+	return nil
+}
+
+func (a *AdaptiveBatching) convert() client.AdaptiveBatchConfig {
+	if a == nil {
+		return client.AdaptiveBatchConfig{}
+	}
+	return client.AdaptiveBatchConfig{
+		Enabled:       true,
+		MinBatchSize:  int(a.MinBatchSize),
+		MaxBatchSize:  int(a.MaxBatchSize),
+		TargetLatency: a.TargetLatency,
+	}
+}
+
 func (args Arguments) convertClientConfigs() []client.Config {
 	var res []client.Config
 	for _, cfg := range args.Endpoints {
@@ -110,6 +159,7 @@ func (args Arguments) convertClientConfigs() []client.Config {
 				Capacity:     int(cfg.QueueConfig.Capacity),
 				DrainTimeout: cfg.QueueConfig.DrainTimeout,
 			},
+			AdaptiveBatching: cfg.AdaptiveBatching.convert(),
 		}
 		res = append(res, cc)
 	}
@@ -7,13 +7,17 @@ import (
 	"sync"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/grafana/agent/internal/agentseed"
 	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/dlq"
 	"github.com/grafana/agent/internal/component/common/loki"
 	"github.com/grafana/agent/internal/component/common/loki/client"
 	"github.com/grafana/agent/internal/component/common/loki/limit"
 	"github.com/grafana/agent/internal/component/common/loki/wal"
+	"github.com/grafana/agent/internal/component/common/slo"
 	"github.com/grafana/agent/internal/featuregate"
+	http_service "github.com/grafana/agent/internal/service/http"
 )
 
 func init() {
@@ -35,22 +39,28 @@ type Arguments struct {
 	ExternalLabels map[string]string `river:"external_labels,attr,optional"`
 	MaxStreams     int               `river:"max_streams,attr,optional"`
 	WAL            WalArguments      `river:"wal,block,optional"`
+	SLO            *slo.Arguments    `river:"slo,block,optional"`
+	DeadLetter     *dlq.Arguments    `river:"dead_letter_queue,block,optional"`
 }
 
 // WalArguments holds the settings for configuring the Write-Ahead Log (WAL) used
 // by the underlying remote write client.
 type WalArguments struct {
-	Enabled          bool          `river:"enabled,attr,optional"`
-	MaxSegmentAge    time.Duration `river:"max_segment_age,attr,optional"`
-	MinReadFrequency time.Duration `river:"min_read_frequency,attr,optional"`
-	MaxReadFrequency time.Duration `river:"max_read_frequency,attr,optional"`
-	DrainTimeout     time.Duration `river:"drain_timeout,attr,optional"`
+	Enabled          bool             `river:"enabled,attr,optional"`
+	MaxSegmentAge    time.Duration    `river:"max_segment_age,attr,optional"`
+	MaxSize          units.Base2Bytes `river:"max_size,attr,optional"`
+	MinReadFrequency time.Duration    `river:"min_read_frequency,attr,optional"`
+	MaxReadFrequency time.Duration    `river:"max_read_frequency,attr,optional"`
+	DrainTimeout     time.Duration    `river:"drain_timeout,attr,optional"`
 }
 
 func (wa *WalArguments) Validate() error {
 	if wa.MinReadFrequency >= wa.MaxReadFrequency {
 		return fmt.Errorf("WAL min read frequency should be lower than max read frequency")
 	}
+	if wa.MaxSize < 0 {
+		return fmt.Errorf("WAL max size must not be negative")
+	}
 	return nil
 }
 
@@ -73,7 +83,8 @@ type Exports struct {
 }
 
 var (
-	_ component.Component = (*Component)(nil)
+	_ component.Component    = (*Component)(nil)
+	_ http_service.Component = (*Component)(nil)
 )
 
 // Component implements the loki.write component.
@@ -100,6 +111,14 @@ func New(o component.Options, args Arguments) (*Component, error) {
 		opts:    o,
 		metrics: client.NewMetrics(o.Registerer),
 	}
+	c.metrics.SLO = slo.NewTracker()
+	if err := o.Registerer.Register(c.metrics.SLO); err != nil {
+		return nil, err
+	}
+	c.metrics.DeadLetters = dlq.NewQueue(filepath.Join(o.DataPath, "dead_letter"))
+	if err := o.Registerer.Register(c.metrics.DeadLetters); err != nil {
+		return nil, err
+	}
 
 	// Create and immediately export the receiver which remains the same for
 	// the component's lifetime.
@@ -152,6 +171,10 @@ func (c *Component) Update(args component.Arguments) error {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 	c.args = newArgs
+	c.metrics.SLO.Update(newArgs.SLO)
+	if err := c.metrics.DeadLetters.Update(newArgs.DeadLetter); err != nil {
+		return fmt.Errorf("failed to open dead-letter queue: %w", err)
+	}
 
 	if c.walWriter != nil {
 		c.walWriter.Stop()
@@ -174,6 +197,7 @@ func (c *Component) Update(args component.Arguments) error {
 	walCfg := wal.Config{
 		Enabled:       newArgs.WAL.Enabled,
 		MaxSegmentAge: newArgs.WAL.MaxSegmentAge,
+		MaxSize:       int64(newArgs.WAL.MaxSize),
 		WatchConfig: wal.WatchConfig{
 			MinReadFrequency: newArgs.WAL.MinReadFrequency,
 			MaxReadFrequency: newArgs.WAL.MaxReadFrequency,
@@ -2,6 +2,7 @@ package write
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
@@ -10,13 +11,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/component/common/loki"
+	"github.com/grafana/agent/internal/component/common/loki/client"
 	"github.com/grafana/agent/internal/component/common/loki/wal"
 	"github.com/grafana/agent/internal/component/discovery"
 	lsf "github.com/grafana/agent/internal/component/loki/source/file"
 	"github.com/grafana/agent/internal/flow/componenttest"
 	"github.com/grafana/agent/internal/util"
 	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
@@ -56,6 +60,41 @@ func TestBadRiverConfig(t *testing.T) {
 	require.ErrorContains(t, err, "at most one of basic_auth, authorization, oauth2, bearer_token & bearer_token_file must be configured")
 }
 
+func TestAdaptiveBatchingRiverConfig(t *testing.T) {
+	var exampleRiverConfig = `
+	endpoint {
+		url = "http://0.0.0.0:11111/loki/api/v1/push"
+
+		adaptive_batching {
+			max_batch_size = "8MiB"
+		}
+	}
+`
+
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(exampleRiverConfig), &args))
+
+	cfgs := args.convertClientConfigs()
+	require.Len(t, cfgs, 1)
+	require.Equal(t, client.AdaptiveBatchConfig{
+		Enabled:       true,
+		MinBatchSize:  64 * 1024,
+		MaxBatchSize:  8 * 1024 * 1024,
+		TargetLatency: time.Second,
+	}, cfgs[0].AdaptiveBatching)
+
+	var badRiverConfig = `
+	endpoint {
+		url        = "http://0.0.0.0:11111/loki/api/v1/push"
+		batch_size = "16MiB"
+
+		adaptive_batching {}
+	}
+`
+	err := river.Unmarshal([]byte(badRiverConfig), &args)
+	require.ErrorContains(t, err, "batch_size 16MiB must be between min_batch_size 64KiB and max_batch_size 4MiB")
+}
+
 func TestUnmarshallWalAttrributes(t *testing.T) {
 	type testcase struct {
 		raw           string
@@ -404,3 +443,75 @@ func benchSingleEndpoint(b *testing.B, tc testCase, alterConfig func(arguments *
 		}, time.Minute, time.Second, "haven't seen expected number of lines")
 	}
 }
+
+func TestDeadLetterQueue(t *testing.T) {
+	// The endpoint rejects the first request and accepts the others.
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Inc() == 1 {
+			http.Error(w, "entry too far behind", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := fmt.Sprintf(`
+		endpoint {
+			name       = "default"
+			url        = "%s"
+			batch_wait = "10ms"
+		}
+
+		dead_letter_queue {
+			max_size = "1MiB"
+		}
+	`, srv.URL)
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+
+	c, err := New(component.Options{
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		DataPath:      t.TempDir(),
+		OnStateChange: func(e component.Exports) {},
+	}, args)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { require.NoError(t, c.Run(ctx)) }()
+
+	c.receiver.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"foo": "bar"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "very important log"},
+	}
+
+	// The rejected batch is stored instead of being dropped.
+	require.Eventually(t, func() bool {
+		return len(c.metrics.DeadLetters.List()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	rec := c.metrics.DeadLetters.List()[0]
+	require.Equal(t, "default", rec.Endpoint)
+	require.Equal(t, http.StatusBadRequest, rec.Status)
+	require.Equal(t, 1, rec.Entries)
+
+	handler := httptest.NewServer(c.Handler())
+	defer handler.Close()
+
+	resp, err := http.Get(fmt.Sprintf("%s/dead_letter/records/%d", handler.URL, rec.ID))
+	require.NoError(t, err)
+	var details struct {
+		Payload []logproto.Stream `json:"payload"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&details))
+	resp.Body.Close()
+	require.Len(t, details.Payload, 1)
+	require.Equal(t, "very important log", details.Payload[0].Entries[0].Line)
+
+	resp, err = http.Post(fmt.Sprintf("%s/dead_letter/records/%d/redrive", handler.URL, rec.ID), "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Empty(t, c.metrics.DeadLetters.List())
+	require.Equal(t, int64(2), requests.Load())
+}
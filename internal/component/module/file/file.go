@@ -15,10 +15,16 @@ import (
 
 func init() {
 	component.Register(component.Registration{
-		Name:      "module.file",
-		Stability: featuregate.StabilityBeta,
-		Args:      Arguments{},
-		Exports:   module.Exports{},
+		Name:       "module.file",
+		Stability:  featuregate.StabilityBeta,
+		Deprecated: "replaced by import.file",
+		Args:       Arguments{},
+		Exports:    module.Exports{},
+		Sandbox: component.SandboxExceptions{
+			ArgumentReadPaths: func(args component.Arguments) []string {
+				return []string{args.(Arguments).LocalFileArguments.Filename}
+			},
+		},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
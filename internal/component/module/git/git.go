@@ -19,10 +19,11 @@ import (
 
 func init() {
 	component.Register(component.Registration{
-		Name:      "module.git",
-		Stability: featuregate.StabilityBeta,
-		Args:      Arguments{},
-		Exports:   module.Exports{},
+		Name:       "module.git",
+		Stability:  featuregate.StabilityBeta,
+		Deprecated: "replaced by import.git",
+		Args:       Arguments{},
+		Exports:    module.Exports{},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
@@ -15,10 +15,11 @@ import (
 
 func init() {
 	component.Register(component.Registration{
-		Name:      "module.http",
-		Stability: featuregate.StabilityBeta,
-		Args:      Arguments{},
-		Exports:   module.Exports{},
+		Name:       "module.http",
+		Stability:  featuregate.StabilityBeta,
+		Deprecated: "replaced by import.http",
+		Args:       Arguments{},
+		Exports:    module.Exports{},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
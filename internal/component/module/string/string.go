@@ -11,10 +11,11 @@ import (
 
 func init() {
 	component.Register(component.Registration{
-		Name:      "module.string",
-		Stability: featuregate.StabilityBeta,
-		Args:      Arguments{},
-		Exports:   module.Exports{},
+		Name:       "module.string",
+		Stability:  featuregate.StabilityBeta,
+		Deprecated: "replaced by import.string",
+		Args:       Arguments{},
+		Exports:    module.Exports{},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
@@ -9,6 +9,7 @@ import (
 
 	"github.com/grafana/agent/internal/build"
 	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/slo"
 	"github.com/grafana/agent/internal/component/otelcol"
 	"github.com/grafana/agent/internal/component/otelcol/internal/lazycollector"
 	"github.com/grafana/agent/internal/component/otelcol/internal/lazyconsumer"
@@ -17,6 +18,7 @@ import (
 	"github.com/grafana/agent/internal/util/zapadapter"
 	"github.com/prometheus/client_golang/prometheus"
 	otelcomponent "go.opentelemetry.io/collector/component"
+	otelconsumer "go.opentelemetry.io/collector/consumer"
 	otelexporter "go.opentelemetry.io/collector/exporter"
 	otelextension "go.opentelemetry.io/collector/extension"
 	sdkprometheus "go.opentelemetry.io/otel/exporters/prometheus"
@@ -83,6 +85,7 @@ type Exporter struct {
 
 	sched     *scheduler.Scheduler
 	collector *lazycollector.Collector
+	slo       *slo.Tracker
 
 	// Signals which the exporter is able to export.
 	// Can be logs, metrics, traces or any combination of them.
@@ -110,6 +113,11 @@ func New(opts component.Options, f otelexporter.Factory, args Arguments, support
 	collector := lazycollector.New()
 	opts.Registerer.MustRegister(collector)
 
+	// SLO metrics are only reported for exporters which implement
+	// SLOArguments, and only while they're enabled.
+	tracker := slo.NewTracker()
+	opts.Registerer.MustRegister(tracker)
+
 	// Immediately set our state with our consumer. The exports will never change
 	// throughout the lifetime of our component.
 	//
@@ -127,6 +135,7 @@ func New(opts component.Options, f otelexporter.Factory, args Arguments, support
 
 		sched:     scheduler.New(opts.Logger),
 		collector: collector,
+		slo:       tracker,
 
 		supportedSignals: supportedSignals,
 	}
@@ -225,10 +234,38 @@ func (e *Exporter) Update(args component.Arguments) error {
 
 	// Schedule the components to run once our component is running.
 	e.sched.Schedule(host, components...)
-	e.consumer.SetConsumers(tracesExporter, metricsExporter, logsExporter)
+	e.consumer.SetConsumers(e.wrapSLO(eargs, tracesExporter, metricsExporter, logsExporter))
 	return nil
 }
 
+// wrapSLO wraps the exporters created for args to record the outcome of
+// every export when args enables SLO metrics. Otherwise, the exporters are
+// returned unmodified.
+func (e *Exporter) wrapSLO(args Arguments, t otelexporter.Traces, m otelexporter.Metrics, l otelexporter.Logs) (otelconsumer.Traces, otelconsumer.Metrics, otelconsumer.Logs) {
+	sloArgs, ok := args.(SLOArguments)
+	if !ok || sloArgs.SLOConfig() == nil {
+		e.slo.Update(nil)
+		return t, m, l
+	}
+	e.slo.Update(sloArgs.SLOConfig())
+
+	var (
+		traces  otelconsumer.Traces
+		metrics otelconsumer.Metrics
+		logs    otelconsumer.Logs
+	)
+	if t != nil {
+		traces = sloTraces{Traces: t, tracker: e.slo, endpoint: sloArgs.SLOEndpoint(otelcomponent.DataTypeTraces)}
+	}
+	if m != nil {
+		metrics = sloMetrics{Metrics: m, tracker: e.slo, endpoint: sloArgs.SLOEndpoint(otelcomponent.DataTypeMetrics)}
+	}
+	if l != nil {
+		logs = sloLogs{Logs: l, tracker: e.slo, endpoint: sloArgs.SLOEndpoint(otelcomponent.DataTypeLogs)}
+	}
+	return traces, metrics, logs
+}
+
 // CurrentHealth implements component.HealthComponent.
 func (e *Exporter) CurrentHealth() component.Health {
 	return e.sched.CurrentHealth()
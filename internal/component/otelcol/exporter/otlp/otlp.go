@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/slo"
 	"github.com/grafana/agent/internal/component/otelcol"
 	"github.com/grafana/agent/internal/component/otelcol/exporter"
 	"github.com/grafana/agent/internal/featuregate"
@@ -39,9 +40,14 @@ type Arguments struct {
 	DebugMetrics otelcol.DebugMetricsArguments `river:"debug_metrics,block,optional"`
 
 	Client GRPCClientArguments `river:"client,block"`
+
+	SLO *slo.Arguments `river:"slo,block,optional"`
 }
 
-var _ exporter.Arguments = Arguments{}
+var (
+	_ exporter.Arguments    = Arguments{}
+	_ exporter.SLOArguments = Arguments{}
+)
 
 // SetToDefault implements river.Defaulter.
 func (args *Arguments) SetToDefault() {
@@ -82,6 +88,16 @@ func (args Arguments) DebugMetricsConfig() otelcol.DebugMetricsArguments {
 	return args.DebugMetrics
 }
 
+// SLOConfig implements exporter.SLOArguments.
+func (args Arguments) SLOConfig() *slo.Arguments {
+	return args.SLO
+}
+
+// SLOEndpoint implements exporter.SLOArguments.
+func (args Arguments) SLOEndpoint(otelcomponent.DataType) string {
+	return args.Client.Endpoint
+}
+
 // GRPCClientArguments is used to configure otelcol.exporter.otlp with
 // component-specific defaults.
 type GRPCClientArguments otelcol.GRPCClientArguments
@@ -4,9 +4,11 @@ package otlphttp
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/slo"
 	"github.com/grafana/agent/internal/component/otelcol"
 	"github.com/grafana/agent/internal/component/otelcol/exporter"
 	"github.com/grafana/agent/internal/featuregate"
@@ -47,9 +49,14 @@ type Arguments struct {
 	LogsEndpoint    string `river:"logs_endpoint,attr,optional"`
 
 	Encoding string `river:"encoding,attr,optional"`
+
+	SLO *slo.Arguments `river:"slo,block,optional"`
 }
 
-var _ exporter.Arguments = Arguments{}
+var (
+	_ exporter.Arguments    = Arguments{}
+	_ exporter.SLOArguments = Arguments{}
+)
 
 const (
 	EncodingProto string = "proto"
@@ -95,6 +102,28 @@ func (args Arguments) DebugMetricsConfig() otelcol.DebugMetricsArguments {
 	return args.DebugMetrics
 }
 
+// SLOConfig implements exporter.SLOArguments.
+func (args Arguments) SLOConfig() *slo.Arguments {
+	return args.SLO
+}
+
+// SLOEndpoint implements exporter.SLOArguments.
+func (args Arguments) SLOEndpoint(dataType otelcomponent.DataType) string {
+	var endpoint string
+	switch dataType {
+	case otelcomponent.DataTypeTraces:
+		endpoint = args.TracesEndpoint
+	case otelcomponent.DataTypeMetrics:
+		endpoint = args.MetricsEndpoint
+	case otelcomponent.DataTypeLogs:
+		endpoint = args.LogsEndpoint
+	}
+	if endpoint != "" {
+		return endpoint
+	}
+	return strings.TrimSuffix(args.Client.Endpoint, "/") + "/v1/" + string(dataType)
+}
+
 // Validate implements river.Validator.
 func (args *Arguments) Validate() error {
 	if args.Client.Endpoint == "" && args.TracesEndpoint == "" && args.MetricsEndpoint == "" && args.LogsEndpoint == "" {
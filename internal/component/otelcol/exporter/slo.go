@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/agent/internal/component/common/slo"
+	otelcomponent "go.opentelemetry.io/collector/component"
+	otelexporter "go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// SLOArguments is an optional extension of Arguments for exporters which can
+// report SLO metrics for the endpoint they export to.
+type SLOArguments interface {
+	// SLOConfig returns the SLO settings of the exporter. SLO metrics are
+	// disabled when SLOConfig returns nil.
+	SLOConfig() *slo.Arguments
+
+	// SLOEndpoint returns the endpoint telemetry of the given type is exported
+	// to.
+	SLOEndpoint(dataType otelcomponent.DataType) string
+}
+
+// sloTraces records the outcome of every export of traces.
+type sloTraces struct {
+	otelexporter.Traces
+	tracker  *slo.Tracker
+	endpoint string
+}
+
+func (e sloTraces) ConsumeTraces(ctx context.Context, td ptrace.Traces) error {
+	start := time.Now()
+	err := e.Traces.ConsumeTraces(ctx, td)
+	e.tracker.Observe(e.endpoint, time.Since(start), err == nil)
+	return err
+}
+
+// sloMetrics records the outcome of every export of metrics.
+type sloMetrics struct {
+	otelexporter.Metrics
+	tracker  *slo.Tracker
+	endpoint string
+}
+
+func (e sloMetrics) ConsumeMetrics(ctx context.Context, md pmetric.Metrics) error {
+	start := time.Now()
+	err := e.Metrics.ConsumeMetrics(ctx, md)
+	e.tracker.Observe(e.endpoint, time.Since(start), err == nil)
+	return err
+}
+
+// sloLogs records the outcome of every export of logs.
+type sloLogs struct {
+	otelexporter.Logs
+	tracker  *slo.Tracker
+	endpoint string
+}
+
+func (e sloLogs) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
+	start := time.Now()
+	err := e.Logs.ConsumeLogs(ctx, ld)
+	e.tracker.Observe(e.endpoint, time.Since(start), err == nil)
+	return err
+}
@@ -136,9 +136,10 @@ func (dc *DetectorConfig) SetToDefault() {
 }
 
 var (
-	_ processor.Arguments = Arguments{}
-	_ river.Validator     = (*Arguments)(nil)
-	_ river.Defaulter     = (*Arguments)(nil)
+	_ processor.Arguments          = Arguments{}
+	_ component.SensitiveArguments = Arguments{}
+	_ river.Validator              = (*Arguments)(nil)
+	_ river.Defaulter              = (*Arguments)(nil)
 )
 
 // SetToDefault implements river.Defaulter.
@@ -247,3 +248,9 @@ func (args Arguments) Exporters() map[otelcomponent.DataType]map[otelcomponent.I
 func (args Arguments) NextConsumers() *otelcol.ConsumerArguments {
 	return args.Output
 }
+
+// SensitiveBlocks implements component.SensitiveArguments. The token of the
+// openshift detector is a plain string, as required by the upstream detector.
+func (args Arguments) SensitiveBlocks() []string {
+	return []string{"openshift"}
+}
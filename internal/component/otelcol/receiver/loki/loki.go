@@ -92,7 +92,10 @@ func (c *Component) Run(ctx context.Context) error {
 			err := c.logsSink.ConsumeLogs(ctx, logs)
 			if err != nil {
 				level.Error(c.opts.Logger).Log("msg", "failed to consume log entries", "err", err)
+				entry.Token.Fail()
+				continue
 			}
+			entry.Token.Done()
 		}
 	}
 }
@@ -0,0 +1,490 @@
+package adaptive_metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	http_service "github.com/grafana/agent/internal/service/http"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/useragent"
+	"github.com/grafana/regexp"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	prom_config "github.com/prometheus/common/config"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.adaptive_metrics",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// prometheus.adaptive_metrics component.
+type Arguments struct {
+	// Where metrics are forwarded to.
+	ForwardTo []storage.Appendable `river:"forward_to,attr"`
+
+	// Names of metrics which are known to be used downstream.
+	UsedMetrics []string `river:"used_metrics,attr,optional"`
+
+	// How long a metric must be observed before recommendations are made.
+	MinObservationPeriod time.Duration `river:"min_observation_period,attr,optional"`
+
+	// How often recommendations are recalculated.
+	EvaluationInterval time.Duration `river:"evaluation_interval,attr,optional"`
+
+	// The number of distinct values of an unused label above which an
+	// aggregation is recommended.
+	MaxLabelValues int `river:"max_label_values,attr,optional"`
+
+	// Whether to drop metrics which are recommended to be dropped.
+	AutoApply bool `river:"auto_apply,attr,optional"`
+
+	UsageEndpoint *UsageEndpoint `river:"usage_endpoint,block,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	MinObservationPeriod: time.Hour,
+	EvaluationInterval:   time.Minute,
+	MaxLabelValues:       1000,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.MinObservationPeriod < 0 {
+		return fmt.Errorf("min_observation_period must not be negative")
+	}
+	if args.EvaluationInterval <= 0 {
+		return fmt.Errorf("evaluation_interval must be greater than 0")
+	}
+	if args.MaxLabelValues <= 0 {
+		return fmt.Errorf("max_label_values must be greater than 0")
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.adaptive_metrics
+// component.
+type Exports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+
+	// Rules holds relabel rules which drop the metrics recommended to be
+	// dropped.
+	Rules flow_relabel.Rules `river:"rules,attr"`
+}
+
+// DebugInfo holds the debug information of the component.
+type DebugInfo struct {
+	UsageKnown      bool             `river:"usage_known,attr"`
+	LastEvaluation  time.Time        `river:"last_evaluation,attr,optional"`
+	Recommendations []Recommendation `river:"recommendation,block,optional"`
+}
+
+// Component implements the prometheus.adaptive_metrics component.
+type Component struct {
+	opts     component.Options
+	tracker  *tracker
+	fanout   *prometheus.Fanout
+	receiver *prometheus.Interceptor
+
+	droppedSamples  prometheus_client.Counter
+	recommendations *prometheus_client.GaugeVec
+
+	mut         sync.RWMutex
+	args        Arguments
+	cli         *http.Client
+	remoteUsage map[string][]string
+	lastPoll    time.Time
+	lastEval    time.Time
+	recs        []Recommendation
+	drop        map[string]struct{}
+
+	// updated is written to whenever args updates.
+	updated chan struct{}
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+	_ component.DebugComponent  = (*Component)(nil)
+	_ http_service.Component    = (*Component)(nil)
+)
+
+// New creates a new prometheus.adaptive_metrics component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		opts:    o,
+		tracker: newTracker(args.MaxLabelValues),
+		drop:    make(map[string]struct{}),
+		updated: make(chan struct{}, 1),
+		health: component.Health{
+			Health:     component.HealthTypeHealthy,
+			UpdateTime: time.Now(),
+		},
+	}
+	c.droppedSamples = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_adaptive_metrics_dropped_samples_total",
+		Help: "Total number of samples dropped by automatically applied recommendations",
+	})
+	c.recommendations = prometheus_client.NewGaugeVec(prometheus_client.GaugeOpts{
+		Name: "agent_prometheus_adaptive_metrics_recommendations",
+		Help: "Number of current recommendations by action",
+	}, []string{"action"})
+	for _, metric := range []prometheus_client.Collector{c.droppedSamples, c.recommendations} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls)
+	c.receiver = prometheus.NewInterceptor(
+		c.fanout,
+		ls,
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			if c.observe(l) {
+				c.droppedSamples.Inc()
+				return ref, nil
+			}
+			return next.Append(ref, l, t, v)
+		}),
+		prometheus.WithHistogramHook(func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			if c.observe(l) {
+				c.droppedSamples.Inc()
+				return ref, nil
+			}
+			return next.AppendHistogram(ref, l, t, h, fh)
+		}),
+		prometheus.WithExemplarHook(func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			if c.isDropped(l) {
+				return ref, nil
+			}
+			return next.AppendExemplar(ref, l, e)
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			if c.isDropped(l) {
+				return ref, nil
+			}
+			return next.UpdateMetadata(ref, l, m)
+		}),
+	)
+
+	o.OnStateChange(Exports{Receiver: c.receiver, Rules: flow_relabel.Rules{}})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// observe records the series and reports whether it should be dropped.
+func (c *Component) observe(l labels.Labels) bool {
+	c.tracker.Observe(l, time.Now())
+	return c.isDropped(l)
+}
+
+// isDropped reports whether the series belongs to a metric which is dropped
+// by an automatically applied recommendation.
+func (c *Component) isDropped(l labels.Labels) bool {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	if !c.args.AutoApply {
+		return false
+	}
+	_, drop := c.drop[l.Get(labels.MetricName)]
+	return drop
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.nextPoll()):
+			c.poll(ctx)
+		case <-time.After(c.nextEvaluation()):
+			c.evaluate()
+		case <-c.updated:
+			// no-op; force the next wait to be reread.
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	var cli *http.Client
+	if newArgs.UsageEndpoint != nil {
+		var err error
+		cli, err = prom_config.NewClientFromConfig(
+			*newArgs.UsageEndpoint.Client.Convert(),
+			c.opts.ID,
+			prom_config.WithUserAgent(useragent.Get()),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.mut.Lock()
+	if newArgs.UsageEndpoint == nil || c.args.UsageEndpoint == nil || c.args.UsageEndpoint.URL != newArgs.UsageEndpoint.URL {
+		// Usage from a previous endpoint no longer applies.
+		c.remoteUsage = nil
+		c.lastPoll = time.Time{}
+	}
+	c.args = newArgs
+	c.cli = cli
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	c.mut.Unlock()
+
+	c.tracker.SetMaxLabelValues(newArgs.MaxLabelValues)
+	c.evaluate()
+
+	// Send an updated event if one wasn't already read.
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// nextPoll returns how long to wait before polling the usage endpoint.
+func (c *Component) nextPoll() time.Duration {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if c.args.UsageEndpoint == nil {
+		// Wait for an update to enable polling.
+		return 24 * time.Hour
+	}
+	return time.Until(c.lastPoll.Add(c.args.UsageEndpoint.PollFrequency))
+}
+
+// nextEvaluation returns how long to wait before recalculating
+// recommendations.
+func (c *Component) nextEvaluation() time.Duration {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	return time.Until(c.lastEval.Add(c.args.EvaluationInterval))
+}
+
+// poll fetches the used metrics from the usage endpoint and updates the
+// component's health.
+func (c *Component) poll(ctx context.Context) {
+	c.mut.Lock()
+	var (
+		endpoint = c.args.UsageEndpoint
+		cli      = c.cli
+	)
+	c.lastPoll = time.Now()
+	c.mut.Unlock()
+
+	if endpoint == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, endpoint.PollTimeout)
+	defer cancel()
+
+	used, err := fetchUsage(ctx, cli, endpoint.URL)
+	if err != nil {
+		level.Error(c.opts.Logger).Log("msg", "failed to fetch metric usage", "url", endpoint.URL, "err", err)
+		c.setHealth(fmt.Errorf("fetching metric usage: %w", err))
+		return
+	}
+	c.setHealth(nil)
+
+	c.mut.Lock()
+	c.remoteUsage = used
+	c.mut.Unlock()
+
+	c.evaluate()
+}
+
+func (c *Component) setHealth(err error) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+
+	if err == nil {
+		c.health = component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    "fetched metric usage",
+			UpdateTime: time.Now(),
+		}
+	} else {
+		c.health = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    err.Error(),
+			UpdateTime: time.Now(),
+		}
+	}
+}
+
+// currentUsage merges the statically configured and remotely fetched metric
+// usage. It returns false if usage is unknown, in which case no
+// recommendations should be made. c.mut must be held when calling.
+func (c *Component) currentUsage() (usage, bool) {
+	if len(c.args.UsedMetrics) == 0 && c.remoteUsage == nil {
+		return usage{}, false
+	}
+
+	u := usage{metrics: make(map[string][]string, len(c.args.UsedMetrics)+len(c.remoteUsage))}
+	for name, lbls := range c.remoteUsage {
+		u.metrics[name] = lbls
+	}
+	for _, name := range c.args.UsedMetrics {
+		// Statically configured metrics carry no label usage, so any label may
+		// be in use.
+		u.metrics[name] = nil
+	}
+	return u, true
+}
+
+// evaluate recalculates recommendations and exports the generated rules.
+func (c *Component) evaluate() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := time.Now()
+	c.lastEval = now
+
+	u, ok := c.currentUsage()
+	if !ok {
+		c.recs = nil
+	} else {
+		c.recs = c.tracker.Recommend(u, now, c.args.MinObservationPeriod)
+	}
+
+	drop := make(map[string]struct{})
+	counts := map[string]int{ActionDrop: 0, ActionAggregate: 0}
+	for _, r := range c.recs {
+		counts[r.Action]++
+		if r.Action == ActionDrop {
+			drop[r.Metric] = struct{}{}
+		}
+	}
+	for action, n := range counts {
+		c.recommendations.WithLabelValues(action).Set(float64(n))
+	}
+
+	if !equalSets(c.drop, drop) {
+		c.drop = drop
+		c.opts.OnStateChange(Exports{Receiver: c.receiver, Rules: dropRules(drop)})
+	}
+}
+
+// dropRules returns relabel rules which drop every metric in names.
+func dropRules(names map[string]struct{}) flow_relabel.Rules {
+	if len(names) == 0 {
+		return flow_relabel.Rules{}
+	}
+
+	quoted := make([]string, 0, len(names))
+	for name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	sort.Strings(quoted)
+
+	return flow_relabel.Rules{{
+		SourceLabels: []string{labels.MetricName},
+		Separator:    flow_relabel.DefaultRelabelConfig.Separator,
+		Regex:        flow_relabel.Regexp{Regexp: regexp.MustCompile("^(?:" + strings.Join(quoted, "|") + ")$")},
+		Replacement:  flow_relabel.DefaultRelabelConfig.Replacement,
+		Action:       flow_relabel.Drop,
+	}}
+}
+
+func equalSets(a, b map[string]struct{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Recommendations returns the current recommendations.
+func (c *Component) Recommendations() []Recommendation {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	return c.recs
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
+
+// DebugInfo implements component.DebugComponent.
+func (c *Component) DebugInfo() interface{} {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	_, known := c.currentUsage()
+	return DebugInfo{
+		UsageKnown:      known,
+		LastEvaluation:  c.lastEval,
+		Recommendations: c.recs,
+	}
+}
+
+// Handler implements http_service.Component. It serves the current
+// recommendations as JSON at /recommendations.
+func (c *Component) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/recommendations", func(w http.ResponseWriter, r *http.Request) {
+		recs := c.Recommendations()
+		if recs == nil {
+			recs = []Recommendation{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(recs); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	return mux
+}
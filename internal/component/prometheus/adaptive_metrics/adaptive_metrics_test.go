@@ -0,0 +1,95 @@
+package adaptive_metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoApply(t *testing.T) {
+	ls := labelstore.New(nil, prom.DefaultRegisterer)
+
+	var received []string
+	capture := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+		received = append(received, l.Get("__name__"))
+		return ref, nil
+	}))
+
+	var exports Exports
+	args := DefaultArguments
+	args.ForwardTo = []storage.Appendable{capture}
+	args.UsedMetrics = []string{"used"}
+	args.MinObservationPeriod = 0
+	args.AutoApply = true
+
+	c, err := New(component.Options{
+		ID:     "prometheus.adaptive_metrics.test",
+		Logger: util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {
+			exports = e.(Exports)
+		},
+		Registerer: prom.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+
+	appendAll := func() {
+		app := exports.Receiver.Appender(context.Background())
+		for _, name := range []string{"used", "unused"} {
+			_, err := app.Append(0, labels.FromStrings("__name__", name), time.Now().UnixMilli(), 1)
+			require.NoError(t, err)
+		}
+		require.NoError(t, app.Commit())
+	}
+
+	// Nothing is dropped before the metrics have been evaluated.
+	appendAll()
+	require.Equal(t, []string{"used", "unused"}, received)
+	require.Empty(t, exports.Rules)
+
+	c.evaluate()
+	require.Len(t, exports.Rules, 1)
+	require.Equal(t, flow_relabel.Drop, exports.Rules[0].Action)
+	require.Equal(t, "unused", exports.Rules[0].Regex.String())
+
+	received = nil
+	appendAll()
+	require.Equal(t, []string{"used"}, received)
+
+	// The recommendations are served over HTTP.
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/recommendations", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var recs []Recommendation
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &recs))
+	require.Len(t, recs, 1)
+	require.Equal(t, "unused", recs[0].Metric)
+	require.Equal(t, ActionDrop, recs[0].Action)
+}
+
+func TestUsageEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"metrics": [{"name": "a", "labels": ["job"]}, {"name": "b"}]}`))
+	}))
+	defer srv.Close()
+
+	used, err := fetchUsage(context.Background(), srv.Client(), srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"a": {"job"}, "b": nil}, used)
+}
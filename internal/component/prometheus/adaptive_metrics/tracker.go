@@ -0,0 +1,181 @@
+package adaptive_metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// Recommendation actions.
+const (
+	// ActionDrop recommends dropping every series of a metric.
+	ActionDrop = "drop"
+	// ActionAggregate recommends aggregating a metric by dropping some of its
+	// labels.
+	ActionAggregate = "aggregate"
+)
+
+// Recommendation is a suggested change for a single metric.
+type Recommendation struct {
+	Metric     string   `river:"metric,attr" json:"metric"`
+	Action     string   `river:"action,attr" json:"action"`
+	DropLabels []string `river:"drop_labels,attr,optional" json:"drop_labels,omitempty"`
+	Reason     string   `river:"reason,attr" json:"reason"`
+	Samples    uint64   `river:"samples,attr" json:"samples"`
+}
+
+// histogramSuffixes are trimmed from metric names when checking whether a
+// metric is used, so that using a histogram or summary counts as using all of
+// its series.
+var histogramSuffixes = []string{"_bucket", "_count", "_sum"}
+
+// metricStats holds what is known about a single metric name.
+type metricStats struct {
+	firstSeen time.Time
+	samples   uint64
+
+	// labelValues holds the distinct values seen for each label, up to the
+	// cardinality limit plus one.
+	labelValues map[string]map[string]struct{}
+}
+
+// tracker records the metrics which pass through the component.
+type tracker struct {
+	mut     sync.Mutex
+	metrics map[string]*metricStats
+
+	// maxLabelValues is the number of distinct values tracked per label.
+	maxLabelValues int
+}
+
+func newTracker(maxLabelValues int) *tracker {
+	return &tracker{
+		metrics:        make(map[string]*metricStats),
+		maxLabelValues: maxLabelValues,
+	}
+}
+
+// Observe records a sample for the series with the given labels.
+func (t *tracker) Observe(l labels.Labels, now time.Time) {
+	name := l.Get(labels.MetricName)
+	if name == "" {
+		return
+	}
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	ms, ok := t.metrics[name]
+	if !ok {
+		ms = &metricStats{firstSeen: now, labelValues: make(map[string]map[string]struct{})}
+		t.metrics[name] = ms
+	}
+	ms.samples++
+
+	l.Range(func(lbl labels.Label) {
+		if lbl.Name == labels.MetricName {
+			return
+		}
+		values, ok := ms.labelValues[lbl.Name]
+		if !ok {
+			values = make(map[string]struct{})
+			ms.labelValues[lbl.Name] = values
+		}
+		if len(values) <= t.maxLabelValues {
+			values[lbl.Value] = struct{}{}
+		}
+	})
+}
+
+// SetMaxLabelValues changes the number of distinct values tracked per label.
+func (t *tracker) SetMaxLabelValues(n int) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	t.maxLabelValues = n
+}
+
+// usage describes which metrics, and which of their labels, are used
+// downstream.
+type usage struct {
+	// metrics maps used metric names to the labels used by queries. A nil
+	// list means label usage is unknown, and no labels are recommended for
+	// aggregation.
+	metrics map[string][]string
+}
+
+// lookup returns the used labels of a metric, and whether the metric is used
+// at all.
+func (u usage) lookup(name string) ([]string, bool) {
+	if lbls, ok := u.metrics[name]; ok {
+		return lbls, true
+	}
+	for _, suffix := range histogramSuffixes {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			if lbls, ok := u.metrics[base]; ok {
+				return lbls, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Recommend returns recommendations for every metric which has been observed
+// for at least minObservation.
+func (t *tracker) Recommend(u usage, now time.Time, minObservation time.Duration) []Recommendation {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	var res []Recommendation
+	for name, ms := range t.metrics {
+		if now.Sub(ms.firstSeen) < minObservation {
+			continue
+		}
+
+		usedLabels, used := u.lookup(name)
+		if !used {
+			res = append(res, Recommendation{
+				Metric:  name,
+				Action:  ActionDrop,
+				Reason:  "metric is not used",
+				Samples: ms.samples,
+			})
+			continue
+		}
+		if usedLabels == nil {
+			continue
+		}
+
+		var drop []string
+		for lbl, values := range ms.labelValues {
+			if len(values) <= t.maxLabelValues || containsString(usedLabels, lbl) {
+				continue
+			}
+			drop = append(drop, lbl)
+		}
+		if len(drop) > 0 {
+			sort.Strings(drop)
+			res = append(res, Recommendation{
+				Metric:     name,
+				Action:     ActionAggregate,
+				DropLabels: drop,
+				Reason:     "unused labels have high cardinality",
+				Samples:    ms.samples,
+			})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Metric < res[j].Metric })
+	return res
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
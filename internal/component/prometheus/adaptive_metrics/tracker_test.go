@@ -0,0 +1,46 @@
+package adaptive_metrics
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecommend(t *testing.T) {
+	var (
+		now = time.Now()
+		tr  = newTracker(10)
+	)
+
+	for i := 0; i < 20; i++ {
+		pod := strconv.Itoa(i)
+		tr.Observe(labels.FromStrings("__name__", "http_requests_total", "job", "api", "pod", pod), now)
+		tr.Observe(labels.FromStrings("__name__", "request_duration_seconds_bucket", "le", "1", "pod", pod), now)
+		tr.Observe(labels.FromStrings("__name__", "unused_metric", "pod", pod), now)
+	}
+	tr.Observe(labels.FromStrings("__name__", "new_metric"), now.Add(time.Hour))
+
+	u := usage{metrics: map[string][]string{
+		"http_requests_total":      {"job"},
+		"request_duration_seconds": nil,
+	}}
+
+	require.Equal(t, []Recommendation{
+		{
+			Metric:     "http_requests_total",
+			Action:     ActionAggregate,
+			DropLabels: []string{"pod"},
+			Reason:     "unused labels have high cardinality",
+			Samples:    20,
+		},
+		{
+			Metric:  "unused_metric",
+			Action:  ActionDrop,
+			Reason:  "metric is not used",
+			Samples: 20,
+		},
+	}, tr.Recommend(u, now.Add(time.Hour), time.Hour))
+}
@@ -0,0 +1,90 @@
+package adaptive_metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	common_config "github.com/grafana/agent/internal/component/common/config"
+)
+
+// UsageEndpoint configures a remote API which reports the metrics used
+// downstream.
+type UsageEndpoint struct {
+	URL           string        `river:"url,attr"`
+	PollFrequency time.Duration `river:"poll_frequency,attr,optional"`
+	PollTimeout   time.Duration `river:"poll_timeout,attr,optional"`
+
+	Client common_config.HTTPClientConfig `river:"client,block,optional"`
+}
+
+// DefaultUsageEndpoint holds default settings for UsageEndpoint.
+var DefaultUsageEndpoint = UsageEndpoint{
+	PollFrequency: time.Hour,
+	PollTimeout:   30 * time.Second,
+	Client:        common_config.DefaultHTTPClientConfig,
+}
+
+// SetToDefault implements river.Defaulter.
+func (e *UsageEndpoint) SetToDefault() {
+	*e = DefaultUsageEndpoint
+}
+
+// Validate implements river.Validator.
+func (e *UsageEndpoint) Validate() error {
+	if e.PollFrequency <= 0 {
+		return fmt.Errorf("poll_frequency must be greater than 0")
+	}
+	if e.PollTimeout <= 0 || e.PollTimeout >= e.PollFrequency {
+		return fmt.Errorf("poll_timeout must be greater than 0 and less than poll_frequency")
+	}
+	if _, err := http.NewRequest(http.MethodGet, e.URL, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// usageResponse is the response body of a usage endpoint.
+type usageResponse struct {
+	Metrics []struct {
+		Name   string   `json:"name"`
+		Labels []string `json:"labels"`
+	} `json:"metrics"`
+}
+
+// fetchUsage retrieves the used metrics from a usage endpoint.
+func fetchUsage(ctx context.Context, cli *http.Client, url string) (map[string][]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("performing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %s", resp.Status)
+	}
+
+	bb, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	var ur usageResponse
+	if err := json.Unmarshal(bb, &ur); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	res := make(map[string][]string, len(ur.Metrics))
+	for _, m := range ur.Metrics {
+		// A missing labels field means label usage is unknown, while an empty
+		// list means no labels are used.
+		res[m.Name] = m.Labels
+	}
+	return res, nil
+}
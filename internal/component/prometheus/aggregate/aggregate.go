@@ -0,0 +1,399 @@
+// Package aggregate implements the prometheus.aggregate component.
+package aggregate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/regexp"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.aggregate",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Operations supported by rules.
+const (
+	OperationSum   = "sum"
+	OperationAvg   = "avg"
+	OperationMin   = "min"
+	OperationMax   = "max"
+	OperationCount = "count"
+)
+
+// Types of the metrics aggregated by rules.
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// Arguments holds values which are used to configure the prometheus.aggregate
+// component.
+type Arguments struct {
+	// Where unmatched and aggregated metrics are forwarded to.
+	ForwardTo []storage.Appendable `river:"forward_to,attr"`
+
+	// How often aggregated samples are forwarded.
+	Interval time.Duration `river:"interval,attr,optional"`
+
+	// How long an input series contributes to its aggregation after its
+	// latest sample.
+	StaleAfter time.Duration `river:"stale_after,attr,optional"`
+
+	Rules []RuleArguments `river:"rule,block,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Interval:   time.Minute,
+	StaleAfter: 5 * time.Minute,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if args.StaleAfter < args.Interval {
+		return fmt.Errorf("stale_after must not be less than interval")
+	}
+	return nil
+}
+
+// RuleArguments configures how the series of matching metrics are
+// aggregated.
+type RuleArguments struct {
+	// Regular expressions matching the names of the aggregated metrics.
+	Metrics []string `river:"metrics,attr"`
+
+	// Labels which are kept in aggregated series. Mutually exclusive with
+	// Without.
+	By []string `river:"by,attr,optional"`
+
+	// Labels which are dropped from aggregated series. Mutually exclusive
+	// with By.
+	Without []string `river:"without,attr,optional"`
+
+	Operation string `river:"operation,attr,optional"`
+	Type      string `river:"type,attr,optional"`
+}
+
+// DefaultRuleArguments holds default settings for RuleArguments.
+var DefaultRuleArguments = RuleArguments{
+	Operation: OperationSum,
+	Type:      TypeGauge,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *RuleArguments) SetToDefault() {
+	*args = DefaultRuleArguments
+}
+
+// Validate implements river.Validator.
+func (args *RuleArguments) Validate() error {
+	if len(args.Metrics) == 0 {
+		return fmt.Errorf("metrics must not be empty")
+	}
+	for _, m := range args.Metrics {
+		if _, err := regexp.Compile(m); err != nil {
+			return fmt.Errorf("invalid metrics regular expression %q: %w", m, err)
+		}
+	}
+
+	switch {
+	case len(args.By) > 0 && len(args.Without) > 0:
+		return fmt.Errorf("by and without are mutually exclusive")
+	case len(args.By) == 0 && len(args.Without) == 0:
+		return fmt.Errorf("one of by or without must be set")
+	}
+	for _, names := range [][]string{args.By, args.Without} {
+		for _, name := range names {
+			if name == labels.MetricName {
+				return fmt.Errorf("%s can't be used in by or without", labels.MetricName)
+			}
+		}
+	}
+
+	switch args.Operation {
+	case OperationSum, OperationAvg, OperationMin, OperationMax, OperationCount:
+	default:
+		return fmt.Errorf("unknown operation %q", args.Operation)
+	}
+	switch args.Type {
+	case TypeGauge:
+	case TypeCounter:
+		if args.Operation != OperationSum {
+			return fmt.Errorf("counters only support the %s operation", OperationSum)
+		}
+	default:
+		return fmt.Errorf("unknown type %q", args.Type)
+	}
+	return nil
+}
+
+// rule is a compiled RuleArguments.
+type rule struct {
+	RuleArguments
+	metrics *regexp.Regexp
+	counter bool
+}
+
+func newRule(args RuleArguments) (*rule, error) {
+	metrics, err := regexp.Compile("^(?:" + strings.Join(args.Metrics, "|") + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return &rule{
+		RuleArguments: args,
+		metrics:       metrics,
+		counter:       args.Type == TypeCounter,
+	}, nil
+}
+
+// outputLabels returns the labels of the aggregated series of an input
+// series.
+func (r *rule) outputLabels(lbls labels.Labels) labels.Labels {
+	b := labels.NewBuilder(lbls)
+	if len(r.By) > 0 {
+		b.Keep(append([]string{labels.MetricName}, r.By...)...)
+	} else {
+		b.Del(r.Without...)
+	}
+	return b.Labels()
+}
+
+// Exports holds values which are exported by the prometheus.aggregate
+// component.
+type Exports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+}
+
+// Component implements the prometheus.aggregate component.
+type Component struct {
+	opts       component.Options
+	aggregator *aggregator
+	fanout     *prometheus.Fanout
+	receiver   *prometheus.Interceptor
+
+	aggregatedSamples prometheus_client.Counter
+	outputSeries      prometheus_client.GaugeFunc
+
+	mut   sync.RWMutex
+	args  Arguments
+	rules []*rule
+
+	// updated is written to whenever args updates.
+	updated chan struct{}
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new prometheus.aggregate component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		opts:       o,
+		aggregator: newAggregator(),
+		updated:    make(chan struct{}, 1),
+	}
+	c.aggregatedSamples = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_aggregate_input_samples_total",
+		Help: "Total number of samples received which matched a rule and were aggregated",
+	})
+	c.outputSeries = prometheus_client.NewGaugeFunc(prometheus_client.GaugeOpts{
+		Name: "agent_prometheus_aggregate_output_series",
+		Help: "Number of aggregated series forwarded by the component",
+	}, func() float64 { return float64(c.aggregator.len()) })
+	for _, metric := range []prometheus_client.Collector{c.aggregatedSamples, c.outputSeries} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls)
+	c.receiver = prometheus.NewInterceptor(
+		c.fanout,
+		ls,
+		prometheus.WithAppenderMiddleware(c.newMiddleware),
+	)
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// matchRule returns the first rule matching the metric of lbls, if any.
+func (c *Component) matchRule(lbls labels.Labels) *rule {
+	name := lbls.Get(labels.MetricName)
+
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	for _, r := range c.rules {
+		if r.metrics.MatchString(name) {
+			return r
+		}
+	}
+	return nil
+}
+
+// newMiddleware returns a middleware which holds back the samples of series
+// matching a rule. They're aggregated once the appender commits. Samples of
+// other series, histograms and metadata are passed through.
+func (c *Component) newMiddleware() prometheus.Middleware {
+	var samples []sample
+
+	return prometheus.MiddlewareFuncs{
+		OnAppend: func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			if r := c.matchRule(l); r != nil {
+				samples = append(samples, sample{rule: r, labels: l, value: v})
+				return ref, nil
+			}
+			if next == nil {
+				return ref, nil
+			}
+			return next.Append(ref, l, t, v)
+		},
+		OnAppendExemplar: func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			// Exemplars of aggregated series would reference series which are
+			// no longer forwarded.
+			if c.matchRule(l) != nil || next == nil {
+				return ref, nil
+			}
+			return next.AppendExemplar(ref, l, e)
+		},
+		OnCommit: func(next storage.Appender) error {
+			if len(samples) > 0 {
+				c.aggregator.add(samples, time.Now())
+				c.aggregatedSamples.Add(float64(len(samples)))
+			}
+			samples = nil
+
+			if next == nil {
+				return nil
+			}
+			return next.Commit()
+		},
+		OnRollback: func(next storage.Appender) error {
+			samples = nil
+
+			if next == nil {
+				return nil
+			}
+			return next.Rollback()
+		},
+	}
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	newTicker := func() *time.Ticker {
+		c.mut.RLock()
+		defer c.mut.RUnlock()
+		return time.NewTicker(c.args.Interval)
+	}
+	ticker := newTicker()
+	defer func() { ticker.Stop() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.updated:
+			ticker.Stop()
+			ticker = newTicker()
+		case now := <-ticker.C:
+			if err := c.flush(ctx, now); err != nil {
+				level.Error(c.opts.Logger).Log("msg", "failed to forward aggregated samples", "err", err)
+			}
+		}
+	}
+}
+
+// flush forwards the aggregated samples of every group.
+func (c *Component) flush(ctx context.Context, now time.Time) error {
+	c.mut.RLock()
+	staleAfter := c.args.StaleAfter
+	c.mut.RUnlock()
+
+	results := c.aggregator.flush(now.Add(-staleAfter))
+	if len(results) == 0 {
+		return nil
+	}
+
+	app := c.fanout.Appender(ctx)
+	for _, r := range results {
+		if _, err := app.Append(0, r.labels, now.UnixMilli(), r.value); err != nil {
+			_ = app.Rollback()
+			return err
+		}
+	}
+	return app.Commit()
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	rules := make([]*rule, 0, len(newArgs.Rules))
+	for _, ruleArgs := range newArgs.Rules {
+		r, err := newRule(ruleArgs)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, r)
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	// Aggregations of the previous rules can't be carried over.
+	if !reflect.DeepEqual(c.args.Rules, newArgs.Rules) {
+		c.aggregator.reset()
+	}
+	c.args = newArgs
+	c.rules = rules
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package aggregate
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/prometheus/prometheustest"
+	"github.com/grafana/river"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+type testSample struct {
+	labels string
+	value  float64
+}
+
+// receivedSamples returns the labels and values of the samples received by
+// capture.
+func receivedSamples(capture *prometheustest.Capture) []testSample {
+	var res []testSample
+	for _, s := range capture.Samples() {
+		res = append(res, testSample{labels: s.Labels.String(), value: s.V})
+	}
+	return res
+}
+
+func newTestComponent(t *testing.T, rules ...RuleArguments) (*Component, storage.Appendable, *prometheustest.Capture) {
+	h := prometheustest.NewHarness(t, "prometheus.aggregate.test", nil)
+	capture := &prometheustest.Capture{}
+
+	args := DefaultArguments
+	args.ForwardTo = []storage.Appendable{capture}
+	args.Rules = rules
+
+	c, err := New(h.Options, args)
+	require.NoError(t, err)
+	return c, h.Exports().(Exports).Receiver, capture
+}
+
+func appendSamples(t *testing.T, receiver storage.Appendable, value float64, lbls ...labels.Labels) {
+	app := receiver.Appender(context.Background())
+	for _, l := range lbls {
+		_, err := app.Append(0, l, time.Now().UnixMilli(), value)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+}
+
+func TestAggregate_Gauges(t *testing.T) {
+	c, receiver, received := newTestComponent(t, RuleArguments{
+		Metrics:   []string{"queue_.*"},
+		Without:   []string{"instance"},
+		Operation: OperationAvg,
+		Type:      TypeGauge,
+	})
+
+	a := labels.FromStrings("__name__", "queue_length", "job", "app", "instance", "a")
+	b := labels.FromStrings("__name__", "queue_length", "job", "app", "instance", "b")
+	appendSamples(t, receiver, 2, a)
+	appendSamples(t, receiver, 4, b)
+	appendSamples(t, receiver, 1, labels.FromStrings("__name__", "up", "instance", "a"))
+
+	// Unmatched series are forwarded unchanged.
+	require.Equal(t, []testSample{{labels: `{__name__="up", instance="a"}`, value: 1}}, receivedSamples(received))
+
+	received.Reset()
+	now := time.Now()
+	require.NoError(t, c.flush(context.Background(), now))
+	require.Equal(t, []testSample{{labels: `{__name__="queue_length", job="app"}`, value: 3}}, receivedSamples(received))
+
+	// A stale marker removes the contribution of its series.
+	received.Reset()
+	appendSamples(t, receiver, math.Float64frombits(value.StaleNaN), a)
+	require.NoError(t, c.flush(context.Background(), now))
+	require.Equal(t, []testSample{{labels: `{__name__="queue_length", job="app"}`, value: 4}}, receivedSamples(received))
+
+	// The aggregated series is marked stale once all its series are stale.
+	received.Reset()
+	require.NoError(t, c.flush(context.Background(), now.Add(DefaultArguments.StaleAfter+time.Second)))
+	samples := receivedSamples(received)
+	require.Len(t, samples, 1)
+	require.True(t, value.IsStaleNaN(samples[0].value))
+	require.Equal(t, 0, c.aggregator.len())
+}
+
+func TestAggregate_Counters(t *testing.T) {
+	c, receiver, received := newTestComponent(t, RuleArguments{
+		Metrics:   []string{"requests_total"},
+		By:        []string{"job"},
+		Operation: OperationSum,
+		Type:      TypeCounter,
+	})
+
+	a := labels.FromStrings("__name__", "requests_total", "job", "app", "instance", "a")
+	b := labels.FromStrings("__name__", "requests_total", "job", "app", "instance", "b")
+	appendSamples(t, receiver, 10, a, b)
+	appendSamples(t, receiver, 15, a)
+	// b restarted, so it increased by its whole value.
+	appendSamples(t, receiver, 3, b)
+
+	require.NoError(t, c.flush(context.Background(), time.Now()))
+	require.Equal(t, []testSample{{labels: `{__name__="requests_total", job="app"}`, value: 28}}, receivedSamples(received))
+
+	// The aggregated counter keeps the increases of stale series.
+	received.Reset()
+	appendSamples(t, receiver, math.Float64frombits(value.StaleNaN), a)
+	appendSamples(t, receiver, 5, b)
+	require.NoError(t, c.flush(context.Background(), time.Now()))
+	require.Equal(t, []testSample{{labels: `{__name__="requests_total", job="app"}`, value: 30}}, receivedSamples(received))
+}
+
+func TestAggregate_Rollback(t *testing.T) {
+	c, receiver, received := newTestComponent(t, RuleArguments{
+		Metrics:   []string{"queue_length"},
+		Without:   []string{"instance"},
+		Operation: OperationSum,
+		Type:      TypeGauge,
+	})
+
+	app := receiver.Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("__name__", "queue_length", "instance", "a"), time.Now().UnixMilli(), 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Rollback())
+
+	require.NoError(t, c.flush(context.Background(), time.Now()))
+	require.Empty(t, received.Samples())
+}
+
+func TestRuleArguments_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       string
+		expectErr string
+	}{
+		{
+			name: "valid",
+			cfg: `
+				metrics   = ["queue_.*"]
+				without   = ["instance"]
+				operation = "max"`,
+		},
+		{
+			name: "by and without",
+			cfg: `
+				metrics = ["queue_.*"]
+				by      = ["job"]
+				without = ["instance"]`,
+			expectErr: "by and without are mutually exclusive",
+		},
+		{
+			name: "no labels",
+			cfg: `
+				metrics = ["queue_.*"]`,
+			expectErr: "one of by or without must be set",
+		},
+		{
+			name: "metric name",
+			cfg: `
+				metrics = ["queue_.*"]
+				by      = ["__name__"]`,
+			expectErr: "__name__ can't be used in by or without",
+		},
+		{
+			name: "counter average",
+			cfg: `
+				metrics   = ["requests_total"]
+				by        = ["job"]
+				operation = "avg"
+				type      = "counter"`,
+			expectErr: "counters only support the sum operation",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var args RuleArguments
+			err := river.Unmarshal([]byte(tc.cfg), &args)
+			if tc.expectErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.expectErr)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package aggregate
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+)
+
+// sample is a sample of a series matched by a rule.
+type sample struct {
+	rule   *rule
+	labels labels.Labels
+	value  float64
+}
+
+// source is the latest state of an input series of a group.
+type source struct {
+	value   float64
+	updated time.Time
+}
+
+// group is an output series aggregating the input series which have the same
+// labels once the labels of their rule are dropped.
+type group struct {
+	rule    *rule
+	labels  labels.Labels
+	sources map[string]*source // Input series by labels.
+
+	// total is the sum of the increases of the input series when the rule
+	// aggregates counters.
+	total float64
+	// updated is the last time an input series of the group received a
+	// sample.
+	updated time.Time
+}
+
+// result is an aggregated sample to forward.
+type result struct {
+	labels labels.Labels
+	value  float64
+}
+
+// aggregator aggregates the samples of input series into groups. It's safe
+// for concurrent use.
+type aggregator struct {
+	mut    sync.Mutex
+	groups map[string]*group // Groups by output labels.
+}
+
+func newAggregator() *aggregator {
+	return &aggregator{groups: make(map[string]*group)}
+}
+
+// reset forgets every group.
+func (a *aggregator) reset() {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.groups = make(map[string]*group)
+}
+
+// add adds samples received within a single transaction to their groups.
+func (a *aggregator) add(samples []sample, now time.Time) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	for _, s := range samples {
+		out := s.rule.outputLabels(s.labels)
+		groupKey := out.String()
+		g, ok := a.groups[groupKey]
+		if !ok {
+			g = &group{rule: s.rule, labels: out, sources: make(map[string]*source)}
+			a.groups[groupKey] = g
+		}
+
+		sourceKey := s.labels.String()
+		if value.IsStaleNaN(s.value) {
+			// The input series ended. Counters keep its increases.
+			delete(g.sources, sourceKey)
+			continue
+		}
+
+		src, ok := g.sources[sourceKey]
+		switch {
+		case !s.rule.counter:
+		case !ok:
+			g.total += s.value
+		case s.value < src.value:
+			// The counter was reset, so it increased by its whole value.
+			g.total += s.value
+		default:
+			g.total += s.value - src.value
+		}
+
+		if !ok {
+			src = &source{}
+			g.sources[sourceKey] = src
+		}
+		src.value, src.updated = s.value, now
+		g.updated = now
+	}
+}
+
+// flush returns the aggregated value of every group. Input series which
+// didn't receive samples since staleBefore are forgotten, and groups without
+// input series left are returned with a stale marker and forgotten.
+func (a *aggregator) flush(staleBefore time.Time) []result {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	results := make([]result, 0, len(a.groups))
+	for key, g := range a.groups {
+		for sourceKey, src := range g.sources {
+			if src.updated.Before(staleBefore) {
+				delete(g.sources, sourceKey)
+			}
+		}
+
+		if len(g.sources) == 0 {
+			// Counters stay exposed until all their input series are stale, so
+			// that their increases aren't lost when a single input ends.
+			if !g.rule.counter || g.updated.Before(staleBefore) {
+				delete(a.groups, key)
+				results = append(results, result{labels: g.labels, value: math.Float64frombits(value.StaleNaN)})
+				continue
+			}
+		}
+		results = append(results, result{labels: g.labels, value: g.value()})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return labels.Compare(results[i].labels, results[j].labels) < 0
+	})
+	return results
+}
+
+// len returns the number of groups.
+func (a *aggregator) len() int {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return len(a.groups)
+}
+
+// value returns the aggregated value of the input series of g.
+func (g *group) value() float64 {
+	if g.rule.counter {
+		return g.total
+	}
+
+	var result float64
+	first := true
+	for _, src := range g.sources {
+		switch g.rule.Operation {
+		case OperationSum, OperationAvg:
+			result += src.value
+		case OperationMin:
+			if first || src.value < result {
+				result = src.value
+			}
+		case OperationMax:
+			if first || src.value > result {
+				result = src.value
+			}
+		case OperationCount:
+			result++
+		}
+		first = false
+	}
+	if g.rule.Operation == OperationAvg && len(g.sources) > 0 {
+		result /= float64(len(g.sources))
+	}
+	return result
+}
@@ -0,0 +1,338 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/cluster"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/ckit/shard"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.dedup",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Reasons a sample is deduplicated, used as the reason label of the
+// deduplicated samples metric.
+const (
+	reasonReplica = "replica"
+	reasonCluster = "cluster"
+)
+
+// Arguments holds values which are used to configure the prometheus.dedup
+// component.
+type Arguments struct {
+	// Where deduplicated metrics are forwarded to.
+	ForwardTo []storage.Appendable `river:"forward_to,attr"`
+
+	// The label identifying a group of HA replicas.
+	GroupLabel string `river:"group_label,attr,optional"`
+
+	// The label identifying the replica within a group.
+	ReplicaLabel string `river:"replica_label,attr,optional"`
+
+	// How long the elected replica of a group can go without sending samples
+	// before another replica is elected.
+	FailoverTimeout time.Duration `river:"failover_timeout,attr,optional"`
+
+	Clustering cluster.ComponentBlock `river:"clustering,block,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	GroupLabel:      "cluster",
+	ReplicaLabel:    "__replica__",
+	FailoverTimeout: 30 * time.Second,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.ReplicaLabel == "" {
+		return fmt.Errorf("replica_label must not be empty")
+	}
+	if args.GroupLabel == args.ReplicaLabel {
+		return fmt.Errorf("group_label and replica_label must be different")
+	}
+	if args.FailoverTimeout <= 0 {
+		return fmt.Errorf("failover_timeout must be greater than 0")
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.dedup component.
+type Exports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+}
+
+// DebugInfo holds the debug information of the component.
+type DebugInfo struct {
+	ElectedReplicas []ElectedReplica `river:"elected_replica,block,optional"`
+}
+
+// ElectedReplica is the replica currently forwarded for a group.
+type ElectedReplica struct {
+	Group    string    `river:"group,attr"`
+	Replica  string    `river:"replica,attr"`
+	LastSeen time.Time `river:"last_seen,attr"`
+}
+
+// Component implements the prometheus.dedup component.
+type Component struct {
+	opts     component.Options
+	ls       labelstore.LabelStore
+	cluster  cluster.Cluster
+	elector  *elector
+	fanout   *prometheus.Fanout
+	receiver *prometheus.Interceptor
+
+	deduplicatedSamples *prometheus_client.CounterVec
+	failovers           prometheus_client.Counter
+
+	mut  sync.RWMutex
+	args Arguments
+
+	// owned caches whether the local node owns each group when clustering is
+	// enabled. It is reset whenever the cluster changes.
+	ownedMut sync.Mutex
+	owned    map[string]bool
+
+	// updated is written to whenever args updates.
+	updated chan struct{}
+}
+
+var (
+	_ component.Component      = (*Component)(nil)
+	_ component.DebugComponent = (*Component)(nil)
+	_ cluster.Component        = (*Component)(nil)
+)
+
+// New creates a new prometheus.dedup component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	data, err = o.GetServiceData(cluster.ServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get information about cluster: %w", err)
+	}
+
+	c := &Component{
+		opts:    o,
+		ls:      ls,
+		cluster: data.(cluster.Cluster),
+		elector: newElector(args.FailoverTimeout),
+		owned:   make(map[string]bool),
+		updated: make(chan struct{}, 1),
+	}
+	c.deduplicatedSamples = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_dedup_deduplicated_samples_total",
+		Help: "Total number of samples dropped because they came from a replica which isn't forwarded",
+	}, []string{"reason"})
+	c.failovers = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_dedup_failovers_total",
+		Help: "Total number of times a new replica was elected for a group after the failover timeout",
+	})
+	for _, metric := range []prometheus_client.Collector{c.deduplicatedSamples, c.failovers} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls)
+	c.receiver = prometheus.NewInterceptor(
+		c.fanout,
+		ls,
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			newLbls, ok := c.accept(l)
+			if !ok {
+				return ref, nil
+			}
+			return next.Append(0, newLbls, t, v)
+		}),
+		prometheus.WithHistogramHook(func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			newLbls, ok := c.accept(l)
+			if !ok {
+				return ref, nil
+			}
+			return next.AppendHistogram(0, newLbls, t, h, fh)
+		}),
+		prometheus.WithExemplarHook(func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			newLbls, ok := c.forwarded(l)
+			if !ok {
+				return ref, nil
+			}
+			return next.AppendExemplar(0, newLbls, e)
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			newLbls, ok := c.forwarded(l)
+			if !ok {
+				return ref, nil
+			}
+			return next.UpdateMetadata(0, newLbls, m)
+		}),
+	)
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// accept returns whether a sample of the series with labels l is forwarded,
+// and the labels to forward it with. Series without the replica label are
+// always forwarded unchanged.
+func (c *Component) accept(l labels.Labels) (labels.Labels, bool) {
+	c.mut.RLock()
+	args := c.args
+	c.mut.RUnlock()
+
+	replica := l.Get(args.ReplicaLabel)
+	if replica == "" {
+		return l, true
+	}
+	group := l.Get(args.GroupLabel)
+
+	if args.Clustering.Enabled && !c.ownsGroup(group) {
+		c.deduplicatedSamples.WithLabelValues(reasonCluster).Inc()
+		return nil, false
+	}
+
+	accepted, failover := c.elector.Accept(group, replica, time.Now())
+	if failover {
+		c.failovers.Inc()
+	}
+	if !accepted {
+		c.deduplicatedSamples.WithLabelValues(reasonReplica).Inc()
+		return nil, false
+	}
+	return labels.NewBuilder(l).Del(args.ReplicaLabel).Labels(), true
+}
+
+// forwarded is like accept for data which isn't a sample, such as exemplars
+// and metadata. It doesn't elect replicas.
+func (c *Component) forwarded(l labels.Labels) (labels.Labels, bool) {
+	c.mut.RLock()
+	args := c.args
+	c.mut.RUnlock()
+
+	replica := l.Get(args.ReplicaLabel)
+	if replica == "" {
+		return l, true
+	}
+	group := l.Get(args.GroupLabel)
+
+	if args.Clustering.Enabled && !c.ownsGroup(group) {
+		return nil, false
+	}
+	if !c.elector.Elected(group, replica) {
+		return nil, false
+	}
+	return labels.NewBuilder(l).Del(args.ReplicaLabel).Labels(), true
+}
+
+// ownsGroup returns whether the local node forwards the samples of group.
+func (c *Component) ownsGroup(group string) bool {
+	c.ownedMut.Lock()
+	defer c.ownedMut.Unlock()
+
+	if owned, ok := c.owned[group]; ok {
+		return owned
+	}
+
+	peers, err := c.cluster.Lookup(shard.StringKey(group), 1, shard.OpReadWrite)
+	// Lookup can only fail when asking for more owners than there are peers.
+	// In that case, fall back to owning the group.
+	owned := err != nil || len(peers) == 0 || peers[0].Self
+	c.owned[group] = owned
+	return owned
+}
+
+// NotifyClusterChange implements cluster.Component.
+func (c *Component) NotifyClusterChange() {
+	c.ownedMut.Lock()
+	defer c.ownedMut.Unlock()
+	c.owned = make(map[string]bool)
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	newTicker := func() *time.Ticker {
+		c.mut.RLock()
+		defer c.mut.RUnlock()
+		return time.NewTicker(c.args.FailoverTimeout)
+	}
+	ticker := newTicker()
+	defer func() { ticker.Stop() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.updated:
+			ticker.Stop()
+			ticker = newTicker()
+		case now := <-ticker.C:
+			// Groups whose elected replica timed out elect the next replica
+			// which sends a sample anyway, so they can be forgotten.
+			c.mut.RLock()
+			timeout := c.args.FailoverTimeout
+			c.mut.RUnlock()
+			c.elector.Sweep(now.Add(-timeout))
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+	c.args = newArgs
+	c.elector.SetFailoverTimeout(newArgs.FailoverTimeout)
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// DebugInfo implements component.DebugComponent.
+func (c *Component) DebugInfo() interface{} {
+	return DebugInfo{ElectedReplicas: c.elector.Elections()}
+}
@@ -0,0 +1,139 @@
+package dedup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/prometheus/prometheustest"
+	"github.com/grafana/agent/internal/service/cluster"
+	"github.com/grafana/ckit/peer"
+	"github.com/grafana/ckit/shard"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// ownerCluster is a cluster where the local node owns the keys in owned.
+type ownerCluster struct {
+	owned map[string]bool
+}
+
+func (c ownerCluster) Lookup(key shard.Key, _ int, _ shard.Op) ([]peer.Peer, error) {
+	for group := range c.owned {
+		if shard.StringKey(group) == key {
+			return []peer.Peer{{Name: "local", Self: true}}, nil
+		}
+	}
+	return []peer.Peer{{Name: "remote"}}, nil
+}
+
+func (c ownerCluster) Peers() []peer.Peer {
+	return []peer.Peer{{Name: "local", Self: true}, {Name: "remote"}}
+}
+
+func newTestComponent(t *testing.T, args Arguments, cl cluster.Cluster) (*Component, *prometheustest.Capture, storage.Appendable) {
+	h := prometheustest.NewHarness(t, "prometheus.dedup.test", map[string]interface{}{cluster.ServiceName: cl})
+	capture := &prometheustest.Capture{}
+	args.ForwardTo = []storage.Appendable{capture}
+
+	c, err := New(h.Options, args)
+	require.NoError(t, err)
+	return c, capture, h.Exports().(Exports).Receiver
+}
+
+func appendSeries(t *testing.T, receiver storage.Appendable, series ...labels.Labels) {
+	app := receiver.Appender(context.Background())
+	for _, l := range series {
+		_, err := app.Append(0, l, time.Now().UnixMilli(), 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+}
+
+func TestDedup(t *testing.T) {
+	c, received, receiver := newTestComponent(t, DefaultArguments, cluster.Mock())
+
+	appendSeries(t, receiver,
+		labels.FromStrings("__name__", "up", "cluster", "prod", "__replica__", "a"),
+		labels.FromStrings("__name__", "up", "cluster", "prod", "__replica__", "b"),
+		labels.FromStrings("__name__", "up", "cluster", "dev", "__replica__", "b"),
+		labels.FromStrings("__name__", "up", "cluster", "prod"),
+	)
+
+	require.Equal(t, []labels.Labels{
+		labels.FromStrings("__name__", "up", "cluster", "prod"),
+		labels.FromStrings("__name__", "up", "cluster", "dev"),
+		labels.FromStrings("__name__", "up", "cluster", "prod"),
+	}, received.Labels())
+	require.Equal(t, 1.0, testutil.ToFloat64(c.deduplicatedSamples.WithLabelValues(reasonReplica)))
+
+	info := c.DebugInfo().(DebugInfo)
+	require.Len(t, info.ElectedReplicas, 2)
+	require.Equal(t, "dev", info.ElectedReplicas[0].Group)
+	require.Equal(t, "a", info.ElectedReplicas[1].Replica)
+}
+
+func TestDedup_Clustering(t *testing.T) {
+	args := DefaultArguments
+	args.Clustering.Enabled = true
+	c, received, receiver := newTestComponent(t, args, ownerCluster{owned: map[string]bool{"prod": true}})
+
+	appendSeries(t, receiver,
+		labels.FromStrings("__name__", "up", "cluster", "prod", "__replica__", "a"),
+		labels.FromStrings("__name__", "up", "cluster", "dev", "__replica__", "a"),
+	)
+
+	require.Equal(t, []labels.Labels{
+		labels.FromStrings("__name__", "up", "cluster", "prod"),
+	}, received.Labels())
+	require.Equal(t, 1.0, testutil.ToFloat64(c.deduplicatedSamples.WithLabelValues(reasonCluster)))
+}
+
+func TestDedup_NativeHistograms(t *testing.T) {
+	c, received, receiver := newTestComponent(t, DefaultArguments, cluster.Mock())
+
+	h := &histogram.Histogram{Count: 1, Sum: 1}
+	fh := &histogram.FloatHistogram{Count: 1, Sum: 1}
+	app := receiver.Appender(context.Background())
+	for _, replica := range []string{"a", "b"} {
+		_, err := app.AppendHistogram(0, labels.FromStrings("__name__", "latency", "cluster", "prod", "__replica__", replica), 1000, h, nil)
+		require.NoError(t, err)
+		_, err = app.AppendHistogram(0, labels.FromStrings("__name__", "size", "cluster", "prod", "__replica__", replica), 1000, nil, fh)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	// Histograms of the replica which isn't elected are dropped, and the
+	// replica label is removed from the others.
+	require.Equal(t, []prometheustest.Sample{
+		{Labels: labels.FromStrings("__name__", "latency", "cluster", "prod"), T: 1000, H: h},
+		{Labels: labels.FromStrings("__name__", "size", "cluster", "prod"), T: 1000, FH: fh},
+	}, received.Samples())
+	require.Equal(t, 2.0, testutil.ToFloat64(c.deduplicatedSamples.WithLabelValues(reasonReplica)))
+}
+
+func TestRiverArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		forward_to       = []
+		failover_timeout = "1m"
+
+		clustering {
+			enabled = true
+		}
+	`), &args))
+	require.Equal(t, "cluster", args.GroupLabel)
+	require.Equal(t, "__replica__", args.ReplicaLabel)
+	require.Equal(t, time.Minute, args.FailoverTimeout)
+	require.True(t, args.Clustering.Enabled)
+
+	require.Error(t, river.Unmarshal([]byte(`
+		forward_to    = []
+		group_label   = "replica"
+		replica_label = "replica"
+	`), &args))
+}
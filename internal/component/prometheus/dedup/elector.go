@@ -0,0 +1,93 @@
+package dedup
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// election holds the elected replica of a group of HA replicas.
+type election struct {
+	replica  string
+	lastSeen time.Time
+}
+
+// elector elects which replica of each group is forwarded. The elected
+// replica of a group changes once it hasn't sent samples for the failover
+// timeout.
+type elector struct {
+	mut             sync.Mutex
+	failoverTimeout time.Duration
+	groups          map[string]*election
+}
+
+func newElector(failoverTimeout time.Duration) *elector {
+	return &elector{
+		failoverTimeout: failoverTimeout,
+		groups:          make(map[string]*election),
+	}
+}
+
+// Accept returns whether a sample from replica of group is forwarded, and
+// whether replica was elected in place of another replica.
+func (e *elector) Accept(group, replica string, now time.Time) (accepted, failover bool) {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	el, ok := e.groups[group]
+	switch {
+	case !ok:
+		e.groups[group] = &election{replica: replica, lastSeen: now}
+		return true, false
+	case el.replica == replica:
+		el.lastSeen = now
+		return true, false
+	case now.Sub(el.lastSeen) > e.failoverTimeout:
+		el.replica, el.lastSeen = replica, now
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+// Elected returns whether replica is the elected replica of group.
+func (e *elector) Elected(group, replica string) bool {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	el, ok := e.groups[group]
+	return ok && el.replica == replica
+}
+
+// Sweep forgets the groups whose elected replica wasn't seen since deadline.
+func (e *elector) Sweep(deadline time.Time) {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	for group, el := range e.groups {
+		if el.lastSeen.Before(deadline) {
+			delete(e.groups, group)
+		}
+	}
+}
+
+// SetFailoverTimeout changes the failover timeout, keeping the elected
+// replicas.
+func (e *elector) SetFailoverTimeout(timeout time.Duration) {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	e.failoverTimeout = timeout
+}
+
+// Elections returns the elected replica of each group, sorted by group.
+func (e *elector) Elections() []ElectedReplica {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	res := make([]ElectedReplica, 0, len(e.groups))
+	for group, el := range e.groups {
+		res = append(res, ElectedReplica{Group: group, Replica: el.replica, LastSeen: el.lastSeen})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Group < res[j].Group })
+	return res
+}
@@ -0,0 +1,40 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestElector(t *testing.T) {
+	var (
+		now = time.Now()
+		e   = newElector(30 * time.Second)
+	)
+
+	// The first replica to send a sample is elected.
+	accepted, failover := e.Accept("prod", "a", now)
+	require.True(t, accepted)
+	require.False(t, failover)
+	accepted, _ = e.Accept("prod", "b", now)
+	require.False(t, accepted)
+
+	// Groups are independent.
+	accepted, _ = e.Accept("dev", "b", now)
+	require.True(t, accepted)
+
+	// Another replica is elected once the elected one times out.
+	accepted, _ = e.Accept("prod", "b", now.Add(30*time.Second))
+	require.False(t, accepted)
+	accepted, failover = e.Accept("prod", "b", now.Add(31*time.Second))
+	require.True(t, accepted)
+	require.True(t, failover)
+	require.True(t, e.Elected("prod", "b"))
+	require.False(t, e.Elected("prod", "a"))
+
+	e.Sweep(now.Add(time.Second))
+	require.Equal(t, []ElectedReplica{
+		{Group: "prod", Replica: "b", LastSeen: now.Add(31 * time.Second)},
+	}, e.Elections())
+}
@@ -16,6 +16,22 @@ func init() {
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
 		Exports:   exporter.Exports{},
+		Lazy:      true,
+		Sandbox: component.SandboxExceptions{
+			// Container runtime state and the disks backing the container
+			// filesystems.
+			ReadPaths: []string{"/var/lib/docker", "/var/lib/containerd", "/dev/disk"},
+			ArgumentReadPaths: func(args component.Arguments) []string {
+				a := args.(Arguments)
+				var paths []string
+				for _, path := range []string{a.DockerTLSCert, a.DockerTLSKey, a.DockerTLSCA} {
+					if path != "" {
+						paths = append(paths, path)
+					}
+				}
+				return paths
+			},
+		},
 
 		Build: exporter.New(createExporter, "cadvisor"),
 	})
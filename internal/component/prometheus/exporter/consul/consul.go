@@ -16,6 +16,7 @@ func init() {
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
 		Exports:   exporter.Exports{},
+		Lazy:      true,
 
 		Build: exporter.New(createExporter, "consul"),
 	})
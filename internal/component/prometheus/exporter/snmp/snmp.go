@@ -22,6 +22,7 @@ func init() {
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
 		Exports:   exporter.Exports{},
+		Lazy:      true,
 
 		Build: exporter.NewWithTargetBuilder(createExporter, "snmp", buildSNMPTargets),
 	})
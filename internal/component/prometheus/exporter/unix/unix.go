@@ -13,6 +13,10 @@ func init() {
 		Stability: featuregate.StabilityStable,
 		Args:      Arguments{},
 		Exports:   exporter.Exports{},
+		Lazy:      true,
+		Sandbox: component.SandboxExceptions{
+			ArgumentReadPaths: sandboxReadPaths,
+		},
 
 		Build: exporter.New(createExporter, "unix"),
 	})
@@ -22,3 +26,17 @@ func createExporter(opts component.Options, args component.Arguments, defaultIns
 	a := args.(Arguments)
 	return integrations.NewIntegrationWithInstanceKey(opts.Logger, a.Convert(), defaultInstanceKey)
 }
+
+// sandboxReadPaths returns the paths the collectors read from. The root
+// filesystem path is only used to stat mount points, which doesn't need
+// access to it.
+func sandboxReadPaths(args component.Arguments) []string {
+	a := args.(Arguments)
+	var paths []string
+	for _, path := range []string{a.ProcFSPath, a.SysFSPath, a.UdevDataPath, a.Textfile.Directory} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,286 @@
+// Package expose implements the prometheus.expose component.
+package expose
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	http_service "github.com/grafana/agent/internal/service/http"
+	"github.com/grafana/agent/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.expose",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the prometheus.expose
+// component.
+type Arguments struct {
+	// Where received metrics are forwarded to, in addition to being exposed.
+	ForwardTo []storage.Appendable `river:"forward_to,attr,optional"`
+
+	// Maximum number of series exposed. Samples of new series are dropped
+	// once it's reached. 0 means no limit.
+	MaxSeries int `river:"max_series,attr,optional"`
+
+	// How long a series is exposed after its latest sample.
+	StaleAfter time.Duration `river:"stale_after,attr,optional"`
+
+	// Whether the timestamps of samples are exposed.
+	IncludeTimestamps bool `river:"include_timestamps,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	MaxSeries:  10000,
+	StaleAfter: 5 * time.Minute,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.MaxSeries < 0 {
+		return fmt.Errorf("max_series must not be negative")
+	}
+	if args.StaleAfter <= 0 {
+		return fmt.Errorf("stale_after must be greater than 0")
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.expose component.
+type Exports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+}
+
+// Component implements the prometheus.expose component.
+type Component struct {
+	opts     component.Options
+	store    *seriesStore
+	fanout   *prometheus.Fanout
+	receiver *prometheus.Interceptor
+
+	exposedSeries prometheus_client.GaugeFunc
+	droppedSeries prometheus_client.Counter
+
+	mut  sync.RWMutex
+	args Arguments
+
+	// updated is written to whenever args updates.
+	updated chan struct{}
+}
+
+var (
+	_ component.Component    = (*Component)(nil)
+	_ http_service.Component = (*Component)(nil)
+)
+
+// New creates a new prometheus.expose component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{
+		opts:    o,
+		store:   newSeriesStore(),
+		updated: make(chan struct{}, 1),
+	}
+	c.exposedSeries = prometheus_client.NewGaugeFunc(prometheus_client.GaugeOpts{
+		Name: "agent_prometheus_expose_series",
+		Help: "Number of series exposed by the component",
+	}, func() float64 { return float64(c.store.len()) })
+	c.droppedSeries = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_expose_dropped_series_total",
+		Help: "Total number of samples of new series dropped because max_series was reached",
+	})
+	for _, metric := range []prometheus_client.Collector{c.exposedSeries, c.droppedSeries} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls)
+	c.receiver = prometheus.NewInterceptor(
+		c.fanout,
+		ls,
+		prometheus.WithAppenderMiddleware(c.newMiddleware),
+	)
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newMiddleware returns a middleware buffering the samples, native
+// histograms, and metadata of an appender, which are only exposed once the appender commits.
+func (c *Component) newMiddleware() prometheus.Middleware {
+	var (
+		samples []sample
+		md      = make(map[string]metadata.Metadata)
+	)
+
+	return prometheus.MiddlewareFuncs{
+		OnAppend: func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			samples = append(samples, sample{labels: l, value: v, timestamp: t})
+			if next == nil {
+				return ref, nil
+			}
+			return next.Append(ref, l, t, v)
+		},
+		OnAppendHistogram: func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			samples = append(samples, sample{labels: l, h: h, fh: fh, timestamp: t})
+			if next == nil {
+				return ref, nil
+			}
+			return next.AppendHistogram(ref, l, t, h, fh)
+		},
+		OnUpdateMetadata: func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			if name := l.Get(labels.MetricName); name != "" {
+				md[name] = m
+			}
+			if next == nil {
+				return ref, nil
+			}
+			return next.UpdateMetadata(ref, l, m)
+		},
+		OnCommit: func(next storage.Appender) error {
+			c.mut.RLock()
+			maxSeries := c.args.MaxSeries
+			c.mut.RUnlock()
+
+			if dropped := c.store.apply(samples, md, maxSeries, time.Now()); dropped > 0 {
+				c.droppedSeries.Add(float64(dropped))
+			}
+			samples, md = nil, make(map[string]metadata.Metadata)
+
+			if next == nil {
+				return nil
+			}
+			return next.Commit()
+		},
+		OnRollback: func(next storage.Appender) error {
+			samples, md = nil, make(map[string]metadata.Metadata)
+
+			if next == nil {
+				return nil
+			}
+			return next.Rollback()
+		},
+	}
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	newTicker := func() *time.Ticker {
+		c.mut.RLock()
+		defer c.mut.RUnlock()
+		return time.NewTicker(c.args.StaleAfter)
+	}
+	ticker := newTicker()
+	defer func() { ticker.Stop() }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.updated:
+			ticker.Stop()
+			ticker = newTicker()
+		case now := <-ticker.C:
+			c.removeStale(now)
+		}
+	}
+}
+
+// removeStale removes the series which weren't updated within stale_after
+// of now.
+func (c *Component) removeStale(now time.Time) {
+	c.mut.RLock()
+	staleAfter := c.args.StaleAfter
+	c.mut.RUnlock()
+	c.store.removeStale(now.Add(-staleAfter))
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+	c.args = newArgs
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Handler implements http_service.Component. It serves the exposed series at
+// /metrics in the text, OpenMetrics, or protobuf format, depending on the
+// Accept header of the request. Only the protobuf format carries the buckets
+// of native histograms.
+func (c *Component) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		// Series can go stale between two runs of the cleanup, so they're
+		// removed before each scrape to never expose stale series.
+		c.removeStale(time.Now())
+
+		c.mut.RLock()
+		includeTimestamps := c.args.IncludeTimestamps
+		c.mut.RUnlock()
+
+		format := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+		w.Header().Set("Content-Type", string(format))
+
+		enc := expfmt.NewEncoder(w, format)
+		for _, mf := range c.store.metricFamilies(includeTimestamps) {
+			if err := enc.Encode(mf); err != nil {
+				level.Error(c.opts.Logger).Log("msg", "failed to encode metric family", "metric", mf.GetName(), "err", err)
+				return
+			}
+		}
+		if closer, ok := enc.(expfmt.Closer); ok {
+			if err := closer.Close(); err != nil {
+				level.Error(c.opts.Logger).Log("msg", "failed to close encoder", "err", err)
+			}
+		}
+	})
+	return mux
+}
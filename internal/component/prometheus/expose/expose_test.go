@@ -0,0 +1,207 @@
+package expose
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/prometheus/prometheustest"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/textparse"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestComponent(t *testing.T, args Arguments) (*Component, storage.Appendable) {
+	h := prometheustest.NewHarness(t, "prometheus.expose.test", nil)
+	c, err := New(h.Options, args)
+	require.NoError(t, err)
+	return c, h.Exports().(Exports).Receiver
+}
+
+func scrape(t *testing.T, c *Component, accept string) string {
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestExpose(t *testing.T) {
+	c, receiver := newTestComponent(t, DefaultArguments)
+
+	app := receiver.Appender(context.Background())
+	series := labels.FromStrings("__name__", "requests_total", "job", "test", "__replica__", "a")
+	_, err := app.Append(0, series, 1000, 5)
+	require.NoError(t, err)
+	_, err = app.UpdateMetadata(0, series, metadata.Metadata{Type: textparse.MetricTypeCounter, Help: "Total requests."})
+	require.NoError(t, err)
+	_, err = app.Append(0, labels.FromStrings("__name__", "temperature", "room", "kitchen"), 1000, 21.5)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	expect := `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{job="test"} 5
+# TYPE temperature untyped
+temperature{room="kitchen"} 21.5
+`
+	require.Equal(t, expect, scrape(t, c, ""))
+
+	openMetrics := scrape(t, c, "application/openmetrics-text; version=1.0.0")
+	require.Contains(t, openMetrics, `requests_total{job="test"} 5.0`)
+	require.Contains(t, openMetrics, "# EOF")
+}
+
+func TestExpose_Rollback(t *testing.T) {
+	c, receiver := newTestComponent(t, DefaultArguments)
+
+	app := receiver.Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("__name__", "up"), 1000, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Rollback())
+
+	require.Empty(t, scrape(t, c, ""))
+}
+
+func TestExpose_MaxSeries(t *testing.T) {
+	args := DefaultArguments
+	args.MaxSeries = 2
+	c, receiver := newTestComponent(t, args)
+
+	app := receiver.Appender(context.Background())
+	for _, instance := range []string{"a", "b", "c"} {
+		_, err := app.Append(0, labels.FromStrings("__name__", "up", "instance", instance), 1000, 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	require.Equal(t, 2, c.store.len())
+	require.Equal(t, 1.0, testutil.ToFloat64(c.droppedSeries))
+
+	// Samples of exposed series are still accepted once the limit is reached.
+	app = receiver.Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("__name__", "up", "instance", "a"), 2000, 0)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.Contains(t, scrape(t, c, ""), `up{instance="a"} 0`)
+}
+
+func TestExpose_Staleness(t *testing.T) {
+	c, receiver := newTestComponent(t, DefaultArguments)
+
+	app := receiver.Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("__name__", "up", "instance", "a"), 1000, 1)
+	require.NoError(t, err)
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "instance", "b"), 1000, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.Equal(t, 2, c.store.len())
+
+	// Stale markers end series immediately.
+	app = receiver.Appender(context.Background())
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "instance", "a"), 2000, math.Float64frombits(value.StaleNaN))
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.Equal(t, 1, c.store.len())
+
+	// Series without samples for stale_after are removed.
+	c.removeStale(time.Now().Add(DefaultArguments.StaleAfter + time.Second))
+	require.Equal(t, 0, c.store.len())
+}
+
+func TestExpose_NativeHistograms(t *testing.T) {
+	c, receiver := newTestComponent(t, DefaultArguments)
+
+	h := &histogram.Histogram{
+		Count:           5,
+		Sum:             12.5,
+		Schema:          0,
+		ZeroThreshold:   0.001,
+		ZeroCount:       1,
+		PositiveSpans:   []histogram.Span{{Offset: 0, Length: 2}},
+		PositiveBuckets: []int64{1, 2}, // Delta-encoded counts 1 and 3.
+	}
+	fh := &histogram.FloatHistogram{
+		Count:           2.5,
+		Sum:             4,
+		Schema:          1,
+		PositiveSpans:   []histogram.Span{{Offset: 1, Length: 1}},
+		PositiveBuckets: []float64{2.5},
+	}
+
+	app := receiver.Appender(context.Background())
+	_, err := app.AppendHistogram(0, labels.FromStrings("__name__", "latency", "job", "test"), 1000, h, nil)
+	require.NoError(t, err)
+	_, err = app.AppendHistogram(0, labels.FromStrings("__name__", "size", "job", "test"), 1000, nil, fh)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	// The buckets of native histograms are only exposed in the protobuf format.
+	body := scrape(t, c, "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited")
+	dec := expfmt.NewDecoder(strings.NewReader(body), expfmt.NewFormat(expfmt.TypeProtoDelim))
+
+	var latency dto.MetricFamily
+	require.NoError(t, dec.Decode(&latency))
+	require.Equal(t, "latency", latency.GetName())
+	require.Equal(t, dto.MetricType_HISTOGRAM, latency.GetType())
+	require.Len(t, latency.Metric, 1)
+	got := latency.Metric[0].GetHistogram()
+	require.Equal(t, uint64(5), got.GetSampleCount())
+	require.Equal(t, 12.5, got.GetSampleSum())
+	require.Equal(t, 0.001, got.GetZeroThreshold())
+	require.Equal(t, uint64(1), got.GetZeroCount())
+	require.Equal(t, []int64{1, 2}, got.GetPositiveDelta())
+	require.Len(t, got.GetPositiveSpan(), 1)
+	require.Equal(t, uint32(2), got.GetPositiveSpan()[0].GetLength())
+
+	var size dto.MetricFamily
+	require.NoError(t, dec.Decode(&size))
+	require.Equal(t, "size", size.GetName())
+	require.Equal(t, dto.MetricType_HISTOGRAM, size.GetType())
+	got = size.Metric[0].GetHistogram()
+	require.Equal(t, 2.5, got.GetSampleCountFloat())
+	require.Equal(t, int32(1), got.GetSchema())
+	require.Equal(t, []float64{2.5}, got.GetPositiveCount())
+	require.Equal(t, int32(1), got.GetPositiveSpan()[0].GetOffset())
+
+	// The text format exposes their count and sum.
+	text := scrape(t, c, "")
+	require.Contains(t, text, "# TYPE latency histogram")
+	require.Contains(t, text, `latency_count{job="test"} 5`)
+	require.Contains(t, text, `size_sum{job="test"} 4`)
+
+	// Stale markers end native histograms as well.
+	app = receiver.Appender(context.Background())
+	_, err = app.AppendHistogram(0, labels.FromStrings("__name__", "latency", "job", "test"), 2000, &histogram.Histogram{Sum: math.Float64frombits(value.StaleNaN)}, nil)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.Equal(t, 1, c.store.len())
+}
+
+func TestArguments_Validate(t *testing.T) {
+	args := DefaultArguments
+	args.MaxSeries = -1
+	require.EqualError(t, args.Validate(), "max_series must not be negative")
+
+	args = DefaultArguments
+	args.StaleAfter = 0
+	require.EqualError(t, args.Validate(), "stale_after must be greater than 0")
+}
@@ -0,0 +1,265 @@
+package expose
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/textparse"
+	"github.com/prometheus/prometheus/model/value"
+)
+
+// sample is the latest sample of a series. Samples of native histograms
+// have either h or fh set instead of value.
+type sample struct {
+	labels    labels.Labels
+	value     float64
+	h         *histogram.Histogram
+	fh        *histogram.FloatHistogram
+	timestamp int64     // Timestamp of the sample in milliseconds.
+	updated   time.Time // When the sample was received.
+}
+
+// isStale reports whether smp is a staleness marker.
+func (smp *sample) isStale() bool {
+	switch {
+	case smp.h != nil:
+		return value.IsStaleNaN(smp.h.Sum)
+	case smp.fh != nil:
+		return value.IsStaleNaN(smp.fh.Sum)
+	default:
+		return value.IsStaleNaN(smp.value)
+	}
+}
+
+// isHistogram reports whether smp is a sample of a native histogram.
+func (smp *sample) isHistogram() bool {
+	return smp.h != nil || smp.fh != nil
+}
+
+// seriesStore holds the latest sample of each series, and the metadata of
+// each metric.
+type seriesStore struct {
+	mut      sync.Mutex
+	series   map[string]*sample // Latest sample of each series by labels.
+	metadata map[string]metadata.Metadata
+}
+
+func newSeriesStore() *seriesStore {
+	return &seriesStore{
+		series:   make(map[string]*sample),
+		metadata: make(map[string]metadata.Metadata),
+	}
+}
+
+// apply stores samples and metadata received within a single transaction.
+// Samples of new series are dropped once the store holds maxSeries series,
+// unless maxSeries is 0. apply returns the number of dropped series.
+func (s *seriesStore) apply(samples []sample, md map[string]metadata.Metadata, maxSeries int, now time.Time) (dropped int) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for name, m := range md {
+		s.metadata[name] = m
+	}
+
+	for _, smp := range samples {
+		key := smp.labels.String()
+		if smp.isStale() {
+			// The series ended, so it's removed instead of being exposed with a
+			// stale value.
+			delete(s.series, key)
+			continue
+		}
+
+		existing, ok := s.series[key]
+		if !ok {
+			if maxSeries > 0 && len(s.series) >= maxSeries {
+				dropped++
+				continue
+			}
+			existing = &sample{labels: smp.labels}
+			s.series[key] = existing
+		}
+		if smp.timestamp < existing.timestamp {
+			// Keep the latest sample when samples arrive out of order.
+			continue
+		}
+		existing.value, existing.h, existing.fh = smp.value, smp.h, smp.fh
+		existing.timestamp, existing.updated = smp.timestamp, now
+	}
+	return dropped
+}
+
+// removeStale removes the series which weren't updated since before.
+func (s *seriesStore) removeStale(before time.Time) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	names := make(map[string]struct{})
+	for key, smp := range s.series {
+		if smp.updated.Before(before) {
+			delete(s.series, key)
+			continue
+		}
+		names[smp.labels.Get(model.MetricNameLabel)] = struct{}{}
+	}
+
+	// Forget the metadata of metrics without series left.
+	for name := range s.metadata {
+		if _, ok := names[name]; !ok {
+			delete(s.metadata, name)
+		}
+	}
+}
+
+// len returns the number of series in the store.
+func (s *seriesStore) len() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return len(s.series)
+}
+
+// metricFamilies returns the series of the store grouped by metric name.
+// Counters and gauges are typed according to their metadata, and metrics with
+// native histograms are histograms; other metrics are untyped, since their
+// series aren't grouped by family. Labels starting with __ are reserved and
+// aren't exposed.
+func (s *seriesStore) metricFamilies(includeTimestamps bool) []*dto.MetricFamily {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	// A family can't mix native histograms with float samples, so the float
+	// samples of metrics with native histograms aren't exposed.
+	histograms := make(map[string]struct{})
+	for _, smp := range s.series {
+		if smp.isHistogram() {
+			histograms[smp.labels.Get(model.MetricNameLabel)] = struct{}{}
+		}
+	}
+
+	families := make(map[string]*dto.MetricFamily)
+	for _, smp := range s.series {
+		name := smp.labels.Get(model.MetricNameLabel)
+		if name == "" {
+			continue
+		}
+		_, isHistogram := histograms[name]
+		if isHistogram && !smp.isHistogram() {
+			continue
+		}
+
+		mf, ok := families[name]
+		if !ok {
+			mf = &dto.MetricFamily{Name: proto(name), Type: dto.MetricType_UNTYPED.Enum()}
+			md, hasMetadata := s.metadata[name]
+			if hasMetadata {
+				mf.Help = proto(md.Help)
+			}
+			switch {
+			case isHistogram:
+				mf.Type = dto.MetricType_HISTOGRAM.Enum()
+			case hasMetadata && md.Type == textparse.MetricTypeCounter:
+				mf.Type = dto.MetricType_COUNTER.Enum()
+			case hasMetadata && md.Type == textparse.MetricTypeGauge:
+				mf.Type = dto.MetricType_GAUGE.Enum()
+			}
+			families[name] = mf
+		}
+
+		m := &dto.Metric{}
+		smp.labels.Range(func(l labels.Label) {
+			if len(l.Name) >= 2 && l.Name[:2] == "__" {
+				return
+			}
+			m.Label = append(m.Label, &dto.LabelPair{Name: proto(l.Name), Value: proto(l.Value)})
+		})
+		switch mf.GetType() {
+		case dto.MetricType_HISTOGRAM:
+			m.Histogram = nativeHistogram(smp)
+		case dto.MetricType_COUNTER:
+			m.Counter = &dto.Counter{Value: proto(smp.value)}
+		case dto.MetricType_GAUGE:
+			m.Gauge = &dto.Gauge{Value: proto(smp.value)}
+		default:
+			m.Untyped = &dto.Untyped{Value: proto(smp.value)}
+		}
+		if includeTimestamps {
+			m.TimestampMs = proto(smp.timestamp)
+		}
+		mf.Metric = append(mf.Metric, m)
+	}
+
+	result := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		sort.Slice(mf.Metric, func(i, j int) bool {
+			return labelPairsLess(mf.Metric[i].Label, mf.Metric[j].Label)
+		})
+		result = append(result, mf)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetName() < result[j].GetName()
+	})
+	return result
+}
+
+// nativeHistogram converts the native histogram of smp to its protobuf
+// representation. Integer histograms keep their delta-encoded bucket counts,
+// while float histograms use absolute counts.
+func nativeHistogram(smp *sample) *dto.Histogram {
+	if fh := smp.fh; fh != nil {
+		return &dto.Histogram{
+			// The text formats only read the integer count.
+			SampleCount:      proto(uint64(fh.Count)),
+			SampleCountFloat: proto(fh.Count),
+			SampleSum:        proto(fh.Sum),
+			Schema:           proto(fh.Schema),
+			ZeroThreshold:    proto(fh.ZeroThreshold),
+			ZeroCountFloat:   proto(fh.ZeroCount),
+			NegativeSpan:     bucketSpans(fh.NegativeSpans),
+			NegativeCount:    fh.NegativeBuckets,
+			PositiveSpan:     bucketSpans(fh.PositiveSpans),
+			PositiveCount:    fh.PositiveBuckets,
+		}
+	}
+
+	h := smp.h
+	return &dto.Histogram{
+		SampleCount:   proto(h.Count),
+		SampleSum:     proto(h.Sum),
+		Schema:        proto(h.Schema),
+		ZeroThreshold: proto(h.ZeroThreshold),
+		ZeroCount:     proto(h.ZeroCount),
+		NegativeSpan:  bucketSpans(h.NegativeSpans),
+		NegativeDelta: h.NegativeBuckets,
+		PositiveSpan:  bucketSpans(h.PositiveSpans),
+		PositiveDelta: h.PositiveBuckets,
+	}
+}
+
+func bucketSpans(spans []histogram.Span) []*dto.BucketSpan {
+	res := make([]*dto.BucketSpan, 0, len(spans))
+	for _, s := range spans {
+		res = append(res, &dto.BucketSpan{Offset: proto(s.Offset), Length: proto(s.Length)})
+	}
+	return res
+}
+
+func labelPairsLess(a, b []*dto.LabelPair) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].GetName() != b[i].GetName() {
+			return a[i].GetName() < b[i].GetName()
+		}
+		if a[i].GetValue() != b[i].GetValue() {
+			return a[i].GetValue() < b[i].GetValue()
+		}
+	}
+	return len(a) < len(b)
+}
+
+func proto[T any](v T) *T { return &v }
@@ -0,0 +1,183 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.filter",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the prometheus.filter
+// component.
+type Arguments struct {
+	// Where kept metrics are forwarded to.
+	ForwardTo []storage.Appendable `river:"forward_to,attr"`
+
+	// The name of the profile listing the metrics to keep.
+	Profile string `river:"profile,attr"`
+
+	// Metrics to keep in addition to the metrics of the profile.
+	KeepMetrics []string `river:"keep_metrics,attr,optional"`
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if _, ok := profiles[args.Profile]; !ok {
+		return fmt.Errorf("unknown profile %q, must be one of %s", args.Profile, strings.Join(profileNames(), ", "))
+	}
+	for _, name := range args.KeepMetrics {
+		if !model.IsValidMetricName(model.LabelValue(name)) {
+			return fmt.Errorf("invalid metric name %q in keep_metrics", name)
+		}
+	}
+	return nil
+}
+
+// profileNames returns the sorted names of the profiles.
+func profileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Exports holds values which are exported by the prometheus.filter component.
+type Exports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+}
+
+// Component implements the prometheus.filter component.
+type Component struct {
+	opts     component.Options
+	fanout   *prometheus.Fanout
+	receiver *prometheus.Interceptor
+
+	droppedSamples prometheus_client.Counter
+
+	mut  sync.RWMutex
+	keep map[string]struct{}
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+)
+
+// New creates a new prometheus.filter component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	ls := data.(labelstore.LabelStore)
+
+	c := &Component{opts: o}
+	c.droppedSamples = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_filter_dropped_samples_total",
+		Help: "Total number of samples, native histograms, and exemplars dropped because their metric isn't kept",
+	})
+	if err := o.Registerer.Register(c.droppedSamples); err != nil {
+		return nil, err
+	}
+
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, ls)
+	c.receiver = prometheus.NewInterceptor(
+		c.fanout,
+		ls,
+		prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			if !c.kept(l) {
+				c.droppedSamples.Inc()
+				return ref, nil
+			}
+			return next.Append(ref, l, t, v)
+		}),
+		prometheus.WithHistogramHook(func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			if !c.kept(l) {
+				c.droppedSamples.Inc()
+				return ref, nil
+			}
+			return next.AppendHistogram(ref, l, t, h, fh)
+		}),
+		prometheus.WithExemplarHook(func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			if !c.kept(l) {
+				c.droppedSamples.Inc()
+				return ref, nil
+			}
+			return next.AppendExemplar(ref, l, e)
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			if !c.kept(l) {
+				return ref, nil
+			}
+			return next.UpdateMetadata(ref, l, m)
+		}),
+	)
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// kept returns whether the series with labels l is kept.
+func (c *Component) kept(l labels.Labels) bool {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+	_, ok := c.keep[l.Get(model.MetricNameLabel)]
+	return ok
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	keep := make(map[string]struct{})
+	for _, lists := range [][]string{scrapeMetrics, profiles[newArgs.Profile], newArgs.KeepMetrics} {
+		for _, name := range lists {
+			keep[name] = struct{}{}
+		}
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.keep = keep
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	return nil
+}
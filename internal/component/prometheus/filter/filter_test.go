@@ -0,0 +1,116 @@
+package filter
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/prometheus/prometheustest"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestComponent(t *testing.T, args Arguments) (*Component, *prometheustest.Capture, storage.Appendable) {
+	h := prometheustest.NewHarness(t, "prometheus.filter.test", nil)
+	capture := &prometheustest.Capture{}
+	args.ForwardTo = []storage.Appendable{capture}
+
+	c, err := New(h.Options, args)
+	require.NoError(t, err)
+	return c, capture, h.Exports().(Exports).Receiver
+}
+
+func TestFilter(t *testing.T) {
+	c, received, receiver := newTestComponent(t, Arguments{
+		Profile:     "node_minimal",
+		KeepMetrics: []string{"node_hwmon_temp_celsius"},
+	})
+
+	app := receiver.Appender(context.Background())
+	for _, l := range []labels.Labels{
+		labels.FromStrings("__name__", "node_cpu_seconds_total", "cpu", "0"),
+		labels.FromStrings("__name__", "node_scrape_collector_duration_seconds", "collector", "cpu"),
+		labels.FromStrings("__name__", "node_hwmon_temp_celsius"),
+		labels.FromStrings("__name__", "up"),
+	} {
+		_, err := app.Append(0, l, time.Now().UnixMilli(), 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	require.Equal(t, []labels.Labels{
+		labels.FromStrings("__name__", "node_cpu_seconds_total", "cpu", "0"),
+		labels.FromStrings("__name__", "node_hwmon_temp_celsius"),
+		labels.FromStrings("__name__", "up"),
+	}, received.Labels())
+	require.Equal(t, 1.0, testutil.ToFloat64(c.droppedSamples))
+}
+
+func TestFilter_DroppedSamples(t *testing.T) {
+	c, received, receiver := newTestComponent(t, Arguments{Profile: "node_minimal"})
+
+	kept := labels.FromStrings("__name__", "node_load1")
+	dropped := labels.FromStrings("__name__", "http_request_duration_seconds")
+	ts := time.Now().UnixMilli()
+
+	app := receiver.Appender(context.Background())
+	for _, l := range []labels.Labels{kept, dropped} {
+		_, err := app.Append(0, l, ts, 1)
+		require.NoError(t, err)
+		_, err = app.AppendHistogram(0, l, ts, &histogram.Histogram{Count: 1, Sum: 1}, nil)
+		require.NoError(t, err)
+		_, err = app.AppendHistogram(0, l, ts, nil, &histogram.FloatHistogram{Count: 1, Sum: 1})
+		require.NoError(t, err)
+		_, err = app.AppendExemplar(0, l, exemplar.Exemplar{Labels: labels.FromStrings("trace_id", "abc"), Value: 1, Ts: ts, HasTs: true})
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	require.Equal(t, []labels.Labels{kept, kept, kept}, received.Labels())
+	require.Equal(t, 4.0, testutil.ToFloat64(c.droppedSamples))
+}
+
+func TestProfiles(t *testing.T) {
+	for name, metrics := range profiles {
+		t.Run(name, func(t *testing.T) {
+			require.NotEmpty(t, metrics)
+			require.True(t, sort.StringsAreSorted(metrics), "metrics must be sorted")
+			for i, metric := range metrics {
+				require.True(t, model.IsValidMetricName(model.LabelValue(metric)), "invalid metric name %q", metric)
+				if i > 0 {
+					require.NotEqual(t, metrics[i-1], metric, "duplicate metric")
+				}
+			}
+		})
+	}
+}
+
+func TestRiverArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		forward_to   = []
+		profile      = "kubelet_minimal"
+		keep_metrics = ["kubelet_evictions"]
+	`), &args))
+	require.Equal(t, "kubelet_minimal", args.Profile)
+
+	err := river.Unmarshal([]byte(`
+		forward_to = []
+		profile    = "unknown"
+	`), &args)
+	require.ErrorContains(t, err, `unknown profile "unknown", must be one of cadvisor_minimal, kube_state_metrics_minimal, kubelet_minimal, node_minimal`)
+
+	err = river.Unmarshal([]byte(`
+		forward_to   = []
+		profile      = "node_minimal"
+		keep_metrics = ["not a metric"]
+	`), &args)
+	require.ErrorContains(t, err, `invalid metric name "not a metric" in keep_metrics`)
+}
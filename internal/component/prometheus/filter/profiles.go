@@ -0,0 +1,170 @@
+package filter
+
+// Profiles are curated lists of the metrics used by the dashboards and alerts
+// of common integrations. Metric names are sorted within each profile.
+var profiles = map[string][]string{
+	// Metrics of node_exporter and prometheus.exporter.unix.
+	"node_minimal": {
+		"node_boot_time_seconds",
+		"node_context_switches_total",
+		"node_cpu_seconds_total",
+		"node_disk_io_time_seconds_total",
+		"node_disk_io_time_weighted_seconds_total",
+		"node_disk_read_bytes_total",
+		"node_disk_reads_completed_total",
+		"node_disk_writes_completed_total",
+		"node_disk_written_bytes_total",
+		"node_filefd_allocated",
+		"node_filefd_maximum",
+		"node_filesystem_avail_bytes",
+		"node_filesystem_device_error",
+		"node_filesystem_files",
+		"node_filesystem_files_free",
+		"node_filesystem_readonly",
+		"node_filesystem_size_bytes",
+		"node_intr_total",
+		"node_load1",
+		"node_load15",
+		"node_load5",
+		"node_memory_Buffers_bytes",
+		"node_memory_Cached_bytes",
+		"node_memory_MemAvailable_bytes",
+		"node_memory_MemFree_bytes",
+		"node_memory_MemTotal_bytes",
+		"node_memory_Slab_bytes",
+		"node_memory_SwapFree_bytes",
+		"node_memory_SwapTotal_bytes",
+		"node_network_receive_bytes_total",
+		"node_network_receive_drop_total",
+		"node_network_receive_errs_total",
+		"node_network_receive_packets_total",
+		"node_network_transmit_bytes_total",
+		"node_network_transmit_drop_total",
+		"node_network_transmit_errs_total",
+		"node_network_transmit_packets_total",
+		"node_os_info",
+		"node_time_seconds",
+		"node_timex_offset_seconds",
+		"node_timex_sync_status",
+		"node_uname_info",
+		"node_vmstat_pgmajfault",
+		"node_vmstat_pgpgin",
+		"node_vmstat_pgpgout",
+		"node_vmstat_pswpin",
+		"node_vmstat_pswpout",
+	},
+
+	// Metrics of the kubelet /metrics endpoint.
+	"kubelet_minimal": {
+		"go_goroutines",
+		"kubelet_certificate_manager_client_expiration_renew_errors",
+		"kubelet_certificate_manager_client_ttl_seconds",
+		"kubelet_certificate_manager_server_ttl_seconds",
+		"kubelet_cgroup_manager_duration_seconds_bucket",
+		"kubelet_cgroup_manager_duration_seconds_count",
+		"kubelet_node_config_error",
+		"kubelet_node_name",
+		"kubelet_pleg_relist_duration_seconds_bucket",
+		"kubelet_pleg_relist_duration_seconds_count",
+		"kubelet_pleg_relist_interval_seconds_bucket",
+		"kubelet_pod_start_duration_seconds_bucket",
+		"kubelet_pod_start_duration_seconds_count",
+		"kubelet_pod_worker_duration_seconds_bucket",
+		"kubelet_pod_worker_duration_seconds_count",
+		"kubelet_running_containers",
+		"kubelet_running_pods",
+		"kubelet_runtime_operations_errors_total",
+		"kubelet_runtime_operations_total",
+		"kubelet_server_expiration_renew_errors",
+		"kubelet_volume_stats_available_bytes",
+		"kubelet_volume_stats_capacity_bytes",
+		"kubelet_volume_stats_inodes",
+		"kubelet_volume_stats_inodes_used",
+		"process_cpu_seconds_total",
+		"process_resident_memory_bytes",
+		"rest_client_requests_total",
+		"storage_operation_duration_seconds_count",
+		"storage_operation_errors_total",
+		"volume_manager_total_volumes",
+	},
+
+	// Metrics of the kubelet /metrics/cadvisor endpoint and
+	// prometheus.exporter.cadvisor.
+	"cadvisor_minimal": {
+		"container_cpu_cfs_periods_total",
+		"container_cpu_cfs_throttled_periods_total",
+		"container_cpu_usage_seconds_total",
+		"container_fs_reads_bytes_total",
+		"container_fs_reads_total",
+		"container_fs_writes_bytes_total",
+		"container_fs_writes_total",
+		"container_memory_cache",
+		"container_memory_rss",
+		"container_memory_swap",
+		"container_memory_working_set_bytes",
+		"container_network_receive_bytes_total",
+		"container_network_receive_packets_dropped_total",
+		"container_network_receive_packets_total",
+		"container_network_transmit_bytes_total",
+		"container_network_transmit_packets_dropped_total",
+		"container_network_transmit_packets_total",
+		"machine_memory_bytes",
+	},
+
+	// Metrics of kube-state-metrics.
+	"kube_state_metrics_minimal": {
+		"kube_daemonset_status_current_number_scheduled",
+		"kube_daemonset_status_desired_number_scheduled",
+		"kube_daemonset_status_number_available",
+		"kube_daemonset_status_number_misscheduled",
+		"kube_daemonset_status_updated_number_scheduled",
+		"kube_deployment_metadata_generation",
+		"kube_deployment_spec_replicas",
+		"kube_deployment_status_observed_generation",
+		"kube_deployment_status_replicas_available",
+		"kube_deployment_status_replicas_updated",
+		"kube_horizontalpodautoscaler_spec_max_replicas",
+		"kube_horizontalpodautoscaler_spec_min_replicas",
+		"kube_horizontalpodautoscaler_status_current_replicas",
+		"kube_horizontalpodautoscaler_status_desired_replicas",
+		"kube_job_failed",
+		"kube_job_status_active",
+		"kube_job_status_start_time",
+		"kube_namespace_status_phase",
+		"kube_node_info",
+		"kube_node_spec_taint",
+		"kube_node_status_allocatable",
+		"kube_node_status_capacity",
+		"kube_node_status_condition",
+		"kube_persistentvolumeclaim_resource_requests_storage_bytes",
+		"kube_pod_container_info",
+		"kube_pod_container_resource_limits",
+		"kube_pod_container_resource_requests",
+		"kube_pod_container_status_restarts_total",
+		"kube_pod_container_status_waiting_reason",
+		"kube_pod_info",
+		"kube_pod_owner",
+		"kube_pod_status_phase",
+		"kube_pod_status_reason",
+		"kube_replicaset_owner",
+		"kube_resourcequota",
+		"kube_statefulset_metadata_generation",
+		"kube_statefulset_replicas",
+		"kube_statefulset_status_current_revision",
+		"kube_statefulset_status_observed_generation",
+		"kube_statefulset_status_replicas",
+		"kube_statefulset_status_replicas_ready",
+		"kube_statefulset_status_replicas_updated",
+		"kube_statefulset_status_update_revision",
+	},
+}
+
+// scrapeMetrics are the series added by prometheus.scrape for every target.
+// They're kept by every profile.
+var scrapeMetrics = []string{
+	"scrape_duration_seconds",
+	"scrape_samples_post_metric_relabeling",
+	"scrape_samples_scraped",
+	"scrape_series_added",
+	"up",
+}
@@ -11,14 +11,19 @@ import (
 	"github.com/prometheus/prometheus/storage"
 )
 
-// Interceptor is a storage.Appendable which invokes callback functions upon
-// getting data. Interceptor should not be modified once created. All callback
-// fields are optional.
+// Interceptor is a storage.Appendable which passes data through a chain of
+// middlewares before sending it to the next Appendable. Interceptor should not
+// be modified once created.
+//
+// Middlewares are called in the order they were provided through
+// WithMiddleware, followed by the middlewares created for each Appender
+// through WithAppenderMiddleware, followed by any hook functions. Every middleware is given
+// the global ref ID of the series; staleness tracking of the series is
+// handled by Interceptor.
 type Interceptor struct {
-	onAppend          func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error)
-	onAppendExemplar  func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error)
-	onUpdateMetadata  func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error)
-	onAppendHistogram func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error)
+	middlewares         []Middleware
+	appenderMiddlewares []func() Middleware
+	hooks               MiddlewareFuncs
 
 	// next is the next appendable to pass in the chain.
 	next storage.Appendable
@@ -29,7 +34,8 @@ type Interceptor struct {
 var _ storage.Appendable = (*Interceptor)(nil)
 
 // NewInterceptor creates a new Interceptor storage.Appendable. Options can be
-// provided to NewInterceptor to install custom hooks for different methods.
+// provided to NewInterceptor to install middlewares or custom hooks for
+// different methods.
 func NewInterceptor(next storage.Appendable, ls labelstore.LabelStore, opts ...InterceptorOption) *Interceptor {
 	i := &Interceptor{
 		next: next,
@@ -44,53 +50,81 @@ func NewInterceptor(next storage.Appendable, ls labelstore.LabelStore, opts ...I
 // InterceptorOption is an option argument passed to NewInterceptor.
 type InterceptorOption func(*Interceptor)
 
+// WithMiddleware returns an InterceptorOption which appends middlewares to
+// the chain of the Interceptor.
+func WithMiddleware(middlewares ...Middleware) InterceptorOption {
+	return func(i *Interceptor) {
+		i.middlewares = append(i.middlewares, middlewares...)
+	}
+}
+
+// WithAppenderMiddleware returns an InterceptorOption which appends a
+// middleware created by newMiddleware for each Appender to the chain of the
+// Interceptor. Unlike the middlewares passed to WithMiddleware, these aren't
+// shared between Appenders, so they may hold state for the lifetime of a
+// single transaction and don't need to be safe for concurrent use.
+func WithAppenderMiddleware(newMiddleware func() Middleware) InterceptorOption {
+	return func(i *Interceptor) {
+		i.appenderMiddlewares = append(i.appenderMiddlewares, newMiddleware)
+	}
+}
+
 // WithAppendHook returns an InterceptorOption which hooks into calls to
-// Append.
+// Append. Hooks are called after all middlewares.
 func WithAppendHook(f func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error)) InterceptorOption {
 	return func(i *Interceptor) {
-		i.onAppend = f
+		i.hooks.OnAppend = f
 	}
 }
 
 // WithExemplarHook returns an InterceptorOption which hooks into calls to
-// AppendExemplar.
+// AppendExemplar. Hooks are called after all middlewares.
 func WithExemplarHook(f func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error)) InterceptorOption {
 	return func(i *Interceptor) {
-		i.onAppendExemplar = f
+		i.hooks.OnAppendExemplar = f
 	}
 }
 
 // WithMetadataHook returns an InterceptorOption which hooks into calls to
-// UpdateMetadata.
+// UpdateMetadata. Hooks are called after all middlewares.
 func WithMetadataHook(f func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error)) InterceptorOption {
 	return func(i *Interceptor) {
-		i.onUpdateMetadata = f
+		i.hooks.OnUpdateMetadata = f
 	}
 }
 
 // WithHistogramHook returns an InterceptorOption which hooks into calls to
-// AppendHistogram.
+// AppendHistogram. Hooks are called after all middlewares.
 func WithHistogramHook(f func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error)) InterceptorOption {
 	return func(i *Interceptor) {
-		i.onAppendHistogram = f
+		i.hooks.OnAppendHistogram = f
 	}
 }
 
 // Appender satisfies the Appendable interface.
 func (f *Interceptor) Appender(ctx context.Context) storage.Appender {
-	app := &interceptappender{
-		interceptor:       f,
+	var next storage.Appender
+	if f.next != nil {
+		next = f.next.Appender(ctx)
+	}
+
+	mws := make(chain, 0, len(f.middlewares)+len(f.appenderMiddlewares)+1)
+	mws = append(mws, f.middlewares...)
+	for _, newMiddleware := range f.appenderMiddlewares {
+		mws = append(mws, newMiddleware())
+	}
+	mws = append(mws, f.hooks)
+
+	return &interceptappender{
+		child:             mws.wrap(next),
 		ls:                f.ls,
 		stalenessTrackers: make([]labelstore.StalenessTracker, 0),
 	}
-	if f.next != nil {
-		app.child = f.next.Appender(ctx)
-	}
-	return app
 }
 
+// interceptappender assigns global ref IDs to series and tracks their
+// staleness before passing calls to the middleware chain in child.
 type interceptappender struct {
-	interceptor       *Interceptor
 	child             storage.Appender
 	ls                labelstore.LabelStore
 	stalenessTrackers []labelstore.StalenessTracker
@@ -109,30 +143,18 @@ func (a *interceptappender) Append(ref storage.SeriesRef, l labels.Labels, t int
 		Value:       v,
 	})
 
-	if a.interceptor.onAppend != nil {
-		return a.interceptor.onAppend(ref, l, t, v, a.child)
-	}
-	if a.child == nil {
-		return 0, nil
-	}
 	return a.child.Append(ref, l, t, v)
 }
 
 // Commit satisfies the Appender interface.
 func (a *interceptappender) Commit() error {
 	a.ls.TrackStaleness(a.stalenessTrackers)
-	if a.child == nil {
-		return nil
-	}
 	return a.child.Commit()
 }
 
 // Rollback satisfies the Appender interface.
 func (a *interceptappender) Rollback() error {
 	a.ls.TrackStaleness(a.stalenessTrackers)
-	if a.child == nil {
-		return nil
-	}
 	return a.child.Rollback()
 }
 
@@ -147,12 +169,6 @@ func (a *interceptappender) AppendExemplar(
 		ref = storage.SeriesRef(a.ls.GetOrAddGlobalRefID(l))
 	}
 
-	if a.interceptor.onAppendExemplar != nil {
-		return a.interceptor.onAppendExemplar(ref, l, e, a.child)
-	}
-	if a.child == nil {
-		return 0, nil
-	}
 	return a.child.AppendExemplar(ref, l, e)
 }
 
@@ -167,12 +183,6 @@ func (a *interceptappender) UpdateMetadata(
 		ref = storage.SeriesRef(a.ls.GetOrAddGlobalRefID(l))
 	}
 
-	if a.interceptor.onUpdateMetadata != nil {
-		return a.interceptor.onUpdateMetadata(ref, l, m, a.child)
-	}
-	if a.child == nil {
-		return 0, nil
-	}
 	return a.child.UpdateMetadata(ref, l, m)
 }
 
@@ -188,11 +198,5 @@ func (a *interceptappender) AppendHistogram(
 		ref = storage.SeriesRef(a.ls.GetOrAddGlobalRefID(l))
 	}
 
-	if a.interceptor.onAppendHistogram != nil {
-		return a.interceptor.onAppendHistogram(ref, l, t, h, fh, a.child)
-	}
-	if a.child == nil {
-		return 0, nil
-	}
 	return a.child.AppendHistogram(ref, l, t, h, fh)
 }
@@ -0,0 +1,184 @@
+package prometheus
+
+import (
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Middleware intercepts calls made to an Appender created by an Interceptor.
+// Each method is given the next Appender in the chain, which may be nil if
+// the Interceptor doesn't have a next Appendable. A Middleware may modify the
+// call before passing it to next, handle it without calling next, or pass it
+// through unmodified.
+//
+// The ref passed to a Middleware is always the global ref ID of the series.
+//
+// Middlewares are shared between all Appenders created by an Interceptor and
+// must be safe for concurrent use.
+type Middleware interface {
+	Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error)
+	AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error)
+	UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error)
+	AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error)
+	Commit(next storage.Appender) error
+	Rollback(next storage.Appender) error
+}
+
+// MiddlewareFuncs implements Middleware with optional functions. Calls for
+// which no function is set are passed through to the next Appender
+// unmodified, which allows a Middleware to only implement the calls it cares
+// about.
+type MiddlewareFuncs struct {
+	OnAppend          func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error)
+	OnAppendExemplar  func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error)
+	OnUpdateMetadata  func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error)
+	OnAppendHistogram func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error)
+	OnCommit          func(next storage.Appender) error
+	OnRollback        func(next storage.Appender) error
+}
+
+var _ Middleware = MiddlewareFuncs{}
+
+// Append implements Middleware.
+func (m MiddlewareFuncs) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+	if m.OnAppend != nil {
+		return m.OnAppend(ref, l, t, v, next)
+	}
+	if next == nil {
+		return 0, nil
+	}
+	return next.Append(ref, l, t, v)
+}
+
+// AppendExemplar implements Middleware.
+func (m MiddlewareFuncs) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+	if m.OnAppendExemplar != nil {
+		return m.OnAppendExemplar(ref, l, e, next)
+	}
+	if next == nil {
+		return 0, nil
+	}
+	return next.AppendExemplar(ref, l, e)
+}
+
+// UpdateMetadata implements Middleware.
+func (m MiddlewareFuncs) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, md metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+	if m.OnUpdateMetadata != nil {
+		return m.OnUpdateMetadata(ref, l, md, next)
+	}
+	if next == nil {
+		return 0, nil
+	}
+	return next.UpdateMetadata(ref, l, md)
+}
+
+// AppendHistogram implements Middleware.
+func (m MiddlewareFuncs) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+	if m.OnAppendHistogram != nil {
+		return m.OnAppendHistogram(ref, l, t, h, fh, next)
+	}
+	if next == nil {
+		return 0, nil
+	}
+	return next.AppendHistogram(ref, l, t, h, fh)
+}
+
+// Commit implements Middleware.
+func (m MiddlewareFuncs) Commit(next storage.Appender) error {
+	if m.OnCommit != nil {
+		return m.OnCommit(next)
+	}
+	if next == nil {
+		return nil
+	}
+	return next.Commit()
+}
+
+// Rollback implements Middleware.
+func (m MiddlewareFuncs) Rollback(next storage.Appender) error {
+	if m.OnRollback != nil {
+		return m.OnRollback(next)
+	}
+	if next == nil {
+		return nil
+	}
+	return next.Rollback()
+}
+
+// Chain composes middlewares into a single Middleware. Calls are passed
+// through middlewares in the order they're given, with the last middleware
+// receiving the next Appender of the chain.
+func Chain(middlewares ...Middleware) Middleware {
+	return chain(middlewares)
+}
+
+type chain []Middleware
+
+// wrap returns next wrapped by the middlewares of c. The returned Appender is
+// nil only if c is empty and next is nil.
+func (c chain) wrap(next storage.Appender) storage.Appender {
+	for i := len(c) - 1; i >= 0; i-- {
+		next = &middlewareAppender{mw: c[i], next: next}
+	}
+	return next
+}
+
+func (c chain) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+	return MiddlewareFuncs{}.Append(ref, l, t, v, c.wrap(next))
+}
+
+func (c chain) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+	return MiddlewareFuncs{}.AppendExemplar(ref, l, e, c.wrap(next))
+}
+
+func (c chain) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+	return MiddlewareFuncs{}.UpdateMetadata(ref, l, m, c.wrap(next))
+}
+
+func (c chain) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+	return MiddlewareFuncs{}.AppendHistogram(ref, l, t, h, fh, c.wrap(next))
+}
+
+func (c chain) Commit(next storage.Appender) error {
+	return MiddlewareFuncs{}.Commit(c.wrap(next))
+}
+
+func (c chain) Rollback(next storage.Appender) error {
+	return MiddlewareFuncs{}.Rollback(c.wrap(next))
+}
+
+// middlewareAppender is a storage.Appender which passes calls to a
+// Middleware along with the next Appender in the chain.
+type middlewareAppender struct {
+	mw   Middleware
+	next storage.Appender
+}
+
+var _ storage.Appender = (*middlewareAppender)(nil)
+
+func (a *middlewareAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	return a.mw.Append(ref, l, t, v, a.next)
+}
+
+func (a *middlewareAppender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	return a.mw.AppendExemplar(ref, l, e, a.next)
+}
+
+func (a *middlewareAppender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	return a.mw.UpdateMetadata(ref, l, m, a.next)
+}
+
+func (a *middlewareAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	return a.mw.AppendHistogram(ref, l, t, h, fh, a.next)
+}
+
+func (a *middlewareAppender) Commit() error {
+	return a.mw.Commit(a.next)
+}
+
+func (a *middlewareAppender) Rollback() error {
+	return a.mw.Rollback(a.next)
+}
@@ -0,0 +1,105 @@
+package prometheus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptor_Middleware(t *testing.T) {
+	var calls []string
+
+	// record returns a middleware which records its name and adds a label
+	// before passing the sample on.
+	record := func(name string) Middleware {
+		return MiddlewareFuncs{
+			OnAppend: func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+				calls = append(calls, name)
+				l = labels.NewBuilder(l).Set(name, "true").Labels()
+				return next.Append(ref, l, t, v)
+			},
+		}
+	}
+
+	var (
+		received labels.Labels
+		commits  int
+	)
+	final := NewInterceptor(nil, labelstore.New(nil, prometheus.NewRegistry()),
+		WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+			calls = append(calls, "hook")
+			received = l
+			return ref, nil
+		}),
+	)
+
+	interceptor := NewInterceptor(final, labelstore.New(nil, prometheus.NewRegistry()),
+		WithMiddleware(record("first"), Chain(record("second"), record("third"))),
+		WithMiddleware(MiddlewareFuncs{
+			OnCommit: func(next storage.Appender) error {
+				commits++
+				return next.Commit()
+			},
+		}),
+	)
+
+	app := interceptor.Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("__name__", "up"), 0, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	require.Equal(t, []string{"first", "second", "third", "hook"}, calls)
+	require.Equal(t, labels.FromStrings("__name__", "up", "first", "true", "second", "true", "third", "true"), received)
+	require.Equal(t, 1, commits)
+}
+
+func TestInterceptor_NoNext(t *testing.T) {
+	interceptor := NewInterceptor(nil, labelstore.New(nil, prometheus.NewRegistry()),
+		WithMiddleware(MiddlewareFuncs{}),
+	)
+
+	// Calls are dropped at the end of the chain when there's no next
+	// Appendable.
+	app := interceptor.Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("__name__", "up"), 0, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.NoError(t, app.Rollback())
+}
+
+func TestInterceptor_AppenderMiddleware(t *testing.T) {
+	// count returns a middleware which counts the samples appended through it.
+	var counts []*int
+	count := func() Middleware {
+		n := new(int)
+		counts = append(counts, n)
+		return MiddlewareFuncs{
+			OnAppend: func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+				*n++
+				return ref, nil
+			},
+		}
+	}
+
+	interceptor := NewInterceptor(nil, labelstore.New(nil, prometheus.NewRegistry()),
+		WithAppenderMiddleware(count),
+	)
+
+	// Each Appender gets its own middleware.
+	first, second := interceptor.Appender(context.Background()), interceptor.Appender(context.Background())
+	for i := 0; i < 2; i++ {
+		_, err := first.Append(0, labels.FromStrings("__name__", "up"), 0, 1)
+		require.NoError(t, err)
+	}
+	_, err := second.Append(0, labels.FromStrings("__name__", "up"), 0, 1)
+	require.NoError(t, err)
+
+	require.Len(t, counts, 2)
+	require.Equal(t, 2, *counts[0])
+	require.Equal(t, 1, *counts[1])
+}
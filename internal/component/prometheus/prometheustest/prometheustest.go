@@ -0,0 +1,149 @@
+// Package prometheustest provides utilities for testing Flow components which
+// receive and forward Prometheus metrics.
+package prometheustest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Harness holds the environment of a component under test.
+type Harness struct {
+	// Options are the options to build the component with. The component
+	// registers its metrics to a registry of its own.
+	Options component.Options
+
+	// LabelStore is the label store of the component, which is also
+	// registered to the registry of the component.
+	LabelStore labelstore.LabelStore
+
+	mut     sync.Mutex
+	exports component.Exports
+}
+
+// NewHarness returns a Harness for the component with the given ID. Data of
+// services other than the label store is looked up in services by name.
+func NewHarness(t *testing.T, id string, services map[string]interface{}) *Harness {
+	reg := prometheus.NewRegistry()
+	h := &Harness{LabelStore: labelstore.New(nil, reg)}
+	h.Options = component.Options{
+		ID:     id,
+		Logger: util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {
+			h.mut.Lock()
+			defer h.mut.Unlock()
+			h.exports = e
+		},
+		Registerer: reg,
+		GetServiceData: func(name string) (interface{}, error) {
+			if data, ok := services[name]; ok {
+				return data, nil
+			}
+			return h.LabelStore, nil
+		},
+	}
+	return h
+}
+
+// Exports returns the latest exports of the component.
+func (h *Harness) Exports() component.Exports {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.exports
+}
+
+// Sample is a sample or a native histogram appended to a Capture. Either V,
+// H, or FH is set.
+type Sample struct {
+	Labels labels.Labels
+	T      int64
+	V      float64
+	H      *histogram.Histogram
+	FH     *histogram.FloatHistogram
+}
+
+// Capture is a storage.Appendable which records the samples and native
+// histograms appended to it, so that components under test can forward to
+// it. Exemplars and metadata are discarded. Capture is safe for concurrent
+// use.
+type Capture struct {
+	mut     sync.Mutex
+	samples []Sample
+}
+
+var _ storage.Appendable = (*Capture)(nil)
+
+// Appender implements storage.Appendable. Samples are recorded when they're
+// appended, regardless of whether the appender is committed.
+func (c *Capture) Appender(context.Context) storage.Appender {
+	return captureAppender{c: c}
+}
+
+// Samples returns the samples appended so far, in order.
+func (c *Capture) Samples() []Sample {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return append([]Sample(nil), c.samples...)
+}
+
+// Labels returns the labels of the samples appended so far, in order.
+func (c *Capture) Labels() []labels.Labels {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	res := make([]labels.Labels, 0, len(c.samples))
+	for _, s := range c.samples {
+		res = append(res, s.Labels)
+	}
+	return res
+}
+
+// Reset forgets the samples appended so far.
+func (c *Capture) Reset() {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.samples = nil
+}
+
+func (c *Capture) add(s Sample) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.samples = append(c.samples, s)
+}
+
+type captureAppender struct {
+	c *Capture
+}
+
+var _ storage.Appender = captureAppender{}
+
+func (a captureAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	a.c.add(Sample{Labels: l, T: t, V: v})
+	return ref, nil
+}
+
+func (a captureAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	a.c.add(Sample{Labels: l, T: t, H: h, FH: fh})
+	return ref, nil
+}
+
+func (a captureAppender) AppendExemplar(ref storage.SeriesRef, _ labels.Labels, _ exemplar.Exemplar) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (a captureAppender) UpdateMetadata(ref storage.SeriesRef, _ labels.Labels, _ metadata.Metadata) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (a captureAppender) Commit() error   { return nil }
+func (a captureAppender) Rollback() error { return nil }
@@ -0,0 +1,213 @@
+package rate_limit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+// Reasons a sample is throttled, used as the reason label of the throttled
+// samples metric.
+const (
+	reasonRate         = "rate"
+	reasonSeriesRate   = "series_rate"
+	reasonActiveSeries = "active_series"
+	reasonQueueFull    = "queue_full"
+)
+
+// limits are the limits enforced for each tenant.
+type limits struct {
+	SamplesPerSecond       float64
+	Burst                  int
+	SeriesSamplesPerSecond float64
+	SeriesBurst            int
+	MaxActiveSeries        int
+	MaxDelay               time.Duration
+}
+
+type tenantState struct {
+	limiter *rate.Limiter // nil without a tenant rate limit
+	series  map[uint64]*seriesState
+}
+
+type seriesState struct {
+	lastSeen time.Time
+	limiter  *rate.Limiter // nil without a series rate limit
+}
+
+// limiter tracks the active series and the sample rate of each tenant.
+type limiter struct {
+	activeSeries *prometheus_client.GaugeVec
+
+	mut     sync.Mutex
+	limits  limits
+	tenants map[string]*tenantState
+}
+
+func newLimiter(l limits, activeSeries *prometheus_client.GaugeVec) *limiter {
+	return &limiter{
+		activeSeries: activeSeries,
+		limits:       l,
+		tenants:      make(map[string]*tenantState),
+	}
+}
+
+// Admit decides whether a sample of the series ref of tenant is forwarded.
+// If it is, Admit returns how long to delay it by. Otherwise, Admit returns
+// the reason it is throttled.
+func (l *limiter) Admit(tenant string, ref uint64, now time.Time) (delay time.Duration, reason string) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	ts, ok := l.tenants[tenant]
+	if !ok {
+		ts = &tenantState{
+			limiter: newRateLimiter(l.limits.SamplesPerSecond, l.limits.Burst),
+			series:  make(map[uint64]*seriesState),
+		}
+		l.tenants[tenant] = ts
+	}
+
+	ss, ok := ts.series[ref]
+	if !ok {
+		if l.limits.MaxActiveSeries > 0 && len(ts.series) >= l.limits.MaxActiveSeries {
+			return 0, reasonActiveSeries
+		}
+		ss = &seriesState{limiter: newRateLimiter(l.limits.SeriesSamplesPerSecond, l.limits.SeriesBurst)}
+		ts.series[ref] = ss
+		l.activeSeries.WithLabelValues(tenant).Inc()
+	}
+	ss.lastSeen = now
+
+	seriesRes, ok := reserve(ss.limiter, now, l.limits.MaxDelay)
+	if !ok {
+		return 0, reasonSeriesRate
+	}
+	tenantRes, ok := reserve(ts.limiter, now, l.limits.MaxDelay)
+	if !ok {
+		// Give back the token of the series so that the sample isn't counted
+		// against it.
+		if seriesRes != nil {
+			seriesRes.CancelAt(now)
+		}
+		return 0, reasonRate
+	}
+
+	for _, res := range []*rate.Reservation{seriesRes, tenantRes} {
+		if res != nil {
+			delay = max(delay, res.DelayFrom(now))
+		}
+	}
+	return delay, ""
+}
+
+// reserve reserves a token from lim for a sample received at now. It returns
+// false if the sample would have to wait longer than maxDelay. A nil limiter
+// always succeeds with a nil reservation.
+func reserve(lim *rate.Limiter, now time.Time, maxDelay time.Duration) (*rate.Reservation, bool) {
+	if lim == nil {
+		return nil, true
+	}
+	res := lim.ReserveN(now, 1)
+	if !res.OK() {
+		return nil, false
+	}
+	if res.DelayFrom(now) > maxDelay {
+		res.CancelAt(now)
+		return nil, false
+	}
+	return res, true
+}
+
+// Active returns whether the series ref of tenant is tracked.
+func (l *limiter) Active(tenant string, ref uint64) bool {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	ts, ok := l.tenants[tenant]
+	if !ok {
+		return false
+	}
+	_, ok = ts.series[ref]
+	return ok
+}
+
+// Remove stops tracking the series ref of tenant, for example once it is
+// marked as stale.
+func (l *limiter) Remove(tenant string, ref uint64) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	ts, ok := l.tenants[tenant]
+	if !ok {
+		return
+	}
+	if _, ok := ts.series[ref]; ok {
+		delete(ts.series, ref)
+		l.activeSeries.WithLabelValues(tenant).Dec()
+	}
+}
+
+// Sweep stops tracking the series which weren't seen since deadline, and
+// forgets the tenants which no longer have any series.
+func (l *limiter) Sweep(deadline time.Time) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	for tenant, ts := range l.tenants {
+		for ref, ss := range ts.series {
+			if ss.lastSeen.Before(deadline) {
+				delete(ts.series, ref)
+			}
+		}
+		if len(ts.series) == 0 {
+			delete(l.tenants, tenant)
+			l.activeSeries.DeleteLabelValues(tenant)
+			continue
+		}
+		l.activeSeries.WithLabelValues(tenant).Set(float64(len(ts.series)))
+	}
+}
+
+// SetLimits changes the limits, keeping the tracked series. Series beyond a
+// lowered active series limit are kept until they become inactive.
+func (l *limiter) SetLimits(newLimits limits) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	old := l.limits
+	l.limits = newLimits
+
+	var (
+		tenantChanged = old.SamplesPerSecond != newLimits.SamplesPerSecond || old.Burst != newLimits.Burst
+		seriesChanged = old.SeriesSamplesPerSecond != newLimits.SeriesSamplesPerSecond || old.SeriesBurst != newLimits.SeriesBurst
+	)
+	if !tenantChanged && !seriesChanged {
+		return
+	}
+	for _, ts := range l.tenants {
+		if tenantChanged {
+			ts.limiter = newRateLimiter(newLimits.SamplesPerSecond, newLimits.Burst)
+		}
+		if seriesChanged {
+			for _, ss := range ts.series {
+				ss.limiter = newRateLimiter(newLimits.SeriesSamplesPerSecond, newLimits.SeriesBurst)
+			}
+		}
+	}
+}
+
+// newRateLimiter returns a limiter for perSecond samples, or nil if perSecond
+// is zero. burst defaults to perSecond rounded up.
+func newRateLimiter(perSecond float64, burst int) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(perSecond))
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), burst)
+}
@@ -0,0 +1,88 @@
+package rate_limit
+
+import (
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(l limits) (*limiter, *prom.GaugeVec) {
+	activeSeries := prom.NewGaugeVec(prom.GaugeOpts{Name: "active_series"}, []string{"tenant"})
+	return newLimiter(l, activeSeries), activeSeries
+}
+
+func TestLimiter_ActiveSeries(t *testing.T) {
+	var (
+		now        = time.Now()
+		lim, gauge = newTestLimiter(limits{MaxActiveSeries: 2})
+	)
+
+	for ref := uint64(0); ref < 2; ref++ {
+		_, reason := lim.Admit("a", ref, now)
+		require.Empty(t, reason)
+	}
+	_, reason := lim.Admit("a", 2, now)
+	require.Equal(t, reasonActiveSeries, reason)
+
+	// Known series and other tenants aren't affected.
+	_, reason = lim.Admit("a", 1, now)
+	require.Empty(t, reason)
+	_, reason = lim.Admit("b", 2, now)
+	require.Empty(t, reason)
+	require.Equal(t, 2.0, testutil.ToFloat64(gauge.WithLabelValues("a")))
+
+	// Removed and inactive series make room for new ones.
+	lim.Remove("a", 0)
+	_, reason = lim.Admit("a", 2, now)
+	require.Empty(t, reason)
+
+	lim.Sweep(now.Add(time.Second))
+	require.False(t, lim.Active("a", 1))
+	_, reason = lim.Admit("a", 3, now.Add(time.Second))
+	require.Empty(t, reason)
+	require.Equal(t, 1.0, testutil.ToFloat64(gauge.WithLabelValues("a")))
+}
+
+func TestLimiter_Rate(t *testing.T) {
+	var (
+		now    = time.Now()
+		lim, _ = newTestLimiter(limits{SamplesPerSecond: 2, SeriesSamplesPerSecond: 1})
+	)
+
+	// The series limit applies before the tenant limit.
+	_, reason := lim.Admit("a", 0, now)
+	require.Empty(t, reason)
+	_, reason = lim.Admit("a", 0, now)
+	require.Equal(t, reasonSeriesRate, reason)
+
+	_, reason = lim.Admit("a", 1, now)
+	require.Empty(t, reason)
+	_, reason = lim.Admit("a", 2, now)
+	require.Equal(t, reasonRate, reason)
+
+	// Tokens are refilled over time.
+	_, reason = lim.Admit("a", 2, now.Add(time.Second))
+	require.Empty(t, reason)
+}
+
+func TestLimiter_Delay(t *testing.T) {
+	var (
+		now    = time.Now()
+		lim, _ = newTestLimiter(limits{SamplesPerSecond: 10, Burst: 1, MaxDelay: 150 * time.Millisecond})
+	)
+
+	delay, reason := lim.Admit("a", 0, now)
+	require.Empty(t, reason)
+	require.Zero(t, delay)
+
+	delay, reason = lim.Admit("a", 0, now)
+	require.Empty(t, reason)
+	require.InDelta(t, 100*time.Millisecond, delay, float64(time.Millisecond))
+
+	// Samples which would wait longer than the maximum delay are dropped.
+	_, reason = lim.Admit("a", 0, now)
+	require.Equal(t, reasonRate, reason)
+}
@@ -0,0 +1,151 @@
+package rate_limit
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// maxQueuedSamples is the maximum number of delayed samples waiting to be
+// forwarded. Samples delayed while the queue is full are dropped.
+const maxQueuedSamples = 100_000
+
+// delayedSample is a sample admitted under the rate limits once its delay
+// elapses. Either v, h, or fh is set.
+type delayedSample struct {
+	due time.Time
+	seq uint64 // Order of admission, which keeps the samples of a series in order.
+
+	ref uint64
+	l   labels.Labels
+	t   int64
+	v   float64
+	h   *histogram.Histogram
+	fh  *histogram.FloatHistogram
+}
+
+// append appends s to app.
+func (s *delayedSample) append(app storage.Appender) error {
+	var err error
+	if s.h != nil || s.fh != nil {
+		_, err = app.AppendHistogram(storage.SeriesRef(s.ref), s.l, s.t, s.h, s.fh)
+	} else {
+		_, err = app.Append(storage.SeriesRef(s.ref), s.l, s.t, s.v)
+	}
+	return err
+}
+
+// delayQueue holds delayed samples until they're due. It's safe for
+// concurrent use.
+type delayQueue struct {
+	mut     sync.Mutex
+	samples sampleHeap
+	seq     uint64
+	series  map[uint64]time.Time // Latest due time of the queued samples, by series.
+
+	// wake is written to when a sample is queued, so that the flushing loop
+	// can wait for an earlier due time.
+	wake chan struct{}
+}
+
+func newDelayQueue() *delayQueue {
+	return &delayQueue{
+		series: make(map[uint64]time.Time),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Full returns whether the queue can't hold n more samples.
+func (q *delayQueue) Full(n int) bool {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return len(q.samples)+n > maxQueuedSamples
+}
+
+// LatestDue returns the latest due time of the queued samples of the series
+// ref, and false if none is queued.
+func (q *delayQueue) LatestDue(ref uint64) (time.Time, bool) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	due, ok := q.series[ref]
+	return due, ok
+}
+
+// Push queues samples.
+func (q *delayQueue) Push(samples []*delayedSample) {
+	q.mut.Lock()
+	for _, s := range samples {
+		s.seq = q.seq
+		q.seq++
+		heap.Push(&q.samples, s)
+		if s.due.After(q.series[s.ref]) {
+			q.series[s.ref] = s.due
+		}
+	}
+	q.mut.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// PopDue removes and returns the samples due at now, in order, along with
+// the due time of the next queued sample. The next due time is zero if the
+// queue is empty.
+func (q *delayQueue) PopDue(now time.Time) ([]*delayedSample, time.Time) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	var due []*delayedSample
+	for len(q.samples) > 0 && !q.samples[0].due.After(now) {
+		s := heap.Pop(&q.samples).(*delayedSample)
+		if latest, ok := q.series[s.ref]; ok && !latest.After(s.due) {
+			delete(q.series, s.ref)
+		}
+		due = append(due, s)
+	}
+
+	var next time.Time
+	if len(q.samples) > 0 {
+		next = q.samples[0].due
+	}
+	return due, next
+}
+
+// Len returns the number of queued samples.
+func (q *delayQueue) Len() int {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return len(q.samples)
+}
+
+// sampleHeap is a min-heap of delayed samples ordered by due time, then by
+// order of admission.
+type sampleHeap []*delayedSample
+
+func (h sampleHeap) Len() int { return len(h) }
+
+func (h sampleHeap) Less(i, j int) bool {
+	if !h[i].due.Equal(h[j].due) {
+		return h[i].due.Before(h[j].due)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h sampleHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *sampleHeap) Push(x any) { *h = append(*h, x.(*delayedSample)) }
+
+func (h *sampleHeap) Pop() any {
+	old := *h
+	n := len(old)
+	s := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return s
+}
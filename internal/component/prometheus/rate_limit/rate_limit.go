@@ -0,0 +1,409 @@
+package rate_limit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.rate_limit",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// prometheus.rate_limit component.
+type Arguments struct {
+	// Where metrics are forwarded to.
+	ForwardTo []storage.Appendable `river:"forward_to,attr"`
+
+	// The label whose value identifies the tenant of a series. All series
+	// belong to one tenant if empty.
+	TenantLabel string `river:"tenant_label,attr,optional"`
+
+	// Samples per second allowed for each tenant. Zero disables the limit.
+	SamplesPerSecond float64 `river:"samples_per_second,attr,optional"`
+	Burst            int     `river:"burst,attr,optional"`
+
+	// Samples per second allowed for each series. Zero disables the limit.
+	SeriesSamplesPerSecond float64 `river:"series_samples_per_second,attr,optional"`
+	SeriesBurst            int     `river:"series_burst,attr,optional"`
+
+	// Number of active series allowed for each tenant. Zero disables the
+	// limit.
+	MaxActiveSeries int `river:"max_active_series,attr,optional"`
+
+	// How long a series stays active without receiving samples.
+	ActiveSeriesTimeout time.Duration `river:"active_series_timeout,attr,optional"`
+
+	// How long samples over the rate limits may be delayed for instead of
+	// being dropped.
+	MaxDelay time.Duration `river:"max_delay,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	ActiveSeriesTimeout: 10 * time.Minute,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	switch {
+	case args.SamplesPerSecond < 0:
+		return fmt.Errorf("samples_per_second must not be negative")
+	case args.Burst < 0:
+		return fmt.Errorf("burst must not be negative")
+	case args.SeriesSamplesPerSecond < 0:
+		return fmt.Errorf("series_samples_per_second must not be negative")
+	case args.SeriesBurst < 0:
+		return fmt.Errorf("series_burst must not be negative")
+	case args.MaxActiveSeries < 0:
+		return fmt.Errorf("max_active_series must not be negative")
+	case args.ActiveSeriesTimeout <= 0:
+		return fmt.Errorf("active_series_timeout must be greater than 0")
+	case args.MaxDelay < 0:
+		return fmt.Errorf("max_delay must not be negative")
+	}
+	return nil
+}
+
+func (args *Arguments) limits() limits {
+	return limits{
+		SamplesPerSecond:       args.SamplesPerSecond,
+		Burst:                  args.Burst,
+		SeriesSamplesPerSecond: args.SeriesSamplesPerSecond,
+		SeriesBurst:            args.SeriesBurst,
+		MaxActiveSeries:        args.MaxActiveSeries,
+		MaxDelay:               args.MaxDelay,
+	}
+}
+
+// Exports holds values which are exported by the prometheus.rate_limit
+// component.
+type Exports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+}
+
+// Component implements the prometheus.rate_limit component.
+type Component struct {
+	opts     component.Options
+	ls       labelstore.LabelStore
+	limiter  *limiter
+	fanout   *prometheus.Fanout
+	receiver *prometheus.Interceptor
+	queue    *delayQueue
+
+	throttledSamples *prometheus_client.CounterVec
+	delayedSamples   *prometheus_client.CounterVec
+	queuedSamples    prometheus_client.Gauge
+
+	mut  sync.RWMutex
+	args Arguments
+
+	// updated is written to whenever args updates.
+	updated chan struct{}
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+)
+
+// New creates a new prometheus.rate_limit component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Component{
+		opts:    o,
+		ls:      data.(labelstore.LabelStore),
+		queue:   newDelayQueue(),
+		updated: make(chan struct{}, 1),
+	}
+	c.throttledSamples = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_rate_limit_throttled_samples_total",
+		Help: "Total number of samples dropped for exceeding a limit, by tenant and limit",
+	}, []string{"tenant", "reason"})
+	c.delayedSamples = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_rate_limit_delayed_samples_total",
+		Help: "Total number of samples delayed to stay under the rate limits, by tenant",
+	}, []string{"tenant"})
+	c.queuedSamples = prometheus_client.NewGauge(prometheus_client.GaugeOpts{
+		Name: "agent_prometheus_rate_limit_queued_samples",
+		Help: "Number of delayed samples waiting to be forwarded",
+	})
+	activeSeries := prometheus_client.NewGaugeVec(prometheus_client.GaugeOpts{
+		Name: "agent_prometheus_rate_limit_active_series",
+		Help: "Number of active series, by tenant",
+	}, []string{"tenant"})
+	for _, metric := range []prometheus_client.Collector{c.throttledSamples, c.delayedSamples, c.queuedSamples, activeSeries} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+	c.limiter = newLimiter(args.limits(), activeSeries)
+
+	c.fanout = prometheus.NewFanout(args.ForwardTo, o.ID, o.Registerer, c.ls)
+	c.receiver = prometheus.NewInterceptor(
+		c.fanout,
+		c.ls,
+		prometheus.WithAppenderMiddleware(c.newTransactionMiddleware),
+		prometheus.WithExemplarHook(func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			// Exemplars are only forwarded for series whose samples are.
+			if !c.limiter.Active(c.tenant(l), c.ls.GetOrAddGlobalRefID(l)) {
+				return ref, nil
+			}
+			return next.AppendExemplar(ref, l, e)
+		}),
+		prometheus.WithMetadataHook(func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			return next.UpdateMetadata(ref, l, m)
+		}),
+	)
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.receiver})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newTransactionMiddleware returns the middleware of an Appender, which
+// holds the samples it delays until it's committed. Delayed samples are
+// queued rather than waited for, so that they don't block the component
+// sending them.
+func (c *Component) newTransactionMiddleware() prometheus.Middleware {
+	var (
+		delayed []*delayedSample
+		latest  = make(map[uint64]time.Time) // Latest due time of the delayed samples, by series.
+	)
+
+	// hold returns whether the admitted sample s is delayed, and holds it
+	// until the transaction is committed if so. Samples of series with
+	// delayed samples are delayed after them to stay in order.
+	hold := func(s *delayedSample) bool {
+		if due, ok := latest[s.ref]; ok && due.After(s.due) {
+			s.due = due
+		}
+		if s.due.IsZero() {
+			return false
+		}
+		delayed = append(delayed, s)
+		latest[s.ref] = s.due
+		return true
+	}
+
+	return prometheus.MiddlewareFuncs{
+		OnAppend: func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			s := &delayedSample{l: l, t: t, v: v}
+			if !c.admit(s, value.IsStaleNaN(v), len(delayed)) || hold(s) {
+				return ref, nil
+			}
+			return next.Append(ref, l, t, v)
+		},
+		OnAppendHistogram: func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			s := &delayedSample{l: l, t: t}
+			stale := (h != nil && value.IsStaleNaN(h.Sum)) || (fh != nil && value.IsStaleNaN(fh.Sum))
+			if !c.admit(s, stale, len(delayed)) {
+				return ref, nil
+			}
+			if hold(s) {
+				// The sender may reuse its histograms once the transaction is
+				// committed.
+				if h != nil {
+					s.h = h.Copy()
+				}
+				if fh != nil {
+					s.fh = fh.Copy()
+				}
+				return ref, nil
+			}
+			return next.AppendHistogram(ref, l, t, h, fh)
+		},
+		OnCommit: func(next storage.Appender) error {
+			err := next.Commit()
+			if err == nil && len(delayed) > 0 {
+				c.queue.Push(delayed)
+				c.queuedSamples.Set(float64(c.queue.Len()))
+			}
+			delayed, latest = nil, make(map[uint64]time.Time)
+			return err
+		},
+		OnRollback: func(next storage.Appender) error {
+			delayed, latest = nil, make(map[uint64]time.Time)
+			return next.Rollback()
+		},
+	}
+}
+
+// admit returns whether the sample s is forwarded, and sets its due time if
+// it must be delayed. queued is the number of samples already delayed by the
+// transaction of s. Samples of series with queued samples are delayed after
+// them to stay in order. Stale markers are always forwarded, and stop the
+// tracking of their series.
+func (c *Component) admit(s *delayedSample, stale bool, queued int) bool {
+	var (
+		tenant = c.tenant(s.l)
+		now    = time.Now()
+	)
+	s.ref = uint64(c.ls.GetOrAddGlobalRefID(s.l))
+
+	var due time.Time
+	if stale {
+		c.limiter.Remove(tenant, s.ref)
+	} else {
+		delay, reason := c.limiter.Admit(tenant, s.ref, now)
+		if reason == "" && delay > 0 && c.queue.Full(queued+1) {
+			reason = reasonQueueFull
+		}
+		if reason != "" {
+			c.throttledSamples.WithLabelValues(tenant, reason).Inc()
+			return false
+		}
+		if delay > 0 {
+			c.delayedSamples.WithLabelValues(tenant).Inc()
+			due = now.Add(delay)
+		}
+	}
+
+	if latest, ok := c.queue.LatestDue(s.ref); ok && latest.After(due) {
+		due = latest
+	}
+	s.due = due
+	return true
+}
+
+// flush forwards the queued samples due at now, and returns the due time of
+// the next queued sample, which is zero if none is queued.
+func (c *Component) flush(now time.Time) time.Time {
+	samples, next := c.queue.PopDue(now)
+	c.queuedSamples.Set(float64(c.queue.Len()))
+	if len(samples) == 0 {
+		return next
+	}
+
+	app := c.fanout.Appender(context.Background())
+	for _, s := range samples {
+		if err := s.append(app); err != nil {
+			level.Warn(c.opts.Logger).Log("msg", "failed to forward delayed sample", "series", s.l, "err", err)
+		}
+	}
+	if err := app.Commit(); err != nil {
+		level.Warn(c.opts.Logger).Log("msg", "failed to forward delayed samples", "err", err)
+	}
+	return next
+}
+
+// tenant returns the tenant of the series with labels l.
+func (c *Component) tenant(l labels.Labels) string {
+	c.mut.RLock()
+	label := c.args.TenantLabel
+	c.mut.RUnlock()
+
+	if label == "" {
+		return ""
+	}
+	return l.Get(label)
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	// Sweep twice per timeout so that series are inactive for at most 1.5x
+	// active_series_timeout before they stop counting against the limits.
+	newTicker := func() *time.Ticker {
+		c.mut.RLock()
+		defer c.mut.RUnlock()
+		return time.NewTicker(c.args.ActiveSeriesTimeout / 2)
+	}
+	ticker := newTicker()
+	defer func() { ticker.Stop() }()
+
+	// flushTimer fires when the next queued sample is due, and is nil while
+	// the queue is empty.
+	var flushTimer *time.Timer
+	scheduleFlush := func(next time.Time) {
+		if flushTimer != nil {
+			flushTimer.Stop()
+			flushTimer = nil
+		}
+		if !next.IsZero() {
+			flushTimer = time.NewTimer(time.Until(next))
+		}
+	}
+	flushC := func() <-chan time.Time {
+		if flushTimer == nil {
+			return nil
+		}
+		return flushTimer.C
+	}
+	defer scheduleFlush(time.Time{})
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Forward the remaining samples early rather than losing them.
+			c.flush(time.Unix(math.MaxInt64/int64(time.Second), 0))
+			return nil
+		case <-c.queue.wake:
+			scheduleFlush(c.flush(time.Now()))
+		case now := <-flushC():
+			scheduleFlush(c.flush(now))
+		case <-c.updated:
+			ticker.Stop()
+			ticker = newTicker()
+		case now := <-ticker.C:
+			c.mut.RLock()
+			timeout := c.args.ActiveSeriesTimeout
+			c.mut.RUnlock()
+			c.limiter.Sweep(now.Add(-timeout))
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+	c.args = newArgs
+	c.limiter.SetLimits(newArgs.limits())
+	c.fanout.UpdateChildren(newArgs.ForwardTo)
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
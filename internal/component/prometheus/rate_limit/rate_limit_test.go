@@ -0,0 +1,126 @@
+package rate_limit
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/prometheus/prometheustest"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestComponent(t *testing.T, args Arguments) (*Component, *prometheustest.Capture, storage.Appendable) {
+	h := prometheustest.NewHarness(t, "prometheus.rate_limit.test", nil)
+	capture := &prometheustest.Capture{}
+	args.ForwardTo = []storage.Appendable{capture}
+
+	c, err := New(h.Options, args)
+	require.NoError(t, err)
+	return c, capture, h.Exports().(Exports).Receiver
+}
+
+// receivedPods returns the pod label of the samples received by capture.
+func receivedPods(capture *prometheustest.Capture) []string {
+	var res []string
+	for _, l := range capture.Labels() {
+		res = append(res, l.Get("pod"))
+	}
+	return res
+}
+
+// receivedValues returns the values of the samples received by capture.
+func receivedValues(capture *prometheustest.Capture) []float64 {
+	var res []float64
+	for _, s := range capture.Samples() {
+		res = append(res, s.V)
+	}
+	return res
+}
+
+func TestRateLimit(t *testing.T) {
+	args := DefaultArguments
+	args.TenantLabel = "tenant"
+	args.MaxActiveSeries = 1
+	c, received, receiver := newTestComponent(t, args)
+
+	app := receiver.Appender(context.Background())
+	for _, l := range []labels.Labels{
+		labels.FromStrings("tenant", "a", "pod", "a-1"),
+		labels.FromStrings("tenant", "a", "pod", "a-2"),
+		labels.FromStrings("tenant", "b", "pod", "b-1"),
+	} {
+		_, err := app.Append(0, l, time.Now().UnixMilli(), 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	require.Equal(t, []string{"a-1", "b-1"}, receivedPods(received))
+	require.Equal(t, 1.0, testutil.ToFloat64(c.throttledSamples.WithLabelValues("a", reasonActiveSeries)))
+
+	// A stale marker is forwarded and frees up its series.
+	received.Reset()
+	app = receiver.Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("tenant", "a", "pod", "a-1"), time.Now().UnixMilli(), math.Float64frombits(value.StaleNaN))
+	require.NoError(t, err)
+	_, err = app.Append(0, labels.FromStrings("tenant", "a", "pod", "a-2"), time.Now().UnixMilli(), 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.Equal(t, []string{"a-1", "a-2"}, receivedPods(received))
+}
+
+func TestRateLimit_Delay(t *testing.T) {
+	args := DefaultArguments
+	args.SamplesPerSecond = 10
+	args.Burst = 1
+	args.MaxDelay = time.Second
+	c, received, receiver := newTestComponent(t, args)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	// The second and third samples are over the rate limit, and are queued
+	// rather than blocking the sender, then forwarded in order along with the
+	// stale marker following them.
+	l := labels.FromStrings("pod", "a-1")
+	app := receiver.Appender(context.Background())
+	for _, v := range []float64{1, 2, 3, math.Float64frombits(value.StaleNaN)} {
+		_, err := app.Append(0, l, time.Now().UnixMilli(), v)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []float64{1}, receivedValues(received))
+	require.NoError(t, app.Commit())
+	require.Equal(t, 2.0, testutil.ToFloat64(c.delayedSamples.WithLabelValues("")))
+
+	require.Eventually(t, func() bool {
+		return len(receivedValues(received)) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	got := receivedValues(received)
+	require.Equal(t, []float64{1, 2, 3}, got[:3])
+	require.True(t, value.IsStaleNaN(got[3]))
+	require.Equal(t, 0.0, testutil.ToFloat64(c.queuedSamples))
+}
+
+func TestRiverArguments(t *testing.T) {
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		forward_to         = []
+		tenant_label       = "tenant"
+		samples_per_second = 1000
+		max_active_series  = 100
+		max_delay          = "1s"
+	`), &args))
+	require.Equal(t, 10*time.Minute, args.ActiveSeriesTimeout)
+	require.Equal(t, 1000.0, args.SamplesPerSecond)
+
+	require.Error(t, river.Unmarshal([]byte(`
+		forward_to            = []
+		active_series_timeout = "0s"
+	`), &args))
+}
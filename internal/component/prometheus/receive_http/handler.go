@@ -0,0 +1,293 @@
+package receive_http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+)
+
+// Reasons a series is discarded, used as the reason label of the discarded
+// series metric.
+const (
+	reasonInvalidLabels   = "invalid_labels"
+	reasonMaxLabelNames   = "max_label_names_per_series"
+	reasonLabelNameLength = "max_label_name_length"
+	reasonLabelValueLen   = "max_label_value_length"
+	reasonMaxSeries       = "max_series_per_request"
+)
+
+// errMaxSeriesPerRequest is returned when a request holds more series than
+// allowed by the limits.
+var errMaxSeriesPerRequest = errors.New("request exceeds max_series_per_request")
+
+// writeHandler is an http.Handler which accepts Prometheus remote write
+// requests, validates their series against limits, and appends them to an
+// Appendable. It's safe for concurrent use.
+type writeHandler struct {
+	logger     log.Logger
+	appendable storage.Appendable
+
+	samplesWithInvalidLabels prometheus.Counter
+	discardedSeries          *prometheus.CounterVec
+
+	mut    sync.RWMutex
+	tenant *TenantArguments
+	limits LimitsArguments
+}
+
+func newWriteHandler(logger log.Logger, reg prometheus.Registerer, appendable storage.Appendable) *writeHandler {
+	h := &writeHandler{
+		logger:     logger,
+		appendable: appendable,
+
+		// Kept from the upstream remote write handler which was used before
+		// limits were supported.
+		samplesWithInvalidLabels: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "prometheus",
+			Subsystem: "api",
+			Name:      "remote_write_invalid_labels_samples_total",
+			Help:      "The total number of remote write samples which contains invalid labels.",
+		}),
+		discardedSeries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_prometheus_receive_http_discarded_series_total",
+			Help: "Total number of series discarded because they were invalid or exceeded a limit",
+		}, []string{"reason"}),
+	}
+	reg.MustRegister(h.samplesWithInvalidLabels, h.discardedSeries)
+	return h
+}
+
+// update changes the tenant and limits settings of h.
+func (h *writeHandler) update(tenant *TenantArguments, limits LimitsArguments) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.tenant, h.limits = tenant, limits
+}
+
+func (h *writeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	version, err := remoteWriteVersion(r.Header.Get("Content-Type"))
+	if err != nil {
+		// Senders fall back to another protobuf message when receiving 415.
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	h.mut.RLock()
+	tenant, limits := h.tenant, h.limits
+	h.mut.RUnlock()
+
+	var tenantID string
+	if tenant != nil {
+		tenantID = r.Header.Get(tenant.Header)
+		if tenantID == "" && tenant.Required {
+			http.Error(w, fmt.Sprintf("missing tenant header %s", tenant.Header), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var req *prompb.WriteRequest
+	if version == remoteWriteV2 {
+		req, err = decodeWriteRequestV2(r.Body)
+	} else {
+		req, err = remote.DecodeWriteRequest(r.Body)
+	}
+	if err != nil {
+		level.Error(h.logger).Log("msg", "error decoding remote write request", "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, discarded, err := h.write(r.Context(), req, tenant, tenantID, limits)
+	if version == remoteWriteV2 {
+		// Remote write 2.0 senders rely on these headers to know what was
+		// written, including when the request partly failed.
+		w.Header().Set(samplesWrittenHeader, strconv.Itoa(stats.samples))
+		w.Header().Set(histogramsWrittenHeader, strconv.Itoa(stats.histograms))
+		w.Header().Set(exemplarsWrittenHeader, strconv.Itoa(stats.exemplars))
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, errMaxSeriesPerRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, storage.ErrOutOfOrderSample), errors.Is(err, storage.ErrOutOfBounds), errors.Is(err, storage.ErrDuplicateSampleForTimestamp):
+		// Out of order samples are a bad request to prevent retries.
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	default:
+		level.Error(h.logger).Log("msg", "error appending remote write", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if discarded != nil {
+		// The valid series were appended; the 400 prevents the sender from
+		// retrying the request, which would fail in the same way.
+		http.Error(w, discarded.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Versions of the remote write protocol.
+const (
+	remoteWriteV1 = iota
+	remoteWriteV2
+)
+
+// remoteWriteVersion returns the version of the remote write protocol of
+// requests with the given content type. Requests without a proto parameter
+// are remote write 1.0 requests.
+func remoteWriteVersion(contentType string) (int, error) {
+	if contentType == "" {
+		return remoteWriteV1, nil
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0, fmt.Errorf("invalid content type %q: %w", contentType, err)
+	}
+	switch params["proto"] {
+	case "", "prometheus.WriteRequest":
+		return remoteWriteV1, nil
+	case "io.prometheus.write.v2.Request":
+		return remoteWriteV2, nil
+	default:
+		return 0, fmt.Errorf("unsupported protobuf message %q", params["proto"])
+	}
+}
+
+// writeStats counts what was written from a request.
+type writeStats struct {
+	samples, histograms, exemplars int
+}
+
+// write appends the series of req which pass validation. The error of the
+// first discarded series is returned as discarded. err is non-nil if the
+// request couldn't be appended, in which case nothing is written.
+func (h *writeHandler) write(ctx context.Context, req *prompb.WriteRequest, tenant *TenantArguments, tenantID string, limits LimitsArguments) (stats writeStats, discarded error, err error) {
+	if limits.MaxSeriesPerRequest > 0 && len(req.Timeseries) > limits.MaxSeriesPerRequest {
+		h.discardedSeries.WithLabelValues(reasonMaxSeries).Add(float64(len(req.Timeseries)))
+		return stats, nil, fmt.Errorf("%w: got %d series, limit is %d", errMaxSeriesPerRequest, len(req.Timeseries), limits.MaxSeriesPerRequest)
+	}
+
+	app := h.appendable.Appender(ctx)
+	defer func() {
+		if err == nil {
+			err = app.Commit()
+		} else {
+			_ = app.Rollback()
+		}
+		if err != nil {
+			stats = writeStats{}
+		}
+	}()
+
+	var builder labels.ScratchBuilder
+	for _, ts := range req.Timeseries {
+		builder.Reset()
+		for _, l := range ts.Labels {
+			if tenant != nil && tenantID != "" && l.Name == tenant.Label {
+				continue
+			}
+			builder.Add(l.Name, l.Value)
+		}
+		if tenant != nil && tenantID != "" {
+			builder.Add(tenant.Label, tenantID)
+		}
+		builder.Sort()
+		lbls := builder.Labels()
+
+		if reason, verr := validateSeries(lbls, limits); verr != nil {
+			h.discardedSeries.WithLabelValues(reason).Inc()
+			if reason == reasonInvalidLabels {
+				h.samplesWithInvalidLabels.Add(float64(len(ts.Samples)))
+			}
+			level.Debug(h.logger).Log("msg", "discarding series", "series", lbls.String(), "reason", reason)
+			if discarded == nil {
+				discarded = verr
+			}
+			continue
+		}
+
+		var ref storage.SeriesRef
+		for _, s := range ts.Samples {
+			ref, err = app.Append(ref, lbls, s.Timestamp, s.Value)
+			if err != nil {
+				return stats, nil, err
+			}
+			stats.samples++
+		}
+
+		for _, ep := range ts.Exemplars {
+			e := exemplar.Exemplar{
+				Labels: labelProtosToLabels(ep.Labels),
+				Value:  ep.Value,
+				Ts:     ep.Timestamp,
+				HasTs:  ep.Timestamp != 0,
+			}
+			// Exemplars are best effort and don't fail the request.
+			if _, exemplarErr := app.AppendExemplar(0, lbls, e); exemplarErr != nil {
+				level.Debug(h.logger).Log("msg", "error appending exemplar", "exemplar", fmt.Sprintf("%+v", e), "err", exemplarErr)
+			} else {
+				stats.exemplars++
+			}
+		}
+
+		for _, hp := range ts.Histograms {
+			if hp.IsFloatHistogram() {
+				_, err = app.AppendHistogram(0, lbls, hp.Timestamp, nil, remote.FloatHistogramProtoToFloatHistogram(hp))
+			} else {
+				_, err = app.AppendHistogram(0, lbls, hp.Timestamp, remote.HistogramProtoToHistogram(hp), nil)
+			}
+			if err != nil {
+				return stats, nil, err
+			}
+			stats.histograms++
+		}
+	}
+	return stats, discarded, nil
+}
+
+// validateSeries checks the labels of a series against limits. The reason
+// the series is discarded is returned along with the error.
+func validateSeries(lbls labels.Labels, limits LimitsArguments) (reason string, err error) {
+	if !lbls.IsValid() {
+		return reasonInvalidLabels, fmt.Errorf("series %s has an invalid metric name or labels", lbls)
+	}
+	if limits.MaxLabelNamesPerSeries > 0 && lbls.Len() > limits.MaxLabelNamesPerSeries {
+		return reasonMaxLabelNames, fmt.Errorf("series %s has %d labels, limit is %d", lbls, lbls.Len(), limits.MaxLabelNamesPerSeries)
+	}
+
+	lbls.Range(func(l labels.Label) {
+		switch {
+		case err != nil:
+		case limits.MaxLabelNameLength > 0 && len(l.Name) > limits.MaxLabelNameLength:
+			reason, err = reasonLabelNameLength, fmt.Errorf("label name %q of series %s is longer than %d", l.Name, lbls, limits.MaxLabelNameLength)
+		case limits.MaxLabelValueLength > 0 && len(l.Value) > limits.MaxLabelValueLength:
+			reason, err = reasonLabelValueLen, fmt.Errorf("value of label %q of series %s is longer than %d", l.Name, lbls, limits.MaxLabelValueLength)
+		}
+	})
+	return reason, err
+}
+
+func labelProtosToLabels(labelPairs []prompb.Label) labels.Labels {
+	var b labels.ScratchBuilder
+	for _, l := range labelPairs {
+		b.Add(l.Name, l.Value)
+	}
+	b.Sort()
+	return b.Labels()
+}
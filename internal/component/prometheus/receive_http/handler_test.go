@@ -0,0 +1,236 @@
+package receive_http
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+func newTestWriteHandler(t *testing.T) (*writeHandler, chan testSample) {
+	actualSamples := make(chan testSample, 100)
+	h := newWriteHandler(util.TestFlowLogger(t), prometheus.NewRegistry(), testAppendable(actualSamples)[0])
+	return h, actualSamples
+}
+
+func serveWriteRequest(t *testing.T, h http.Handler, req *prompb.WriteRequest, header http.Header) *httptest.ResponseRecorder {
+	buf, err := proto.Marshal(protoadapt.MessageV2Of(req))
+	require.NoError(t, err)
+
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/v1/metrics/write", bytes.NewReader(snappy.Encode(nil, buf)))
+	for name, values := range header {
+		httpReq.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httpReq)
+	return rec
+}
+
+func series(ts int64, lbls ...string) prompb.TimeSeries {
+	var series prompb.TimeSeries
+	for i := 0; i < len(lbls); i += 2 {
+		series.Labels = append(series.Labels, prompb.Label{Name: lbls[i], Value: lbls[i+1]})
+	}
+	series.Samples = []prompb.Sample{{Timestamp: ts, Value: 1}}
+	return series
+}
+
+func receivedSamples(ch chan testSample) []testSample {
+	var samples []testSample
+	for {
+		select {
+		case s := <-ch:
+			samples = append(samples, s)
+		default:
+			return samples
+		}
+	}
+}
+
+func TestWriteHandler_Tenant(t *testing.T) {
+	h, actualSamples := newTestWriteHandler(t)
+	h.update(&TenantArguments{Header: "X-Scope-OrgID", Label: "tenant"}, LimitsArguments{})
+
+	req := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		series(1000, "__name__", "up", "tenant", "spoofed"),
+	}}
+	rec := serveWriteRequest(t, h, req, http.Header{"X-Scope-Orgid": {"team-a"}})
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, []testSample{
+		{ts: 1000, val: 1, l: labels.FromStrings("__name__", "up", "tenant", "team-a")},
+	}, receivedSamples(actualSamples))
+
+	// Series keep their labels when the header is missing.
+	rec = serveWriteRequest(t, h, req, nil)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, []testSample{
+		{ts: 1000, val: 1, l: labels.FromStrings("__name__", "up", "tenant", "spoofed")},
+	}, receivedSamples(actualSamples))
+
+	h.update(&TenantArguments{Header: "X-Scope-OrgID", Label: "tenant", Required: true}, LimitsArguments{})
+	rec = serveWriteRequest(t, h, req, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Empty(t, receivedSamples(actualSamples))
+}
+
+func TestWriteHandler_Limits(t *testing.T) {
+	h, actualSamples := newTestWriteHandler(t)
+	h.update(nil, LimitsArguments{
+		MaxLabelNamesPerSeries: 3,
+		MaxLabelNameLength:     10,
+		MaxLabelValueLength:    10,
+		MaxSeriesPerRequest:    4,
+	})
+
+	req := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		series(1000, "__name__", "up", "job", "a"),
+		series(1000, "__name__", "up", "job", "a", "instance", "b", "pod", "c"),
+		series(1000, "__name__", "up", "a_very_long_label", "a"),
+		series(1000, "__name__", "up", "job", strings.Repeat("a", 11)),
+	}}
+	rec := serveWriteRequest(t, h, req, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "has 5 labels, limit is 3")
+	require.Equal(t, []testSample{
+		{ts: 1000, val: 1, l: labels.FromStrings("__name__", "up", "job", "a")},
+	}, receivedSamples(actualSamples))
+
+	require.Equal(t, 1.0, testutil.ToFloat64(h.discardedSeries.WithLabelValues(reasonMaxLabelNames)))
+	require.Equal(t, 1.0, testutil.ToFloat64(h.discardedSeries.WithLabelValues(reasonLabelNameLength)))
+	require.Equal(t, 1.0, testutil.ToFloat64(h.discardedSeries.WithLabelValues(reasonLabelValueLen)))
+
+	// Requests with too many series are rejected as a whole.
+	req.Timeseries = append(req.Timeseries, series(1000, "__name__", "up", "job", "b"))
+	rec = serveWriteRequest(t, h, req, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Empty(t, receivedSamples(actualSamples))
+	require.Equal(t, 5.0, testutil.ToFloat64(h.discardedSeries.WithLabelValues(reasonMaxSeries)))
+}
+
+// encodeWriteRequestV2 encodes the series of req as a remote write 2.0
+// request, with the labels of exemplars dropped.
+func encodeWriteRequestV2(req *prompb.WriteRequest) []byte {
+	symbols := []string{""}
+	refs := map[string]uint32{"": 0}
+	ref := func(s string) uint64 {
+		r, ok := refs[s]
+		if !ok {
+			r = uint32(len(symbols))
+			refs[s] = r
+			symbols = append(symbols, s)
+		}
+		return uint64(r)
+	}
+
+	var series []byte
+	for _, ts := range req.Timeseries {
+		var b, packed []byte
+		for _, l := range ts.Labels {
+			packed = protowire.AppendVarint(packed, ref(l.Name))
+			packed = protowire.AppendVarint(packed, ref(l.Value))
+		}
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, packed)
+		for _, s := range ts.Samples {
+			sample, _ := s.Marshal()
+			b = protowire.AppendTag(b, 2, protowire.BytesType)
+			b = protowire.AppendBytes(b, sample)
+		}
+		for _, h := range ts.Histograms {
+			histogram, _ := h.Marshal()
+			b = protowire.AppendTag(b, 3, protowire.BytesType)
+			b = protowire.AppendBytes(b, histogram)
+		}
+		for _, e := range ts.Exemplars {
+			var exemplar []byte
+			exemplar = protowire.AppendTag(exemplar, 2, protowire.Fixed64Type)
+			exemplar = protowire.AppendFixed64(exemplar, math.Float64bits(e.Value))
+			exemplar = protowire.AppendTag(exemplar, 3, protowire.VarintType)
+			exemplar = protowire.AppendVarint(exemplar, uint64(e.Timestamp))
+			b = protowire.AppendTag(b, 4, protowire.BytesType)
+			b = protowire.AppendBytes(b, exemplar)
+		}
+		series = protowire.AppendTag(series, 5, protowire.BytesType)
+		series = protowire.AppendBytes(series, b)
+	}
+
+	// Symbols are written after the series referencing them, which decoders
+	// must support.
+	buf := series
+	for _, s := range symbols {
+		buf = protowire.AppendTag(buf, 4, protowire.BytesType)
+		buf = protowire.AppendString(buf, s)
+	}
+	return buf
+}
+
+func TestWriteHandler_RemoteWriteV2(t *testing.T) {
+	h, actualSamples := newTestWriteHandler(t)
+	h.update(&TenantArguments{Header: "X-Scope-OrgID", Label: "tenant"}, LimitsArguments{MaxLabelValueLength: 10})
+
+	up := series(1000, "__name__", "up", "job", "a")
+	up.Exemplars = []prompb.Exemplar{{Value: 1, Timestamp: 1000}}
+	req := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		up,
+		series(2000, "__name__", "up", "job", "b"),
+		series(2000, "__name__", "up", "job", strings.Repeat("a", 11)),
+	}}
+
+	serve := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		httpReq := httptest.NewRequest(http.MethodPost, "/api/v1/metrics/write", bytes.NewReader(snappy.Encode(nil, body)))
+		httpReq.Header.Set("Content-Type", contentType)
+		httpReq.Header.Set("X-Scope-OrgID", "team-a")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httpReq)
+		return rec
+	}
+
+	rec := serve("application/x-protobuf;proto=io.prometheus.write.v2.Request", encodeWriteRequestV2(req))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), `value of label "job"`)
+	require.Equal(t, []testSample{
+		{ts: 1000, val: 1, l: labels.FromStrings("__name__", "up", "job", "a", "tenant", "team-a")},
+		{ts: 2000, val: 1, l: labels.FromStrings("__name__", "up", "job", "b", "tenant", "team-a")},
+	}, receivedSamples(actualSamples))
+	require.Equal(t, "2", rec.Header().Get("X-Prometheus-Remote-Write-Samples-Written"))
+	require.Equal(t, "0", rec.Header().Get("X-Prometheus-Remote-Write-Histograms-Written"))
+	require.Equal(t, "1", rec.Header().Get("X-Prometheus-Remote-Write-Exemplars-Written"))
+
+	// Label references must point to symbols.
+	invalid := protowire.AppendTag(nil, 1, protowire.VarintType)
+	invalid = protowire.AppendVarint(invalid, 0)
+	invalid = protowire.AppendTag(invalid, 1, protowire.VarintType)
+	invalid = protowire.AppendVarint(invalid, 7)
+	body := protowire.AppendTag(nil, 5, protowire.BytesType)
+	body = protowire.AppendBytes(body, invalid)
+	rec = serve("application/x-protobuf;proto=io.prometheus.write.v2.Request", body)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Contains(t, rec.Body.String(), "label reference out of the 0 symbols")
+
+	// Unknown messages are rejected so that senders fall back to another.
+	rec = serve("application/x-protobuf;proto=io.prometheus.write.v3.Request", encodeWriteRequestV2(req))
+	require.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	require.Empty(t, receivedSamples(actualSamples))
+
+	// Remote write 1.0 requests are still accepted, and don't get the
+	// written headers.
+	buf, err := proto.Marshal(protoadapt.MessageV2Of(&prompb.WriteRequest{Timeseries: req.Timeseries[:1]}))
+	require.NoError(t, err)
+	rec = serve("application/x-protobuf;proto=prometheus.WriteRequest", buf)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Len(t, receivedSamples(actualSamples), 1)
+	require.Empty(t, rec.Header().Get("X-Prometheus-Remote-Write-Samples-Written"))
+}
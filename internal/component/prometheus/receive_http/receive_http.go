@@ -3,7 +3,6 @@ package receive_http
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"reflect"
 	"sync"
 
@@ -16,8 +15,8 @@ import (
 	"github.com/grafana/agent/internal/service/labelstore"
 	"github.com/grafana/agent/internal/util"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/storage"
-	"github.com/prometheus/prometheus/storage/remote"
 )
 
 func init() {
@@ -35,6 +34,8 @@ func init() {
 type Arguments struct {
 	Server    *fnet.ServerConfig   `river:",squash"`
 	ForwardTo []storage.Appendable `river:"forward_to,attr"`
+	Tenant    *TenantArguments     `river:"tenant,block,optional"`
+	Limits    LimitsArguments      `river:"limits,block,optional"`
 }
 
 // SetToDefault implements river.Defaulter.
@@ -44,9 +45,53 @@ func (args *Arguments) SetToDefault() {
 	}
 }
 
+// TenantArguments configures how the tenant of a request is added to the
+// labels of its series.
+type TenantArguments struct {
+	// Header holding the tenant of a request.
+	Header string `river:"header,attr,optional"`
+	// Label set to the tenant on every series of a request.
+	Label string `river:"label,attr"`
+	// Whether requests without the tenant header are rejected.
+	Required bool `river:"required,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *TenantArguments) SetToDefault() {
+	*args = TenantArguments{Header: "X-Scope-OrgID"}
+}
+
+// Validate implements river.Validator.
+func (args *TenantArguments) Validate() error {
+	if args.Header == "" {
+		return fmt.Errorf("header must not be empty")
+	}
+	if !model.LabelName(args.Label).IsValid() {
+		return fmt.Errorf("label %q is not a valid label name", args.Label)
+	}
+	return nil
+}
+
+// LimitsArguments configures the limits series of requests are validated
+// against. A limit of 0 disables it.
+type LimitsArguments struct {
+	MaxLabelNamesPerSeries int `river:"max_label_names_per_series,attr,optional"`
+	MaxLabelNameLength     int `river:"max_label_name_length,attr,optional"`
+	MaxLabelValueLength    int `river:"max_label_value_length,attr,optional"`
+	MaxSeriesPerRequest    int `river:"max_series_per_request,attr,optional"`
+}
+
+// Validate implements river.Validator.
+func (args *LimitsArguments) Validate() error {
+	if args.MaxLabelNamesPerSeries < 0 || args.MaxLabelNameLength < 0 || args.MaxLabelValueLength < 0 || args.MaxSeriesPerRequest < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
+
 type Component struct {
 	opts               component.Options
-	handler            http.Handler
+	handler            *writeHandler
 	fanout             *agentprom.Fanout
 	uncheckedCollector *util.UncheckedCollector
 
@@ -68,7 +113,7 @@ func New(opts component.Options, args Arguments) (*Component, error) {
 
 	c := &Component{
 		opts:               opts,
-		handler:            remote.NewWriteHandler(opts.Logger, opts.Registerer, fanout),
+		handler:            newWriteHandler(opts.Logger, opts.Registerer, fanout),
 		fanout:             fanout,
 		uncheckedCollector: uncheckedCollector,
 	}
@@ -96,6 +141,7 @@ func (c *Component) Run(ctx context.Context) error {
 func (c *Component) Update(args component.Arguments) error {
 	newArgs := args.(Arguments)
 	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	c.handler.update(newArgs.Tenant, newArgs.Limits)
 
 	c.updateMut.Lock()
 	defer c.updateMut.Unlock()
@@ -0,0 +1,217 @@
+package receive_http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the io.prometheus.write.v2.Request message and of the
+// messages it holds. The vendored Prometheus version doesn't include the
+// generated remote write 2.0 types, so requests are decoded by hand.
+const (
+	requestSymbolsField    = 4
+	requestTimeseriesField = 5
+
+	seriesLabelsRefsField = 1
+	seriesSamplesField    = 2
+	seriesHistogramsField = 3
+	seriesExemplarsField  = 4
+
+	exemplarLabelsRefsField = 1
+	exemplarValueField      = 2
+	exemplarTimestampField  = 3
+)
+
+// customBucketsSchema is the schema of native histograms with custom bucket
+// boundaries, which remote write 1.0 types can't represent.
+const customBucketsSchema = -53
+
+// Headers of remote write 2.0 responses reporting what was written.
+const (
+	samplesWrittenHeader    = "X-Prometheus-Remote-Write-Samples-Written"
+	histogramsWrittenHeader = "X-Prometheus-Remote-Write-Histograms-Written"
+	exemplarsWrittenHeader  = "X-Prometheus-Remote-Write-Exemplars-Written"
+)
+
+var errInvalidProto = errors.New("invalid protobuf")
+
+// decodeWriteRequestV2 reads a snappy-compressed remote write 2.0 request
+// from r and converts it into a remote write 1.0 request. Metadata and
+// created timestamps of series are ignored, as they are for remote write
+// 1.0 requests.
+func decodeWriteRequestV2(r io.Reader) (*prompb.WriteRequest, error) {
+	compressed, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Symbols may follow the series referencing them, so series are only
+	// converted once the whole request is read.
+	var (
+		symbols []string
+		series  [][]byte
+	)
+	err = forEachField(buf, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		switch {
+		case num == requestSymbolsField && typ == protowire.BytesType:
+			symbols = append(symbols, string(b))
+		case num == requestTimeseriesField && typ == protowire.BytesType:
+			series = append(series, b)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req := &prompb.WriteRequest{Timeseries: make([]prompb.TimeSeries, 0, len(series))}
+	for _, b := range series {
+		ts, err := decodeTimeSeriesV2(b, symbols)
+		if err != nil {
+			return nil, err
+		}
+		req.Timeseries = append(req.Timeseries, ts)
+	}
+	return req, nil
+}
+
+func decodeTimeSeriesV2(buf []byte, symbols []string) (prompb.TimeSeries, error) {
+	var (
+		ts   prompb.TimeSeries
+		refs []uint32
+	)
+	err := forEachField(buf, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		var err error
+		switch num {
+		case seriesLabelsRefsField:
+			refs, err = appendRefs(refs, typ, b)
+		case seriesSamplesField:
+			// Samples have the same fields as in remote write 1.0.
+			var s prompb.Sample
+			err = s.Unmarshal(b)
+			ts.Samples = append(ts.Samples, s)
+		case seriesHistogramsField:
+			// Histograms have the same fields as in remote write 1.0, plus
+			// the custom values of custom bucket histograms.
+			var h prompb.Histogram
+			if err = h.Unmarshal(b); err == nil && h.Schema == customBucketsSchema {
+				err = errors.New("native histograms with custom buckets aren't supported")
+			}
+			ts.Histograms = append(ts.Histograms, h)
+		case seriesExemplarsField:
+			var e prompb.Exemplar
+			e, err = decodeExemplarV2(b, symbols)
+			ts.Exemplars = append(ts.Exemplars, e)
+		}
+		return err
+	})
+	if err != nil {
+		return ts, err
+	}
+
+	ts.Labels, err = resolveLabels(refs, symbols)
+	return ts, err
+}
+
+func decodeExemplarV2(buf []byte, symbols []string) (prompb.Exemplar, error) {
+	var (
+		e    prompb.Exemplar
+		refs []uint32
+	)
+	err := forEachField(buf, func(num protowire.Number, typ protowire.Type, b []byte) error {
+		var err error
+		switch {
+		case num == exemplarLabelsRefsField:
+			refs, err = appendRefs(refs, typ, b)
+		case num == exemplarValueField && typ == protowire.Fixed64Type:
+			v, _ := protowire.ConsumeFixed64(b)
+			e.Value = math.Float64frombits(v)
+		case num == exemplarTimestampField && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(b)
+			e.Timestamp = int64(v)
+		}
+		return err
+	})
+	if err != nil {
+		return e, err
+	}
+
+	e.Labels, err = resolveLabels(refs, symbols)
+	return e, err
+}
+
+// resolveLabels converts pairs of references to the symbols of a request
+// into labels.
+func resolveLabels(refs []uint32, symbols []string) ([]prompb.Label, error) {
+	if len(refs)%2 != 0 {
+		return nil, fmt.Errorf("%w: odd number of label references", errInvalidProto)
+	}
+	labels := make([]prompb.Label, 0, len(refs)/2)
+	for i := 0; i < len(refs); i += 2 {
+		name, value := refs[i], refs[i+1]
+		if int(name) >= len(symbols) || int(value) >= len(symbols) {
+			return nil, fmt.Errorf("%w: label reference out of the %d symbols", errInvalidProto, len(symbols))
+		}
+		labels = append(labels, prompb.Label{Name: symbols[name], Value: symbols[value]})
+	}
+	return labels, nil
+}
+
+// appendRefs appends the symbol references of a packed or unpacked repeated
+// uint32 field to refs.
+func appendRefs(refs []uint32, typ protowire.Type, b []byte) ([]uint32, error) {
+	switch typ {
+	case protowire.VarintType:
+		v, _ := protowire.ConsumeVarint(b)
+		return append(refs, uint32(v)), nil
+	case protowire.BytesType:
+		for len(b) > 0 {
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, fmt.Errorf("%w: %w", errInvalidProto, protowire.ParseError(n))
+			}
+			refs = append(refs, uint32(v))
+			b = b[n:]
+		}
+		return refs, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected wire type %d for label references", errInvalidProto, typ)
+	}
+}
+
+// forEachField calls fn with the number, type, and raw value of each field of
+// the message in buf. The value of length-delimited fields is their content.
+func forEachField(buf []byte, fn func(num protowire.Number, typ protowire.Type, b []byte) error) error {
+	for len(buf) > 0 {
+		num, typ, n := protowire.ConsumeTag(buf)
+		if n < 0 {
+			return fmt.Errorf("%w: %w", errInvalidProto, protowire.ParseError(n))
+		}
+		buf = buf[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, buf)
+		if n < 0 {
+			return fmt.Errorf("%w: %w", errInvalidProto, protowire.ParseError(n))
+		}
+		value := buf[:n]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+		buf = buf[n:]
+
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package relabel
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/atomic"
+)
+
+// maxCacheShards is the number of shards of the relabel cache, unless the
+// cache is too small to hold an entry per shard.
+const maxCacheShards = 16
+
+// relabelCache holds the results of relabeling series by their global ref ID.
+// Series are spread across shards, each of which is an LRU cache with its own
+// lock, so that concurrent appenders rarely contend on the same lock. Each
+// shard evicts its own least recently used entries, which approximates a
+// single LRU cache.
+type relabelCache struct {
+	shards atomic.Pointer[[]*cacheShard]
+
+	hits   *prometheus_client.CounterVec
+	misses *prometheus_client.CounterVec
+}
+
+type cacheShard struct {
+	entries *lru.Cache[uint64, *cacheEntry]
+	hits    prometheus_client.Counter
+	misses  prometheus_client.Counter
+}
+
+// newRelabelCache returns a cache holding up to size entries. hits and misses
+// count the lookups of each shard by its index in the shard label.
+func newRelabelCache(size int, hits, misses *prometheus_client.CounterVec) (*relabelCache, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("cache size must be greater than 0 and is %d", size)
+	}
+	c := &relabelCache{hits: hits, misses: misses}
+	c.shards.Store(c.newShards(size))
+	return c, nil
+}
+
+// newShards returns shards which hold up to size entries in total.
+func (c *relabelCache) newShards(size int) *[]*cacheShard {
+	shards := make([]*cacheShard, min(size, maxCacheShards))
+	for i, shardSize := range shardSizes(size, len(shards)) {
+		entries, _ := lru.New[uint64, *cacheEntry](shardSize)
+		shards[i] = &cacheShard{
+			entries: entries,
+			hits:    c.hits.WithLabelValues(strconv.Itoa(i)),
+			misses:  c.misses.WithLabelValues(strconv.Itoa(i)),
+		}
+	}
+	return &shards
+}
+
+// shardSizes splits size between n shards as evenly as possible.
+func shardSizes(size, n int) []int {
+	sizes := make([]int, n)
+	for i := range sizes {
+		sizes[i] = size / n
+		if i < size%n {
+			sizes[i]++
+		}
+	}
+	return sizes
+}
+
+// shard returns the shard of the series with the given global ref ID. Global
+// ref IDs are sequential, so they're mixed before being split between shards.
+func (c *relabelCache) shard(id uint64) *cacheShard {
+	shards := *c.shards.Load()
+	return shards[(id*0x9E3779B97F4A7C15>>32)%uint64(len(shards))]
+}
+
+// Get returns the entry for id, counting the lookup as a hit or a miss. When
+// touch is true, the time the entry was last used is updated.
+func (c *relabelCache) Get(id uint64, touch bool) (*cacheEntry, bool) {
+	shard := c.shard(id)
+	entry, found := shard.entries.Get(id)
+	if !found {
+		shard.misses.Inc()
+		return nil, false
+	}
+	shard.hits.Inc()
+	if touch {
+		entry.lastUsed.Store(time.Now().UnixNano())
+	}
+	return entry, true
+}
+
+// Add adds an entry for id, evicting the least recently used entry of its
+// shard if the shard is full.
+func (c *relabelCache) Add(id uint64, entry *cacheEntry) {
+	c.shard(id).entries.Add(id, entry)
+}
+
+// Remove removes the entry for id.
+func (c *relabelCache) Remove(id uint64) {
+	c.shard(id).entries.Remove(id)
+}
+
+// Len returns the number of entries in the cache.
+func (c *relabelCache) Len() int {
+	var n int
+	for _, shard := range *c.shards.Load() {
+		n += shard.entries.Len()
+	}
+	return n
+}
+
+// Reset removes all entries and sets the size of the cache.
+func (c *relabelCache) Reset(size int) {
+	c.shards.Store(c.newShards(size))
+}
+
+// Resize changes the size of the cache, evicting the least recently used
+// entries if it shrinks.
+func (c *relabelCache) Resize(size int) {
+	shards := *c.shards.Load()
+	if n := min(size, maxCacheShards); n != len(shards) {
+		c.reshard(shards, size)
+		return
+	}
+	for i, shardSize := range shardSizes(size, len(shards)) {
+		shards[i].entries.Resize(shardSize)
+	}
+}
+
+// reshard moves the entries of shards to a new set of shards holding up to
+// size entries. Entries are added from the least to the most recently used
+// according to the time they were last used, so the most recently used are
+// kept if they don't all fit. The time an entry was last used is only updated
+// by lookups when cache_ttl is set; otherwise it's the time it was added.
+func (c *relabelCache) reshard(shards []*cacheShard, size int) {
+	type idAndEntry struct {
+		id    uint64
+		entry *cacheEntry
+	}
+	var all []idAndEntry
+	for _, shard := range shards {
+		for _, id := range shard.entries.Keys() {
+			if entry, ok := shard.entries.Peek(id); ok {
+				all = append(all, idAndEntry{id, entry})
+			}
+		}
+	}
+	slices.SortStableFunc(all, func(a, b idAndEntry) int {
+		return cmp.Compare(a.entry.lastUsed.Load(), b.entry.lastUsed.Load())
+	})
+
+	newShards := c.newShards(size)
+	c.shards.Store(newShards)
+	for _, e := range all {
+		c.Add(e.id, e.entry)
+	}
+}
+
+// Expire removes the entries which weren't used since the deadline and
+// returns how many were removed.
+func (c *relabelCache) Expire(deadline time.Time) int {
+	var expired int
+	for _, shard := range *c.shards.Load() {
+		for _, id := range shard.entries.Keys() {
+			// Peek doesn't update the recency of the entry.
+			entry, ok := shard.entries.Peek(id)
+			if ok && entry.lastUsed.Load() < deadline.UnixNano() {
+				shard.entries.Remove(id)
+				expired++
+			}
+		}
+	}
+	return expired
+}
@@ -0,0 +1,101 @@
+package relabel
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(t *testing.T, size int) (*relabelCache, *prom.CounterVec, *prom.CounterVec) {
+	t.Helper()
+
+	hits := prom.NewCounterVec(prom.CounterOpts{Name: "hits"}, []string{"shard"})
+	misses := prom.NewCounterVec(prom.CounterOpts{Name: "misses"}, []string{"shard"})
+	cache, err := newRelabelCache(size, hits, misses)
+	require.NoError(t, err)
+	return cache, hits, misses
+}
+
+func TestShardSizes(t *testing.T) {
+	require.Equal(t, []int{7, 7, 6}, shardSizes(20, 3))
+	require.Equal(t, []int{1}, shardSizes(1, 1))
+
+	for _, size := range []int{1, 15, 16, 17, 100_000} {
+		var total int
+		for _, n := range shardSizes(size, min(size, maxCacheShards)) {
+			require.Positive(t, n)
+			total += n
+		}
+		require.Equal(t, size, total)
+	}
+}
+
+func TestRelabelCache(t *testing.T) {
+	cache, hits, misses := newTestCache(t, 1000)
+
+	for id := uint64(0); id < 100; id++ {
+		cache.Add(id, &cacheEntry{})
+	}
+	require.Equal(t, 100, cache.Len())
+
+	for id := uint64(0); id < 200; id++ {
+		_, found := cache.Get(id, false)
+		require.Equal(t, id < 100, found)
+	}
+
+	// Lookups are spread across the shards, which count them separately.
+	var totalHits, totalMisses float64
+	for i := 0; i < maxCacheShards; i++ {
+		shardHits := testutil.ToFloat64(hits.WithLabelValues(strconv.Itoa(i)))
+		require.Positive(t, shardHits, "shard %d was never hit", i)
+		totalHits += shardHits
+		totalMisses += testutil.ToFloat64(misses.WithLabelValues(strconv.Itoa(i)))
+	}
+	require.Equal(t, 100.0, totalHits)
+	require.Equal(t, 100.0, totalMisses)
+
+	cache.Remove(0)
+	require.Equal(t, 99, cache.Len())
+
+	cache.Reset(1000)
+	require.Equal(t, 0, cache.Len())
+}
+
+func TestRelabelCache_Reshard(t *testing.T) {
+	cache, _, _ := newTestCache(t, 1000)
+
+	// Shrinking the cache below the number of shards keeps the most recently
+	// used entries of each shard.
+	now := time.Now()
+	for id := uint64(0); id < 10; id++ {
+		entry := &cacheEntry{}
+		entry.lastUsed.Store(now.Add(time.Duration(id) * time.Second).UnixNano())
+		cache.Add(id, entry)
+	}
+	cache.Resize(3)
+	require.LessOrEqual(t, cache.Len(), 3)
+	_, found := cache.Get(9, false)
+	require.True(t, found, "most recently used entry was evicted")
+
+	// Growing the cache keeps all entries.
+	n := cache.Len()
+	cache.Resize(1000)
+	require.Equal(t, n, cache.Len())
+}
+
+func TestRelabelCache_Expire(t *testing.T) {
+	cache, _, _ := newTestCache(t, 1000)
+
+	now := time.Now()
+	for id := uint64(0); id < 10; id++ {
+		entry := &cacheEntry{}
+		entry.lastUsed.Store(now.Add(time.Duration(id) * time.Second).UnixNano())
+		cache.Add(id, entry)
+	}
+	require.Equal(t, 5, cache.Expire(now.Add(5*time.Second)))
+	require.Equal(t, 5, cache.Len())
+}
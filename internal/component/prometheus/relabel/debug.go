@@ -0,0 +1,197 @@
+package relabel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+)
+
+// DebugArguments configures capturing how series are relabeled.
+type DebugArguments struct {
+	// Number of most recently relabeled series to keep.
+	SampleSize int `river:"sample_size,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *DebugArguments) SetToDefault() {
+	*args = DebugArguments{
+		SampleSize: 100,
+	}
+}
+
+// Validate implements river.Validator.
+func (args *DebugArguments) Validate() error {
+	if args.SampleSize <= 0 {
+		return fmt.Errorf("sample_size must be greater than 0 and is %d", args.SampleSize)
+	}
+	return nil
+}
+
+// debugSample describes how a series was relabeled.
+type debugSample struct {
+	Time            time.Time `river:"time,attr" json:"time"`
+	Input           string    `river:"input,attr" json:"input"`
+	Output          string    `river:"output,attr,optional" json:"output,omitempty"`
+	Dropped         bool      `river:"dropped,attr" json:"dropped"`
+	DroppedByRule   int       `river:"dropped_by_rule,attr" json:"droppedByRule"` // Index of the rule which dropped the series; -1 if it was kept.
+	ModifiedByRules []int     `river:"modified_by_rules,attr,optional" json:"modifiedByRules,omitempty"`
+}
+
+// traceRelabel applies cfgs to lbls one rule at a time, recording which rules
+// changed the labels and which rule dropped the series.
+func traceRelabel(lbls labels.Labels, cfgs []*relabel.Config) debugSample {
+	sample := debugSample{
+		Time:          time.Now(),
+		Input:         lbls.String(),
+		DroppedByRule: -1,
+	}
+
+	current := lbls.Copy()
+	for i, cfg := range cfgs {
+		next, keep := relabel.Process(current.Copy(), cfg)
+		if !keep {
+			sample.Dropped = true
+			sample.DroppedByRule = i
+			return sample
+		}
+		if !labels.Equal(current, next) {
+			sample.ModifiedByRules = append(sample.ModifiedByRules, i)
+		}
+		current = next
+	}
+	sample.Output = current.String()
+	return sample
+}
+
+// debugInfo is the debug information of the component when debugging is
+// enabled.
+type debugInfo struct {
+	Samples []debugSample `river:"sample,block,optional"`
+}
+
+// debugRecorder keeps the most recent relabeled series and sends new ones to
+// subscribers.
+type debugRecorder struct {
+	mut         sync.Mutex
+	size        int
+	samples     []debugSample // Ring buffer of samples
+	next        int           // Index of samples to write the next sample to
+	subscribers map[chan debugSample]struct{}
+}
+
+func newDebugRecorder(size int) *debugRecorder {
+	return &debugRecorder{
+		size:        size,
+		subscribers: make(map[chan debugSample]struct{}),
+	}
+}
+
+func (r *debugRecorder) Record(s debugSample) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if len(r.samples) < r.size {
+		r.samples = append(r.samples, s)
+	} else {
+		r.samples[r.next] = s
+	}
+	r.next = (r.next + 1) % r.size
+
+	for ch := range r.subscribers {
+		select {
+		case ch <- s:
+		default:
+			// Drop samples for subscribers which can't keep up rather than
+			// blocking relabeling.
+		}
+	}
+}
+
+// Samples returns the recorded samples, oldest first.
+func (r *debugRecorder) Samples() []debugSample {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if len(r.samples) < r.size {
+		return append([]debugSample(nil), r.samples...)
+	}
+	return append(append([]debugSample(nil), r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+// Subscribe returns a channel which receives new samples until the returned
+// function is called.
+func (r *debugRecorder) Subscribe() (<-chan debugSample, func()) {
+	ch := make(chan debugSample, r.size)
+
+	r.mut.Lock()
+	r.subscribers[ch] = struct{}{}
+	r.mut.Unlock()
+
+	return ch, func() {
+		r.mut.Lock()
+		defer r.mut.Unlock()
+		delete(r.subscribers, ch)
+	}
+}
+
+// DebugInfo implements component.DebugComponent.
+func (c *Component) DebugInfo() interface{} {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if c.debug == nil {
+		return nil
+	}
+	return debugInfo{Samples: c.debug.Samples()}
+}
+
+// Handler implements http_service.Component. When debugging is enabled, it
+// streams relabeled series as Server-Sent Events at /debug/stream.
+func (c *Component) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/stream", func(w http.ResponseWriter, r *http.Request) {
+		c.mut.RLock()
+		recorder := c.debug
+		c.mut.RUnlock()
+
+		if recorder == nil {
+			http.Error(w, "debugging isn't enabled; add a debug block to the component", http.StatusNotFound)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming isn't supported", http.StatusNotImplemented)
+			return
+		}
+
+		samples, unsubscribe := recorder.Subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case s := <-samples:
+				bb, err := json.Marshal(s)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: sample\ndata: %s\n\n", bb); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+	return mux
+}
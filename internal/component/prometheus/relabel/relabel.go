@@ -3,14 +3,16 @@ package relabel
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/grafana/agent/internal/component"
 	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
 	"github.com/grafana/agent/internal/component/prometheus"
 	"github.com/grafana/agent/internal/featuregate"
+	http_service "github.com/grafana/agent/internal/service/http"
 	"github.com/grafana/agent/internal/service/labelstore"
-	lru "github.com/hashicorp/golang-lru/v2"
 	prometheus_client "github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/histogram"
@@ -46,6 +48,13 @@ type Arguments struct {
 
 	// Cache size to use for LRU cache.
 	CacheSize int `river:"max_cache_size,attr,optional"`
+
+	// How long cache entries are kept without being used. Zero disables
+	// expiry.
+	CacheTTL time.Duration `river:"cache_ttl,attr,optional"`
+
+	// Capture how series are relabeled for debugging.
+	Debug *DebugArguments `river:"debug,block,optional"`
 }
 
 // SetToDefault implements river.Defaulter.
@@ -60,6 +69,9 @@ func (arg *Arguments) Validate() error {
 	if arg.CacheSize <= 0 {
 		return fmt.Errorf("max_cache_size must be greater than 0 and is %d", arg.CacheSize)
 	}
+	if arg.CacheTTL < 0 {
+		return fmt.Errorf("cache_ttl must not be negative and is %s", arg.CacheTTL)
+	}
 	return nil
 }
 
@@ -73,40 +85,44 @@ type Exports struct {
 type Component struct {
 	mut              sync.RWMutex
 	opts             component.Options
+	rules            []*flow_relabel.Config
 	mrc              []*relabel.Config
 	receiver         *prometheus.Interceptor
 	metricsProcessed prometheus_client.Counter
 	metricsOutgoing  prometheus_client.Counter
-	cacheHits        prometheus_client.Counter
-	cacheMisses      prometheus_client.Counter
-	cacheSize        prometheus_client.Gauge
+	cacheHits        *prometheus_client.CounterVec
+	cacheMisses      *prometheus_client.CounterVec
+	cacheSize        prometheus_client.GaugeFunc
 	cacheDeletes     prometheus_client.Counter
+	cacheExpired     prometheus_client.Counter
 	fanout           *prometheus.Fanout
 	exited           atomic.Bool
 	ls               labelstore.LabelStore
+	debug            *debugRecorder // nil unless debugging is enabled
+	cacheTTL         time.Duration
+	cacheTTLUpdated  chan struct{}
 
-	cacheMut sync.RWMutex
-	cache    *lru.Cache[uint64, *labelAndID]
+	cache        *relabelCache
+	maxCacheSize int
 }
 
 var (
-	_ component.Component = (*Component)(nil)
+	_ component.Component      = (*Component)(nil)
+	_ component.DebugComponent = (*Component)(nil)
+	_ http_service.Component   = (*Component)(nil)
 )
 
 // New creates a new prometheus.relabel component.
 func New(o component.Options, args Arguments) (*Component, error) {
-	cache, err := lru.New[uint64, *labelAndID](args.CacheSize)
-	if err != nil {
-		return nil, err
-	}
 	data, err := o.GetServiceData(labelstore.ServiceName)
 	if err != nil {
 		return nil, err
 	}
 	c := &Component{
-		opts:  o,
-		cache: cache,
-		ls:    data.(labelstore.LabelStore),
+		opts:            o,
+		maxCacheSize:    args.CacheSize,
+		ls:              data.(labelstore.LabelStore),
+		cacheTTLUpdated: make(chan struct{}, 1),
 	}
 	c.metricsProcessed = prometheus_client.NewCounter(prometheus_client.CounterOpts{
 		Name: "agent_prometheus_relabel_metrics_processed",
@@ -116,24 +132,35 @@ func New(o component.Options, args Arguments) (*Component, error) {
 		Name: "agent_prometheus_relabel_metrics_written",
 		Help: "Total number of metrics written",
 	})
-	c.cacheMisses = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+	c.cacheMisses = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
 		Name: "agent_prometheus_relabel_cache_misses",
-		Help: "Total number of cache misses",
-	})
-	c.cacheHits = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Help: "Total number of cache misses, by cache shard",
+	}, []string{"shard"})
+	c.cacheHits = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
 		Name: "agent_prometheus_relabel_cache_hits",
-		Help: "Total number of cache hits",
-	})
-	c.cacheSize = prometheus_client.NewGauge(prometheus_client.GaugeOpts{
+		Help: "Total number of cache hits, by cache shard",
+	}, []string{"shard"})
+	c.cacheSize = prometheus_client.NewGaugeFunc(prometheus_client.GaugeOpts{
 		Name: "agent_prometheus_relabel_cache_size",
 		Help: "Total size of relabel cache",
+	}, func() float64 {
+		return float64(c.cache.Len())
 	})
 	c.cacheDeletes = prometheus_client.NewCounter(prometheus_client.CounterOpts{
 		Name: "agent_prometheus_relabel_cache_deletes",
 		Help: "Total number of cache deletes",
 	})
+	c.cacheExpired = prometheus_client.NewCounter(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_relabel_cache_expired",
+		Help: "Total number of cache entries expired after cache_ttl",
+	})
 
-	for _, metric := range []prometheus_client.Collector{c.metricsProcessed, c.metricsOutgoing, c.cacheMisses, c.cacheHits, c.cacheSize, c.cacheDeletes} {
+	c.cache, err = newRelabelCache(args.CacheSize, c.cacheHits, c.cacheMisses)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, metric := range []prometheus_client.Collector{c.metricsProcessed, c.metricsOutgoing, c.cacheMisses, c.cacheHits, c.cacheSize, c.cacheDeletes, c.cacheExpired} {
 		err = o.Registerer.Register(metric)
 		if err != nil {
 			return nil, err
@@ -144,51 +171,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 	c.receiver = prometheus.NewInterceptor(
 		c.fanout,
 		c.ls,
-		prometheus.WithAppendHook(func(_ storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
-			if c.exited.Load() {
-				return 0, fmt.Errorf("%s has exited", o.ID)
-			}
-
-			newLbl := c.relabel(v, l)
-			if newLbl.IsEmpty() {
-				return 0, nil
-			}
-			c.metricsOutgoing.Inc()
-			return next.Append(0, newLbl, t, v)
-		}),
-		prometheus.WithExemplarHook(func(_ storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
-			if c.exited.Load() {
-				return 0, fmt.Errorf("%s has exited", o.ID)
-			}
-
-			newLbl := c.relabel(0, l)
-			if newLbl.IsEmpty() {
-				return 0, nil
-			}
-			return next.AppendExemplar(0, newLbl, e)
-		}),
-		prometheus.WithMetadataHook(func(_ storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
-			if c.exited.Load() {
-				return 0, fmt.Errorf("%s has exited", o.ID)
-			}
-
-			newLbl := c.relabel(0, l)
-			if newLbl.IsEmpty() {
-				return 0, nil
-			}
-			return next.UpdateMetadata(0, newLbl, m)
-		}),
-		prometheus.WithHistogramHook(func(_ storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
-			if c.exited.Load() {
-				return 0, fmt.Errorf("%s has exited", o.ID)
-			}
-
-			newLbl := c.relabel(0, l)
-			if newLbl.IsEmpty() {
-				return 0, nil
-			}
-			return next.AppendHistogram(0, newLbl, t, h, fh)
-		}),
+		prometheus.WithAppenderMiddleware(c.newTransactionMiddleware),
 	)
 
 	// Immediately export the receiver which remains the same for the component
@@ -207,8 +190,47 @@ func New(o component.Options, args Arguments) (*Component, error) {
 func (c *Component) Run(ctx context.Context) error {
 	defer c.exited.Store(true)
 
-	<-ctx.Done()
-	return nil
+	var (
+		ticker *time.Ticker
+		tick   <-chan time.Time
+	)
+	resetTicker := func() {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+
+		c.mut.RLock()
+		ttl := c.cacheTTL
+		c.mut.RUnlock()
+
+		// Sweep twice per TTL so entries are removed at most 1.5x cache_ttl after
+		// they were last used.
+		if ttl > 0 {
+			ticker = time.NewTicker(ttl / 2)
+			tick = ticker.C
+		}
+	}
+	resetTicker()
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.cacheTTLUpdated:
+			resetTicker()
+		case now := <-tick:
+			c.mut.RLock()
+			ttl := c.cacheTTL
+			c.mut.RUnlock()
+			c.expireCache(now.Add(-ttl))
+		}
+	}
 }
 
 // Update implements component.Component.
@@ -217,27 +239,117 @@ func (c *Component) Update(args component.Arguments) error {
 	defer c.mut.Unlock()
 
 	newArgs := args.(Arguments)
-	c.clearCache(newArgs.CacheSize)
+
+	// Cached results are only invalid if the rules changed. The cache is also
+	// cleared when debugging is enabled so that every series is captured again.
+	switch {
+	case !rulesEqual(c.rules, newArgs.MetricRelabelConfigs), newArgs.Debug != nil && c.debug == nil:
+		c.clearCache(newArgs.CacheSize)
+	case c.maxCacheSize != newArgs.CacheSize:
+		c.resizeCache(newArgs.CacheSize)
+	}
+	c.rules = newArgs.MetricRelabelConfigs
 	c.mrc = flow_relabel.ComponentToPromRelabelConfigs(newArgs.MetricRelabelConfigs)
 	c.fanout.UpdateChildren(newArgs.ForwardTo)
 
+	if c.cacheTTL != newArgs.CacheTTL {
+		c.cacheTTL = newArgs.CacheTTL
+		select {
+		case c.cacheTTLUpdated <- struct{}{}:
+		default:
+		}
+	}
+
+	switch {
+	case newArgs.Debug == nil:
+		c.debug = nil
+	case c.debug == nil || c.debug.size != newArgs.Debug.SampleSize:
+		c.debug = newDebugRecorder(newArgs.Debug.SampleSize)
+	}
+
 	c.opts.OnStateChange(Exports{Receiver: c.receiver, Rules: newArgs.MetricRelabelConfigs})
 
 	return nil
 }
 
+// newTransactionMiddleware returns the middleware which relabels the series
+// appended in a single transaction. Each series is relabeled once per
+// transaction, and the decision is shared by its samples, exemplars, metadata,
+// and histograms, so that they don't each look it up in the cache.
+//
+// Decisions are kept until the end of the transaction, even if the rules are
+// updated in the meantime.
+func (c *Component) newTransactionMiddleware() prometheus.Middleware {
+	// Relabeled labels of the series of the transaction by global ref ID. Empty
+	// labels mean the series is dropped.
+	decisions := make(map[storage.SeriesRef]labels.Labels)
+
+	relabel := func(ref storage.SeriesRef, l labels.Labels, v float64) (labels.Labels, error) {
+		if c.exited.Load() {
+			return labels.EmptyLabels(), fmt.Errorf("%s has exited", c.opts.ID)
+		}
+
+		newLbl, found := decisions[ref]
+		switch {
+		case !found:
+			newLbl = c.relabelSeries(uint64(ref), v, l)
+			decisions[ref] = newLbl
+		case value.IsStaleNaN(v):
+			// The series went stale after its decision was made.
+			c.deleteFromCache(uint64(ref))
+		}
+		return newLbl, nil
+	}
+
+	return prometheus.MiddlewareFuncs{
+		OnAppend: func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+			newLbl, err := relabel(ref, l, v)
+			if err != nil || newLbl.IsEmpty() {
+				return 0, err
+			}
+			c.metricsOutgoing.Inc()
+			return next.Append(0, newLbl, t, v)
+		},
+		OnAppendExemplar: func(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar, next storage.Appender) (storage.SeriesRef, error) {
+			newLbl, err := relabel(ref, l, 0)
+			if err != nil || newLbl.IsEmpty() {
+				return 0, err
+			}
+			return next.AppendExemplar(0, newLbl, e)
+		},
+		OnUpdateMetadata: func(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata, next storage.Appender) (storage.SeriesRef, error) {
+			newLbl, err := relabel(ref, l, 0)
+			if err != nil || newLbl.IsEmpty() {
+				return 0, err
+			}
+			return next.UpdateMetadata(0, newLbl, m)
+		},
+		OnAppendHistogram: func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+			newLbl, err := relabel(ref, l, 0)
+			if err != nil || newLbl.IsEmpty() {
+				return 0, err
+			}
+			return next.AppendHistogram(0, newLbl, t, h, fh)
+		},
+	}
+}
+
 func (c *Component) relabel(val float64, lbls labels.Labels) labels.Labels {
+	return c.relabelSeries(c.ls.GetOrAddGlobalRefID(lbls), val, lbls)
+}
+
+// relabelSeries relabels the series lbls, whose global ref ID is globalRef,
+// using the cache.
+func (c *Component) relabelSeries(globalRef uint64, val float64, lbls labels.Labels) labels.Labels {
 	c.mut.RLock()
 	defer c.mut.RUnlock()
 
-	globalRef := c.ls.GetOrAddGlobalRefID(lbls)
 	var (
 		relabelled labels.Labels
 		keep       bool
 	)
 	newLbls, found := c.getFromCache(globalRef)
 	if found {
-		c.cacheHits.Inc()
 		// If newLbls is nil but cache entry was found then we want to keep the value nil, if it's not we want to reuse the labels
 		if newLbls != nil {
 			relabelled = newLbls.labels
@@ -246,57 +358,99 @@ func (c *Component) relabel(val float64, lbls labels.Labels) labels.Labels {
 		// Relabel against a copy of the labels to prevent modifying the original
 		// slice.
 		relabelled, keep = relabel.Process(lbls.Copy(), c.mrc...)
-		c.cacheMisses.Inc()
 		c.addToCache(globalRef, relabelled, keep)
+
+		if c.debug != nil {
+			c.debug.Record(traceRelabel(lbls, c.mrc))
+		}
 	}
 
 	// If stale remove from the cache, the reason we don't exit early is so the stale value can propagate.
-	// TODO: (@mattdurham) This caching can leak and likely needs a timed eviction at some point, but this is simple.
-	// In the future the global ref cache may have some hooks to allow notification of when caches should be evicted.
+	// Series which are never marked stale are only removed by the LRU or, if
+	// cache_ttl is set, once they're unused for cache_ttl.
 	if value.IsStaleNaN(val) {
 		c.deleteFromCache(globalRef)
 	}
-	// Set the cache size to the cache.len
-	// TODO(@mattdurham): Instead of setting this each time could collect on demand for better performance.
-	c.cacheSize.Set(float64(c.cache.Len()))
 	return relabelled
 }
 
+// getFromCache looks up the cached result of relabeling the series with the
+// given global ref ID. c.mut must be held.
 func (c *Component) getFromCache(id uint64) (*labelAndID, bool) {
-	c.cacheMut.RLock()
-	defer c.cacheMut.RUnlock()
-
-	fm, found := c.cache.Get(id)
-	return fm, found
+	entry, found := c.cache.Get(id, c.cacheTTL > 0)
+	if !found {
+		return nil, false
+	}
+	return entry.value, true
 }
 
 func (c *Component) deleteFromCache(id uint64) {
-	c.cacheMut.Lock()
-	defer c.cacheMut.Unlock()
 	c.cacheDeletes.Inc()
 	c.cache.Remove(id)
 }
 
 func (c *Component) clearCache(cacheSize int) {
-	c.cacheMut.Lock()
-	defer c.cacheMut.Unlock()
-	cache, _ := lru.New[uint64, *labelAndID](cacheSize)
-	c.cache = cache
+	c.cache.Reset(cacheSize)
+	c.maxCacheSize = cacheSize
+}
+
+// resizeCache changes the size of the cache, evicting the least recently used
+// entries if it shrinks.
+func (c *Component) resizeCache(cacheSize int) {
+	c.cache.Resize(cacheSize)
+	c.maxCacheSize = cacheSize
+}
+
+// expireCache removes the cache entries which weren't used since the
+// deadline.
+func (c *Component) expireCache(deadline time.Time) {
+	c.cacheExpired.Add(float64(c.cache.Expire(deadline)))
 }
 
 func (c *Component) addToCache(originalID uint64, lbls labels.Labels, keep bool) {
-	c.cacheMut.Lock()
-	defer c.cacheMut.Unlock()
+	entry := &cacheEntry{}
+	entry.lastUsed.Store(time.Now().UnixNano())
+	if keep {
+		entry.value = &labelAndID{
+			labels: lbls,
+			id:     c.ls.GetOrAddGlobalRefID(lbls),
+		}
+	}
+	c.cache.Add(originalID, entry)
+}
+
+// cacheEntry is the result of relabeling a series, which is nil if the series
+// is dropped.
+type cacheEntry struct {
+	value    *labelAndID
+	lastUsed atomic.Int64 // Unix nanoseconds of the last time the entry was used.
+}
 
-	if !keep {
-		c.cache.Add(originalID, nil)
-		return
+// rulesEqual returns whether a and b are the same relabeling rules.
+func rulesEqual(a, b []*flow_relabel.Config) bool {
+	if len(a) != len(b) {
+		return false
 	}
-	newGlobal := c.ls.GetOrAddGlobalRefID(lbls)
-	c.cache.Add(originalID, &labelAndID{
-		labels: lbls,
-		id:     newGlobal,
-	})
+	for i := range a {
+		x, y := a[i], b[i]
+		if x == nil || y == nil {
+			if x != y {
+				return false
+			}
+			continue
+		}
+		if x.Separator != y.Separator || x.Modulus != y.Modulus || x.TargetLabel != y.TargetLabel ||
+			x.Replacement != y.Replacement || x.Action != y.Action || !slices.Equal(x.SourceLabels, y.SourceLabels) {
+			return false
+		}
+		if (x.Regex.Regexp == nil) != (y.Regex.Regexp == nil) {
+			return false
+		}
+		if x.Regex.Regexp != nil && x.Regex.String() != y.Regex.String() {
+			return false
+		}
+	}
+	return true
 }
 
 // labelAndID stores both the globalrefid for the label and the id itself. We store the id so that it doesn't have
@@ -16,8 +16,13 @@ import (
 	"github.com/grafana/agent/internal/util"
 	"github.com/grafana/river"
 	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
 	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/model/textparse"
 	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/stretchr/testify/require"
@@ -50,6 +55,43 @@ func TestUpdateReset(t *testing.T) {
 	require.True(t, relabeller.cache.Len() == 0)
 }
 
+func TestUpdateKeepsCache(t *testing.T) {
+	relabeller := generateRelabel(t)
+	relabeller.relabel(0, labels.FromStrings("__address__", "first"))
+	relabeller.relabel(0, labels.FromStrings("__address__", "second"))
+	require.Equal(t, 2, relabeller.cache.Len())
+
+	// Identical rules keep the cache.
+	args := Arguments{
+		CacheSize: 100_000,
+		MetricRelabelConfigs: []*flow_relabel.Config{
+			{
+				SourceLabels: []string{"__address__"},
+				Regex:        flow_relabel.Regexp(relabel.MustNewRegexp("(.+)")),
+				TargetLabel:  "new_label",
+				Replacement:  "new_value",
+				Action:       "replace",
+			},
+		},
+	}
+	require.NoError(t, relabeller.Update(args))
+	require.Equal(t, 2, relabeller.cache.Len())
+
+	// Shrinking the cache only evicts the least recently used entries.
+	args.CacheSize = 1
+	require.NoError(t, relabeller.Update(args))
+	require.Equal(t, 1, relabeller.cache.Len())
+	_, found := relabeller.getFromCache(relabeller.ls.GetOrAddGlobalRefID(labels.FromStrings("__address__", "second")))
+	require.True(t, found)
+
+	// Changing a rule clears the cache.
+	changed := *args.MetricRelabelConfigs[0]
+	changed.Replacement = "other_value"
+	args.MetricRelabelConfigs = []*flow_relabel.Config{&changed}
+	require.NoError(t, relabeller.Update(args))
+	require.Equal(t, 0, relabeller.cache.Len())
+}
+
 func TestValidator(t *testing.T) {
 	args := Arguments{CacheSize: 0}
 	err := args.Validate()
@@ -111,6 +153,38 @@ func TestLRUNaN(t *testing.T) {
 	require.True(t, relabeller.cache.Len() == 0)
 }
 
+func TestCacheTTL(t *testing.T) {
+	relabeller := generateRelabel(t)
+	require.NoError(t, relabeller.Update(Arguments{
+		CacheSize: 100_000,
+		CacheTTL:  time.Minute,
+	}))
+
+	var (
+		used   = labels.FromStrings("__address__", "used")
+		unused = labels.FromStrings("__address__", "unused")
+	)
+	relabeller.relabel(0, used)
+	relabeller.relabel(0, unused)
+	require.Equal(t, 2, relabeller.cache.Len())
+
+	// Entries used since the deadline are kept.
+	deadline := time.Now()
+	relabeller.relabel(0, used)
+	relabeller.expireCache(deadline)
+	require.Equal(t, 1, relabeller.cache.Len())
+	_, found := relabeller.getFromCache(relabeller.ls.GetOrAddGlobalRefID(used))
+	require.True(t, found)
+
+	relabeller.expireCache(time.Now().Add(time.Minute))
+	require.Equal(t, 0, relabeller.cache.Len())
+}
+
+func TestValidatorCacheTTL(t *testing.T) {
+	args := Arguments{CacheSize: 1, CacheTTL: -time.Second}
+	require.Error(t, args.Validate())
+}
+
 func BenchmarkCache(b *testing.B) {
 	ls := labelstore.New(nil, prom.DefaultRegisterer)
 	fanout := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
@@ -147,7 +221,101 @@ func BenchmarkCache(b *testing.B) {
 	app.Commit()
 }
 
-func generateRelabel(t *testing.T) *Component {
+func TestTransactionSharesDecision(t *testing.T) {
+	relabeller := generateRelabel(t)
+	lbls := labels.FromStrings("__address__", "localhost")
+
+	// The sample, exemplar, metadata, and histogram of a series are relabeled
+	// once per transaction.
+	app := relabeller.receiver.Appender(context.Background())
+	appendAllSignals(t, app, lbls)
+	require.NoError(t, app.Commit())
+	require.Equal(t, 1.0, counterTotal(relabeller.cacheMisses))
+	require.Equal(t, 0.0, counterTotal(relabeller.cacheHits))
+
+	// The next transaction uses the cache.
+	app = relabeller.receiver.Appender(context.Background())
+	appendAllSignals(t, app, lbls)
+	require.NoError(t, app.Commit())
+	require.Equal(t, 1.0, counterTotal(relabeller.cacheMisses))
+	require.Equal(t, 1.0, counterTotal(relabeller.cacheHits))
+
+	// Stale markers still remove the series from the cache.
+	app = relabeller.receiver.Appender(context.Background())
+	appendAllSignals(t, app, lbls)
+	_, err := app.Append(0, lbls, time.Now().UnixMilli(), math.Float64frombits(value.StaleNaN))
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.Equal(t, 0, relabeller.cache.Len())
+}
+
+// counterTotal returns the sum of the counters of all cache shards in v.
+func counterTotal(v *prom.CounterVec) float64 {
+	var total float64
+	for i := 0; i < maxCacheShards; i++ {
+		total += testutil.ToFloat64(v.WithLabelValues(strconv.Itoa(i)))
+	}
+	return total
+}
+
+// appendAllSignals appends a sample, an exemplar, metadata, and a histogram for
+// the series lbls to app.
+func appendAllSignals(t testing.TB, app storage.Appender, lbls labels.Labels) {
+	ts := time.Now().UnixMilli()
+	_, err := app.Append(0, lbls, ts, 1)
+	require.NoError(t, err)
+	_, err = app.AppendExemplar(0, lbls, exemplar.Exemplar{Value: 1, Ts: ts})
+	require.NoError(t, err)
+	_, err = app.UpdateMetadata(0, lbls, metadata.Metadata{Type: textparse.MetricTypeCounter})
+	require.NoError(t, err)
+	_, err = app.AppendHistogram(0, lbls, ts, &histogram.Histogram{Count: 1}, nil)
+	require.NoError(t, err)
+}
+
+// BenchmarkAllSignals compares relabeling every signal of a series separately
+// with sharing the decision across the signals of a transaction, with
+// concurrent transactions contending on the cache. per_transaction also
+// includes the cost of forwarding the signals.
+func BenchmarkAllSignals(b *testing.B) {
+	const seriesCount = 100
+
+	series := make([]labels.Labels, seriesCount)
+	for i := range series {
+		series[i] = labels.FromStrings("__address__", "localhost", "series", strconv.Itoa(i))
+	}
+
+	b.Run("per_signal", func(b *testing.B) {
+		relabeller := generateRelabel(b)
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				for _, lbls := range series {
+					for signal := 0; signal < 4; signal++ {
+						relabeller.relabel(0, lbls)
+					}
+				}
+			}
+		})
+	})
+
+	b.Run("per_transaction", func(b *testing.B) {
+		relabeller := generateRelabel(b)
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				app := relabeller.receiver.Appender(context.Background())
+				for _, lbls := range series {
+					appendAllSignals(b, app, lbls)
+				}
+				_ = app.Commit()
+			}
+		})
+	})
+}
+
+func generateRelabel(t testing.TB) *Component {
 	ls := labelstore.New(nil, prom.DefaultRegisterer)
 	fanout := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
 		require.True(t, l.Has("new_label"))
@@ -225,3 +393,50 @@ func TestRuleGetter(t *testing.T) {
 	require.Equal(t, gotUpdated[0].SourceLabels, gotOriginal[0].SourceLabels)
 	require.Equal(t, gotUpdated[0].Regex, gotOriginal[0].Regex)
 }
+
+func TestDebugSamples(t *testing.T) {
+	relabeller := generateRelabel(t)
+	require.Nil(t, relabeller.DebugInfo())
+
+	args := Arguments{
+		CacheSize: 100_000,
+		Debug:     &DebugArguments{SampleSize: 2},
+		MetricRelabelConfigs: []*flow_relabel.Config{
+			{
+				SourceLabels: []string{"__address__"},
+				Regex:        flow_relabel.Regexp(relabel.MustNewRegexp("(.+)")),
+				TargetLabel:  "new_label",
+				Replacement:  "new_value",
+				Action:       "replace",
+			},
+			{
+				SourceLabels: []string{"__name__"},
+				Regex:        flow_relabel.Regexp(relabel.MustNewRegexp("dropped")),
+				Action:       "drop",
+			},
+		},
+	}
+	require.NoError(t, relabeller.Update(args))
+
+	relabeller.relabel(0, labels.FromStrings("__address__", "localhost", "__name__", "kept"))
+	relabeller.relabel(0, labels.FromStrings("__address__", "localhost", "__name__", "dropped"))
+
+	samples := relabeller.DebugInfo().(debugInfo).Samples
+	require.Len(t, samples, 2)
+
+	require.False(t, samples[0].Dropped)
+	require.Equal(t, -1, samples[0].DroppedByRule)
+	require.Equal(t, []int{0}, samples[0].ModifiedByRules)
+	require.Equal(t, `{__address__="localhost", __name__="kept", new_label="new_value"}`, samples[0].Output)
+
+	require.True(t, samples[1].Dropped)
+	require.Equal(t, 1, samples[1].DroppedByRule)
+	require.Empty(t, samples[1].Output)
+
+	// Only the most recent samples are kept.
+	relabeller.relabel(0, labels.FromStrings("__address__", "other", "__name__", "kept"))
+	samples = relabeller.DebugInfo().(debugInfo).Samples
+	require.Len(t, samples, 2)
+	require.Equal(t, `{__address__="localhost", __name__="dropped"}`, samples[0].Input)
+	require.Equal(t, `{__address__="other", __name__="kept"}`, samples[1].Input)
+}
@@ -0,0 +1,73 @@
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/agent/internal/component/common/dlq"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/prometheus/storage/remote"
+)
+
+// Handler implements http_service.Component. It serves the API of the
+// dead-letter queue under /dead_letter/.
+func (c *Component) Handler() http.Handler {
+	return c.deadLetters.Handler(c.redrive, decodeWriteRequest)
+}
+
+// isPermanentError reports whether a request which failed with the given
+// status will never be accepted by retrying it. Requests which failed with
+// connection-level errors, 429s, or 5xxs aren't permanent errors.
+func isPermanentError(status int) bool {
+	return status/100 == 4 && status != http.StatusTooManyRequests
+}
+
+// storeDeadLetter stores a request which an endpoint rejected with a
+// permanent error in the dead-letter queue.
+func (c *Component) storeDeadLetter(e *relayEndpoint, resp relayResponse) {
+	if !isPermanentError(resp.Status) || !c.deadLetters.Enabled() {
+		return
+	}
+
+	var entries int
+	if req, err := remote.DecodeWriteRequest(bytes.NewReader(resp.Payload)); err == nil {
+		for _, ts := range req.Timeseries {
+			entries += len(ts.Samples) + len(ts.Histograms)
+		}
+	}
+
+	stored, err := c.deadLetters.Add(dlq.Record{
+		Endpoint: e.name,
+		Status:   resp.Status,
+		Error:    resp.Err().Error(),
+		Entries:  entries,
+		Payload:  resp.Payload,
+	})
+	switch {
+	case err != nil:
+		level.Error(c.log).Log("msg", "failed to store request in dead-letter queue", "url", e.url, "error", err)
+	case stored:
+		level.Info(c.log).Log("msg", "stored request in dead-letter queue", "url", e.url, "samples", entries)
+	}
+}
+
+// redrive sends a request stored in the dead-letter queue to its endpoint
+// again.
+func (c *Component) redrive(ctx context.Context, rec dlq.Record) error {
+	if err := c.relay.Redrive(ctx, rec.Endpoint, rec.Payload); err != nil {
+		return fmt.Errorf("redriving record %d to %s: %w", rec.ID, rec.Endpoint, err)
+	}
+	return nil
+}
+
+// decodeWriteRequest decodes a request stored in the dead-letter queue so
+// that its series can be inspected.
+func decodeWriteRequest(payload []byte) (any, error) {
+	req, err := remote.DecodeWriteRequest(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	return req.Timeseries, nil
+}
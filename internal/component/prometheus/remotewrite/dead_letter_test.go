@@ -0,0 +1,106 @@
+package remotewrite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestDeadLetterQueue(t *testing.T) {
+	// The endpoint rejects the first request and accepts the others.
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Inc() == 1 {
+			http.Error(w, "out of order sample", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := fmt.Sprintf(`
+		endpoint {
+			name           = "default"
+			url            = "%s/api/v1/write"
+			remote_timeout = "100ms"
+
+			queue_config {
+				batch_send_deadline = "100ms"
+			}
+		}
+
+		dead_letter_queue {
+			max_size = "1MiB"
+		}
+	`, srv.URL)
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+
+	ls := labelstore.New(nil, prometheus.NewRegistry())
+	c, err := New(component.Options{
+		ID:            "prometheus.remote_write.test",
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		DataPath:      t.TempDir(),
+		OnStateChange: func(e component.Exports) {},
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, args)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { require.NoError(t, c.Run(ctx)) }()
+
+	// Use a future timestamp since remote_write ignores samples which are
+	// earlier than the time when it started.
+	ts := time.Now().Add(time.Minute).UnixMilli()
+	app := c.receiver.Appender(context.Background())
+	_, err = app.Append(0, labels.FromStrings("__name__", "up", "job", "agent"), ts, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	// The rejected request is stored instead of being dropped.
+	require.Eventually(t, func() bool {
+		return len(c.deadLetters.List()) == 1
+	}, time.Minute, 10*time.Millisecond)
+	rec := c.deadLetters.List()[0]
+	require.Equal(t, "default", rec.Endpoint)
+	require.Equal(t, http.StatusBadRequest, rec.Status)
+	require.Equal(t, "server returned HTTP status 400 Bad Request: out of order sample", rec.Error)
+	require.Equal(t, 1, rec.Entries)
+
+	handler := httptest.NewServer(c.Handler())
+	defer handler.Close()
+
+	resp, err := http.Get(fmt.Sprintf("%s/dead_letter/records/%d", handler.URL, rec.ID))
+	require.NoError(t, err)
+	var details struct {
+		Payload []prompb.TimeSeries `json:"payload"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&details))
+	resp.Body.Close()
+	require.Len(t, details.Payload, 1)
+	require.Equal(t, []prompb.Sample{{Timestamp: ts, Value: 1}}, details.Payload[0].Samples)
+
+	resp, err = http.Post(fmt.Sprintf("%s/dead_letter/records/%d/redrive", handler.URL, rec.ID), "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Empty(t, c.deadLetters.List())
+	require.Equal(t, int64(2), requests.Load())
+}
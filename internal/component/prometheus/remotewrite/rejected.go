@@ -0,0 +1,210 @@
+package remotewrite
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage/remote"
+)
+
+// maxRejectedSamples is the number of most recently rejected requests kept
+// for debug information.
+const maxRejectedSamples = 20
+
+// Categories of requests rejected by an endpoint.
+const (
+	categoryOutOfOrder    = "out_of_order"
+	categoryDuplicate     = "duplicate_sample"
+	categoryOutOfBounds   = "out_of_bounds"
+	categoryInvalidLabels = "invalid_labels"
+	categorySeriesLimit   = "series_limit"
+	categoryExemplar      = "invalid_exemplar"
+	categoryHistogram     = "invalid_histogram"
+	categoryOther         = "other"
+)
+
+// categoryPatterns maps substrings of lowercased error messages to the
+// category of the rejection. They cover the messages of Prometheus, Cortex,
+// Mimir, and Thanos receivers; the first matching pattern wins.
+var categoryPatterns = []struct {
+	substr   string
+	category string
+}{
+	{"exemplar", categoryExemplar},
+	{"histogram", categoryHistogram},
+	{"out of order", categoryOutOfOrder},
+	{"out-of-order", categoryOutOfOrder},
+	{"duplicate sample", categoryDuplicate},
+	{"duplicate timestamp", categoryDuplicate},
+	{"duplicate label", categoryInvalidLabels},
+	{"out of bounds", categoryOutOfBounds},
+	{"too old", categoryOutOfBounds},
+	{"too far in the future", categoryOutOfBounds},
+	{"series limit", categorySeriesLimit},
+	{"max series", categorySeriesLimit},
+	{"per-user series", categorySeriesLimit},
+	{"per-metric series", categorySeriesLimit},
+	{"metric name", categoryInvalidLabels},
+	{"label", categoryInvalidLabels},
+}
+
+var (
+	// seriesRegexp matches a series in the text exposition format, such as
+	// up{job="agent"} or {__name__="up", job="agent"}.
+	seriesRegexp    = regexp.MustCompile(`[a-zA-Z_:][a-zA-Z0-9_:]*\{[^{}]*\}|\{[^{}]*\}`)
+	timestampRegexp = regexp.MustCompile(`timestamp:? ([0-9][0-9TZ:.+\-]*)`)
+)
+
+// rejectedRequest describes a request which was rejected by an endpoint.
+type rejectedRequest struct {
+	Time      time.Time         `river:"time,attr"`
+	URL       string            `river:"url,attr"`
+	Category  string            `river:"category,attr"`
+	Error     string            `river:"error,attr"`
+	Metric    string            `river:"metric,attr,optional"`
+	Labels    map[string]string `river:"labels,attr,optional"`
+	Timestamp time.Time         `river:"timestamp,attr,optional"`
+}
+
+// debugInfo is the debug information of the component.
+type debugInfo struct {
+	RejectedRequests []rejectedRequest `river:"rejected_request,block,optional"`
+}
+
+// rejectionTracker counts requests rejected by endpoints and keeps a sample
+// of the most recent ones, so that producers of bad data can be identified.
+type rejectionTracker struct {
+	rejected *promclient.CounterVec
+
+	mut     sync.Mutex
+	samples []rejectedRequest // Ring buffer of samples
+	next    int               // Index of samples to write the next sample to
+}
+
+func newRejectionTracker() *rejectionTracker {
+	return &rejectionTracker{
+		rejected: promclient.NewCounterVec(promclient.CounterOpts{
+			Name: "prometheus_remote_write_rejected_requests_total",
+			Help: "Total number of requests rejected by the endpoint with HTTP status 400, by category of the error.",
+		}, []string{urlLabel, "category"}),
+	}
+}
+
+// Observe records the response of the endpoint at url to a request if the
+// endpoint rejected it with HTTP status 400.
+func (t *rejectionTracker) Observe(url string, resp relayResponse) {
+	if resp.Status != http.StatusBadRequest {
+		return
+	}
+
+	req := parseRejection(resp.Err().Error())
+	if req.Labels == nil {
+		// The endpoint didn't name the offending series, which is still known
+		// if the request only had one.
+		req.Metric, req.Labels, req.Timestamp = payloadSeries(resp.Payload, req.Timestamp)
+	}
+	req.Time = time.Now()
+	req.URL = url
+	t.rejected.WithLabelValues(url, req.Category).Inc()
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if len(t.samples) < maxRejectedSamples {
+		t.samples = append(t.samples, req)
+	} else {
+		t.samples[t.next] = req
+	}
+	t.next = (t.next + 1) % maxRejectedSamples
+}
+
+// Samples returns the most recently rejected requests, oldest first.
+func (t *rejectionTracker) Samples() []rejectedRequest {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if len(t.samples) < maxRejectedSamples {
+		return append([]rejectedRequest(nil), t.samples...)
+	}
+	return append(append([]rejectedRequest(nil), t.samples[t.next:]...), t.samples[:t.next]...)
+}
+
+// parseRejection extracts the category of the error and, if the endpoint
+// included it in the error, the offending series and timestamp.
+func parseRejection(err string) rejectedRequest {
+	req := rejectedRequest{Error: err, Category: categoryOther}
+
+	// Series are removed before matching categories so that label names and
+	// values don't affect the category.
+	lower := strings.ToLower(seriesRegexp.ReplaceAllString(err, ""))
+	for _, p := range categoryPatterns {
+		if strings.Contains(lower, p.substr) {
+			req.Category = p.category
+			break
+		}
+	}
+
+	for _, s := range seriesRegexp.FindAllString(err, -1) {
+		lbls, perr := parser.ParseMetric(s)
+		if perr != nil || lbls.IsEmpty() {
+			continue
+		}
+		req.Metric = lbls.Get(labels.MetricName)
+		req.Labels = lbls.Map()
+		break
+	}
+
+	if m := timestampRegexp.FindStringSubmatch(err); m != nil {
+		req.Timestamp = parseTimestamp(strings.TrimRight(m[1], ".,"))
+	}
+	return req
+}
+
+// payloadSeries returns the metric name and labels of the series of a remote
+// write request if it has exactly one, along with the timestamp of its sample
+// if ts is zero and it has exactly one.
+func payloadSeries(payload []byte, ts time.Time) (string, map[string]string, time.Time) {
+	wr, err := remote.DecodeWriteRequest(bytes.NewReader(payload))
+	if err != nil || len(wr.Timeseries) != 1 {
+		return "", nil, ts
+	}
+
+	series := wr.Timeseries[0]
+	lbls := make(map[string]string, len(series.Labels))
+	for _, l := range series.Labels {
+		lbls[l.Name] = l.Value
+	}
+	if ts.IsZero() {
+		switch {
+		case len(series.Samples) == 1 && len(series.Histograms) == 0:
+			ts = time.UnixMilli(series.Samples[0].Timestamp).UTC()
+		case len(series.Histograms) == 1 && len(series.Samples) == 0:
+			ts = time.UnixMilli(series.Histograms[0].Timestamp).UTC()
+		}
+	}
+	return lbls[labels.MetricName], lbls, ts
+}
+
+// parseTimestamp parses a timestamp either in RFC 3339 format or in seconds
+// since the Unix epoch. The zero time is returned if ts can't be parsed.
+func parseTimestamp(ts string) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+		return t
+	}
+	if secs, err := strconv.ParseFloat(ts, 64); err == nil {
+		return time.UnixMilli(int64(secs * 1000)).UTC()
+	}
+	return time.Time{}
+}
+
+// DebugInfo implements component.DebugComponent.
+func (c *Component) DebugInfo() interface{} {
+	return debugInfo{RejectedRequests: c.rejections.Samples()}
+}
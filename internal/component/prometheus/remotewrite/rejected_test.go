@@ -0,0 +1,155 @@
+package remotewrite
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRejection(t *testing.T) {
+	tt := []struct {
+		name   string
+		err    string
+		expect rejectedRequest
+	}{
+		{
+			name: "mimir out of order",
+			err:  `server returned HTTP status 400 Bad Request: failed pushing to ingester: user=anonymous: the sample has been rejected because another sample with a more recent timestamp has already been ingested and out-of-order samples are not allowed (err-mimir-sample-out-of-order). The affected sample has timestamp 2024-01-02T03:04:05Z and is from series {__name__="up", job="agent"}`,
+			expect: rejectedRequest{
+				Category:  categoryOutOfOrder,
+				Metric:    "up",
+				Labels:    map[string]string{"__name__": "up", "job": "agent"},
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		},
+		{
+			name: "cortex duplicate sample",
+			err:  `server returned HTTP status 400 Bad Request: user=fake: duplicate sample for timestamp; last timestamp: 1700000000, incoming timestamp: 1700000000 for series node_load1{instance="a"}`,
+			expect: rejectedRequest{
+				Category:  categoryDuplicate,
+				Metric:    "node_load1",
+				Labels:    map[string]string{"__name__": "node_load1", "instance": "a"},
+				Timestamp: time.Unix(1700000000, 0).UTC(),
+			},
+		},
+		{
+			name: "invalid label name",
+			err:  `server returned HTTP status 400 Bad Request: received a series with an invalid label: '0abc' series: 'up{0abc="x"}'`,
+			expect: rejectedRequest{
+				Category: categoryInvalidLabels,
+			},
+		},
+		{
+			name: "series labels don't affect the category",
+			err:  `server returned HTTP status 400 Bad Request: out of bounds: series http_histogram_bucket{le="1"}`,
+			expect: rejectedRequest{
+				Category: categoryOutOfBounds,
+				Metric:   "http_histogram_bucket",
+				Labels:   map[string]string{"__name__": "http_histogram_bucket", "le": "1"},
+			},
+		},
+		{
+			name: "unknown error",
+			err:  `server returned HTTP status 400 Bad Request: snappy: corrupt input`,
+			expect: rejectedRequest{
+				Category: categoryOther,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect.Error = tc.err
+			require.Equal(t, tc.expect, parseRejection(tc.err))
+		})
+	}
+}
+
+func TestRejectionTracker(t *testing.T) {
+	tracker := newRejectionTracker()
+	url := "http://mimir/api/v1/push"
+
+	// Only requests rejected with HTTP status 400 are tracked.
+	tracker.Observe(url, relayResponse{Status: http.StatusBadRequest, Message: "out of order sample"})
+	tracker.Observe(url, relayResponse{Status: http.StatusUnauthorized, Message: "invalid token"})
+	tracker.Observe(url, relayResponse{Status: http.StatusNoContent})
+
+	require.Equal(t, 1.0, testutil.ToFloat64(tracker.rejected.WithLabelValues(url, categoryOutOfOrder)))
+	samples := tracker.Samples()
+	require.Len(t, samples, 1)
+	require.Equal(t, url, samples[0].URL)
+	require.Equal(t, "server returned HTTP status 400 Bad Request: out of order sample", samples[0].Error)
+
+	// Only the most recent requests are kept.
+	for i := 0; i < maxRejectedSamples+5; i++ {
+		tracker.Observe("url", relayResponse{Status: http.StatusBadRequest, Message: fmt.Sprint(i)})
+	}
+	samples = tracker.Samples()
+	require.Len(t, samples, maxRejectedSamples)
+	require.Equal(t, "server returned HTTP status 400 Bad Request: 5", samples[0].Error)
+	require.Equal(t, fmt.Sprintf("server returned HTTP status 400 Bad Request: %d", maxRejectedSamples+4), samples[maxRejectedSamples-1].Error)
+}
+
+func TestRejectionTracker_PayloadSeries(t *testing.T) {
+	series := prompb.TimeSeries{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "agent"}},
+		Samples: []prompb.Sample{{Timestamp: 1700000000000, Value: 1}},
+	}
+
+	t.Run("single series", func(t *testing.T) {
+		tracker := newRejectionTracker()
+		tracker.Observe("url", relayResponse{
+			Status:  http.StatusBadRequest,
+			Message: "out of order sample",
+			Payload: encodeWriteRequest(t, series),
+		})
+		samples := tracker.Samples()
+		require.Len(t, samples, 1)
+		require.Equal(t, "up", samples[0].Metric)
+		require.Equal(t, map[string]string{"__name__": "up", "job": "agent"}, samples[0].Labels)
+		require.Equal(t, time.Unix(1700000000, 0).UTC(), samples[0].Timestamp)
+	})
+
+	t.Run("several series", func(t *testing.T) {
+		other := prompb.TimeSeries{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "down"}},
+			Samples: []prompb.Sample{{Timestamp: 1700000000000, Value: 1}},
+		}
+		tracker := newRejectionTracker()
+		tracker.Observe("url", relayResponse{
+			Status:  http.StatusBadRequest,
+			Message: "out of order sample",
+			Payload: encodeWriteRequest(t, series, other),
+		})
+		samples := tracker.Samples()
+		require.Len(t, samples, 1)
+		require.Empty(t, samples[0].Metric)
+		require.Nil(t, samples[0].Labels)
+		require.True(t, samples[0].Timestamp.IsZero())
+	})
+
+	t.Run("series in the error", func(t *testing.T) {
+		tracker := newRejectionTracker()
+		tracker.Observe("url", relayResponse{
+			Status:  http.StatusBadRequest,
+			Message: `out of order sample for series node_load1{instance="a"}`,
+			Payload: encodeWriteRequest(t, series),
+		})
+		samples := tracker.Samples()
+		require.Len(t, samples, 1)
+		require.Equal(t, "node_load1", samples[0].Metric)
+		require.True(t, samples[0].Timestamp.IsZero())
+	})
+}
+
+func encodeWriteRequest(t *testing.T, series ...prompb.TimeSeries) []byte {
+	buf, err := (&prompb.WriteRequest{Timeseries: series}).Marshal()
+	require.NoError(t, err)
+	return snappy.Encode(nil, buf)
+}
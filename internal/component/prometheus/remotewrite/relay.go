@@ -0,0 +1,430 @@
+package remotewrite
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	common "github.com/prometheus/common/config"
+	"github.com/prometheus/common/sigv4"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/storage/remote/azuread"
+	"gopkg.in/yaml.v2"
+)
+
+// maxErrMsgLen is the maximum length of the error messages of endpoints
+// which are read, like in the remote storage.
+const maxErrMsgLen = 1024
+
+// relay forwards the requests of the remote storage to the endpoints, so that
+// the component sees the status, error message, and payload of every
+// request. The remote storage creates its own HTTP clients and doesn't expose
+// a hook into its requests, so it's configured to send requests to the relay
+// on a loopback address, which sends them on with a client built from the
+// configuration of the endpoint.
+type relay struct {
+	srv *http.Server
+	url string // Base URL of the relay.
+
+	// onResponse is called with every response of an endpoint to a request
+	// relayed by the remote storage.
+	onResponse func(e *relayEndpoint, resp relayResponse)
+
+	mut       sync.RWMutex
+	endpoints map[string]*relayEndpoint // By path of their relay URL.
+	urls      map[string]string         // Endpoint URLs by relay URL.
+}
+
+// relayEndpoint is an endpoint requests are relayed to.
+type relayEndpoint struct {
+	name   string // Name of the queue of the endpoint.
+	url    string // Redacted URL of the endpoint.
+	client *http.Client
+	target string
+}
+
+// relayResponse is the outcome of a request sent to an endpoint.
+type relayResponse struct {
+	Status  int
+	Message string // First line of the response body.
+	Payload []byte // Request body, a snappy-compressed remote write request.
+}
+
+// Err returns the error of the response as reported by the remote storage,
+// or nil if the request succeeded.
+func (r relayResponse) Err() error {
+	if r.Status/100 == 2 {
+		return nil
+	}
+	return fmt.Errorf("server returned HTTP status %d %s: %s", r.Status, http.StatusText(r.Status), r.Message)
+}
+
+// newRelay starts a relay listening on a loopback address.
+func newRelay(onResponse func(e *relayEndpoint, resp relayResponse)) (*relay, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("starting remote_write relay: %w", err)
+	}
+
+	r := &relay{
+		url:        "http://" + lis.Addr().String(),
+		onResponse: onResponse,
+		endpoints:  make(map[string]*relayEndpoint),
+		urls:       make(map[string]string),
+	}
+	r.srv = &http.Server{Handler: r}
+	go func() { _ = r.srv.Serve(lis) }()
+	return r, nil
+}
+
+// Close stops the relay.
+func (r *relay) Close() error {
+	return r.srv.Close()
+}
+
+// ApplyConfig calls apply with a copy of cfg whose remote write configs send
+// requests to the relay. Endpoints of the previous config are relayed until
+// apply returns, so that queues which are stopped by apply can flush.
+func (r *relay) ApplyConfig(cfg *config.Config, apply func(*config.Config) error) error {
+	var (
+		relayed   = *cfg
+		endpoints = make(map[string]*relayEndpoint, len(cfg.RemoteWriteConfigs))
+		urls      = make(map[string]string, len(cfg.RemoteWriteConfigs))
+	)
+	relayed.RemoteWriteConfigs = make([]*config.RemoteWriteConfig, 0, len(cfg.RemoteWriteConfigs))
+
+	for _, rwConf := range cfg.RemoteWriteConfigs {
+		// Queues are named like the remote storage names them, so that
+		// their metrics keep the same remote_name label.
+		hash, err := toHash(rwConf)
+		if err != nil {
+			return err
+		}
+		name := rwConf.Name
+		if name == "" {
+			name = hash[:6]
+		}
+
+		client, err := newEndpointClient(rwConf)
+		if err != nil {
+			return err
+		}
+		relayURL, err := url.Parse(r.url + "/" + hash)
+		if err != nil {
+			return err
+		}
+		endpoints[hash] = &relayEndpoint{
+			name:   name,
+			url:    rwConf.URL.Redacted(),
+			client: client,
+			target: rwConf.URL.String(),
+		}
+		urls[relayURL.String()] = rwConf.URL.Redacted()
+
+		// Authentication, TLS, and headers are handled by the client of the
+		// relay.
+		relayedConf := *rwConf
+		relayedConf.Name = name
+		relayedConf.URL = &common.URL{URL: relayURL}
+		relayedConf.HTTPClientConfig = common.DefaultHTTPClientConfig
+		relayedConf.Headers = nil
+		relayedConf.SigV4Config = nil
+		relayedConf.AzureADConfig = nil
+		relayed.RemoteWriteConfigs = append(relayed.RemoteWriteConfigs, &relayedConf)
+	}
+
+	r.mut.Lock()
+	prevEndpoints, prevURLs := r.endpoints, r.urls
+	r.endpoints, r.urls = merge(prevEndpoints, endpoints), merge(prevURLs, urls)
+	r.mut.Unlock()
+
+	err := apply(&relayed)
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if err != nil {
+		r.endpoints, r.urls = prevEndpoints, prevURLs
+		return err
+	}
+	r.endpoints, r.urls = endpoints, urls
+	return nil
+}
+
+func merge[V any](a, b map[string]V) map[string]V {
+	res := make(map[string]V, len(a)+len(b))
+	for k, v := range a {
+		res[k] = v
+	}
+	for k, v := range b {
+		res[k] = v
+	}
+	return res
+}
+
+// toHash returns the hash the remote storage identifies a remote write config
+// with.
+func toHash(rwConf *config.RemoteWriteConfig) (string, error) {
+	bb, err := yaml.Marshal(rwConf)
+	if err != nil {
+		return "", err
+	}
+	hash := md5.Sum(bb)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// newEndpointClient returns a client for the endpoint of rwConf, built like
+// the remote storage builds its clients.
+func newEndpointClient(rwConf *config.RemoteWriteConfig) (*http.Client, error) {
+	client, err := common.NewClientFromConfig(rwConf.HTTPClientConfig, "remote_storage_write_client")
+	if err != nil {
+		return nil, err
+	}
+	t := client.Transport
+
+	if len(rwConf.Headers) > 0 {
+		t = headersRoundTripper{headers: rwConf.Headers, next: t}
+	}
+	if rwConf.SigV4Config != nil {
+		if t, err = sigv4.NewSigV4RoundTripper(rwConf.SigV4Config, t); err != nil {
+			return nil, err
+		}
+	}
+	if rwConf.AzureADConfig != nil {
+		if t, err = azuread.NewAzureADRoundTripper(rwConf.AzureADConfig, t); err != nil {
+			return nil, err
+		}
+	}
+
+	client.Transport = t
+	return client, nil
+}
+
+// headersRoundTripper sets headers on every request.
+type headersRoundTripper struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+func (t headersRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// ServeHTTP relays a request of the remote storage to its endpoint, and
+// returns the response of the endpoint.
+func (r *relay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mut.RLock()
+	e := r.endpoints[strings.TrimPrefix(req.URL.Path, "/")]
+	r.mut.RUnlock()
+	if e == nil {
+		// The remote storage retries requests which fail with a 5xx, so that
+		// no data is dropped.
+		http.Error(w, "endpoint isn't configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	payload, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := e.send(req.Context(), payload, req.Header)
+	if err != nil {
+		// Connection errors are retried, like when the remote storage sends
+		// requests itself.
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if v := resp.header.Get("Retry-After"); v != "" {
+		w.Header().Set("Retry-After", v)
+	}
+	w.WriteHeader(resp.Status)
+	_, _ = io.WriteString(w, resp.Message)
+
+	if r.onResponse != nil {
+		r.onResponse(e, resp.relayResponse)
+	}
+}
+
+// Redrive sends the payload of a request to the endpoint of the queue with
+// the given name again.
+func (r *relay) Redrive(ctx context.Context, name string, payload []byte) error {
+	r.mut.RLock()
+	var e *relayEndpoint
+	for _, candidate := range r.endpoints {
+		if candidate.name == name {
+			e = candidate
+			break
+		}
+	}
+	r.mut.RUnlock()
+	if e == nil {
+		return fmt.Errorf("endpoint %s isn't configured", name)
+	}
+
+	header := http.Header{}
+	header.Set("Content-Encoding", "snappy")
+	header.Set("Content-Type", "application/x-protobuf")
+	header.Set("User-Agent", remote.UserAgent)
+	header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := e.send(ctx, payload, header)
+	if err != nil {
+		return err
+	}
+	return resp.Err()
+}
+
+// EndpointURL returns the redacted URL of the endpoint whose requests are
+// relayed from relayURL, or relayURL if there's none.
+func (r *relay) EndpointURL(relayURL string) string {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+	if u, ok := r.urls[relayURL]; ok {
+		return u
+	}
+	return relayURL
+}
+
+type sentResponse struct {
+	relayResponse
+	header http.Header
+}
+
+// send sends payload to the endpoint with the given request headers.
+func (e *relayEndpoint) send(ctx context.Context, payload []byte, header http.Header) (sentResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.target, bytes.NewReader(payload))
+	if err != nil {
+		return sentResponse{}, err
+	}
+	req.Header = header.Clone()
+	req.Header.Del("Connection")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return sentResponse{}, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	res := sentResponse{
+		relayResponse: relayResponse{Status: resp.StatusCode, Payload: payload},
+		header:        resp.Header,
+	}
+	if resp.StatusCode/100 != 2 {
+		scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxErrMsgLen))
+		if scanner.Scan() {
+			res.Message = scanner.Text()
+		}
+	}
+	return res, nil
+}
+
+// relayRegisterer registers collectors whose metrics report the URL of the
+// endpoint in their url label instead of the URL of the relay.
+type relayRegisterer struct {
+	next  promclient.Registerer
+	relay *relay
+}
+
+var _ promclient.Registerer = relayRegisterer{}
+
+func (r relayRegisterer) Register(c promclient.Collector) error {
+	err := r.next.Register(&relayCollector{Collector: c, relay: r.relay})
+
+	// The remote storage reuses existing collectors.
+	var are promclient.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(*relayCollector); ok {
+			are.ExistingCollector = existing.Collector
+		}
+		return are
+	}
+	return err
+}
+
+func (r relayRegisterer) MustRegister(cs ...promclient.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Unregister unregisters c. Collectors are identified by their descriptors,
+// so the collector registered for c is unregistered.
+func (r relayRegisterer) Unregister(c promclient.Collector) bool {
+	return r.next.Unregister(c)
+}
+
+type relayCollector struct {
+	promclient.Collector
+	relay *relay
+}
+
+func (c *relayCollector) Collect(ch chan<- promclient.Metric) {
+	metrics := make(chan promclient.Metric)
+	go func() {
+		c.Collector.Collect(metrics)
+		close(metrics)
+	}()
+	for m := range metrics {
+		ch <- relayMetric{Metric: m, relay: c.relay}
+	}
+}
+
+type relayMetric struct {
+	promclient.Metric
+	relay *relay
+}
+
+func (m relayMetric) Write(out *dto.Metric) error {
+	if err := m.Metric.Write(out); err != nil {
+		return err
+	}
+	for _, l := range out.Label {
+		if l.GetName() == urlLabel {
+			u := m.relay.EndpointURL(l.GetValue())
+			l.Value = &u
+		}
+	}
+	return nil
+}
+
+// relayLogger replaces the URL of the relay with the URL of the endpoint in
+// the log lines of the remote storage.
+type relayLogger struct {
+	next  log.Logger
+	relay *relay
+}
+
+var _ log.Logger = relayLogger{}
+
+func (l relayLogger) Log(keyvals ...interface{}) error {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if s, ok := keyvals[i+1].(string); ok && keyvals[i] == urlLabel {
+			keyvals = append([]interface{}(nil), keyvals...)
+			keyvals[i+1] = l.relay.EndpointURL(s)
+		}
+	}
+	return l.next.Log(keyvals...)
+}
@@ -12,12 +12,17 @@ import (
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/internal/agentseed"
 	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/dlq"
+	"github.com/grafana/agent/internal/component/common/slo"
 	"github.com/grafana/agent/internal/component/prometheus"
 	"github.com/grafana/agent/internal/featuregate"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	http_service "github.com/grafana/agent/internal/service/http"
 	"github.com/grafana/agent/internal/service/labelstore"
 	"github.com/grafana/agent/internal/static/metrics/wal"
 	"github.com/grafana/agent/internal/useragent"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/prometheus/prometheus/model/labels"
@@ -56,6 +61,10 @@ type Component struct {
 	walStore    *wal.Storage
 	remoteStore *remote.Storage
 	storage     storage.Storage
+	slo         *slo.Tracker
+	rejections  *rejectionTracker
+	relay       *relay
+	deadLetters *dlq.Queue
 	exited      atomic.Bool
 
 	mut sync.RWMutex
@@ -81,8 +90,23 @@ func New(o component.Options, c Arguments) (*Component, error) {
 		return nil, err
 	}
 
-	remoteLogger := log.With(o.Logger, "subcomponent", "rw")
-	remoteStore := remote.NewStorage(remoteLogger, o.Registerer, startTime, o.DataPath, remoteFlushDeadline, nil)
+	// Metrics of the remote storage are also registered to a private registry
+	// which SLO metrics are derived from.
+	remoteReg := promclient.NewRegistry()
+	tracker := slo.NewTracker()
+	if err := o.Registerer.Register(&sloCollector{tracker: tracker, source: remoteReg}); err != nil {
+		return nil, err
+	}
+
+	rejections := newRejectionTracker()
+	if err := o.Registerer.Register(rejections.rejected); err != nil {
+		return nil, err
+	}
+
+	deadLetters := dlq.NewQueue(filepath.Join(o.DataPath, "dead_letter"))
+	if err := o.Registerer.Register(deadLetters); err != nil {
+		return nil, err
+	}
 
 	service, err := o.GetServiceData(labelstore.ServiceName)
 	if err != nil {
@@ -94,9 +118,22 @@ func New(o component.Options, c Arguments) (*Component, error) {
 		log:         o.Logger,
 		opts:        o,
 		walStore:    walStorage,
-		remoteStore: remoteStore,
-		storage:     storage.NewFanout(o.Logger, walStorage, remoteStore),
+		slo:         tracker,
+		rejections:  rejections,
+		deadLetters: deadLetters,
 	}
+
+	// Requests of the remote storage go through a relay, which reports the
+	// outcome of every request. Metrics and logs of the remote storage report
+	// the URL of the endpoints rather than the URL of the relay.
+	res.relay, err = newRelay(res.observeResponse)
+	if err != nil {
+		return nil, err
+	}
+	remoteLogger := log.With(relayLogger{next: o.Logger, relay: res.relay}, "subcomponent", "rw")
+	storeReg := relayRegisterer{next: teeRegisterer{o.Registerer, remoteReg}, relay: res.relay}
+	res.remoteStore = remote.NewStorage(remoteLogger, storeReg, startTime, o.DataPath, remoteFlushDeadline, nil)
+	res.storage = storage.NewFanout(o.Logger, walStorage, res.remoteStore)
 	res.receiver = prometheus.NewInterceptor(
 		res.storage,
 		ls,
@@ -162,6 +199,7 @@ func New(o component.Options, c Arguments) (*Component, error) {
 	o.OnStateChange(Exports{Receiver: res.receiver})
 
 	if err := res.Update(c); err != nil {
+		_ = res.relay.Close()
 		return nil, err
 	}
 	return res, nil
@@ -169,7 +207,17 @@ func New(o component.Options, c Arguments) (*Component, error) {
 
 func startTime() (int64, error) { return 0, nil }
 
-var _ component.Component = (*Component)(nil)
+// observeResponse records the response of an endpoint to a request of the
+// remote storage.
+func (c *Component) observeResponse(e *relayEndpoint, resp relayResponse) {
+	c.rejections.Observe(e.url, resp)
+	c.storeDeadLetter(e, resp)
+}
+
+var (
+	_ component.Component    = (*Component)(nil)
+	_ http_service.Component = (*Component)(nil)
+)
 
 // Run implements Component.
 func (c *Component) Run(ctx context.Context) error {
@@ -182,6 +230,7 @@ func (c *Component) Run(ctx context.Context) error {
 		if err != nil {
 			level.Error(c.log).Log("msg", "error when closing storage", "err", err)
 		}
+		_ = c.relay.Close()
 	}()
 
 	// Track the last timestamp we truncated for to prevent segments from getting
@@ -264,10 +313,16 @@ func (c *Component) Update(newConfig component.Arguments) error {
 		}
 		cfg.Headers[agentseed.HeaderName] = uid
 	}
-	err = c.remoteStore.ApplyConfig(convertedConfig)
+	err = c.relay.ApplyConfig(convertedConfig, func(relayed *config.Config) error {
+		return c.remoteStore.ApplyConfig(relayed)
+	})
 	if err != nil {
 		return err
 	}
+	c.slo.Update(cfg.SLO)
+	if err := c.deadLetters.Update(cfg.DeadLetter); err != nil {
+		return fmt.Errorf("failed to open dead-letter queue: %w", err)
+	}
 
 	c.cfg = cfg
 	return nil
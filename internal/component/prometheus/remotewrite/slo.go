@@ -0,0 +1,114 @@
+package remotewrite
+
+import (
+	"github.com/grafana/agent/internal/component/common/slo"
+	promclient "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Names of the remote storage metrics which SLO metrics are derived from.
+const (
+	samplesTotalMetric       = "prometheus_remote_storage_samples_total"
+	samplesFailedTotalMetric = "prometheus_remote_storage_samples_failed_total"
+	sentBatchDurationMetric  = "prometheus_remote_storage_sent_batch_duration_seconds"
+
+	// urlLabel is the label of the remote storage metrics holding the endpoint
+	// URL.
+	urlLabel = "url"
+)
+
+// teeRegisterer registers collectors to two registerers.
+type teeRegisterer struct {
+	primary, secondary promclient.Registerer
+}
+
+var _ promclient.Registerer = teeRegisterer{}
+
+func (t teeRegisterer) Register(c promclient.Collector) error {
+	if err := t.primary.Register(c); err != nil {
+		return err
+	}
+	if err := t.secondary.Register(c); err != nil {
+		t.primary.Unregister(c)
+		return err
+	}
+	return nil
+}
+
+func (t teeRegisterer) MustRegister(cs ...promclient.Collector) {
+	for _, c := range cs {
+		if err := t.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (t teeRegisterer) Unregister(c promclient.Collector) bool {
+	secondary := t.secondary.Unregister(c)
+	return t.primary.Unregister(c) || secondary
+}
+
+// sloCollector exposes SLO metrics derived from the metrics of the remote
+// storage. The remote storage doesn't expose a hook into individual requests,
+// so the totals of its metrics are fed into the tracker whenever metrics are
+// collected.
+type sloCollector struct {
+	tracker *slo.Tracker
+	source  promclient.Gatherer
+}
+
+var _ promclient.Collector = (*sloCollector)(nil)
+
+func (c *sloCollector) Describe(ch chan<- *promclient.Desc) {
+	c.tracker.Describe(ch)
+}
+
+func (c *sloCollector) Collect(ch chan<- promclient.Metric) {
+	if c.tracker.Enabled() {
+		c.observe()
+	}
+	c.tracker.Collect(ch)
+}
+
+func (c *sloCollector) observe() {
+	families, err := c.source.Gather()
+	if err != nil {
+		return
+	}
+
+	var (
+		totals  = make(map[string]float64)
+		failed  = make(map[string]float64)
+		latency = make(map[string]*dto.Histogram)
+	)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			url := labelValue(m, urlLabel)
+			if url == "" {
+				continue
+			}
+
+			switch mf.GetName() {
+			case samplesTotalMetric:
+				totals[url] += m.GetCounter().GetValue()
+			case samplesFailedTotalMetric:
+				failed[url] += m.GetCounter().GetValue()
+			case sentBatchDurationMetric:
+				latency[url] = m.GetHistogram()
+			}
+		}
+	}
+
+	for url, total := range totals {
+		c.tracker.ObserveTotals(url, total, failed[url], latency[url])
+	}
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
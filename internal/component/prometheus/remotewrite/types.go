@@ -7,7 +7,9 @@ import (
 	"time"
 
 	types "github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/component/common/dlq"
 	flow_relabel "github.com/grafana/agent/internal/component/common/relabel"
+	"github.com/grafana/agent/internal/component/common/slo"
 	"github.com/grafana/river/rivertypes"
 
 	"github.com/google/uuid"
@@ -57,6 +59,8 @@ type Arguments struct {
 	ExternalLabels map[string]string  `river:"external_labels,attr,optional"`
 	Endpoints      []*EndpointOptions `river:"endpoint,block,optional"`
 	WALOptions     WALOptions         `river:"wal,block,optional"`
+	SLO            *slo.Arguments     `river:"slo,block,optional"`
+	DeadLetter     *dlq.Arguments     `river:"dead_letter_queue,block,optional"`
 }
 
 // SetToDefault implements river.Defaulter.
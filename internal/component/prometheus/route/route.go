@@ -0,0 +1,266 @@
+package route
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.route",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Supported routing modes.
+const (
+	// ModeFirstMatch sends a series only to the first matching route at each
+	// level of the routing tree.
+	ModeFirstMatch = "first_match"
+	// ModeAllMatches sends a series to every matching route at each level of
+	// the routing tree.
+	ModeAllMatches = "all_matches"
+)
+
+// DefaultRouteName is the name used for the root of the routing tree in
+// metrics.
+const DefaultRouteName = "default"
+
+// Arguments holds values which are used to configure the prometheus.route
+// component.
+type Arguments struct {
+	// How sibling routes are evaluated.
+	Mode string `river:"mode,attr,optional"`
+
+	// Where series which don't match any route are forwarded to.
+	DefaultForwardTo []storage.Appendable `river:"default_forward_to,attr,optional"`
+
+	Routes []Route `river:"route,block,optional"`
+}
+
+// Route is a node in the routing tree. A series is sent to the first (or
+// every, depending on the mode) matching child route; if no child route
+// matches, the series is sent to ForwardTo.
+type Route struct {
+	Name      string               `river:",label"`
+	Matchers  []string             `river:"matchers,attr"`
+	ForwardTo []storage.Appendable `river:"forward_to,attr,optional"`
+	Routes    []Route              `river:"route,block,optional"`
+}
+
+// DefaultArguments holds the default settings for the prometheus.route
+// component.
+var DefaultArguments = Arguments{
+	Mode: ModeFirstMatch,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	switch args.Mode {
+	case ModeFirstMatch, ModeAllMatches:
+	default:
+		return fmt.Errorf("unsupported mode %q, must be one of %q or %q", args.Mode, ModeFirstMatch, ModeAllMatches)
+	}
+
+	names := map[string]struct{}{DefaultRouteName: {}}
+	return validateRoutes(args.Routes, names)
+}
+
+func validateRoutes(routes []Route, names map[string]struct{}) error {
+	for _, r := range routes {
+		if _, ok := names[r.Name]; ok {
+			if r.Name == DefaultRouteName {
+				return fmt.Errorf("route name %q is reserved", DefaultRouteName)
+			}
+			return fmt.Errorf("route %q is defined more than once", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		if len(r.Matchers) == 0 {
+			return fmt.Errorf("route %q must have at least one matcher", r.Name)
+		}
+		if _, err := parseMatchers(r.Matchers); err != nil {
+			return fmt.Errorf("route %q: %w", r.Name, err)
+		}
+		if err := validateRoutes(r.Routes, names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseMatchers parses a list of PromQL label matchers, such as
+// `team=~"a|b"`.
+func parseMatchers(in []string) ([]*labels.Matcher, error) {
+	var res []*labels.Matcher
+	for _, s := range in {
+		ms, err := parser.ParseMetricSelector("{" + strings.TrimSpace(s) + "}")
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher %q: %w", s, err)
+		}
+		res = append(res, ms...)
+	}
+	return res, nil
+}
+
+// Exports holds values which are exported by the prometheus.route component.
+type Exports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+}
+
+// Component implements the prometheus.route component.
+type Component struct {
+	opts component.Options
+	ls   labelstore.LabelStore
+
+	routedSamples *prometheus_client.CounterVec
+
+	mut    sync.Mutex
+	routes *prometheus.RouteSet
+	router *prometheus.Router
+}
+
+// node is a compiled node of the routing tree.
+type node struct {
+	idx      int
+	matchers []*labels.Matcher
+	children []*node
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+)
+
+// New creates a new prometheus.route component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Component{
+		opts: o,
+		ls:   data.(labelstore.LabelStore),
+	}
+	c.routedSamples = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_route_samples_total",
+		Help: "Total number of samples forwarded by each route",
+	}, []string{"route"})
+	if err := o.Registerer.Register(c.routedSamples); err != nil {
+		return nil, err
+	}
+	c.routes = prometheus.NewRouteSet(o.ID, o.Registerer, c.ls, c.routedSamples)
+
+	c.router = prometheus.NewRouter(nil, func(labels.Labels) []int { return nil })
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.router})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+
+	var appendables []storage.Appendable
+	addRoute := func(name string, forwardTo []storage.Appendable) int {
+		appendables = append(appendables, c.routes.Get(name, forwardTo))
+		return len(appendables) - 1
+	}
+
+	var compile func(routes []Route) ([]*node, error)
+	compile = func(routes []Route) ([]*node, error) {
+		nodes := make([]*node, 0, len(routes))
+		for _, r := range routes {
+			matchers, err := parseMatchers(r.Matchers)
+			if err != nil {
+				return nil, err
+			}
+			n := &node{idx: addRoute(r.Name, r.ForwardTo), matchers: matchers}
+			if n.children, err = compile(r.Routes); err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+		}
+		return nodes, nil
+	}
+
+	root := &node{idx: addRoute(DefaultRouteName, newArgs.DefaultForwardTo)}
+	children, err := compile(newArgs.Routes)
+	if err != nil {
+		return err
+	}
+	root.children = children
+	c.routes.Prune()
+
+	firstMatch := newArgs.Mode != ModeAllMatches
+	c.router.Update(appendables, func(l labels.Labels) []int {
+		return root.match(l, firstMatch, nil)
+	})
+	return nil
+}
+
+// match appends the indexes of the routes the labels are sent to. The labels
+// are assumed to match n itself.
+func (n *node) match(l labels.Labels, firstMatch bool, res []int) []int {
+	matched := false
+	for _, child := range n.children {
+		if !matchesAll(child.matchers, l) {
+			continue
+		}
+		matched = true
+		res = child.match(l, firstMatch, res)
+		if firstMatch {
+			break
+		}
+	}
+	if !matched {
+		res = append(res, n.idx)
+	}
+	return res
+}
+
+func matchesAll(matchers []*labels.Matcher, l labels.Labels) bool {
+	for _, m := range matchers {
+		if !m.Matches(l.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
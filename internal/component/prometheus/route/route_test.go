@@ -0,0 +1,211 @@
+package route
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouting(t *testing.T) {
+	tests := []struct {
+		mode   string
+		series labels.Labels
+		expect []string
+	}{
+		{ModeFirstMatch, labels.FromStrings("team", "a", "env", "prod"), []string{"a-prod"}},
+		{ModeFirstMatch, labels.FromStrings("team", "a", "env", "dev"), []string{"a"}},
+		{ModeFirstMatch, labels.FromStrings("team", "b", "env", "prod"), []string{"prod"}},
+		{ModeFirstMatch, labels.FromStrings("team", "c"), []string{"default"}},
+		{ModeAllMatches, labels.FromStrings("team", "a", "env", "prod"), []string{"a-prod", "prod"}},
+		{ModeAllMatches, labels.FromStrings("team", "a", "env", "dev"), []string{"a"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.mode+"/"+tc.series.String(), func(t *testing.T) {
+			var (
+				ls       = labelstore.New(nil, prom.DefaultRegisterer)
+				received []string
+			)
+			capture := func(name string) []storage.Appendable {
+				return []storage.Appendable{prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, _ labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+					received = append(received, name)
+					return ref, nil
+				}))}
+			}
+
+			var receiver storage.Appendable
+			_, err := New(component.Options{
+				ID:     "prometheus.route.test",
+				Logger: util.TestFlowLogger(t),
+				OnStateChange: func(e component.Exports) {
+					receiver = e.(Exports).Receiver
+				},
+				Registerer: prom.NewRegistry(),
+				GetServiceData: func(name string) (interface{}, error) {
+					return ls, nil
+				},
+			}, Arguments{
+				Mode:             tc.mode,
+				DefaultForwardTo: capture("default"),
+				Routes: []Route{
+					{
+						Name:      "a",
+						Matchers:  []string{`team="a"`},
+						ForwardTo: capture("a"),
+						Routes: []Route{
+							{Name: "a-prod", Matchers: []string{`env=~"prod|production"`}, ForwardTo: capture("a-prod")},
+						},
+					},
+					{Name: "prod", Matchers: []string{`env="prod"`}, ForwardTo: capture("prod")},
+				},
+			})
+			require.NoError(t, err)
+
+			app := receiver.Appender(context.Background())
+			_, err = app.Append(0, tc.series, 0, 1)
+			require.NoError(t, err)
+			require.NoError(t, app.Commit())
+
+			sort.Strings(received)
+			require.Equal(t, tc.expect, received)
+		})
+	}
+}
+
+func TestRouteReadded(t *testing.T) {
+	var (
+		ls       = labelstore.New(nil, prom.DefaultRegisterer)
+		reg      = prom.NewRegistry()
+		receiver storage.Appendable
+	)
+	discard := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, _ labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+		return ref, nil
+	}))
+
+	withRoute := Arguments{
+		Mode:   ModeFirstMatch,
+		Routes: []Route{{Name: "a", Matchers: []string{`team="a"`}, ForwardTo: []storage.Appendable{discard}}},
+	}
+	withoutRoute := Arguments{Mode: ModeFirstMatch}
+
+	c, err := New(component.Options{
+		ID:     "prometheus.route.test",
+		Logger: util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {
+			receiver = e.(Exports).Receiver
+		},
+		Registerer: reg,
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, withRoute)
+	require.NoError(t, err)
+
+	send := func(n int) {
+		app := receiver.Appender(context.Background())
+		for i := 0; i < n; i++ {
+			_, err := app.Append(0, labels.FromStrings("__name__", "up", "team", "a"), int64(i), 1)
+			require.NoError(t, err)
+		}
+		require.NoError(t, app.Commit())
+	}
+	forwarded := func() float64 {
+		families, err := reg.Gather()
+		require.NoError(t, err)
+		for _, mf := range families {
+			if mf.GetName() != "agent_prometheus_forwarded_samples_total" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "route" && l.GetValue() == "a" {
+						return m.GetCounter().GetValue()
+					}
+				}
+			}
+		}
+		return -1
+	}
+
+	send(2)
+	require.Equal(t, 2.0, forwarded())
+
+	// Removing the route unregisters the metrics of its fanout.
+	require.NoError(t, c.Update(withoutRoute))
+	require.Equal(t, -1.0, forwarded())
+
+	// Adding the route back must not fail with an AlreadyRegistered error.
+	require.NoError(t, c.Update(withRoute))
+	send(1)
+	require.Equal(t, 1.0, forwarded())
+}
+
+func TestRiverConfig(t *testing.T) {
+	cfg := `
+		mode = "all_matches"
+		default_forward_to = []
+
+		route "a" {
+			matchers   = ["team=\"a\""]
+			forward_to = []
+
+			route "a_prod" {
+				matchers   = ["env=\"prod\""]
+				forward_to = []
+			}
+		}
+	`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+	require.Equal(t, ModeAllMatches, args.Mode)
+	require.Equal(t, "a_prod", args.Routes[0].Routes[0].Name)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		args Arguments
+		err  string
+	}{
+		{
+			name: "bad mode",
+			args: Arguments{Mode: "some"},
+			err:  `unsupported mode "some", must be one of "first_match" or "all_matches"`,
+		},
+		{
+			name: "reserved name",
+			args: Arguments{Mode: ModeFirstMatch, Routes: []Route{{Name: "default", Matchers: []string{`a="b"`}}}},
+			err:  `route name "default" is reserved`,
+		},
+		{
+			name: "nested duplicate name",
+			args: Arguments{Mode: ModeFirstMatch, Routes: []Route{
+				{Name: "a", Matchers: []string{`a="b"`}, Routes: []Route{{Name: "a", Matchers: []string{`a="b"`}}}},
+			}},
+			err: `route "a" is defined more than once`,
+		},
+		{
+			name: "no matchers",
+			args: Arguments{Mode: ModeFirstMatch, Routes: []Route{{Name: "a"}}},
+			err:  `route "a" must have at least one matcher`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.EqualError(t, tc.args.Validate(), tc.err)
+		})
+	}
+
+	args := Arguments{Mode: ModeFirstMatch, Routes: []Route{{Name: "a", Matchers: []string{`a=`}}}}
+	require.ErrorContains(t, args.Validate(), `route "a": invalid matcher "a="`)
+}
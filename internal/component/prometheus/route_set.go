@@ -0,0 +1,94 @@
+package prometheus
+
+import (
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// RouteSet holds the named destinations of a Router. Each route has its own
+// Fanout, whose metrics are labeled with the name of the route so that they
+// can be told apart from the other routes, and counts the samples sent
+// through it. The metrics of a route are unregistered when it's removed.
+//
+// RouteSet isn't safe for concurrent use.
+type RouteSet struct {
+	componentID   string
+	reg           prometheus.Registerer
+	ls            labelstore.LabelStore
+	routedSamples *prometheus.CounterVec
+
+	routes map[string]*namedRoute
+	used   map[string]struct{} // Routes returned by Get since the last Prune.
+}
+
+type namedRoute struct {
+	fanout   *Fanout
+	receiver storage.Appendable
+	reg      *util.Unregisterer
+}
+
+// NewRouteSet creates a new RouteSet. The metrics of the fanouts of the
+// routes are registered to reg. routedSamples must have a single route label
+// and is incremented for every sample sent through a route.
+func NewRouteSet(componentID string, reg prometheus.Registerer, ls labelstore.LabelStore, routedSamples *prometheus.CounterVec) *RouteSet {
+	return &RouteSet{
+		componentID:   componentID,
+		reg:           reg,
+		ls:            ls,
+		routedSamples: routedSamples,
+		routes:        make(map[string]*namedRoute),
+		used:          make(map[string]struct{}),
+	}
+}
+
+// Get returns the receiver of the named route, creating the route if it
+// doesn't exist yet, or updating the destinations of the existing route in
+// place.
+func (s *RouteSet) Get(name string, forwardTo []storage.Appendable) storage.Appendable {
+	s.used[name] = struct{}{}
+
+	if r, ok := s.routes[name]; ok {
+		r.fanout.UpdateChildren(forwardTo)
+		return r.receiver
+	}
+
+	reg := util.WrapWithUnregisterer(prometheus.WrapRegistererWith(prometheus.Labels{"route": name}, s.reg))
+	fanout := NewFanout(forwardTo, s.componentID, reg, s.ls)
+	counter := s.routedSamples.WithLabelValues(name)
+
+	r := &namedRoute{
+		reg:    reg,
+		fanout: fanout,
+		receiver: NewInterceptor(
+			fanout,
+			s.ls,
+			WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, t int64, v float64, next storage.Appender) (storage.SeriesRef, error) {
+				counter.Inc()
+				return next.Append(ref, l, t, v)
+			}),
+			WithHistogramHook(func(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram, next storage.Appender) (storage.SeriesRef, error) {
+				counter.Inc()
+				return next.AppendHistogram(ref, l, t, h, fh)
+			}),
+		),
+	}
+	s.routes[name] = r
+	return r.receiver
+}
+
+// Prune removes the routes which weren't returned by Get since the previous
+// call to Prune, and unregisters their metrics.
+func (s *RouteSet) Prune() {
+	for name, r := range s.routes {
+		if _, ok := s.used[name]; !ok {
+			r.reg.UnregisterAll()
+			delete(s.routes, name)
+			s.routedSamples.DeleteLabelValues(name)
+		}
+	}
+	s.used = make(map[string]struct{}, len(s.routes))
+}
@@ -0,0 +1,141 @@
+package prometheus
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+)
+
+var _ storage.Appendable = (*Router)(nil)
+
+// RouteFunc returns the indexes of the routes a series with the given labels
+// should be sent to. Returning no indexes drops the series.
+type RouteFunc func(l labels.Labels) []int
+
+// Router is an appendable which sends each series to a subset of its routes,
+// as chosen by a RouteFunc. Appenders for each route are only created once a
+// series is routed to them.
+type Router struct {
+	mut    sync.RWMutex
+	routes []storage.Appendable
+	fn     RouteFunc
+}
+
+// NewRouter creates a new Router.
+func NewRouter(routes []storage.Appendable, fn RouteFunc) *Router {
+	return &Router{routes: routes, fn: fn}
+}
+
+// Update replaces the routes and the RouteFunc of the Router. In-flight
+// appenders keep using the routes they were created with.
+func (r *Router) Update(routes []storage.Appendable, fn RouteFunc) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.routes = routes
+	r.fn = fn
+}
+
+// Appender satisfies the Appendable interface.
+func (r *Router) Appender(ctx context.Context) storage.Appender {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	return &routerAppender{
+		ctx:       ctx,
+		routes:    r.routes,
+		fn:        r.fn,
+		appenders: make([]storage.Appender, len(r.routes)),
+	}
+}
+
+type routerAppender struct {
+	ctx       context.Context
+	routes    []storage.Appendable
+	fn        RouteFunc
+	appenders []storage.Appender
+}
+
+var _ storage.Appender = (*routerAppender)(nil)
+
+// forEach calls f with the appender of every route the labels are sent to.
+func (a *routerAppender) forEach(l labels.Labels, f func(app storage.Appender) error) error {
+	var multiErr error
+	for _, idx := range a.fn(l) {
+		if idx < 0 || idx >= len(a.routes) || a.routes[idx] == nil {
+			continue
+		}
+		if a.appenders[idx] == nil {
+			a.appenders[idx] = a.routes[idx].Appender(a.ctx)
+		}
+		if err := f(a.appenders[idx]); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
+	}
+	return multiErr
+}
+
+// Append satisfies the Appender interface.
+func (a *routerAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	return ref, a.forEach(l, func(app storage.Appender) error {
+		_, err := app.Append(ref, l, t, v)
+		return err
+	})
+}
+
+// AppendExemplar satisfies the Appender interface.
+func (a *routerAppender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	return ref, a.forEach(l, func(app storage.Appender) error {
+		_, err := app.AppendExemplar(ref, l, e)
+		return err
+	})
+}
+
+// UpdateMetadata satisfies the Appender interface.
+func (a *routerAppender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	return ref, a.forEach(l, func(app storage.Appender) error {
+		_, err := app.UpdateMetadata(ref, l, m)
+		return err
+	})
+}
+
+// AppendHistogram satisfies the Appender interface.
+func (a *routerAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	return ref, a.forEach(l, func(app storage.Appender) error {
+		_, err := app.AppendHistogram(ref, l, t, h, fh)
+		return err
+	})
+}
+
+// Commit satisfies the Appender interface.
+func (a *routerAppender) Commit() error {
+	var multiErr error
+	for _, app := range a.appenders {
+		if app == nil {
+			continue
+		}
+		if err := app.Commit(); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
+	}
+	return multiErr
+}
+
+// Rollback satisfies the Appender interface.
+func (a *routerAppender) Rollback() error {
+	var multiErr error
+	for _, app := range a.appenders {
+		if app == nil {
+			continue
+		}
+		if err := app.Rollback(); err != nil {
+			multiErr = multierror.Append(multiErr, err)
+		}
+	}
+	return multiErr
+}
@@ -0,0 +1,282 @@
+// Package file implements the prometheus.rules.file component.
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.rules.file",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// prometheus.rules.file component.
+type Arguments struct {
+	// Files holds paths or glob patterns of rule files to read.
+	Files []string `river:"files,attr"`
+	// PollFrequency determines how often the files are read again.
+	PollFrequency time.Duration `river:"poll_frequency,attr,optional"`
+}
+
+// DefaultArguments provides the default arguments for the
+// prometheus.rules.file component.
+var DefaultArguments = Arguments{
+	PollFrequency: time.Minute,
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if len(a.Files) == 0 {
+		return fmt.Errorf("at least one file must be specified")
+	}
+	for _, pattern := range a.Files {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if a.PollFrequency <= 0 {
+		return fmt.Errorf("poll_frequency must be greater than 0")
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.rules.file
+// component.
+type Exports struct {
+	Groups []RuleGroup `river:"groups,attr"`
+}
+
+// RuleGroup is a group of rules read from a rule file.
+type RuleGroup struct {
+	Name     string        `river:"name,attr"`
+	File     string        `river:"file,attr"`
+	Interval time.Duration `river:"interval,attr"`
+	Limit    int           `river:"limit,attr"`
+	Rules    []Rule        `river:"rules,attr"`
+}
+
+// Rule is a single recording or alerting rule. Exactly one of Record and
+// Alert is set.
+type Rule struct {
+	Record        string            `river:"record,attr"`
+	Alert         string            `river:"alert,attr"`
+	Expr          string            `river:"expr,attr"`
+	For           time.Duration     `river:"for,attr"`
+	KeepFiringFor time.Duration     `river:"keep_firing_for,attr"`
+	Labels        map[string]string `river:"labels,attr"`
+	Annotations   map[string]string `river:"annotations,attr"`
+}
+
+// Component implements the prometheus.rules.file component.
+type Component struct {
+	opts component.Options
+
+	mut         sync.Mutex
+	args        Arguments
+	lastExports Exports
+
+	// updated is written to whenever args updates.
+	updated chan struct{}
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new prometheus.rules.file component. The rule files are read
+// immediately, and an error is returned if they can't be read or parsed.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		updated: make(chan struct{}, 1),
+	}
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		c.mut.Lock()
+		pollFrequency := c.args.PollFrequency
+		c.mut.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollFrequency):
+			if err := c.load(); err != nil {
+				level.Error(c.opts.Logger).Log("msg", "failed to load rule files", "err", err)
+			}
+		case <-c.updated:
+			// no-op; force the poll frequency to be reread.
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	c.args = args.(Arguments)
+	c.mut.Unlock()
+
+	// Send an updated event if one wasn't already read.
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+
+	return c.load()
+}
+
+// load reads and parses the rule files, exporting the rule groups if they
+// changed. The component's health is updated with the result.
+func (c *Component) load() error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	groups, err := readRuleFiles(c.args.Files)
+	c.setHealth(err)
+	if err != nil {
+		return err
+	}
+
+	newExports := Exports{Groups: groups}
+	if !reflect.DeepEqual(c.lastExports, newExports) {
+		c.lastExports = newExports
+		c.opts.OnStateChange(newExports)
+	}
+	return nil
+}
+
+func (c *Component) setHealth(err error) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+
+	if err == nil {
+		c.health = component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    "loaded rule files",
+			UpdateTime: time.Now(),
+		}
+	} else {
+		c.health = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    err.Error(),
+			UpdateTime: time.Now(),
+		}
+	}
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
+
+// readRuleFiles reads every rule file matching patterns, in lexical order of
+// their paths.
+func readRuleFiles(patterns []string) ([]RuleGroup, error) {
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	groups := []RuleGroup{}
+	seen := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+
+		fileGroups, err := readRuleFile(path)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, fileGroups...)
+	}
+	return groups, nil
+}
+
+func readRuleFile(path string) ([]RuleGroup, error) {
+	bb, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rgs, errs := rulefmt.Parse(bb)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("parsing %s: %w", path, errs[0])
+	}
+
+	res := make([]RuleGroup, 0, len(rgs.Groups))
+	for _, rg := range rgs.Groups {
+		group := RuleGroup{
+			Name:     rg.Name,
+			File:     path,
+			Interval: time.Duration(rg.Interval),
+			Limit:    rg.Limit,
+			Rules:    make([]Rule, 0, len(rg.Rules)),
+		}
+		for _, r := range rg.Rules {
+			group.Rules = append(group.Rules, Rule{
+				Record:        r.Record.Value,
+				Alert:         r.Alert.Value,
+				Expr:          r.Expr.Value,
+				For:           time.Duration(r.For),
+				KeepFiringFor: time.Duration(r.KeepFiringFor),
+				Labels:        nonNilMap(r.Labels),
+				Annotations:   nonNilMap(r.Annotations),
+			})
+		}
+		res = append(res, group)
+	}
+	return res, nil
+}
+
+// nonNilMap returns m, or an empty map if m is nil, so that exported rules
+// always have objects for their labels and annotations.
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
@@ -0,0 +1,81 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `
+groups:
+  - name: example
+    interval: 30s
+    rules:
+      - record: job:up:sum
+        expr: sum by (job) (up)
+      - alert: InstanceDown
+        expr: up == 0
+        for: 5m
+        labels:
+          severity: page
+        annotations:
+          summary: "Instance {{ $labels.instance }} down"
+`
+
+func TestReadRuleFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yaml"), []byte(testRules), 0644))
+
+	var exports Exports
+	_, err := New(component.Options{
+		Logger: util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {
+			exports = e.(Exports)
+		},
+	}, Arguments{
+		Files:         []string{filepath.Join(dir, "*.yaml")},
+		PollFrequency: time.Minute,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []RuleGroup{{
+		Name:     "example",
+		File:     filepath.Join(dir, "a.yaml"),
+		Interval: 30 * time.Second,
+		Rules: []Rule{
+			{
+				Record:      "job:up:sum",
+				Expr:        "sum by (job) (up)",
+				Labels:      map[string]string{},
+				Annotations: map[string]string{},
+			},
+			{
+				Alert:       "InstanceDown",
+				Expr:        "up == 0",
+				For:         5 * time.Minute,
+				Labels:      map[string]string{"severity": "page"},
+				Annotations: map[string]string{"summary": "Instance {{ $labels.instance }} down"},
+			},
+		},
+	}}, exports.Groups)
+}
+
+func TestInvalidRuleFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("groups:\n  - name: bad\n    rules:\n      - expr: up\n"), 0644))
+
+	_, err := New(component.Options{
+		Logger:        util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {},
+	}, Arguments{
+		Files:         []string{path},
+		PollFrequency: time.Minute,
+	})
+	require.ErrorContains(t, err, "parsing "+path)
+}
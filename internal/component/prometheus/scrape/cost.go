@@ -0,0 +1,205 @@
+package scrape
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// sampleOverheadBytes is the estimated size of the timestamp and value of a
+// sample, which is added to the size of its labels.
+const sampleOverheadBytes = 16
+
+// healthMetricName is the name of the report series the scrape manager
+// appends at the end of every scrape of a target. It is labeled with the
+// labels of the target.
+const healthMetricName = "up"
+
+// targetCost holds the cost of the last scrape of a target.
+type targetCost struct {
+	samples  int     // Samples appended by the last scrape.
+	bytes    int     // Estimated size of the samples appended by the last scrape.
+	interval float64 // Seconds between the last two scrapes.
+	last     time.Time
+}
+
+// costTracker records the cost of the samples appended by the scrapes of each
+// target. Scrapes are attributed to targets through the labels of the health
+// series appended at the end of each scrape, which are the labels of the
+// target.
+type costTracker struct {
+	next storage.Appendable
+
+	mut     sync.Mutex
+	targets map[uint64]*targetCost // Costs by the hash of the target labels.
+}
+
+var _ storage.Appendable = (*costTracker)(nil)
+
+func newCostTracker(next storage.Appendable) *costTracker {
+	return &costTracker{
+		next:    next,
+		targets: make(map[uint64]*targetCost),
+	}
+}
+
+// Appender implements storage.Appendable.
+func (ct *costTracker) Appender(ctx context.Context) storage.Appender {
+	return &costAppender{Appender: ct.next.Appender(ctx), tracker: ct}
+}
+
+func (ct *costTracker) observe(target uint64, samples, bytes int) {
+	ct.mut.Lock()
+	defer ct.mut.Unlock()
+
+	now := time.Now()
+	tc, ok := ct.targets[target]
+	if !ok {
+		tc = &targetCost{}
+		ct.targets[target] = tc
+	} else {
+		tc.interval = now.Sub(tc.last).Seconds()
+	}
+	tc.samples, tc.bytes, tc.last = samples, bytes, now
+}
+
+// forget removes the cost of a target which is no longer scraped.
+func (ct *costTracker) forget(target uint64) {
+	ct.mut.Lock()
+	defer ct.mut.Unlock()
+	delete(ct.targets, target)
+}
+
+// report returns the cost of the active targets. Targets which are no longer
+// active are forgotten, in case their removal wasn't observed.
+func (ct *costTracker) report(active map[string][]*scrape.Target) component.ScrapeCost {
+	ct.mut.Lock()
+	defer ct.mut.Unlock()
+
+	var (
+		cost    = component.ScrapeCost{Targets: []component.TargetCost{}}
+		current = make(map[uint64]struct{})
+	)
+	for job, targets := range active {
+		for _, t := range targets {
+			hash := t.Labels().Hash()
+			current[hash] = struct{}{}
+			tc, ok := ct.targets[hash]
+			if !ok {
+				continue
+			}
+
+			target := component.TargetCost{
+				Job:          job,
+				URL:          t.URL().String(),
+				Labels:       t.Labels().Map(),
+				ActiveSeries: tc.samples,
+			}
+			if tc.interval > 0 {
+				target.SamplesPerSecond = float64(tc.samples) / tc.interval
+				target.BytesPerSecond = float64(tc.bytes) / tc.interval
+			}
+			cost.ActiveSeries += target.ActiveSeries
+			cost.SamplesPerSecond += target.SamplesPerSecond
+			cost.BytesPerSecond += target.BytesPerSecond
+			cost.Targets = append(cost.Targets, target)
+		}
+	}
+	for hash := range ct.targets {
+		if _, ok := current[hash]; !ok {
+			delete(ct.targets, hash)
+		}
+	}
+
+	sort.Slice(cost.Targets, func(i, j int) bool {
+		if cost.Targets[i].ActiveSeries != cost.Targets[j].ActiveSeries {
+			return cost.Targets[i].ActiveSeries > cost.Targets[j].ActiveSeries
+		}
+		return cost.Targets[i].URL < cost.Targets[j].URL
+	})
+	return cost
+}
+
+// costAppender counts the samples appended by a single scrape of a target.
+type costAppender struct {
+	storage.Appender
+	tracker *costTracker
+
+	samples, bytes int
+
+	target  labels.Labels // Labels of the target, set by the health series.
+	stopped bool          // Whether the target is no longer scraped.
+}
+
+func (app *costAppender) observe(l labels.Labels) {
+	app.samples++
+	app.bytes += sampleOverheadBytes
+	l.Range(func(l labels.Label) {
+		app.bytes += len(l.Name) + len(l.Value)
+	})
+}
+
+// observeHealth records the target of the scrape from the labels of its
+// health series. The health series is marked stale once the target is
+// removed.
+func (app *costAppender) observeHealth(l labels.Labels, stale bool) {
+	if l.Get(labels.MetricName) != healthMetricName {
+		return
+	}
+	app.target = labels.NewBuilder(l).Del(labels.MetricName).Labels()
+	app.stopped = stale
+}
+
+// Append implements storage.Appender. Staleness markers aren't counted, as
+// their series are no longer active.
+func (app *costAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	ref, err := app.Appender.Append(ref, l, t, v)
+	if err != nil {
+		return ref, err
+	}
+	stale := value.IsStaleNaN(v)
+	if !stale {
+		app.observe(l)
+	}
+	app.observeHealth(l, stale)
+	return ref, nil
+}
+
+// AppendHistogram implements storage.Appender.
+func (app *costAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	ref, err := app.Appender.AppendHistogram(ref, l, t, h, fh)
+	if err == nil && !isStaleHistogram(h, fh) {
+		app.observe(l)
+	}
+	return ref, err
+}
+
+// Commit implements storage.Appender. Appenders which didn't append a health
+// series, such as those not used by a scrape, aren't tracked.
+func (app *costAppender) Commit() error {
+	err := app.Appender.Commit()
+	if err != nil || app.target.IsEmpty() {
+		return err
+	}
+	if app.stopped {
+		app.tracker.forget(app.target.Hash())
+	} else {
+		app.tracker.observe(app.target.Hash(), app.samples, app.bytes)
+	}
+	return nil
+}
+
+func isStaleHistogram(h *histogram.Histogram, fh *histogram.FloatHistogram) bool {
+	if h != nil {
+		return value.IsStaleNaN(h.Sum)
+	}
+	return fh != nil && value.IsStaleNaN(fh.Sum)
+}
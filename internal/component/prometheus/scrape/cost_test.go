@@ -0,0 +1,101 @@
+package scrape
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/util/testappender"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+type testAppendable struct{}
+
+func (testAppendable) Appender(context.Context) storage.Appender { return &testappender.Appender{} }
+
+func TestCostTracker(t *testing.T) {
+	ct := newCostTracker(testAppendable{})
+
+	var (
+		small = scrape.NewTarget(labels.FromStrings("instance", "small", "job", "test"), labels.EmptyLabels(), nil)
+		large = scrape.NewTarget(labels.FromStrings("instance", "large", "job", "test"), labels.EmptyLabels(), nil)
+		gone  = scrape.NewTarget(labels.FromStrings("instance", "gone", "job", "test"), labels.EmptyLabels(), nil)
+	)
+
+	// scrapeTarget appends the samples of a scrape of target, followed by the
+	// health series the scrape manager appends at the end of each scrape.
+	scrapeTarget := func(target *scrape.Target, series int) {
+		app := ct.Appender(context.Background())
+		for i := 0; i < series; i++ {
+			_, err := app.Append(0, labels.FromStrings("__name__", "m", "i", string(rune('a'+i))), 0, 1)
+			require.NoError(t, err)
+		}
+		// Staleness markers aren't counted.
+		_, err := app.Append(0, labels.FromStrings("__name__", "stale"), 0, math.Float64frombits(value.StaleNaN))
+		require.NoError(t, err)
+		_, err = app.Append(0, healthSeries(target), 0, 1)
+		require.NoError(t, err)
+		require.NoError(t, app.Commit())
+	}
+
+	// Appenders without a health series aren't tracked.
+	app := ct.Appender(context.Background())
+	_, err := app.Append(0, labels.FromStrings("__name__", "m"), 0, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.Empty(t, ct.targets)
+
+	scrapeTarget(small, 1)
+	scrapeTarget(large, 3)
+	scrapeTarget(gone, 2)
+	time.Sleep(10 * time.Millisecond)
+	scrapeTarget(small, 1)
+	scrapeTarget(large, 3)
+
+	cost := ct.report(map[string][]*scrape.Target{"test": {small, large}})
+	// The health series of each target is counted.
+	require.Equal(t, 6, cost.ActiveSeries)
+	require.Len(t, cost.Targets, 2)
+	require.Equal(t, map[string]string{"instance": "large", "job": "test"}, cost.Targets[0].Labels)
+	require.Equal(t, 4, cost.Targets[0].ActiveSeries)
+	require.Greater(t, cost.Targets[0].SamplesPerSecond, 0.0)
+	// Each sample is estimated as the size of its labels plus 16 bytes.
+	scrapeBytes := 3*(len("__name__m")+len("ia")+sampleOverheadBytes) +
+		len("__name__up") + len("instancelarge") + len("jobtest") + sampleOverheadBytes
+	require.InDelta(t, cost.Targets[0].SamplesPerSecond*float64(scrapeBytes)/4, cost.Targets[0].BytesPerSecond, 0.001)
+	require.Equal(t, cost.Targets[0].SamplesPerSecond+cost.Targets[1].SamplesPerSecond, cost.SamplesPerSecond)
+
+	// Inactive targets are forgotten.
+	require.NotContains(t, ct.targets, gone.Labels().Hash())
+}
+
+func TestCostTracker_TargetRemoved(t *testing.T) {
+	ct := newCostTracker(testAppendable{})
+	target := scrape.NewTarget(labels.FromStrings("instance", "removed", "job", "test"), labels.EmptyLabels(), nil)
+
+	app := ct.Appender(context.Background())
+	_, err := app.Append(0, healthSeries(target), 0, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.Contains(t, ct.targets, target.Labels().Hash())
+
+	// Once a target is removed, the scrape manager marks its series stale,
+	// including the health series.
+	app = ct.Appender(context.Background())
+	_, err = app.Append(0, labels.FromStrings("__name__", "m"), 0, math.Float64frombits(value.StaleNaN))
+	require.NoError(t, err)
+	_, err = app.Append(0, healthSeries(target), 0, math.Float64frombits(value.StaleNaN))
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+	require.Empty(t, ct.targets)
+}
+
+// healthSeries returns the labels of the health series of target.
+func healthSeries(target *scrape.Target) labels.Labels {
+	return labels.NewBuilder(target.Labels()).Set(labels.MetricName, healthMetricName).Labels()
+}
@@ -130,11 +130,13 @@ type Component struct {
 	args         Arguments
 	scraper      *scrape.Manager
 	appendable   *prometheus.Fanout
+	costs        *costTracker
 	targetsGauge client_prometheus.Gauge
 }
 
 var (
-	_ component.Component = (*Component)(nil)
+	_ component.Component           = (*Component)(nil)
+	_ component.ScrapeCostComponent = (*Component)(nil)
 )
 
 // New creates a new prometheus.scrape component.
@@ -165,7 +167,8 @@ func New(o component.Options, args Arguments) (*Component, error) {
 		},
 		EnableProtobufNegotiation: args.EnableProtobufNegotiation,
 	}
-	scraper := scrape.NewManager(scrapeOptions, o.Logger, flowAppendable)
+	costs := newCostTracker(flowAppendable)
+	scraper := scrape.NewManager(scrapeOptions, o.Logger, costs)
 
 	targetsGauge := client_prometheus.NewGauge(client_prometheus.GaugeOpts{
 		Name: "agent_prometheus_scrape_targets_gauge",
@@ -181,6 +184,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 		reloadTargets: make(chan struct{}, 1),
 		scraper:       scraper,
 		appendable:    flowAppendable,
+		costs:         costs,
 		targetsGauge:  targetsGauge,
 	}
 
@@ -374,6 +378,11 @@ func (c *Component) DebugInfo() interface{} {
 	}
 }
 
+// ScrapeCost implements component.ScrapeCostComponent.
+func (c *Component) ScrapeCost() component.ScrapeCost {
+	return c.costs.report(c.scraper.TargetsActive())
+}
+
 func (c *Component) componentTargetsToProm(jobName string, tgs []discovery.Target) map[string][]*targetgroup.Group {
 	promGroup := &targetgroup.Group{Source: jobName}
 	for _, tg := range tgs {
@@ -0,0 +1,171 @@
+package tenant_router
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service/labelstore"
+	prometheus_client "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "prometheus.tenant_router",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// DefaultRouteName is the name used for the default route in metrics.
+const DefaultRouteName = "default"
+
+// Arguments holds values which are used to configure the
+// prometheus.tenant_router component.
+type Arguments struct {
+	// The label whose value selects the route for a series.
+	Label string `river:"label,attr"`
+
+	// Where series which don't match any route are forwarded to.
+	DefaultForwardTo []storage.Appendable `river:"default_forward_to,attr,optional"`
+
+	Routes []Route `river:"route,block,optional"`
+}
+
+// Route forwards series whose label value is one of Values.
+type Route struct {
+	Name      string               `river:",label"`
+	Values    []string             `river:"values,attr"`
+	ForwardTo []storage.Appendable `river:"forward_to,attr"`
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Label == "" {
+		return fmt.Errorf("label must not be empty")
+	}
+
+	names := make(map[string]struct{}, len(args.Routes))
+	values := make(map[string]string)
+	for _, r := range args.Routes {
+		if r.Name == DefaultRouteName {
+			return fmt.Errorf("route name %q is reserved", DefaultRouteName)
+		}
+		if _, ok := names[r.Name]; ok {
+			return fmt.Errorf("route %q is defined more than once", r.Name)
+		}
+		names[r.Name] = struct{}{}
+
+		if len(r.Values) == 0 {
+			return fmt.Errorf("route %q must have at least one value", r.Name)
+		}
+		for _, v := range r.Values {
+			if other, ok := values[v]; ok {
+				return fmt.Errorf("value %q is used by both route %q and route %q", v, other, r.Name)
+			}
+			values[v] = r.Name
+		}
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the prometheus.tenant_router
+// component.
+type Exports struct {
+	Receiver storage.Appendable `river:"receiver,attr"`
+}
+
+// Component implements the prometheus.tenant_router component.
+type Component struct {
+	opts component.Options
+	ls   labelstore.LabelStore
+
+	routedSamples *prometheus_client.CounterVec
+
+	mut    sync.Mutex
+	routes *prometheus.RouteSet
+	router *prometheus.Router
+}
+
+var (
+	_ component.Component = (*Component)(nil)
+)
+
+// New creates a new prometheus.tenant_router component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(labelstore.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Component{
+		opts: o,
+		ls:   data.(labelstore.LabelStore),
+	}
+	c.routedSamples = prometheus_client.NewCounterVec(prometheus_client.CounterOpts{
+		Name: "agent_prometheus_tenant_router_samples_total",
+		Help: "Total number of samples forwarded by each route",
+	}, []string{"route"})
+	if err := o.Registerer.Register(c.routedSamples); err != nil {
+		return nil, err
+	}
+	c.routes = prometheus.NewRouteSet(o.ID, o.Registerer, c.ls, c.routedSamples)
+
+	c.router = prometheus.NewRouter(nil, func(labels.Labels) []int { return nil })
+
+	// Immediately export the receiver which remains the same for the component
+	// lifetime.
+	o.OnStateChange(Exports{Receiver: c.router})
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	newArgs := args.(Arguments)
+
+	var (
+		appendables = make([]storage.Appendable, 0, len(newArgs.Routes)+1)
+		byValue     = make(map[string]int)
+	)
+	for i, r := range newArgs.Routes {
+		appendables = append(appendables, c.routes.Get(r.Name, r.ForwardTo))
+		for _, v := range r.Values {
+			byValue[v] = i
+		}
+	}
+	defaultIdx := len(appendables)
+	appendables = append(appendables, c.routes.Get(DefaultRouteName, newArgs.DefaultForwardTo))
+	c.routes.Prune()
+
+	label := newArgs.Label
+	c.router.Update(appendables, func(l labels.Labels) []int {
+		if idx, ok := byValue[l.Get(label)]; ok {
+			return []int{idx}
+		}
+		return []int{defaultIdx}
+	})
+	return nil
+}
@@ -0,0 +1,187 @@
+package tenant_router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/prometheus"
+	"github.com/grafana/agent/internal/service/labelstore"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouting(t *testing.T) {
+	var (
+		ls       = labelstore.New(nil, prom.DefaultRegisterer)
+		received = map[string][]string{}
+	)
+	capture := func(name string) storage.Appendable {
+		return prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+			received[name] = append(received[name], l.Get("tenant"))
+			return ref, nil
+		}))
+	}
+
+	var receiver storage.Appendable
+	c, err := New(component.Options{
+		ID:     "prometheus.tenant_router.test",
+		Logger: util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {
+			receiver = e.(Exports).Receiver
+		},
+		Registerer: prom.NewRegistry(),
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, Arguments{
+		Label:            "tenant",
+		DefaultForwardTo: []storage.Appendable{capture("default")},
+		Routes: []Route{
+			{Name: "a", Values: []string{"team-a", "team-a2"}, ForwardTo: []storage.Appendable{capture("a")}},
+			{Name: "b", Values: []string{"team-b"}, ForwardTo: []storage.Appendable{capture("b")}},
+		},
+	})
+	require.NoError(t, err)
+
+	app := receiver.Appender(context.Background())
+	for _, tenant := range []string{"team-a", "team-b", "team-a2", "other", ""} {
+		_, err := app.Append(0, labels.FromStrings("__name__", "up", "tenant", tenant), 0, 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	require.Equal(t, map[string][]string{
+		"a":       {"team-a", "team-a2"},
+		"b":       {"team-b"},
+		"default": {"other", ""},
+	}, received)
+	require.Equal(t, 2.0, testutil.ToFloat64(c.routedSamples.WithLabelValues("a")))
+	require.Equal(t, 2.0, testutil.ToFloat64(c.routedSamples.WithLabelValues(DefaultRouteName)))
+}
+
+func TestRouteReadded(t *testing.T) {
+	var (
+		ls       = labelstore.New(nil, prom.DefaultRegisterer)
+		reg      = prom.NewRegistry()
+		receiver storage.Appendable
+	)
+	discard := prometheus.NewInterceptor(nil, ls, prometheus.WithAppendHook(func(ref storage.SeriesRef, _ labels.Labels, _ int64, _ float64, _ storage.Appender) (storage.SeriesRef, error) {
+		return ref, nil
+	}))
+
+	withRoute := Arguments{
+		Label:  "tenant",
+		Routes: []Route{{Name: "a", Values: []string{"team-a"}, ForwardTo: []storage.Appendable{discard}}},
+	}
+	withoutRoute := Arguments{Label: "tenant"}
+
+	c, err := New(component.Options{
+		ID:     "prometheus.tenant_router.test",
+		Logger: util.TestFlowLogger(t),
+		OnStateChange: func(e component.Exports) {
+			receiver = e.(Exports).Receiver
+		},
+		Registerer: reg,
+		GetServiceData: func(name string) (interface{}, error) {
+			return ls, nil
+		},
+	}, withRoute)
+	require.NoError(t, err)
+
+	send := func(n int) {
+		app := receiver.Appender(context.Background())
+		for i := 0; i < n; i++ {
+			_, err := app.Append(0, labels.FromStrings("__name__", "up", "tenant", "team-a"), int64(i), 1)
+			require.NoError(t, err)
+		}
+		require.NoError(t, app.Commit())
+	}
+	forwarded := func() float64 {
+		families, err := reg.Gather()
+		require.NoError(t, err)
+		for _, mf := range families {
+			if mf.GetName() != "agent_prometheus_forwarded_samples_total" {
+				continue
+			}
+			for _, m := range mf.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if l.GetName() == "route" && l.GetValue() == "a" {
+						return m.GetCounter().GetValue()
+					}
+				}
+			}
+		}
+		return -1
+	}
+
+	send(2)
+	require.Equal(t, 2.0, forwarded())
+
+	// Removing the route unregisters the metrics of its fanout.
+	require.NoError(t, c.Update(withoutRoute))
+	require.Equal(t, -1.0, forwarded())
+
+	// Adding the route back registers new metrics rather than leaving the
+	// collectors of the removed route in place.
+	require.NoError(t, c.Update(withRoute))
+	send(1)
+	require.Equal(t, 1.0, forwarded())
+}
+
+func TestRiverConfig(t *testing.T) {
+	cfg := `
+		label = "tenant"
+		default_forward_to = []
+
+		route "a" {
+			values     = ["team-a"]
+			forward_to = []
+		}
+	`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+	require.Equal(t, "a", args.Routes[0].Name)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		args Arguments
+		err  string
+	}{
+		{
+			name: "missing label",
+			args: Arguments{},
+			err:  "label must not be empty",
+		},
+		{
+			name: "reserved name",
+			args: Arguments{Label: "tenant", Routes: []Route{{Name: "default", Values: []string{"a"}}}},
+			err:  `route name "default" is reserved`,
+		},
+		{
+			name: "duplicate value",
+			args: Arguments{Label: "tenant", Routes: []Route{
+				{Name: "a", Values: []string{"x"}},
+				{Name: "b", Values: []string{"x"}},
+			}},
+			err: `value "x" is used by both route "a" and route "b"`,
+		},
+		{
+			name: "no values",
+			args: Arguments{Label: "tenant", Routes: []Route{{Name: "a"}}},
+			err:  `route "a" must have at least one value`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.EqualError(t, tc.args.Validate(), tc.err)
+		})
+	}
+}
@@ -20,5 +20,7 @@ type Arguments struct {
 	CollectUserProfile   bool                   `river:"collect_user_profile,attr,optional"`
 	CollectKernelProfile bool                   `river:"collect_kernel_profile,attr,optional"`
 	Demangle             string                 `river:"demangle,attr,optional"`
+	DemangleRust         bool                   `river:"demangle_rust,attr,optional"`
+	ContainerMetadata    bool                   `river:"container_metadata,attr,optional"`
 	PythonEnabled        bool                   `river:"python_enabled,attr,optional"`
 }
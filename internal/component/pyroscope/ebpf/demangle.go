@@ -0,0 +1,43 @@
+package ebpf
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/pprof/profile"
+	"github.com/ianlancetaylor/demangle"
+)
+
+// rustHashSuffix matches the hash legacy Rust mangling appends to every
+// symbol, once demangled.
+var rustHashSuffix = regexp.MustCompile(`::h[0-9a-f]{16}$`)
+
+// demangleRust demangles Rust symbols in the function names of p. Both the
+// legacy and v0 mangling schemes are supported. Symbols which were already
+// demangled as C++ have their legacy Rust hash removed.
+func demangleRust(p *profile.Profile) {
+	for _, fn := range p.Function {
+		fn.Name = demangleRustSymbol(fn.Name)
+		fn.SystemName = demangleRustSymbol(fn.SystemName)
+	}
+}
+
+func demangleRustSymbol(name string) string {
+	if strings.HasPrefix(name, "_R") || isLegacyRustSymbol(name) {
+		if demangled, err := demangle.ToString(name, demangle.NoClones); err == nil {
+			name = demangled
+		}
+	}
+	return rustHashSuffix.ReplaceAllString(name, "")
+}
+
+// isLegacyRustSymbol reports whether name is mangled with the legacy Rust
+// scheme, which is compatible with C++ mangling but ends each symbol with a
+// 17 character hash path component.
+func isLegacyRustSymbol(name string) bool {
+	if !strings.HasPrefix(name, "_ZN") || !strings.HasSuffix(name, "E") {
+		return false
+	}
+	i := strings.LastIndex(name, "17h")
+	return i >= 0 && i+3+16 == len(name)-1
+}
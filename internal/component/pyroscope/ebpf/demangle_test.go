@@ -0,0 +1,45 @@
+package ebpf
+
+import (
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemangleRust(t *testing.T) {
+	p := &profile.Profile{Function: []*profile.Function{
+		{Name: "_ZN3std2rt10lang_start17h0123456789abcdefE"},
+		{Name: "_RNvCs15kBYyAo9fc_7mycrate7example"},
+		{Name: "core::fmt::write::h0123456789abcdef"},
+		{Name: "main"},
+	}}
+	demangleRust(p)
+
+	var names []string
+	for _, fn := range p.Function {
+		names = append(names, fn.Name)
+	}
+	require.Equal(t, []string{
+		"std::rt::lang_start",
+		"mycrate::example",
+		"core::fmt::write",
+		"main",
+	}, names)
+}
+
+func TestWithContainerMetadata(t *testing.T) {
+	target := discovery.Target{
+		"__meta_kubernetes_namespace":          "default",
+		"__meta_kubernetes_pod_name":           "app-1",
+		"__meta_kubernetes_pod_container_name": "app",
+		"container":                            "custom",
+	}
+	res := withContainerMetadata(target)
+
+	require.Equal(t, "default", res["namespace"])
+	require.Equal(t, "app-1", res["pod"])
+	require.Equal(t, "custom", res["container"], "existing labels are kept")
+	require.NotContains(t, target, "namespace", "the original target is not modified")
+}
@@ -27,6 +27,12 @@ func init() {
 		Name:      "pyroscope.ebpf",
 		Stability: featuregate.StabilityBeta,
 		Args:      Arguments{},
+		Sandbox: component.SandboxExceptions{
+			// Executables and libraries of profiled processes, which can be
+			// anywhere on the host, are read to symbolize stack traces.
+			ReadPaths: []string{"/"},
+			Syscalls:  []string{"bpf", "perf_event_open"},
+		},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			arguments := args.(Arguments)
@@ -177,6 +183,10 @@ func (c *Component) collectProfiles() error {
 		c.metrics.pprofsTotal.WithLabelValues(serviceName).Inc()
 		c.metrics.pprofSamplesTotal.WithLabelValues(serviceName).Add(float64(len(builder.Profile.Sample)))
 
+		if args.DemangleRust {
+			demangleRust(builder.Profile)
+		}
+
 		buf := bytes.NewBuffer(nil)
 		_, err := builder.Write(buf)
 		if err != nil {
@@ -217,6 +227,9 @@ func (c *Component) updateDebugInfo() {
 func targetsOptionFromArgs(args Arguments) sd.TargetsOptions {
 	targets := make([]sd.DiscoveryTarget, 0, len(args.Targets))
 	for _, t := range args.Targets {
+		if args.ContainerMetadata {
+			t = withContainerMetadata(t)
+		}
 		targets = append(targets, sd.DiscoveryTarget(t))
 	}
 	return sd.TargetsOptions{
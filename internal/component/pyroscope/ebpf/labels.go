@@ -0,0 +1,33 @@
+package ebpf
+
+import (
+	"github.com/grafana/agent/internal/component/discovery"
+)
+
+// containerMetadataLabels maps discovery metadata labels to the labels they're
+// copied to when container_metadata is enabled.
+var containerMetadataLabels = []struct {
+	from, to string
+}{
+	{"__meta_kubernetes_namespace", "namespace"},
+	{"__meta_kubernetes_pod_name", "pod"},
+	{"__meta_kubernetes_pod_container_name", "container"},
+	{"__meta_kubernetes_pod_node_name", "node"},
+	{"__meta_docker_container_name", "container"},
+}
+
+// withContainerMetadata returns a copy of target with the Kubernetes pod and
+// container metadata from service discovery added as labels. Labels already
+// set on target are kept.
+func withContainerMetadata(target discovery.Target) discovery.Target {
+	res := make(discovery.Target, len(target)+len(containerMetadataLabels))
+	for k, v := range target {
+		res[k] = v
+	}
+	for _, l := range containerMetadataLabels {
+		if v := target[l.from]; v != "" && res[l.to] == "" {
+			res[l.to] = v
+		}
+	}
+	return res
+}
@@ -3,6 +3,7 @@ package java
 import (
 	"time"
 
+	"github.com/grafana/agent/internal/component/common/process"
 	"github.com/grafana/agent/internal/component/discovery"
 	"github.com/grafana/agent/internal/component/pyroscope"
 )
@@ -13,6 +14,7 @@ type Arguments struct {
 
 	TmpDir          string          `river:"tmp_dir,attr,optional"`
 	ProfilingConfig ProfilingConfig `river:"profiling_config,block,optional"`
+	Process         process.Config  `river:"process,block,optional"`
 }
 
 type ProfilingConfig struct {
@@ -31,7 +33,8 @@ func (rc *Arguments) UnmarshalRiver(f func(interface{}) error) error {
 
 func defaultArguments() Arguments {
 	return Arguments{
-		TmpDir: "/tmp",
+		TmpDir:  "/tmp",
+		Process: process.DefaultConfig,
 		ProfilingConfig: ProfilingConfig{
 			Interval:   60 * time.Second,
 			SampleRate: 100,
@@ -4,6 +4,7 @@ package asprof
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
 	_ "embed"
 	"encoding/hex"
@@ -16,6 +17,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/grafana/agent/internal/component/common/process"
 	"github.com/prometheus/procfs"
 )
 
@@ -61,6 +63,7 @@ type Profiler struct {
 	tmpDirMarker any
 	archiveHash  string
 	archive      Archive
+	supervisor   *process.Supervisor
 }
 
 type Archive struct {
@@ -79,11 +82,25 @@ func NewProfiler(tmpDir string, archive Archive) *Profiler {
 	return res
 }
 
+// SetSupervisor makes the profiler run the launcher through s, applying its
+// user, resource limits and output capture. It must be called before the
+// first call to Execute.
+func (p *Profiler) SetSupervisor(s *process.Supervisor) {
+	p.supervisor = s
+}
+
 func (p *Profiler) Execute(dist *Distribution, argv []string) (string, string, error) {
+	exe := dist.LauncherPath()
+	if p.supervisor != nil {
+		stdout, stderr, err := p.supervisor.Exec(context.Background(), exe, argv...)
+		if err != nil {
+			return stdout, stderr, fmt.Errorf("asprof failed to run %s: %w", exe, err)
+		}
+		return stdout, stderr, nil
+	}
+
 	stdout := bytes.NewBuffer(nil)
 	stderr := bytes.NewBuffer(nil)
-
-	exe := dist.LauncherPath()
 	cmd := exec.Command(exe, argv...)
 
 	cmd.Stdout = stdout
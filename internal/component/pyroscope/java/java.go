@@ -6,10 +6,13 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/process"
 	"github.com/grafana/agent/internal/component/pyroscope"
 	"github.com/grafana/agent/internal/component/pyroscope/java/asprof"
 	"github.com/grafana/agent/internal/featuregate"
@@ -25,6 +28,13 @@ func init() {
 		Name:      "pyroscope.java",
 		Stability: featuregate.StabilityBeta,
 		Args:      Arguments{},
+		Sandbox: component.SandboxExceptions{
+			// The profiler is copied into the filesystem of the profiled
+			// processes through /proc/<pid>/root, which resolves to paths
+			// anywhere on the host, and jattach enters their mount namespace.
+			WritePaths: []string{"/"},
+			Syscalls:   []string{"setns"},
+		},
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			if os.Getuid() != 0 {
@@ -32,6 +42,8 @@ func init() {
 			}
 			a := args.(Arguments)
 			var profiler = asprof.NewProfiler(a.TmpDir, asprof.EmbeddedArchive)
+			supervisor := process.NewSupervisor(opts.Logger, a.Process)
+			profiler.SetSupervisor(supervisor)
 			err := profiler.ExtractDistributions()
 			if err != nil {
 				return nil, fmt.Errorf("extract async profiler: %w", err)
@@ -43,6 +55,7 @@ func init() {
 				args:        a,
 				forwardTo:   forwardTo,
 				profiler:    profiler,
+				supervisor:  supervisor,
 				pid2process: make(map[int]*profilingLoop),
 			}
 			c.updateTargets(a)
@@ -56,11 +69,18 @@ type javaComponent struct {
 	args      Arguments
 	forwardTo *pyroscope.Fanout
 
-	mutex       sync.Mutex
-	pid2process map[int]*profilingLoop
-	profiler    *asprof.Profiler
+	mutex        sync.Mutex
+	pid2process  map[int]*profilingLoop
+	targetErrors []string
+	profiler     *asprof.Profiler
+	supervisor   *process.Supervisor
 }
 
+var (
+	_ component.HealthComponent = (*javaComponent)(nil)
+	_ component.DebugComponent  = (*javaComponent)(nil)
+)
+
 func (j *javaComponent) Run(ctx context.Context) error {
 	defer func() {
 		j.stop()
@@ -72,6 +92,7 @@ func (j *javaComponent) Run(ctx context.Context) error {
 func (j *javaComponent) Update(args component.Arguments) error {
 	newArgs := args.(Arguments)
 	j.forwardTo.UpdateChildren(newArgs.ForwardTo)
+	j.supervisor.Update(newArgs.Process)
 	j.updateTargets(newArgs)
 	return nil
 }
@@ -82,6 +103,7 @@ func (j *javaComponent) updateTargets(args Arguments) {
 	j.args = args
 
 	active := make(map[int]struct{})
+	j.targetErrors = nil
 	for _, target := range args.Targets {
 		pid, err := strconv.Atoi(target[labelProcessID])
 		_ = level.Debug(j.opts.Logger).Log("msg", "active target",
@@ -89,15 +111,22 @@ func (j *javaComponent) updateTargets(args Arguments) {
 			"pid", pid)
 		if err != nil {
 			_ = level.Error(j.opts.Logger).Log("msg", "invalid target", "target", fmt.Sprintf("%v", target), "err", err)
+			j.targetErrors = append(j.targetErrors, fmt.Sprintf("invalid target %v: %s", target, err))
+			continue
+		}
+		cfg, err := targetProfilingConfig(target, j.args.ProfilingConfig)
+		if err != nil {
+			_ = level.Error(j.opts.Logger).Log("msg", "invalid target", "target", fmt.Sprintf("%v", target), "err", err)
+			j.targetErrors = append(j.targetErrors, fmt.Sprintf("pid %d: %s", pid, err))
 			continue
 		}
 		proc := j.pid2process[pid]
 		if proc == nil {
-			proc = newProfilingLoop(pid, target, j.opts.Logger, j.profiler, j.forwardTo, j.args.ProfilingConfig)
+			proc = newProfilingLoop(pid, target, j.opts.Logger, j.profiler, j.forwardTo, cfg)
 			_ = level.Debug(j.opts.Logger).Log("msg", "new process", "target", fmt.Sprintf("%+v", target))
 			j.pid2process[pid] = proc
 		} else {
-			proc.update(target, j.args.ProfilingConfig)
+			proc.update(target, cfg)
 		}
 		active[pid] = struct{}{}
 	}
@@ -111,6 +140,61 @@ func (j *javaComponent) updateTargets(args Arguments) {
 	}
 }
 
+// CurrentHealth implements component.HealthComponent. The component is
+// unhealthy while any of its targets can't be profiled.
+func (j *javaComponent) CurrentHealth() component.Health {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	errs := append([]string(nil), j.targetErrors...)
+	for _, st := range j.statuses() {
+		if st.Error != "" {
+			errs = append(errs, fmt.Sprintf("pid %d: %s", st.PID, st.Error))
+		}
+	}
+	if len(errs) == 0 {
+		return component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    fmt.Sprintf("profiling %d processes", len(j.pid2process)),
+			UpdateTime: time.Now(),
+		}
+	}
+	return component.Health{
+		Health:     component.HealthTypeUnhealthy,
+		Message:    fmt.Sprintf("failed to profile %d targets, first error: %s", len(errs), errs[0]),
+		UpdateTime: time.Now(),
+	}
+}
+
+// DebugInfo implements component.DebugComponent.
+func (j *javaComponent) DebugInfo() interface{} {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return debugInfo{Targets: j.statuses()}
+}
+
+// statuses returns the status of every profiled process, ordered by PID. j.mutex
+// must be held when calling statuses.
+func (j *javaComponent) statuses() []targetStatus {
+	res := make([]targetStatus, 0, len(j.pid2process))
+	for _, proc := range j.pid2process {
+		res = append(res, proc.status())
+	}
+	sort.Slice(res, func(i, k int) bool { return res[i].PID < res[k].PID })
+	return res
+}
+
+type debugInfo struct {
+	Targets []targetStatus `river:"target,block,optional"`
+}
+
+type targetStatus struct {
+	PID         int       `river:"pid,attr"`
+	ServiceName string    `river:"service_name,attr"`
+	Error       string    `river:"error,attr,optional"`
+	LastPush    time.Time `river:"last_push,attr,optional"`
+}
+
 func (j *javaComponent) stop() {
 	_ = level.Debug(j.opts.Logger).Log("msg", "stopping")
 	j.mutex.Lock()
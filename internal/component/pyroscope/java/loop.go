@@ -40,6 +40,7 @@ type profilingLoop struct {
 	startTime  time.Time
 	profiler   *asprof.Profiler
 	sampleRate int
+	lastPush   time.Time
 }
 
 func newProfilingLoop(pid int, target discovery.Target, logger log.Logger, profiler *asprof.Profiler, output *pyroscope.Fanout, cfg ProfilingConfig) *profilingLoop {
@@ -108,6 +109,8 @@ func (p *profilingLoop) loop(ctx context.Context) {
 			if !alive {
 				return
 			}
+		} else {
+			p.onSuccess()
 		}
 	}
 }
@@ -244,17 +247,43 @@ func (p *profilingLoop) Close() error {
 
 func (p *profilingLoop) onError(err error) bool {
 	alive := p.alive()
-	if alive {
-		_ = level.Error(p.logger).Log("err", err)
-	} else {
+	if !alive {
+		// The process exited, which isn't an error of the profiler.
 		_ = level.Debug(p.logger).Log("err", err)
+		return false
 	}
+	_ = level.Error(p.logger).Log("err", err)
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 	p.error = err
 	return alive
 }
 
+func (p *profilingLoop) onSuccess() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.error = nil
+	p.lastPush = time.Now()
+}
+
+// status returns the current state of the profilingLoop.
+func (p *profilingLoop) status() targetStatus {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	st := targetStatus{
+		PID:         p.pid,
+		ServiceName: inferServiceName(p.target),
+		LastPush:    p.lastPush,
+	}
+	if p.target[labelServiceName] != "" {
+		st.ServiceName = p.target[labelServiceName]
+	}
+	if p.error != nil {
+		st.Error = p.error.Error()
+	}
+	return st
+}
+
 func (p *profilingLoop) interval() time.Duration {
 	return p.getConfig().Interval
 }
@@ -2,6 +2,8 @@ package java
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/grafana/agent/internal/component/discovery"
 )
@@ -9,8 +11,48 @@ import (
 const (
 	labelServiceName    = "service_name"
 	labelServiceNameK8s = "__meta_kubernetes_pod_annotation_pyroscope_io_service_name"
+
+	// Labels which override the profiling_config block for a single target.
+	labelInterval   = "__pyroscope_java_interval__"
+	labelSampleRate = "__pyroscope_java_sample_rate__"
+	labelAlloc      = "__pyroscope_java_alloc__"
+	labelLock       = "__pyroscope_java_lock__"
+	labelCPU        = "__pyroscope_java_cpu__"
 )
 
+// targetProfilingConfig returns the profiling configuration of target, which
+// is cfg with the settings overridden by the labels of target applied.
+func targetProfilingConfig(target discovery.Target, cfg ProfilingConfig) (ProfilingConfig, error) {
+	if v, ok := target[labelInterval]; ok {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval < time.Second {
+			return cfg, fmt.Errorf("invalid %s label %q: must be a duration of at least 1s", labelInterval, v)
+		}
+		cfg.Interval = interval
+	}
+	if v, ok := target[labelSampleRate]; ok {
+		sampleRate, err := strconv.Atoi(v)
+		if err != nil || sampleRate <= 0 {
+			return cfg, fmt.Errorf("invalid %s label %q: must be a positive integer", labelSampleRate, v)
+		}
+		cfg.SampleRate = sampleRate
+	}
+	if v, ok := target[labelAlloc]; ok {
+		cfg.Alloc = v
+	}
+	if v, ok := target[labelLock]; ok {
+		cfg.Lock = v
+	}
+	if v, ok := target[labelCPU]; ok {
+		cpu, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s label %q: must be a boolean", labelCPU, v)
+		}
+		cfg.CPU = cpu
+	}
+	return cfg, nil
+}
+
 func inferServiceName(target discovery.Target) string {
 	k8sServiceName := target[labelServiceNameK8s]
 	if k8sServiceName != "" {
@@ -0,0 +1,37 @@
+package java
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/discovery"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetProfilingConfig(t *testing.T) {
+	defaults := defaultArguments().ProfilingConfig
+
+	cfg, err := targetProfilingConfig(discovery.Target{labelProcessID: "1"}, defaults)
+	require.NoError(t, err)
+	require.Equal(t, defaults, cfg)
+
+	cfg, err = targetProfilingConfig(discovery.Target{
+		labelInterval:   "15s",
+		labelSampleRate: "250",
+		labelAlloc:      "",
+		labelCPU:        "false",
+	}, defaults)
+	require.NoError(t, err)
+	require.Equal(t, ProfilingConfig{
+		Interval:   15 * time.Second,
+		SampleRate: 250,
+		Alloc:      "",
+		Lock:       defaults.Lock,
+		CPU:        false,
+	}, cfg)
+
+	_, err = targetProfilingConfig(discovery.Target{labelSampleRate: "0"}, defaults)
+	require.ErrorContains(t, err, "invalid __pyroscope_java_sample_rate__ label")
+	_, err = targetProfilingConfig(discovery.Target{labelInterval: "10ms"}, defaults)
+	require.ErrorContains(t, err, "invalid __pyroscope_java_interval__ label")
+}
@@ -218,6 +218,10 @@ func (t *Target) Health() TargetHealth {
 
 // LabelsByProfiles returns the labels for a given ProfilingConfig.
 func LabelsByProfiles(lset labels.Labels, c *ProfilingConfig) []labels.Labels {
+	return labelsByProfilingTargets(lset, c.AllTargets())
+}
+
+func labelsByProfilingTargets(lset labels.Labels, targets map[string]ProfilingTarget) []labels.Labels {
 	res := []labels.Labels{}
 	add := func(profileType string, cfgs ...ProfilingTarget) {
 		for _, p := range cfgs {
@@ -229,13 +233,81 @@ func LabelsByProfiles(lset labels.Labels, c *ProfilingConfig) []labels.Labels {
 		}
 	}
 
-	for profilingType, profilingConfig := range c.AllTargets() {
+	for profilingType, profilingConfig := range targets {
 		add(profilingType, profilingConfig)
 	}
 
 	return res
 }
 
+// targetProfilingTargets returns the profiling targets of a single target,
+// which are the configured targets with the overrides from the labels of lset
+// applied:
+//
+//   - ProfilePathLabelPrefix<type> sets the path of a profile type, enabling
+//     it. Unknown types are added as custom profile types.
+//   - ProfileDeltaLabelPrefix<type> sets whether a profile type is a delta
+//     profile.
+//   - ProfileTypesLabel restricts the profile types scraped to the
+//     comma-separated list of types it holds.
+func targetProfilingTargets(lset labels.Labels, configured map[string]ProfilingTarget) (map[string]ProfilingTarget, error) {
+	var overridden bool
+	for _, l := range lset {
+		if l.Name == ProfileTypesLabel || strings.HasPrefix(l.Name, ProfilePathLabelPrefix) || strings.HasPrefix(l.Name, ProfileDeltaLabelPrefix) {
+			overridden = true
+		}
+	}
+	if !overridden {
+		return configured, nil
+	}
+
+	res := make(map[string]ProfilingTarget, len(configured))
+	for k, v := range configured {
+		res[k] = v
+	}
+
+	for _, l := range lset {
+		switch {
+		case strings.HasPrefix(l.Name, ProfilePathLabelPrefix):
+			profType := strings.TrimPrefix(l.Name, ProfilePathLabelPrefix)
+			t := res[profType]
+			t.Enabled, t.Path = true, l.Value
+			res[profType] = t
+		case strings.HasPrefix(l.Name, ProfileDeltaLabelPrefix):
+			profType := strings.TrimPrefix(l.Name, ProfileDeltaLabelPrefix)
+			delta, err := strconv.ParseBool(l.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q for label %s: %w", l.Value, l.Name, err)
+			}
+			t := res[profType]
+			t.Delta = delta
+			res[profType] = t
+		}
+	}
+
+	if types := lset.Get(ProfileTypesLabel); types != "" {
+		enabled := make(map[string]struct{})
+		for _, profType := range strings.Split(types, ",") {
+			profType = strings.TrimSpace(profType)
+			if t, ok := res[profType]; !ok || t.Path == "" {
+				return nil, fmt.Errorf("unknown profile type %q in label %s", profType, ProfileTypesLabel)
+			}
+			enabled[profType] = struct{}{}
+		}
+		for profType, t := range res {
+			_, t.Enabled = enabled[profType]
+			res[profType] = t
+		}
+	}
+
+	for profType, t := range res {
+		if t.Enabled && t.Path == "" {
+			return nil, fmt.Errorf("no path set for profile type %q", profType)
+		}
+	}
+	return res, nil
+}
+
 // Targets is a sortable list of targets.
 type Targets []*Target
 
@@ -244,7 +316,14 @@ func (ts Targets) Less(i, j int) bool { return ts[i].URL() < ts[j].URL() }
 func (ts Targets) Swap(i, j int)      { ts[i], ts[j] = ts[j], ts[i] }
 
 const (
-	ProfilePath         = "__profile_path__"
+	ProfilePath = "__profile_path__"
+
+	// ProfileTypesLabel, ProfilePathLabelPrefix and ProfileDeltaLabelPrefix
+	// override the profiling config for a single target.
+	ProfileTypesLabel       = "__pyroscope_profile_types__"
+	ProfilePathLabelPrefix  = "__pyroscope_profile_path_"
+	ProfileDeltaLabelPrefix = "__pyroscope_profile_delta_"
+
 	ProfileName         = "__name__"
 	serviceNameLabel    = "service_name"
 	serviceNameK8SLabel = "__meta_kubernetes_pod_annotation_pyroscope_io_service_name"
@@ -367,7 +446,11 @@ func targetsFromGroup(group *targetgroup.Group, cfg Arguments, targetTypes map[s
 		}
 
 		lset := labels.New(lbls...)
-		lsets := LabelsByProfiles(lset, &cfg.ProfilingConfig)
+		types, err := targetProfilingTargets(lset, targetTypes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("instance %d in group %s: %s", i, group, err)
+		}
+		lsets := labelsByProfilingTargets(lset, types)
 
 		for _, lset := range lsets {
 			var profType string
@@ -400,12 +483,13 @@ func targetsFromGroup(group *targetgroup.Group, cfg Arguments, targetTypes map[s
 				continue
 			}
 			if lbls != nil || origLabels != nil {
-				params := cfg.Params
-				if params == nil {
-					params = url.Values{}
+				// params is copied as it's modified for delta profiles.
+				params := url.Values{}
+				for k, v := range cfg.Params {
+					params[k] = append([]string(nil), v...)
 				}
 
-				if pcfg, found := targetTypes[profType]; found && pcfg.Delta {
+				if pcfg, found := types[profType]; found && pcfg.Delta {
 					params.Add("seconds", strconv.Itoa(int((cfg.ScrapeInterval)/time.Second)-1))
 				}
 				targets = append(targets, NewTarget(lbls, origLabels, params))
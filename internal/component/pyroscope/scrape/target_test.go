@@ -236,3 +236,71 @@ func Test_targetsFromGroup(t *testing.T) {
 	require.Equal(t, expected, active)
 	require.Empty(t, dropped)
 }
+
+func Test_targetProfilingTargets(t *testing.T) {
+	configured := map[string]ProfilingTarget{
+		pprofMemory:     {Enabled: true, Path: "/debug/pprof/allocs"},
+		pprofProcessCPU: {Enabled: true, Path: "/debug/pprof/profile", Delta: true},
+		pprofMutex:      {Enabled: false, Path: "/debug/pprof/mutex", Delta: true},
+	}
+
+	tt := []struct {
+		name     string
+		labels   map[string]string
+		expected map[string]ProfilingTarget
+		err      string
+	}{
+		{
+			name:     "no overrides",
+			labels:   map[string]string{"foo": "bar"},
+			expected: configured,
+		},
+		{
+			name: "path override and custom type",
+			labels: map[string]string{
+				ProfilePathLabelPrefix + pprofMutex: "/custom/mutex",
+				ProfilePathLabelPrefix + "custom":   "/custom/profile",
+				ProfileDeltaLabelPrefix + "custom":  "true",
+			},
+			expected: map[string]ProfilingTarget{
+				pprofMemory:     {Enabled: true, Path: "/debug/pprof/allocs"},
+				pprofProcessCPU: {Enabled: true, Path: "/debug/pprof/profile", Delta: true},
+				pprofMutex:      {Enabled: true, Path: "/custom/mutex", Delta: true},
+				"custom":        {Enabled: true, Path: "/custom/profile", Delta: true},
+			},
+		},
+		{
+			name: "restricted types",
+			labels: map[string]string{
+				ProfileTypesLabel: pprofMutex + ", " + pprofMemory,
+			},
+			expected: map[string]ProfilingTarget{
+				pprofMemory:     {Enabled: true, Path: "/debug/pprof/allocs"},
+				pprofProcessCPU: {Enabled: false, Path: "/debug/pprof/profile", Delta: true},
+				pprofMutex:      {Enabled: true, Path: "/debug/pprof/mutex", Delta: true},
+			},
+		},
+		{
+			name:   "invalid delta",
+			labels: map[string]string{ProfileDeltaLabelPrefix + pprofMemory: "maybe"},
+			err:    `invalid value "maybe" for label __pyroscope_profile_delta_memory`,
+		},
+		{
+			name:   "unknown type",
+			labels: map[string]string{ProfileTypesLabel: "unknown"},
+			err:    `unknown profile type "unknown" in label __pyroscope_profile_types__`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := targetProfilingTargets(labels.FromMap(tc.labels), configured)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
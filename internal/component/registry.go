@@ -3,8 +3,10 @@ package component
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/internal/featuregate"
@@ -127,6 +129,10 @@ type Registration struct {
 	// This field must be set to a non-zero value.
 	Stability featuregate.Stability
 
+	// Deprecated, if set, marks the component as deprecated. It should
+	// explain what to use instead, such as "replaced by import.git".
+	Deprecated string
+
 	// An example Arguments value that the registered component expects to
 	// receive as input. Components should provide the zero value of their
 	// Arguments type here.
@@ -136,11 +142,71 @@ type Registration struct {
 	// A component which does not expose exports must leave this set to nil.
 	Exports Exports
 
+	// CheckSecrets, if set, verifies that the secrets referenced by args can
+	// be resolved without building the component. It is only invoked before
+	// the component is first built, and only when secret reference checking is
+	// enabled for the controller.
+	CheckSecrets func(ctx context.Context, args Arguments) error
+
+	// Sandbox declares the exceptions the component needs when the agent runs
+	// with self-sandboxing enabled. Most components can leave this empty.
+	Sandbox SandboxExceptions
+
+	// MinEvaluationInterval, if set, is the minimum time between two
+	// evaluations of the component caused by changes of the exports it
+	// depends on. Changes arriving sooner are coalesced into a single
+	// evaluation once the interval elapses. Components whose dependencies
+	// change rapidly, such as those consuming fast-churning discovery
+	// targets, can set it to avoid evaluation storms.
+	MinEvaluationInterval time.Duration
+
+	// Lazy marks components which only do work on behalf of the components
+	// referencing their exports, such as exporters which are only useful when
+	// scraped. When lazy components are enabled for the controller, a lazy
+	// component isn't started while nothing references its exports.
+	// Components with side effects, such as those sending data elsewhere, must
+	// leave this unset.
+	Lazy bool
+
 	// Build should construct a new component from an initial Arguments and set
 	// of options.
 	Build func(opts Options, args Arguments) (Component, error)
 }
 
+// SandboxExceptions describes access a component requires beyond what is
+// granted by the process sandbox by default.
+type SandboxExceptions struct {
+	// ReadPaths are extra paths the component must be able to read.
+	ReadPaths []string
+	// WritePaths are extra paths the component must be able to write to.
+	WritePaths []string
+	// Syscalls are the names of system calls, such as "bpf", that the
+	// component must be able to make.
+	Syscalls []string
+	// SocketFamilies are the names of extra socket address families, such as
+	// "packet", the component must be able to create sockets for.
+	SocketFamilies []string
+
+	// ArgumentReadPaths optionally returns extra paths to read which depend
+	// on the arguments of a component, such as the file it watches. Arguments
+	// named like *_file, such as TLS certificates, are always readable and
+	// don't need to be returned.
+	ArgumentReadPaths func(args Arguments) []string
+	// ArgumentWritePaths optionally returns extra paths to write to which
+	// depend on the arguments of a component, such as the file it writes.
+	ArgumentWritePaths func(args Arguments) []string
+}
+
+// SandboxGlobDir returns the path to grant sandbox access to for files
+// matching the glob pattern: the deepest directory of pattern without glob
+// characters, or pattern itself if it has none.
+func SandboxGlobDir(pattern string) string {
+	if i := strings.IndexAny(pattern, "*?[{"); i >= 0 {
+		return filepath.Dir(pattern[:i+1])
+	}
+	return pattern
+}
+
 // CloneArguments returns a new zero value of the registered Arguments type.
 func (r Registration) CloneArguments() Arguments {
 	return reflect.New(reflect.TypeOf(r.Args)).Interface()
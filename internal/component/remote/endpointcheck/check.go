@@ -0,0 +1,202 @@
+package endpointcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Stage is a step of checking the reachability of an endpoint.
+type Stage string
+
+// Stages of a check, in the order they run.
+const (
+	StageParse   Stage = "parse"   // The address of the endpoint is parsed.
+	StageDNS     Stage = "dns"     // The host of the endpoint is resolved.
+	StageTCP     Stage = "tcp"     // A TCP connection is opened.
+	StageTLS     Stage = "tls"     // A TLS handshake is performed.
+	StageRequest Stage = "request" // An HTTP request is sent.
+	StageAuth    Stage = "auth"    // The HTTP request is authorized.
+)
+
+// Stages lists the stages in the order they run.
+var Stages = []Stage{StageParse, StageDNS, StageTCP, StageTLS, StageRequest, StageAuth}
+
+// Endpoint is an endpoint to check.
+type Endpoint struct {
+	// Address of the endpoint, either an http or https URL, or a host:port
+	// pair for endpoints which don't use HTTP such as OTLP gRPC endpoints.
+	Address string
+
+	// Whether a TLS handshake is performed with host:port endpoints. URLs use
+	// TLS when their scheme is https.
+	TLS bool
+
+	// TLSConfig used for the TLS handshake. May be nil.
+	TLSConfig *tls.Config
+
+	// Client sends the HTTP request to URL endpoints. If nil, the request is
+	// sent without credentials.
+	Client *http.Client
+}
+
+// Result is the result of checking an endpoint.
+type Result struct {
+	// Stage which failed. Empty if the check succeeded.
+	FailedStage Stage
+	// Why FailedStage failed.
+	Err error
+
+	// Addresses the host of the endpoint resolved to.
+	Addrs []string
+	// Expiry of the certificate of the endpoint. Zero if no TLS handshake was
+	// performed.
+	CertificateExpiry time.Time
+	// Status code of the response to the HTTP request. Zero if no request was
+	// sent.
+	StatusCode int
+	// Time taken by the check.
+	Duration time.Duration
+}
+
+// Success returns whether all the stages of the check succeeded.
+func (r Result) Success() bool { return r.FailedStage == "" }
+
+// Check checks whether e is reachable. The host of e is resolved, then a TCP
+// connection and a TLS handshake are made to it. For URLs, an HTTP GET
+// request is finally sent, which fails the check if it's rejected as
+// unauthorized or forbidden. Other status codes, such as 405 for endpoints
+// which only accept POST requests, show the endpoint is reachable.
+func Check(ctx context.Context, e Endpoint) Result {
+	start := time.Now()
+	res := check(ctx, e)
+	res.Duration = time.Since(start)
+	return res
+}
+
+func check(ctx context.Context, e Endpoint) (res Result) {
+	fail := func(stage Stage, err error) Result {
+		res.FailedStage, res.Err = stage, err
+		return res
+	}
+
+	u, host, port, useTLS, err := parseAddress(e)
+	if err != nil {
+		return fail(StageParse, err)
+	}
+
+	res.Addrs, err = net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return fail(StageDNS, fmt.Errorf("resolving %q: %w", host, unwrapDNSError(err)))
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return fail(StageTCP, fmt.Errorf("connecting to %s: %w", net.JoinHostPort(host, port), unwrapOpError(err)))
+	}
+	defer conn.Close()
+
+	if useTLS {
+		cfg := &tls.Config{}
+		if e.TLSConfig != nil {
+			cfg = e.TLSConfig.Clone()
+		}
+		if cfg.ServerName == "" {
+			cfg.ServerName = host
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fail(StageTLS, fmt.Errorf("TLS handshake with %s: %w", cfg.ServerName, err))
+		}
+		if certs := tlsConn.ConnectionState().PeerCertificates; len(certs) > 0 {
+			res.CertificateExpiry = certs[0].NotAfter
+		}
+	}
+
+	if u == nil {
+		return res
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fail(StageRequest, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fail(StageRequest, err)
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+
+	res.StatusCode = resp.StatusCode
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return fail(StageAuth, fmt.Errorf("server responded with %s: credentials are missing or invalid", resp.Status))
+	case http.StatusForbidden:
+		return fail(StageAuth, fmt.Errorf("server responded with %s: credentials lack permissions", resp.Status))
+	}
+	return res
+}
+
+// parseAddress parses the address of e. u is nil for host:port addresses.
+func parseAddress(e Endpoint) (u *url.URL, host, port string, useTLS bool, err error) {
+	if !strings.Contains(e.Address, "://") {
+		host, port, err = net.SplitHostPort(e.Address)
+		if err != nil {
+			return nil, "", "", false, fmt.Errorf("%q must be a URL or a host:port pair: %w", e.Address, err)
+		}
+		return nil, host, port, e.TLS, nil
+	}
+
+	u, err = url.Parse(e.Address)
+	if err != nil {
+		return nil, "", "", false, err
+	}
+	switch u.Scheme {
+	case "http":
+		port = "80"
+	case "https":
+		port, useTLS = "443", true
+	default:
+		return nil, "", "", false, fmt.Errorf("unsupported scheme %q, must be http or https", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	if u.Hostname() == "" {
+		return nil, "", "", false, fmt.Errorf("%q has no host", e.Address)
+	}
+	return u, u.Hostname(), port, useTLS, nil
+}
+
+// unwrapDNSError returns the reason of DNS errors, without the name of the
+// host which is already part of the message of the check.
+func unwrapDNSError(err error) error {
+	if dnsErr, ok := err.(*net.DNSError); ok {
+		if dnsErr.IsNotFound {
+			return fmt.Errorf("no such host")
+		}
+		return fmt.Errorf("%s", dnsErr.Err)
+	}
+	return err
+}
+
+// unwrapOpError returns the underlying error of network operation errors,
+// such as "connection refused".
+func unwrapOpError(err error) error {
+	if opErr, ok := err.(*net.OpError); ok && opErr.Err != nil {
+		return opErr.Err
+	}
+	return err
+}
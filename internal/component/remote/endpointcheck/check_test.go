@@ -0,0 +1,74 @@
+package endpointcheck
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer srv.Close()
+
+	t.Run("unauthorized", func(t *testing.T) {
+		res := Check(context.Background(), Endpoint{Address: srv.URL})
+		require.Equal(t, StageAuth, res.FailedStage)
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		require.ErrorContains(t, res.Err, "credentials are missing or invalid")
+	})
+
+	t.Run("authorized", func(t *testing.T) {
+		client := &http.Client{Transport: basicAuthTransport{}}
+		res := Check(context.Background(), Endpoint{Address: srv.URL, Client: client})
+		require.True(t, res.Success(), "unexpected failure: %v", res.Err)
+		require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+		require.NotEmpty(t, res.Addrs)
+	})
+
+	t.Run("host and port", func(t *testing.T) {
+		res := Check(context.Background(), Endpoint{Address: srv.Listener.Addr().String()})
+		require.True(t, res.Success(), "unexpected failure: %v", res.Err)
+		require.Zero(t, res.StatusCode)
+	})
+
+	t.Run("TLS to plain server", func(t *testing.T) {
+		res := Check(context.Background(), Endpoint{Address: srv.Listener.Addr().String(), TLS: true})
+		require.Equal(t, StageTLS, res.FailedStage)
+	})
+}
+
+func TestCheck_ConnectionRefused(t *testing.T) {
+	// Find a port nothing listens on.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	res := Check(context.Background(), Endpoint{Address: "http://" + addr})
+	require.Equal(t, StageTCP, res.FailedStage)
+}
+
+func TestCheck_InvalidAddress(t *testing.T) {
+	for _, address := range []string{"ftp://example.com", "example.com", "http://"} {
+		res := Check(context.Background(), Endpoint{Address: address})
+		require.Equal(t, StageParse, res.FailedStage, address)
+	}
+}
+
+type basicAuthTransport struct{}
+
+func (basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.SetBasicAuth("user", "pass")
+	return http.DefaultTransport.RoundTrip(req)
+}
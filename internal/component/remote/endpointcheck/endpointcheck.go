@@ -0,0 +1,362 @@
+// Package endpointcheck implements the remote.endpoint_check component.
+package endpointcheck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	common_config "github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_config "github.com/prometheus/common/config"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "remote.endpoint_check",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the
+// remote.endpoint_check component.
+type Arguments struct {
+	Endpoints []EndpointArguments `river:"endpoint,block,optional"`
+	Interval  time.Duration       `river:"interval,attr,optional"`
+	Timeout   time.Duration       `river:"timeout,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Interval: time.Minute,
+	Timeout:  10 * time.Second,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if args.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	if args.Timeout >= args.Interval {
+		return fmt.Errorf("timeout must be less than interval")
+	}
+
+	names := make(map[string]struct{}, len(args.Endpoints))
+	for _, e := range args.Endpoints {
+		name := e.name()
+		if _, exists := names[name]; exists {
+			return fmt.Errorf("found multiple endpoints named %q", name)
+		}
+		names[name] = struct{}{}
+	}
+	return nil
+}
+
+// EndpointArguments configures a single endpoint to check.
+type EndpointArguments struct {
+	Name    string                         `river:"name,attr,optional"`
+	Address string                         `river:"address,attr"`
+	TLS     bool                           `river:"tls,attr,optional"`
+	Client  common_config.HTTPClientConfig `river:"client,block,optional"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (e *EndpointArguments) SetToDefault() {
+	*e = EndpointArguments{Client: common_config.DefaultHTTPClientConfig}
+}
+
+// Validate implements river.Validator.
+func (e *EndpointArguments) Validate() error {
+	if _, _, _, _, err := parseAddress(Endpoint{Address: e.Address}); err != nil {
+		return err
+	}
+	return e.Client.Validate()
+}
+
+// name returns the name of the endpoint, which defaults to its address.
+func (e *EndpointArguments) name() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.Address
+}
+
+// endpoint builds the Endpoint to check from e.
+func (e *EndpointArguments) endpoint() (Endpoint, error) {
+	cfg := e.Client.Convert()
+	client, err := prom_config.NewClientFromConfig(*cfg, "remote.endpoint_check")
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("building client for endpoint %q: %w", e.name(), err)
+	}
+	tlsConfig, err := prom_config.NewTLSConfig(&cfg.TLSConfig)
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("building TLS config for endpoint %q: %w", e.name(), err)
+	}
+	return Endpoint{
+		Address:   e.Address,
+		TLS:       e.TLS,
+		TLSConfig: tlsConfig,
+		Client:    client,
+	}, nil
+}
+
+// Component implements the remote.endpoint_check component.
+type Component struct {
+	opts component.Options
+
+	success    *prometheus.GaugeVec
+	duration   *prometheus.GaugeVec
+	certExpiry *prometheus.GaugeVec
+	failures   *prometheus.CounterVec
+
+	// Updated is written to whenever args updates.
+	updated chan struct{}
+
+	endpointMut sync.Mutex
+	args        Arguments
+	endpoints   map[string]Endpoint // Endpoints to check by name.
+
+	resultsMut sync.RWMutex
+	results    map[string]Result // Last result of each endpoint by name.
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+	_ component.DebugComponent  = (*Component)(nil)
+)
+
+// New creates a new remote.endpoint_check component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    o,
+		updated: make(chan struct{}, 1),
+		results: make(map[string]Result),
+
+		success: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "agent_remote_endpoint_check_success",
+			Help: "Whether the last check of the endpoint succeeded",
+		}, []string{"endpoint"}),
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "agent_remote_endpoint_check_duration_seconds",
+			Help: "Duration of the last check of the endpoint",
+		}, []string{"endpoint"}),
+		certExpiry: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "agent_remote_endpoint_check_certificate_expiry_timestamp_seconds",
+			Help: "Expiry of the TLS certificate of the endpoint as of the last check",
+		}, []string{"endpoint"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_remote_endpoint_check_failures_total",
+			Help: "Total number of failed checks of the endpoint by failed stage",
+		}, []string{"endpoint", "stage"}),
+	}
+	for _, metric := range []prometheus.Collector{c.success, c.duration, c.certExpiry, c.failures} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component. Endpoints are checked immediately
+// after each update, since New always updates the component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		c.endpointMut.Lock()
+		interval := c.args.Interval
+		c.endpointMut.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		case <-c.updated:
+		}
+		c.checkAll(ctx)
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	endpoints := make(map[string]Endpoint, len(newArgs.Endpoints))
+	for _, e := range newArgs.Endpoints {
+		endpoint, err := e.endpoint()
+		if err != nil {
+			return err
+		}
+		endpoints[e.name()] = endpoint
+	}
+
+	c.endpointMut.Lock()
+	for name := range c.endpoints {
+		if _, ok := endpoints[name]; !ok {
+			c.forget(name)
+		}
+	}
+	c.args = newArgs
+	c.endpoints = endpoints
+	c.endpointMut.Unlock()
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// forget removes the metrics and the last result of the endpoint name.
+func (c *Component) forget(name string) {
+	labels := prometheus.Labels{"endpoint": name}
+	c.success.DeletePartialMatch(labels)
+	c.duration.DeletePartialMatch(labels)
+	c.certExpiry.DeletePartialMatch(labels)
+	c.failures.DeletePartialMatch(labels)
+
+	c.resultsMut.Lock()
+	delete(c.results, name)
+	c.resultsMut.Unlock()
+}
+
+// checkAll concurrently checks every endpoint and records the results.
+func (c *Component) checkAll(ctx context.Context) {
+	c.endpointMut.Lock()
+	endpoints := c.endpoints
+	timeout := c.args.Timeout
+	c.endpointMut.Unlock()
+
+	var wg sync.WaitGroup
+	for name, e := range endpoints {
+		wg.Add(1)
+		go func(name string, e Endpoint) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			c.record(name, Check(checkCtx, e))
+		}(name, e)
+	}
+	wg.Wait()
+}
+
+// record records the result of checking the endpoint name. Results of
+// endpoints removed while they were checked are dropped.
+func (c *Component) record(name string, res Result) {
+	c.endpointMut.Lock()
+	defer c.endpointMut.Unlock()
+	if _, ok := c.endpoints[name]; !ok {
+		return
+	}
+
+	c.duration.WithLabelValues(name).Set(res.Duration.Seconds())
+	if res.Success() {
+		c.success.WithLabelValues(name).Set(1)
+	} else {
+		c.success.WithLabelValues(name).Set(0)
+		c.failures.WithLabelValues(name, string(res.FailedStage)).Inc()
+		level.Warn(c.opts.Logger).Log("msg", "endpoint check failed", "endpoint", name, "stage", res.FailedStage, "err", res.Err)
+	}
+	if !res.CertificateExpiry.IsZero() {
+		c.certExpiry.WithLabelValues(name).Set(float64(res.CertificateExpiry.Unix()))
+	}
+
+	c.resultsMut.Lock()
+	defer c.resultsMut.Unlock()
+	c.results[name] = res
+}
+
+// CurrentHealth implements component.HealthComponent. The component is
+// unhealthy when any endpoint failed its last check.
+func (c *Component) CurrentHealth() component.Health {
+	c.resultsMut.RLock()
+	defer c.resultsMut.RUnlock()
+
+	var failed []string
+	for name, res := range c.results {
+		if !res.Success() {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+
+	if len(failed) > 0 {
+		return component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("endpoints failed their last check: %s", strings.Join(failed, ", ")),
+			UpdateTime: time.Now(),
+		}
+	}
+	return component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    "all endpoints passed their last check",
+		UpdateTime: time.Now(),
+	}
+}
+
+// DebugInfo implements component.DebugComponent.
+func (c *Component) DebugInfo() interface{} {
+	c.resultsMut.RLock()
+	defer c.resultsMut.RUnlock()
+
+	info := debugInfo{Endpoints: make([]endpointDebugInfo, 0, len(c.results))}
+	for name, res := range c.results {
+		ei := endpointDebugInfo{
+			Name:       name,
+			Success:    res.Success(),
+			Addrs:      res.Addrs,
+			StatusCode: res.StatusCode,
+			Duration:   res.Duration.String(),
+		}
+		if res.Err != nil {
+			ei.FailedStage = string(res.FailedStage)
+			ei.Error = res.Err.Error()
+		}
+		if !res.CertificateExpiry.IsZero() {
+			ei.CertificateExpiry = res.CertificateExpiry.UTC().Format(time.RFC3339)
+		}
+		info.Endpoints = append(info.Endpoints, ei)
+	}
+	sort.Slice(info.Endpoints, func(i, j int) bool {
+		return info.Endpoints[i].Name < info.Endpoints[j].Name
+	})
+	return info
+}
+
+type debugInfo struct {
+	Endpoints []endpointDebugInfo `river:"endpoint,block"`
+}
+
+type endpointDebugInfo struct {
+	Name              string   `river:"name,attr"`
+	Success           bool     `river:"success,attr"`
+	FailedStage       string   `river:"failed_stage,attr,optional"`
+	Error             string   `river:"error,attr,optional"`
+	Addrs             []string `river:"addresses,attr,optional"`
+	CertificateExpiry string   `river:"certificate_expiry,attr,optional"`
+	StatusCode        int      `river:"status_code,attr,optional"`
+	Duration          string   `river:"duration,attr"`
+}
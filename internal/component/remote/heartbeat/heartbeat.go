@@ -0,0 +1,355 @@
+// Package heartbeat implements the remote.heartbeat component.
+package heartbeat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/agentseed"
+	"github.com/grafana/agent/internal/build"
+	"github.com/grafana/agent/internal/component"
+	common_config "github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	http_service "github.com/grafana/agent/internal/service/http"
+	"github.com/grafana/agent/internal/useragent"
+	"github.com/grafana/dskit/backoff"
+	"github.com/prometheus/client_golang/prometheus"
+	prom_config "github.com/prometheus/common/config"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "remote.heartbeat",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the remote.heartbeat
+// component.
+type Arguments struct {
+	URL        string                         `river:"url,attr"`
+	ID         string                         `river:"id,attr,optional"`
+	Metadata   map[string]string              `river:"metadata,attr,optional"`
+	Interval   time.Duration                  `river:"interval,attr,optional"`
+	Timeout    time.Duration                  `river:"timeout,attr,optional"`
+	MinBackoff time.Duration                  `river:"min_backoff_period,attr,optional"`
+	MaxBackoff time.Duration                  `river:"max_backoff_period,attr,optional"`
+	Client     common_config.HTTPClientConfig `river:"client,block,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Interval:   time.Minute,
+	Timeout:    10 * time.Second,
+	MinBackoff: time.Second,
+	MaxBackoff: 5 * time.Minute,
+	Client:     common_config.DefaultHTTPClientConfig,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	if args.Timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0")
+	}
+	if args.MinBackoff <= 0 {
+		return fmt.Errorf("min_backoff_period must be greater than 0")
+	}
+	if args.MaxBackoff < args.MinBackoff {
+		return fmt.Errorf("max_backoff_period must not be less than min_backoff_period")
+	}
+	return args.Client.Validate()
+}
+
+// Heartbeat is the JSON payload sent to the configured endpoint.
+type Heartbeat struct {
+	ID         string            `json:"id"`
+	Version    string            `json:"version"`
+	ConfigHash string            `json:"config_hash"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Metadata   map[string]string `json:"metadata"`
+	Host       HostInfo          `json:"host"`
+	Health     HealthSummary     `json:"health"`
+}
+
+// HostInfo describes the host the agent is running on.
+type HostInfo struct {
+	Hostname string `json:"hostname"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+}
+
+// HealthSummary counts the components of the agent by health, including the
+// components of nested modules.
+type HealthSummary struct {
+	Healthy   int `json:"healthy"`
+	Unhealthy int `json:"unhealthy"`
+	Unknown   int `json:"unknown"`
+	Exited    int `json:"exited"`
+
+	// IDs of the components which are unhealthy or exited.
+	FailingComponents []string `json:"failing_components"`
+}
+
+// Component implements the remote.heartbeat component.
+type Component struct {
+	opts     component.Options
+	httpData http_service.Data
+
+	sent     prometheus.Counter
+	failed   prometheus.Counter
+	lastSent prometheus.Gauge
+
+	// Updated is written to whenever args updates.
+	updated chan struct{}
+
+	mut    sync.RWMutex
+	args   Arguments
+	client *http.Client
+
+	healthMut sync.RWMutex
+	lastErr   error // Error of the last heartbeat.
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new remote.heartbeat component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	data, err := o.GetServiceData(http_service.ServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HTTP information: %w", err)
+	}
+
+	c := &Component{
+		opts:     o,
+		httpData: data.(http_service.Data),
+		updated:  make(chan struct{}, 1),
+
+		sent: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_remote_heartbeat_sent_total",
+			Help: "Total number of heartbeats successfully sent",
+		}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_remote_heartbeat_failures_total",
+			Help: "Total number of heartbeats which failed to send",
+		}),
+		lastSent: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_remote_heartbeat_last_success_timestamp_seconds",
+			Help: "Timestamp of the last heartbeat which was successfully sent",
+		}),
+	}
+	for _, metric := range []prometheus.Collector{c.sent, c.failed, c.lastSent} {
+		if err := o.Registerer.Register(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component. A heartbeat is sent immediately after
+// each update, since New always updates the component. Failed heartbeats are
+// retried with backoff instead of waiting for the next interval.
+func (c *Component) Run(ctx context.Context) error {
+	var (
+		timer = time.NewTimer(0)
+		bo    = c.newBackoff(ctx)
+	)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-c.updated:
+			bo = c.newBackoff(ctx)
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(0)
+
+		case <-timer.C:
+			c.mut.RLock()
+			args, client := c.args, c.client
+			c.mut.RUnlock()
+
+			err := c.send(ctx, args, client)
+			c.healthMut.Lock()
+			c.lastErr = err
+			c.healthMut.Unlock()
+
+			if err != nil {
+				c.failed.Inc()
+				delay := bo.NextDelay()
+				level.Warn(c.opts.Logger).Log("msg", "failed to send heartbeat", "url", args.URL, "err", err, "retry_in", delay)
+				timer.Reset(delay)
+				continue
+			}
+
+			c.sent.Inc()
+			c.lastSent.SetToCurrentTime()
+			bo.Reset()
+			timer.Reset(args.Interval)
+		}
+	}
+}
+
+func (c *Component) newBackoff(ctx context.Context) *backoff.Backoff {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	return backoff.New(ctx, backoff.Config{
+		MinBackoff: c.args.MinBackoff,
+		MaxBackoff: c.args.MaxBackoff,
+	})
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	client, err := prom_config.NewClientFromConfig(*newArgs.Client.Convert(), c.opts.ID)
+	if err != nil {
+		return err
+	}
+
+	c.mut.Lock()
+	c.args = newArgs
+	c.client = client
+	c.mut.Unlock()
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// send sends a single heartbeat.
+func (c *Component) send(ctx context.Context, args Arguments, client *http.Client) error {
+	body, err := json.Marshal(c.buildHeartbeat(args))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, args.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, args.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", useragent.Get())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *Component) buildHeartbeat(args Arguments) Heartbeat {
+	hb := Heartbeat{
+		ID:         args.ID,
+		Version:    build.Version,
+		ConfigHash: c.httpData.ConfigHash(),
+		Timestamp:  time.Now().UTC(),
+		Metadata:   args.Metadata,
+		Host: HostInfo{
+			OS:   runtime.GOOS,
+			Arch: runtime.GOARCH,
+		},
+		Health: summarizeHealth(c.httpData.ComponentHealth()),
+	}
+	if hb.ID == "" {
+		hb.ID = agentseed.Get().UID
+	}
+	if hb.Metadata == nil {
+		hb.Metadata = map[string]string{}
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		hb.Host.Hostname = hostname
+	}
+	return hb
+}
+
+// summarizeHealth counts the components by their health.
+func summarizeHealth(infos []*component.Info) HealthSummary {
+	summary := HealthSummary{FailingComponents: []string{}}
+	for _, info := range infos {
+		switch info.Health.Health {
+		case component.HealthTypeHealthy:
+			summary.Healthy++
+			continue
+		case component.HealthTypeUnhealthy:
+			summary.Unhealthy++
+		case component.HealthTypeExited:
+			summary.Exited++
+		default:
+			summary.Unknown++
+			continue
+		}
+		summary.FailingComponents = append(summary.FailingComponents, info.ID.String())
+	}
+	sort.Strings(summary.FailingComponents)
+	return summary
+}
+
+// CurrentHealth implements component.HealthComponent. The component is
+// unhealthy when the last heartbeat failed to send.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+
+	if c.lastErr != nil {
+		return component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("last heartbeat failed to send: %s", c.lastErr),
+			UpdateTime: time.Now(),
+		}
+	}
+	return component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    "no heartbeat failed to send",
+		UpdateTime: time.Now(),
+	}
+}
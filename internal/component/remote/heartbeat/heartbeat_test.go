@@ -0,0 +1,136 @@
+package heartbeat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	http_service "github.com/grafana/agent/internal/service/http"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeat(t *testing.T) {
+	received := make(chan Heartbeat, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var hb Heartbeat
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&hb))
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		received <- hb
+	}))
+	defer srv.Close()
+
+	args := DefaultArguments
+	args.URL = srv.URL
+	args.ID = "agent-1"
+	args.Metadata = map[string]string{"cluster": "dev"}
+	c, err := New(testOptions(t), args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { require.NoError(t, c.Run(ctx)) }()
+
+	select {
+	case hb := <-received:
+		require.Equal(t, "agent-1", hb.ID)
+		require.Equal(t, "abc123", hb.ConfigHash)
+		require.Equal(t, map[string]string{"cluster": "dev"}, hb.Metadata)
+		require.NotEmpty(t, hb.Host.OS)
+		require.Equal(t, HealthSummary{
+			Healthy:           2,
+			Unhealthy:         1,
+			Exited:            0,
+			Unknown:           0,
+			FailingComponents: []string{"module.file.inner/remote.http.broken"},
+		}, hb.Health)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no heartbeat received")
+	}
+}
+
+func TestHeartbeat_Backoff(t *testing.T) {
+	attempts := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts <- struct{}{}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	args := DefaultArguments
+	args.URL = srv.URL
+	args.Interval = time.Hour
+	args.MinBackoff = 10 * time.Millisecond
+	args.MaxBackoff = 20 * time.Millisecond
+	c, err := New(testOptions(t), args)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { require.NoError(t, c.Run(ctx)) }()
+
+	// Failed heartbeats are retried well before the interval elapses.
+	for i := 0; i < 3; i++ {
+		select {
+		case <-attempts:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "heartbeat was not retried")
+		}
+	}
+	require.Equal(t, component.HealthTypeUnhealthy, c.CurrentHealth().Health)
+}
+
+func TestRiverConfig(t *testing.T) {
+	cfg := `
+		url      = "https://fleet.example.com/heartbeat"
+		interval = "30s"
+
+		client {
+			tls_config {
+				cert_file = "/etc/agent/client.crt"
+				key_file  = "/etc/agent/client.key"
+			}
+		}
+	`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+	require.Equal(t, 30*time.Second, args.Interval)
+	require.Equal(t, 10*time.Second, args.Timeout)
+	require.Equal(t, "/etc/agent/client.crt", args.Client.TLSConfig.CertFile)
+
+	cfg = `
+		url                = "https://fleet.example.com/heartbeat"
+		min_backoff_period = "1m"
+		max_backoff_period = "1s"
+	`
+	require.ErrorContains(t, river.Unmarshal([]byte(cfg), &args), "max_backoff_period must not be less than min_backoff_period")
+}
+
+func testOptions(t *testing.T) component.Options {
+	httpData := http_service.Data{
+		ComponentHealth: func() []*component.Info {
+			return []*component.Info{
+				{ID: component.ID{LocalID: "prometheus.scrape.default"}, Health: component.Health{Health: component.HealthTypeHealthy}},
+				{ID: component.ID{LocalID: "module.file.inner"}, Health: component.Health{Health: component.HealthTypeHealthy}},
+				{ID: component.ID{ModuleID: "module.file.inner", LocalID: "remote.http.broken"}, Health: component.Health{Health: component.HealthTypeUnhealthy}},
+			}
+		},
+		ConfigHash: func() string { return "abc123" },
+	}
+
+	return component.Options{
+		ID:            "remote.heartbeat.test",
+		Logger:        util.TestFlowLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+		GetServiceData: func(name string) (interface{}, error) {
+			return httpData, nil
+		},
+	}
+}
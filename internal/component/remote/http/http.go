@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/internal/component"
 	common_config "github.com/grafana/agent/internal/component/common/config"
@@ -45,6 +48,20 @@ type Arguments struct {
 	Headers map[string]string `river:"headers,attr,optional"`
 	Body    string            `river:"body,attr,optional"`
 
+	// Status codes of successful responses.
+	AllowedStatusCodes []int `river:"allowed_status_codes,attr,optional"`
+
+	// Format to parse the response body as.
+	Format string `river:"format,attr,optional"`
+
+	// Backoff between polls after failures. Failed polls are retried after
+	// PollFrequency when MinBackoffPeriod is zero.
+	MinBackoffPeriod time.Duration `river:"min_backoff_period,attr,optional"`
+	MaxBackoffPeriod time.Duration `river:"max_backoff_period,attr,optional"`
+
+	// Maximum size of the response body. Zero doesn't limit the size.
+	MaxResponseSize units.Base2Bytes `river:"max_response_size,attr,optional"`
+
 	Client common_config.HTTPClientConfig `river:"client,block,optional"`
 }
 
@@ -54,6 +71,10 @@ var DefaultArguments = Arguments{
 	PollTimeout:   10 * time.Second,
 	Client:        common_config.DefaultHTTPClientConfig,
 	Method:        http.MethodGet,
+
+	AllowedStatusCodes: []int{http.StatusOK},
+	Format:             FormatText,
+	MaxBackoffPeriod:   5 * time.Minute,
 }
 
 // SetToDefault implements river.Defaulter.
@@ -77,12 +98,42 @@ func (args *Arguments) Validate() error {
 		return err
 	}
 
+	if args.MaxResponseSize < 0 {
+		return fmt.Errorf("max_response_size must not be negative")
+	}
+
+	if len(args.AllowedStatusCodes) == 0 {
+		return fmt.Errorf("allowed_status_codes must not be empty")
+	}
+	for _, code := range args.AllowedStatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("invalid status code %d in allowed_status_codes", code)
+		}
+	}
+
+	switch args.Format {
+	case FormatText, FormatJSON, FormatYAML:
+	default:
+		return fmt.Errorf("format must be one of %q, %q, or %q", FormatText, FormatJSON, FormatYAML)
+	}
+
+	if args.MinBackoffPeriod < 0 {
+		return fmt.Errorf("min_backoff_period must not be negative")
+	}
+	if args.MinBackoffPeriod > 0 && args.MaxBackoffPeriod < args.MinBackoffPeriod {
+		return fmt.Errorf("max_backoff_period must not be less than min_backoff_period")
+	}
+
 	return nil
 }
 
 // Exports holds settings exported by remote.http.
 type Exports struct {
 	Content rivertypes.OptionalSecret `river:"content,attr"`
+
+	// Parsed holds the response body parsed according to the format
+	// argument. It's nil when the format is text.
+	Parsed any `river:"parsed,attr"`
 }
 
 // Component implements the remote.http component.
@@ -94,8 +145,11 @@ type Component struct {
 	args        Arguments
 	cli         *http.Client
 	lastPoll    time.Time
+	failures    int     // Number of consecutive failed polls.
 	lastExports Exports // Used for determining whether exports should be updated
 
+	pollObserver func(time.Duration, error) // Called after each poll; may be nil.
+
 	// Updated is written to whenever args updates.
 	updated chan struct{}
 
@@ -149,7 +203,7 @@ func (c *Component) nextPoll() time.Duration {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
-	nextPoll := c.lastPoll.Add(c.args.PollFrequency)
+	nextPoll := c.lastPoll.Add(pollInterval(c.args, c.failures))
 	now := time.Now()
 
 	if now.After(nextPoll) {
@@ -159,6 +213,32 @@ func (c *Component) nextPoll() time.Duration {
 	return nextPoll.Sub(now)
 }
 
+// statusAllowed reports whether code is one of the allowed status codes. Only
+// 200 is allowed when allowed is empty, which happens when Arguments are
+// built without SetToDefault.
+func statusAllowed(allowed []int, code int) bool {
+	if len(allowed) == 0 {
+		return code == http.StatusOK
+	}
+	return slices.Contains(allowed, code)
+}
+
+// pollInterval returns how long to wait between the last poll and the next one
+// after the given number of consecutive failed polls. The backoff after
+// failures starts at MinBackoffPeriod and doubles after each failure, up to
+// MaxBackoffPeriod.
+func pollInterval(args Arguments, failures int) time.Duration {
+	if failures == 0 || args.MinBackoffPeriod <= 0 {
+		return args.PollFrequency
+	}
+
+	backoff := args.MinBackoffPeriod
+	for i := 1; i < failures && backoff < args.MaxBackoffPeriod; i++ {
+		backoff *= 2
+	}
+	return min(backoff, args.MaxBackoffPeriod)
+}
+
 // poll performs a HTTP GET for the component's configured URL. c.mut must
 // not be held when calling. After polling, the component's health is updated
 // with the success or failure status.
@@ -186,12 +266,30 @@ func (c *Component) updatePollHealth(err error) {
 	}
 }
 
+// ObservePolls sets a function which is called after each poll with the
+// duration of the poll and the error it returned, if any.
+func (c *Component) ObservePolls(observer func(time.Duration, error)) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.pollObserver = observer
+}
+
 // pollError is like poll but returns an error if one occurred.
-func (c *Component) pollError() error {
+func (c *Component) pollError() (err error) {
 	c.mut.Lock()
 	defer c.mut.Unlock()
 
 	c.lastPoll = time.Now()
+	defer func() {
+		if err != nil {
+			c.failures++
+		} else {
+			c.failures = 0
+		}
+	}()
+	if c.pollObserver != nil {
+		defer func() { c.pollObserver(time.Since(c.lastPoll), err) }()
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), c.args.PollTimeout)
 	defer cancel()
@@ -217,29 +315,36 @@ func (c *Component) pollError() error {
 		return fmt.Errorf("performing request: %w", err)
 	}
 
-	bb, err := io.ReadAll(resp.Body)
+	bb, err := readBody(resp.Body, int64(c.args.MaxResponseSize))
 	if err != nil {
 		level.Error(c.log).Log("msg", "failed to read response", "err", err)
 		return fmt.Errorf("reading response: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	if !statusAllowed(c.args.AllowedStatusCodes, resp.StatusCode) {
 		level.Error(c.log).Log("msg", "unexpected status code from response", "status", resp.Status)
 		return fmt.Errorf("unexpected status code %s", resp.Status)
 	}
 
 	stringContent := strings.TrimSpace(string(bb))
 
+	parsed, err := parseContent(c.args.Format, stringContent, c.args.IsSecret)
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to parse response", "format", c.args.Format, "err", err)
+		return err
+	}
+
 	newExports := Exports{
 		Content: rivertypes.OptionalSecret{
 			IsSecret: c.args.IsSecret,
 			Value:    stringContent,
 		},
+		Parsed: parsed,
 	}
 
 	// Only send a state change event if the exports have changed from the
 	// previous poll.
-	if c.lastExports != newExports {
+	if !reflect.DeepEqual(c.lastExports, newExports) {
 		c.opts.OnStateChange(newExports)
 	}
 	c.lastExports = newExports
@@ -299,3 +404,19 @@ func (c *Component) CurrentHealth() component.Health {
 	defer c.healthMut.RUnlock()
 	return c.health
 }
+
+// readBody reads body, failing once it's larger than limit bytes. A limit of
+// zero doesn't limit the size of body.
+func readBody(body io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(body)
+	}
+	bb, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(bb)) > limit {
+		return nil, fmt.Errorf("response exceeds the maximum size of %d bytes", limit)
+	}
+	return bb, nil
+}
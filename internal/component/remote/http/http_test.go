@@ -6,10 +6,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/grafana/agent/internal/component"
 	http_component "github.com/grafana/agent/internal/component/remote/http"
 	"github.com/grafana/agent/internal/flow/componenttest"
 	"github.com/grafana/agent/internal/flow/logging/level"
@@ -94,6 +96,67 @@ func Test(t *testing.T) {
 	})
 }
 
+func TestFormatAndStatusCodes(t *testing.T) {
+	ctx := componenttest.TestContext(t)
+
+	var handler lazyHandler
+	srv := httptest.NewServer(&handler)
+	defer srv.Close()
+
+	handler.SetHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, `{"targets": ["localhost:9090"]}`)
+	})
+
+	ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "remote.http")
+	require.NoError(t, err)
+
+	cfg := fmt.Sprintf(`
+		url                  = "%s"
+		format               = "json"
+		allowed_status_codes = [200, 202]
+	`, srv.URL)
+	var args http_component.Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+
+	go func() {
+		err := ctrl.Run(ctx, args)
+		require.NoError(t, err)
+	}()
+
+	require.NoError(t, ctrl.WaitRunning(time.Second), "component never started")
+	require.NoError(t, ctrl.WaitExports(time.Second), "component never exported anything")
+
+	exports := ctrl.Exports().(http_component.Exports)
+	require.Equal(t, `{"targets": ["localhost:9090"]}`, exports.Content.Value)
+	require.Equal(t, map[string]any{"targets": []any{"localhost:9090"}}, exports.Parsed)
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, strings.Repeat("a", 100))
+	}))
+	defer srv.Close()
+
+	newComponent := func(maxSize string) error {
+		cfg := fmt.Sprintf(`
+			url               = "%s"
+			max_response_size = "%s"
+		`, srv.URL, maxSize)
+		var args http_component.Arguments
+		require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+
+		_, err := http_component.New(component.Options{
+			Logger:        util.TestFlowLogger(t),
+			OnStateChange: func(component.Exports) {},
+		}, args)
+		return err
+	}
+
+	require.NoError(t, newComponent("100B"))
+	require.ErrorContains(t, newComponent("64B"), "response exceeds the maximum size of 64 bytes")
+}
+
 func TestUnmarshalValidation(t *testing.T) {
 	var tests = []struct {
 		testname      string
@@ -122,6 +185,31 @@ func TestUnmarshalValidation(t *testing.T) {
 			`,
 			`poll_frequency must be greater than 0`,
 		},
+		{
+			"Invalid format",
+			`
+			url = "http://example.com"
+			format = "xml"
+			`,
+			`format must be one of "text", "json", or "yaml"`,
+		},
+		{
+			"Invalid status code",
+			`
+			url = "http://example.com"
+			allowed_status_codes = [200, 42]
+			`,
+			`invalid status code 42 in allowed_status_codes`,
+		},
+		{
+			"Invalid backoff",
+			`
+			url = "http://example.com"
+			min_backoff_period = "1m"
+			max_backoff_period = "10s"
+			`,
+			`max_backoff_period must not be less than min_backoff_period`,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.testname, func(t *testing.T) {
@@ -0,0 +1,40 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/agent/internal/util/rivervalue"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats the response body can be parsed as.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// parseContent parses content in the given format into a River value. Text
+// content, or content with an empty format, isn't parsed and results in a nil
+// value.
+//
+// When isSecret is true, every string in the result is a secret.
+func parseContent(format string, content string, isSecret bool) (any, error) {
+	var out any
+	switch format {
+	case "", FormatText:
+		return nil, nil
+	case FormatJSON:
+		if err := json.Unmarshal([]byte(content), &out); err != nil {
+			return nil, fmt.Errorf("parsing JSON response: %w", err)
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal([]byte(content), &out); err != nil {
+			return nil, fmt.Errorf("parsing YAML response: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	return rivervalue.FromYAML(out, isSecret), nil
+}
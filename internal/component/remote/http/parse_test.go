@@ -0,0 +1,63 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/river/rivertypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContent(t *testing.T) {
+	expect := map[string]any{
+		"targets": []any{"localhost:9090", "localhost:9091"},
+		"labels":  map[string]any{"env": "prod"},
+		"port":    float64(8080),
+	}
+
+	actual, err := parseContent(FormatJSON, `{"targets": ["localhost:9090", "localhost:9091"], "labels": {"env": "prod"}, "port": 8080}`, false)
+	require.NoError(t, err)
+	require.Equal(t, expect, actual)
+
+	actual, err = parseContent(FormatYAML, "targets: [localhost:9090, localhost:9091]\nlabels:\n  env: prod\nport: 8080", false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"targets": []any{"localhost:9090", "localhost:9091"},
+		"labels":  map[string]any{"env": "prod"},
+		"port":    8080,
+	}, actual)
+
+	actual, err = parseContent(FormatText, "hello", false)
+	require.NoError(t, err)
+	require.Nil(t, actual)
+
+	_, err = parseContent(FormatJSON, `{"unterminated": `, false)
+	require.ErrorContains(t, err, "parsing JSON response")
+}
+
+func TestParseContent_Secret(t *testing.T) {
+	actual, err := parseContent(FormatJSON, `{"password": "hunter2", "port": 8080}`, true)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"password": rivertypes.Secret("hunter2"),
+		"port":     float64(8080),
+	}, actual)
+}
+
+func TestPollInterval(t *testing.T) {
+	args := Arguments{
+		PollFrequency:    time.Minute,
+		MinBackoffPeriod: time.Second,
+		MaxBackoffPeriod: 5 * time.Second,
+	}
+	require.Equal(t, time.Minute, pollInterval(args, 0))
+	require.Equal(t, time.Second, pollInterval(args, 1))
+	require.Equal(t, 2*time.Second, pollInterval(args, 2))
+	require.Equal(t, 4*time.Second, pollInterval(args, 3))
+	require.Equal(t, 5*time.Second, pollInterval(args, 4))
+	require.Equal(t, 5*time.Second, pollInterval(args, 100))
+
+	// Without backoff, failed polls are retried after the poll frequency.
+	args.MinBackoffPeriod = 0
+	require.Equal(t, time.Minute, pollInterval(args, 3))
+}
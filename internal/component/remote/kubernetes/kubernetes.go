@@ -243,6 +243,33 @@ func (c *Component) Update(args component.Arguments) (err error) {
 	return err
 }
 
+// CheckResource verifies that the resource described by args exists and can
+// be read, without starting a component.
+func CheckResource(ctx context.Context, args Arguments, rType ResourceType) error {
+	restConfig, err := args.Client.BuildRESTConfig(log.NewNopLogger())
+	if err != nil {
+		return err
+	}
+	client, err := client_go.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("creating kubernetes client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, args.PollTimeout)
+	defer cancel()
+
+	switch rType {
+	case TypeSecret:
+		_, err = client.CoreV1().Secrets(args.Namespace).Get(ctx, args.Name, v1.GetOptions{})
+	case TypeConfigMap:
+		_, err = client.CoreV1().ConfigMaps(args.Namespace).Get(ctx, args.Name, v1.GetOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s %s/%s: %w", rType, args.Namespace, args.Name, err)
+	}
+	return nil
+}
+
 // CurrentHealth returns the current health of the component.
 func (c *Component) CurrentHealth() component.Health {
 	c.healthMut.RLock()
@@ -1,6 +1,8 @@
 package secret
 
 import (
+	"context"
+
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/component/remote/kubernetes"
 	"github.com/grafana/agent/internal/featuregate"
@@ -15,5 +17,8 @@ func init() {
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return kubernetes.New(opts, args.(kubernetes.Arguments), kubernetes.TypeSecret)
 		},
+		CheckSecrets: func(ctx context.Context, args component.Arguments) error {
+			return kubernetes.CheckResource(ctx, args.(kubernetes.Arguments), kubernetes.TypeSecret)
+		},
 	})
 }
@@ -26,6 +26,10 @@ func init() {
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return New(opts, args.(Arguments))
 		},
+		CheckSecrets: func(ctx context.Context, args component.Arguments) error {
+			a := args.(Arguments)
+			return a.checkSecret(ctx)
+		},
 	})
 }
 
@@ -78,6 +82,22 @@ func (a *Arguments) client() (*vault.Client, error) {
 	return cli, nil
 }
 
+// checkSecret authenticates against Vault and reads the secret at the
+// configured path without retaining it.
+func (a *Arguments) checkSecret(ctx context.Context) error {
+	cli, err := a.client()
+	if err != nil {
+		return err
+	}
+	if _, err := a.authMethod().vaultAuthenticate(ctx, cli); err != nil {
+		return fmt.Errorf("authenticating to %s: %w", a.Server, err)
+	}
+	if _, err := a.secretStore(cli).Read(ctx, a); err != nil {
+		return fmt.Errorf("reading %s: %w", a.Path, err)
+	}
+	return nil
+}
+
 // SetToDefault implements river.Defaulter.
 func (a *Arguments) SetToDefault() {
 	*a = DefaultArguments
@@ -0,0 +1,230 @@
+// Package cron implements the trigger.cron component.
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/hashicorp/cronexpr"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "trigger.cron",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the trigger.cron
+// component.
+type Arguments struct {
+	// Cron expression of the times the component triggers at.
+	Schedule string `river:"schedule,attr"`
+
+	// IANA time zone the schedule is interpreted in.
+	Timezone string `river:"timezone,attr,optional"`
+
+	// How long the component stays active after each trigger. Zero means
+	// the component is never active.
+	Duration time.Duration `river:"duration,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Timezone: "UTC",
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if _, err := cronexpr.Parse(args.Schedule); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	if _, err := time.LoadLocation(args.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+	if args.Duration < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the trigger.cron component.
+type Exports struct {
+	// Number of times the component triggered since it started.
+	Count int `river:"count,attr"`
+
+	// Time of the last trigger in the RFC 3339 format, or an empty string if
+	// the component never triggered.
+	LastTrigger string `river:"last_trigger,attr"`
+
+	// Whether the last trigger happened less than Duration ago.
+	Active bool `river:"active,attr"`
+}
+
+// Component implements the trigger.cron component.
+type Component struct {
+	opts component.Options
+
+	mut         sync.Mutex
+	schedule    *cronexpr.Expression
+	location    *time.Location
+	duration    time.Duration
+	count       int
+	lastTrigger time.Time
+	checked     time.Time // Time up to which triggers were counted.
+	exported    bool
+	lastExports Exports
+
+	// updated is written to whenever the arguments change.
+	updated chan struct{}
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new trigger.cron component.
+func New(opts component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts:    opts,
+		updated: make(chan struct{}, 1),
+	}
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	for {
+		timer := time.NewTimer(time.Until(c.nextEvent(time.Now())))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case now := <-timer.C:
+			c.tick(now)
+		case <-c.updated:
+			// Recompute the next event with the new arguments.
+			timer.Stop()
+		}
+	}
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	schedule, err := cronexpr.Parse(newArgs.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	location, err := time.LoadLocation(newArgs.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+
+	c.mut.Lock()
+	c.schedule = schedule
+	c.location = location
+	c.duration = newArgs.Duration
+
+	// When the component starts, or when the schedule changes, it may already
+	// be inside an active window: pick up the last trigger within it so that
+	// active is accurate without waiting for the next trigger.
+	now := time.Now()
+	if last := lastTriggerSince(schedule, now.Add(-c.duration).In(location), now); !last.IsZero() && last.After(c.lastTrigger) {
+		c.lastTrigger = last
+	}
+	c.checked = now
+	c.exportLocked(now)
+	c.mut.Unlock()
+
+	select {
+	case c.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// tick handles an event scheduled by nextEvent: either a trigger, the end of
+// an active window, or both.
+func (c *Component) tick(now time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	// Triggers which happened while the timer was late are merged into one.
+	if last := lastTriggerSince(c.schedule, c.checked.In(c.location), now); !last.IsZero() {
+		c.count++
+		c.lastTrigger = last
+	}
+	c.checked = now
+	c.exportLocked(now)
+}
+
+// nextEvent returns the time of the next trigger, or of the end of the
+// current active window if it comes first.
+func (c *Component) nextEvent(now time.Time) time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	next := c.schedule.Next(now.In(c.location))
+	if end := c.lastTrigger.Add(c.duration); c.active(now) && (next.IsZero() || end.Before(next)) {
+		return end
+	}
+	if next.IsZero() {
+		// The schedule never triggers again, such as a schedule for a past
+		// year. Wake up rarely to honor updates to the arguments.
+		return now.Add(24 * time.Hour)
+	}
+	return next
+}
+
+// active reports whether now is inside the active window of the last
+// trigger. c.mut must be held.
+func (c *Component) active(now time.Time) bool {
+	return !c.lastTrigger.IsZero() && now.Before(c.lastTrigger.Add(c.duration))
+}
+
+// exportLocked exports the current state if it changed. c.mut must be held.
+func (c *Component) exportLocked(now time.Time) {
+	exports := Exports{
+		Count:  c.count,
+		Active: c.active(now),
+	}
+	if !c.lastTrigger.IsZero() {
+		exports.LastTrigger = c.lastTrigger.In(c.location).Format(time.RFC3339)
+	}
+	if c.exported && exports == c.lastExports {
+		return
+	}
+	c.exported = true
+	c.lastExports = exports
+	c.opts.OnStateChange(exports)
+}
+
+// lastTriggerSince returns the last time schedule triggers after from and no
+// later than now, or the zero time if it doesn't trigger in that range.
+// Schedules are evaluated in the location of from.
+func lastTriggerSince(schedule *cronexpr.Expression, from, now time.Time) time.Time {
+	var last time.Time
+	for t := schedule.Next(from); !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+		last = t
+	}
+	return last
+}
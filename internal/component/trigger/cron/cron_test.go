@@ -0,0 +1,83 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/flow/componenttest"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river"
+	"github.com/hashicorp/cronexpr"
+	"github.com/stretchr/testify/require"
+)
+
+func Test(t *testing.T) {
+	ctx := componenttest.TestContext(t)
+
+	ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "trigger.cron")
+	require.NoError(t, err)
+
+	// Trigger every second and stay active for half a second.
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(`
+		schedule = "* * * * * * *"
+		duration = "500ms"
+	`), &args))
+
+	go func() {
+		require.NoError(t, ctrl.Run(ctx, args))
+	}()
+	require.NoError(t, ctrl.WaitRunning(time.Second), "component never started")
+	require.NoError(t, ctrl.WaitExports(time.Second), "component never exported anything")
+
+	require.Eventually(t, func() bool {
+		exports := ctrl.Exports().(Exports)
+		return exports.Count >= 2 && exports.LastTrigger != ""
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var sawActive, sawInactive bool
+	require.Eventually(t, func() bool {
+		if ctrl.Exports().(Exports).Active {
+			sawActive = true
+		} else {
+			sawInactive = true
+		}
+		return sawActive && sawInactive
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestArguments_Validate(t *testing.T) {
+	tests := []struct {
+		config string
+		err    string
+	}{
+		{`schedule = "0 9 * * 1-5"`, ""},
+		{`schedule = "@hourly"`, ""},
+		{`schedule = "not a schedule"`, "invalid schedule"},
+		{`schedule = "0 9 * * *"
+		  timezone = "Nowhere/Special"`, "invalid timezone"},
+		{`schedule = "0 9 * * *"
+		  duration = "-1h"`, "duration must not be negative"},
+	}
+	for _, tc := range tests {
+		var args Arguments
+		err := river.Unmarshal([]byte(tc.config), &args)
+		if tc.err == "" {
+			require.NoError(t, err)
+		} else {
+			require.ErrorContains(t, err, tc.err)
+		}
+	}
+}
+
+func TestLastTriggerSince(t *testing.T) {
+	schedule := cronexpr.MustParse("0 9,17 * * *")
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, time.March, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	require.Equal(t, at(9, 0), lastTriggerSince(schedule, at(8, 0), at(12, 0)))
+	require.Equal(t, at(17, 0), lastTriggerSince(schedule, at(8, 0), at(18, 0)))
+	require.Equal(t, at(9, 0), lastTriggerSince(schedule, at(8, 0), at(9, 0)))
+	require.True(t, lastTriggerSince(schedule, at(9, 0), at(12, 0)).IsZero())
+}
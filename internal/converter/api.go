@@ -0,0 +1,71 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/grafana/agent/internal/converter/convertapi"
+	"github.com/grafana/agent/internal/converter/diag"
+	"github.com/grafana/agent/internal/static/metrics/cluster/configapi"
+)
+
+// maxAPIRequestSize is the largest request accepted by the conversion API.
+const maxAPIRequestSize = 10 << 20
+
+// allowedAPIExtraArgs are the extra arguments accepted by the conversion API.
+// Extra arguments are parsed as command-line flags of the converted program,
+// so arbitrary arguments must not be accepted from the API: flags like
+// -version exit the process, and -config.expand-env would expand the
+// environment variables of the agent into the response.
+var allowedAPIExtraArgs = []string{
+	"-enable-features=integrations-next",
+	"--enable-features=integrations-next",
+}
+
+// APIHandler returns the handler of the conversion API, which is served at
+// convertapi.Path. It converts the config of a convertapi.ConvertRequest and
+// responds with a convertapi.ConvertResponse.
+//
+// Conversions which fail because of the converted config still respond with
+// a 200 status code, with the reasons of the failure in the diagnostics of
+// the response. Invalid requests respond with a 400 status code, including
+// requests with extra arguments other than allowedAPIExtraArgs.
+func APIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req convertapi.ConvertRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestSize)).Decode(&req); err != nil {
+			_ = configapi.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+			return
+		}
+		if !slices.Contains(SupportedFormats, req.Format) {
+			_ = configapi.WriteError(w, http.StatusBadRequest, fmt.Errorf("unsupported format %q, supported formats are %s", req.Format, strings.Join(SupportedFormats, ", ")))
+			return
+		}
+		if req.Config == "" {
+			_ = configapi.WriteError(w, http.StatusBadRequest, fmt.Errorf("config must not be empty"))
+			return
+		}
+		for _, arg := range req.ExtraArgs {
+			if !slices.Contains(allowedAPIExtraArgs, arg) {
+				_ = configapi.WriteError(w, http.StatusBadRequest, fmt.Errorf("unsupported extra argument %q, supported extra arguments are %s", arg, strings.Join(allowedAPIExtraArgs, ", ")))
+				return
+			}
+		}
+
+		riverBytes, diags := Convert([]byte(req.Config), Input(req.Format), req.ExtraArgs)
+		failed := diags.HasSeverityLevel(diag.SeverityLevelCritical) ||
+			(!req.BypassErrors && diags.HasSeverityLevel(diag.SeverityLevelError))
+
+		resp := convertapi.ConvertResponse{
+			Success:     !failed,
+			Diagnostics: convertapi.FromDiagnostics(diags),
+		}
+		if !failed {
+			resp.Config = string(riverBytes)
+		}
+		_ = configapi.WriteResponse(w, http.StatusOK, resp)
+	})
+}
@@ -0,0 +1,116 @@
+package converter_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/agent/internal/converter"
+	"github.com/grafana/agent/internal/converter/convertapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIHandler(t *testing.T) {
+	const prometheusConfig = `
+scrape_configs:
+  - job_name: prometheus
+    static_configs:
+      - targets: ["localhost:9090"]
+remote_write:
+  - url: http://localhost:9009/api/prom/push
+`
+
+	tests := []struct {
+		name        string
+		req         convertapi.ConvertRequest
+		expectCode  int
+		expectError string
+		expectResp  func(t *testing.T, resp convertapi.ConvertResponse)
+	}{
+		{
+			name:       "converts config",
+			req:        convertapi.ConvertRequest{Format: "prometheus", Config: prometheusConfig},
+			expectCode: http.StatusOK,
+			expectResp: func(t *testing.T, resp convertapi.ConvertResponse) {
+				require.True(t, resp.Success)
+				require.Contains(t, resp.Config, `prometheus.scrape "prometheus"`)
+				require.Contains(t, resp.Config, `prometheus.remote_write "default"`)
+			},
+		},
+		{
+			name:       "invalid config",
+			req:        convertapi.ConvertRequest{Format: "prometheus", Config: "scrape_configs: {"},
+			expectCode: http.StatusOK,
+			expectResp: func(t *testing.T, resp convertapi.ConvertResponse) {
+				require.False(t, resp.Success)
+				require.Empty(t, resp.Config)
+				require.NotEmpty(t, resp.Diagnostics)
+				require.Equal(t, "critical", resp.Diagnostics[0].Severity)
+			},
+		},
+		{
+			name:        "unsupported format",
+			req:         convertapi.ConvertRequest{Format: "telegraf", Config: prometheusConfig},
+			expectCode:  http.StatusBadRequest,
+			expectError: `unsupported format "telegraf"`,
+		},
+		{
+			name:       "allowed extra argument",
+			req:        convertapi.ConvertRequest{Format: "static", Config: "metrics:\n  wal_directory: /tmp/wal\n", ExtraArgs: []string{"-enable-features=integrations-next"}},
+			expectCode: http.StatusOK,
+			expectResp: func(t *testing.T, resp convertapi.ConvertResponse) {
+				// The conversion itself is covered by the tests of the static
+				// converter; the request must only be accepted.
+				require.NotNil(t, resp.Diagnostics)
+			},
+		},
+		{
+			name:        "version flag",
+			req:         convertapi.ConvertRequest{Format: "static", Config: "metrics: {}\n", ExtraArgs: []string{"-version"}},
+			expectCode:  http.StatusBadRequest,
+			expectError: `unsupported extra argument \"-version\"`,
+		},
+		{
+			name:        "environment expansion",
+			req:         convertapi.ConvertRequest{Format: "static", Config: "metrics: {}\n", ExtraArgs: []string{"-config.expand-env"}},
+			expectCode:  http.StatusBadRequest,
+			expectError: `unsupported extra argument \"-config.expand-env\"`,
+		},
+		{
+			name:        "empty config",
+			req:         convertapi.ConvertRequest{Format: "prometheus"},
+			expectCode:  http.StatusBadRequest,
+			expectError: "config must not be empty",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bb, err := json.Marshal(tc.req)
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			converter.APIHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, convertapi.Path, strings.NewReader(string(bb))))
+			require.Equal(t, tc.expectCode, rec.Code)
+
+			var apiResp struct {
+				Status string          `json:"status"`
+				Data   json.RawMessage `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &apiResp))
+
+			if tc.expectError != "" {
+				require.Equal(t, "error", apiResp.Status)
+				require.Contains(t, string(apiResp.Data), tc.expectError)
+				return
+			}
+
+			require.Equal(t, "success", apiResp.Status)
+			var resp convertapi.ConvertResponse
+			require.NoError(t, json.Unmarshal(apiResp.Data, &resp))
+			tc.expectResp(t, resp)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Package convertapi holds the types of the config conversion API, which
+// converts config files from other programs to Grafana Agent Flow configs.
+package convertapi
+
+import (
+	"github.com/grafana/agent/internal/converter/diag"
+)
+
+// Path is the path the conversion API is served at. Requests must use POST.
+const Path = "/agent/api/v1/convert"
+
+// ConvertRequest is the request body of the conversion API.
+type ConvertRequest struct {
+	// Format is the format of Config: one of otelcol, prometheus, promtail or
+	// static.
+	Format string `json:"format"`
+
+	// Config is the config file to convert.
+	Config string `json:"config"`
+
+	// ExtraArgs are passed to the converter of Format, like the
+	// --extra-args flag of the convert command.
+	ExtraArgs []string `json:"extra_args,omitempty"`
+
+	// BypassErrors returns the converted config even if the conversion
+	// reported errors. Critical errors can't be bypassed.
+	BypassErrors bool `json:"bypass_errors,omitempty"`
+}
+
+// ConvertResponse is contained inside an APIResponse and holds the result of
+// a conversion.
+type ConvertResponse struct {
+	// Success is false if the conversion failed, in which case Config is
+	// empty and Diagnostics hold the reasons of the failure.
+	Success bool `json:"success"`
+
+	// Config is the converted River config.
+	Config string `json:"config,omitempty"`
+
+	// Diagnostics are the diagnostics reported by the conversion.
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Diagnostic is a diagnostic reported by a conversion.
+type Diagnostic struct {
+	// Severity is one of info, warning, error or critical.
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// FromDiagnostics converts the diagnostics of the converter to their API
+// representation.
+func FromDiagnostics(diags diag.Diagnostics) []Diagnostic {
+	res := make([]Diagnostic, 0, len(diags))
+	for _, d := range diags {
+		res = append(res, Diagnostic{
+			Severity: severityName(d.Severity),
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+		})
+	}
+	return res
+}
+
+func severityName(s diag.Severity) string {
+	switch s {
+	case diag.SeverityLevelInfo:
+		return "info"
+	case diag.SeverityLevelWarn:
+		return "warning"
+	case diag.SeverityLevelError:
+		return "error"
+	case diag.SeverityLevelCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
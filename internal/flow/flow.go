@@ -95,6 +95,43 @@ type Options struct {
 	// the user, for example, via command-line flags.
 	MinStability featuregate.Stability
 
+	// CheckSecrets enables checking that secrets referenced by the loaded
+	// config resolve before components are built. Missing secrets are reported
+	// together as load diagnostics.
+	CheckSecrets bool
+
+	// LazyComponents enables lazily starting components. Components registered
+	// as lazy, and custom components made only of such components, aren't
+	// started while nothing references their exports. They're started once
+	// another component or export block references them, and are stopped
+	// once they're no longer referenced.
+	LazyComponents bool
+
+	// ImportMaxContentSize is the maximum total size in bytes of the content
+	// returned by an import source. Defaults to 10MiB if zero.
+	ImportMaxContentSize int64
+
+	// ImportParseTimeout is the maximum time to parse a file returned by an
+	// import source. Defaults to 10s if zero.
+	ImportParseTimeout time.Duration
+
+	// ImportEvaluationConcurrency is the maximum number of nested import blocks
+	// of a module evaluated concurrently. Defaults to 1, evaluating them
+	// sequentially, if zero.
+	ImportEvaluationConcurrency int
+
+	// MaxExportsSize is the maximum number of values in the exports of a
+	// component, counting list elements, map entries and fields. Exports
+	// exceeding the limit mark the component unhealthy and aren't propagated
+	// to dependants. There is no limit if zero.
+	MaxExportsSize int
+
+	// RecordCollectorCallSites records where components register Prometheus
+	// collectors from, so that collectors still registered when a component
+	// is rebuilt can be traced back to the code which registered them.
+	// Recording call sites has a cost, so it's disabled by default.
+	RecordCollectorCallSites bool
+
 	// OnExportsChange is called when the exports of the controller change.
 	// Exports are controlled by "export" configuration blocks. If
 	// OnExportsChange is nil, export configuration blocks are not allowed in the
@@ -118,11 +155,17 @@ type Flow struct {
 	sched       *controller.Scheduler
 	loader      *controller.Loader
 	modules     *moduleRegistry
+	workerPool  worker.Pool
 
 	loadFinished chan struct{}
 
 	loadMut    sync.RWMutex
 	loadedOnce atomic.Bool
+
+	// Last source applied to the controller and when it was applied. Guarded
+	// by loadMut.
+	loadedSource     *Source
+	loadedSourceTime time.Time
 }
 
 // New creates a new, unstarted Flow controller. Call Run to run the controller.
@@ -145,6 +188,9 @@ type controllerOptions struct {
 	IsModule          bool                         // Whether this controller is for a module.
 	// A worker pool to evaluate components asynchronously. A default one will be created if this is nil.
 	WorkerPool worker.Pool
+	// DryRun evaluates configs without building components. Controllers
+	// created with DryRun are only used to validate configs and never run.
+	DryRun bool
 }
 
 // newController creates a new, unstarted Flow controller with a specific
@@ -179,7 +225,8 @@ func newController(o controllerOptions) *Flow {
 		updateQueue: controller.NewQueue(),
 		sched:       controller.NewScheduler(),
 
-		modules: o.ModuleRegistry,
+		modules:    o.ModuleRegistry,
+		workerPool: workerPool,
 
 		loadFinished: make(chan struct{}, 1),
 	}
@@ -192,6 +239,15 @@ func newController(o controllerOptions) *Flow {
 			TraceProvider: tracer,
 			DataPath:      o.DataPath,
 			MinStability:  o.MinStability,
+			CheckSecrets:  o.CheckSecrets,
+			DryRun:        o.DryRun,
+			ImportLimits: controller.ImportLimits{
+				MaxContentSize: o.ImportMaxContentSize,
+				ParseTimeout:   o.ImportParseTimeout,
+				Concurrency:    o.ImportEvaluationConcurrency,
+			},
+			MaxExportsSize:     o.MaxExportsSize,
+			CollectorCallSites: o.RecordCollectorCallSites,
 			OnBlockNodeUpdate: func(cn controller.BlockNode) {
 				// Changed node should be queued for reevaluation.
 				f.updateQueue.Enqueue(&controller.QueuedNode{Node: cn, LastUpdatedTime: time.Now()})
@@ -208,9 +264,19 @@ func newController(o controllerOptions) *Flow {
 					Reg:               o.Reg,
 					DataPath:          o.DataPath,
 					MinStability:      o.MinStability,
-					ID:                id,
-					ServiceMap:        serviceMap,
-					WorkerPool:        workerPool,
+					CheckSecrets:      o.CheckSecrets,
+					LazyComponents:    o.LazyComponents,
+					ImportLimits: controller.ImportLimits{
+						MaxContentSize: o.ImportMaxContentSize,
+						ParseTimeout:   o.ImportParseTimeout,
+						Concurrency:    o.ImportEvaluationConcurrency,
+					},
+					MaxExportsSize:           o.MaxExportsSize,
+					RecordCollectorCallSites: o.RecordCollectorCallSites,
+					ID:                       id,
+					ServiceMap:               serviceMap,
+					WorkerPool:               workerPool,
+					DryRun:                   o.DryRun,
 				})
 			},
 			GetServiceData: func(name string) (interface{}, error) {
@@ -239,10 +305,26 @@ func (f *Flow) Run(ctx context.Context) {
 	defer level.Debug(f.log).Log("msg", "flow controller exiting")
 
 	for {
+		// Wake up when the next block calling time.now is due for evaluation.
+		// The schedule can only change on reload, which sends to loadFinished.
+		var (
+			timer  *time.Timer
+			timerC <-chan time.Time
+		)
+		if next, ok := f.loader.NextTimedEvaluation(); ok {
+			timer = time.NewTimer(time.Until(next))
+			timerC = timer.C
+		}
+
 		select {
 		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
 			return
 
+		case now := <-timerC:
+			f.loader.EvaluateTimedNodes(ctx, now)
 		case <-f.updateQueue.Chan():
 			// Evaluate all nodes that have been updated. Sending the entire batch together will improve
 			// throughput - it prevents the situation where two nodes have the same dependency, and the first time
@@ -259,9 +341,17 @@ func (f *Flow) Run(ctx context.Context) {
 
 				runnables = make([]controller.RunnableNode, 0, len(components)+len(services)+len(imports))
 			)
+			var lazy int
 			for _, c := range components {
+				if f.opts.LazyComponents && f.loader.Unconsumed(c) {
+					lazy++
+					continue
+				}
 				runnables = append(runnables, c)
 			}
+			if lazy > 0 {
+				level.Debug(f.log).Log("msg", "not starting components without consumers", "count", lazy)
+			}
 
 			for _, i := range imports {
 				runnables = append(runnables, i)
@@ -280,6 +370,10 @@ func (f *Flow) Run(ctx context.Context) {
 				level.Error(f.log).Log("msg", "failed to load components and services", "err", err)
 			}
 		}
+
+		if timer != nil {
+			timer.Stop()
+		}
 	}
 }
 
@@ -314,6 +408,7 @@ func (f *Flow) loadSource(source *Source, args map[string]any, customComponentRe
 		return diags
 	}
 	f.loadedOnce.Store(true)
+	f.loadedSource, f.loadedSourceTime = source, time.Now()
 
 	select {
 	case f.loadFinished <- struct{}{}:
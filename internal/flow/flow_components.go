@@ -2,10 +2,20 @@ package flow
 
 import (
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/flow/internal/controller"
 	"github.com/grafana/agent/internal/flow/internal/dag"
+	"github.com/grafana/river/encoding/riverjson"
+)
+
+var (
+	_ component.ModuleInfoProvider = (*Flow)(nil)
+	_ component.ImportInfoProvider = (*Flow)(nil)
+	_ component.GraphProvider      = (*Flow)(nil)
 )
 
 // GetComponent implements [component.Provider].
@@ -63,6 +73,163 @@ func (f *Flow) ListComponents(moduleID string, opts component.InfoOptions) ([]*c
 	return detail, nil
 }
 
+// ModuleIDs implements [component.ModuleInfoProvider].
+func (f *Flow) ModuleIDs() []string {
+	var ids []string
+	for _, mod := range f.modules.List() {
+		ids = append(ids, mod.o.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// GetModuleInfo implements [component.ModuleInfoProvider].
+func (f *Flow) GetModuleInfo(moduleID string) (*component.ModuleInfo, error) {
+	if moduleID != "" {
+		mod, ok := f.modules.Get(moduleID)
+		if !ok {
+			return nil, component.ErrModuleNotFound
+		}
+
+		return mod.f.GetModuleInfo("")
+	}
+
+	f.loadMut.RLock()
+	defer f.loadMut.RUnlock()
+
+	info := &component.ModuleInfo{
+		ID:                 f.opts.ControllerID,
+		ComponentsByHealth: make(map[component.HealthType]int),
+	}
+	for _, cn := range f.loader.Components() {
+		info.ComponentsByHealth[cn.CurrentHealth().Health]++
+
+		exports := cn.Exports()
+		if exports == nil {
+			continue
+		}
+		bb, err := riverjson.MarshalBody(exports)
+		if err != nil {
+			return nil, fmt.Errorf("encoding exports of %q: %w", cn.NodeID(), err)
+		}
+		info.ExportsSize += len(bb)
+	}
+	info.LastEvaluation, info.LastEvaluationDuration = f.loader.LastEvaluation()
+	return info, nil
+}
+
+// ListImports implements [component.ImportInfoProvider].
+func (f *Flow) ListImports(moduleID string) ([]*component.ImportInfo, error) {
+	if moduleID != "" {
+		mod, ok := f.modules.Get(moduleID)
+		if !ok {
+			return nil, component.ErrModuleNotFound
+		}
+
+		return mod.f.ListImports("")
+	}
+
+	f.loadMut.RLock()
+	defer f.loadMut.RUnlock()
+
+	return f.getImportDetails(f.loader.Imports()), nil
+}
+
+// GetGraph implements [component.GraphProvider].
+func (f *Flow) GetGraph(moduleID string) (*component.Graph, error) {
+	if moduleID != "" {
+		mod, ok := f.modules.Get(moduleID)
+		if !ok {
+			return nil, component.ErrModuleNotFound
+		}
+
+		return mod.f.GetGraph("")
+	}
+
+	f.loadMut.RLock()
+	defer f.loadMut.RUnlock()
+
+	graph := f.loader.OriginalGraph()
+	res := &component.Graph{
+		ModuleID: f.opts.ControllerID,
+		Nodes:    []component.GraphNode{},
+		Edges:    []component.GraphEdge{},
+	}
+	for _, n := range graph.Nodes() {
+		res.Nodes = append(res.Nodes, getGraphNode(n))
+	}
+	for _, e := range graph.Edges() {
+		res.Edges = append(res.Edges, component.GraphEdge{From: e.From.NodeID(), To: e.To.NodeID()})
+	}
+
+	sort.Slice(res.Nodes, func(i, j int) bool { return res.Nodes[i].ID < res.Nodes[j].ID })
+	sort.Slice(res.Edges, func(i, j int) bool {
+		if res.Edges[i].From != res.Edges[j].From {
+			return res.Edges[i].From < res.Edges[j].From
+		}
+		return res.Edges[i].To < res.Edges[j].To
+	})
+	return res, nil
+}
+
+func getGraphNode(n dag.Node) component.GraphNode {
+	gn := component.GraphNode{ID: n.NodeID(), Kind: component.GraphNodeConfig}
+	if bn, ok := n.(controller.BlockNode); ok && bn.Block() != nil {
+		gn.Name = strings.Join(bn.Block().Name, ".")
+	}
+
+	switch n := n.(type) {
+	case *controller.BuiltinComponentNode:
+		gn.Kind = component.GraphNodeComponent
+		gn.ModuleIDs = n.ModuleIDs()
+	case *controller.CustomComponentNode:
+		gn.Kind = component.GraphNodeCustomComponent
+		gn.ModuleIDs = n.ModuleIDs()
+	case *controller.ImportConfigNode:
+		gn.Kind = component.GraphNodeImport
+	case *controller.DeclareNode:
+		gn.Kind = component.GraphNodeDeclare
+	case *controller.ServiceNode:
+		gn.Kind = component.GraphNodeService
+		gn.Name = n.NodeID()
+	}
+	if len(gn.ModuleIDs) == 0 {
+		gn.ModuleIDs = nil
+	} else {
+		gn.ModuleIDs = slices.Clone(gn.ModuleIDs)
+		sort.Strings(gn.ModuleIDs)
+	}
+	return gn
+}
+
+// getImportDetails returns information about the import nodes in, sorted by
+// label.
+func (f *Flow) getImportDetails(in map[string]*controller.ImportConfigNode) []*component.ImportInfo {
+	imports := make([]*component.ImportInfo, 0, len(in))
+	for _, node := range in {
+		declares := make([]string, 0)
+		for name := range node.ImportedDeclares() {
+			declares = append(declares, name)
+		}
+		sort.Strings(declares)
+
+		imports = append(imports, &component.ImportInfo{
+			ModuleID:         f.opts.ControllerID,
+			Label:            node.Label(),
+			SourceType:       node.ComponentName(),
+			LastUpdate:       node.LastContentUpdate(),
+			Health:           node.CurrentHealth(),
+			EvaluationHealth: node.EvaluationHealth(),
+			RunHealth:        node.RunHealth(),
+			Declares:         declares,
+			Imports:          f.getImportDetails(node.ImportConfigNodesChildren()),
+			DebugInfo:        node.DebugInfo(),
+		})
+	}
+	sort.Slice(imports, func(i, j int) bool { return imports[i].Label < imports[j].Label })
+	return imports
+}
+
 func (f *Flow) getComponentDetail(cn controller.ComponentNode, graph *dag.Graph, opts component.InfoOptions) *component.Info {
 	var references, referencedBy []string
 
@@ -121,6 +288,10 @@ func (f *Flow) getComponentDetail(cn controller.ComponentNode, graph *dag.Graph,
 		ModuleIDs: cn.ModuleIDs(),
 	}
 
+	if sa, ok := arguments.(component.SensitiveArguments); ok && opts.RedactSensitiveBlocks {
+		componentInfo.SensitiveBlocks = sa.SensitiveBlocks()
+	}
+
 	if builtinComponent, ok := cn.(*controller.BuiltinComponentNode); ok {
 		componentInfo.Component = builtinComponent.Component()
 		if opts.GetDebugInfo {
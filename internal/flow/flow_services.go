@@ -73,14 +73,21 @@ func (f *Flow) NewController(id string) service.Controller {
 	return serviceController{
 		f: newController(controllerOptions{
 			Options: Options{
-				ControllerID:    id,
-				Logger:          f.opts.Logger,
-				Tracer:          f.opts.Tracer,
-				DataPath:        f.opts.DataPath,
-				MinStability:    f.opts.MinStability,
-				Reg:             f.opts.Reg,
-				Services:        f.opts.Services,
-				OnExportsChange: nil, // NOTE(@tpaschalis, @wildum) The isolated controller shouldn't be able to export any values.
+				ControllerID:                id,
+				Logger:                      f.opts.Logger,
+				Tracer:                      f.opts.Tracer,
+				DataPath:                    f.opts.DataPath,
+				MinStability:                f.opts.MinStability,
+				CheckSecrets:                f.opts.CheckSecrets,
+				LazyComponents:              f.opts.LazyComponents,
+				ImportMaxContentSize:        f.opts.ImportMaxContentSize,
+				ImportParseTimeout:          f.opts.ImportParseTimeout,
+				ImportEvaluationConcurrency: f.opts.ImportEvaluationConcurrency,
+				MaxExportsSize:              f.opts.MaxExportsSize,
+				RecordCollectorCallSites:    f.opts.RecordCollectorCallSites,
+				Reg:                         f.opts.Reg,
+				Services:                    f.opts.Services,
+				OnExportsChange:             nil, // NOTE(@tpaschalis, @wildum) The isolated controller shouldn't be able to export any values.
 			},
 			IsModule:       true,
 			ModuleRegistry: newModuleRegistry(),
@@ -2,8 +2,10 @@ package flow
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/featuregate"
@@ -54,6 +56,191 @@ func TestController_LoadSource_Evaluation(t *testing.T) {
 	require.Equal(t, "hello, world!", out.(testcomponents.PassthroughExports).Output)
 }
 
+func TestController_GetModuleInfo(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+	ctrl := New(testOptions(t))
+	defer cleanUpController(ctrl)
+
+	info, err := ctrl.GetModuleInfo("")
+	require.NoError(t, err)
+	require.True(t, info.LastEvaluation.IsZero())
+
+	f, err := ParseSource(t.Name(), []byte(testFile))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	info, err = ctrl.GetModuleInfo("")
+	require.NoError(t, err)
+	require.Equal(t, "", info.ID)
+	require.False(t, info.LastEvaluation.IsZero())
+	require.Positive(t, info.ExportsSize)
+
+	var total int
+	for _, count := range info.ComponentsByHealth {
+		total += count
+	}
+	require.Equal(t, 4, total)
+
+	_, err = ctrl.GetModuleInfo("missing")
+	require.ErrorIs(t, err, component.ErrModuleNotFound)
+}
+
+func TestController_ListImports(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+	ctrl := New(testOptions(t))
+	defer cleanUpController(ctrl)
+
+	var (
+		nested = `declare "b" {}`
+		module = fmt.Sprintf(`import.string "nested" { content = %q }`, nested) + "\n" + `declare "a" {}`
+		config = fmt.Sprintf(`import.string "mod" { content = %q }`, module)
+	)
+	f, err := ParseSource(t.Name(), []byte(config))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	imports, err := ctrl.ListImports("")
+	require.NoError(t, err)
+	require.Len(t, imports, 1)
+
+	mod := imports[0]
+	require.Equal(t, "mod", mod.Label)
+	require.Equal(t, "import.string", mod.SourceType)
+	require.Equal(t, []string{"a"}, mod.Declares)
+	require.False(t, mod.LastUpdate.IsZero())
+	require.Equal(t, component.HealthTypeHealthy, mod.EvaluationHealth.Health)
+
+	require.Len(t, mod.Imports, 1)
+	require.Equal(t, "nested", mod.Imports[0].Label)
+	require.Equal(t, []string{"b"}, mod.Imports[0].Declares)
+
+	_, err = ctrl.ListImports("missing")
+	require.ErrorIs(t, err, component.ErrModuleNotFound)
+}
+
+func TestController_GetGraph(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+	ctrl := New(testOptions(t))
+	defer cleanUpController(ctrl)
+
+	f, err := ParseSource(t.Name(), []byte(testFile))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	graph, err := ctrl.GetGraph("")
+	require.NoError(t, err)
+	require.Equal(t, "", graph.ModuleID)
+
+	var components []component.GraphNode
+	for _, n := range graph.Nodes {
+		if n.Kind == component.GraphNodeComponent {
+			components = append(components, n)
+		}
+	}
+	require.Equal(t, []component.GraphNode{
+		{ID: "testcomponents.passthrough.forwarded", Kind: component.GraphNodeComponent, Name: "testcomponents.passthrough"},
+		{ID: "testcomponents.passthrough.static", Kind: component.GraphNodeComponent, Name: "testcomponents.passthrough"},
+		{ID: "testcomponents.passthrough.ticker", Kind: component.GraphNodeComponent, Name: "testcomponents.passthrough"},
+		{ID: "testcomponents.tick.ticker", Kind: component.GraphNodeComponent, Name: "testcomponents.tick"},
+	}, components)
+
+	require.Subset(t, graph.Edges, []component.GraphEdge{
+		{From: "testcomponents.passthrough.forwarded", To: "testcomponents.passthrough.ticker"},
+		{From: "testcomponents.passthrough.ticker", To: "testcomponents.tick.ticker"},
+	})
+
+	_, err = ctrl.GetGraph("missing")
+	require.ErrorIs(t, err, component.ErrModuleNotFound)
+}
+
+func TestController_LazyComponents(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+
+	opts := testOptions(t)
+	opts.LazyComponents = true
+	ctrl := New(opts)
+
+	f, err := ParseSource(t.Name(), []byte(`
+		testcomponents.tick "used" {
+			frequency = "1s"
+		}
+
+		testcomponents.tick "unused" {
+			frequency = "1s"
+		}
+
+		testcomponents.passthrough "consumer" {
+			input = testcomponents.tick.used.tick_time
+		}
+
+		declare "ticker" {
+			testcomponents.tick "inner" {
+				frequency = "1s"
+			}
+
+			export "tick_time" {
+				value = testcomponents.tick.inner.tick_time
+			}
+		}
+
+		declare "forwarder" {
+			testcomponents.tick "inner" {
+				frequency = "1s"
+			}
+
+			testcomponents.passthrough "inner" {
+				input = testcomponents.tick.inner.tick_time
+			}
+
+			export "output" {
+				value = testcomponents.passthrough.inner.output
+			}
+		}
+
+		ticker "used" { }
+
+		ticker "unused" { }
+
+		forwarder "unused" { }
+
+		testcomponents.passthrough "custom_consumer" {
+			input = ticker.used.tick_time
+		}
+	`))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	health := func(id string) component.HealthType {
+		info, err := ctrl.GetComponent(component.ParseID(id), component.InfoOptions{GetHealth: true})
+		require.NoError(t, err)
+		return info.Health.Health
+	}
+
+	require.Eventually(t, func() bool {
+		return health("testcomponents.tick.used") == component.HealthTypeHealthy &&
+			health("testcomponents.passthrough.consumer") == component.HealthTypeHealthy &&
+			health("ticker.used") == component.HealthTypeHealthy &&
+			health("forwarder.unused") == component.HealthTypeHealthy
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Lazy components whose exports aren't referenced are never started, while
+	// components which didn't opt in are started even if nothing references
+	// them. Custom components are only lazy if all their components are.
+	require.Equal(t, component.HealthTypeUnknown, health("testcomponents.tick.unused"))
+	require.Equal(t, component.HealthTypeUnknown, health("ticker.unused"))
+}
+
 func getFields(t *testing.T, g *dag.Graph, nodeID string) (component.Arguments, component.Exports) {
 	t.Helper()
 
@@ -5,6 +5,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/internal/controller"
 	"github.com/grafana/agent/internal/flow/internal/testcomponents"
 	"github.com/grafana/agent/internal/flow/internal/worker"
 	"github.com/stretchr/testify/require"
@@ -370,6 +373,63 @@ func TestController_Updates_WithLaggingComponent(t *testing.T) {
 	require.Equal(t, 10, in.(testcomponents.SummationConfig).Input)
 }
 
+func TestController_Updates_WithMinEvaluationInterval(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+
+	config := `
+	testcomponents.count "inc" {
+		frequency = "10ms"
+		max = 20
+	}
+
+	testcomponents.summation "sum" {
+		input = testcomponents.count.inc.count
+	}
+`
+
+	count, ok := component.Get("testcomponents.count")
+	require.True(t, ok)
+	summation, ok := component.Get("testcomponents.summation")
+	require.True(t, ok)
+	summation.MinEvaluationInterval = 100 * time.Millisecond
+
+	ctrl := newController(controllerOptions{
+		Options:        testOptions(t),
+		ModuleRegistry: newModuleRegistry(),
+		ComponentRegistry: controller.NewRegistryMap(featuregate.StabilityBeta, map[string]component.Registration{
+			count.Name:     count,
+			summation.Name: summation,
+		}),
+		WorkerPool: worker.NewFixedWorkerPool(4, 100),
+	})
+
+	f, err := ParseSource(t.Name(), []byte(config))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ctrl.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// The last count is eventually evaluated.
+	require.Eventually(t, func() bool {
+		_, out := getFields(t, ctrl.loader.Graph(), "testcomponents.summation.sum")
+		return out.(testcomponents.SummationExports).LastAdded == 20
+	}, 3*time.Second, 10*time.Millisecond)
+
+	// Evaluating every count would add up to 1+2+...+20 = 210; coalescing
+	// updates skips some of them.
+	_, out := getFields(t, ctrl.loader.Graph(), "testcomponents.summation.sum")
+	require.Less(t, out.(testcomponents.SummationExports).Sum, 210)
+}
+
 func newTestController(t *testing.T) *Flow {
 	return newController(controllerOptions{
 		Options:        testOptions(t),
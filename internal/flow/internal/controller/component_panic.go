@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/dskit/backoff"
+)
+
+// panicBackoff is the backoff used to restart components whose Run method
+// panicked.
+var panicBackoff = backoff.Config{
+	MinBackoff: 1 * time.Second,
+	MaxBackoff: 1 * time.Minute,
+	MaxRetries: 0, // Retry until the component is stopped.
+}
+
+// componentPanic describes the last panic recovered from a component.
+type componentPanic struct {
+	Value string    `river:"value,attr"`
+	Stack string    `river:"stack,attr"`
+	Time  time.Time `river:"time,attr"`
+	Count int       `river:"count,attr"`
+}
+
+// panicDebugInfo is the debug info of a component which panicked. It wraps
+// the debug info reported by the component itself.
+type panicDebugInfo struct {
+	LastPanic componentPanic `river:"last_panic,block"`
+	Component interface{}    `river:"component,attr,optional"`
+}
+
+// recoverPanic records a value recovered from a panic of the managed
+// component during op, returning an error describing it. r must be the
+// result of calling recover in a deferred function.
+func (cn *BuiltinComponentNode) recoverPanic(op string, r interface{}) error {
+	stack := string(debug.Stack())
+
+	cn.panicMut.Lock()
+	count := 1
+	if cn.lastPanic != nil {
+		count = cn.lastPanic.Count + 1
+	}
+	cn.lastPanic = &componentPanic{
+		Value: fmt.Sprint(r),
+		Stack: stack,
+		Time:  time.Now(),
+		Count: count,
+	}
+	cn.panicMut.Unlock()
+
+	level.Error(cn.managedOpts.Logger).Log("msg", "recovered from component panic", "op", op, "panic", r, "stack", stack)
+	return fmt.Errorf("component panicked while %s: %v", op, r)
+}
+
+// runManaged runs managed until ctx is canceled, recovering from panics.
+// Only panics in the goroutine calling Run are recovered; a panic in a
+// goroutine the component starts itself still stops the process.
+//
+// managed runs under its own context, which is canceled once Run returns, so
+// that goroutines left behind by an instance which panicked are stopped
+// before a new instance is built.
+func (cn *BuiltinComponentNode) runManaged(ctx context.Context, managed component.Component) (panicked bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			panicked, err = true, cn.recoverPanic("running", r)
+		}
+	}()
+	return false, managed.Run(ctx)
+}
+
+// rebuildManaged replaces the managed component with a new instance built
+// from the current arguments, recovering from panics while building.
+func (cn *BuiltinComponentNode) rebuildManaged() (managed component.Component, err error) {
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			managed, err = nil, cn.recoverPanic("building", r)
+		}
+	}()
+	managed, err = cn.build(cn.args)
+	if err != nil {
+		return nil, fmt.Errorf("rebuilding component: %w", err)
+	}
+	cn.managed = managed
+	return managed, nil
+}
+
+// updateManaged updates managed with args, recovering from panics. Like
+// runManaged, panics in goroutines the component starts itself aren't
+// recovered.
+func (cn *BuiltinComponentNode) updateManaged(managed component.Component, args component.Arguments) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = cn.recoverPanic("updating", r)
+		}
+	}()
+	return managed.Update(args)
+}
@@ -4,9 +4,9 @@ import (
 	"fmt"
 
 	"github.com/grafana/agent/internal/flow/internal/dag"
+	"github.com/grafana/agent/internal/flow/internal/stdlib"
 	"github.com/grafana/river/ast"
 	"github.com/grafana/river/diag"
-	"github.com/grafana/river/vm"
 )
 
 // Traversal describes accessing a sequence of fields relative to a component.
@@ -42,13 +42,12 @@ func ComponentReferences(cn dag.Node, g *dag.Graph) ([]Reference, diag.Diagnosti
 
 	refs := make([]Reference, 0, len(traversals))
 	for _, t := range traversals {
-		// We use an empty scope to determine if a reference refers to something in
-		// the stdlib, since vm.Scope.Lookup will search the scope tree + the
-		// stdlib.
+		// We use the scope of the Flow stdlib to determine if a reference refers
+		// to something in the stdlib, since vm.Scope.Lookup will search the
+		// scope tree + the River stdlib.
 		//
 		// Any call to an stdlib function is ignored.
-		var emptyScope vm.Scope
-		if _, ok := emptyScope.Lookup(t[0].Name); ok {
+		if _, ok := stdlib.Scope.Lookup(t[0].Name); ok {
 			continue
 		}
 
@@ -130,6 +129,14 @@ func (tw *traversalWalker) flush() {
 }
 
 func resolveTraversal(t Traversal, g *dag.Graph) (Reference, diag.Diagnostics) {
+	// Services are resolved first. They don't export values, so they're only
+	// referenced by their name, which may also be the namespace of components,
+	// such as the cluster service and the cluster.distribute component.
+	service, _ := g.GetByID(t[0].Name).(*ServiceNode)
+	if service != nil && len(t) == 1 {
+		return Reference{Target: service}, nil
+	}
+
 	var (
 		diags diag.Diagnostics
 
@@ -139,10 +146,12 @@ func resolveTraversal(t Traversal, g *dag.Graph) (Reference, diag.Diagnostics) {
 
 	for {
 		if n := g.GetByID(partial.String()); n != nil {
-			return Reference{
-				Target:    n.(BlockNode),
-				Traversal: rem,
-			}, nil
+			if _, ok := n.(*ServiceNode); !ok {
+				return Reference{
+					Target:    n.(BlockNode),
+					Traversal: rem,
+				}, nil
+			}
 		}
 
 		if len(rem) == 0 {
@@ -155,6 +164,12 @@ func resolveTraversal(t Traversal, g *dag.Graph) (Reference, diag.Diagnostics) {
 		rem = rem[1:]
 	}
 
+	if service != nil {
+		// No component matches, so the traversal is a field of the service,
+		// which fails when it's evaluated.
+		return Reference{Target: service, Traversal: t[1:]}, nil
+	}
+
 	diags = append(diags, diag.Diagnostic{
 		Severity: diag.SeverityLevelError,
 		Message:  fmt.Sprintf("component %q does not exist or is out of scope", partial),
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/grafana/agent/internal/flow/internal/dag"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/river/ast"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveTraversal_Services(t *testing.T) {
+	traversal := func(names ...string) Traversal {
+		t := make(Traversal, 0, len(names))
+		for _, name := range names {
+			t = append(t, &ast.Ident{Name: name})
+		}
+		return t
+	}
+
+	var (
+		g          dag.Graph
+		cluster    = &ServiceNode{def: service.Definition{Name: "cluster"}}
+		distribute = &BuiltinComponentNode{nodeID: "cluster.distribute.x"}
+	)
+	g.Add(cluster)
+	g.Add(distribute)
+
+	// Services are referenced by their name.
+	ref, diags := resolveTraversal(traversal("cluster"), &g)
+	require.False(t, diags.HasErrors())
+	require.Equal(t, cluster, ref.Target)
+	require.Empty(t, ref.Traversal)
+
+	// Components in the namespace of a service are resolved to the component.
+	ref, diags = resolveTraversal(traversal("cluster", "distribute", "x", "targets"), &g)
+	require.False(t, diags.HasErrors())
+	require.Equal(t, distribute, ref.Target)
+	require.Equal(t, traversal("targets"), ref.Traversal)
+
+	// Other fields are resolved to the service.
+	ref, diags = resolveTraversal(traversal("cluster", "distribute", "y", "targets"), &g)
+	require.False(t, diags.HasErrors())
+	require.Equal(t, cluster, ref.Target)
+	require.Equal(t, traversal("distribute", "y", "targets"), ref.Traversal)
+
+	_, diags = resolveTraversal(traversal("discovery", "kubernetes", "pods", "targets"), &g)
+	require.True(t, diags.HasErrors())
+	require.Equal(t, `component "discovery.kubernetes.pods.targets" does not exist or is out of scope`, diags[0].Message)
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/agent/internal/flow/internal/dag"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// debouncedNode tracks the evaluations of a component which declares a
+// minimum evaluation interval.
+type debouncedNode struct {
+	lastSubmit time.Time   // Last time the node was submitted for evaluation.
+	timer      *time.Timer // Pending delayed evaluation; nil if there is none.
+}
+
+// minEvaluationInterval returns the minimum evaluation interval declared by
+// the component of n, or 0 if n doesn't declare one.
+func minEvaluationInterval(n dag.Node) time.Duration {
+	if cn, ok := n.(*BuiltinComponentNode); ok {
+		return cn.Registration().MinEvaluationInterval
+	}
+	return 0
+}
+
+// debounce reports whether the evaluation of n must be delayed because n was
+// submitted for evaluation less than its minimum evaluation interval ago.
+// Delayed evaluations are coalesced: n is submitted once when the interval
+// elapses, and is evaluated with the latest exports of its dependencies.
+func (l *Loader) debounce(ctx context.Context, n dag.Node, parent *QueuedNode) bool {
+	interval := minEvaluationInterval(n)
+	if interval <= 0 {
+		return false
+	}
+
+	l.debounceMut.Lock()
+	defer l.debounceMut.Unlock()
+
+	id := n.NodeID()
+	dn, ok := l.debouncedNodes[id]
+	if !ok {
+		dn = &debouncedNode{}
+		l.debouncedNodes[id] = dn
+	}
+
+	if dn.timer != nil {
+		l.cm.coalescedEvaluations.WithLabelValues(id).Inc()
+		return true
+	}
+
+	now := time.Now()
+	wait := dn.lastSubmit.Add(interval).Sub(now)
+	if wait <= 0 {
+		dn.lastSubmit = now
+		return false
+	}
+
+	l.cm.delayedEvaluations.WithLabelValues(id).Inc()
+	dn.timer = time.AfterFunc(wait, func() {
+		l.debounceMut.Lock()
+		dn.timer = nil
+		dn.lastSubmit = time.Now()
+		l.debounceMut.Unlock()
+
+		l.submitDebounced(ctx, id, parent)
+	})
+	return true
+}
+
+// submitDebounced submits the node with the given ID for evaluation once its
+// minimum evaluation interval elapsed.
+func (l *Loader) submitDebounced(ctx context.Context, id string, parent *QueuedNode) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	l.mut.RLock()
+	defer l.mut.RUnlock()
+
+	// The node may have been removed by a reload in the meantime.
+	n := l.graph.GetByID(id)
+	if n == nil {
+		return
+	}
+
+	tracer := l.tracer.Tracer("")
+	spanCtx, span := tracer.Start(context.Background(), "SubmitDebouncedForEvaluation", trace.WithSpanKind(trace.SpanKindInternal))
+	span.SetAttributes(attribute.String("node_id", id))
+	span.SetStatus(codes.Ok, "debounced node submitted for evaluation")
+	defer span.End()
+
+	l.submitNode(ctx, spanCtx, tracer, n, parent)
+	l.cm.evaluationQueueSize.Set(float64(l.workerPool.QueueSize()))
+}
+
+// syncDebouncedNodes forgets the nodes which aren't in g anymore, canceling
+// their pending evaluations.
+func (l *Loader) syncDebouncedNodes(g *dag.Graph) {
+	l.debounceMut.Lock()
+	defer l.debounceMut.Unlock()
+
+	for id, dn := range l.debouncedNodes {
+		if g.GetByID(id) != nil {
+			continue
+		}
+		if dn.timer != nil {
+			dn.timer.Stop()
+		}
+		delete(l.debouncedNodes, id)
+	}
+}
+
+// stopDebouncedNodes cancels every pending delayed evaluation.
+func (l *Loader) stopDebouncedNodes() {
+	l.debounceMut.Lock()
+	defer l.debounceMut.Unlock()
+
+	for _, dn := range l.debouncedNodes {
+		if dn.timer != nil {
+			dn.timer.Stop()
+			dn.timer = nil
+		}
+	}
+}
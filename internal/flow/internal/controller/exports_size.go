@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"reflect"
+)
+
+// exportsSize returns the number of values held by exports: every list
+// element, map entry and struct field exposed to River counts as one value,
+// recursively. Values which River treats as capsules, such as interfaces, are
+// counted without looking into them.
+//
+// The size approximates how expensive the exports are to copy and compare
+// when dependants are evaluated.
+func exportsSize(exports any) int {
+	if exports == nil {
+		return 0
+	}
+	return valueSize(reflect.ValueOf(exports))
+}
+
+func valueSize(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return 0
+		}
+		return valueSize(v.Elem())
+
+	case reflect.Slice, reflect.Array:
+		size := 0
+		for i := 0; i < v.Len(); i++ {
+			size += 1 + valueSize(v.Index(i))
+		}
+		return size
+
+	case reflect.Map:
+		size := 0
+		iter := v.MapRange()
+		for iter.Next() {
+			size += 1 + valueSize(iter.Value())
+		}
+		return size
+
+	case reflect.Struct:
+		size := 0
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if _, ok := t.Field(i).Tag.Lookup("river"); !ok {
+				continue
+			}
+			size += 1 + valueSize(v.Field(i))
+		}
+		return size
+
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportsSize(t *testing.T) {
+	type target map[string]string
+
+	type exports struct {
+		Targets []target          `river:"targets,attr"`
+		Labels  map[string]string `river:"labels,attr"`
+		Name    string            `river:"name,attr"`
+		Extra   *exports          `river:"extra,attr,optional"`
+		Ignored []string
+	}
+
+	var testcases = []struct {
+		name   string
+		input  any
+		expect int
+	}{
+		{"nil", nil, 0},
+		{"empty", exports{}, 4},
+		{
+			name: "nested",
+			input: exports{
+				Targets: []target{
+					{"__address__": "a", "job": "x"},
+					{"__address__": "b"},
+				},
+				Labels:  map[string]string{"env": "prod"},
+				Ignored: []string{"a", "b", "c"},
+			},
+			// 4 fields, 2 targets with 3 labels and 1 map entry.
+			expect: 4 + 2 + 3 + 1,
+		},
+		{
+			name:   "pointer",
+			input:  &exports{Extra: &exports{Name: "extra"}},
+			expect: 4 + 4,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expect, exportsSize(tt.input))
+		})
+	}
+}
+
+func TestCheckExportsSize(t *testing.T) {
+	cn := &BuiltinComponentNode{
+		maxExportsSize: 10,
+		managedOpts:    component.Options{Logger: log.NewNopLogger()},
+	}
+
+	require.True(t, cn.checkExportsSize(10))
+	require.Equal(t, component.HealthTypeUnknown, cn.CurrentHealth().Health)
+
+	require.False(t, cn.checkExportsSize(11))
+	health := cn.CurrentHealth()
+	require.Equal(t, component.HealthTypeUnhealthy, health.Health)
+	require.Contains(t, health.Message, "limit of 10")
+
+	require.True(t, cn.checkExportsSize(5))
+	require.NotEqual(t, component.HealthTypeUnhealthy, cn.CurrentHealth().Health)
+
+	cn.maxExportsSize = 0
+	require.True(t, cn.checkExportsSize(1000))
+}
+
+func TestSetExports_SizeOnlyMeasuredWithLimit(t *testing.T) {
+	type exports struct {
+		Names []string `river:"names,attr"`
+	}
+
+	var updates int
+	cn := &BuiltinComponentNode{
+		exportsType:       reflect.TypeOf(exports{}),
+		exportsSizeGauge:  prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_exports_size"}),
+		managedOpts:       component.Options{Logger: log.NewNopLogger()},
+		OnBlockNodeUpdate: func(BlockNode) { updates++ },
+	}
+
+	cn.setExports(exports{Names: []string{"a", "b"}})
+	require.Equal(t, 1, updates)
+	require.Zero(t, testutil.ToFloat64(cn.exportsSizeGauge))
+
+	cn.maxExportsSize = 10
+	cn.setExports(exports{Names: []string{"a", "b", "c"}})
+	require.Equal(t, 2, updates)
+	require.Equal(t, 4.0, testutil.ToFloat64(cn.exportsSizeGauge))
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/river/ast"
 	"github.com/grafana/river/diag"
+	"github.com/grafana/river/vm"
 	"github.com/hashicorp/go-multierror"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -52,6 +53,22 @@ type Loader struct {
 	cc                   *controllerCollector
 	moduleExportIndex    int
 	componentNodeManager *ComponentNodeManager
+
+	// timedMut guards the nodes which are re-evaluated periodically because
+	// they call time.now, by node ID.
+	timedMut   sync.Mutex
+	timedNodes map[string]*timedNode
+
+	// debounceMut guards the components which declare a minimum evaluation
+	// interval, by node ID.
+	debounceMut    sync.Mutex
+	debouncedNodes map[string]*debouncedNode
+
+	// evalMut guards the time and duration of the last complete evaluation,
+	// which are read while mut is held by Apply.
+	evalMut                sync.Mutex
+	lastEvaluation         time.Time
+	lastEvaluationDuration time.Duration
 }
 
 // LoaderOptions holds options for creating a Loader.
@@ -102,6 +119,8 @@ func NewLoader(opts LoaderOptions) *Loader {
 		originalGraph: &dag.Graph{},
 		cache:         newValueCache(),
 		cm:            newControllerMetrics(parent, id),
+
+		debouncedNodes: make(map[string]*debouncedNode),
 	}
 	l.cc = newControllerCollector(l, parent, id)
 
@@ -160,6 +179,12 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 		return diags
 	}
 
+	// Missing environment variables are reported alongside the secret checks
+	// performed while building components so that all of them surface at once.
+	if l.globals.CheckSecrets {
+		diags = append(diags, checkEnvReferences(options.ComponentBlocks, options.ConfigBlocks, options.DeclareBlocks)...)
+	}
+
 	var (
 		components   = make([]ComponentNode, 0)
 		componentIDs = make([]ComponentID, 0)
@@ -175,17 +200,44 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 	defer func() {
 		span.SetStatus(codes.Ok, "")
 
-		level.Info(logger).Log("msg", "finished complete graph evaluation", "duration", time.Since(start))
+		duration := time.Since(start)
+		level.Info(logger).Log("msg", "finished complete graph evaluation", "duration", duration)
+
+		l.evalMut.Lock()
+		l.lastEvaluation, l.lastEvaluationDuration = start, duration
+		l.evalMut.Unlock()
 	}()
 
 	l.cache.ClearModuleExports()
 
+	// In dry-run mode, components aren't built, so their exports keep their
+	// zero values. Nodes depending on those exports may fail to evaluate
+	// because of the zero values rather than because of the config, so their
+	// errors are reported as warnings.
+	unknownExports := make(map[dag.Node]struct{})
+
 	// Evaluate all the components.
 	_ = dag.WalkTopological(&newGraph, newGraph.Leaves(), func(n dag.Node) error {
 		_, span := tracer.Start(spanCtx, "EvaluateNode", trace.WithSpanKind(trace.SpanKindInternal))
 		span.SetAttributes(attribute.String("node_id", n.NodeID()))
 		defer span.End()
 
+		if l.globals.DryRun {
+			dependsOnUnknown := dependsOnAny(&newGraph, n, unknownExports)
+			if _, isComponent := n.(ComponentNode); isComponent || dependsOnUnknown {
+				unknownExports[n] = struct{}{}
+			}
+			if dependsOnUnknown {
+				start := len(diags)
+				defer func() {
+					for i := start; i < len(diags); i++ {
+						diags[i].Severity = diag.SeverityLevelWarn
+						diags[i].Message += " (depends on component exports which aren't known when validating)"
+					}
+				}()
+			}
+		}
+
 		start := time.Now()
 		defer func() {
 			level.Info(logger).Log("msg", "finished node evaluation", "node_id", n.NodeID(), "duration", time.Since(start))
@@ -256,6 +308,8 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 	l.componentNodes = components
 	l.serviceNodes = services
 	l.graph = &newGraph
+	l.syncTimedNodes(l.graph)
+	l.syncDebouncedNodes(l.graph)
 	l.cache.SyncIDs(componentIDs)
 	l.blocks = options.ComponentBlocks
 	if l.globals.OnExportsChange != nil && l.cache.ExportChangeIndex() != l.moduleExportIndex {
@@ -267,6 +321,7 @@ func (l *Loader) Apply(options ApplyOptions) diag.Diagnostics {
 
 // Cleanup unregisters any existing metrics and optionally stops the worker pool.
 func (l *Loader) Cleanup(stopWorkerPool bool) {
+	l.stopDebouncedNodes()
 	if stopWorkerPool {
 		l.workerPool.Stop()
 	}
@@ -648,6 +703,12 @@ func (l *Loader) Variables() map[string]interface{} {
 	return l.cache.BuildContext().Variables
 }
 
+// Scope returns the scope which the expressions of components are evaluated
+// against.
+func (l *Loader) Scope() *vm.Scope {
+	return l.cache.BuildContext()
+}
+
 // Components returns the current set of loaded components.
 func (l *Loader) Components() []ComponentNode {
 	l.mut.RLock()
@@ -655,6 +716,55 @@ func (l *Loader) Components() []ComponentNode {
 	return l.componentNodes
 }
 
+// Unconsumed reports whether cn can be left unstarted because nothing uses
+// its work: it's a builtin component registered as lazy, or a custom
+// component, and no other node in the current graph references its exports.
+func (l *Loader) Unconsumed(cn ComponentNode) bool {
+	l.mut.RLock()
+	defer l.mut.RUnlock()
+
+	if !lazyComponent(cn) || l.graph.GetByID(cn.NodeID()) == nil {
+		return false
+	}
+	return len(l.graph.Dependants(cn)) == 0
+}
+
+// Lazy reports whether every loaded component may be started lazily, so
+// that nothing is lost by not running them while nothing references them.
+func (l *Loader) Lazy() bool {
+	for _, cn := range l.Components() {
+		if !lazyComponent(cn) {
+			return false
+		}
+	}
+	return true
+}
+
+// lazyComponent reports whether cn may be started lazily. Builtin components
+// must opt in through their registration, while custom components qualify
+// when they have exports which can be referenced by other nodes and every
+// component they contain may be started lazily.
+func lazyComponent(cn ComponentNode) bool {
+	switch cn := cn.(type) {
+	case *BuiltinComponentNode:
+		return cn.reg.Lazy && cn.exportsType != nil
+	case *CustomComponentNode:
+		exports, _ := cn.Exports().(map[string]any)
+		return len(exports) > 0 && cn.lazy()
+	default:
+		return false
+	}
+}
+
+// LastEvaluation returns the start time and duration of the most recent
+// complete graph evaluation. The returned time is zero if Apply hasn't
+// evaluated a graph yet.
+func (l *Loader) LastEvaluation() (time.Time, time.Duration) {
+	l.evalMut.Lock()
+	defer l.evalMut.Unlock()
+	return l.lastEvaluation, l.lastEvaluationDuration
+}
+
 // Services returns the current set of service nodes.
 func (l *Loader) Services() []*ServiceNode {
 	l.mut.RLock()
@@ -727,49 +837,63 @@ func (l *Loader) EvaluateDependants(ctx context.Context, updatedNodes []*QueuedN
 	// During evaluation, if a node's exports change, Flow will add it to updated nodes queue (controller.Queue) and
 	// the Flow controller will call EvaluateDependants on it again. This results in a concurrent breadth-first
 	// traversal of the nodes that need to be evaluated.
-	for n, parent := range dependenciesToParentsMap {
-		dependantCtx, span := tracer.Start(spanCtx, "SubmitForEvaluation", trace.WithSpanKind(trace.SpanKindInternal))
-		span.SetAttributes(attribute.String("node_id", n.NodeID()))
-		span.SetAttributes(attribute.String("originator_id", parent.Node.NodeID()))
+	l.submitForEvaluation(ctx, spanCtx, tracer, dependenciesToParentsMap)
+}
 
-		// Submit for asynchronous evaluation with retries and backoff. Don't use range variables in the closure.
-		var (
-			nodeRef, parentRef = n, parent
-			retryBackoff       = backoff.New(ctx, l.backoffConfig)
-			err                error
-		)
-		for retryBackoff.Ongoing() {
-			globalUniqueKey := path.Join(l.globals.ControllerID, nodeRef.NodeID())
-			err = l.workerPool.SubmitWithKey(globalUniqueKey, func() {
-				l.concurrentEvalFn(nodeRef, dependantCtx, tracer, parentRef)
-			})
-			if err != nil {
-				level.Error(l.log).Log(
-					"msg", "failed to submit node for evaluation - the agent is likely overloaded "+
-						"and cannot keep up with evaluating components - will retry",
-					"err", err,
-					"node_id", n.NodeID(),
-					"originator_id", parent.Node.NodeID(),
-					"retries", retryBackoff.NumRetries(),
-				)
-				retryBackoff.Wait()
-			} else {
-				break
-			}
-		}
-		span.SetAttributes(attribute.Int("retries", retryBackoff.NumRetries()))
-		if err != nil {
-			span.SetStatus(codes.Error, err.Error())
-		} else {
-			span.SetStatus(codes.Ok, "node submitted for evaluation")
+// submitForEvaluation submits nodes for asynchronous evaluation, along with
+// the node which caused each of them to be evaluated. l.mut must be held.
+func (l *Loader) submitForEvaluation(ctx context.Context, spanCtx context.Context, tracer trace.Tracer, nodes map[dag.Node]*QueuedNode) {
+	for n, parent := range nodes {
+		if l.debounce(ctx, n, parent) {
+			continue
 		}
-		span.End()
+		l.submitNode(ctx, spanCtx, tracer, n, parent)
 	}
 
 	// Report queue size metric.
 	l.cm.evaluationQueueSize.Set(float64(l.workerPool.QueueSize()))
 }
 
+// submitNode submits n for asynchronous evaluation, retrying with a backoff
+// while the worker pool is full. l.mut must be held.
+func (l *Loader) submitNode(ctx context.Context, spanCtx context.Context, tracer trace.Tracer, n dag.Node, parent *QueuedNode) {
+	dependantCtx, span := tracer.Start(spanCtx, "SubmitForEvaluation", trace.WithSpanKind(trace.SpanKindInternal))
+	span.SetAttributes(attribute.String("node_id", n.NodeID()))
+	span.SetAttributes(attribute.String("originator_id", parent.Node.NodeID()))
+
+	// Submit for asynchronous evaluation with retries and backoff.
+	var (
+		retryBackoff = backoff.New(ctx, l.backoffConfig)
+		err          error
+	)
+	for retryBackoff.Ongoing() {
+		globalUniqueKey := path.Join(l.globals.ControllerID, n.NodeID())
+		err = l.workerPool.SubmitWithKey(globalUniqueKey, func() {
+			l.concurrentEvalFn(n, dependantCtx, tracer, parent)
+		})
+		if err != nil {
+			level.Error(l.log).Log(
+				"msg", "failed to submit node for evaluation - the agent is likely overloaded "+
+					"and cannot keep up with evaluating components - will retry",
+				"err", err,
+				"node_id", n.NodeID(),
+				"originator_id", parent.Node.NodeID(),
+				"retries", retryBackoff.NumRetries(),
+			)
+			retryBackoff.Wait()
+		} else {
+			break
+		}
+	}
+	span.SetAttributes(attribute.Int("retries", retryBackoff.NumRetries()))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "node submitted for evaluation")
+	}
+	span.End()
+}
+
 // concurrentEvalFn returns a function that evaluates a node and updates the cache. This function can be submitted to
 // a worker pool for asynchronous evaluation.
 func (l *Loader) concurrentEvalFn(n dag.Node, spanCtx context.Context, tracer trace.Tracer, parent *QueuedNode) {
@@ -782,6 +906,7 @@ func (l *Loader) concurrentEvalFn(n dag.Node, spanCtx context.Context, tracer tr
 	defer func() {
 		duration := time.Since(start)
 		l.cm.onComponentEvaluationDone(n.NodeID(), duration)
+		l.cm.onExportsPropagated(parent.Node.NodeID(), n.NodeID(), time.Since(parent.LastUpdatedTime))
 		level.Info(l.log).Log("msg", "finished node evaluation", "node_id", n.NodeID(), "duration", duration)
 	}()
 
@@ -862,6 +987,16 @@ func (l *Loader) postEvaluate(logger log.Logger, bn BlockNode, err error) error
 	return nil
 }
 
+// dependsOnAny reports whether n directly depends on any node in set.
+func dependsOnAny(g *dag.Graph, n dag.Node, set map[dag.Node]struct{}) bool {
+	for _, dep := range g.Dependencies(n) {
+		if _, ok := set[dep]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 func multierrToDiags(errors error) diag.Diagnostics {
 	var diags diag.Diagnostics
 	for _, err := range errors.(*multierror.Error).Errors {
@@ -11,6 +11,7 @@ import (
 	"github.com/grafana/agent/internal/featuregate"
 	"github.com/grafana/agent/internal/flow/internal/controller"
 	"github.com/grafana/agent/internal/flow/internal/dag"
+	"github.com/grafana/agent/internal/flow/internal/testcomponents"
 	"github.com/grafana/agent/internal/flow/logging"
 	"github.com/grafana/agent/internal/service"
 	"github.com/grafana/river/ast"
@@ -19,8 +20,6 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/trace/noop"
-
-	_ "github.com/grafana/agent/internal/flow/internal/testcomponents" // Include test components
 )
 
 func TestLoader(t *testing.T) {
@@ -416,6 +415,90 @@ func TestScopeWithFailingComponent(t *testing.T) {
 	require.True(t, strings.Contains(diags.Error(), `unrecognized attribute name "frequenc"`))
 }
 
+func TestLoader_CheckSecrets(t *testing.T) {
+	passthrough, ok := component.Get("testcomponents.passthrough")
+	require.True(t, ok)
+
+	// testcomponents.secret is a passthrough whose secret check fails when its
+	// input is "missing".
+	var checked []string
+	secret := passthrough
+	secret.Name = "testcomponents.secret"
+	secret.CheckSecrets = func(_ context.Context, args component.Arguments) error {
+		input := args.(testcomponents.PassthroughConfig).Input
+		checked = append(checked, input)
+		if input == "missing" {
+			return errors.New(`secret "missing" not found`)
+		}
+		return nil
+	}
+
+	newLoader := func(checkSecrets bool) *controller.Loader {
+		l, _ := logging.New(os.Stderr, logging.DefaultOptions)
+		return controller.NewLoader(controller.LoaderOptions{
+			ComponentGlobals: controller.ComponentGlobals{
+				Logger:            l,
+				TraceProvider:     noop.NewTracerProvider(),
+				DataPath:          t.TempDir(),
+				MinStability:      featuregate.StabilityBeta,
+				CheckSecrets:      checkSecrets,
+				OnBlockNodeUpdate: func(cn controller.BlockNode) { /* no-op */ },
+				Registerer:        prometheus.NewRegistry(),
+				NewModuleController: func(id string) controller.ModuleController {
+					return fakeModuleController{}
+				},
+			},
+			ComponentRegistry: controller.NewRegistryMap(featuregate.StabilityBeta, map[string]component.Registration{
+				passthrough.Name: passthrough,
+				secret.Name:      secret,
+			}),
+		})
+	}
+
+	testFile := `
+		testcomponents.secret "found" {
+			input = "found"
+		}
+
+		testcomponents.secret "missing" {
+			input = "missing"
+		}
+
+		testcomponents.passthrough "env" {
+			input = env("LOADER_CHECK_SECRETS_UNSET")
+		}
+	`
+
+	t.Run("Missing secrets are reported together", func(t *testing.T) {
+		checked = nil
+		diags := applyFromContent(t, newLoader(true), []byte(testFile), nil, nil)
+		require.Len(t, diags, 2)
+		require.ErrorContains(t, diags.ErrorOrNil(), `environment variable "LOADER_CHECK_SECRETS_UNSET" referenced by env() is not set`)
+		require.ErrorContains(t, diags.ErrorOrNil(), `secret reference cannot be resolved: secret "missing" not found`)
+		require.ElementsMatch(t, []string{"found", "missing"}, checked)
+	})
+
+	t.Run("Secrets are only checked before the first build", func(t *testing.T) {
+		checked = nil
+		l := newLoader(true)
+		found := []byte(`
+			testcomponents.secret "found" {
+				input = "found"
+			}
+		`)
+		require.NoError(t, applyFromContent(t, l, found, nil, nil).ErrorOrNil())
+		require.NoError(t, applyFromContent(t, l, found, nil, nil).ErrorOrNil())
+		require.Equal(t, []string{"found"}, checked)
+	})
+
+	t.Run("Secrets aren't checked when disabled", func(t *testing.T) {
+		checked = nil
+		diags := applyFromContent(t, newLoader(false), []byte(testFile), nil, nil)
+		require.NoError(t, diags.ErrorOrNil())
+		require.Empty(t, checked)
+	})
+}
+
 func applyFromContent(t *testing.T, l *controller.Loader, componentBytes []byte, configBytes []byte, declareBytes []byte) diag.Diagnostics {
 	t.Helper()
 
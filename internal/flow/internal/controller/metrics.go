@@ -15,6 +15,9 @@ type controllerMetrics struct {
 	evaluationQueueSize         prometheus.Gauge
 	slowComponentThreshold      time.Duration
 	slowComponentEvaluationTime *prometheus.CounterVec
+	exportsPropagationTime      *prometheus.HistogramVec
+	delayedEvaluations          *prometheus.CounterVec
+	coalescedEvaluations        *prometheus.CounterVec
 }
 
 // newControllerMetrics inits the metrics for the components controller
@@ -69,6 +72,25 @@ func newControllerMetrics(parent, id string) *controllerMetrics {
 		ConstLabels: map[string]string{"controller_path": parent, "controller_id": id},
 	}, []string{"component_id"})
 
+	cm.exportsPropagationTime = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "agent_component_exports_propagation_seconds",
+		Help:        "Time between a node updating its exports and a dependant finishing its evaluation with them, per edge of the graph",
+		ConstLabels: map[string]string{"controller_path": parent, "controller_id": id},
+		Buckets:     evaluationTimesBuckets,
+	}, []string{"originator_id", "node_id"})
+
+	cm.delayedEvaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "agent_component_evaluations_delayed_total",
+		Help:        "Number of component evaluations delayed until the minimum evaluation interval of the component elapsed",
+		ConstLabels: map[string]string{"controller_path": parent, "controller_id": id},
+	}, []string{"component_id"})
+
+	cm.coalescedEvaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "agent_component_evaluations_coalesced_total",
+		Help:        "Number of component evaluations merged into an already delayed evaluation",
+		ConstLabels: map[string]string{"controller_path": parent, "controller_id": id},
+	}, []string{"component_id"})
+
 	return cm
 }
 
@@ -79,12 +101,19 @@ func (cm *controllerMetrics) onComponentEvaluationDone(name string, duration tim
 	}
 }
 
+func (cm *controllerMetrics) onExportsPropagated(originator, name string, duration time.Duration) {
+	cm.exportsPropagationTime.WithLabelValues(originator, name).Observe(duration.Seconds())
+}
+
 func (cm *controllerMetrics) Collect(ch chan<- prometheus.Metric) {
 	cm.componentEvaluationTime.Collect(ch)
 	cm.controllerEvaluation.Collect(ch)
 	cm.dependenciesWaitTime.Collect(ch)
 	cm.evaluationQueueSize.Collect(ch)
 	cm.slowComponentEvaluationTime.Collect(ch)
+	cm.exportsPropagationTime.Collect(ch)
+	cm.delayedEvaluations.Collect(ch)
+	cm.coalescedEvaluations.Collect(ch)
 }
 
 func (cm *controllerMetrics) Describe(ch chan<- *prometheus.Desc) {
@@ -93,6 +122,9 @@ func (cm *controllerMetrics) Describe(ch chan<- *prometheus.Desc) {
 	cm.dependenciesWaitTime.Describe(ch)
 	cm.evaluationQueueSize.Describe(ch)
 	cm.slowComponentEvaluationTime.Describe(ch)
+	cm.exportsPropagationTime.Describe(ch)
+	cm.delayedEvaluations.Describe(ch)
+	cm.coalescedEvaluations.Describe(ch)
 }
 
 type controllerCollector struct {
@@ -126,7 +158,7 @@ func (cc *controllerCollector) Collect(ch chan<- prometheus.Metric) {
 	for _, im := range cc.l.Imports() {
 		health := im.CurrentHealth().Health.String()
 		componentsByHealth[health]++
-		im.registry.Collect(ch)
+		im.collectMetrics(ch)
 	}
 
 	for health, count := range componentsByHealth {
@@ -137,3 +169,46 @@ func (cc *controllerCollector) Collect(ch chan<- prometheus.Metric) {
 func (cc *controllerCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- cc.runningComponentsTotal
 }
+
+// importMetrics contains the metrics of an import node.
+type importMetrics struct {
+	contentUpdates prometheus.Counter
+	lastUpdate     prometheus.Gauge
+	children       prometheus.Gauge
+	evalFailures   *prometheus.CounterVec
+}
+
+// Reasons an import node fails to evaluate, used as the reason label of the
+// evaluation failures metric.
+const (
+	importFailureSource  = "source"        // The source couldn't be evaluated.
+	importFailureSize    = "size"          // The content exceeds the maximum size.
+	importFailureParse   = "parse"         // The content couldn't be parsed.
+	importFailureInvalid = "invalid"       // The content holds invalid blocks.
+	importFailureNested  = "nested_import" // A nested import block failed to evaluate.
+)
+
+// newImportMetrics creates the metrics of an import node and registers them
+// to reg.
+func newImportMetrics(reg prometheus.Registerer) *importMetrics {
+	im := &importMetrics{
+		contentUpdates: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_import_content_updates_total",
+			Help: "Total number of times new content of the import was successfully loaded from its source",
+		}),
+		lastUpdate: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_import_last_update_timestamp",
+			Help: "Timestamp in seconds of the last time the source of the import returned valid content",
+		}),
+		children: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_import_children",
+			Help: "Number of import blocks nested in the content of the import",
+		}),
+		evalFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_import_eval_failures_total",
+			Help: "Total number of failures to evaluate the source or the content of the import",
+		}, []string{"reason"}),
+	}
+	reg.MustRegister(im.contentUpdates, im.lastUpdate, im.children, im.evalFailures)
+	return im
+}
@@ -8,8 +8,10 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"runtime/pprof"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/log"
@@ -18,6 +20,8 @@ import (
 	"github.com/grafana/agent/internal/flow/logging"
 	"github.com/grafana/agent/internal/flow/logging/level"
 	"github.com/grafana/agent/internal/flow/tracing"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/river/ast"
 	"github.com/grafana/river/vm"
 	"github.com/prometheus/client_golang/prometheus"
@@ -67,6 +71,11 @@ type ComponentGlobals struct {
 	TraceProvider       trace.TracerProvider                   // Tracer shared between all managed components.
 	DataPath            string                                 // Shared directory where component data may be stored
 	MinStability        featuregate.Stability                  // Minimum allowed stability level for features
+	CheckSecrets        bool                                   // Check secret references before building components
+	DryRun              bool                                   // Evaluate component arguments without building components
+	ImportLimits        ImportLimits                           // Limits applied to the content of import sources
+	MaxExportsSize      int                                    // Maximum number of values in the exports of a component; 0 for no limit
+	CollectorCallSites  bool                                   // Record where components register collectors from
 	OnBlockNodeUpdate   func(cn BlockNode)                     // Informs controller that we need to reevaluate
 	OnExportsChange     func(exports map[string]any)           // Invoked when the managed component updated its exports
 	Registerer          prometheus.Registerer                  // Registerer for serving agent and component metrics
@@ -91,7 +100,14 @@ type BuiltinComponentNode struct {
 	registry          *prometheus.Registry
 	exportsType       reflect.Type
 	moduleController  ModuleController
-	OnBlockNodeUpdate func(cn BlockNode) // Informs controller that we need to reevaluate
+	checkSecrets      bool                              // Whether to check secret references before building
+	dryRun            bool                              // Whether to only evaluate arguments without building
+	maxExportsSize    int                               // Maximum number of values in exports; 0 for no limit
+	recordCallSites   bool                              // Whether to record where collectors are registered from
+	exportsSizeGauge  prometheus.Gauge                  // Number of values in the latest exports; only set with a limit
+	managedReg        atomic.Pointer[util.Unregisterer] // Collectors registered by the managed component
+	profileLabels     pprof.LabelSet                    // Labels set on goroutines of the managed component
+	OnBlockNodeUpdate func(cn BlockNode)                // Informs controller that we need to reevaluate
 
 	mut     sync.RWMutex
 	block   *ast.BlockStmt // Current River block to derive args from
@@ -103,12 +119,16 @@ type BuiltinComponentNode struct {
 	// set asynchronously while mut is still being held (i.e., when calling Evaluate
 	// and the managed component immediately creates new exports)
 
-	healthMut  sync.RWMutex
-	evalHealth component.Health // Health of the last evaluate
-	runHealth  component.Health // Health of running the component
+	healthMut     sync.RWMutex
+	evalHealth    component.Health // Health of the last evaluate
+	runHealth     component.Health // Health of running the component
+	exportsHealth component.Health // Health of the latest exports; only reported when unhealthy
 
 	exportsMut sync.RWMutex
 	exports    component.Exports // Evaluated exports for the managed component
+
+	panicMut  sync.Mutex
+	lastPanic *componentPanic // Last panic recovered from the managed component
 }
 
 var _ ComponentNode = (*BuiltinComponentNode)(nil)
@@ -146,8 +166,17 @@ func NewBuiltinComponentNode(globals ComponentGlobals, reg component.Registratio
 		reg:               reg,
 		exportsType:       getExportsType(reg),
 		moduleController:  globals.NewModuleController(globalID),
+		checkSecrets:      globals.CheckSecrets,
+		dryRun:            globals.DryRun,
+		maxExportsSize:    globals.MaxExportsSize,
+		recordCallSites:   globals.CollectorCallSites,
 		OnBlockNodeUpdate: globals.OnBlockNodeUpdate,
 
+		profileLabels: pprof.Labels(
+			component.ProfileLabelComponentID, nodeID,
+			component.ProfileLabelModule, globals.ControllerID,
+		),
+
 		block: b,
 		eval:  vm.New(b.Body),
 
@@ -160,6 +189,21 @@ func NewBuiltinComponentNode(globals ComponentGlobals, reg component.Registratio
 	}
 	cn.managedOpts = getManagedOptions(globals, cn)
 
+	cn.exportsSizeGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "agent_component_exports_size",
+		Help: "Number of values in the latest exports of the component, counting list elements, map entries and fields. Only reported when the exports size is limited",
+	})
+	cn.managedOpts.Registerer.MustRegister(cn.exportsSizeGauge)
+	cn.managedOpts.Registerer.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "agent_component_registered_collectors",
+		Help: "Number of Prometheus collectors currently registered by the component",
+	}, func() float64 {
+		if reg := cn.managedReg.Load(); reg != nil {
+			return float64(reg.Count())
+		}
+		return 0
+	}))
+
 	return cn
 }
 
@@ -242,7 +286,12 @@ func (cn *BuiltinComponentNode) UpdateBlock(b *ast.BlockStmt) {
 // Evaluate will return an error if the River block cannot be evaluated or if
 // decoding to arguments fails.
 func (cn *BuiltinComponentNode) Evaluate(scope *vm.Scope) error {
-	err := cn.evaluate(scope)
+	// Goroutines started while building or updating the managed component
+	// inherit the profiling labels of the component.
+	var err error
+	pprof.Do(context.Background(), cn.profileLabels, func(context.Context) {
+		err = cn.evaluate(scope)
+	})
 
 	switch err {
 	case nil:
@@ -268,8 +317,25 @@ func (cn *BuiltinComponentNode) evaluate(scope *vm.Scope) error {
 	argsCopyValue := reflect.ValueOf(argsPointer).Elem().Interface()
 
 	if cn.managed == nil {
+		if cn.checkSecrets && cn.reg.CheckSecrets != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), secretCheckTimeout)
+			err := cn.reg.CheckSecrets(ctx, argsCopyValue)
+			cancel()
+			if err != nil {
+				return fmt.Errorf("secret reference cannot be resolved: %w", err)
+			}
+		}
+
+		if cn.dryRun {
+			// The arguments are valid. The exports keep their zero value, so
+			// the loader reports errors of the nodes depending on them as
+			// warnings.
+			cn.args = argsCopyValue
+			return nil
+		}
+
 		// We haven't built the managed component successfully yet.
-		managed, err := cn.reg.Build(cn.managedOpts, argsCopyValue)
+		managed, err := cn.build(argsCopyValue)
 		if err != nil {
 			return fmt.Errorf("building component: %w", err)
 		}
@@ -287,7 +353,7 @@ func (cn *BuiltinComponentNode) evaluate(scope *vm.Scope) error {
 	}
 
 	// Update the existing managed component
-	if err := cn.managed.Update(argsCopyValue); err != nil {
+	if err := cn.updateManaged(cn.managed, argsCopyValue); err != nil {
 		return fmt.Errorf("updating component: %w", err)
 	}
 
@@ -295,13 +361,48 @@ func (cn *BuiltinComponentNode) evaluate(scope *vm.Scope) error {
 	return nil
 }
 
+// build builds a new instance of the managed component from args. Collectors
+// registered by a previous instance are unregistered first. cn.mut must be
+// held when calling build.
+func (cn *BuiltinComponentNode) build(args component.Arguments) (component.Component, error) {
+	if prev := cn.managedReg.Load(); prev != nil && !prev.UnregisterAll() {
+		level.Warn(cn.managedOpts.Logger).Log("msg", "collectors of the previous instance of the component are still registered", "collectors", util.FormatLeaks(prev.LeakReport()))
+	}
+
+	// When enabled, record where collectors are registered from, so that the
+	// leaks reported above point to the code which registered them.
+	managedReg := util.WrapWithUnregisterer(cn.managedOpts.Registerer)
+	managedReg.SetDebug(cn.recordCallSites)
+	cn.managedReg.Store(managedReg)
+
+	opts := cn.managedOpts
+	opts.Registerer = managedReg
+	return cn.reg.Build(opts, args)
+}
+
 // Run runs the managed component in the calling goroutine until ctx is
 // canceled. Evaluate must have been called at least once without returning an
 // error before calling Run.
 //
+// If the managed component panics while running, the panic is recovered and
+// the component is rebuilt from its current arguments and restarted with a
+// backoff until ctx is canceled. Panics in goroutines the managed component
+// starts itself aren't recovered.
+//
+// The managed component and every goroutine it starts are labeled with the
+// component and module IDs, so that profiles can be grouped by component.
+//
 // Run will immediately return ErrUnevaluated if Evaluate has never been called
 // successfully. Otherwise, Run will return nil.
 func (cn *BuiltinComponentNode) Run(ctx context.Context) error {
+	var err error
+	pprof.Do(ctx, cn.profileLabels, func(ctx context.Context) {
+		err = cn.run(ctx)
+	})
+	return err
+}
+
+func (cn *BuiltinComponentNode) run(ctx context.Context) error {
 	cn.mut.RLock()
 	managed := cn.managed
 	cn.mut.RUnlock()
@@ -310,11 +411,44 @@ func (cn *BuiltinComponentNode) Run(ctx context.Context) error {
 		return ErrUnevaluated
 	}
 
-	cn.setRunHealth(component.HealthTypeHealthy, "started component")
-	err := cn.managed.Run(ctx)
+	var (
+		err      error
+		panicked bool
+		restarts = backoff.New(ctx, panicBackoff)
+	)
+	logger := cn.managedOpts.Logger
+run:
+	for {
+		cn.setRunHealth(component.HealthTypeHealthy, "started component")
+
+		start := time.Now()
+		panicked, err = cn.runManaged(ctx, managed)
+		if !panicked || ctx.Err() != nil {
+			break
+		}
+
+		// Components which ran for a while before panicking are restarted
+		// quickly again.
+		if time.Since(start) > panicBackoff.MaxBackoff {
+			restarts.Reset()
+		}
+
+		// The panic may have left the instance in an inconsistent state, so a
+		// new one is built before restarting.
+		for {
+			cn.setRunHealth(component.HealthTypeUnhealthy, fmt.Sprintf("%s; restarting component", err))
+			restarts.Wait()
+			if !restarts.Ongoing() {
+				break run
+			}
+			if managed, err = cn.rebuildManaged(); err == nil {
+				break
+			}
+			level.Error(logger).Log("msg", "failed to rebuild component after panic", "err", err)
+		}
+	}
 
 	var exitMsg string
-	logger := cn.managedOpts.Logger
 	if err != nil {
 		level.Error(logger).Log("msg", "component exited with error", "err", err)
 		exitMsg = fmt.Sprintf("component shut down with error: %s", err)
@@ -372,6 +506,17 @@ func (cn *BuiltinComponentNode) setExports(e component.Exports) {
 	// exports.
 	var changed bool
 
+	// Exports larger than the limit are rejected, so that dependants don't
+	// have to be evaluated with them. Dependants keep the previous exports.
+	// Exports aren't walked to measure their size when there's no limit.
+	if cn.maxExportsSize > 0 {
+		size := exportsSize(e)
+		cn.exportsSizeGauge.Set(float64(size))
+		if !cn.checkExportsSize(size) {
+			return
+		}
+	}
+
 	cn.exportsMut.Lock()
 	if !reflect.DeepEqual(cn.exports, e) {
 		changed = true
@@ -391,7 +536,8 @@ func (cn *BuiltinComponentNode) setExports(e component.Exports) {
 //
 //  1. Health from the call to Run().
 //  2. Health from the last call to Evaluate().
-//  3. Health reported from the component.
+//  3. Health of the latest exports, if they exceeded the size limit.
+//  4. Health reported from the component.
 func (cn *BuiltinComponentNode) CurrentHealth() component.Health {
 	cn.healthMut.RLock()
 	defer cn.healthMut.RUnlock()
@@ -401,12 +547,41 @@ func (cn *BuiltinComponentNode) CurrentHealth() component.Health {
 		evalHealth = cn.evalHealth
 	)
 
+	healths := []component.Health{evalHealth}
+	if cn.exportsHealth.Health == component.HealthTypeUnhealthy {
+		healths = append(healths, cn.exportsHealth)
+	}
 	if hc, ok := cn.managed.(component.HealthComponent); ok {
-		componentHealth := hc.CurrentHealth()
-		return component.LeastHealthy(runHealth, evalHealth, componentHealth)
+		healths = append(healths, hc.CurrentHealth())
 	}
+	return component.LeastHealthy(runHealth, healths...)
+}
 
-	return component.LeastHealthy(runHealth, evalHealth)
+// checkExportsSize reports whether exports of the given size are within the
+// limit, updating the health of the exports accordingly.
+func (cn *BuiltinComponentNode) checkExportsSize(size int) bool {
+	cn.healthMut.Lock()
+	defer cn.healthMut.Unlock()
+
+	if cn.maxExportsSize <= 0 || size <= cn.maxExportsSize {
+		cn.exportsHealth = component.Health{
+			Health:     component.HealthTypeHealthy,
+			Message:    "exports within size limit",
+			UpdateTime: time.Now(),
+		}
+		return true
+	}
+
+	msg := fmt.Sprintf("exports have %d values, more than the limit of %d; dependants keep using the previous exports", size, cn.maxExportsSize)
+	if cn.exportsHealth.Health != component.HealthTypeUnhealthy {
+		level.Warn(cn.managedOpts.Logger).Log("msg", "rejecting exports exceeding the size limit", "size", size, "limit", cn.maxExportsSize)
+	}
+	cn.exportsHealth = component.Health{
+		Health:     component.HealthTypeUnhealthy,
+		Message:    msg,
+		UpdateTime: time.Now(),
+	}
+	return false
 }
 
 // DebugInfo returns debugging information from the managed component (if any).
@@ -414,10 +589,17 @@ func (cn *BuiltinComponentNode) DebugInfo() interface{} {
 	cn.mut.RLock()
 	defer cn.mut.RUnlock()
 
+	var info interface{}
 	if dc, ok := cn.managed.(component.DebugComponent); ok {
-		return dc.DebugInfo()
+		info = dc.DebugInfo()
 	}
-	return nil
+
+	cn.panicMut.Lock()
+	defer cn.panicMut.Unlock()
+	if cn.lastPanic != nil {
+		return panicDebugInfo{LastPanic: *cn.lastPanic, Component: info}
+	}
+	return info
 }
 
 // setEvalHealth sets the internal health from a call to Evaluate. See Health
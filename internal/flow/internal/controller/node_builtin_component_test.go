@@ -1,11 +1,20 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/internal/testcomponents"
+	"github.com/grafana/dskit/backoff"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 )
 
 func TestGlobalID(t *testing.T) {
@@ -62,3 +71,123 @@ func TestSplitPath(t *testing.T) {
 		require.Equal(t, tt.id, id)
 	}
 }
+
+func TestRunRecoversFromPanics(t *testing.T) {
+	var builds, runs atomic.Int32
+	leftoverStopped := make(chan struct{})
+	reg := component.Registration{
+		Build: func(opts component.Options, _ component.Arguments) (component.Component, error) {
+			builds.Inc()
+
+			// Rebuilt instances can register the same metrics again.
+			opts.Registerer.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+				Name: "test_builds_total",
+			}))
+
+			return &testcomponents.Fake{
+				RunFunc: func(ctx context.Context) error {
+					if runs.Inc() == 1 {
+						go func() {
+							<-ctx.Done()
+							close(leftoverStopped)
+						}()
+						panic("boom")
+					}
+					<-ctx.Done()
+					return nil
+				},
+				UpdateFunc: func(component.Arguments) error {
+					panic("bad update")
+				},
+			}, nil
+		},
+	}
+	cn := &BuiltinComponentNode{
+		nodeID: "testcomponents.panic",
+		reg:    reg,
+		managedOpts: component.Options{
+			Logger:     log.NewNopLogger(),
+			Registerer: prometheus.NewRegistry(),
+		},
+	}
+	managed, err := cn.build(nil)
+	require.NoError(t, err)
+	cn.managed = managed
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- cn.Run(ctx) }()
+
+	// The component is rebuilt and restarted after the panic.
+	require.Eventually(t, func() bool {
+		return runs.Load() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(2), builds.Load())
+	require.Equal(t, component.HealthTypeHealthy, cn.CurrentHealth().Health)
+
+	// Goroutines of the instance which panicked are stopped.
+	select {
+	case <-leftoverStopped:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "context of the instance which panicked wasn't canceled")
+	}
+
+	cn.mut.RLock()
+	rebuilt := cn.managed
+	cn.mut.RUnlock()
+	require.NotSame(t, managed, rebuilt)
+
+	info, ok := cn.DebugInfo().(panicDebugInfo)
+	require.True(t, ok)
+	require.Equal(t, "boom", info.LastPanic.Value)
+	require.Equal(t, 1, info.LastPanic.Count)
+	require.Contains(t, info.LastPanic.Stack, "TestRunRecoversFromPanics")
+
+	err = cn.updateManaged(rebuilt, nil)
+	require.EqualError(t, err, "component panicked while updating: bad update")
+	require.Equal(t, 2, cn.DebugInfo().(panicDebugInfo).LastPanic.Count)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestRunRetriesFailedRebuilds(t *testing.T) {
+	defer func(b backoff.Config) { panicBackoff = b }(panicBackoff)
+	panicBackoff = backoff.Config{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	var builds atomic.Int32
+	reg := component.Registration{
+		Build: func(component.Options, component.Arguments) (component.Component, error) {
+			switch builds.Inc() {
+			case 1:
+				return &testcomponents.Fake{
+					RunFunc: func(context.Context) error { panic("boom") },
+				}, nil
+			case 2:
+				return nil, errors.New("build failed")
+			default:
+				return &testcomponents.Fake{}, nil
+			}
+		},
+	}
+	cn := &BuiltinComponentNode{
+		nodeID:      "testcomponents.panic",
+		reg:         reg,
+		managedOpts: component.Options{Logger: log.NewNopLogger()},
+	}
+	managed, err := cn.build(nil)
+	require.NoError(t, err)
+	cn.managed = managed
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- cn.Run(ctx) }()
+
+	// A failed rebuild is retried until it succeeds.
+	require.Eventually(t, func() bool {
+		return builds.Load() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
@@ -2,11 +2,15 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"hash/fnv"
 	"maps"
+	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -16,21 +20,75 @@ import (
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/flow/internal/importsource"
+	"github.com/grafana/agent/internal/flow/internal/stdlib"
 	"github.com/grafana/agent/internal/flow/logging/level"
 	"github.com/grafana/agent/internal/flow/tracing"
 	"github.com/grafana/agent/internal/runner"
 	"github.com/grafana/river/ast"
 	"github.com/grafana/river/parser"
+	"github.com/grafana/river/printer"
 	"github.com/grafana/river/vm"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Default limits applied to the content of import sources.
+const (
+	DefaultImportMaxContentSize = 10 << 20 // 10MiB
+	DefaultImportParseTimeout   = 10 * time.Second
+	DefaultImportConcurrency    = 1
+)
+
+// importCacheFile is the name of the file in the data directory of an import
+// node which holds the last content successfully loaded from its source.
+const importCacheFile = "import_cache.json"
+
+// importCacheRetryInterval is how often the source of an import node is
+// evaluated again while the node uses cached content.
+var importCacheRetryInterval = 30 * time.Second
+
+// cachedImportContent is the content of an import node cached on disk.
+type cachedImportContent struct {
+	Time    time.Time         `json:"time"`
+	Content map[string]string `json:"content"`
+}
+
+// ImportLimits limits the content of import sources, so that a source
+// returning unexpected content such as a large binary can't exhaust the
+// resources of the controller. Zero values use the defaults.
+type ImportLimits struct {
+	MaxContentSize int64         // Maximum total size in bytes of the files returned by a source.
+	ParseTimeout   time.Duration // Maximum time to parse a file returned by a source.
+	Concurrency    int           // Maximum number of nested import blocks evaluated concurrently.
+}
+
+func (l ImportLimits) maxContentSize() int64 {
+	if l.MaxContentSize <= 0 {
+		return DefaultImportMaxContentSize
+	}
+	return l.MaxContentSize
+}
+
+func (l ImportLimits) parseTimeout() time.Duration {
+	if l.ParseTimeout <= 0 {
+		return DefaultImportParseTimeout
+	}
+	return l.ParseTimeout
+}
+
+func (l ImportLimits) concurrency() int {
+	if l.Concurrency <= 0 {
+		return DefaultImportConcurrency
+	}
+	return l.Concurrency
+}
+
 // ImportConfigNode imports declare and import blocks via a managed import source.
 // The imported declare are stored in importedDeclares.
 // For every imported import block, the ImportConfigNode will create ImportConfigNode children.
 // The children are evaluated and ran by the parent.
-// When an ImportConfigNode receives new content from its source, it updates its importedDeclares and recreates its children.
-// Then an update call is propagated to the root ImportConfigNode to inform the controller for reevaluation.
+// When an ImportConfigNode receives new content from its source, it updates its importedDeclares and its children.
+// Children whose import block didn't change are kept running.
+// If any declare or import block changed, an update call is propagated to the root ImportConfigNode to inform the controller for reevaluation.
 type ImportConfigNode struct {
 	nodeID        string
 	globalID      string
@@ -40,17 +98,30 @@ type ImportConfigNode struct {
 	block         *ast.BlockStmt            // Current River blocks to derive config from
 	source        importsource.ImportSource // source retrieves the module content
 	registry      *prometheus.Registry
+	metrics       *importMetrics
+	blockHash     uint64 // Hash of the block when the node is a child of another import node
 
 	OnBlockNodeUpdate func(cn BlockNode) // notifies the controller or the parent for reevaluation
 	logger            log.Logger
 
 	importChildrenUpdateChan chan struct{} // used to trigger an update of the running children
 
+	// cachePath is where the last successfully loaded content is cached, so
+	// that the node can be evaluated from the cached content when its source
+	// is unreachable on startup. It's empty if the source isn't cached.
+	cachePath     string
+	loadingCache  atomic.Bool // Set while the cached content is loaded, so that it isn't cached again
+	evalMut       sync.Mutex  // Serializes evaluations of the source
+	fallbackScope *vm.Scope   // Scope to evaluate the source with while cached content is used; nil otherwise
+
 	mut                       sync.RWMutex
 	importedContent           map[string]string
 	importConfigNodesChildren map[string]*ImportConfigNode
 	importChildrenRunning     bool
 	importedDeclares          map[string]ast.Body
+	importedDeclareHashes     map[string]uint64 // Hashes of the importedDeclares, used to detect changes
+	lastContentUpdate         time.Time         // Last time the content was successfully loaded; zero if it never was
+	failedChildren            map[string]error  // Errors of the nested import blocks which failed to evaluate in the last content update, by label
 
 	healthMut     sync.RWMutex
 	evalHealth    component.Health // Health of the last source evaluation
@@ -84,7 +155,14 @@ func NewImportConfigNode(block *ast.BlockStmt, globals ComponentGlobals, sourceT
 	}
 	managedOpts := getImportManagedOptions(globals, cn)
 	cn.logger = managedOpts.Logger
-	cn.source = importsource.NewImportSource(sourceType, managedOpts, vm.New(block.Body), cn.onContentUpdate)
+	cn.metrics = newImportMetrics(managedOpts.Registerer)
+	switch sourceType {
+	case importsource.Git, importsource.HTTP:
+		if globals.DataPath != "" {
+			cn.cachePath = filepath.Join(managedOpts.DataPath, importCacheFile)
+		}
+	}
+	cn.source = importsource.NewImportSource(sourceType, managedOpts, vm.New(block.Body), globals.ImportLimits.maxContentSize(), cn.onContentUpdate)
 	return cn
 }
 
@@ -145,6 +223,37 @@ func (cn *ImportConfigNode) setContentHealth(t component.HealthType, msg string)
 	}
 }
 
+// EvaluationHealth returns the health of the last evaluation of the import
+// source.
+func (cn *ImportConfigNode) EvaluationHealth() component.Health {
+	cn.healthMut.RLock()
+	defer cn.healthMut.RUnlock()
+	return cn.evalHealth
+}
+
+// RunHealth returns the health of running the import source.
+func (cn *ImportConfigNode) RunHealth() component.Health {
+	cn.healthMut.RLock()
+	defer cn.healthMut.RUnlock()
+	return component.LeastHealthy(cn.runHealth, cn.source.CurrentHealth())
+}
+
+// LastContentUpdate returns the last time the imported content was
+// successfully loaded, or the zero time if it never was.
+func (cn *ImportConfigNode) LastContentUpdate() time.Time {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.lastContentUpdate
+}
+
+// ImportedContent returns a copy of the content last received from the
+// import source by file name.
+func (cn *ImportConfigNode) ImportedContent() map[string]string {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return maps.Clone(cn.importedContent)
+}
+
 // CurrentHealth returns the current health of the ImportConfigNode.
 //
 // The health of a ImportConfigNode is determined by combining:
@@ -175,18 +284,133 @@ func (cn *ImportConfigNode) CurrentHealth() component.Health {
 }
 
 // Evaluate implements BlockNode and evaluates the import source.
+//
+// If the source fails to be evaluated before any content was loaded, such
+// as when a remote source is unreachable on startup, the content cached on
+// disk is loaded instead and the source is evaluated again while the node
+// runs, until it succeeds.
 func (cn *ImportConfigNode) Evaluate(scope *vm.Scope) error {
+	cn.evalMut.Lock()
+	defer cn.evalMut.Unlock()
+	return cn.evaluate(scope)
+}
+
+// evaluate evaluates the import source. evalMut must be held.
+func (cn *ImportConfigNode) evaluate(scope *vm.Scope) error {
 	err := cn.source.Evaluate(scope)
-	switch err {
-	case nil:
+	if err == nil {
+		if cn.fallbackScope != nil {
+			level.Info(cn.logger).Log("msg", "source evaluated, no longer using cached content")
+			cn.fallbackScope = nil
+		}
 		cn.setEvalHealth(component.HealthTypeHealthy, "source evaluated")
-	default:
-		msg := fmt.Sprintf("source evaluation failed: %s", err)
-		cn.setEvalHealth(component.HealthTypeUnhealthy, msg)
+		return nil
 	}
+	cn.metrics.evalFailures.WithLabelValues(importFailureSource).Inc()
+
+	if cached, ok := cn.loadCachedContent(); ok {
+		if cn.fallbackScope == nil {
+			level.Warn(cn.logger).Log("msg", "source evaluation failed, using cached content", "cached_at", cached.Time, "err", err)
+		}
+		cn.fallbackScope = scope
+		cn.setEvalHealth(component.HealthTypeUnhealthy, fmt.Sprintf("source evaluation failed, using content cached at %s: %s", cached.Time.Format(time.RFC3339), err))
+		return nil
+	}
+
+	cn.setEvalHealth(component.HealthTypeUnhealthy, fmt.Sprintf("source evaluation failed: %s", err))
 	return err
 }
 
+// loadCachedContent loads the content cached on disk, unless content was
+// already loaded from the source. evalMut must be held.
+func (cn *ImportConfigNode) loadCachedContent() (cachedImportContent, bool) {
+	if cn.cachePath == "" || (cn.fallbackScope == nil && !cn.LastContentUpdate().IsZero()) {
+		return cachedImportContent{}, false
+	}
+
+	bb, err := os.ReadFile(cn.cachePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			level.Error(cn.logger).Log("msg", "failed to read cached content", "err", err)
+		}
+		return cachedImportContent{}, false
+	}
+	var cached cachedImportContent
+	if err := json.Unmarshal(bb, &cached); err != nil {
+		level.Error(cn.logger).Log("msg", "failed to decode cached content", "err", err)
+		return cachedImportContent{}, false
+	}
+
+	cn.loadingCache.Store(true)
+	cn.onContentUpdate(cached.Content)
+	cn.loadingCache.Store(false)
+	if cn.LastContentUpdate().IsZero() {
+		// The cached content couldn't be loaded; the content health holds the
+		// reason.
+		return cachedImportContent{}, false
+	}
+	return cached, true
+}
+
+// storeCachedContent caches content on disk. mut must be held.
+func (cn *ImportConfigNode) storeCachedContent(content map[string]string) {
+	if cn.cachePath == "" || cn.loadingCache.Load() {
+		return
+	}
+
+	bb, err := json.Marshal(cachedImportContent{Time: time.Now(), Content: content})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(cn.cachePath), 0750)
+	}
+	if err == nil {
+		err = os.WriteFile(cn.cachePath+".tmp", bb, 0640)
+	}
+	if err == nil {
+		err = os.Rename(cn.cachePath+".tmp", cn.cachePath)
+	}
+	if err != nil {
+		level.Error(cn.logger).Log("msg", "failed to cache imported content", "err", err)
+	}
+}
+
+// evaluateUntilRecovered evaluates the source again every
+// importCacheRetryInterval while the node uses cached content. It reports
+// whether the source was evaluated successfully before ctx was canceled.
+func (cn *ImportConfigNode) evaluateUntilRecovered(ctx context.Context) bool {
+	ticker := time.NewTicker(importCacheRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		cn.evalMut.Lock()
+		usingCache := cn.fallbackScope != nil
+		cn.evalMut.Unlock()
+		if !usingCache {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+
+		cn.evalMut.Lock()
+		if cn.fallbackScope != nil {
+			_ = cn.evaluate(cn.fallbackScope)
+		}
+		cn.evalMut.Unlock()
+	}
+}
+
+// importedModule holds the declare blocks and the import children built from
+// a version of the imported content.
+type importedModule struct {
+	declares      map[string]ast.Body
+	declareHashes map[string]uint64
+	children      map[string]*ImportConfigNode
+	newChildren   []*ImportConfigNode // Children which were created rather than reused
+}
+
 // onContentUpdate is triggered every time the managed import source has new content.
 func (cn *ImportConfigNode) onContentUpdate(importedContent map[string]string) {
 	cn.mut.Lock()
@@ -197,6 +421,13 @@ func (cn *ImportConfigNode) onContentUpdate(importedContent map[string]string) {
 
 	// If the source sent the same content, there is no need to reload.
 	if maps.Equal(cn.importedContent, importedContent) {
+		cn.healthMut.RLock()
+		loaded := cn.contentHealth.Health == component.HealthTypeHealthy
+		cn.healthMut.RUnlock()
+		if loaded {
+			// The source is still up to date.
+			cn.metrics.lastUpdate.SetToCurrentTime()
+		}
 		return
 	}
 
@@ -204,48 +435,131 @@ func (cn *ImportConfigNode) onContentUpdate(importedContent map[string]string) {
 	for k, v := range importedContent {
 		cn.importedContent[k] = v
 	}
-	cn.importedDeclares = make(map[string]ast.Body)
-	cn.importConfigNodesChildren = make(map[string]*ImportConfigNode)
 
-	for f, ic := range importedContent {
-		parsedImportedContent, err := parser.ParseFile(cn.label, []byte(ic))
+	module := &importedModule{
+		declares:      make(map[string]ast.Body),
+		declareHashes: make(map[string]uint64),
+		children:      make(map[string]*ImportConfigNode),
+	}
+
+	var contentSize int64
+	for _, ic := range importedContent {
+		contentSize += int64(len(ic))
+	}
+	if maxSize := cn.globals.ImportLimits.maxContentSize(); contentSize > maxSize {
+		level.Error(cn.logger).Log("msg", "imported content is too large", "size", contentSize, "max_size", maxSize)
+		cn.setContentHealth(component.HealthTypeUnhealthy, fmt.Sprintf("imported content is %d bytes, which exceeds the maximum of %d bytes", contentSize, maxSize))
+		cn.metrics.evalFailures.WithLabelValues(importFailureSize).Inc()
+		return
+	}
+
+	// Files are processed in a stable order so that errors about blocks defined
+	// in several files are consistent.
+	files := make([]string, 0, len(importedContent))
+	for f := range importedContent {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	for _, f := range files {
+		parsedImportedContent, err := cn.parseImportedContent(importedContent[f])
 		if err != nil {
 			level.Error(cn.logger).Log("msg", "failed to parse file on update", "file", f, "err", err)
 			cn.setContentHealth(component.HealthTypeUnhealthy, fmt.Sprintf("imported content from %q cannot be parsed: %s", f, err))
+			cn.metrics.evalFailures.WithLabelValues(importFailureParse).Inc()
 			return
 		}
 
-		// populate importedDeclares and importConfigNodesChildren
-		err = cn.processImportedContent(parsedImportedContent)
+		// populate the declares and children of the module
+		err = cn.processImportedContent(module, parsedImportedContent)
 		if err != nil {
 			level.Error(cn.logger).Log("msg", "failed to process imported content", "file", f, "err", err)
 			cn.setContentHealth(component.HealthTypeUnhealthy, fmt.Sprintf("imported content from %q is invalid: %s", f, err))
+			cn.metrics.evalFailures.WithLabelValues(importFailureInvalid).Inc()
 			return
 		}
 	}
 
-	// evaluate the importConfigNodesChildren that have been created
-	err := cn.evaluateChildren()
-	if err != nil {
-		level.Error(cn.logger).Log("msg", "failed to evaluate nested import", "err", err)
-		cn.setContentHealth(component.HealthTypeUnhealthy, fmt.Sprintf("nested import block failed to evaluate: %s", err))
+	// evaluate the children that have been created; reused children are
+	// already evaluated.
+	cn.failedChildren = evaluateChildren(module.newChildren, cn.globals.ImportLimits.concurrency())
+	if len(cn.failedChildren) > 0 {
+		labels := make([]string, 0, len(cn.failedChildren))
+		for label, err := range cn.failedChildren {
+			level.Error(cn.logger).Log("msg", "failed to evaluate nested import", "label", label, "err", err)
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		cn.setContentHealth(component.HealthTypeUnhealthy, fmt.Sprintf("%d of %d nested import blocks failed to evaluate: %s", len(labels), len(module.newChildren), strings.Join(labels, ", ")))
+		cn.metrics.evalFailures.WithLabelValues(importFailureNested).Inc()
 		return
 	}
 
-	// trigger to stop previous children from running and to start running the new ones.
-	if cn.importChildrenRunning {
+	var (
+		declaresChanged = cn.lastContentUpdate.IsZero() || !maps.Equal(cn.importedDeclareHashes, module.declareHashes)
+		childrenChanged = !maps.Equal(cn.importConfigNodesChildren, module.children)
+	)
+
+	cn.importedDeclares = module.declares
+	cn.importedDeclareHashes = module.declareHashes
+	cn.importConfigNodesChildren = module.children
+	cn.lastContentUpdate = time.Now()
+	cn.setContentHealth(component.HealthTypeHealthy, "content updated")
+	cn.metrics.contentUpdates.Inc()
+	cn.metrics.lastUpdate.SetToCurrentTime()
+	cn.metrics.children.Set(float64(len(module.children)))
+	cn.storeCachedContent(importedContent)
+
+	// trigger to stop removed children from running and to start running the new ones.
+	if childrenChanged && cn.importChildrenRunning {
 		select {
 		case cn.importChildrenUpdateChan <- struct{}{}: // queued trigger
 		default: // trigger already queued; no-op
 		}
 	}
 
-	cn.setContentHealth(component.HealthTypeHealthy, "content updated")
+	if !declaresChanged && !childrenChanged {
+		level.Debug(cn.logger).Log("msg", "imported content changed without changing its declare or import blocks")
+		return
+	}
 	cn.OnBlockNodeUpdate(cn)
 }
 
+// parseImportedContent parses a file of the imported content. Parsing is
+// abandoned once it takes longer than the parse timeout.
+//
+// The River parser can't be canceled, so the goroutine parsing an abandoned
+// file keeps running until the parser returns. The size of the content is
+// limited by the import source, which bounds how long that takes, and the
+// result is discarded.
+func (cn *ImportConfigNode) parseImportedContent(content string) (*ast.File, error) {
+	type parseResult struct {
+		file *ast.File
+		err  error
+	}
+
+	// The result channel is buffered so that the goroutine can exit once
+	// parsing ends, even after the timeout.
+	resultCh := make(chan parseResult, 1)
+	go func() {
+		file, err := parser.ParseFile(cn.label, []byte(content))
+		resultCh <- parseResult{file: file, err: err}
+	}()
+
+	timeout := cn.globals.ImportLimits.parseTimeout()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-resultCh:
+		return res.file, res.err
+	case <-timer.C:
+		return nil, fmt.Errorf("parsing took longer than %s", timeout)
+	}
+}
+
 // processImportedContent processes declare and import blocks of the provided ast content.
-func (cn *ImportConfigNode) processImportedContent(content *ast.File) error {
+func (cn *ImportConfigNode) processImportedContent(module *importedModule, content *ast.File) error {
 	for _, stmt := range content.Body {
 		blockStmt, ok := stmt.(*ast.BlockStmt)
 		if !ok {
@@ -255,9 +569,12 @@ func (cn *ImportConfigNode) processImportedContent(content *ast.File) error {
 		componentName := strings.Join(blockStmt.Name, ".")
 		switch componentName {
 		case declareType:
-			cn.processDeclareBlock(blockStmt)
-		case importsource.BlockImportFile, importsource.BlockImportString, importsource.BlockImportHTTP, importsource.BlockImportGit:
-			err := cn.processImportBlock(blockStmt, componentName)
+			err := cn.processDeclareBlock(module, blockStmt)
+			if err != nil {
+				return err
+			}
+		case importsource.BlockImportFile, importsource.BlockImportString, importsource.BlockImportHTTP, importsource.BlockImportGit, importsource.BlockImportS3, importsource.BlockImportKubernetes, importsource.BlockImportRegistry:
+			err := cn.processImportBlock(module, blockStmt, componentName)
 			if err != nil {
 				return err
 			}
@@ -268,40 +585,141 @@ func (cn *ImportConfigNode) processImportedContent(content *ast.File) error {
 	return nil
 }
 
-// processDeclareBlock stores the declare definition in the importedDeclares.
-func (cn *ImportConfigNode) processDeclareBlock(stmt *ast.BlockStmt) {
-	if _, ok := cn.importedDeclares[stmt.Label]; ok {
-		level.Error(cn.logger).Log("msg", "declare block redefined", "name", stmt.Label)
-		return
+// processDeclareBlock stores the declare definition in the module.
+func (cn *ImportConfigNode) processDeclareBlock(module *importedModule, stmt *ast.BlockStmt) error {
+	if _, ok := module.declares[stmt.Label]; ok {
+		return fmt.Errorf("declare block redefined %s", stmt.Label)
 	}
-	cn.importedDeclares[stmt.Label] = stmt.Body
+	hash, err := hashNode(stmt.Body)
+	if err != nil {
+		return fmt.Errorf("declare block %s: %w", stmt.Label, err)
+	}
+	module.declares[stmt.Label] = stmt.Body
+	module.declareHashes[stmt.Label] = hash
+	return nil
 }
 
-// processDeclareBlock creates an ImportConfigNode child from the provided import block.
-func (cn *ImportConfigNode) processImportBlock(stmt *ast.BlockStmt, fullName string) error {
-	sourceType := importsource.GetSourceType(fullName)
-	if _, ok := cn.importConfigNodesChildren[stmt.Label]; ok {
+// processImportBlock adds an ImportConfigNode child for the provided import
+// block to the module. The existing child is reused if its block didn't
+// change.
+func (cn *ImportConfigNode) processImportBlock(module *importedModule, stmt *ast.BlockStmt, fullName string) error {
+	if _, ok := module.children[stmt.Label]; ok {
 		return fmt.Errorf("import block redefined %s", stmt.Label)
 	}
+	hash, err := hashNode(stmt)
+	if err != nil {
+		return fmt.Errorf("import block %s: %w", stmt.Label, err)
+	}
+	if child, ok := cn.importConfigNodesChildren[stmt.Label]; ok && child.blockHash == hash {
+		module.children[stmt.Label] = child
+		return nil
+	}
+
+	sourceType := importsource.GetSourceType(fullName)
 	childGlobals := cn.globals
+	// Children are scoped to the parent, so that their metrics and data path
+	// don't collide with those of import blocks with the same label elsewhere.
+	childGlobals.ControllerID = cn.globalID
 	// Children have a special OnBlockNodeUpdate function which notifies the parent when its content changes.
 	childGlobals.OnBlockNodeUpdate = cn.onChildrenContentUpdate
-	cn.importConfigNodesChildren[stmt.Label] = NewImportConfigNode(stmt, childGlobals, sourceType)
+	child := NewImportConfigNode(stmt, childGlobals, sourceType)
+	child.blockHash = hash
+	module.children[stmt.Label] = child
+	module.newChildren = append(module.newChildren, child)
 	return nil
 }
 
-// evaluateChildren evaluates the import nodes managed by this import node.
-func (cn *ImportConfigNode) evaluateChildren() error {
-	for _, child := range cn.importConfigNodesChildren {
-		err := child.Evaluate(&vm.Scope{
-			Parent:    nil,
-			Variables: make(map[string]interface{}),
+// hashNode returns a hash of the formatted River of node. Changes to the
+// formatting of node or to its comments don't change its hash.
+func hashNode(node ast.Node) (uint64, error) {
+	fnvHash := fnv.New64a()
+	if err := printer.Fprint(fnvHash, node); err != nil {
+		return 0, err
+	}
+	return fnvHash.Sum64(), nil
+}
+
+// evaluateChildren evaluates the provided import nodes managed by an import
+// node, running at most concurrency evaluations at once. All children are
+// evaluated even if some fail. It returns the errors of the failed children
+// by label, or nil if none failed.
+func evaluateChildren(children []*ImportConfigNode, concurrency int) map[string]error {
+	var (
+		errs = make([]error, len(children))
+		sem  = make(chan struct{}, concurrency)
+		wg   sync.WaitGroup
+	)
+	for i, child := range children {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, child *ImportConfigNode) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := child.Evaluate(&vm.Scope{
+				Parent:    stdlib.Scope,
+				Variables: make(map[string]interface{}),
+			})
+			errs[i] = err
+		}(i, child)
+	}
+	wg.Wait()
+
+	var failed map[string]error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if failed == nil {
+			failed = make(map[string]error)
+		}
+		failed[children[i].label] = err
+	}
+	return failed
+}
+
+// importDebugInfo is the debug info of an ImportConfigNode.
+type importDebugInfo struct {
+	NestedImports []nestedImportStatus `river:"nested_import,block,optional"`
+}
+
+// nestedImportStatus is the status of a nested import block.
+type nestedImportStatus struct {
+	Label   string `river:"label,attr"`
+	Health  string `river:"health,attr"`
+	Message string `river:"message,attr,optional"`
+}
+
+// DebugInfo returns the status of the nested import blocks of the node,
+// including the ones which failed to evaluate in the last content update.
+func (cn *ImportConfigNode) DebugInfo() interface{} {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+
+	var info importDebugInfo
+	for label, err := range cn.failedChildren {
+		info.NestedImports = append(info.NestedImports, nestedImportStatus{
+			Label:   label,
+			Health:  component.HealthTypeUnhealthy.String(),
+			Message: fmt.Sprintf("failed to evaluate: %s", err),
 		})
-		if err != nil {
-			return fmt.Errorf("imported node %s failed to evaluate, %v", child.label, err)
+	}
+	for label, child := range cn.importConfigNodesChildren {
+		if _, failed := cn.failedChildren[label]; failed {
+			continue
 		}
+		health := child.CurrentHealth()
+		info.NestedImports = append(info.NestedImports, nestedImportStatus{
+			Label:   label,
+			Health:  health.Health.String(),
+			Message: health.Message,
+		})
 	}
-	return nil
+	sort.Slice(info.NestedImports, func(i, j int) bool {
+		return info.NestedImports[i].Label < info.NestedImports[j].Label
+	})
+	return info
 }
 
 // onChildrenContentUpdate notifies the parent that the content has been updated.
@@ -317,8 +735,12 @@ func (cn *ImportConfigNode) onChildrenContentUpdate(child BlockNode) {
 // canceled. Evaluate must have been called at least once without returning an
 // error before calling Run.
 //
+// If the managed source or one of the import children stops with an error,
+// the source and all other children are stopped and Run returns the errors of
+// all of them joined together.
+//
 // Run will immediately return ErrUnevaluated if Evaluate has never been called
-// successfully. Otherwise, Run will return nil.
+// successfully.
 func (cn *ImportConfigNode) Run(ctx context.Context) error {
 	if cn.source == nil {
 		return ErrUnevaluated
@@ -328,10 +750,12 @@ func (cn *ImportConfigNode) Run(ctx context.Context) error {
 	defer cancel() // This will stop the children and the managed source.
 
 	errChan := make(chan error, 1)
+	childErrs := newChildErrors()
 
 	runner := runner.New(func(node *ImportConfigNode) runner.Worker {
 		return &childRunner{
 			node: node,
+			errs: childErrs,
 		}
 	})
 	defer runner.Stop()
@@ -358,10 +782,20 @@ func (cn *ImportConfigNode) Run(ctx context.Context) error {
 	}
 
 	go func() {
+		// The source can only run once it was evaluated successfully.
+		if !cn.evaluateUntilRecovered(newCtx) {
+			errChan <- nil
+			return
+		}
 		errChan <- cn.source.Run(newCtx)
 	}()
 
-	err = cn.run(errChan, updateTasks)
+	err = cn.run(errChan, childErrs.failed, cancel, updateTasks)
+
+	// Wait for all children to exit before collecting their errors, so that no
+	// nested import is left running after Run returns.
+	runner.Stop()
+	err = errors.Join(err, childErrs.join())
 
 	var exitMsg string
 	if err != nil {
@@ -375,10 +809,20 @@ func (cn *ImportConfigNode) Run(ctx context.Context) error {
 	return err
 }
 
-func (cn *ImportConfigNode) run(errChan chan error, updateTasks func() error) error {
+// run handles updates of the import children until the managed source exits
+// and returns its error. If one of the children fails, stop is called to stop
+// the source.
+func (cn *ImportConfigNode) run(errChan chan error, childFailed <-chan struct{}, stop context.CancelFunc, updateTasks func() error) error {
+	var stopping bool
 	for {
 		select {
+		case <-childFailed:
+			stopping = true
+			stop()
 		case <-cn.importChildrenUpdateChan:
+			if stopping {
+				continue
+			}
 			err := updateTasks()
 			if err != nil {
 				level.Error(cn.logger).Log("msg", "error encountered while updating nested import blocks", "err", err)
@@ -411,6 +855,9 @@ func (cn *ImportConfigNode) UpdateBlock(b *ast.BlockStmt) {
 
 func (cn *ImportConfigNode) Label() string { return cn.label }
 
+// ComponentName returns the name of the import block, such as "import.file".
+func (cn *ImportConfigNode) ComponentName() string { return cn.componentName }
+
 // Block implements BlockNode and returns the current block of the managed config node.
 func (cn *ImportConfigNode) Block() *ast.BlockStmt { return cn.block }
 
@@ -431,8 +878,18 @@ func (cn *ImportConfigNode) ImportConfigNodesChildren() map[string]*ImportConfig
 	return cn.importConfigNodesChildren
 }
 
+// collectMetrics collects the metrics of the node and of its nested import
+// nodes.
+func (cn *ImportConfigNode) collectMetrics(ch chan<- prometheus.Metric) {
+	cn.registry.Collect(ch)
+	for _, child := range cn.ImportConfigNodesChildren() {
+		child.collectMetrics(ch)
+	}
+}
+
 type childRunner struct {
 	node *ImportConfigNode
+	errs *childErrors
 }
 
 func (cr *childRunner) Run(ctx context.Context) {
@@ -440,19 +897,50 @@ func (cr *childRunner) Run(ctx context.Context) {
 	if err != nil {
 		level.Error(cr.node.logger).Log("msg", "nested import stopped running", "err", err)
 		cr.node.setRunHealth(component.HealthTypeUnhealthy, fmt.Sprintf("nested import stopped running: %s", err))
+		cr.errs.add(fmt.Errorf("nested import %q stopped running: %w", cr.node.label, err))
+	}
+}
+
+// childErrors collects the errors of the import children which stopped
+// running. failed receives a value after the first error is added.
+type childErrors struct {
+	mut    sync.Mutex
+	errs   []error
+	failed chan struct{}
+}
+
+func newChildErrors() *childErrors {
+	return &childErrors{failed: make(chan struct{}, 1)}
+}
+
+func (ce *childErrors) add(err error) {
+	ce.mut.Lock()
+	defer ce.mut.Unlock()
+	ce.errs = append(ce.errs, err)
+
+	select {
+	case ce.failed <- struct{}{}:
+	default:
 	}
 }
 
+// join returns the collected errors joined together, or nil if no child
+// failed.
+func (ce *childErrors) join() error {
+	ce.mut.Lock()
+	defer ce.mut.Unlock()
+	return errors.Join(ce.errs...)
+}
+
 func (cn *ImportConfigNode) Hash() uint64 {
 	fnvHash := fnv.New64a()
 	fnvHash.Write([]byte(cn.NodeID()))
 	return fnvHash.Sum64()
 }
 
-// We don't want to reuse previous running tasks.
-// On every updates, the previous workers should be stopped and new ones should spawn.
+// Equals only reuses running tasks of the same node: children which are kept
+// across content updates keep running, while recreated children replace the
+// previous workers.
 func (cn *ImportConfigNode) Equals(other runner.Task) bool {
-	// pointers are exactly the same.
-	// TODO: if possible we could find a way to safely reuse previous nodes
 	return cn == other.(*ImportConfigNode)
 }
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/grafana/agent/internal/flow/internal/importsource"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var importedContentSeeds = []string{
+	``,
+	`declare "a" { }`,
+	`declare "a" { argument "x" { optional = true } }`,
+	`declare "a" { export "y" { value = argument.x.value } }` + "\n" + `declare "a" { }`,
+	`import.string "nested" { content = "declare \"b\" {}" }`,
+	`import.file "nested" { filename = "module.river" }` + "\n" + `import.file "nested" { filename = "other.river" }`,
+	`logging { level = "debug" }`,
+	`prometheus.scrape "default" { targets = [] }`,
+	`declare { }`,
+	`attr = 5`,
+}
+
+// newFuzzImportConfigNode returns an import node whose source is never run,
+// so that fuzzing doesn't fetch remote content.
+func newFuzzImportConfigNode(t *testing.T) *ImportConfigNode {
+	file, err := parser.ParseFile("", []byte(`import.string "fuzz" { content = "" }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewImportConfigNode(file.Body[0].(*ast.BlockStmt), ComponentGlobals{
+		Logger:            newTestLogger(t),
+		TraceProvider:     noop.NewTracerProvider(),
+		DataPath:          t.TempDir(),
+		OnBlockNodeUpdate: func(BlockNode) {},
+	}, importsource.String)
+}
+
+// FuzzImportConfigNode_ProcessImportedContent looks for module content which
+// crashes the parser or the processing of declare and import blocks. Nested
+// import nodes are created but not evaluated, so their sources aren't used.
+func FuzzImportConfigNode_ProcessImportedContent(f *testing.F) {
+	for _, seed := range importedContentSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		file, err := parser.ParseFile("fuzz", []byte(content))
+		if err != nil {
+			return
+		}
+
+		cn := newFuzzImportConfigNode(t)
+		module := &importedModule{
+			declares:      make(map[string]ast.Body),
+			declareHashes: make(map[string]uint64),
+			children:      make(map[string]*ImportConfigNode),
+		}
+		if err := cn.processImportedContent(module, file); err != nil {
+			return
+		}
+		for name := range module.declares {
+			if _, ok := module.declareHashes[name]; !ok {
+				t.Fatalf("declare %q has no hash", name)
+			}
+		}
+	})
+}
+
+// FuzzImportConfigNode_ContentUpdate looks for module content which crashes
+// the import node when it is received from a source. Content with nested
+// imports is skipped, as evaluating them would use real sources.
+func FuzzImportConfigNode_ContentUpdate(f *testing.F) {
+	for _, seed := range importedContentSeeds {
+		f.Add(seed, `declare "other" { }`)
+	}
+
+	f.Fuzz(func(t *testing.T, main, other string) {
+		if strings.Contains(main, "import.") || strings.Contains(other, "import.") {
+			t.Skip("nested imports aren't fuzzed")
+		}
+
+		cn := newFuzzImportConfigNode(t)
+		cn.onContentUpdate(map[string]string{"main.river": main, "other.river": other})
+
+		// Receiving the same content again must be a no-op.
+		health := cn.CurrentHealth()
+		cn.onContentUpdate(map[string]string{"main.river": main, "other.river": other})
+		if got := cn.CurrentHealth(); got.Health != health.Health || got.Message != health.Message {
+			t.Fatalf("health changed on identical content: %v != %v", got, health)
+		}
+	})
+}
@@ -0,0 +1,343 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/internal/importsource"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/vm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/atomic"
+)
+
+func TestImportConfigNode_ContentUpdate(t *testing.T) {
+	file, err := parser.ParseFile("", []byte(`import.string "mod" { content = "" }`))
+	require.NoError(t, err)
+
+	var updates int
+	cn := NewImportConfigNode(file.Body[0].(*ast.BlockStmt), ComponentGlobals{
+		Logger:            newTestLogger(t),
+		TraceProvider:     noop.NewTracerProvider(),
+		DataPath:          t.TempDir(),
+		OnBlockNodeUpdate: func(BlockNode) { updates++ },
+	}, importsource.String)
+
+	const (
+		nestedImport  = `import.string "nested" { content = "declare \"b\" {}" }`
+		declareA      = `declare "a" { argument "x" {} }`
+		declareAOther = `declare "a" { argument "y" {} }`
+	)
+
+	cn.onContentUpdate(map[string]string{"main": nestedImport + "\n" + declareA})
+	require.Equal(t, 1, updates)
+	require.Contains(t, cn.ImportedDeclares(), "a")
+	nested := cn.ImportConfigNodesChildren()["nested"]
+	require.NotNil(t, nested)
+	require.Contains(t, nested.ImportedDeclares(), "b")
+
+	// Formatting and comment changes don't cause an update.
+	cn.onContentUpdate(map[string]string{"main": "// comment\n" + nestedImport + "\n\n" + declareA})
+	require.Equal(t, 1, updates)
+
+	// Changing a declare block causes an update, but the unchanged nested
+	// import is kept.
+	cn.onContentUpdate(map[string]string{"main": nestedImport + "\n" + declareAOther})
+	require.Equal(t, 2, updates)
+	require.Same(t, nested, cn.ImportConfigNodesChildren()["nested"])
+
+	// Changing the nested import recreates it.
+	cn.onContentUpdate(map[string]string{"main": `import.string "nested" { content = "declare \"c\" {}" }` + "\n" + declareAOther})
+	require.Equal(t, 3, updates)
+	newNested := cn.ImportConfigNodesChildren()["nested"]
+	require.NotSame(t, nested, newNested)
+	require.Contains(t, newNested.ImportedDeclares(), "c")
+}
+
+func TestImportConfigNode_DuplicateDeclare(t *testing.T) {
+	file, err := parser.ParseFile("", []byte(`import.string "mod" { content = "" }`))
+	require.NoError(t, err)
+
+	cn := NewImportConfigNode(file.Body[0].(*ast.BlockStmt), ComponentGlobals{
+		Logger:            newTestLogger(t),
+		TraceProvider:     noop.NewTracerProvider(),
+		DataPath:          t.TempDir(),
+		OnBlockNodeUpdate: func(BlockNode) {},
+	}, importsource.String)
+
+	cn.onContentUpdate(map[string]string{"a.river": `declare "a" {}`, "b.river": `declare "a" {}`})
+	require.Equal(t, component.HealthTypeUnhealthy, cn.contentHealth.Health)
+	require.Equal(t, `imported content from "b.river" is invalid: declare block redefined a`, cn.contentHealth.Message)
+}
+
+func TestImportConfigNode_ContentLimits(t *testing.T) {
+	newNode := func(limits ImportLimits) *ImportConfigNode {
+		file, err := parser.ParseFile("", []byte(`import.string "mod" { content = "" }`))
+		require.NoError(t, err)
+
+		return NewImportConfigNode(file.Body[0].(*ast.BlockStmt), ComponentGlobals{
+			Logger:            newTestLogger(t),
+			TraceProvider:     noop.NewTracerProvider(),
+			DataPath:          t.TempDir(),
+			OnBlockNodeUpdate: func(BlockNode) {},
+			ImportLimits:      limits,
+		}, importsource.String)
+	}
+
+	t.Run("max content size", func(t *testing.T) {
+		cn := newNode(ImportLimits{MaxContentSize: 20})
+		cn.onContentUpdate(map[string]string{"a": `declare "a" {}`, "b": `declare "b" {}`})
+		require.Equal(t, component.HealthTypeUnhealthy, cn.contentHealth.Health)
+		require.Contains(t, cn.contentHealth.Message, "exceeds the maximum of 20 bytes")
+		require.Empty(t, cn.ImportedDeclares())
+
+		cn.onContentUpdate(map[string]string{"a": `declare "a" {}`})
+		require.Equal(t, component.HealthTypeHealthy, cn.contentHealth.Health)
+		require.Contains(t, cn.ImportedDeclares(), "a")
+	})
+
+	t.Run("parse timeout", func(t *testing.T) {
+		cn := newNode(ImportLimits{ParseTimeout: time.Nanosecond})
+		cn.onContentUpdate(map[string]string{"a": strings.Repeat(`declare "a" { argument "x" {} }`+"\n", 100_000)})
+		require.Equal(t, component.HealthTypeUnhealthy, cn.contentHealth.Health)
+		require.Contains(t, cn.contentHealth.Message, "parsing took longer than 1ns")
+	})
+}
+
+func TestImportConfigNode_Metrics(t *testing.T) {
+	file, err := parser.ParseFile("", []byte(`import.string "mod" { content = "" }`))
+	require.NoError(t, err)
+
+	cn := NewImportConfigNode(file.Body[0].(*ast.BlockStmt), ComponentGlobals{
+		Logger:            newTestLogger(t),
+		TraceProvider:     noop.NewTracerProvider(),
+		DataPath:          t.TempDir(),
+		OnBlockNodeUpdate: func(BlockNode) {},
+	}, importsource.String)
+
+	cn.onContentUpdate(map[string]string{"main": `import.string "nested" { content = "declare \"b\" {}" }` + "\n" + `declare "a" {}`})
+	require.Equal(t, 1.0, testutil.ToFloat64(cn.metrics.contentUpdates))
+	require.Equal(t, 1.0, testutil.ToFloat64(cn.metrics.children))
+	require.NotZero(t, testutil.ToFloat64(cn.metrics.lastUpdate))
+
+	cn.onContentUpdate(map[string]string{"main": `declare "a" {`})
+	require.Equal(t, 1.0, testutil.ToFloat64(cn.metrics.contentUpdates))
+	require.Equal(t, 1.0, testutil.ToFloat64(cn.metrics.evalFailures.WithLabelValues(importFailureParse)))
+
+	cn.onContentUpdate(map[string]string{"main": `logging {}`})
+	require.Equal(t, 1.0, testutil.ToFloat64(cn.metrics.evalFailures.WithLabelValues(importFailureInvalid)))
+
+	cn.onContentUpdate(map[string]string{"main": `declare "a" {}`})
+	require.Equal(t, 2.0, testutil.ToFloat64(cn.metrics.contentUpdates))
+	require.Equal(t, 0.0, testutil.ToFloat64(cn.metrics.children))
+
+	// The metrics are exposed with the ID of the node.
+	families, err := cn.registry.Gather()
+	require.NoError(t, err)
+	var names []string
+	for _, mf := range families {
+		names = append(names, mf.GetName())
+		require.Equal(t, "import.string.mod", mf.GetMetric()[0].GetLabel()[0].GetValue())
+	}
+	require.Subset(t, names, []string{"agent_import_content_updates_total", "agent_import_last_update_timestamp", "agent_import_children", "agent_import_eval_failures_total"})
+}
+
+func TestImportConfigNode_NestedMetrics(t *testing.T) {
+	file, err := parser.ParseFile("", []byte(`import.string "mod" { content = "" }`))
+	require.NoError(t, err)
+
+	cn := NewImportConfigNode(file.Body[0].(*ast.BlockStmt), ComponentGlobals{
+		Logger:            newTestLogger(t),
+		TraceProvider:     noop.NewTracerProvider(),
+		DataPath:          t.TempDir(),
+		OnBlockNodeUpdate: func(BlockNode) {},
+	}, importsource.String)
+	cn.onContentUpdate(map[string]string{"main": `import.string "nested" { content = "import.string \"inner\" { content = \"declare \\\"b\\\" {}\" }" }`})
+	require.Equal(t, component.HealthTypeHealthy, cn.contentHealth.Health)
+
+	// The metrics of nested import nodes are collected along with those of
+	// their parent, scoped to the path of the parent.
+	ch := make(chan prometheus.Metric, 100)
+	cn.collectMetrics(ch)
+	close(ch)
+
+	updates := make(map[string]float64)
+	for m := range ch {
+		var pb dto.Metric
+		require.NoError(t, m.Write(&pb))
+		if pb.Counter == nil || len(pb.Label) != 2 {
+			continue
+		}
+		// Labels are sorted by name: config_id, then config_path.
+		updates[path.Join(pb.Label[1].GetValue(), pb.Label[0].GetValue())] = pb.Counter.GetValue()
+	}
+	require.Equal(t, map[string]float64{
+		"/import.string.mod":                                          1,
+		"/import.string.mod/import.string.nested":                     1,
+		"/import.string.mod/import.string.nested/import.string.inner": 1,
+	}, updates)
+}
+
+func TestImportConfigNode_ConcurrentChildren(t *testing.T) {
+	file, err := parser.ParseFile("", []byte(`import.string "mod" { content = "" }`))
+	require.NoError(t, err)
+
+	cn := NewImportConfigNode(file.Body[0].(*ast.BlockStmt), ComponentGlobals{
+		Logger:            newTestLogger(t),
+		TraceProvider:     noop.NewTracerProvider(),
+		DataPath:          t.TempDir(),
+		OnBlockNodeUpdate: func(BlockNode) {},
+		ImportLimits:      ImportLimits{Concurrency: 4},
+	}, importsource.String)
+
+	var content strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&content, "import.string \"nested_%d\" { content = \"declare \\\"d%d\\\" {}\" }\n", i, i)
+	}
+
+	cn.onContentUpdate(map[string]string{"main": content.String()})
+	require.Equal(t, component.HealthTypeHealthy, cn.contentHealth.Health)
+	require.Len(t, cn.ImportConfigNodesChildren(), 10)
+	for i := 0; i < 10; i++ {
+		require.Contains(t, cn.ImportConfigNodesChildren()[fmt.Sprintf("nested_%d", i)].ImportedDeclares(), fmt.Sprintf("d%d", i))
+	}
+
+	// The errors of all failed children are reported.
+	cn.onContentUpdate(map[string]string{"main": content.String() + `
+import.string "bad_a" { content = [] }
+import.string "bad_b" { content = [] }
+`})
+	require.Equal(t, component.HealthTypeUnhealthy, cn.contentHealth.Health)
+	require.Equal(t, "2 of 2 nested import blocks failed to evaluate: bad_a, bad_b", cn.contentHealth.Message)
+
+	// The failed children are reported in the debug info along with the
+	// children kept from the last successful update.
+	info := cn.DebugInfo().(importDebugInfo)
+	require.Len(t, info.NestedImports, 12)
+	require.Equal(t, "bad_a", info.NestedImports[0].Label)
+	require.Equal(t, "unhealthy", info.NestedImports[0].Health)
+	require.Contains(t, info.NestedImports[0].Message, "failed to evaluate")
+	require.Equal(t, "nested_0", info.NestedImports[2].Label)
+	require.NotEqual(t, "unhealthy", info.NestedImports[2].Health)
+}
+
+func TestImportConfigNode_RunStopsOnChildError(t *testing.T) {
+	file, err := parser.ParseFile("", []byte(`import.string "mod" { content = "" }`))
+	require.NoError(t, err)
+
+	cn := NewImportConfigNode(file.Body[0].(*ast.BlockStmt), ComponentGlobals{
+		Logger:            newTestLogger(t),
+		TraceProvider:     noop.NewTracerProvider(),
+		DataPath:          t.TempDir(),
+		OnBlockNodeUpdate: func(BlockNode) {},
+	}, importsource.String)
+
+	cn.onContentUpdate(map[string]string{"main": `import.string "nested" { content = "declare \"b\" {}" }`})
+	nested := cn.ImportConfigNodesChildren()["nested"]
+	require.NotNil(t, nested)
+
+	// A child without a source fails as soon as it runs.
+	cn.mut.Lock()
+	cn.importConfigNodesChildren["broken"] = &ImportConfigNode{
+		nodeID: "import.string.broken",
+		label:  "broken",
+		logger: newTestLogger(t),
+	}
+	cn.mut.Unlock()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- cn.Run(context.Background()) }()
+
+	select {
+	case err := <-errCh:
+		require.ErrorIs(t, err, ErrUnevaluated)
+		require.ErrorContains(t, err, `nested import "broken" stopped running`)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Run didn't return after a child failed")
+	}
+
+	// The sibling was stopped along with the parent.
+	require.Equal(t, component.HealthTypeExited, nested.RunHealth().Health)
+	require.Equal(t, component.HealthTypeExited, cn.RunHealth().Health)
+}
+
+func TestImportConfigNode_CachedContent(t *testing.T) {
+	defer func(interval time.Duration) { importCacheRetryInterval = interval }(importCacheRetryInterval)
+	importCacheRetryInterval = 10 * time.Millisecond
+
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`declare "a" {}`))
+	}))
+	defer srv.Close()
+
+	file, err := parser.ParseFile("", []byte(fmt.Sprintf(`import.http "mod" { url = %q }`, srv.URL)))
+	require.NoError(t, err)
+	dataPath := t.TempDir()
+	newNode := func() *ImportConfigNode {
+		return NewImportConfigNode(file.Body[0].(*ast.BlockStmt), ComponentGlobals{
+			Logger:            newTestLogger(t),
+			TraceProvider:     noop.NewTracerProvider(),
+			DataPath:          dataPath,
+			OnBlockNodeUpdate: func(BlockNode) {},
+		}, importsource.HTTP)
+	}
+
+	// The content is cached once it's loaded from the source.
+	cn := newNode()
+	require.NoError(t, cn.Evaluate(&vm.Scope{}))
+	require.Contains(t, cn.ImportedDeclares(), "a")
+	require.FileExists(t, cn.cachePath)
+
+	// When the source is unreachable, a new node loads the cached content.
+	down.Store(true)
+	cn = newNode()
+	require.NoError(t, cn.Evaluate(&vm.Scope{}))
+	require.Contains(t, cn.ImportedDeclares(), "a")
+	require.Equal(t, component.HealthTypeUnhealthy, cn.CurrentHealth().Health)
+	require.Contains(t, cn.CurrentHealth().Message, "using content cached at")
+
+	// The source is evaluated again while the node runs, until it's reachable.
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- cn.Run(ctx) }()
+	defer func() {
+		cancel()
+		require.NoError(t, <-errCh)
+	}()
+
+	down.Store(false)
+	require.Eventually(t, func() bool {
+		return cn.CurrentHealth().Health == component.HealthTypeHealthy
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Without a cache, evaluation fails.
+	down.Store(true)
+	file, err = parser.ParseFile("", []byte(fmt.Sprintf(`import.http "other" { url = %q }`, srv.URL)))
+	require.NoError(t, err)
+	require.Error(t, newNode().Evaluate(&vm.Scope{}))
+}
+
+func newTestLogger(t testing.TB) *logging.Logger {
+	l, err := logging.New(io.Discard, logging.DefaultOptions)
+	require.NoError(t, err)
+	return l
+}
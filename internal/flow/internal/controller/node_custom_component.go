@@ -324,3 +324,14 @@ func (cn *CustomComponentNode) ComponentName() string {
 func (cn *CustomComponentNode) ModuleIDs() []string {
 	return cn.moduleController.ModuleIDs()
 }
+
+// lazy reports whether every component of the managed custom component may
+// be started lazily. Custom components which can't tell aren't lazy.
+func (cn *CustomComponentNode) lazy() bool {
+	cn.mut.RLock()
+	managed := cn.managed
+	cn.mut.RUnlock()
+
+	lc, ok := managed.(interface{ Lazy() bool })
+	return ok && lc.Lazy()
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/diag"
+	"github.com/grafana/river/token"
+)
+
+// secretCheckTimeout is the maximum amount of time a component may take to
+// check that its secret references resolve.
+const secretCheckTimeout = 30 * time.Second
+
+// checkEnvReferences returns an error diagnostic for every call to the env
+// stdlib function with a literal argument naming an unset environment
+// variable.
+func checkEnvReferences(blockLists ...[]*ast.BlockStmt) diag.Diagnostics {
+	var w envWalker
+	for _, blocks := range blockLists {
+		for _, b := range blocks {
+			ast.Walk(&w, b.Body)
+		}
+	}
+	return w.diags
+}
+
+type envWalker struct {
+	diags diag.Diagnostics
+}
+
+func (w *envWalker) Visit(node ast.Node) ast.Visitor {
+	call, ok := node.(*ast.CallExpr)
+	if !ok {
+		return w
+	}
+
+	ident, ok := call.Value.(*ast.IdentifierExpr)
+	if !ok || ident.Ident.Name != "env" || len(call.Args) != 1 {
+		return w
+	}
+	lit, ok := call.Args[0].(*ast.LiteralExpr)
+	if !ok || lit.Kind != token.STRING {
+		// Only literal names can be checked before evaluation.
+		return w
+	}
+
+	name, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return w
+	}
+	if _, set := os.LookupEnv(name); !set {
+		w.diags.Add(diag.Diagnostic{
+			Severity: diag.SeverityLevelError,
+			Message:  fmt.Sprintf("environment variable %q referenced by env() is not set", name),
+			StartPos: ast.StartPos(call).Position(),
+			EndPos:   ast.EndPos(call).Position(),
+		})
+	}
+	return w
+}
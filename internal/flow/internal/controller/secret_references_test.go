@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckEnvReferences(t *testing.T) {
+	t.Setenv("SECRET_REFERENCES_SET", "value")
+
+	file, err := parser.ParseFile("", []byte(`
+		testcomponents.passthrough "a" {
+			input = env("SECRET_REFERENCES_SET")
+		}
+		testcomponents.passthrough "b" {
+			input = env("SECRET_REFERENCES_MISSING")
+		}
+		testcomponents.passthrough "c" {
+			input = env(testcomponents.passthrough.a.output)
+		}
+	`))
+	require.NoError(t, err)
+
+	var blocks []*ast.BlockStmt
+	for _, stmt := range file.Body {
+		blocks = append(blocks, stmt.(*ast.BlockStmt))
+	}
+
+	diags := checkEnvReferences(blocks)
+	require.Len(t, diags, 1)
+	require.Equal(t, `environment variable "SECRET_REFERENCES_MISSING" referenced by env() is not set`, diags[0].Message)
+}
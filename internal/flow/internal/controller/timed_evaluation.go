@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/grafana/agent/internal/flow/internal/dag"
+	"github.com/grafana/agent/internal/flow/internal/stdlib"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/token"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// timedNode is a node which is re-evaluated periodically because its block
+// calls time.now.
+type timedNode struct {
+	node     BlockNode
+	interval time.Duration
+	next     time.Time // Next time the node is due for evaluation.
+}
+
+// syncTimedNodes updates the set of timed nodes from the nodes of g. Nodes
+// whose interval didn't change keep their schedule.
+func (l *Loader) syncTimedNodes(g *dag.Graph) {
+	l.timedMut.Lock()
+	defer l.timedMut.Unlock()
+
+	var (
+		now        = time.Now()
+		timedNodes = make(map[string]*timedNode)
+	)
+	for _, n := range g.Nodes() {
+		bn, ok := n.(BlockNode)
+		if !ok || bn.Block() == nil {
+			continue
+		}
+		// Declare blocks are templates; the nodes instantiating them are
+		// re-evaluated instead.
+		if _, ok := n.(*DeclareNode); ok {
+			continue
+		}
+		interval := nowInterval(bn.Block().Body)
+		if interval == 0 {
+			continue
+		}
+
+		next := now.Add(interval)
+		if prev, ok := l.timedNodes[n.NodeID()]; ok && prev.interval == interval {
+			next = prev.next
+		}
+		timedNodes[n.NodeID()] = &timedNode{node: bn, interval: interval, next: next}
+	}
+	l.timedNodes = timedNodes
+}
+
+// NextTimedEvaluation returns the next time a node calling time.now is due
+// for evaluation. It returns false if no node calls time.now.
+func (l *Loader) NextTimedEvaluation() (time.Time, bool) {
+	l.timedMut.Lock()
+	defer l.timedMut.Unlock()
+
+	var next time.Time
+	for _, tn := range l.timedNodes {
+		if next.IsZero() || tn.next.Before(next) {
+			next = tn.next
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// EvaluateTimedNodes submits the nodes calling time.now which are due at now
+// for evaluation. Their dependants are evaluated in turn if their exports
+// change.
+func (l *Loader) EvaluateTimedNodes(ctx context.Context, now time.Time) {
+	var due []BlockNode
+
+	l.timedMut.Lock()
+	for _, tn := range l.timedNodes {
+		if tn.next.After(now) {
+			continue
+		}
+		due = append(due, tn.node)
+		tn.next = now.Add(tn.interval)
+	}
+	l.timedMut.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	tracer := l.tracer.Tracer("")
+	spanCtx, span := tracer.Start(context.Background(), "SubmitTimedNodesForEvaluation", trace.WithSpanKind(trace.SpanKindInternal))
+	span.SetAttributes(attribute.Int("nodes_count", len(due)))
+	span.SetStatus(codes.Ok, "timed nodes submitted for evaluation")
+	defer span.End()
+
+	l.mut.RLock()
+	defer l.mut.RUnlock()
+
+	// Each node is its own originator: it's evaluated because time passed,
+	// not because a dependency changed.
+	nodes := make(map[dag.Node]*QueuedNode, len(due))
+	for _, n := range due {
+		// Skip nodes removed by a reload since they were collected.
+		if l.graph.GetByID(n.NodeID()) != n {
+			continue
+		}
+		nodes[n] = &QueuedNode{Node: n, LastUpdatedTime: now}
+	}
+	l.submitForEvaluation(ctx, spanCtx, tracer, nodes)
+}
+
+// nowInterval returns the smallest interval passed to time.now in body, or
+// 0 if body doesn't call time.now. Only literal intervals are considered;
+// time.now reports invalid intervals when the block is evaluated.
+func nowInterval(body ast.Body) time.Duration {
+	var w nowWalker
+	ast.Walk(&w, body)
+	return w.interval
+}
+
+type nowWalker struct {
+	interval time.Duration
+}
+
+func (w *nowWalker) Visit(node ast.Node) ast.Visitor {
+	call, ok := node.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return w
+	}
+
+	access, ok := call.Value.(*ast.AccessExpr)
+	if !ok || access.Name.Name != "now" {
+		return w
+	}
+	ident, ok := access.Value.(*ast.IdentifierExpr)
+	if !ok || ident.Ident.Name != "time" {
+		return w
+	}
+	lit, ok := call.Args[0].(*ast.LiteralExpr)
+	if !ok || lit.Kind != token.STRING {
+		return w
+	}
+
+	value, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return w
+	}
+	interval, err := stdlib.ParseNowInterval(value)
+	if err != nil {
+		return w
+	}
+	if w.interval == 0 || interval < w.interval {
+		w.interval = interval
+	}
+	return w
+}
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNowInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		expect time.Duration
+	}{
+		{"no call", `input = "x"`, 0},
+		{"single call", `input = time.now("1m")`, time.Minute},
+		{"smallest interval", `input = [time.format(time.now("1m"), "15:04"), time.now("30s")]`, 30 * time.Second},
+		{"nested block", "inner {\n\tinput = time.now(\"5m\")\n}", 5 * time.Minute},
+		{"non-literal interval", `input = time.now(testcomponents.passthrough.a.output)`, 0},
+		{"interval below minimum", `input = time.now("10ms")`, 0},
+		{"other namespace", `input = other.now("1m")`, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := parser.ParseFile("", []byte("testcomponents.passthrough \"a\" {\n"+tc.body+"\n}"))
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, nowInterval(file.Body[0].(*ast.BlockStmt).Body))
+		})
+	}
+}
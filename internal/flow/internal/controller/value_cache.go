@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/internal/stdlib"
 	"github.com/grafana/river/vm"
 )
 
@@ -165,7 +166,7 @@ func (vc *valueCache) BuildContext() *vm.Scope {
 	defer vc.mut.RUnlock()
 
 	scope := &vm.Scope{
-		Parent:    nil,
+		Parent:    stdlib.Scope,
 		Variables: make(map[string]interface{}),
 	}
 
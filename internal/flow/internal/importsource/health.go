@@ -0,0 +1,54 @@
+package importsource
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/grafana/agent/internal/component"
+)
+
+// failureBudget counts the consecutive failed polls of an import source, so
+// that a number of them can be tolerated before the source reports itself as
+// unhealthy. This avoids flapping health for transient network errors.
+type failureBudget struct {
+	mut       sync.Mutex
+	tolerance int // Number of consecutive failures tolerated.
+	failures  int // Number of consecutive failures observed.
+}
+
+// setTolerance sets the number of consecutive failures tolerated.
+func (b *failureBudget) setTolerance(n int) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.tolerance = n
+}
+
+// observe records the result of a poll. A successful poll resets the number
+// of consecutive failures.
+func (b *failureBudget) observe(err error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if err != nil {
+		b.failures++
+	} else {
+		b.failures = 0
+	}
+}
+
+// apply returns h unchanged, unless h is unhealthy and the consecutive
+// failures are still within the tolerance. In that case a healthy health
+// describing the tolerated failure is returned instead.
+func (b *failureBudget) apply(h component.Health) component.Health {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if h.Health != component.HealthTypeUnhealthy || b.failures == 0 || b.failures > b.tolerance {
+		return h
+	}
+	return component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    fmt.Sprintf("tolerating %d of %d consecutive failures: %s", b.failures, b.tolerance, h.Message),
+		UpdateTime: h.UpdateTime,
+	}
+}
@@ -0,0 +1,46 @@
+package importsource
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailureBudget(t *testing.T) {
+	var b failureBudget
+	b.setTolerance(2)
+
+	unhealthy := component.Health{
+		Health:     component.HealthTypeUnhealthy,
+		Message:    "polling failed: connection refused",
+		UpdateTime: time.Now(),
+	}
+
+	// Healthy states are never changed.
+	healthy := component.Health{Health: component.HealthTypeHealthy, Message: "ok"}
+	require.Equal(t, healthy, b.apply(healthy))
+
+	// The first two consecutive failures are tolerated.
+	for i := 1; i <= 2; i++ {
+		b.observe(errors.New("connection refused"))
+		h := b.apply(unhealthy)
+		require.Equal(t, component.HealthTypeHealthy, h.Health)
+		require.Contains(t, h.Message, "connection refused")
+	}
+
+	// The third one isn't.
+	b.observe(errors.New("connection refused"))
+	require.Equal(t, unhealthy, b.apply(unhealthy))
+
+	// A successful poll resets the budget.
+	b.observe(nil)
+	b.observe(errors.New("connection refused"))
+	require.Equal(t, component.HealthTypeHealthy, b.apply(unhealthy).Health)
+
+	// Without a tolerance, every failure is reported.
+	b.setTolerance(0)
+	require.Equal(t, unhealthy, b.apply(unhealthy))
+}
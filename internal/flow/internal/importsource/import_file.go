@@ -19,12 +19,15 @@ import (
 	"github.com/grafana/river/vm"
 )
 
-// ImportFile imports a module from a file or a folder.
+// ImportFile imports a module from a file, a folder, or the files matching a
+// glob pattern.
 type ImportFile struct {
 	managedOpts     component.Options
 	eval            *vm.Evaluator
 	onContentChange func(map[string]string)
 	logger          log.Logger
+	metrics         *sourceMetrics
+	maxContentSize  int64 // Maximum total size of the files read; zero for no limit.
 
 	reloadCh chan struct{}
 	args     FileArguments
@@ -36,12 +39,21 @@ type ImportFile struct {
 	health    component.Health
 }
 
-// waitReadPeriod holds the time to wait before reading a file while the
-// source is running.
+// waitReadPeriod holds the time to wait without changes before reading a file
+// while the source is running.
 //
 // This prevents from updating too frequently and exporting partial writes.
 const waitReadPeriod time.Duration = 30 * time.Millisecond
 
+// Directories and glob patterns wait longer without changes, so that several
+// files written in a row, for example by an editor or a configuration
+// management tool, result in a single content update. Reads are delayed by
+// at most maxBatchPeriod while changes keep coming.
+const (
+	waitReadDirPeriod time.Duration = 250 * time.Millisecond
+	maxBatchPeriod    time.Duration = 5 * time.Second
+)
+
 var _ ImportSource = (*ImportFile)(nil)
 
 func NewImportFile(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportFile {
@@ -52,11 +64,12 @@ func NewImportFile(managedOpts component.Options, eval *vm.Evaluator, onContentC
 		eval:            eval,
 		onContentChange: onContentChange,
 		logger:          managedOpts.Logger,
+		metrics:         newSourceMetrics(managedOpts.Registerer),
 	}
 }
 
 type FileArguments struct {
-	// Filename indicates the file to watch.
+	// Filename indicates the file, folder, or glob pattern to watch.
 	Filename string `river:"filename,attr"`
 	// Type indicates how to detect changes to the file.
 	Type filedetector.Detector `river:"detector,attr,optional"`
@@ -113,14 +126,14 @@ func (im *ImportFile) Evaluate(scope *vm.Scope) error {
 	switch im.args.Type {
 	case filedetector.DetectorPoll:
 		im.detector = filedetector.NewPoller(filedetector.PollerOptions{
-			Filename:      im.args.Filename,
+			Filename:      im.watchPath(),
 			ReloadFile:    reloadFile,
 			PollFrequency: im.args.PollFrequency,
 		})
 	case filedetector.DetectorFSNotify:
 		im.detector, err = filedetector.NewFSNotify(filedetector.FSNotifyOptions{
 			Logger:        im.managedOpts.Logger,
-			Filename:      im.args.Filename,
+			Filename:      im.watchPath(),
 			ReloadFile:    reloadFile,
 			PollFrequency: im.args.PollFrequency,
 		})
@@ -143,7 +156,9 @@ func (im *ImportFile) Run(ctx context.Context) error {
 		case <-ctx.Done():
 			return nil
 		case <-im.reloadCh:
-			time.Sleep(waitReadPeriod)
+			if !im.waitForChanges(ctx) {
+				return nil
+			}
 
 			// We ignore the error here from readFile since readFile will log errors
 			// and also report the error and update the health of the source.
@@ -152,8 +167,54 @@ func (im *ImportFile) Run(ctx context.Context) error {
 	}
 }
 
-func (im *ImportFile) readFile() error {
-	files, dir, err := im.collectFiles()
+// waitForChanges waits until no change was detected for the read period,
+// batching successive changes into a single read. It returns false if ctx is
+// canceled while waiting.
+func (im *ImportFile) waitForChanges(ctx context.Context) bool {
+	period := im.readPeriod()
+
+	quiet := time.NewTimer(period)
+	defer quiet.Stop()
+	deadline := time.NewTimer(maxBatchPeriod)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-im.reloadCh:
+			if !quiet.Stop() {
+				<-quiet.C
+			}
+			quiet.Reset(period)
+		case <-quiet.C:
+			return true
+		case <-deadline.C:
+			return true
+		}
+	}
+}
+
+// readPeriod returns how long to wait without changes before reading.
+func (im *ImportFile) readPeriod() time.Duration {
+	im.mut.RLock()
+	fpath := im.args.Filename
+	im.mut.RUnlock()
+
+	if isGlob(fpath) {
+		return waitReadDirPeriod
+	}
+	if fi, err := os.Stat(fpath); err == nil && fi.IsDir() {
+		return waitReadDirPeriod
+	}
+	return waitReadPeriod
+}
+
+func (im *ImportFile) readFile() (err error) {
+	start := time.Now()
+	defer func() { im.metrics.observeFetch(start, err) }()
+
+	files, err := im.collectFiles()
 	if err != nil {
 		im.setHealth(component.Health{
 			Health:     component.HealthTypeUnhealthy,
@@ -163,13 +224,12 @@ func (im *ImportFile) readFile() error {
 		level.Error(im.managedOpts.Logger).Log("msg", "failed to collect files", "err", err)
 		return err
 	}
-	fileContents := make(map[string]string)
-	for _, f := range files {
-		fpath := f
-		if dir {
-			fpath = filepath.Join(im.args.Filename, fpath)
-		}
-		bb, err := os.ReadFile(fpath)
+	var (
+		fileContents = make(map[string]string)
+		contentSize  int64
+	)
+	for f, fpath := range files {
+		bb, err := im.readContent(fpath, contentSize)
 		if err != nil {
 			im.setHealth(component.Health{
 				Health:     component.HealthTypeUnhealthy,
@@ -180,6 +240,7 @@ func (im *ImportFile) readFile() error {
 			return err
 		}
 		fileContents[f] = string(bb)
+		contentSize += int64(len(bb))
 	}
 
 	im.setHealth(component.Health{
@@ -187,6 +248,7 @@ func (im *ImportFile) readFile() error {
 		Message:    "read file",
 		UpdateTime: time.Now(),
 	})
+	im.metrics.setContent(fileContents)
 	im.onContentChange(fileContents)
 	return nil
 }
@@ -203,24 +265,77 @@ func (im *ImportFile) setHealth(h component.Health) {
 	im.health = h
 }
 
-func (im *ImportFile) collectFiles() (content []string, dir bool, err error) {
+// collectFiles returns the files to read, mapping the name of each file in
+// the imported content to its path.
+func (im *ImportFile) collectFiles() (map[string]string, error) {
 	fpath := im.args.Filename
+	if isGlob(fpath) {
+		return collectFilesFromGlob(fpath)
+	}
+
 	fi, err := os.Stat(fpath)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
-	files := make([]string, 0)
-	dir = fi.IsDir()
-	if dir {
-		files, err = collectFilesFromDir(fpath)
+	files := make(map[string]string)
+	if fi.IsDir() {
+		names, err := collectFilesFromDir(fpath)
 		if err != nil {
-			return nil, true, err
+			return nil, err
+		}
+		for _, name := range names {
+			files[name] = filepath.Join(fpath, name)
 		}
 	} else {
-		files = append(files, fpath)
+		files[fpath] = fpath
+	}
+	return files, nil
+}
+
+// watchPath returns the path to watch for changes. Glob patterns are watched
+// through the directory containing their first wildcard; files matched in
+// nested directories are picked up when the detector polls.
+func (im *ImportFile) watchPath() string {
+	fpath := im.args.Filename
+	if !isGlob(fpath) {
+		return fpath
+	}
+
+	dir := fpath
+	for isGlob(dir) {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// isGlob returns whether path is a glob pattern.
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
+
+func collectFilesFromGlob(pattern string) (map[string]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(matches))
+	for _, match := range matches {
+		fi, err := os.Stat(match)
+		if err != nil {
+			return nil, err
+		}
+		// Directories matched by the pattern are ignored.
+		if fi.IsDir() {
+			continue
+		}
+		files[match] = match
 	}
-	return files, dir, nil
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %q", pattern)
+	}
+	return files, nil
 }
 
 func collectFilesFromDir(path string) ([]string, error) {
@@ -254,3 +369,21 @@ func collectFilesFromDir(path string) ([]string, error) {
 func (im *ImportFile) SetEval(eval *vm.Evaluator) {
 	im.eval = eval
 }
+
+// readContent reads the file at path. read is the size of the files already
+// read, which counts towards the maximum content size.
+func (im *ImportFile) readContent(path string, read int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if im.maxContentSize <= 0 {
+		return io.ReadAll(f)
+	}
+	if read >= im.maxContentSize {
+		return nil, fmt.Errorf("content exceeds the maximum size of %d bytes", im.maxContentSize)
+	}
+	return readLimited(f, im.maxContentSize-read)
+}
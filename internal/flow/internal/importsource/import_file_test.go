@@ -0,0 +1,100 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/vm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportFile_BatchesDirectoryChanges(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.river"), []byte(`declare "a" {}`), 0600))
+
+	file, err := parser.ParseFile("", []byte(fmt.Sprintf(`import.file "lib" { filename = %q }`, dir)))
+	require.NoError(t, err)
+
+	contentCh := make(chan map[string]string, 10)
+	im := NewImportFile(component.Options{Logger: log.NewNopLogger()}, vm.New(file.Body[0].(*ast.BlockStmt).Body), func(content map[string]string) {
+		contentCh <- content
+	})
+	require.NoError(t, im.Evaluate(&vm.Scope{}))
+	require.Len(t, <-contentCh, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = im.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Files written in quick succession result in a single update.
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("b%d.river", i)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(fmt.Sprintf(`declare "b%d" {}`, i)), 0600))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case content := <-contentCh:
+		require.Len(t, content, 6)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no content update after writing files")
+	}
+
+	select {
+	case content := <-contentCh:
+		t.Fatalf("unexpected second content update: %v", content)
+	case <-time.After(2 * waitReadDirPeriod):
+	}
+}
+
+func TestImportFile_Metrics(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "module.river")
+	require.NoError(t, os.WriteFile(filename, []byte(`declare "a" {}`), 0600))
+
+	file, err := parser.ParseFile("", []byte(fmt.Sprintf(`import.file "lib" { filename = %q }`, filename)))
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	im := NewImportFile(component.Options{Logger: log.NewNopLogger(), Registerer: reg}, vm.New(file.Body[0].(*ast.BlockStmt).Body), func(map[string]string) {})
+	require.NoError(t, im.Evaluate(&vm.Scope{}))
+
+	require.Equal(t, float64(len(`declare "a" {}`)), testutil.ToFloat64(im.metrics.contentBytes))
+	require.Equal(t, 1, testutil.CollectAndCount(im.metrics.fetchDuration))
+
+	require.NoError(t, os.Remove(filename))
+	require.Error(t, im.readFile())
+	require.Equal(t, 1.0, testutil.ToFloat64(im.metrics.fetchErrors.WithLabelValues(fetchErrorNotFound)))
+}
+
+func TestImportFile_MaxContentSize(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.river"), []byte(`declare "a" {}`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.river"), []byte(`declare "b" {}`), 0600))
+
+	file, err := parser.ParseFile("", []byte(fmt.Sprintf(`import.file "lib" { filename = %q }`, dir)))
+	require.NoError(t, err)
+
+	// Each file fits within the limit, but not both together.
+	im := NewImportSource(File, component.Options{Logger: log.NewNopLogger()}, vm.New(file.Body[0].(*ast.BlockStmt).Body), 20, func(map[string]string) {
+		require.FailNow(t, "content larger than the limit was imported")
+	})
+	require.ErrorContains(t, im.Evaluate(&vm.Scope{}), "content exceeds the maximum size of 20 bytes")
+}
@@ -29,11 +29,14 @@ type ImportGit struct {
 	repoOpts        vcs.GitRepoOptions
 	args            GitArguments
 	onContentChange func(map[string]string)
+	metrics         *sourceMetrics
+	maxContentSize  int64 // Maximum total size of the files read; zero for no limit.
 
 	argsChanged chan struct{}
 
 	healthMut sync.RWMutex
 	health    component.Health
+	failures  failureBudget
 }
 
 var (
@@ -48,6 +51,15 @@ type GitArguments struct {
 	Path          string            `river:"path,attr"`
 	PullFrequency time.Duration     `river:"pull_frequency,attr,optional"`
 	GitAuthConfig vcs.GitAuthConfig `river:",squash"`
+
+	// SparsePaths limits the checked out files to the given directories.
+	SparsePaths []string `river:"sparse_paths,attr,optional"`
+	// Submodules initializes and updates the submodules of the repository.
+	Submodules bool `river:"submodules,attr,optional"`
+
+	// FailureTolerance is the number of consecutive failed pulls tolerated
+	// before the import reports unhealthy.
+	FailureTolerance int `river:"failure_tolerance,attr,optional"`
 }
 
 var DefaultGitArguments = GitArguments{
@@ -60,6 +72,17 @@ func (args *GitArguments) SetToDefault() {
 	*args = DefaultGitArguments
 }
 
+// Validate implements river.Validator.
+func (args *GitArguments) Validate() error {
+	if args.FailureTolerance < 0 {
+		return fmt.Errorf("failure_tolerance must not be negative")
+	}
+	if len(args.SparsePaths) > 0 && !vcs.InSparsePaths(args.Path, args.SparsePaths) {
+		return fmt.Errorf("path %q must be inside one of sparse_paths", args.Path)
+	}
+	return nil
+}
+
 func NewImportGit(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportGit {
 	return &ImportGit{
 		opts:            managedOpts,
@@ -67,6 +90,7 @@ func NewImportGit(managedOpts component.Options, eval *vm.Evaluator, onContentCh
 		eval:            eval,
 		argsChanged:     make(chan struct{}, 1),
 		onContentChange: onContentChange,
+		metrics:         newSourceMetrics(managedOpts.Registerer),
 	}
 }
 
@@ -150,6 +174,8 @@ func (im *ImportGit) updateHealth(err error) {
 	im.healthMut.Lock()
 	defer im.healthMut.Unlock()
 
+	im.failures.observe(err)
+
 	if err != nil {
 		im.health = component.Health{
 			Health:     component.HealthTypeUnhealthy,
@@ -177,6 +203,7 @@ func (im *ImportGit) Update(args component.Arguments) (err error) {
 	defer im.mut.Unlock()
 
 	newArgs := args.(GitArguments)
+	im.failures.setTolerance(newArgs.FailureTolerance)
 
 	// TODO(rfratto): store in a repo-specific directory so changing repositories
 	// doesn't risk break the module loader if there's a SHA collision between
@@ -187,6 +214,9 @@ func (im *ImportGit) Update(args component.Arguments) (err error) {
 		Repository: newArgs.Repository,
 		Revision:   newArgs.Revision,
 		Auth:       newArgs.GitAuthConfig,
+
+		SparsePaths: newArgs.SparsePaths,
+		Submodules:  newArgs.Submodules,
 	}
 
 	// Create or update the repo field.
@@ -228,7 +258,10 @@ func (im *ImportGit) Update(args component.Arguments) (err error) {
 
 // pollFile fetches the latest content from the repository and updates the
 // controller. pollFile must only be called with im.mut held.
-func (im *ImportGit) pollFile(ctx context.Context, args GitArguments) error {
+func (im *ImportGit) pollFile(ctx context.Context, args GitArguments) (err error) {
+	start := time.Now()
+	defer func() { im.metrics.observeFetch(start, err) }()
+
 	// Make sure our repo is up-to-date.
 	if err := im.repo.Update(ctx); err != nil {
 		return err
@@ -252,27 +285,34 @@ func (im *ImportGit) handleDirectory(path string) error {
 		return err
 	}
 
-	content := make(map[string]string)
+	var (
+		content     = make(map[string]string)
+		contentSize int64
+	)
 	for _, fi := range filesInfo {
 		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".river") {
 			continue
 		}
-		bb, err := im.repo.ReadFile(filepath.Join(path, fi.Name()))
+		bb, err := im.readContent(filepath.Join(path, fi.Name()), contentSize)
 		if err != nil {
 			return err
 		}
 		content[fi.Name()] = string(bb)
+		contentSize += int64(len(bb))
 	}
+	im.metrics.setContent(content)
 	im.onContentChange(content)
 	return nil
 }
 
 func (im *ImportGit) handleFile(path string) error {
-	bb, err := im.repo.ReadFile(path)
+	bb, err := im.readContent(path, 0)
 	if err != nil {
 		return err
 	}
-	im.onContentChange(map[string]string{path: string(bb)})
+	content := map[string]string{path: string(bb)}
+	im.metrics.setContent(content)
+	im.onContentChange(content)
 	return nil
 }
 
@@ -280,10 +320,28 @@ func (im *ImportGit) handleFile(path string) error {
 func (im *ImportGit) CurrentHealth() component.Health {
 	im.healthMut.RLock()
 	defer im.healthMut.RUnlock()
-	return im.health
+	return im.failures.apply(im.health)
 }
 
 // Update the evaluator.
 func (im *ImportGit) SetEval(eval *vm.Evaluator) {
 	im.eval = eval
 }
+
+// readContent reads the file at path from the repository. read is the size of
+// the files already read, which counts towards the maximum content size.
+func (im *ImportGit) readContent(path string, read int64) ([]byte, error) {
+	if im.maxContentSize <= 0 {
+		return im.repo.ReadFile(path)
+	}
+	if read >= im.maxContentSize {
+		return nil, fmt.Errorf("content exceeds the maximum size of %d bytes", im.maxContentSize)
+	}
+
+	f, err := im.repo.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readLimited(f, im.maxContentSize-read)
+}
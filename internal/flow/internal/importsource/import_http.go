@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/grafana/agent/internal/component"
 	common_config "github.com/grafana/agent/internal/component/common/config"
 	remote_http "github.com/grafana/agent/internal/component/remote/http"
@@ -19,18 +20,25 @@ type ImportHTTP struct {
 	arguments         component.Arguments
 	managedOpts       component.Options
 	eval              *vm.Evaluator
+	metrics           *sourceMetrics
+	failures          failureBudget
+	maxContentSize    int64 // Maximum size of the response; zero for no limit.
 }
 
 var _ ImportSource = (*ImportHTTP)(nil)
 
 func NewImportHTTP(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportHTTP {
+	metrics := newSourceMetrics(managedOpts.Registerer)
 	opts := managedOpts
 	opts.OnStateChange = func(e component.Exports) {
-		onContentChange(map[string]string{opts.ID: e.(remote_http.Exports).Content.Value})
+		content := map[string]string{opts.ID: e.(remote_http.Exports).Content.Value}
+		metrics.setContent(content)
+		onContentChange(content)
 	}
 	return &ImportHTTP{
 		managedOpts: opts,
 		eval:        eval,
+		metrics:     metrics,
 	}
 }
 
@@ -45,6 +53,10 @@ type HTTPArguments struct {
 	Body    string            `river:"body,attr,optional"`
 
 	Client common_config.HTTPClientConfig `river:"client,block,optional"`
+
+	// FailureTolerance is the number of consecutive failed polls tolerated
+	// before the import reports unhealthy.
+	FailureTolerance int `river:"failure_tolerance,attr,optional"`
 }
 
 // DefaultHTTPArguments holds default settings for HTTPArguments.
@@ -60,25 +72,50 @@ func (args *HTTPArguments) SetToDefault() {
 	*args = DefaultHTTPArguments
 }
 
+// Validate implements river.Validator.
+func (args *HTTPArguments) Validate() error {
+	if args.FailureTolerance < 0 {
+		return fmt.Errorf("failure_tolerance must not be negative")
+	}
+	return args.Client.Validate()
+}
+
+// remoteHTTPArguments returns the arguments of the managed remote.http
+// component, which stops reading responses larger than maxSize bytes.
+func (args HTTPArguments) remoteHTTPArguments(maxSize int64) remote_http.Arguments {
+	return remote_http.Arguments{
+		URL:             args.URL,
+		PollFrequency:   args.PollFrequency,
+		PollTimeout:     args.PollTimeout,
+		Method:          args.Method,
+		Headers:         args.Headers,
+		Body:            args.Body,
+		Client:          args.Client,
+		MaxResponseSize: units.Base2Bytes(maxSize),
+	}
+}
+
 func (im *ImportHTTP) Evaluate(scope *vm.Scope) error {
 	var arguments HTTPArguments
 	if err := im.eval.Evaluate(scope, &arguments); err != nil {
 		return fmt.Errorf("decoding River: %w", err)
 	}
+	im.failures.setTolerance(arguments.FailureTolerance)
+
 	if im.managedRemoteHTTP == nil {
 		var err error
-		im.managedRemoteHTTP, err = remote_http.New(im.managedOpts, remote_http.Arguments{
-			URL:           arguments.URL,
-			PollFrequency: arguments.PollFrequency,
-			PollTimeout:   arguments.PollTimeout,
-			Method:        arguments.Method,
-			Headers:       arguments.Headers,
-			Body:          arguments.Body,
-			Client:        arguments.Client,
-		})
+		start := time.Now()
+		im.managedRemoteHTTP, err = remote_http.New(im.managedOpts, arguments.remoteHTTPArguments(im.maxContentSize))
+		// The first poll happens synchronously in New, before the observer is
+		// set, so it is recorded here.
+		im.metrics.observeFetch(start, err)
 		if err != nil {
 			return fmt.Errorf("creating http component: %w", err)
 		}
+		im.managedRemoteHTTP.ObservePolls(func(d time.Duration, err error) {
+			im.metrics.observeFetchDuration(d, err)
+			im.failures.observe(err)
+		})
 		im.arguments = arguments
 	}
 
@@ -87,7 +124,7 @@ func (im *ImportHTTP) Evaluate(scope *vm.Scope) error {
 	}
 
 	// Update the existing managed component
-	if err := im.managedRemoteHTTP.Update(arguments); err != nil {
+	if err := im.managedRemoteHTTP.Update(arguments.remoteHTTPArguments(im.maxContentSize)); err != nil {
 		return fmt.Errorf("updating component: %w", err)
 	}
 	im.arguments = arguments
@@ -99,7 +136,7 @@ func (im *ImportHTTP) Run(ctx context.Context) error {
 }
 
 func (im *ImportHTTP) CurrentHealth() component.Health {
-	return im.managedRemoteHTTP.CurrentHealth()
+	return im.failures.apply(im.managedRemoteHTTP.CurrentHealth())
 }
 
 // Update the evaluator.
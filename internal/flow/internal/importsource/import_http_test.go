@@ -0,0 +1,78 @@
+package importsource
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/vm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportHTTP_Authentication(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "agent" || password != "secret" || r.Header.Get("X-Tenant") != "team-a" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `declare "a" {}`)
+	}))
+	defer srv.Close()
+
+	evaluator := func(config string) *vm.Evaluator {
+		file, err := parser.ParseFile("", []byte(config))
+		require.NoError(t, err)
+		return vm.New(file.Body[0].(*ast.BlockStmt).Body)
+	}
+
+	var content map[string]string
+	im := NewImportHTTP(component.Options{ID: "import.http.lib", Logger: log.NewNopLogger()}, evaluator(fmt.Sprintf(`import.http "lib" {
+		url     = %q
+		headers = { "X-Tenant" = "team-a" }
+
+		client {
+			basic_auth {
+				username = "agent"
+				password = "secret"
+			}
+		}
+	}`, srv.URL)), func(c map[string]string) { content = c })
+	require.NoError(t, im.Evaluate(&vm.Scope{}))
+	require.Equal(t, map[string]string{"import.http.lib": `declare "a" {}`}, content)
+
+	// Updated credentials are passed to the managed remote.http component.
+	im.SetEval(evaluator(fmt.Sprintf(`import.http "lib" {
+		url     = %q
+		headers = { "X-Tenant" = "team-a" }
+
+		client {
+			bearer_token = "token"
+		}
+	}`, srv.URL)))
+	require.ErrorContains(t, im.Evaluate(&vm.Scope{}), "401 Unauthorized")
+}
+
+func TestImportHTTP_ValidateClient(t *testing.T) {
+	file, err := parser.ParseFile("", []byte(`import.http "lib" {
+		url = "http://localhost"
+
+		client {
+			bearer_token = "token"
+
+			basic_auth {
+				username = "agent"
+			}
+		}
+	}`))
+	require.NoError(t, err)
+
+	var args HTTPArguments
+	err = vm.New(file.Body[0].(*ast.BlockStmt).Body).Evaluate(&vm.Scope{}, &args)
+	require.ErrorContains(t, err, "at most one of basic_auth, authorization, oauth2, bearer_token & bearer_token_file must be configured")
+}
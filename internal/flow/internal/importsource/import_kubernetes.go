@@ -0,0 +1,389 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/component/common/kubernetes"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/river/vm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	client_go "k8s.io/client-go/kubernetes"
+)
+
+// Kinds of Kubernetes objects which modules can be imported from.
+const (
+	KubernetesKindConfigMap = "configmap"
+	KubernetesKindSecret    = "secret"
+)
+
+const (
+	// kubernetesGetTimeout is the timeout for reading the object when the
+	// source is evaluated.
+	kubernetesGetTimeout = 15 * time.Second
+
+	// kubernetesRetryPeriod is how long to wait before watching the object
+	// again after the watch failed.
+	kubernetesRetryPeriod = 5 * time.Second
+)
+
+// ImportKubernetes imports a module from a Kubernetes ConfigMap or Secret.
+// The object is watched, and the module is updated whenever it changes.
+type ImportKubernetes struct {
+	managedOpts     component.Options
+	eval            *vm.Evaluator
+	onContentChange func(map[string]string)
+	metrics         *sourceMetrics
+	restartCh       chan struct{}
+
+	mut             sync.Mutex
+	args            KubernetesArguments
+	client          client_go.Interface // Nil until the source was successfully evaluated.
+	resourceVersion string              // Resource version of the last read object; empty if it needs to be read again.
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+var _ ImportSource = (*ImportKubernetes)(nil)
+
+func NewImportKubernetes(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportKubernetes {
+	return &ImportKubernetes{
+		managedOpts:     managedOpts,
+		eval:            eval,
+		onContentChange: onContentChange,
+		metrics:         newSourceMetrics(managedOpts.Registerer),
+		restartCh:       make(chan struct{}, 1),
+	}
+}
+
+// KubernetesArguments holds values which are used to configure the
+// import.kubernetes block.
+type KubernetesArguments struct {
+	Kind      string `river:"kind,attr,optional"`
+	Namespace string `river:"namespace,attr"`
+	Name      string `river:"name,attr"`
+	// Key is the key of the object holding the module. When empty, every key
+	// ending in .river is imported.
+	Key string `river:"key,attr,optional"`
+
+	// Client settings to connect to Kubernetes.
+	Client kubernetes.ClientArguments `river:"client,block,optional"`
+}
+
+// DefaultKubernetesArguments holds default settings for KubernetesArguments.
+var DefaultKubernetesArguments = KubernetesArguments{
+	Kind:   KubernetesKindConfigMap,
+	Client: kubernetes.DefaultClientArguments,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *KubernetesArguments) SetToDefault() {
+	*args = DefaultKubernetesArguments
+}
+
+// Validate implements river.Validator.
+func (args *KubernetesArguments) Validate() error {
+	switch args.Kind {
+	case KubernetesKindConfigMap, KubernetesKindSecret:
+	default:
+		return fmt.Errorf("kind must be %q or %q, got %q", KubernetesKindConfigMap, KubernetesKindSecret, args.Kind)
+	}
+	return nil
+}
+
+func (im *ImportKubernetes) Evaluate(scope *vm.Scope) error {
+	var arguments KubernetesArguments
+	if err := im.eval.Evaluate(scope, &arguments); err != nil {
+		return fmt.Errorf("decoding River: %w", err)
+	}
+
+	im.mut.Lock()
+	unchanged := im.client != nil && reflect.DeepEqual(im.args, arguments)
+	im.mut.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	restConfig, err := arguments.Client.BuildRESTConfig(im.managedOpts.Logger)
+	if err != nil {
+		return fmt.Errorf("building Kubernetes config: %w", err)
+	}
+	client, err := client_go.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("creating Kubernetes client: %w", err)
+	}
+
+	// Force an immediate read of the object to report any potential errors
+	// early. The arguments are only kept once the read succeeded, so that the
+	// next evaluation tries again with the same arguments.
+	ctx, cancel := context.WithTimeout(context.Background(), kubernetesGetTimeout)
+	defer cancel()
+	content, resourceVersion, err := im.read(ctx, arguments, client)
+	if err != nil {
+		return err
+	}
+
+	im.mut.Lock()
+	im.args, im.client, im.resourceVersion = arguments, client, resourceVersion
+	im.mut.Unlock()
+	im.publish(arguments, content)
+
+	// Restart watching with the new arguments.
+	select {
+	case im.restartCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (im *ImportKubernetes) Run(ctx context.Context) error {
+	for {
+		err := im.watch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			// The watch expired or the arguments changed; watch again right away.
+			continue
+		}
+
+		level.Error(im.managedOpts.Logger).Log("msg", "failed to watch Kubernetes object", "err", err)
+		im.setHealth(component.HealthTypeUnhealthy, fmt.Sprintf("failed to watch %s: %s", im.objectName(), err))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-im.restartCh:
+		case <-time.After(kubernetesRetryPeriod):
+		}
+	}
+}
+
+// watch watches the object and updates the module until ctx is canceled, the
+// watch expires, or the arguments change.
+func (im *ImportKubernetes) watch(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	im.mut.Lock()
+	var (
+		args            = im.args
+		client          = im.client
+		resourceVersion = im.resourceVersion
+	)
+	im.mut.Unlock()
+
+	if client == nil {
+		// The source wasn't successfully evaluated yet; wait until it is.
+		select {
+		case <-ctx.Done():
+		case <-im.restartCh:
+		}
+		return nil
+	}
+
+	if resourceVersion == "" {
+		// The object must be read again, as changes may have been missed.
+		content, rv, err := im.read(ctx, args, client)
+		if err != nil {
+			return err
+		}
+		if !im.setResourceVersion(client, rv) {
+			return nil
+		}
+		im.publish(args, content)
+		resourceVersion = rv
+	}
+
+	opts := metav1.ListOptions{
+		FieldSelector:   fields.OneTermEqualSelector("metadata.name", args.Name).String(),
+		ResourceVersion: resourceVersion,
+	}
+
+	var (
+		w   watch.Interface
+		err error
+	)
+	switch args.Kind {
+	case KubernetesKindSecret:
+		w, err = client.CoreV1().Secrets(args.Namespace).Watch(ctx, opts)
+	default:
+		w, err = client.CoreV1().ConfigMaps(args.Namespace).Watch(ctx, opts)
+	}
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-im.restartCh:
+			return nil
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return nil
+			}
+
+			switch ev.Type {
+			case watch.Added, watch.Modified:
+				content, rv, err := im.parse(args, ev.Object)
+				if err != nil {
+					return err
+				}
+				if !im.setResourceVersion(client, rv) {
+					return nil
+				}
+				im.publish(args, content)
+			case watch.Deleted:
+				im.setHealth(component.HealthTypeUnhealthy, fmt.Sprintf("%s was deleted", objectName(args)))
+			case watch.Error:
+				im.setResourceVersion(client, "")
+				return apierrors.FromObject(ev.Object)
+			}
+		}
+	}
+}
+
+// setResourceVersion records the resource version of the last object read
+// with client. It returns false if the arguments changed in the meantime, in
+// which case the object read with client is stale and must be ignored.
+func (im *ImportKubernetes) setResourceVersion(client client_go.Interface, resourceVersion string) bool {
+	im.mut.Lock()
+	defer im.mut.Unlock()
+	if im.client != client {
+		return false
+	}
+	im.resourceVersion = resourceVersion
+	return true
+}
+
+// read gets the object described by args and returns its module content and
+// resource version.
+func (im *ImportKubernetes) read(ctx context.Context, args KubernetesArguments, client client_go.Interface) (map[string]string, string, error) {
+	var (
+		obj runtime.Object
+		err error
+	)
+	start := time.Now()
+	switch args.Kind {
+	case KubernetesKindSecret:
+		obj, err = client.CoreV1().Secrets(args.Namespace).Get(ctx, args.Name, metav1.GetOptions{})
+	default:
+		obj, err = client.CoreV1().ConfigMaps(args.Namespace).Get(ctx, args.Name, metav1.GetOptions{})
+	}
+	im.metrics.observeFetch(start, err)
+	if err != nil {
+		im.setHealth(component.HealthTypeUnhealthy, fmt.Sprintf("failed to get %s: %s", objectName(args), err))
+		return nil, "", fmt.Errorf("failed to get %s: %w", objectName(args), err)
+	}
+	return im.parse(args, obj)
+}
+
+// parse returns the module content and resource version of obj.
+func (im *ImportKubernetes) parse(args KubernetesArguments, obj runtime.Object) (map[string]string, string, error) {
+	var (
+		data            = map[string]string{}
+		resourceVersion string
+	)
+	switch obj := obj.(type) {
+	case *corev1.ConfigMap:
+		resourceVersion = obj.ResourceVersion
+		for k, v := range obj.Data {
+			data[k] = v
+		}
+	case *corev1.Secret:
+		resourceVersion = obj.ResourceVersion
+		for k, v := range obj.Data {
+			data[k] = string(v)
+		}
+	default:
+		return nil, "", fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	content, err := moduleContent(args, data)
+	if err != nil {
+		im.setHealth(component.HealthTypeUnhealthy, err.Error())
+		return nil, "", err
+	}
+	return content, resourceVersion, nil
+}
+
+// publish updates the module with content. It must not be called with im.mut
+// held, as onContentChange may evaluate the module and take a while.
+func (im *ImportKubernetes) publish(args KubernetesArguments, content map[string]string) {
+	im.setHealth(component.HealthTypeHealthy, fmt.Sprintf("read %s", objectName(args)))
+	im.metrics.setContent(content)
+	im.onContentChange(content)
+}
+
+// moduleContent returns the module content from the data of the object.
+func moduleContent(args KubernetesArguments, data map[string]string) (map[string]string, error) {
+	if args.Key != "" {
+		v, ok := data[args.Key]
+		if !ok {
+			return nil, fmt.Errorf("key %q not found in %s", args.Key, objectName(args))
+		}
+		return map[string]string{args.Key: v}, nil
+	}
+
+	content := make(map[string]string)
+	for k, v := range data {
+		if strings.HasSuffix(k, ".river") {
+			content[k] = v
+		}
+	}
+	if len(content) == 0 {
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return nil, fmt.Errorf("no keys ending in .river found in %s; found keys %v", objectName(args), keys)
+	}
+	return content, nil
+}
+
+func (im *ImportKubernetes) objectName() string {
+	im.mut.Lock()
+	defer im.mut.Unlock()
+	return objectName(im.args)
+}
+
+func objectName(args KubernetesArguments) string {
+	return fmt.Sprintf("%s %s/%s", args.Kind, args.Namespace, args.Name)
+}
+
+func (im *ImportKubernetes) CurrentHealth() component.Health {
+	im.healthMut.RLock()
+	defer im.healthMut.RUnlock()
+	return im.health
+}
+
+func (im *ImportKubernetes) setHealth(t component.HealthType, msg string) {
+	im.healthMut.Lock()
+	defer im.healthMut.Unlock()
+	im.health = component.Health{
+		Health:     t,
+		Message:    msg,
+		UpdateTime: time.Now(),
+	}
+}
+
+// Update the evaluator.
+func (im *ImportKubernetes) SetEval(eval *vm.Evaluator) {
+	im.eval = eval
+}
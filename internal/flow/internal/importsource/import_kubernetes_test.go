@@ -0,0 +1,92 @@
+package importsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestImportKubernetes(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "agent", Name: "modules", ResourceVersion: "1"},
+		Data: map[string]string{
+			"math.river": `declare "add" {}`,
+			"README.md":  "not a module",
+		},
+	}
+	client := fake.NewSimpleClientset(cm)
+
+	contentCh := make(chan map[string]string, 10)
+	im := NewImportKubernetes(component.Options{Logger: log.NewNopLogger()}, nil, func(content map[string]string) {
+		contentCh <- content
+	})
+	im.args = KubernetesArguments{Kind: KubernetesKindConfigMap, Namespace: "agent", Name: "modules"}
+	im.client = client
+
+	content, resourceVersion, err := im.read(context.Background(), im.args, client)
+	require.NoError(t, err)
+	require.Equal(t, "1", resourceVersion)
+	im.resourceVersion = resourceVersion
+	im.publish(im.args, content)
+	require.Equal(t, map[string]string{"math.river": `declare "add" {}`}, <-contentCh)
+	require.Equal(t, component.HealthTypeHealthy, im.CurrentHealth().Health)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = im.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Changes to the ConfigMap are picked up by the watch.
+	require.Eventually(t, func() bool {
+		updated := cm.DeepCopy()
+		updated.Data["math.river"] = `declare "sub" {}`
+		if _, err := client.CoreV1().ConfigMaps("agent").Update(context.Background(), updated, metav1.UpdateOptions{}); err != nil {
+			return false
+		}
+
+		select {
+		case content := <-contentCh:
+			return content["math.river"] == `declare "sub" {}`
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestImportKubernetes_NotEvaluated(t *testing.T) {
+	im := NewImportKubernetes(component.Options{Logger: log.NewNopLogger()}, nil, nil)
+
+	// Running before a successful evaluation waits for one instead of using
+	// the missing client.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	require.NoError(t, im.Run(ctx))
+}
+
+func TestImportKubernetes_ModuleContent(t *testing.T) {
+	args := KubernetesArguments{Kind: KubernetesKindSecret, Namespace: "agent", Name: "modules", Key: "lib"}
+
+	content, err := moduleContent(args, map[string]string{"lib": "declare \"a\" {}", "other": "b"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"lib": "declare \"a\" {}"}, content)
+
+	_, err = moduleContent(args, map[string]string{"other": "b"})
+	require.EqualError(t, err, `key "lib" not found in secret agent/modules`)
+
+	args.Key = ""
+	_, err = moduleContent(args, map[string]string{"other": "b"})
+	require.EqualError(t, err, "no keys ending in .river found in secret agent/modules; found keys [other]")
+}
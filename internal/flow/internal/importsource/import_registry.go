@@ -0,0 +1,201 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service/moduleregistry"
+	"github.com/grafana/river/vm"
+)
+
+// registryResolveTimeout is the timeout for resolving the module when the
+// source is evaluated.
+const registryResolveTimeout = time.Minute
+
+// ImportRegistry imports a module from a source of the module_registry
+// service. The newest version matching the version constraint is imported,
+// and the module is updated whenever the registry resolves a newer one.
+type ImportRegistry struct {
+	managedOpts     component.Options
+	eval            *vm.Evaluator
+	onContentChange func(map[string]string)
+	metrics         *sourceMetrics
+	label           string
+
+	mut      sync.Mutex
+	args     RegistryArguments
+	registry moduleregistry.Registry
+	version  string // Version of the imported module.
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+var _ ImportSource = (*ImportRegistry)(nil)
+
+func NewImportRegistry(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportRegistry {
+	// The source defaults to the label of the block, which is the last part
+	// of the ID.
+	label := managedOpts.ID
+	if i := strings.LastIndex(label, BlockImportRegistry+"."); i >= 0 {
+		label = label[i+len(BlockImportRegistry)+1:]
+	}
+
+	return &ImportRegistry{
+		managedOpts:     managedOpts,
+		eval:            eval,
+		onContentChange: onContentChange,
+		metrics:         newSourceMetrics(managedOpts.Registerer),
+		label:           label,
+	}
+}
+
+// RegistryArguments holds values which are used to configure the
+// import.registry block.
+type RegistryArguments struct {
+	// Source is the name of the module registry source. It defaults to the
+	// label of the block.
+	Source  string `river:"source,attr,optional"`
+	Version string `river:"version,attr,optional"`
+}
+
+// DefaultRegistryArguments holds default settings for RegistryArguments.
+var DefaultRegistryArguments = RegistryArguments{
+	Version: "*",
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *RegistryArguments) SetToDefault() {
+	*args = DefaultRegistryArguments
+}
+
+// Validate implements river.Validator.
+func (args *RegistryArguments) Validate() error {
+	if _, err := semver.NewConstraint(args.Version); err != nil {
+		return fmt.Errorf("invalid version constraint %q: %w", args.Version, err)
+	}
+	return nil
+}
+
+func (im *ImportRegistry) Evaluate(scope *vm.Scope) error {
+	im.mut.Lock()
+	defer im.mut.Unlock()
+
+	var arguments RegistryArguments
+	if err := im.eval.Evaluate(scope, &arguments); err != nil {
+		return fmt.Errorf("decoding River: %w", err)
+	}
+	if arguments.Source == "" {
+		arguments.Source = im.label
+	}
+
+	if im.registry == nil {
+		data, err := im.managedOpts.GetServiceData(moduleregistry.ServiceName)
+		if err != nil {
+			return fmt.Errorf("getting module registry: %w", err)
+		}
+		im.registry = data.(moduleregistry.Registry)
+	}
+
+	if reflect.DeepEqual(im.args, arguments) {
+		return nil
+	}
+	im.args = arguments
+	im.version = ""
+
+	ctx, cancel := context.WithTimeout(context.Background(), registryResolveTimeout)
+	defer cancel()
+	return im.resolve(ctx)
+}
+
+func (im *ImportRegistry) Run(ctx context.Context) error {
+	im.mut.Lock()
+	registry := im.registry
+	im.mut.Unlock()
+
+	if registry == nil {
+		// Evaluation failed before the registry could be retrieved.
+		<-ctx.Done()
+		return nil
+	}
+
+	updates, unsubscribe := registry.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-updates:
+			resolveCtx, cancel := context.WithTimeout(ctx, registryResolveTimeout)
+			im.mut.Lock()
+			err := im.resolve(resolveCtx)
+			im.mut.Unlock()
+			cancel()
+			if err != nil {
+				level.Error(im.managedOpts.Logger).Log("msg", "failed to resolve module", "err", err)
+			}
+		}
+	}
+}
+
+// resolve imports the newest version of the module which matches the
+// version constraint. The content is only updated when the version changes.
+// resolve must only be called with im.mut held.
+func (im *ImportRegistry) resolve(ctx context.Context) (err error) {
+	defer func() { im.updateHealth(err) }()
+
+	start := time.Now()
+	module, err := im.registry.Resolve(ctx, im.args.Source, im.args.Version)
+	im.metrics.observeFetch(start, err)
+	if err != nil {
+		return err
+	}
+	if module.Version == im.version {
+		return nil
+	}
+
+	level.Info(im.managedOpts.Logger).Log("msg", "importing module version", "source", im.args.Source, "version", module.Version)
+	im.version = module.Version
+	im.metrics.setContent(module.Content)
+	im.onContentChange(module.Content)
+	return nil
+}
+
+func (im *ImportRegistry) updateHealth(err error) {
+	im.healthMut.Lock()
+	defer im.healthMut.Unlock()
+
+	if err != nil {
+		im.health = component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    err.Error(),
+			UpdateTime: time.Now(),
+		}
+		return
+	}
+	im.health = component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    fmt.Sprintf("imported version %s", im.version),
+		UpdateTime: time.Now(),
+	}
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (im *ImportRegistry) CurrentHealth() component.Health {
+	im.healthMut.RLock()
+	defer im.healthMut.RUnlock()
+	return im.health
+}
+
+// Update the evaluator.
+func (im *ImportRegistry) SetEval(eval *vm.Evaluator) {
+	im.eval = eval
+}
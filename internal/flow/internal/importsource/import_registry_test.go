@@ -0,0 +1,98 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/service/moduleregistry"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistry resolves every constraint to its current module.
+type fakeRegistry struct {
+	mut     sync.Mutex
+	modules map[string]moduleregistry.Module
+	updates chan struct{}
+}
+
+func (r *fakeRegistry) Resolve(_ context.Context, source, _ string) (moduleregistry.Module, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	m, ok := r.modules[source]
+	if !ok {
+		return moduleregistry.Module{}, fmt.Errorf("module registry source %q isn't defined", source)
+	}
+	return m, nil
+}
+
+func (r *fakeRegistry) Subscribe() (<-chan struct{}, func()) {
+	return r.updates, func() {}
+}
+
+func (r *fakeRegistry) set(source string, m moduleregistry.Module) {
+	r.mut.Lock()
+	r.modules[source] = m
+	r.mut.Unlock()
+	r.updates <- struct{}{}
+}
+
+func TestImportRegistry(t *testing.T) {
+	registry := &fakeRegistry{
+		modules: map[string]moduleregistry.Module{
+			"lib": {Version: "1.2.0", Content: map[string]string{"lib.river": `declare "a" {}`}},
+		},
+		updates: make(chan struct{}),
+	}
+
+	contentCh := make(chan map[string]string, 10)
+	im := NewImportRegistry(component.Options{ID: "module/import.registry.lib", Logger: log.NewNopLogger()}, nil, func(content map[string]string) {
+		contentCh <- content
+	})
+	require.Equal(t, "lib", im.label)
+
+	im.registry = registry
+	im.args = RegistryArguments{Source: "lib", Version: ">=1.2 <2"}
+	im.mut.Lock()
+	require.NoError(t, im.resolve(context.Background()))
+	im.mut.Unlock()
+	require.Equal(t, map[string]string{"lib.river": `declare "a" {}`}, <-contentCh)
+	require.Equal(t, component.HealthTypeHealthy, im.CurrentHealth().Health)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = im.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Content is only updated when the resolved version changes.
+	registry.set("lib", moduleregistry.Module{Version: "1.2.0", Content: map[string]string{"lib.river": `declare "a" {}`}})
+	registry.set("lib", moduleregistry.Module{Version: "1.3.0", Content: map[string]string{"lib.river": `declare "b" {}`}})
+	select {
+	case content := <-contentCh:
+		require.Equal(t, map[string]string{"lib.river": `declare "b" {}`}, content)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for content")
+	}
+	require.Empty(t, contentCh)
+}
+
+func TestRegistryArguments_Validate(t *testing.T) {
+	args := DefaultRegistryArguments
+	require.NoError(t, args.Validate())
+
+	args.Version = ">=1.2 <2"
+	require.NoError(t, args.Validate())
+
+	args.Version = "latest"
+	require.ErrorContains(t, args.Validate(), `invalid version constraint "latest"`)
+}
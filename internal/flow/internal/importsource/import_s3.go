@@ -0,0 +1,103 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	remote_s3 "github.com/grafana/agent/internal/component/remote/s3"
+	"github.com/grafana/river/vm"
+)
+
+// ImportS3 imports a module from an S3-compatible object store via the
+// remote.s3 component.
+type ImportS3 struct {
+	managedRemoteS3 *remote_s3.Component
+	arguments       component.Arguments
+	managedOpts     component.Options
+	eval            *vm.Evaluator
+}
+
+var _ ImportSource = (*ImportS3)(nil)
+
+func NewImportS3(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportS3 {
+	opts := managedOpts
+	opts.OnStateChange = func(e component.Exports) {
+		onContentChange(map[string]string{opts.ID: e.(remote_s3.Exports).Content.Value})
+	}
+	return &ImportS3{
+		managedOpts: opts,
+		eval:        eval,
+	}
+}
+
+// S3Arguments holds values which are used to configure the remote.s3 component.
+type S3Arguments struct {
+	Path          string           `river:"path,attr"`
+	PollFrequency time.Duration    `river:"poll_frequency,attr,optional"`
+	Client        remote_s3.Client `river:"client,block,optional"`
+}
+
+// DefaultS3Arguments holds default settings for S3Arguments.
+var DefaultS3Arguments = S3Arguments{
+	PollFrequency: remote_s3.DefaultArguments.PollFrequency,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *S3Arguments) SetToDefault() {
+	*args = DefaultS3Arguments
+}
+
+func (args S3Arguments) remoteS3Arguments() remote_s3.Arguments {
+	return remote_s3.Arguments{
+		Path:          args.Path,
+		PollFrequency: args.PollFrequency,
+		Options:       args.Client,
+	}
+}
+
+func (im *ImportS3) Evaluate(scope *vm.Scope) error {
+	var arguments S3Arguments
+	if err := im.eval.Evaluate(scope, &arguments); err != nil {
+		return fmt.Errorf("decoding River: %w", err)
+	}
+	remoteArgs := arguments.remoteS3Arguments()
+	if err := remoteArgs.Validate(); err != nil {
+		return err
+	}
+
+	if im.managedRemoteS3 == nil {
+		var err error
+		im.managedRemoteS3, err = remote_s3.New(im.managedOpts, remoteArgs)
+		if err != nil {
+			return fmt.Errorf("creating s3 component: %w", err)
+		}
+		im.arguments = arguments
+	}
+
+	if reflect.DeepEqual(im.arguments, arguments) {
+		return nil
+	}
+
+	// Update the existing managed component
+	if err := im.managedRemoteS3.Update(remoteArgs); err != nil {
+		return fmt.Errorf("updating component: %w", err)
+	}
+	im.arguments = arguments
+	return nil
+}
+
+func (im *ImportS3) Run(ctx context.Context) error {
+	return im.managedRemoteS3.Run(ctx)
+}
+
+func (im *ImportS3) CurrentHealth() component.Health {
+	return im.managedRemoteS3.CurrentHealth()
+}
+
+// Update the evaluator.
+func (im *ImportS3) SetEval(eval *vm.Evaluator) {
+	im.eval = eval
+}
@@ -0,0 +1,186 @@
+package importsource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/vm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 serves objects for path-style S3 GetObject requests.
+type fakeS3 struct {
+	mut      sync.Mutex
+	objects  map[string]string
+	requests int
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.requests++
+
+	if !strings.Contains(r.Header.Get("Authorization"), "Credential=agent/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	content, ok := s.objects[r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	fmt.Fprint(w, content)
+}
+
+func (s *fakeS3) requestCount() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.requests
+}
+
+func TestImportS3_Evaluate(t *testing.T) {
+	fake := &fakeS3{objects: map[string]string{
+		"/modules/a.river": `declare "a" {}`,
+		"/modules/b.river": `declare "b" {}`,
+	}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	evaluator := func(path string) *vm.Evaluator {
+		file, err := parser.ParseFile("", []byte(fmt.Sprintf(`import.s3 "lib" {
+			path = %q
+
+			client {
+				endpoint       = %q
+				key            = "agent"
+				secret         = "secret"
+				region         = "us-east-1"
+				use_path_style = true
+			}
+		}`, path, srv.URL)))
+		require.NoError(t, err)
+		return vm.New(file.Body[0].(*ast.BlockStmt).Body)
+	}
+
+	contentCh := make(chan map[string]string, 10)
+	opts := component.Options{
+		ID:         "import.s3.lib",
+		Logger:     log.NewNopLogger(),
+		Registerer: prometheus.NewRegistry(),
+	}
+	im := NewImportS3(opts, evaluator("s3://modules/a.river"), func(content map[string]string) {
+		contentCh <- content
+	})
+
+	// The content is fetched when the import is first evaluated.
+	require.NoError(t, im.Evaluate(&vm.Scope{}))
+	require.Equal(t, map[string]string{"import.s3.lib": `declare "a" {}`}, <-contentCh)
+	require.Equal(t, component.HealthTypeHealthy, im.CurrentHealth().Health)
+
+	// Evaluating unchanged arguments doesn't fetch the content again.
+	requests := fake.requestCount()
+	require.NoError(t, im.Evaluate(&vm.Scope{}))
+	require.Equal(t, requests, fake.requestCount())
+
+	// Updated arguments are passed to the managed remote.s3 component, which
+	// fetches the new object once it runs.
+	im.SetEval(evaluator("s3://modules/b.river"))
+	require.NoError(t, im.Evaluate(&vm.Scope{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = im.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	select {
+	case content := <-contentCh:
+		require.Equal(t, map[string]string{"import.s3.lib": `declare "b" {}`}, content)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no content update after changing the path")
+	}
+}
+
+func TestImportS3_Errors(t *testing.T) {
+	srv := httptest.NewServer(&fakeS3{})
+	defer srv.Close()
+
+	tt := []struct {
+		name   string
+		config string
+		expect string
+	}{
+		{
+			name:   "poll frequency too low",
+			config: `import.s3 "lib" { path = "s3://modules/a.river", poll_frequency = "1s" }`,
+			expect: "poll_frequency must be greater than 30s",
+		},
+		{
+			name:   "key without secret",
+			config: `import.s3 "lib" { path = "s3://modules/a.river", client { key = "agent" } }`,
+			expect: "creating s3 component",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			file, err := parser.ParseFile("", []byte(tc.config))
+			require.NoError(t, err)
+
+			opts := component.Options{
+				ID:         "import.s3.lib",
+				Logger:     log.NewNopLogger(),
+				Registerer: prometheus.NewRegistry(),
+			}
+			im := NewImportS3(opts, vm.New(file.Body[0].(*ast.BlockStmt).Body), func(map[string]string) {})
+			require.ErrorContains(t, im.Evaluate(&vm.Scope{}), tc.expect)
+		})
+	}
+}
+
+func TestImportS3_FetchError(t *testing.T) {
+	srv := httptest.NewServer(&fakeS3{objects: map[string]string{}})
+	defer srv.Close()
+
+	file, err := parser.ParseFile("", []byte(fmt.Sprintf(`import.s3 "lib" {
+		path = "s3://modules/missing.river"
+
+		client {
+			endpoint       = %q
+			key            = "agent"
+			secret         = "secret"
+			region         = "us-east-1"
+			use_path_style = true
+		}
+	}`, srv.URL)))
+	require.NoError(t, err)
+
+	var updates int
+	opts := component.Options{
+		ID:         "import.s3.lib",
+		Logger:     log.NewNopLogger(),
+		Registerer: prometheus.NewRegistry(),
+	}
+	im := NewImportS3(opts, vm.New(file.Body[0].(*ast.BlockStmt).Body), func(map[string]string) { updates++ })
+
+	// A missing object marks the import unhealthy without updating the content.
+	require.NoError(t, im.Evaluate(&vm.Scope{}))
+	require.Zero(t, updates)
+	require.Equal(t, component.HealthTypeUnhealthy, im.CurrentHealth().Health)
+}
@@ -3,6 +3,7 @@ package importsource
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/river/vm"
@@ -15,13 +16,19 @@ const (
 	String
 	Git
 	HTTP
+	S3
+	Kubernetes
+	Registry
 )
 
 const (
-	BlockImportFile   = "import.file"
-	BlockImportString = "import.string"
-	BlockImportHTTP   = "import.http"
-	BlockImportGit    = "import.git"
+	BlockImportFile       = "import.file"
+	BlockImportString     = "import.string"
+	BlockImportHTTP       = "import.http"
+	BlockImportGit        = "import.git"
+	BlockImportS3         = "import.s3"
+	BlockImportKubernetes = "import.kubernetes"
+	BlockImportRegistry   = "import.registry"
 )
 
 // ImportSource retrieves a module from a source.
@@ -38,20 +45,51 @@ type ImportSource interface {
 
 // NewImportSource creates a new ImportSource depending on the type.
 // onContentChange is used by the source when it receives new content.
-func NewImportSource(sourceType SourceType, managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) ImportSource {
+// Sources which read content stop reading once the content is larger than
+// maxContentSize bytes; zero doesn't limit the content.
+func NewImportSource(sourceType SourceType, managedOpts component.Options, eval *vm.Evaluator, maxContentSize int64, onContentChange func(map[string]string)) ImportSource {
 	switch sourceType {
 	case File:
-		return NewImportFile(managedOpts, eval, onContentChange)
+		im := NewImportFile(managedOpts, eval, onContentChange)
+		im.maxContentSize = maxContentSize
+		return im
 	case String:
 		return NewImportString(eval, onContentChange)
 	case HTTP:
-		return NewImportHTTP(managedOpts, eval, onContentChange)
+		im := NewImportHTTP(managedOpts, eval, onContentChange)
+		im.maxContentSize = maxContentSize
+		return im
 	case Git:
-		return NewImportGit(managedOpts, eval, onContentChange)
+		im := NewImportGit(managedOpts, eval, onContentChange)
+		im.maxContentSize = maxContentSize
+		return im
+	case S3:
+		return NewImportS3(managedOpts, eval, onContentChange)
+	case Kubernetes:
+		return NewImportKubernetes(managedOpts, eval, onContentChange)
+	case Registry:
+		return NewImportRegistry(managedOpts, eval, onContentChange)
 	}
 	panic(fmt.Errorf("unsupported source type: %v", sourceType))
 }
 
+// readLimited reads r until EOF. It fails once more than limit bytes are
+// read, without reading the rest of r. A limit of zero or less doesn't limit
+// reading.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(r)
+	}
+	bb, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(bb)) > limit {
+		return nil, fmt.Errorf("content exceeds the maximum size of %d bytes", limit)
+	}
+	return bb, nil
+}
+
 // GetSourceType returns a SourceType matching a source name.
 func GetSourceType(fullName string) SourceType {
 	switch fullName {
@@ -63,6 +101,12 @@ func GetSourceType(fullName string) SourceType {
 		return HTTP
 	case BlockImportGit:
 		return Git
+	case BlockImportS3:
+		return S3
+	case BlockImportKubernetes:
+		return Kubernetes
+	case BlockImportRegistry:
+		return Registry
 	}
 	panic(fmt.Errorf("name does not map to a known source type: %v", fullName))
 }
@@ -0,0 +1,95 @@
+package importsource
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"time"
+
+	"github.com/grafana/agent/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Types of fetch errors reported by the
+// agent_import_source_fetch_errors_total metric.
+const (
+	fetchErrorTimeout    = "timeout"
+	fetchErrorNotFound   = "not_found"
+	fetchErrorPermission = "permission"
+	fetchErrorOther      = "other"
+)
+
+// sourceMetrics holds the metrics shared by the import sources. They are
+// registered on the registry of the import node, so they are labeled with the
+// node which owns the source.
+type sourceMetrics struct {
+	fetchDuration prometheus.Histogram
+	fetchErrors   *prometheus.CounterVec
+	contentBytes  prometheus.Gauge
+}
+
+func newSourceMetrics(reg prometheus.Registerer) *sourceMetrics {
+	m := &sourceMetrics{
+		fetchDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "agent_import_source_fetch_duration_seconds",
+			Help:    "Time spent fetching the content of the import source.",
+			Buckets: []float64{.001, .005, .025, .1, .5, 1, 2.5, 5, 10, 30, 60},
+		}),
+		fetchErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "agent_import_source_fetch_errors_total",
+			Help: "Total number of failed fetches of the import source content by error type.",
+		}, []string{"type"}),
+		contentBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_import_source_content_bytes",
+			Help: "Size in bytes of the content last fetched by the import source.",
+		}),
+	}
+
+	if reg != nil {
+		m.fetchDuration = util.MustRegisterOrGet(reg, m.fetchDuration).(prometheus.Histogram)
+		m.fetchErrors = util.MustRegisterOrGet(reg, m.fetchErrors).(*prometheus.CounterVec)
+		m.contentBytes = util.MustRegisterOrGet(reg, m.contentBytes).(prometheus.Gauge)
+	}
+	return m
+}
+
+// observeFetch records a fetch which started at start and failed if err is
+// not nil.
+func (m *sourceMetrics) observeFetch(start time.Time, err error) {
+	m.observeFetchDuration(time.Since(start), err)
+}
+
+func (m *sourceMetrics) observeFetchDuration(d time.Duration, err error) {
+	m.fetchDuration.Observe(d.Seconds())
+	if err != nil {
+		m.fetchErrors.WithLabelValues(fetchErrorType(err)).Inc()
+	}
+}
+
+// setContent records the size of the content of the source.
+func (m *sourceMetrics) setContent(content map[string]string) {
+	var size int
+	for _, c := range content {
+		size += len(c)
+	}
+	m.contentBytes.Set(float64(size))
+}
+
+// fetchErrorType classifies err for the fetch errors metric.
+func fetchErrorType(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout(), apierrors.IsTimeout(err):
+		return fetchErrorTimeout
+	case errors.Is(err, fs.ErrNotExist), apierrors.IsNotFound(err):
+		return fetchErrorNotFound
+	case errors.Is(err, fs.ErrPermission), apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return fetchErrorPermission
+	default:
+		return fetchErrorOther
+	}
+}
@@ -0,0 +1,141 @@
+// Package stdlib holds Flow-specific functions which extend the River standard
+// library. The functions are made available to expressions through the parent
+// of the scopes which Flow evaluates blocks against; see [Scope].
+//
+// Functions accept secrets wherever they accept strings. The result of a
+// function called with a secret is a secret too, so that values derived from
+// secrets stay redacted.
+package stdlib
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/grafana/agent/internal/util/rivervalue"
+	"github.com/grafana/river/rivertypes"
+	"github.com/grafana/river/vm"
+	"gopkg.in/yaml.v3"
+)
+
+// Identifiers holds the functions of the package by name.
+var Identifiers = map[string]any{
+	"base64_encode": mapString(func(in string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(in)), nil
+	}),
+	"base64_decode": mapString(func(in string) (string, error) {
+		out, err := base64.StdEncoding.DecodeString(in)
+		return string(out), err
+	}),
+	"url_encode": mapString(func(in string) (string, error) {
+		return url.QueryEscape(in), nil
+	}),
+	"json_encode": jsonEncode,
+	"yaml_decode": yamlDecode,
+
+	"time": timeFunctions,
+}
+
+// Scope is the scope holding Identifiers. Lookups which aren't found in
+// Scope fall back to the River standard library.
+//
+// Scope must not be modified.
+var Scope = &vm.Scope{Variables: Identifiers}
+
+// mapString returns a function which applies f to a string or a secret,
+// keeping the kind of the input.
+func mapString(f func(in string) (string, error)) func(in any) (any, error) {
+	return func(in any) (any, error) {
+		s, isSecret, err := stringValue(in)
+		if err != nil {
+			return nil, err
+		}
+		out, err := f(s)
+		if err != nil {
+			return nil, err
+		}
+		return wrapString(out, isSecret), nil
+	}
+}
+
+// stringValue returns the string held by in, which must be a string, a
+// secret, or an optional secret.
+func stringValue(in any) (s string, isSecret bool, err error) {
+	switch v := in.(type) {
+	case string:
+		return v, false, nil
+	case rivertypes.Secret:
+		return string(v), true, nil
+	case rivertypes.OptionalSecret:
+		return v.Value, v.IsSecret, nil
+	default:
+		return "", false, fmt.Errorf("expected string or secret, got %T", in)
+	}
+}
+
+func wrapString(s string, isSecret bool) any {
+	if isSecret {
+		return rivertypes.Secret(s)
+	}
+	return s
+}
+
+// jsonEncode encodes in as JSON. The result is a secret if in holds any
+// secret.
+func jsonEncode(in any) (any, error) {
+	plain, isSecret := unwrapSecrets(in)
+	bb, err := json.Marshal(plain)
+	if err != nil {
+		return nil, err
+	}
+	return wrapString(string(bb), isSecret), nil
+}
+
+// unwrapSecrets replaces the secrets held by in with their string values,
+// reporting whether in held any secret.
+func unwrapSecrets(in any) (any, bool) {
+	switch v := in.(type) {
+	case rivertypes.Secret:
+		return string(v), true
+	case rivertypes.OptionalSecret:
+		return v.Value, v.IsSecret
+	case []any:
+		var isSecret bool
+		out := make([]any, len(v))
+		for i, elem := range v {
+			var elemSecret bool
+			out[i], elemSecret = unwrapSecrets(elem)
+			isSecret = isSecret || elemSecret
+		}
+		return out, isSecret
+	case map[string]any:
+		var isSecret bool
+		out := make(map[string]any, len(v))
+		for key, elem := range v {
+			var elemSecret bool
+			out[key], elemSecret = unwrapSecrets(elem)
+			isSecret = isSecret || elemSecret
+		}
+		return out, isSecret
+	default:
+		return in, false
+	}
+}
+
+// yamlDecode decodes a YAML document into a River value. JSON documents are
+// valid YAML documents, so yamlDecode can also decode JSON secrets.
+//
+// When in is a secret, every string in the result is a secret.
+func yamlDecode(in any) (any, error) {
+	s, isSecret, err := stringValue(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var out any
+	if err := yaml.Unmarshal([]byte(s), &out); err != nil {
+		return nil, err
+	}
+	return rivervalue.FromYAML(out, isSecret), nil
+}
@@ -0,0 +1,106 @@
+package stdlib_test
+
+import (
+	"testing"
+
+	"github.com/grafana/agent/internal/flow/internal/stdlib"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/rivertypes"
+	"github.com/grafana/river/vm"
+	"github.com/stretchr/testify/require"
+)
+
+func eval(t *testing.T, expr string, v any) error {
+	t.Helper()
+
+	node, err := parser.ParseExpression(expr)
+	require.NoError(t, err)
+
+	scope := &vm.Scope{
+		Parent: stdlib.Scope,
+		Variables: map[string]any{
+			"secret":      rivertypes.Secret("hunter2"),
+			"json_secret": rivertypes.Secret(`{"username": "admin", "port": 8080}`),
+		},
+	}
+	return vm.New(node).Evaluate(scope, v)
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []struct {
+		expr   string
+		expect string
+	}{
+		{`base64_encode("hello")`, "aGVsbG8="},
+		{`base64_decode("aGVsbG8=")`, "hello"},
+		{`url_encode("a b&c=d")`, "a+b%26c%3Dd"},
+		{`json_encode({a = 1, b = [true, "x"]})`, `{"a":1,"b":[true,"x"]}`},
+		{`json_encode(null)`, "null"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			var actual string
+			require.NoError(t, eval(t, tc.expr, &actual))
+			require.Equal(t, tc.expect, actual)
+		})
+	}
+}
+
+func TestFunctions_Errors(t *testing.T) {
+	var actual string
+	require.ErrorContains(t, eval(t, `base64_decode("not base64!")`, &actual), "illegal base64 data")
+	require.ErrorContains(t, eval(t, `base64_encode(5)`, &actual), "expected string or secret")
+	require.Error(t, eval(t, `yaml_decode("a: [")`, &actual))
+}
+
+func TestFunctions_SecretPropagation(t *testing.T) {
+	for _, expr := range []string{
+		`base64_encode(secret)`,
+		`url_encode(secret)`,
+		`json_encode({password = secret})`,
+	} {
+		t.Run(expr, func(t *testing.T) {
+			// Values derived from secrets can't be used as strings...
+			var s string
+			require.Error(t, eval(t, expr, &s))
+
+			// ...but can be used as secrets.
+			var secret rivertypes.Secret
+			require.NoError(t, eval(t, expr, &secret))
+		})
+	}
+
+	var decoded rivertypes.Secret
+	require.NoError(t, eval(t, `base64_decode(base64_encode(secret))`, &decoded))
+	require.Equal(t, rivertypes.Secret("hunter2"), decoded)
+
+	var encoded rivertypes.Secret
+	require.NoError(t, eval(t, `json_encode({password = secret})`, &encoded))
+	require.Equal(t, rivertypes.Secret(`{"password":"hunter2"}`), encoded)
+}
+
+func TestYAMLDecode(t *testing.T) {
+	type object struct {
+		Username string `river:"username,attr"`
+		Ports    []int  `river:"ports,attr"`
+	}
+
+	var actual object
+	require.NoError(t, eval(t, `yaml_decode("username: admin\nports: [80, 443]")`, &actual))
+	require.Equal(t, object{Username: "admin", Ports: []int{80, 443}}, actual)
+
+	// Strings decoded from a secret are secrets.
+	type secretObject struct {
+		Username rivertypes.Secret `river:"username,attr"`
+		Port     int               `river:"port,attr"`
+	}
+	var actualSecret secretObject
+	require.NoError(t, eval(t, `yaml_decode(json_secret)`, &actualSecret))
+	require.Equal(t, secretObject{Username: "admin", Port: 8080}, actualSecret)
+
+	var leaked struct {
+		Username string `river:"username,attr"`
+		Port     int    `river:"port,attr"`
+	}
+	require.Error(t, eval(t, `yaml_decode(json_secret)`, &leaked))
+}
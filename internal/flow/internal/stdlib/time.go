@@ -0,0 +1,123 @@
+package stdlib
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MinNowInterval is the smallest interval accepted by time.now.
+const MinNowInterval = time.Second
+
+// timeFunctions holds the functions of the time namespace. Times are
+// represented as RFC 3339 strings.
+var timeFunctions = map[string]any{
+	"now":         timeNow,
+	"format":      timeFormat,
+	"add":         timeAdd,
+	"in_location": timeInLocation,
+	"schedule":    timeSchedule,
+}
+
+// timeNow returns the current time in UTC. Flow re-evaluates blocks which
+// call time.now with a literal interval on that interval; see
+// [ParseNowInterval].
+func timeNow(interval string) (string, error) {
+	if _, err := ParseNowInterval(interval); err != nil {
+		return "", err
+	}
+	return time.Now().UTC().Format(time.RFC3339), nil
+}
+
+// ParseNowInterval parses the interval passed to time.now.
+func ParseNowInterval(interval string) (time.Duration, error) {
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return 0, err
+	}
+	if d < MinNowInterval {
+		return 0, fmt.Errorf("interval %s is smaller than the minimum of %s", d, MinNowInterval)
+	}
+	return d, nil
+}
+
+func parseTime(t string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, t)
+}
+
+// timeFormat formats t using a Go time layout, such as "15:04".
+func timeFormat(t string, layout string) (string, error) {
+	parsed, err := parseTime(t)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Format(layout), nil
+}
+
+// timeAdd adds a duration, which may be negative, to t.
+func timeAdd(t string, duration string) (string, error) {
+	parsed, err := parseTime(t)
+	if err != nil {
+		return "", err
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Add(d).Format(time.RFC3339), nil
+}
+
+// timeInLocation converts t to the IANA time zone with the given name, such
+// as "Europe/Paris".
+func timeInLocation(t string, name string) (string, error) {
+	parsed, err := parseTime(t)
+	if err != nil {
+		return "", err
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return "", err
+	}
+	return parsed.In(loc).Format(time.RFC3339), nil
+}
+
+// timeSchedule returns the value of the schedule entry active at t. The keys
+// of schedule are times of day in the "15:04" format, compared to the time of
+// day of t in its own time zone. The active entry is the one with the latest
+// time of day which isn't after t; before the first entry of the day, the last
+// entry of the previous day is active.
+func timeSchedule(t string, schedule map[string]any) (any, error) {
+	parsed, err := parseTime(t)
+	if err != nil {
+		return nil, err
+	}
+	if len(schedule) == 0 {
+		return nil, fmt.Errorf("schedule must have at least one entry")
+	}
+
+	type entry struct {
+		minute int // Minute of the day of the entry.
+		value  any
+	}
+	entries := make([]entry, 0, len(schedule))
+	for key, value := range schedule {
+		start, err := time.Parse("15:04", key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule entry %q: expected a time of day such as 08:00", key)
+		}
+		entries = append(entries, entry{minute: start.Hour()*60 + start.Minute(), value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].minute < entries[j].minute })
+
+	var (
+		minute = parsed.Hour()*60 + parsed.Minute()
+		active = entries[len(entries)-1]
+	)
+	for _, e := range entries {
+		if e.minute > minute {
+			break
+		}
+		active = e
+	}
+	return active.value, nil
+}
@@ -0,0 +1,49 @@
+package stdlib_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeFunctions(t *testing.T) {
+	tests := []struct {
+		expr   string
+		expect string
+	}{
+		{`time.format("2024-03-01T08:30:00Z", "15:04")`, "08:30"},
+		{`time.add("2024-03-01T08:30:00Z", "-1h30m")`, "2024-03-01T07:00:00Z"},
+		{`time.in_location("2024-03-01T08:30:00Z", "Europe/Paris")`, "2024-03-01T09:30:00+01:00"},
+		{`time.schedule("2024-03-01T08:30:00Z", {"08:00" = "15s", "20:00" = "60s"})`, "15s"},
+		{`time.schedule("2024-03-01T21:00:00Z", {"08:00" = "15s", "20:00" = "60s"})`, "60s"},
+		{`time.schedule("2024-03-01T03:00:00Z", {"08:00" = "15s", "20:00" = "60s"})`, "60s"},
+		{`time.schedule("2024-03-01T08:00:00+01:00", {"08:00" = "15s", "20:00" = "60s"})`, "15s"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			var actual string
+			require.NoError(t, eval(t, tc.expr, &actual))
+			require.Equal(t, tc.expect, actual)
+		})
+	}
+}
+
+func TestTimeNow(t *testing.T) {
+	var actual string
+	require.NoError(t, eval(t, `time.now("1m")`, &actual))
+	now, err := time.Parse(time.RFC3339, actual)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), now, time.Minute)
+
+	require.ErrorContains(t, eval(t, `time.now("10ms")`, &actual), "smaller than the minimum")
+	require.Error(t, eval(t, `time.now("soon")`, &actual))
+}
+
+func TestTimeFunctions_Errors(t *testing.T) {
+	var actual string
+	require.Error(t, eval(t, `time.format("yesterday", "15:04")`, &actual))
+	require.Error(t, eval(t, `time.in_location("2024-03-01T08:30:00Z", "Nowhere/Special")`, &actual))
+	require.ErrorContains(t, eval(t, `time.schedule("2024-03-01T08:30:00Z", {})`, &actual), "at least one entry")
+	require.ErrorContains(t, eval(t, `time.schedule("2024-03-01T08:30:00Z", {"morning" = "15s"})`, &actual), "invalid schedule entry")
+}
@@ -18,6 +18,7 @@ func init() {
 		Stability: featuregate.StabilityBeta,
 		Args:      TickConfig{},
 		Exports:   TickExports{},
+		Lazy:      true,
 
 		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
 			return NewTick(opts, args.(TickConfig))
@@ -135,13 +135,21 @@ func newModule(o *moduleOptions) *module {
 			ModuleRegistry:    o.ModuleRegistry,
 			ComponentRegistry: o.ComponentRegistry,
 			WorkerPool:        o.WorkerPool,
+			DryRun:            o.DryRun,
 			Options: Options{
-				ControllerID: o.ID,
-				Tracer:       o.Tracer,
-				Reg:          o.Reg,
-				Logger:       o.Logger,
-				DataPath:     o.DataPath,
-				MinStability: o.MinStability,
+				ControllerID:                o.ID,
+				Tracer:                      o.Tracer,
+				Reg:                         o.Reg,
+				Logger:                      o.Logger,
+				DataPath:                    o.DataPath,
+				MinStability:                o.MinStability,
+				CheckSecrets:                o.CheckSecrets,
+				LazyComponents:              o.LazyComponents,
+				ImportMaxContentSize:        o.ImportLimits.MaxContentSize,
+				ImportParseTimeout:          o.ImportLimits.ParseTimeout,
+				ImportEvaluationConcurrency: o.ImportLimits.Concurrency,
+				MaxExportsSize:              o.MaxExportsSize,
+				RecordCollectorCallSites:    o.RecordCollectorCallSites,
 				OnExportsChange: func(exports map[string]any) {
 					if o.export != nil {
 						o.export(exports)
@@ -171,6 +179,14 @@ func (c *module) LoadBody(body ast.Body, args map[string]any, customComponentReg
 	return c.f.loadSource(ff, args, customComponentRegistry)
 }
 
+// Lazy reports whether every component of the module may be started lazily.
+// Custom components backed by the module are only started lazily if so, as
+// other components may do work even when nothing references the exports of
+// the module.
+func (c *module) Lazy() bool {
+	return c.f.loader.Lazy()
+}
+
 // Run starts the Module. No components within the Module
 // will be run until Run is called.
 //
@@ -209,6 +225,25 @@ type moduleControllerOptions struct {
 	// the user, for example, via command-line flags.
 	MinStability featuregate.Stability
 
+	// CheckSecrets enables checking secret references before components are
+	// built.
+	CheckSecrets bool
+
+	// LazyComponents enables lazily starting components which have no
+	// consumers.
+	LazyComponents bool
+
+	// ImportLimits limits the content of import sources.
+	ImportLimits controller.ImportLimits
+
+	// MaxExportsSize is the maximum number of values in the exports of a
+	// component.
+	MaxExportsSize int
+
+	// RecordCollectorCallSites records where components register Prometheus
+	// collectors from.
+	RecordCollectorCallSites bool
+
 	// ID is the attached components full ID.
 	ID string
 
@@ -226,4 +261,7 @@ type moduleControllerOptions struct {
 	// WorkerPool is a worker pool that can be used to run tasks asynchronously. A default pool will be created if this
 	// is nil.
 	WorkerPool worker.Pool
+
+	// DryRun evaluates the module without building its components.
+	DryRun bool
 }
@@ -0,0 +1,170 @@
+package flow
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"sort"
+	"strings"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/printer"
+	"github.com/grafana/river/token"
+)
+
+var _ component.ModuleContentProvider = (*Flow)(nil)
+
+// GetModuleContent implements [component.ModuleContentProvider].
+func (f *Flow) GetModuleContent(id string) (*component.ModuleContent, error) {
+	if mod, ok := f.modules.Get(id); ok {
+		return mod.f.getModuleContent(id), nil
+	}
+
+	// id isn't a module, so it must refer to an import block.
+	ctrl, nodeID := f, id
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		mod, ok := f.modules.Get(id[:i])
+		if !ok {
+			return nil, component.ErrModuleNotFound
+		}
+		ctrl, nodeID = mod.f, id[i+1:]
+	}
+	return ctrl.getImportContent(id, nodeID)
+}
+
+// getModuleContent returns the content of the last source applied to f.
+func (f *Flow) getModuleContent(id string) *component.ModuleContent {
+	f.loadMut.RLock()
+	source, lastUpdate := f.loadedSource, f.loadedSourceTime
+	f.loadMut.RUnlock()
+
+	mc := &component.ModuleContent{ID: id, LastUpdate: lastUpdate}
+	if source == nil {
+		return mc
+	}
+
+	raw := source.RawConfigs()
+	mc.SHA256 = source.SHA256()
+	if len(raw) == 0 {
+		// Sources of custom components are loaded from an AST, so their content
+		// is printed back from it.
+		bb := printSource(source)
+		raw = map[string][]byte{id: bb}
+		mc.SHA256 = sha256.Sum256(bb)
+	}
+
+	mc.Files = make(map[string]string, len(raw))
+	for name, bb := range raw {
+		mc.Files[name] = scrubSecrets(name, bb)
+	}
+	return mc
+}
+
+// getImportContent returns the content of the import block of f with the
+// given node ID.
+func (f *Flow) getImportContent(id, nodeID string) (*component.ModuleContent, error) {
+	f.loadMut.RLock()
+	defer f.loadMut.RUnlock()
+
+	for _, node := range f.loader.Imports() {
+		if node.NodeID() != nodeID {
+			continue
+		}
+
+		content := node.ImportedContent()
+		names := make([]string, 0, len(content))
+		for name := range content {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		// The checksum is computed like the one of sources with multiple files.
+		hash := sha256.New()
+		files := make(map[string]string, len(content))
+		for _, name := range names {
+			hash.Write([]byte(content[name]))
+			files[name] = scrubSecrets(name, []byte(content[name]))
+		}
+
+		return &component.ModuleContent{
+			ID:         id,
+			Files:      files,
+			SHA256:     [sha256.Size]byte(hash.Sum(nil)),
+			LastUpdate: node.LastContentUpdate(),
+		}, nil
+	}
+	return nil, component.ErrModuleNotFound
+}
+
+// printSource prints the blocks of source. Blocks are grouped by kind, so
+// their order may differ from the order they were defined in.
+func printSource(source *Source) []byte {
+	var buf bytes.Buffer
+	for _, blocks := range [][]*ast.BlockStmt{source.declareBlocks, source.configBlocks, source.components} {
+		for _, block := range blocks {
+			if buf.Len() > 0 {
+				buf.WriteString("\n\n")
+			}
+			_ = printer.Fprint(&buf, block)
+		}
+	}
+	return buf.Bytes()
+}
+
+// Content which can't be parsed can't be scrubbed, so it's omitted.
+const unparsableContent = "// The content can't be parsed and is omitted.\n"
+
+// scrubSecrets replaces every string literal of bb with "(secret)". The rest
+// of bb is returned unchanged.
+//
+// Content is scrubbed before it's evaluated, so the types of the attributes
+// aren't known yet. Secrets can be assigned to attributes of any name, such
+// as the keys of header maps or the userinfo of URLs, so every string literal
+// is assumed to be a secret. The names of the environment variables read by
+// env are kept, as they aren't secret values themselves.
+func scrubSecrets(name string, bb []byte) string {
+	file, err := parser.ParseFile(name, bb)
+	if err != nil {
+		return unparsableContent
+	}
+
+	literals := make(map[int]*ast.LiteralExpr)
+	ast.Walk(literalVisitor(func(lit *ast.LiteralExpr) {
+		if lit.Kind == token.STRING {
+			literals[lit.ValuePos.Position().Offset] = lit
+		}
+	}), file.Body)
+
+	// Replace the literals back to front so that offsets stay valid.
+	offsets := make([]int, 0, len(literals))
+	for offset := range literals {
+		offsets = append(offsets, offset)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+
+	out := bytes.Clone(bb)
+	for _, start := range offsets {
+		end := start + len(literals[start].Value)
+		if start < 0 || end > len(out) {
+			return unparsableContent
+		}
+		out = append(out[:start], append([]byte(`"(secret)"`), out[end:]...)...)
+	}
+	return string(out)
+}
+
+// literalVisitor calls itself for every literal outside of calls to env.
+type literalVisitor func(lit *ast.LiteralExpr)
+
+func (v literalVisitor) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.CallExpr:
+		if ident, ok := n.Value.(*ast.IdentifierExpr); ok && ident.Ident.Name == "env" {
+			return nil
+		}
+	case *ast.LiteralExpr:
+		v(n)
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package flow
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/rivertypes"
+	"github.com/grafana/river/vm"
+)
+
+// resolveDisabledFuncs are standard library functions which can't be called
+// when resolving expressions, as they would reveal values which the resolved
+// result otherwise redacts.
+var resolveDisabledFuncs = []string{"env", "nonsensitive"}
+
+// resolveSecretFuncs replace standard library functions which accept
+// arguments of any type when resolving expressions. Their results are secrets
+// whenever any of their arguments holds a secret, so that they can't be used
+// to turn a secret into a plain string.
+//
+// The other standard library functions either reject secrets as arguments or
+// return them unmodified.
+var resolveSecretFuncs = map[string]any{
+	"format": func(format string, args ...any) any {
+		s := fmt.Sprintf(format, args...)
+		for _, arg := range args {
+			if containsSecret(reflect.ValueOf(arg)) {
+				return rivertypes.Secret(s)
+			}
+		}
+		return s
+	},
+}
+
+// EvaluateExpression evaluates a River expression against the current exports
+// of the components of f, returning its resolved value. Secrets in the
+// resolved value remain secrets; the env and nonsensitive functions can't be
+// used, and values derived from secrets by other functions are secrets too, so
+// that they can't be revealed.
+func (f *Flow) EvaluateExpression(expr string) (any, error) {
+	node, err := parser.ParseExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	if err := vm.New(node).Evaluate(resolveScope(f.loader.Scope()), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// resolveScope replaces the standard library functions in scope which could
+// reveal secrets.
+func resolveScope(scope *vm.Scope) *vm.Scope {
+	for _, name := range resolveDisabledFuncs {
+		name := name
+		scope.Variables[name] = func(...any) (any, error) {
+			return nil, fmt.Errorf("%s can't be used when resolving expressions", name)
+		}
+	}
+	for name, fn := range resolveSecretFuncs {
+		scope.Variables[name] = fn
+	}
+	return scope
+}
+
+// containsSecret reports whether v is a secret or holds one in one of its
+// elements.
+func containsSecret(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+
+	switch val := v.Interface().(type) {
+	case rivertypes.Secret:
+		return true
+	case rivertypes.OptionalSecret:
+		return val.IsSecret
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		return containsSecret(v.Elem())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if containsSecret(v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if containsSecret(iter.Value()) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() && containsSecret(v.Field(i)) {
+				return true
+			}
+		}
+	}
+	return false
+}
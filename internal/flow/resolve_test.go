@@ -0,0 +1,65 @@
+package flow
+
+import (
+	"testing"
+
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/rivertypes"
+	"github.com/grafana/river/vm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestController_EvaluateExpression(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+	ctrl := New(testOptions(t))
+	defer cleanUpController(ctrl)
+
+	f, err := ParseSource(t.Name(), []byte(testFile))
+	require.NoError(t, err)
+	require.NoError(t, ctrl.LoadSource(f, nil))
+
+	v, err := ctrl.EvaluateExpression(`testcomponents.passthrough.static.output`)
+	require.NoError(t, err)
+	require.Equal(t, "hello, world!", v)
+
+	v, err = ctrl.EvaluateExpression(`concat([testcomponents.passthrough.static.output], ["goodbye"])`)
+	require.NoError(t, err)
+	require.Equal(t, []any{"hello, world!", "goodbye"}, v)
+
+	_, err = ctrl.EvaluateExpression(`env("HOME")`)
+	require.ErrorContains(t, err, "env can't be used when resolving expressions")
+
+	_, err = ctrl.EvaluateExpression(`testcomponents.passthrough.missing.output`)
+	require.Error(t, err)
+
+	_, err = ctrl.EvaluateExpression(`[`)
+	require.Error(t, err)
+}
+
+func TestResolveScope_Secrets(t *testing.T) {
+	tt := []struct {
+		expr   string
+		expect any
+	}{
+		{`format("%s", secret)`, rivertypes.Secret("hunter2")},
+		{`format("%v", [secret])`, rivertypes.Secret("[hunter2]")},
+		{`format("%v", {"password" = secret})`, rivertypes.Secret("map[password:hunter2]")},
+		{`format("%s", plain)`, "hello"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.expr, func(t *testing.T) {
+			node, err := parser.ParseExpression(tc.expr)
+			require.NoError(t, err)
+
+			scope := resolveScope(&vm.Scope{Variables: map[string]any{
+				"secret": rivertypes.Secret("hunter2"),
+				"plain":  "hello",
+			}})
+
+			var v any
+			require.NoError(t, vm.New(node).Evaluate(scope, &v))
+			require.Equal(t, tc.expect, v)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package riverfmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/agent/internal/static/metrics/cluster/configapi"
+)
+
+// APIPath is the path the formatting API is served at. Requests must use
+// POST.
+const APIPath = "/agent/api/v1/fmt"
+
+// maxAPIRequestSize is the largest request accepted by the formatting API.
+const maxAPIRequestSize = 10 << 20
+
+// APIRequest is the request body of the formatting API.
+type APIRequest struct {
+	// Source is the River source to format.
+	Source string `json:"source"`
+
+	// Filename is the name of the file of Source, used in the diff.
+	// Defaults to config.river.
+	Filename string `json:"filename,omitempty"`
+
+	// Lint runs the lint checks on Source, including the validate check if
+	// the handler validates sources.
+	Lint bool `json:"lint,omitempty"`
+}
+
+// APIHandler returns the handler of the formatting API, which is served at
+// APIPath. It formats the source of an APIRequest and responds with a
+// Result. Sources which can't be parsed still respond with a 200 status
+// code, with the parse errors in the diagnostics of the result.
+//
+// If validate is non-nil, it's run as the validate lint check.
+func APIHandler(validate ValidateFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req APIRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestSize)).Decode(&req); err != nil {
+			_ = configapi.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+			return
+		}
+		if req.Filename == "" {
+			req.Filename = "config.river"
+		}
+
+		res, err := Format(req.Filename, []byte(req.Source), Options{Lint: req.Lint, Validate: validate})
+		if err != nil {
+			_ = configapi.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		_ = configapi.WriteResponse(w, http.StatusOK, res)
+	})
+}
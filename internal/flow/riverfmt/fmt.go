@@ -0,0 +1,195 @@
+// Package riverfmt formats River config files canonically, like the fmt command,
+// and checks them for common mistakes.
+package riverfmt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/diag"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/printer"
+	"github.com/grafana/river/token"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// ValidateFunc validates River source the way it's validated when it's
+// loaded, for example by evaluating it with a dry-run controller. Findings
+// are returned as diag.Diagnostics.
+type ValidateFunc func(filename string, src []byte) error
+
+// Options configures Format.
+type Options struct {
+	// Lint runs the lint checks on the source in addition to formatting it.
+	Lint bool
+
+	// Validate, if set, is run as the validate lint check.
+	Validate ValidateFunc
+}
+
+// Result is the result of Format.
+type Result struct {
+	// Formatted is the formatted source. It is empty if the source can't be
+	// parsed.
+	Formatted string `json:"formatted,omitempty"`
+
+	// Changed is true if Formatted differs from the source.
+	Changed bool `json:"changed"`
+
+	// Diff is a unified diff from the source to Formatted, empty unless
+	// Changed is true.
+	Diff string `json:"diff,omitempty"`
+
+	// Diagnostics holds the errors which prevented parsing the source, or the
+	// findings of the lint checks.
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Severity levels of diagnostics.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is an error or a lint finding in River source.
+type Diagnostic struct {
+	// Severity is either SeverityError or SeverityWarning.
+	Severity string `json:"severity"`
+
+	// Check is the name of the lint check which reported the diagnostic. It
+	// is empty for parse errors.
+	Check string `json:"check,omitempty"`
+
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+}
+
+// String returns the diagnostic in the file:line:col: message form.
+func (d Diagnostic) String() string {
+	msg := d.Message
+	if d.Check != "" {
+		msg = fmt.Sprintf("%s (%s)", d.Message, d.Check)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, msg)
+}
+
+// Source formats River source canonically. Errors parsing the source are
+// returned as diag.Diagnostics.
+func Source(filename string, src []byte) ([]byte, error) {
+	f, err := parser.ParseFile(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	return formatFile(f)
+}
+
+func formatFile(f *ast.File) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, f); err != nil {
+		return nil, err
+	}
+
+	// Add a newline at the end of the file.
+	_, _ = buf.Write([]byte{'\n'})
+	return buf.Bytes(), nil
+}
+
+// Format formats River source canonically and, if opts.Lint is set, runs
+// the lint checks on it. Parse errors and validation findings are reported
+// as diagnostics rather than returned; an error is only returned if the
+// source can't be parsed for reasons other than syntax errors, or can't be
+// printed.
+func Format(filename string, src []byte, opts Options) (Result, error) {
+	f, err := parser.ParseFile(filename, src)
+	if err != nil {
+		diags, err := fromError("", err)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Diagnostics: diags}, nil
+	}
+
+	formatted, err := formatFile(f)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{
+		Formatted:   string(formatted),
+		Changed:     !bytes.Equal(src, formatted),
+		Diagnostics: []Diagnostic{},
+	}
+	if res.Changed {
+		res.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(string(src)),
+			B:        difflib.SplitLines(res.Formatted),
+			FromFile: filename,
+			ToFile:   filename + " (formatted)",
+			Context:  3,
+		})
+		if err != nil {
+			return Result{}, err
+		}
+	}
+	if opts.Lint {
+		res.Diagnostics = append(res.Diagnostics, Lint(f)...)
+	}
+	if opts.Lint && opts.Validate != nil {
+		diags, _ := fromError(CheckValidate, opts.Validate(filename, src))
+		res.Diagnostics = append(res.Diagnostics, diags...)
+	}
+	return res, nil
+}
+
+// HasErrors reports whether any diagnostic of r is an error.
+func (r Result) HasErrors() bool {
+	for _, d := range r.Diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// fromError converts the River diagnostics held by err into diagnostics
+// reported by check. Other errors are returned as is, along with a single
+// error diagnostic without a position.
+func fromError(check string, err error) ([]Diagnostic, error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	var diags diag.Diagnostics
+	if !errors.As(err, &diags) {
+		return []Diagnostic{{Severity: SeverityError, Check: check, Message: err.Error()}}, err
+	}
+	res := make([]Diagnostic, 0, len(diags))
+	for _, d := range diags {
+		severity := SeverityError
+		if d.Severity == diag.SeverityLevelWarn {
+			severity = SeverityWarning
+		}
+		res = append(res, Diagnostic{
+			Severity: severity,
+			Check:    check,
+			Message:  d.Message,
+			Line:     d.StartPos.Line,
+			Column:   d.StartPos.Column,
+		})
+	}
+	return res, nil
+}
+
+func newDiagnostic(check string, pos token.Pos, format string, args ...any) Diagnostic {
+	p := pos.Position()
+	return Diagnostic{
+		Severity: SeverityWarning,
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+		Line:     p.Line,
+		Column:   p.Column,
+	}
+}
@@ -0,0 +1,163 @@
+package riverfmt_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/agent/internal/flow/riverfmt"
+	"github.com/grafana/river/diag"
+	"github.com/grafana/river/token"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/grafana/agent/internal/component/module/string" // Register module.string
+)
+
+func TestFormat(t *testing.T) {
+	src := "local.file \"a\" {\nfilename = \"/tmp/a\"\n}\n"
+
+	res, err := riverfmt.Format("config.river", []byte(src), riverfmt.Options{})
+	require.NoError(t, err)
+	require.True(t, res.Changed)
+	require.Equal(t, "local.file \"a\" {\n\tfilename = \"/tmp/a\"\n}\n", res.Formatted)
+	require.Contains(t, res.Diff, "+++ config.river (formatted)")
+	require.Empty(t, res.Diagnostics)
+
+	res, err = riverfmt.Format("config.river", []byte(res.Formatted), riverfmt.Options{})
+	require.NoError(t, err)
+	require.False(t, res.Changed)
+	require.Empty(t, res.Diff)
+}
+
+func TestFormat_ParseError(t *testing.T) {
+	res, err := riverfmt.Format("config.river", []byte("local.file \"a\" {\n"), riverfmt.Options{})
+	require.NoError(t, err)
+	require.Empty(t, res.Formatted)
+	require.NotEmpty(t, res.Diagnostics)
+	require.Equal(t, riverfmt.SeverityError, res.Diagnostics[0].Severity)
+	require.Empty(t, res.Diagnostics[0].Check)
+}
+
+func TestLint(t *testing.T) {
+	src := `declare "used" {
+	argument "referenced" { }
+	argument "unreferenced" { }
+
+	declare "nested" {
+		argument "unreferenced" { }
+	}
+
+	export "out" {
+		value = argument.referenced.value
+	}
+}
+
+declare "unused" { }
+
+used "default" {
+	referenced = "a"
+}
+
+module.string "legacy" {
+	content = ""
+}
+`
+
+	res, err := riverfmt.Format("config.river", []byte(src), riverfmt.Options{Lint: true})
+	require.NoError(t, err)
+
+	var diags []string
+	for _, d := range res.Diagnostics {
+		diags = append(diags, d.String())
+	}
+	require.Equal(t, []string{
+		`5:2: warning: declare "nested" is never used (unused-declare)`,
+		`14:1: warning: declare "unused" is never used (unused-declare)`,
+		`3:2: warning: argument "unreferenced" is never referenced (unreferenced-argument)`,
+		`6:3: warning: argument "unreferenced" is never referenced (unreferenced-argument)`,
+		`20:1: warning: component module.string is deprecated: replaced by import.string (deprecated-component)`,
+	}, diags)
+}
+
+func TestLint_Library(t *testing.T) {
+	src := `declare "a" { }
+
+declare "b" { }
+`
+	res, err := riverfmt.Format("lib.river", []byte(src), riverfmt.Options{Lint: true})
+	require.NoError(t, err)
+	require.Empty(t, res.Diagnostics)
+}
+
+func TestLint_Validate(t *testing.T) {
+	src := "local.file \"a\" {\n\tfilename = \"/tmp/a\"\n}\n"
+
+	validate := func(filename string, bb []byte) error {
+		require.Equal(t, "config.river", filename)
+		require.Equal(t, src, string(bb))
+		return diag.Diagnostics{
+			{Severity: diag.SeverityLevelError, Message: "unrecognized attribute name \"bad\"", StartPos: token.Position{Line: 2, Column: 2}},
+			{Severity: diag.SeverityLevelWarn, Message: "reference is unknown", StartPos: token.Position{Line: 1, Column: 1}},
+		}
+	}
+
+	// The validate check only runs with the lint checks.
+	res, err := riverfmt.Format("config.river", []byte(src), riverfmt.Options{Validate: validate})
+	require.NoError(t, err)
+	require.Empty(t, res.Diagnostics)
+
+	res, err = riverfmt.Format("config.river", []byte(src), riverfmt.Options{Lint: true, Validate: validate})
+	require.NoError(t, err)
+	require.Equal(t, src, res.Formatted, "lint findings must not prevent formatting")
+	require.True(t, res.HasErrors())
+
+	var diags []string
+	for _, d := range res.Diagnostics {
+		diags = append(diags, d.String())
+	}
+	require.Equal(t, []string{
+		`2:2: error: unrecognized attribute name "bad" (validate)`,
+		`1:1: warning: reference is unknown (validate)`,
+	}, diags)
+
+	// Errors which aren't diagnostics are reported without a position.
+	res, err = riverfmt.Format("config.river", []byte(src), riverfmt.Options{Lint: true, Validate: func(string, []byte) error {
+		return errors.New("creating data directory: permission denied")
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []riverfmt.Diagnostic{{
+		Severity: riverfmt.SeverityError,
+		Check:    riverfmt.CheckValidate,
+		Message:  "creating data directory: permission denied",
+	}}, res.Diagnostics)
+}
+
+func TestAPIHandler(t *testing.T) {
+	bb, err := json.Marshal(riverfmt.APIRequest{
+		Source: "declare \"unused\" {}\nlocal.file \"a\" { filename = \"/tmp/a\" }\n",
+		Lint:   true,
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	riverfmt.APIHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, riverfmt.APIPath, strings.NewReader(string(bb))))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp struct {
+		Status string          `json:"status"`
+		Data   riverfmt.Result `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "success", resp.Status)
+	require.True(t, resp.Data.Changed)
+	require.Contains(t, resp.Data.Diff, "--- config.river")
+	require.Len(t, resp.Data.Diagnostics, 1)
+	require.Equal(t, riverfmt.CheckUnusedDeclare, resp.Data.Diagnostics[0].Check)
+
+	rec = httptest.NewRecorder()
+	riverfmt.APIHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, riverfmt.APIPath, strings.NewReader("{")))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
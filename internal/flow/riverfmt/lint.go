@@ -0,0 +1,150 @@
+package riverfmt
+
+import (
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/ast"
+)
+
+// Names of the lint checks.
+const (
+	CheckUnusedDeclare        = "unused-declare"
+	CheckUnreferencedArgument = "unreferenced-argument"
+	CheckDeprecatedComponent  = "deprecated-component"
+	CheckValidate             = "validate"
+)
+
+// Lint runs the lint checks on f and returns their findings as warnings:
+//
+//   - unused-declare reports declare blocks which are never instantiated.
+//     Files only made of declare blocks are modules meant to be imported, and
+//     aren't checked.
+//   - unreferenced-argument reports argument blocks of modules which are
+//     never referenced.
+//   - deprecated-component reports components which are deprecated.
+//
+// The validate check isn't run by Lint, since it needs the ValidateFunc of
+// Options. See Format.
+func Lint(f *ast.File) []Diagnostic {
+	var diags []Diagnostic
+	diags = append(diags, checkUnusedDeclares(f)...)
+	diags = append(diags, checkUnreferencedArguments(f)...)
+	diags = append(diags, checkDeprecatedComponents(f)...)
+	return diags
+}
+
+func checkUnusedDeclares(f *ast.File) []Diagnostic {
+	if onlyDeclares(f.Body) {
+		return nil
+	}
+
+	var (
+		declares []*ast.BlockStmt
+		used     = make(map[string]bool)
+	)
+	walkBlocks(f.Body, func(b *ast.BlockStmt) {
+		if name := b.GetBlockName(); name == "declare" {
+			declares = append(declares, b)
+		} else {
+			used[name] = true
+		}
+	})
+
+	var diags []Diagnostic
+	for _, d := range declares {
+		if !used[d.Label] {
+			diags = append(diags, newDiagnostic(CheckUnusedDeclare, d.NamePos, "declare %q is never used", d.Label))
+		}
+	}
+	return diags
+}
+
+func onlyDeclares(body ast.Body) bool {
+	for _, stmt := range body {
+		if b, ok := stmt.(*ast.BlockStmt); !ok || b.GetBlockName() != "declare" {
+			return false
+		}
+	}
+	return true
+}
+
+// checkUnreferencedArguments checks the arguments of the file, which are
+// set when it's loaded as a module, and of each of its declare blocks.
+func checkUnreferencedArguments(f *ast.File) []Diagnostic {
+	diags := unreferencedArguments(f.Body)
+	walkBlocks(f.Body, func(b *ast.BlockStmt) {
+		if b.GetBlockName() == "declare" {
+			diags = append(diags, unreferencedArguments(b.Body)...)
+		}
+	})
+	return diags
+}
+
+func unreferencedArguments(body ast.Body) []Diagnostic {
+	var args []*ast.BlockStmt
+	for _, stmt := range body {
+		if b, ok := stmt.(*ast.BlockStmt); ok && b.GetBlockName() == "argument" {
+			args = append(args, b)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	refs := &argumentRefs{names: make(map[string]bool)}
+	for _, stmt := range body {
+		ast.Walk(refs, stmt)
+	}
+
+	var diags []Diagnostic
+	for _, arg := range args {
+		if !refs.names[arg.Label] {
+			diags = append(diags, newDiagnostic(CheckUnreferencedArgument, arg.NamePos, "argument %q is never referenced", arg.Label))
+		}
+	}
+	return diags
+}
+
+// argumentRefs collects the names of the arguments referenced by
+// expressions like argument.NAME.value. Nested declare blocks have their own
+// arguments, so they aren't walked.
+type argumentRefs struct {
+	names map[string]bool
+}
+
+func (v *argumentRefs) Visit(node ast.Node) ast.Visitor {
+	switch n := node.(type) {
+	case *ast.BlockStmt:
+		if n.GetBlockName() == "declare" {
+			return nil
+		}
+	case *ast.AccessExpr:
+		if ident, ok := n.Value.(*ast.IdentifierExpr); ok && ident.Ident.Name == "argument" {
+			v.names[n.Name.Name] = true
+		}
+	}
+	return v
+}
+
+func checkDeprecatedComponents(f *ast.File) []Diagnostic {
+	var diags []Diagnostic
+	walkBlocks(f.Body, func(b *ast.BlockStmt) {
+		if b.Label == "" {
+			// Components always have a label.
+			return
+		}
+		if reg, ok := component.Get(b.GetBlockName()); ok && reg.Deprecated != "" {
+			diags = append(diags, newDiagnostic(CheckDeprecatedComponent, b.NamePos, "component %s is deprecated: %s", reg.Name, reg.Deprecated))
+		}
+	})
+	return diags
+}
+
+// walkBlocks calls fn for every block of body, including nested blocks.
+func walkBlocks(body ast.Body, fn func(b *ast.BlockStmt)) {
+	for _, stmt := range body {
+		if b, ok := stmt.(*ast.BlockStmt); ok {
+			fn(b)
+			walkBlocks(b.Body, fn)
+		}
+	}
+}
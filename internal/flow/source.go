@@ -10,6 +10,7 @@ import (
 	"github.com/grafana/river/ast"
 	"github.com/grafana/river/diag"
 	"github.com/grafana/river/parser"
+	"github.com/grafana/river/vm"
 )
 
 // A Source holds the contents of a parsed Flow source
@@ -75,7 +76,7 @@ func sourceFromBody(body ast.Body) (*Source, error) {
 			switch fullName {
 			case "declare":
 				declares = append(declares, stmt)
-			case "logging", "tracing", "argument", "export", "import.file", "import.string", "import.http", "import.git":
+			case "logging", "tracing", "argument", "export", "import.file", "import.string", "import.http", "import.git", "import.s3", "import.kubernetes", "import.registry":
 				configs = append(configs, stmt)
 			default:
 				components = append(components, stmt)
@@ -141,6 +142,61 @@ func ParseSources(sources map[string][]byte) (*Source, error) {
 	return mergedSource, nil
 }
 
+// DecodeBlock evaluates the top-level block with the given name into v. The
+// block is evaluated without access to the exports of any component, so it
+// may only contain constant expressions and standard library calls.
+// DecodeBlock returns false if the block isn't defined.
+func (s *Source) DecodeBlock(name string, v interface{}) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+
+	var found *ast.BlockStmt
+	for _, block := range s.components {
+		if strings.Join(block.Name, ".") != name || block.Label != "" {
+			continue
+		}
+		if found != nil {
+			return false, fmt.Errorf("block %q may only be defined once", name)
+		}
+		found = block
+	}
+	if found == nil {
+		return false, nil
+	}
+
+	if err := vm.New(found.Body).Evaluate(nil, v); err != nil {
+		return true, fmt.Errorf("decoding block %q: %w", name, err)
+	}
+	return true, nil
+}
+
+// ImportFilePaths returns the filename of every top-level import.file block
+// whose filename is a constant expression.
+func (s *Source) ImportFilePaths() []string {
+	if s == nil {
+		return nil
+	}
+
+	var paths []string
+	for _, block := range s.configBlocks {
+		if strings.Join(block.Name, ".") != "import.file" {
+			continue
+		}
+		for _, stmt := range block.Body {
+			attr, ok := stmt.(*ast.AttributeStmt)
+			if !ok || attr.Name.Name != "filename" {
+				continue
+			}
+			var filename string
+			if err := vm.New(attr.Value).Evaluate(nil, &filename); err == nil {
+				paths = append(paths, filename)
+			}
+		}
+	}
+	return paths
+}
+
 // RawConfigs returns the raw source content used to create Source.
 // Do not modify the returned map.
 func (s *Source) RawConfigs() map[string][]byte {
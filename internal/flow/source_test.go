@@ -124,6 +124,60 @@ func TestParseSources_UniqueComponent(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestSource_DecodeBlock(t *testing.T) {
+	type settings struct {
+		Name string `river:"name,attr"`
+	}
+
+	s, err := ParseSource(t.Name(), []byte(`
+		settings {
+			name = "a" + "b"
+		}
+
+		testcomponents.tick "ticker" {
+			frequency = "1s"
+		}
+	`))
+	require.NoError(t, err)
+
+	var out settings
+	found, err := s.DecodeBlock("settings", &out)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "ab", out.Name)
+
+	found, err = s.DecodeBlock("missing", &out)
+	require.NoError(t, err)
+	require.False(t, found)
+
+	s, err = ParseSource(t.Name(), []byte(`
+		settings {
+			name = testcomponents.tick.ticker.tick_time
+		}
+	`))
+	require.NoError(t, err)
+	_, err = s.DecodeBlock("settings", &out)
+	require.Error(t, err)
+}
+
+func TestSource_ImportFilePaths(t *testing.T) {
+	s, err := ParseSource(t.Name(), []byte(`
+		import.file "a" {
+			filename = "/etc/agent/" + "a.river"
+		}
+
+		import.file "b" {
+			filename = testcomponents.tick.ticker.tick_time
+		}
+
+		import.string "c" {
+			content = "declare \"x\" {}"
+		}
+	`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"/etc/agent/a.river"}, s.ImportFilePaths())
+}
+
 func getBlockID(b *ast.BlockStmt) string {
 	var parts []string
 	parts = append(parts, b.Name...)
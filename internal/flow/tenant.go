@@ -0,0 +1,126 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/scanner"
+)
+
+// tenantModulePrefix is prepended to the name of a tenant to form the module
+// ID its components are namespaced under.
+const tenantModulePrefix = "tenant."
+
+// TenantModuleID returns the module ID the components of the named tenant are
+// namespaced under.
+func TenantModuleID(name string) string {
+	return tenantModulePrefix + name
+}
+
+var _ component.TenantProvider = (*Flow)(nil)
+
+// A Tenant is a controller which runs an independent configuration inside
+// the same process as its parent controller. Tenants share the services and
+// the worker pool of their parent but are otherwise isolated: their components are namespaced
+// under the module ID returned by [TenantModuleID], which gives them a
+// separate data directory, distinct component_path metric labels, and a
+// separate listing in the UI. Components in different tenants may have the
+// same name.
+//
+// Like modules, tenants can't define service or logging blocks.
+type Tenant struct {
+	name   string
+	parent *Flow
+	mod    *module
+}
+
+// NewTenant creates a new, unstarted tenant of f. Tenants must be created
+// from the root controller.
+func (f *Flow) NewTenant(name string) (*Tenant, error) {
+	if f.opts.IsModule {
+		return nil, fmt.Errorf("tenants can only be created from the root controller")
+	}
+	if !scanner.IsValidIdentifier(name) {
+		return nil, fmt.Errorf("tenant name %q is not a valid River identifier", name)
+	}
+
+	id := TenantModuleID(name)
+	mod := &module{
+		o: &moduleOptions{ID: id},
+		f: newController(controllerOptions{
+			Options: Options{
+				ControllerID:                id,
+				Logger:                      f.opts.Logger,
+				Tracer:                      f.opts.Tracer,
+				DataPath:                    f.opts.DataPath,
+				MinStability:                f.opts.MinStability,
+				CheckSecrets:                f.opts.CheckSecrets,
+				LazyComponents:              f.opts.LazyComponents,
+				ImportMaxContentSize:        f.opts.ImportMaxContentSize,
+				ImportParseTimeout:          f.opts.ImportParseTimeout,
+				ImportEvaluationConcurrency: f.opts.ImportEvaluationConcurrency,
+				MaxExportsSize:              f.opts.MaxExportsSize,
+				RecordCollectorCallSites:    f.opts.RecordCollectorCallSites,
+				Reg:                         f.opts.Reg,
+				Services:                    f.opts.Services,
+			},
+			IsModule:          true,
+			ModuleRegistry:    f.modules,
+			ComponentRegistry: f.opts.ComponentRegistry,
+			WorkerPool:        f.workerPool,
+			DryRun:            f.opts.DryRun,
+		}),
+	}
+
+	return &Tenant{name: name, parent: f, mod: mod}, nil
+}
+
+// Name returns the name of the tenant.
+func (t *Tenant) Name() string { return t.name }
+
+// LoadSource loads a new configuration into the tenant.
+func (t *Tenant) LoadSource(source *Source) error {
+	return t.mod.f.LoadSource(source, nil)
+}
+
+// Ready returns true once the tenant has completed its initial load.
+func (t *Tenant) Ready() bool { return t.mod.f.Ready() }
+
+// Run runs the tenant until the provided context is canceled. Components of
+// the tenant can be retrieved through the parent controller while the
+// tenant is running.
+func (t *Tenant) Run(ctx context.Context) error {
+	if err := t.parent.modules.Register(t.mod.o.ID, t.mod); err != nil {
+		return err
+	}
+	defer t.parent.modules.Unregister(t.mod.o.ID)
+
+	t.mod.f.Run(ctx)
+	return nil
+}
+
+// TenantNames returns the names of the running tenants of f in sorted
+// order.
+func (f *Flow) TenantNames() []string {
+	var names []string
+	for _, mod := range f.modules.List() {
+		if name, ok := strings.CutPrefix(mod.o.ID, tenantModulePrefix); ok && !strings.Contains(name, "/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TenantModuleIDs implements [component.TenantProvider].
+func (f *Flow) TenantModuleIDs() []string {
+	names := f.TenantNames()
+	ids := make([]string, len(names))
+	for i, name := range names {
+		ids[i] = TenantModuleID(name)
+	}
+	return ids
+}
@@ -0,0 +1,80 @@
+package flow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenants(t *testing.T) {
+	defer verifyNoGoroutineLeaks(t)
+
+	// Both tenants define a component with the same name.
+	config := `
+		testcomponents.tick "ticker" {
+			frequency = "1s"
+		}
+	`
+
+	parse := func() *Source {
+		src, err := ParseSource(t.Name(), []byte(config))
+		require.NoError(t, err)
+		return src
+	}
+
+	ctrl := New(testOptions(t))
+	require.NoError(t, ctrl.LoadSource(parse(), nil))
+
+	tenantA, err := ctrl.NewTenant("a")
+	require.NoError(t, err)
+	require.NoError(t, tenantA.LoadSource(parse()))
+
+	tenantB, err := ctrl.NewTenant("b")
+	require.NoError(t, err)
+	require.NoError(t, tenantB.LoadSource(parse()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{}, 3)
+	for _, run := range []func(context.Context){
+		ctrl.Run,
+		func(ctx context.Context) { _ = tenantA.Run(ctx) },
+		func(ctx context.Context) { _ = tenantB.Run(ctx) },
+	} {
+		go func(run func(context.Context)) {
+			run(ctx)
+			done <- struct{}{}
+		}(run)
+	}
+	defer func() {
+		cancel()
+		for i := 0; i < 3; i++ {
+			<-done
+		}
+	}()
+
+	require.Eventually(t, func() bool {
+		return len(ctrl.TenantNames()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"a", "b"}, ctrl.TenantNames())
+
+	// Components of a tenant are reachable through the parent controller.
+	infos, err := ctrl.ListComponents(TenantModuleID("a"), component.InfoOptions{})
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	require.Equal(t, "tenant.a/testcomponents.tick.ticker", infos[0].ID.String())
+
+	info, err := ctrl.GetComponent(component.ParseID("tenant.b/testcomponents.tick.ticker"), component.InfoOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "testcomponents.tick", info.ComponentName)
+}
+
+func TestTenants_InvalidName(t *testing.T) {
+	ctrl := New(testOptions(t))
+	defer cleanUpController(ctrl)
+
+	_, err := ctrl.NewTenant("team-a")
+	require.EqualError(t, err, `tenant name "team-a" is not a valid River identifier`)
+}
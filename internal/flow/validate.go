@@ -0,0 +1,109 @@
+package flow
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/flow/internal/worker"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/agent/internal/flow/tracing"
+	"github.com/grafana/agent/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Validate parses and evaluates source, including its imports, without
+// applying it to f. Components are not built and services are not updated.
+//
+// The returned error holds the diagnostics of the evaluation, if any.
+func (f *Flow) Validate(source *Source) error {
+	_, err := validateSource(f.opts, source)
+	return err
+}
+
+// ValidateComponents validates source like [Flow.Validate] and returns the
+// components source would run, including their arguments when they could be
+// evaluated. Components are returned even if source has errors, as loading it
+// would still build the components which don't.
+func (f *Flow) ValidateComponents(source *Source) ([]*component.Info, error) {
+	return validateSource(f.opts, source)
+}
+
+// Validate parses and evaluates source, including its imports, without
+// applying it to the tenant. See [Flow.Validate].
+func (t *Tenant) Validate(source *Source) error {
+	_, err := validateSource(t.mod.f.opts, source)
+	return err
+}
+
+// ValidateComponents validates source like [Tenant.Validate] and returns the
+// components source would run. See [Flow.ValidateComponents].
+func (t *Tenant) ValidateComponents(source *Source) ([]*component.Info, error) {
+	return validateSource(t.mod.f.opts, source)
+}
+
+// validateSource loads source into a throwaway dry-run controller created
+// from o. The controller is isolated from the one o was used for: it uses its
+// own data directory, metrics registry, logger, and module registry. It
+// returns the components of the dry-run controller.
+func validateSource(o controllerOptions, source *Source) ([]*component.Info, error) {
+	dataPath, err := os.MkdirTemp("", "agent-validate-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating data directory: %w", err)
+	}
+	defer os.RemoveAll(dataPath)
+
+	logger, err := logging.New(io.Discard, logging.DefaultOptions)
+	if err != nil {
+		return nil, err
+	}
+	tracer, err := tracing.New(tracing.DefaultOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]service.Service, 0, len(o.Services))
+	for _, svc := range o.Services {
+		services = append(services, dryRunService{svc})
+	}
+
+	workerPool := worker.NewDefaultWorkerPool()
+	defer workerPool.Stop()
+
+	opts := o.Options
+	opts.Logger = logger
+	opts.Tracer = tracer
+	opts.Reg = prometheus.NewRegistry()
+	opts.DataPath = dataPath
+	opts.Services = services
+	if opts.OnExportsChange != nil {
+		opts.OnExportsChange = func(map[string]any) {}
+	}
+
+	f := newController(controllerOptions{
+		Options:           opts,
+		ComponentRegistry: o.ComponentRegistry,
+		ModuleRegistry:    newModuleRegistry(),
+		IsModule:          o.IsModule,
+		WorkerPool:        workerPool,
+		DryRun:            true,
+	})
+	defer func() {
+		// The controller is never run, so it's cleaned up the way Run would on
+		// exit. The worker pool is stopped above.
+		f.loader.Cleanup(false)
+		_ = f.sched.Close()
+	}()
+	err = f.LoadSource(source, nil)
+	return component.GetAllComponents(f, component.InfoOptions{GetArguments: true}), err
+}
+
+// dryRunService wraps a service so that configuring it during validation
+// doesn't change the running service.
+type dryRunService struct {
+	service.Service
+}
+
+// Update implements [service.Service] and ignores the new config.
+func (dryRunService) Update(newConfig any) error { return nil }
@@ -0,0 +1,86 @@
+package flow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/grafana/agent/internal/flow/internal/testcomponents"
+	"github.com/grafana/river/diag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestController_Validate(t *testing.T) {
+	ctrl := New(testOptions(t))
+	defer cleanUpController(ctrl)
+
+	t.Run("valid config", func(t *testing.T) {
+		f, err := ParseSource(t.Name(), []byte(testFile))
+		require.NoError(t, err)
+		require.NoError(t, ctrl.Validate(f))
+
+		// The config isn't applied to the controller.
+		require.Empty(t, ctrl.loader.Components())
+		require.False(t, ctrl.Ready())
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		f, err := ParseSource("invalid.river", []byte(`
+			testcomponents.passthrough "static" {
+				input   = "hello, world!"
+				unknown = true
+			}
+		`))
+		require.NoError(t, err)
+
+		err = ctrl.Validate(f)
+		var diags diag.Diagnostics
+		require.True(t, errors.As(err, &diags))
+		require.Len(t, diags, 1)
+		require.Equal(t, "invalid.river", diags[0].StartPos.Filename)
+		require.NotZero(t, diags[0].StartPos.Line)
+		require.Empty(t, ctrl.loader.Components())
+	})
+
+	t.Run("depends on exports", func(t *testing.T) {
+		// The exports of components keep their zero value when validating, so
+		// decoding the empty output fails.
+		f, err := ParseSource(t.Name(), []byte(`
+			testcomponents.passthrough "json" {
+				input = "{\"key\": \"value\"}"
+			}
+
+			testcomponents.passthrough "decoded" {
+				input = json_decode(testcomponents.passthrough.json.output)["key"]
+			}
+		`))
+		require.NoError(t, err)
+
+		err = ctrl.Validate(f)
+		var diags diag.Diagnostics
+		require.True(t, errors.As(err, &diags))
+		require.False(t, diags.HasErrors())
+		require.Len(t, diags, 1)
+		require.Equal(t, diag.SeverityLevelWarn, diags[0].Severity)
+		require.Contains(t, diags[0].Message, "depends on component exports which aren't known when validating")
+	})
+}
+
+func TestController_ValidateComponents(t *testing.T) {
+	ctrl := New(testOptions(t))
+	defer cleanUpController(ctrl)
+
+	f, err := ParseSource(t.Name(), []byte(testFile))
+	require.NoError(t, err)
+
+	infos, err := ctrl.ValidateComponents(f)
+	require.NoError(t, err)
+	require.Len(t, infos, 4)
+	for _, info := range infos {
+		if info.ID.LocalID == "testcomponents.passthrough.static" {
+			require.Equal(t, "hello, world!", info.Arguments.(testcomponents.PassthroughConfig).Input)
+		}
+	}
+
+	// The components aren't built.
+	require.Empty(t, ctrl.loader.Components())
+}
@@ -33,6 +33,7 @@ type clusterOptions struct {
 	AdvertiseInterfaces []string
 	ClusterMaxJoinPeers int
 	ClusterName         string
+	ConfigHash          func() string
 }
 
 func buildClusterService(opts clusterOptions) (*cluster.Service, error) {
@@ -49,6 +50,7 @@ func buildClusterService(opts clusterOptions) (*cluster.Service, error) {
 		RejoinInterval:      opts.RejoinInterval,
 		ClusterMaxJoinPeers: opts.ClusterMaxJoinPeers,
 		ClusterName:         opts.ClusterName,
+		ConfigHash:          opts.ConfigHash,
 	}
 
 	if config.NodeName == "" {
@@ -83,27 +85,36 @@ func buildClusterService(opts clusterOptions) (*cluster.Service, error) {
 		config.AdvertiseAddress = appendDefaultPort(config.AdvertiseAddress, listenPort)
 	}
 
+	discoverPeers, err := buildPeerDiscovery(opts)
+	if err != nil {
+		return nil, err
+	}
+	config.DiscoverPeers = discoverPeers
+
+	return cluster.New(config)
+}
+
+// buildPeerDiscovery returns the function used to discover peers to join, or
+// nil if no peers should be joined.
+func buildPeerDiscovery(opts clusterOptions) (discoverFunc, error) {
+	listenPort := findPort(opts.ListenAddress, 80)
+
 	switch {
 	case len(opts.JoinPeers) > 0 && opts.DiscoverPeers != "":
 		return nil, fmt.Errorf("at most one of join peers and discover peers may be set")
 
 	case len(opts.JoinPeers) > 0:
-		config.DiscoverPeers = newStaticDiscovery(opts.JoinPeers, listenPort)
+		return newStaticDiscovery(opts.JoinPeers, listenPort), nil
 
 	case opts.DiscoverPeers != "":
-		discoverFunc, err := newDynamicDiscovery(config.Log, opts.DiscoverPeers, listenPort)
-		if err != nil {
-			return nil, err
-		}
-		config.DiscoverPeers = discoverFunc
+		return newDynamicDiscovery(opts.Log, opts.DiscoverPeers, listenPort)
 
 	default:
 		// Here, both JoinPeers and DiscoverPeers are empty. This is desirable when
 		// starting a seed node that other nodes connect to, so we don't require
 		// one of the fields to be set.
+		return nil, nil
 	}
-
-	return cluster.New(config)
 }
 
 func useAllInterfaces(interfaces []string) bool {
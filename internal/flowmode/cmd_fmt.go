@@ -1,17 +1,27 @@
 package flowmode
 
 import (
-	"bytes"
-	"errors"
 	"fmt"
 	"io"
 	"os"
 
 	"github.com/spf13/cobra"
 
-	"github.com/grafana/river/diag"
-	"github.com/grafana/river/parser"
-	"github.com/grafana/river/printer"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow"
+	"github.com/grafana/agent/internal/flow/logging"
+	"github.com/grafana/agent/internal/flow/riverfmt"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/service/cluster"
+	httpservice "github.com/grafana/agent/internal/service/http"
+	"github.com/grafana/agent/internal/service/labelstore"
+	moduleregistryservice "github.com/grafana/agent/internal/service/moduleregistry"
+	otel_service "github.com/grafana/agent/internal/service/otel"
+	remotecfgservice "github.com/grafana/agent/internal/service/remotecfg"
+	settingsservice "github.com/grafana/agent/internal/service/settings"
+	uiservice "github.com/grafana/agent/internal/service/ui"
+	updaterservice "github.com/grafana/agent/internal/service/updater"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func fmtCommand() *cobra.Command {
@@ -27,39 +37,30 @@ River configuration file.
 
 If the file argument is not supplied or if the file argument is "-", then fmt will read from stdin.
 
-The -w flag can be used to write the formatted file back to disk. -w can not be provided when fmt is reading from stdin. When -w is not provided, fmt will write the result to stdout.`,
+The -w flag can be used to write the formatted file back to disk. -w can not be provided when fmt is reading from stdin. When -w is not provided, fmt will write the result to stdout.
+
+The --lint flag runs lint checks on the file, reporting unused declare blocks, unreferenced module arguments, deprecated components, and the errors the agent would report when loading the file to stderr. The file is still formatted when checks fail, and fmt exits with status 2 if any check reports a finding.`,
 		Args:         cobra.RangeArgs(0, 1),
 		SilenceUsage: true,
 		Aliases:      []string{"format"},
 
 		RunE: func(_ *cobra.Command, args []string) error {
-			var err error
-
 			if len(args) == 0 {
 				// Read from stdin when there are no args provided.
-				err = f.Run("-")
-			} else {
-				err = f.Run(args[0])
-			}
-
-			var diags diag.Diagnostics
-			if errors.As(err, &diags) {
-				for _, diag := range diags {
-					fmt.Fprintln(os.Stderr, diag)
-				}
-				return fmt.Errorf("encountered errors during formatting")
+				return f.Run("-")
 			}
-
-			return err
+			return f.Run(args[0])
 		},
 	}
 
 	cmd.Flags().BoolVarP(&f.write, "write", "w", f.write, "write result to (source) file instead of stdout")
+	cmd.Flags().BoolVar(&f.lint, "lint", f.lint, "run lint checks on the file")
 	return cmd
 }
 
 type flowFmt struct {
 	write bool
+	lint  bool
 }
 
 func (ff *flowFmt) Run(configFile string) error {
@@ -68,7 +69,7 @@ func (ff *flowFmt) Run(configFile string) error {
 		if ff.write {
 			return fmt.Errorf("cannot use -w with standard input")
 		}
-		return format("<stdin>", nil, os.Stdin, false)
+		return format("<stdin>", nil, os.Stdin, false, ff.lint)
 
 	default:
 		fi, err := os.Stat(configFile)
@@ -84,31 +85,57 @@ func (ff *flowFmt) Run(configFile string) error {
 			return err
 		}
 		defer f.Close()
-		return format(configFile, fi, f, ff.write)
+		return format(configFile, fi, f, ff.write, ff.lint)
 	}
 }
 
-func format(filename string, fi os.FileInfo, r io.Reader, write bool) error {
+func format(filename string, fi os.FileInfo, r io.Reader, write, lint bool) error {
 	bb, err := io.ReadAll(r)
 	if err != nil {
 		return err
 	}
 
-	f, err := parser.ParseFile(filename, bb)
+	opts := riverfmt.Options{Lint: lint}
+	if lint {
+		validate, cleanup, err := newLintValidator()
+		if err != nil {
+			return fmt.Errorf("creating validator: %w", err)
+		}
+		defer cleanup()
+		opts.Validate = validate
+	}
+
+	res, err := riverfmt.Format(filename, bb, opts)
 	if err != nil {
 		return err
 	}
+	// Sources which can't be parsed aren't formatted, and their diagnostics
+	// are the parse errors.
+	if res.Formatted == "" {
+		for _, d := range res.Diagnostics {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", filename, d)
+		}
+		return fmt.Errorf("encountered errors during formatting")
+	}
 
-	var buf bytes.Buffer
-	if err := printer.Fprint(&buf, f); err != nil {
+	if err := writeFormatted(filename, fi, res.Formatted, write); err != nil {
 		return err
 	}
 
-	// Add a newline at the end of the file.
-	_, _ = buf.Write([]byte{'\n'})
+	// Lint findings are reported after formatting, so that the file is
+	// formatted regardless of them.
+	for _, d := range res.Diagnostics {
+		fmt.Fprintf(os.Stderr, "%s:%s\n", filename, d)
+	}
+	if len(res.Diagnostics) > 0 {
+		return exitError{code: lintExitCode, err: fmt.Errorf("found %d lint issues", len(res.Diagnostics))}
+	}
+	return nil
+}
 
+func writeFormatted(filename string, fi os.FileInfo, formatted string, write bool) error {
 	if !write {
-		_, err := io.Copy(os.Stdout, &buf)
+		_, err := io.WriteString(os.Stdout, formatted)
 		return err
 	}
 
@@ -118,6 +145,94 @@ func format(filename string, fi os.FileInfo, r io.Reader, write bool) error {
 	}
 	defer wf.Close()
 
-	_, err = io.Copy(wf, &buf)
+	_, err = io.WriteString(wf, formatted)
 	return err
 }
+
+// lintExitCode is the exit status of fmt when lint checks report findings,
+// which tells them apart from files which can't be formatted.
+const lintExitCode = 2
+
+// exitError is an error which makes the process exit with code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e exitError) Error() string { return e.err.Error() }
+func (e exitError) Unwrap() error { return e.err }
+
+// newLintValidator returns a riverfmt.ValidateFunc which validates sources
+// with a dry-run Flow controller, the way they're validated when they're
+// loaded. Services are created with their defaults in a temporary storage
+// directory, which is removed by cleanup.
+func newLintValidator() (validate riverfmt.ValidateFunc, cleanup func(), err error) {
+	storagePath, err := os.MkdirTemp("", "agent-fmt-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(storagePath)
+		}
+	}()
+
+	l, err := logging.New(io.Discard, logging.DefaultOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	reg := prometheus.NewRegistry()
+
+	clusterService, err := cluster.New(cluster.Options{
+		Log:              l,
+		EnableClustering: false,
+		NodeName:         "fmt",
+		AdvertiseAddress: "127.0.0.1:80",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	remoteCfgService, err := remotecfgservice.New(remotecfgservice.Options{Logger: l, StoragePath: storagePath})
+	if err != nil {
+		return nil, nil, err
+	}
+	moduleRegistryService, err := moduleregistryservice.New(moduleregistryservice.Options{Logger: l, Metrics: reg, StoragePath: storagePath})
+	if err != nil {
+		return nil, nil, err
+	}
+	updaterService, err := updaterservice.New(updaterservice.Options{Logger: l, Metrics: reg, StoragePath: storagePath})
+	if err != nil {
+		return nil, nil, err
+	}
+	otelService := otel_service.New(l)
+	if otelService == nil {
+		return nil, nil, fmt.Errorf("failed to create otel service")
+	}
+
+	f := flow.New(flow.Options{
+		Logger:       l,
+		DataPath:     storagePath,
+		Reg:          reg,
+		MinStability: featuregate.StabilityExperimental,
+		Services: []service.Service{
+			httpservice.New(httpservice.Options{Logger: l}),
+			uiservice.New(uiservice.Options{}),
+			clusterService,
+			otelService,
+			labelstore.New(l, reg),
+			remoteCfgService,
+			updaterService,
+			settingsservice.New(settingsservice.Options{}),
+			moduleRegistryService,
+		},
+	})
+
+	validate = func(filename string, src []byte) error {
+		source, err := flow.ParseSource(filename, src)
+		if err != nil {
+			return err
+		}
+		return f.Validate(source)
+	}
+	return validate, func() { os.RemoveAll(storagePath) }, nil
+}
@@ -2,9 +2,11 @@ package flowmode
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/fs"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -26,13 +28,18 @@ import (
 	"github.com/grafana/agent/internal/flow"
 	"github.com/grafana/agent/internal/flow/logging"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/flow/riverfmt"
 	"github.com/grafana/agent/internal/flow/tracing"
+	"github.com/grafana/agent/internal/sandbox"
 	"github.com/grafana/agent/internal/service"
 	httpservice "github.com/grafana/agent/internal/service/http"
 	"github.com/grafana/agent/internal/service/labelstore"
+	moduleregistryservice "github.com/grafana/agent/internal/service/moduleregistry"
 	otel_service "github.com/grafana/agent/internal/service/otel"
 	remotecfgservice "github.com/grafana/agent/internal/service/remotecfg"
+	settingsservice "github.com/grafana/agent/internal/service/settings"
 	uiservice "github.com/grafana/agent/internal/service/ui"
+	updaterservice "github.com/grafana/agent/internal/service/updater"
 	"github.com/grafana/agent/internal/static/config/instrumentation"
 	"github.com/grafana/agent/internal/usagestats"
 	"github.com/grafana/ckit/advertise"
@@ -40,7 +47,9 @@ import (
 	"github.com/grafana/river/diag"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"go.opentelemetry.io/otel"
+	"go.uber.org/atomic"
 	"golang.org/x/exp/maps"
 
 	// Install Components
@@ -60,6 +69,12 @@ func runCommand() *cobra.Command {
 		clusterAdvInterfaces:  advertise.DefaultInterfaces,
 		ClusterMaxJoinPeers:   5,
 		clusterRejoinInterval: 60 * time.Second,
+		importMaxContentSize:  10 << 20,
+		importParseTimeout:    10 * time.Second,
+		importEvalConcurrency: 1,
+
+		remoteConfigPollFrequency:      time.Minute,
+		remoteConfigFallbackToLastGood: true,
 	}
 
 	cmd := &cobra.Command{
@@ -72,6 +87,10 @@ run must be provided an argument pointing at the River dir/file-path to use. If
 River dir/file-path wasn't specified, can't be loaded, or contains errors, run will exit
 immediately.
 
+Instead of a path, the config can be fetched from a remote HTTP or S3 endpoint
+with --config.remote.url. The remote config is polled and reloaded whenever its
+content changes.
+
 If path is a directory, all *.river files in that directory will be combined
 into a single unit. Subdirectories are not recursively searched for further merging.
 
@@ -91,11 +110,17 @@ If reloading the config dir/file-path fails, Grafana Agent Flow will continue ru
 its last valid state. Components which failed may be be listed as unhealthy,
 depending on the nature of the reload error.
 `,
-		Args:         cobra.ExactArgs(1),
+		Args:         cobra.RangeArgs(0, 1),
 		SilenceUsage: true,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return r.Run(args[0])
+			r.flags = cmd.Flags()
+
+			var configPath string
+			if len(args) > 0 {
+				configPath = args[0]
+			}
+			return r.Run(configPath)
 		},
 	}
 
@@ -128,14 +153,38 @@ depending on the nature of the reload error.
 		StringVar(&r.clusterName, "cluster.name", r.clusterName, "The name of the cluster to join")
 
 	// Config flags
+	cmd.Flags().StringArrayVar(&r.tenantConfigs, "config", r.tenantConfigs, "Config file/directory of an isolated tenant, as [NAME=]PATH. May be repeated")
 	cmd.Flags().StringVar(&r.configFormat, "config.format", r.configFormat, fmt.Sprintf("The format of the source file. Supported formats: %s.", supportedFormatsList()))
 	cmd.Flags().BoolVar(&r.configBypassConversionErrors, "config.bypass-conversion-errors", r.configBypassConversionErrors, "Enable bypassing errors when converting")
 	cmd.Flags().StringVar(&r.configExtraArgs, "config.extra-args", r.configExtraArgs, "Extra arguments from the original format used by the converter. Multiple arguments can be passed by separating them with a space.")
+	cmd.Flags().BoolVar(&r.configCheckSecrets, "config.check-secrets", r.configCheckSecrets, "Check that referenced secrets resolve before building components, reporting all missing secrets at once")
+	cmd.Flags().StringVar(&r.configDecryptionKey, "config.decryption-key", r.configDecryptionKey, "Key to decrypt encrypted config files with, as file:PATH, awskms:[KEY_ID], or age:PATH")
+	cmd.Flags().BoolVar(&r.configLazyComponents, "config.lazy-components", r.configLazyComponents, "Only start exporter and custom components whose exports are referenced, stopping them once they're no longer referenced")
+	cmd.Flags().Int64Var(&r.importMaxContentSize, "config.import-max-content-size", r.importMaxContentSize, "Maximum total size in bytes of the module content returned by an import block")
+	cmd.Flags().DurationVar(&r.importParseTimeout, "config.import-parse-timeout", r.importParseTimeout, "Maximum time to parse a file of module content returned by an import block")
+	cmd.Flags().IntVar(&r.importEvalConcurrency, "config.import-evaluation-concurrency", r.importEvalConcurrency, "Maximum number of nested import blocks of a module evaluated concurrently")
+	cmd.Flags().IntVar(&r.maxExportsSize, "config.max-exports-size", r.maxExportsSize, "Maximum number of values in the exports of a component before they're rejected. 0 disables the limit")
+	cmd.Flags().StringVar(&r.remoteConfigURL, "config.remote.url", r.remoteConfigURL, "URL to fetch the config from instead of the path argument, as http(s)://... or s3://BUCKET/KEY")
+	cmd.Flags().StringToStringVar(&r.remoteConfigHeaders, "config.remote.headers", r.remoteConfigHeaders, "Headers to send when fetching the remote config over HTTP, as NAME=VALUE pairs")
+	cmd.Flags().StringVar(&r.remoteConfigHeadersFile, "config.remote.headers-file", r.remoteConfigHeadersFile, "File holding headers to send when fetching the remote config over HTTP, one \"Name: value\" per line")
+	cmd.Flags().DurationVar(&r.remoteConfigPollFrequency, "config.remote.poll-frequency", r.remoteConfigPollFrequency, "How often to poll the remote config for changes. 0 disables polling")
+	cmd.Flags().BoolVar(&r.remoteConfigFallbackToLastGood, "config.remote.fallback-to-last-good", r.remoteConfigFallbackToLastGood, "Load the last good remote config when the remote config can't be fetched or loaded")
 
 	// Misc flags
 	cmd.Flags().
 		BoolVar(&r.disableReporting, "disable-reporting", r.disableReporting, "Disable reporting of enabled components to Grafana.")
 	cmd.Flags().StringVar(&r.storagePath, "storage.path", r.storagePath, "Base directory where components can store data")
+	cmd.Flags().BoolVar(&r.debugCollectorCallSites, "debug.collector-call-sites", r.debugCollectorCallSites, "Record where components register metrics from, and include it in warnings about metrics left registered by a rebuilt component")
+
+	// Sandbox flags
+	cmd.Flags().
+		BoolVar(&r.sandboxEnabled, "sandbox.enabled", r.sandboxEnabled, "Restrict filesystem access, system calls, and network access after startup (Linux only, requires a binary built without cgo)")
+	cmd.Flags().
+		StringSliceVar(&r.sandboxReadPaths, "sandbox.allow-read-paths", r.sandboxReadPaths, "Extra paths which remain readable when the sandbox is enabled")
+	cmd.Flags().
+		StringSliceVar(&r.sandboxWritePaths, "sandbox.allow-write-paths", r.sandboxWritePaths, "Extra paths which remain writable when the sandbox is enabled")
+	cmd.Flags().
+		IntSliceVar(&r.sandboxBindPorts, "sandbox.allow-bind-ports", r.sandboxBindPorts, "Extra TCP ports which may be bound when the sandbox is enabled")
 	return cmd
 }
 
@@ -159,18 +208,77 @@ type flowRun struct {
 	configFormat                 string
 	configBypassConversionErrors bool
 	configExtraArgs              string
+	configCheckSecrets           bool
+	configLazyComponents         bool
+	importMaxContentSize         int64
+	importParseTimeout           time.Duration
+	importEvalConcurrency        int
+	maxExportsSize               int
+	debugCollectorCallSites      bool
+	configDecryptionKey          string
+	sandboxEnabled               bool
+	sandboxReadPaths             []string
+	sandboxWritePaths            []string
+	sandboxBindPorts             []int
+	tenantConfigs                []string
+
+	remoteConfigURL                string
+	remoteConfigHeaders            map[string]string
+	remoteConfigHeadersFile        string
+	remoteConfigPollFrequency      time.Duration
+	remoteConfigFallbackToLastGood bool
+
+	// flags is used to check which flags were explicitly set, which take
+	// precedence over the agent_settings block.
+	flags *pflag.FlagSet
 }
 
 func (fr *flowRun) Run(configPath string) error {
+	// Path of a binary to restart into once everything has shut down, set by
+	// the updater service.
+	var restartBinary atomic.String
+	defer func() {
+		if path := restartBinary.Load(); path != "" {
+			if err := restartInto(path); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to restart into updated binary: %s\n", err)
+			}
+		}
+	}()
+
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
 	ctx, cancel := interruptContext()
 	defer cancel()
 
-	if configPath == "" {
+	if configPath == "" && fr.remoteConfigURL == "" && len(fr.tenantConfigs) == 0 {
 		return fmt.Errorf("path argument not provided")
 	}
+	if configPath != "" && fr.remoteConfigURL != "" {
+		return fmt.Errorf("the path argument can't be used with --config.remote.url")
+	}
+	tenantConfigs, err := parseTenantConfigs(fr.tenantConfigs)
+	if err != nil {
+		return err
+	}
+	if fr.sandboxEnabled {
+		if err := sandbox.Supported(); err != nil {
+			return fmt.Errorf("--sandbox.enabled can't be used: %w", err)
+		}
+		if _, err := sandboxBindPorts(fr.httpListenAddr, fr.sandboxBindPorts); err != nil {
+			return err
+		}
+	}
+
+	// Settings from the agent_settings block are applied before anything is
+	// created from them. Keep a copy of the settings from flags alone so that
+	// reloaded settings can be applied on top of it.
+	flagRun := *fr
+	initialSettings, err := fr.loadSettings(configPath)
+	if err != nil {
+		return fmt.Errorf("reading %s block: %w", settingsservice.ServiceName, err)
+	}
+	fr.applySettings(initialSettings)
 
 	// Buffer logs until log format has been determined
 	l, err := logging.NewDeferred(os.Stderr)
@@ -211,14 +319,46 @@ func (fr *flowRun) Run(configPath string) error {
 	reg := prometheus.DefaultRegisterer
 	reg.MustRegister(newResourcesCollector(l))
 
+	var configPoller *remoteConfig
+	if fr.remoteConfigURL != "" {
+		configPoller, err = newRemoteConfig(log.With(l, "subsystem", "remote_config"), reg, remoteConfigOptions{
+			URL:                fr.remoteConfigURL,
+			Headers:            fr.remoteConfigHeaders,
+			HeadersFile:        fr.remoteConfigHeadersFile,
+			PollFrequency:      fr.remoteConfigPollFrequency,
+			FallbackToLastGood: fr.remoteConfigFallbackToLastGood,
+			StoragePath:        fr.storagePath,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create the remote config poller: %w", err)
+		}
+	}
+
 	// There's a cyclic dependency between the definition of the Flow controller,
 	// the reload/ready functions, and the HTTP service.
 	//
 	// To work around this, we lazily create variables for the functions the HTTP
 	// service needs and set them after the Flow controller exists.
 	var (
-		reload func() (*flow.Source, error)
-		ready  func() bool
+		reload         func() (*flow.Source, error)
+		validate       func() (*flow.Source, error)
+		validateSource func(*flow.Source) error
+		ready          func() bool
+
+		// Hash of the last successfully loaded config, reported by the
+		// HTTP and cluster services.
+		configHash atomic.String
+
+		// Options of the sandbox once it has been applied. Reloads which need
+		// exceptions the sandbox didn't grant are rejected.
+		sandboxOpts atomic.Pointer[sandbox.Options]
+
+		// reloadMut serializes reloads, which can be triggered concurrently by
+		// the /-/reload endpoint, SIGHUP, and the remote config poller, and
+		// guards the last loaded sources.
+		reloadMut         sync.Mutex
+		lastSource        *flow.Source
+		lastTenantSources = make([]*flow.Source, len(tenantConfigs))
 	)
 
 	clusterService, err := buildClusterService(clusterOptions{
@@ -236,6 +376,7 @@ func (fr *flowRun) Run(configPath string) error {
 		AdvertiseInterfaces: fr.clusterAdvInterfaces,
 		ClusterMaxJoinPeers: fr.ClusterMaxJoinPeers,
 		ClusterName:         fr.clusterName,
+		ConfigHash:          configHash.Load,
 	})
 	if err != nil {
 		return err
@@ -246,8 +387,19 @@ func (fr *flowRun) Run(configPath string) error {
 		Tracer:   t,
 		Gatherer: prometheus.DefaultGatherer,
 
-		ReadyFunc:  func() bool { return ready() },
-		ReloadFunc: func() (*flow.Source, error) { return reload() },
+		ReadyFunc:    func() bool { return ready() },
+		ReloadFunc:   func() (*flow.Source, error) { return reload() },
+		ValidateFunc: func() (*flow.Source, error) { return validate() },
+		ConfigHash:   configHash.Load,
+
+		ConvertHandler: converter.APIHandler(),
+		FmtHandler: riverfmt.APIHandler(func(filename string, src []byte) error {
+			source, err := flow.ParseSource(filename, src)
+			if err != nil {
+				return err
+			}
+			return validateSource(source)
+		}),
 
 		HTTPListenAddr:   fr.httpListenAddr,
 		MemoryListenAddr: fr.inMemoryAddr,
@@ -262,8 +414,51 @@ func (fr *flowRun) Run(configPath string) error {
 		return fmt.Errorf("failed to create the remotecfg service: %w", err)
 	}
 
+	moduleRegistryService, err := moduleregistryservice.New(moduleregistryservice.Options{
+		Logger:      log.With(l, "service", "module_registry"),
+		Metrics:     reg,
+		StoragePath: fr.storagePath,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create the module_registry service: %w", err)
+	}
+
+	updaterService, err := updaterservice.New(updaterservice.Options{
+		Logger:      log.With(l, "service", "updater"),
+		Metrics:     reg,
+		StoragePath: fr.storagePath,
+		Restart: func(path string) {
+			restartBinary.Store(path)
+			cancel()
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create the updater service: %w", err)
+	}
+
+	settingsService := settingsservice.New(settingsservice.Options{
+		Initial: initialSettings,
+		OnUpdate: func(args settingsservice.Arguments) error {
+			updated := flagRun
+			updated.applySettings(args)
+
+			discoverPeers, err := buildPeerDiscovery(clusterOptions{
+				Log:           l,
+				ListenAddress: updated.httpListenAddr,
+				JoinPeers:     splitPeers(updated.clusterJoinAddr, ","),
+				DiscoverPeers: updated.clusterDiscoverPeers,
+			})
+			if err != nil {
+				return err
+			}
+			clusterService.UpdatePeerDiscovery(discoverPeers, updated.ClusterMaxJoinPeers)
+			return nil
+		},
+	})
+
 	uiService := uiservice.New(uiservice.Options{
-		UIPrefix: fr.uiPrefix,
+		UIPrefix:    fr.uiPrefix,
+		EnablePProf: fr.enablePprof,
 	})
 
 	otelService := otel_service.New(l)
@@ -275,11 +470,18 @@ func (fr *flowRun) Run(configPath string) error {
 	agentseed.Init(fr.storagePath, l)
 
 	f := flow.New(flow.Options{
-		Logger:       l,
-		Tracer:       t,
-		DataPath:     fr.storagePath,
-		Reg:          reg,
-		MinStability: fr.minStability,
+		Logger:                      l,
+		Tracer:                      t,
+		DataPath:                    fr.storagePath,
+		Reg:                         reg,
+		MinStability:                fr.minStability,
+		CheckSecrets:                fr.configCheckSecrets,
+		LazyComponents:              fr.configLazyComponents,
+		ImportMaxContentSize:        fr.importMaxContentSize,
+		ImportParseTimeout:          fr.importParseTimeout,
+		ImportEvaluationConcurrency: fr.importEvalConcurrency,
+		MaxExportsSize:              fr.maxExportsSize,
+		RecordCollectorCallSites:    fr.debugCollectorCallSites,
 		Services: []service.Service{
 			httpService,
 			uiService,
@@ -287,24 +489,166 @@ func (fr *flowRun) Run(configPath string) error {
 			otelService,
 			labelService,
 			remoteCfgService,
+			updaterService,
+			settingsService,
+			moduleRegistryService,
 		},
 	})
 
-	ready = f.Ready
+	tenants := make([]*flow.Tenant, 0, len(tenantConfigs))
+	for _, tc := range tenantConfigs {
+		tenant, err := f.NewTenant(tc.Name)
+		if err != nil {
+			return fmt.Errorf("creating tenant from --config %q: %w", tc.Path, err)
+		}
+		tenants = append(tenants, tenant)
+	}
+
+	validateSource = f.Validate
+
+	ready = func() bool {
+		for _, tenant := range tenants {
+			if !tenant.Ready() {
+				return false
+			}
+		}
+		return f.Ready()
+	}
+	// checkSandbox returns an error if the components or the imports of
+	// source need exceptions which weren't granted by the sandbox. The
+	// components are listed by validate, which evaluates source without
+	// building them, so that source is checked before it's applied.
+	checkSandbox := func(source *flow.Source, validate func(*flow.Source) ([]*component.Info, error)) error {
+		applied := sandboxOpts.Load()
+		if applied == nil {
+			return nil
+		}
+		// Errors of source are reported when it's loaded; the components
+		// without errors would still be built.
+		infos, _ := validate(source)
+		missing := applied.Missing(sandboxRequirements(infos, source))
+		if len(missing) == 0 {
+			return nil
+		}
+		return fmt.Errorf("config needs sandbox exceptions which weren't granted at startup, restart to apply them: %s", strings.Join(missing, ", "))
+	}
 	reload = func() (*flow.Source, error) {
-		flowSource, err := loadFlowSource(configPath, fr.configFormat, fr.configBypassConversionErrors, fr.configExtraArgs)
+		reloadMut.Lock()
+		defer reloadMut.Unlock()
+
+		var (
+			flowSource *flow.Source
+			err        error
+		)
+		switch {
+		case configPath != "":
+			flowSource, err = loadFlowSource(configPath, fr.configFormat, fr.configBypassConversionErrors, fr.configExtraArgs, fr.configDecryptionKey)
+		case configPoller != nil:
+			flowSource, err = configPoller.Source(ctx)
+		default:
+			// Only tenants were given; the root controller runs an empty config.
+			flowSource, err = flow.ParseSource("", nil)
+		}
 		defer instrumentation.InstrumentSHA256(flowSource.SHA256())
 		defer instrumentation.InstrumentLoad(err == nil)
 
 		if err != nil {
+			if configPoller != nil {
+				return nil, fmt.Errorf("reading remote config: %w", err)
+			}
 			return nil, fmt.Errorf("reading config path %q: %w", configPath, err)
 		}
+		if err := checkSandbox(flowSource, f.ValidateComponents); err != nil {
+			return flowSource, err
+		}
 		if err := f.LoadSource(flowSource, nil); err != nil {
-			return flowSource, fmt.Errorf("error during the initial grafana/agent load: %w", err)
+			if configPoller == nil {
+				return flowSource, fmt.Errorf("error during the initial grafana/agent load: %w", err)
+			}
+			// Restore the last good remote config, if enabled.
+			fallback, fallbackErr := configPoller.Fallback(err)
+			if fallbackErr != nil {
+				return flowSource, fmt.Errorf("error during the initial grafana/agent load: %w", err)
+			}
+			if fallbackErr := checkSandbox(fallback, f.ValidateComponents); fallbackErr != nil {
+				return flowSource, fmt.Errorf("error during the initial grafana/agent load: %w", errors.Join(err, fallbackErr))
+			}
+			if fallbackErr := f.LoadSource(fallback, nil); fallbackErr != nil {
+				return flowSource, fmt.Errorf("error during the initial grafana/agent load: %w", errors.Join(err, fallbackErr))
+			}
+			flowSource = fallback
+		}
+		lastSource = flowSource
+		if configPoller != nil {
+			configPoller.Loaded()
+		}
+		sum := flowSource.SHA256()
+		configHash.Store(hex.EncodeToString(sum[:]))
+
+		for i, tenant := range tenants {
+			path := tenantConfigs[i].Path
+			tenantSource, err := loadFlowSource(path, fr.configFormat, fr.configBypassConversionErrors, fr.configExtraArgs, fr.configDecryptionKey)
+			if err != nil {
+				return flowSource, fmt.Errorf("reading config path %q of tenant %q: %w", path, tenant.Name(), err)
+			}
+			if err := checkSandbox(tenantSource, tenant.ValidateComponents); err != nil {
+				return flowSource, fmt.Errorf("error loading tenant %q: %w", tenant.Name(), err)
+			}
+			if err := tenant.LoadSource(tenantSource); err != nil {
+				return flowSource, fmt.Errorf("error loading tenant %q: %w", tenant.Name(), err)
+			}
+			lastTenantSources[i] = tenantSource
 		}
 
 		return flowSource, nil
 	}
+	validate = func() (*flow.Source, error) {
+		var (
+			flowSource *flow.Source
+			err        error
+		)
+		switch {
+		case configPath != "":
+			flowSource, err = loadFlowSource(configPath, fr.configFormat, fr.configBypassConversionErrors, fr.configExtraArgs, fr.configDecryptionKey)
+		case configPoller != nil:
+			flowSource, err = configPoller.Peek(ctx)
+		default:
+			flowSource, err = flow.ParseSource("", nil)
+		}
+		if err != nil {
+			if configPoller != nil {
+				return nil, fmt.Errorf("reading remote config: %w", err)
+			}
+			return nil, fmt.Errorf("reading config path %q: %w", configPath, err)
+		}
+		// Warnings don't stop the validation, so that the tenant configs are
+		// validated too.
+		var warnings diag.Diagnostics
+		if err := f.Validate(flowSource); err != nil {
+			diags, ok := warningsOnly(err)
+			if !ok {
+				return flowSource, err
+			}
+			warnings = append(warnings, diags...)
+		}
+
+		for i, tenant := range tenants {
+			path := tenantConfigs[i].Path
+			tenantSource, err := loadFlowSource(path, fr.configFormat, fr.configBypassConversionErrors, fr.configExtraArgs, fr.configDecryptionKey)
+			if err != nil {
+				return flowSource, fmt.Errorf("reading config path %q of tenant %q: %w", path, tenant.Name(), err)
+			}
+			if err := tenant.Validate(tenantSource); err != nil {
+				diags, ok := warningsOnly(err)
+				if !ok {
+					return flowSource, fmt.Errorf("validating tenant %q: %w", tenant.Name(), err)
+				}
+				warnings = append(warnings, diags...)
+			}
+		}
+
+		return flowSource, warnings.ErrorOrNil()
+	}
 
 	// Flow controller
 	{
@@ -313,6 +657,16 @@ func (fr *flowRun) Run(configPath string) error {
 			defer wg.Done()
 			f.Run(ctx)
 		}()
+
+		for _, tenant := range tenants {
+			wg.Add(1)
+			go func(tenant *flow.Tenant) {
+				defer wg.Done()
+				if err := tenant.Run(ctx); err != nil {
+					level.Error(l).Log("msg", "failed to run tenant", "tenant", tenant.Name(), "err", err)
+				}
+			}(tenant)
+		}
 	}
 
 	// Report usage of enabled components
@@ -361,6 +715,41 @@ func (fr *flowRun) Run(configPath string) error {
 		return fmt.Errorf("failed to set clusterer state to Participant after initial load")
 	}
 
+	if fr.sandboxEnabled {
+		var configPaths []string
+		if configPath != "" {
+			configPaths = append(configPaths, configPath)
+		}
+		for _, tc := range tenantConfigs {
+			configPaths = append(configPaths, tc.Path)
+		}
+		// The decryption key is read again when the config is reloaded.
+		if k, err := parseConfigKey(fr.configDecryptionKey); err == nil && k.kind == configKeyFile {
+			configPaths = append(configPaths, k.ref)
+		}
+		reloadMut.Lock()
+		sources := append([]*flow.Source{lastSource}, lastTenantSources...)
+		opts, err := fr.applySandbox(l, f, configPaths, sources)
+		if err == nil {
+			sandboxOpts.Store(&opts)
+		}
+		reloadMut.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to apply sandbox: %w", err)
+		}
+	}
+
+	if configPoller != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			configPoller.Run(ctx, func() error {
+				_, err := reload()
+				return err
+			})
+		}()
+	}
+
 	reloadSignal := make(chan os.Signal, 1)
 	signal.Notify(reloadSignal, syscall.SIGHUP)
 	defer signal.Stop(reloadSignal)
@@ -379,6 +768,59 @@ func (fr *flowRun) Run(configPath string) error {
 	}
 }
 
+// applySandbox restricts the process to the storage path, the config paths,
+// the listen port, and the paths, system calls, and socket families required
+// by the running components and the imports of sources. It returns the
+// applied options.
+func (fr *flowRun) applySandbox(l log.Logger, f *flow.Flow, configPaths []string, sources []*flow.Source) (sandbox.Options, error) {
+	if err := os.MkdirAll(fr.storagePath, 0770); err != nil {
+		return sandbox.Options{}, fmt.Errorf("creating storage path: %w", err)
+	}
+
+	bindPorts, err := sandboxBindPorts(fr.httpListenAddr, fr.sandboxBindPorts)
+	if err != nil {
+		return sandbox.Options{}, err
+	}
+
+	opts := sandbox.Options{
+		Logger:         log.With(l, "subsystem", "sandbox"),
+		ReadPaths:      append(append(configPaths, sandbox.DefaultReadPaths...), fr.sandboxReadPaths...),
+		WritePaths:     append(append([]string{fr.storagePath}, sandbox.DefaultWritePaths...), fr.sandboxWritePaths...),
+		SocketFamilies: sandbox.DefaultSocketFamilies,
+		BindPorts:      bindPorts,
+	}
+	for _, source := range sources {
+		opts.ReadPaths = append(opts.ReadPaths, source.ImportFilePaths()...)
+	}
+	opts = opts.WithExceptions(component.GetAllComponents(f, component.InfoOptions{GetArguments: true}))
+	return opts, sandbox.Apply(opts)
+}
+
+// sandboxRequirements returns the sandbox exceptions needed by components and
+// by the import.file blocks of source.
+func sandboxRequirements(components []*component.Info, source *flow.Source) sandbox.Options {
+	required := sandbox.Options{ReadPaths: source.ImportFilePaths()}
+	return required.WithExceptions(components)
+}
+
+// sandboxBindPorts returns the port of the HTTP listen address followed by
+// the extra ports, which must be valid TCP ports.
+func sandboxBindPorts(listenAddr string, extra []int) ([]uint16, error) {
+	var ports []uint16
+	if _, port, err := net.SplitHostPort(listenAddr); err == nil {
+		if p, err := strconv.ParseUint(port, 10, 16); err == nil {
+			ports = append(ports, uint16(p))
+		}
+	}
+	for _, p := range extra {
+		if p <= 0 || p > 65535 {
+			return nil, fmt.Errorf("invalid port %d in --sandbox.allow-bind-ports", p)
+		}
+		ports = append(ports, uint16(p))
+	}
+	return ports, nil
+}
+
 // getEnabledComponentsFunc returns a function that gets the current enabled components
 func getEnabledComponentsFunc(f *flow.Flow) func() map[string]interface{} {
 	return func() map[string]interface{} {
@@ -391,7 +833,17 @@ func getEnabledComponentsFunc(f *flow.Flow) func() map[string]interface{} {
 	}
 }
 
-func loadFlowSource(path string, converterSourceFormat string, converterBypassErrors bool, configExtraArgs string) (*flow.Source, error) {
+// warningsOnly returns the diagnostics held by err if none of them are
+// errors.
+func warningsOnly(err error) (diag.Diagnostics, bool) {
+	var diags diag.Diagnostics
+	if !errors.As(err, &diags) || diags.HasErrors() {
+		return nil, false
+	}
+	return diags, true
+}
+
+func loadFlowSource(path string, converterSourceFormat string, converterBypassErrors bool, configExtraArgs string, decryptionKey string) (*flow.Source, error) {
 	fi, err := os.Stat(path)
 	if err != nil {
 		return nil, err
@@ -410,12 +862,12 @@ func loadFlowSource(path string, converterSourceFormat string, converterBypassEr
 				}
 				return nil
 			}
-			// Ignore files not ending in .river extension
-			if !strings.HasSuffix(curPath, ".river") {
+			// Ignore files not ending in .river or .river.enc extension
+			if !strings.HasSuffix(curPath, ".river") && !strings.HasSuffix(curPath, ".river.enc") {
 				return nil
 			}
 
-			bb, err := os.ReadFile(curPath)
+			bb, err := readConfigFile(curPath, decryptionKey)
 			sources[curPath] = bb
 			return err
 		})
@@ -426,7 +878,7 @@ func loadFlowSource(path string, converterSourceFormat string, converterBypassEr
 		return flow.ParseSources(sources)
 	}
 
-	bb, err := os.ReadFile(path)
+	bb, err := readConfigFile(path, decryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -450,6 +902,29 @@ func loadFlowSource(path string, converterSourceFormat string, converterBypassEr
 	return flow.ParseSource(path, bb)
 }
 
+// readConfigFile reads the config file at path, decrypting it with the key
+// given by decryptionKey if it's encrypted.
+func readConfigFile(path string, decryptionKey string) ([]byte, error) {
+	bb, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isEncryptedConfig(bb) {
+		// Accepting unencrypted configs would let anyone able to write the
+		// config path bypass the integrity check of the encryption.
+		if decryptionKey != "" {
+			return nil, fmt.Errorf("reading config %s: config isn't encrypted but --config.decryption-key is set", path)
+		}
+		return bb, nil
+	}
+
+	bb, err = decryptConfig(decryptionKey, bb)
+	if err != nil {
+		return nil, fmt.Errorf("reading encrypted config %s: %w", path, err)
+	}
+	return bb, nil
+}
+
 func interruptContext() (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(context.Background())
 
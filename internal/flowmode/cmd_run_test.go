@@ -0,0 +1,20 @@
+package flowmode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandboxBindPorts(t *testing.T) {
+	ports, err := sandboxBindPorts("127.0.0.1:12345", []int{4317, 4318})
+	require.NoError(t, err)
+	require.Equal(t, []uint16{12345, 4317, 4318}, ports)
+
+	ports, err = sandboxBindPorts("not-an-address", nil)
+	require.NoError(t, err)
+	require.Empty(t, ports)
+
+	_, err = sandboxBindPorts("127.0.0.1:12345", []int{70000})
+	require.ErrorContains(t, err, "invalid port 70000")
+}
@@ -16,6 +16,12 @@ func toolsCommand() *cobra.Command {
 
 	cmd.AddCommand(
 		getTools("prometheus.remote_write", remotewrite.InstallTools),
+		resolveCommand(),
+		componentsCommand(),
+		checkEndpointsCommand(),
+		encryptConfigCommand(),
+		walInspectCommand(),
+		positionsCommand(),
 	)
 
 	return cmd
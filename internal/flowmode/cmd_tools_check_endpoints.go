@@ -0,0 +1,419 @@
+package flowmode
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	common_config "github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/component/remote/endpointcheck"
+	"github.com/grafana/river/ast"
+	"github.com/grafana/river/parser"
+	"github.com/grafana/river/token"
+	"github.com/grafana/river/vm"
+	commonconfig "github.com/prometheus/common/config"
+	"github.com/spf13/cobra"
+)
+
+func checkEndpointsCommand() *cobra.Command {
+	c := &flowCheckEndpoints{
+		timeout: 10 * time.Second,
+		out:     os.Stdout,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "check-endpoints [flags] file",
+		Short: "Check that the endpoints of a config are reachable",
+		Long: `The check-endpoints subcommand extracts the outbound endpoints of the
+components and blocks of a config file, such as the endpoint URLs of
+prometheus.remote_write and loki.write, and checks that each endpoint is
+reachable from this host.
+
+Each endpoint is checked in stages: its host is resolved, a TCP connection
+and a TLS handshake are made, and for HTTP endpoints a GET request is sent.
+The reason of the first failed stage is reported for each endpoint.
+
+Endpoints are read from string literals and env() calls, and the HTTP client
+settings of an endpoint, such as its credentials and tls_config block, are
+evaluated without the rest of the config. Endpoints set by other expressions,
+or with client settings which reference other components, are reported as
+skipped. The command exits with a non-zero status when an
+endpoint fails its check.
+
+If the file argument is "-", the config is read from stdin.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return c.Run(args[0])
+		},
+	}
+
+	cmd.Flags().DurationVar(&c.timeout, "timeout", c.timeout, "Timeout for checking each endpoint")
+	return cmd
+}
+
+type flowCheckEndpoints struct {
+	timeout time.Duration
+	out     io.Writer
+}
+
+func (c *flowCheckEndpoints) Run(path string) error {
+	var (
+		bb  []byte
+		err error
+	)
+	if path == "-" {
+		bb, err = io.ReadAll(os.Stdin)
+	} else {
+		bb, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	if isEncryptedConfig(bb) {
+		return fmt.Errorf("config is encrypted and must be decrypted first")
+	}
+
+	endpoints, err := extractEndpoints(path, bb)
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		fmt.Fprintln(c.out, "No endpoints found.")
+		return nil
+	}
+
+	results := make([]endpointcheck.Result, len(endpoints))
+	var wg sync.WaitGroup
+	for i, e := range endpoints {
+		if e.skipReason != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, e configEndpoint) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+			defer cancel()
+			results[i] = endpointcheck.Check(ctx, e.endpoint)
+		}(i, e)
+	}
+	wg.Wait()
+
+	var failed int
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BLOCK\tATTRIBUTE\tADDRESS\tRESULT")
+	for i, e := range endpoints {
+		var result string
+		switch res := results[i]; {
+		case e.skipReason != "":
+			result = "skipped: " + e.skipReason
+		case res.Success():
+			result = fmt.Sprintf("ok (%s)", res.Duration.Round(time.Millisecond))
+			if res.StatusCode != 0 {
+				result = fmt.Sprintf("ok, status %d (%s)", res.StatusCode, res.Duration.Round(time.Millisecond))
+			}
+		default:
+			failed++
+			result = fmt.Sprintf("FAILED at %s: %s", res.FailedStage, res.Err)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.block, e.attribute, e.endpoint.Address, result)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d endpoints failed their check", failed, len(endpoints))
+	}
+	return nil
+}
+
+// endpointAttribute is the path of an attribute holding the address of an
+// outbound endpoint, relative to the block of a component or config block.
+type endpointAttribute struct {
+	path []string // Names of the nested blocks followed by the attribute name.
+	grpc bool     // Whether the address is a host:port pair for a gRPC client.
+
+	// Where the HTTP client settings of the endpoint are set. If squashed is
+	// true, they're set in the block holding the attribute. Otherwise, if
+	// clientBlock is set, they're set in the nested block of that name within
+	// the block holding the attribute. If neither is set, the endpoint is
+	// checked with a default client.
+	squashed    bool
+	clientBlock string
+}
+
+// endpointAttributes lists the attributes holding outbound endpoints by the
+// name of the component or config block they belong to.
+var endpointAttributes = map[string][]endpointAttribute{
+	"prometheus.remote_write":   {{path: []string{"endpoint", "url"}, squashed: true}},
+	"loki.write":                {{path: []string{"endpoint", "url"}, squashed: true}},
+	"pyroscope.write":           {{path: []string{"endpoint", "url"}, squashed: true}},
+	"otelcol.exporter.otlp":     {{path: []string{"client", "endpoint"}, grpc: true}},
+	"otelcol.exporter.otlphttp": {{path: []string{"client", "endpoint"}}},
+	"remote.http":               {{path: []string{"url"}, clientBlock: "client"}},
+	"remote.vault":              {{path: []string{"server"}}},
+	"remote.endpoint_check":     {{path: []string{"endpoint", "address"}, clientBlock: "client"}},
+	"remote.heartbeat":          {{path: []string{"url"}, clientBlock: "client"}},
+	"mimir.rules.kubernetes":    {{path: []string{"address"}, squashed: true}},
+	"loki.rules.kubernetes":     {{path: []string{"address"}, squashed: true}},
+	"discovery.http":            {{path: []string{"url"}, squashed: true}},
+	"module.http":               {{path: []string{"url"}, clientBlock: "client"}},
+	"module.git":                {{path: []string{"repository"}}},
+	"import.http":               {{path: []string{"url"}, clientBlock: "client"}},
+	"import.git":                {{path: []string{"repository"}}},
+	"remotecfg":                 {{path: []string{"url"}, squashed: true}},
+	"updater":                   {{path: []string{"url"}, squashed: true}},
+}
+
+// configEndpoint is an outbound endpoint found in a config.
+type configEndpoint struct {
+	block     string // Name and label of the block the endpoint belongs to.
+	attribute string // Path of the attribute within the block.
+	endpoint  endpointcheck.Endpoint
+
+	// Why the endpoint can't be checked. Empty if it can be checked.
+	skipReason string
+}
+
+// extractEndpoints returns the outbound endpoints of the components and
+// config blocks of the config bb, including those of declare blocks.
+func extractEndpoints(name string, bb []byte) ([]configEndpoint, error) {
+	file, err := parser.ParseFile(name, bb)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []configEndpoint
+	var extract func(body ast.Body)
+	extract = func(body ast.Body) {
+		for _, stmt := range body {
+			block, ok := stmt.(*ast.BlockStmt)
+			if !ok {
+				continue
+			}
+			blockName := strings.Join(block.Name, ".")
+			if blockName == "declare" {
+				extract(block.Body)
+				continue
+			}
+
+			id := blockName
+			if block.Label != "" {
+				id += fmt.Sprintf(" %q", block.Label)
+			}
+			for _, attr := range endpointAttributes[blockName] {
+				endpoints = append(endpoints, extractAttribute(id, strings.Join(attr.path, "."), attr, block.Body)...)
+			}
+		}
+	}
+	extract(file.Body)
+	return endpoints, nil
+}
+
+// extractAttribute returns the endpoints set by attr in body. Blocks of the
+// path of attr may be repeated, such as the endpoint blocks of
+// prometheus.remote_write. attrName is the full path of the attribute within
+// the block id.
+func extractAttribute(id, attrName string, attr endpointAttribute, body ast.Body) []configEndpoint {
+	var endpoints []configEndpoint
+	for _, stmt := range body {
+		switch stmt := stmt.(type) {
+		case *ast.BlockStmt:
+			if len(attr.path) == 1 || strings.Join(stmt.Name, ".") != attr.path[0] {
+				continue
+			}
+			nested := attr
+			nested.path = attr.path[1:]
+			endpoints = append(endpoints, extractAttribute(id, attrName, nested, stmt.Body)...)
+
+		case *ast.AttributeStmt:
+			if len(attr.path) != 1 || stmt.Name.Name != attr.path[0] {
+				continue
+			}
+			endpoints = append(endpoints, buildConfigEndpoint(id, attrName, attr, stmt, body))
+		}
+	}
+	return endpoints
+}
+
+// buildConfigEndpoint builds the endpoint set by stmt. body is the body
+// holding stmt, where the client settings of the endpoint are looked for.
+func buildConfigEndpoint(id, attrName string, attr endpointAttribute, stmt *ast.AttributeStmt, body ast.Body) configEndpoint {
+	ce := configEndpoint{block: id, attribute: attrName}
+
+	address, ok := stringValue(stmt.Value)
+	if !ok {
+		ce.endpoint.Address = "(expression)"
+		ce.skipReason = "the address isn't a string literal or env() call"
+		return ce
+	}
+	ce.endpoint.Address = address
+
+	if attr.grpc {
+		// gRPC clients use TLS unless it's explicitly disabled.
+		insecure, _ := boolValue(findAttribute(body, "tls", "insecure"))
+		ce.endpoint.TLS = !insecure
+		return ce
+	}
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		ce.skipReason = "only HTTP endpoints can be checked"
+		return ce
+	}
+
+	var clientBody ast.Body
+	switch {
+	case attr.squashed:
+		clientBody = body
+	case attr.clientBlock != "":
+		clientBody = findBlock(body, attr.clientBlock)
+	}
+	client, tlsConfig, err := buildHTTPClient(clientBody)
+	if err != nil {
+		ce.skipReason = err.Error()
+		return ce
+	}
+	ce.endpoint.Client = client
+	ce.endpoint.TLSConfig = tlsConfig
+	return ce
+}
+
+// httpClientSettings holds the names of the attributes and blocks of
+// common_config.HTTPClientConfig.
+var httpClientSettings = riverNames(reflect.TypeOf(common_config.HTTPClientConfig{}))
+
+// riverNames returns the names of the attributes and blocks of the struct
+// type t, including those of squashed fields.
+func riverNames(t reflect.Type) map[string]struct{} {
+	names := make(map[string]struct{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("river"), ",")
+		switch {
+		case name != "":
+			names[name] = struct{}{}
+		case opts == "squash":
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			for name := range riverNames(ft) {
+				names[name] = struct{}{}
+			}
+		}
+	}
+	return names
+}
+
+// buildHTTPClient builds an HTTP client and the TLS config of its endpoint
+// from the HTTP client settings set in body. Other statements of body are
+// ignored.
+func buildHTTPClient(body ast.Body) (*http.Client, *tls.Config, error) {
+	var settings ast.Body
+	for _, stmt := range body {
+		var name string
+		switch stmt := stmt.(type) {
+		case *ast.AttributeStmt:
+			name = stmt.Name.Name
+		case *ast.BlockStmt:
+			name = strings.Join(stmt.Name, ".")
+		}
+		if _, ok := httpClientSettings[name]; ok {
+			settings = append(settings, stmt)
+		}
+	}
+
+	// Evaluate validates cfg, which also moves bearer tokens into its
+	// authorization settings.
+	cfg := common_config.DefaultHTTPClientConfig
+	if err := vm.New(settings).Evaluate(nil, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("the HTTP client settings can't be evaluated: %w", err)
+	}
+
+	promCfg := cfg.Convert()
+	client, err := commonconfig.NewClientFromConfig(*promCfg, "agent-tools")
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConfig, err := commonconfig.NewTLSConfig(&promCfg.TLSConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, tlsConfig, nil
+}
+
+// findBlock returns the body of the block named name in body, or nil if it
+// isn't set.
+func findBlock(body ast.Body, name string) ast.Body {
+	for _, stmt := range body {
+		if block, ok := stmt.(*ast.BlockStmt); ok && strings.Join(block.Name, ".") == name {
+			return block.Body
+		}
+	}
+	return nil
+}
+
+// findAttribute returns the value of the attribute at path in body, or nil
+// if it isn't set.
+func findAttribute(body ast.Body, path ...string) ast.Expr {
+	for _, stmt := range body {
+		switch stmt := stmt.(type) {
+		case *ast.BlockStmt:
+			if len(path) > 1 && strings.Join(stmt.Name, ".") == path[0] {
+				if expr := findAttribute(stmt.Body, path[1:]...); expr != nil {
+					return expr
+				}
+			}
+		case *ast.AttributeStmt:
+			if len(path) == 1 && stmt.Name.Name == path[0] {
+				return stmt.Value
+			}
+		}
+	}
+	return nil
+}
+
+// stringValue returns the value of expr if it's a string literal or a call
+// to env() with a string literal.
+func stringValue(expr ast.Expr) (string, bool) {
+	switch expr := expr.(type) {
+	case *ast.LiteralExpr:
+		if expr.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(expr.Value)
+		return s, err == nil
+
+	case *ast.CallExpr:
+		ident, ok := expr.Value.(*ast.IdentifierExpr)
+		if !ok || ident.Ident.Name != "env" || len(expr.Args) != 1 {
+			return "", false
+		}
+		name, ok := stringValue(expr.Args[0])
+		if !ok {
+			return "", false
+		}
+		return os.Getenv(name), true
+	}
+	return "", false
+}
+
+// boolValue returns the value of expr if it's a bool literal.
+func boolValue(expr ast.Expr) (bool, bool) {
+	lit, ok := expr.(*ast.LiteralExpr)
+	if !ok || lit.Kind != token.BOOL {
+		return false, false
+	}
+	return lit.Value == "true", true
+}
@@ -0,0 +1,178 @@
+package flowmode
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/agent/internal/component/remote/endpointcheck"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractEndpoints(t *testing.T) {
+	t.Setenv("TEST_LOKI_URL", "https://logs.example.com/loki/api/v1/push")
+
+	config := `
+prometheus.remote_write "default" {
+	endpoint {
+		url = "https://metrics.example.com/api/prom/push"
+
+		basic_auth {
+			username = "user"
+			password = "pass"
+		}
+	}
+
+	endpoint {
+		url = local.file.url.content
+	}
+}
+
+loki.write "default" {
+	endpoint {
+		url = env("TEST_LOKI_URL")
+	}
+}
+
+otelcol.exporter.otlp "default" {
+	client {
+		endpoint = "traces.example.com:4317"
+	}
+}
+
+declare "nested" {
+	remote.http "config" {
+		url = "http://config.example.com"
+	}
+}
+
+import.git "modules" {
+	repository = "git@github.com:example/modules.git"
+}
+
+prometheus.scrape "default" {
+	targets    = []
+	forward_to = [prometheus.remote_write.default.receiver]
+}
+`
+	endpoints, err := extractEndpoints("config.river", []byte(config))
+	require.NoError(t, err)
+
+	type summary struct {
+		block, attribute, address string
+		tls, client, skipped      bool
+	}
+	var actual []summary
+	for _, e := range endpoints {
+		actual = append(actual, summary{
+			block:     e.block,
+			attribute: e.attribute,
+			address:   e.endpoint.Address,
+			tls:       e.endpoint.TLS,
+			client:    e.endpoint.Client != nil,
+			skipped:   e.skipReason != "",
+		})
+	}
+
+	require.Equal(t, []summary{
+		{block: `prometheus.remote_write "default"`, attribute: "endpoint.url", address: "https://metrics.example.com/api/prom/push", client: true},
+		{block: `prometheus.remote_write "default"`, attribute: "endpoint.url", address: "(expression)", skipped: true},
+		{block: `loki.write "default"`, attribute: "endpoint.url", address: "https://logs.example.com/loki/api/v1/push", client: true},
+		{block: `otelcol.exporter.otlp "default"`, attribute: "client.endpoint", address: "traces.example.com:4317", tls: true},
+		{block: `remote.http "config"`, attribute: "url", address: "http://config.example.com", client: true},
+		{block: `import.git "modules"`, attribute: "repository", address: "git@github.com:example/modules.git", skipped: true},
+	}, actual)
+}
+
+func TestExtractEndpoints_ClientSettings(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret-token"), 0600))
+
+	var authHeaders []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	config := fmt.Sprintf(`
+prometheus.remote_write "token_file" {
+	endpoint {
+		url               = %[1]q
+		bearer_token_file = %[2]q
+	}
+}
+
+remote.http "authorization" {
+	url = %[1]q
+
+	client {
+		authorization {
+			type        = "Bearer"
+			credentials = "other-token"
+		}
+	}
+}
+
+loki.write "tls" {
+	endpoint {
+		url = %[1]q
+
+		tls_config {
+			insecure_skip_verify = true
+		}
+	}
+}
+`, srv.URL, tokenFile)
+	endpoints, err := extractEndpoints("config.river", []byte(config))
+	require.NoError(t, err)
+	require.Len(t, endpoints, 3)
+
+	for _, e := range endpoints[:2] {
+		require.Empty(t, e.skipReason)
+		res := endpointcheck.Check(context.Background(), e.endpoint)
+		require.True(t, res.Success(), "checking %s: %v", e.block, res.Err)
+	}
+	require.Equal(t, []string{"Bearer secret-token", "Bearer other-token"}, authHeaders)
+
+	require.Empty(t, endpoints[2].skipReason)
+	require.NotNil(t, endpoints[2].endpoint.TLSConfig)
+	require.True(t, endpoints[2].endpoint.TLSConfig.InsecureSkipVerify)
+}
+
+func TestExtractEndpoints_InvalidClientSettings(t *testing.T) {
+	config := `
+remote.http "conflicting" {
+	url = "https://example.com"
+
+	client {
+		bearer_token = "token"
+
+		basic_auth {
+			username = "user"
+		}
+	}
+}
+
+prometheus.remote_write "reference" {
+	endpoint {
+		url = "https://example.com"
+
+		oauth2 {
+			client_id     = "id"
+			client_secret = local.file.secret.content
+			token_url     = "https://example.com/token"
+		}
+	}
+}
+`
+	endpoints, err := extractEndpoints("config.river", []byte(config))
+	require.NoError(t, err)
+	require.Len(t, endpoints, 2)
+	require.Contains(t, endpoints[0].skipReason, "at most one of basic_auth, authorization, oauth2, bearer_token & bearer_token_file must be configured")
+	require.Contains(t, endpoints[1].skipReason, "the HTTP client settings can't be evaluated")
+}
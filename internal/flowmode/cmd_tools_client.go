@@ -0,0 +1,145 @@
+package flowmode
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	commonconfig "github.com/prometheus/common/config"
+	"github.com/spf13/pflag"
+)
+
+// agentClient sends requests to the HTTP API of a running agent. It's shared
+// by the tools subcommands which talk to a running agent.
+type agentClient struct {
+	addr     string
+	uiPrefix string
+	timeout  time.Duration
+
+	bearerTokenFile       string
+	basicAuthUsername     string
+	basicAuthPasswordFile string
+	tlsCAFile             string
+	tlsCertFile           string
+	tlsKeyFile            string
+	tlsSkipVerify         bool
+}
+
+func newAgentClient() *agentClient {
+	return &agentClient{
+		addr:     "http://127.0.0.1:12345",
+		uiPrefix: "/",
+		timeout:  10 * time.Second,
+	}
+}
+
+// registerFlags registers the flags of the client to fs.
+func (c *agentClient) registerFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&c.addr, "addr", c.addr, "Address of the HTTP server of the agent")
+	fs.StringVar(&c.uiPrefix, "server.http.ui-path-prefix", c.uiPrefix, "Prefix the HTTP UI of the agent is served at")
+	fs.DurationVar(&c.timeout, "timeout", c.timeout, "Timeout for the request to the agent")
+	fs.StringVar(&c.bearerTokenFile, "bearer-token-file", c.bearerTokenFile, "File containing a bearer token to authenticate to the agent with")
+	fs.StringVar(&c.basicAuthUsername, "basic-auth.username", c.basicAuthUsername, "Username to authenticate to the agent with")
+	fs.StringVar(&c.basicAuthPasswordFile, "basic-auth.password-file", c.basicAuthPasswordFile, "File containing the password to authenticate to the agent with")
+	fs.StringVar(&c.tlsCAFile, "tls.ca-file", c.tlsCAFile, "CA certificate to validate the certificate of the agent with")
+	fs.StringVar(&c.tlsCertFile, "tls.cert-file", c.tlsCertFile, "Client certificate to authenticate to the agent with")
+	fs.StringVar(&c.tlsKeyFile, "tls.key-file", c.tlsKeyFile, "Key of the client certificate")
+	fs.BoolVar(&c.tlsSkipVerify, "tls.insecure-skip-verify", c.tlsSkipVerify, "Skip validating the certificate of the agent")
+}
+
+func (c *agentClient) httpClient() (*http.Client, error) {
+	cfg := commonconfig.DefaultHTTPClientConfig
+	cfg.TLSConfig = commonconfig.TLSConfig{
+		CAFile:             c.tlsCAFile,
+		CertFile:           c.tlsCertFile,
+		KeyFile:            c.tlsKeyFile,
+		InsecureSkipVerify: c.tlsSkipVerify,
+	}
+	switch {
+	case c.bearerTokenFile != "" && c.basicAuthUsername != "":
+		return nil, fmt.Errorf("at most one of --bearer-token-file and --basic-auth.username can be set")
+	case c.bearerTokenFile != "":
+		cfg.Authorization = &commonconfig.Authorization{Type: "Bearer", CredentialsFile: c.bearerTokenFile}
+	case c.basicAuthUsername != "":
+		cfg.BasicAuth = &commonconfig.BasicAuth{Username: c.basicAuthUsername, PasswordFile: c.basicAuthPasswordFile}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	client, err := commonconfig.NewClientFromConfig(cfg, "agent-tools")
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = c.timeout
+	return client, nil
+}
+
+// do sends a request to the endpoint of the web API at apiPath and returns
+// the body of the response. Responses without a 200 status code are returned
+// as errors.
+func (c *agentClient) do(method, apiPath, contentType string, body io.Reader) ([]byte, error) {
+	u, err := url.Parse(c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", c.addr, err)
+	}
+	u.Path = path.Join(u.Path, c.uiPrefix, "/api/v0/web", apiPath)
+
+	client, err := c.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bb, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &apiError{statusCode: resp.StatusCode, message: strings.TrimSpace(string(bb))}
+	}
+	return bb, nil
+}
+
+// apiError is returned for responses of the web API without a 200 status
+// code.
+type apiError struct {
+	statusCode int
+	message    string
+}
+
+func (err *apiError) Error() string {
+	if err.message == "" {
+		return http.StatusText(err.statusCode)
+	}
+	return err.message
+}
+
+// readArgument returns arg, or the content of stdin if arg is "-".
+func readArgument(arg string) (string, error) {
+	if arg != "-" {
+		return arg, nil
+	}
+	bb, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	return string(bb), nil
+}
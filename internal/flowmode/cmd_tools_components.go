@@ -0,0 +1,540 @@
+package flowmode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func componentsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "components",
+		Short: "Inspect the components of a running agent",
+		Long: `The components subcommands list the components of a running agent and
+print their health, arguments, and exports.
+
+River values are printed as plain JSON values. The --field flag selects
+fields of the output with a jq-style path such as .exports.targets[0], and
+prints each selected value on its own line. Strings are printed unquoted.`,
+	}
+
+	cmd.AddCommand(
+		componentsListCommand(),
+		componentsGetCommand(),
+	)
+	return cmd
+}
+
+func componentsListCommand() *cobra.Command {
+	c := &flowComponents{client: newAgentClient(), out: os.Stdout}
+
+	cmd := &cobra.Command{
+		Use:   "list [flags]",
+		Short: "List the components of a running agent",
+		Long: `The list subcommand lists the components of a module of a running agent,
+with their health. The components of the root module are listed unless
+--module is set.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return c.List()
+		},
+	}
+
+	c.registerFlags(cmd)
+	cmd.Flags().StringVar(&c.moduleID, "module", c.moduleID, "ID of the module to list the components of")
+	return cmd
+}
+
+func componentsGetCommand() *cobra.Command {
+	c := &flowComponents{client: newAgentClient(), out: os.Stdout}
+
+	cmd := &cobra.Command{
+		Use:   "get [flags] id",
+		Short: "Print a component of a running agent",
+		Long: `The get subcommand prints the health, arguments, exports, and debug
+information of a component of a running agent.
+
+Components of modules are identified by the ID of their module and their ID
+within the module, separated by a slash.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return c.Get(args[0])
+		},
+	}
+
+	c.registerFlags(cmd)
+	return cmd
+}
+
+// Output formats of the components subcommands.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type flowComponents struct {
+	client   *agentClient
+	out      io.Writer
+	output   string
+	field    string
+	moduleID string
+}
+
+func (c *flowComponents) registerFlags(cmd *cobra.Command) {
+	c.output = outputTable
+	c.client.registerFlags(cmd.Flags())
+	cmd.Flags().StringVarP(&c.output, "output", "o", c.output, fmt.Sprintf("Output format, %q or %q", outputTable, outputJSON))
+	cmd.Flags().StringVarP(&c.field, "field", "f", c.field, "jq-style path of the fields to print, such as .exports.targets")
+}
+
+// componentJSON is a component returned by the web API, with its River
+// values converted to plain JSON values.
+type componentJSON struct {
+	Name         string   `json:"name"`
+	LocalID      string   `json:"localID"`
+	ModuleID     string   `json:"moduleID"`
+	Label        string   `json:"label,omitempty"`
+	References   []string `json:"referencesTo"`
+	ReferencedBy []string `json:"referencedBy"`
+	Health       struct {
+		State       string `json:"state"`
+		Message     string `json:"message"`
+		UpdatedTime string `json:"updatedTime"`
+	} `json:"health"`
+	Arguments riverBody `json:"arguments,omitempty"`
+	Exports   riverBody `json:"exports,omitempty"`
+	DebugInfo riverBody `json:"debugInfo,omitempty"`
+}
+
+// id returns the ID of the component, prefixed by its module ID.
+func (cj *componentJSON) id() string {
+	if cj.ModuleID == "" {
+		return cj.LocalID
+	}
+	return cj.ModuleID + "/" + cj.LocalID
+}
+
+func (c *flowComponents) List() error {
+	apiPath := "/components"
+	if c.moduleID != "" {
+		apiPath = path.Join("/modules", c.moduleID, "components")
+	}
+	bb, err := c.client.do(http.MethodGet, apiPath, "", nil)
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.statusCode == http.StatusNotFound {
+			return fmt.Errorf("module %q not found", c.moduleID)
+		}
+		return fmt.Errorf("failed to list components: %w", err)
+	}
+
+	var components []*componentJSON
+	if err := json.Unmarshal(bb, &components); err != nil {
+		return fmt.Errorf("decoding components: %w", err)
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i].id() < components[j].id() })
+
+	if c.field != "" || c.output == outputJSON {
+		return c.printJSON(components)
+	}
+	if c.output != outputTable {
+		return fmt.Errorf("unsupported output format %q", c.output)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tHEALTH\tMESSAGE")
+	for _, cj := range components {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", cj.id(), cj.Name, cj.Health.State, cj.Health.Message)
+	}
+	return w.Flush()
+}
+
+func (c *flowComponents) Get(id string) error {
+	bb, err := c.client.do(http.MethodGet, path.Join("/components", id), "", nil)
+	if err != nil {
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.statusCode == http.StatusNotFound {
+			return fmt.Errorf("component %q not found", id)
+		}
+		return fmt.Errorf("failed to get component: %w", err)
+	}
+
+	var cj componentJSON
+	if err := json.Unmarshal(bb, &cj); err != nil {
+		return fmt.Errorf("decoding component: %w", err)
+	}
+
+	if c.field != "" || c.output == outputJSON {
+		return c.printJSON(&cj)
+	}
+	if c.output != outputTable {
+		return fmt.Errorf("unsupported output format %q", c.output)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", cj.id())
+	fmt.Fprintf(w, "Name:\t%s\n", cj.Name)
+	fmt.Fprintf(w, "Health:\t%s\n", cj.Health.State)
+	fmt.Fprintf(w, "Message:\t%s\n", cj.Health.Message)
+	fmt.Fprintf(w, "Updated:\t%s\n", cj.Health.UpdatedTime)
+	fmt.Fprintf(w, "References:\t%s\n", strings.Join(cj.References, ", "))
+	fmt.Fprintf(w, "Referenced by:\t%s\n", strings.Join(cj.ReferencedBy, ", "))
+	for _, section := range []struct {
+		name string
+		body riverBody
+	}{
+		{"Arguments", cj.Arguments},
+		{"Exports", cj.Exports},
+		{"Debug info", cj.DebugInfo},
+	} {
+		if len(section.body) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:\t\n", section.name)
+		for _, key := range sortedKeys(section.body) {
+			value, err := json.Marshal(section.body[key])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "  %s\t%s\n", key, value)
+		}
+	}
+	return w.Flush()
+}
+
+// printJSON prints v as indented JSON, or the fields of v selected by
+// c.field.
+func (c *flowComponents) printJSON(v any) error {
+	if c.field == "" {
+		bb, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(c.out, string(bb))
+		return err
+	}
+
+	// Selecting fields works on the generic representation of v.
+	bb, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var generic any
+	if err := json.Unmarshal(bb, &generic); err != nil {
+		return err
+	}
+
+	selected, err := selectField(generic, c.field)
+	if err != nil {
+		return err
+	}
+	for _, s := range selected {
+		if str, ok := s.(string); ok {
+			fmt.Fprintln(c.out, str)
+			continue
+		}
+		bb, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out, string(bb))
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// riverBody is a River body encoded by the web API, decoded to plain JSON
+// values. Attributes and blocks are keyed by their name; labeled blocks are
+// keyed by their name and label, and repeated blocks are decoded to lists.
+type riverBody map[string]any
+
+func (rb *riverBody) UnmarshalJSON(bb []byte) error {
+	var stmts []riverStmt
+	if err := json.Unmarshal(bb, &stmts); err != nil {
+		return err
+	}
+	body, err := decodeRiverBody(stmts)
+	if err != nil {
+		return err
+	}
+	*rb = body
+	return nil
+}
+
+// riverStmt is an attribute or a block of a River body encoded as JSON.
+type riverStmt struct {
+	Name  string          `json:"name"`
+	Type  string          `json:"type"`
+	Label string          `json:"label,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Body  []riverStmt     `json:"body,omitempty"`
+}
+
+// riverValue is a River value encoded as JSON.
+type riverValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+func decodeRiverBody(stmts []riverStmt) (map[string]any, error) {
+	body := make(map[string]any, len(stmts))
+	for _, stmt := range stmts {
+		switch stmt.Type {
+		case "attr":
+			v, err := decodeRiverValue(stmt.Value)
+			if err != nil {
+				return nil, fmt.Errorf("decoding %s: %w", stmt.Name, err)
+			}
+			body[stmt.Name] = v
+
+		case "block":
+			block, err := decodeRiverBody(stmt.Body)
+			if err != nil {
+				return nil, err
+			}
+			key := stmt.Name
+			if stmt.Label != "" {
+				key += "." + stmt.Label
+			}
+			switch existing := body[key].(type) {
+			case nil:
+				body[key] = block
+			case []any:
+				body[key] = append(existing, block)
+			default:
+				body[key] = []any{existing, block}
+			}
+
+		default:
+			return nil, fmt.Errorf("unexpected statement type %q", stmt.Type)
+		}
+	}
+	return body, nil
+}
+
+func decodeRiverValue(raw json.RawMessage) (any, error) {
+	var rv riverValue
+	if err := json.Unmarshal(raw, &rv); err != nil {
+		return nil, err
+	}
+
+	switch rv.Type {
+	case "array":
+		var elems []json.RawMessage
+		if err := json.Unmarshal(rv.Value, &elems); err != nil {
+			return nil, err
+		}
+		res := make([]any, 0, len(elems))
+		for _, elem := range elems {
+			v, err := decodeRiverValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, v)
+		}
+		return res, nil
+
+	case "object":
+		var fields []struct {
+			Key   string          `json:"key"`
+			Value json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal(rv.Value, &fields); err != nil {
+			return nil, err
+		}
+		res := make(map[string]any, len(fields))
+		for _, field := range fields {
+			v, err := decodeRiverValue(field.Value)
+			if err != nil {
+				return nil, err
+			}
+			res[field.Key] = v
+		}
+		return res, nil
+
+	default:
+		// Strings, numbers, bools, null, and the string representation of
+		// capsules and functions.
+		var v any
+		if len(rv.Value) == 0 {
+			return nil, nil
+		}
+		if err := json.Unmarshal(rv.Value, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// selectField selects the values of v at the jq-style path expr. The path is
+// made of the following steps:
+//
+//   - .name or ["name"] selects a field of an object.
+//   - [N] selects an element of an array.
+//   - [] selects every element of an array, or every field of an object.
+//
+// The path "." selects v itself. Missing fields and elements select null.
+func selectField(v any, expr string) ([]any, error) {
+	if !strings.HasPrefix(expr, ".") && !strings.HasPrefix(expr, "[") {
+		return nil, fmt.Errorf("invalid field %q: must start with . or [", expr)
+	}
+
+	values := []any{v}
+	rest := expr
+	if rest == "." {
+		rest = ""
+	}
+	for rest != "" {
+		var (
+			step func(any) ([]any, error)
+			err  error
+		)
+		step, rest, err = parseFieldStep(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", expr, err)
+		}
+
+		var next []any
+		for _, v := range values {
+			selected, err := step(v)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", expr, err)
+			}
+			next = append(next, selected...)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// parseFieldStep parses the first step of a field path and returns the
+// remaining path.
+func parseFieldStep(expr string) (func(any) ([]any, error), string, error) {
+	if strings.HasPrefix(expr, ".") {
+		expr = expr[1:]
+		if strings.HasPrefix(expr, "[") {
+			// .[N] is the same as [N].
+			return parseFieldStep(expr)
+		}
+		end := strings.IndexAny(expr, ".[")
+		if end < 0 {
+			end = len(expr)
+		}
+		name := expr[:end]
+		if name == "" {
+			return nil, "", fmt.Errorf("empty field name")
+		}
+		return selectKey(name), expr[end:], nil
+	}
+
+	// expr starts with [.
+	end := strings.Index(expr, "]")
+	if end < 0 {
+		return nil, "", fmt.Errorf("missing ]")
+	}
+	inner, rest := expr[1:end], expr[end+1:]
+
+	switch {
+	case inner == "":
+		return selectAll, rest, nil
+	case strings.HasPrefix(inner, `"`):
+		name, err := strconv.Unquote(inner)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid field name %s", inner)
+		}
+		return selectKey(name), rest, nil
+	default:
+		index, err := strconv.Atoi(inner)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid index %q", inner)
+		}
+		return selectIndex(index), rest, nil
+	}
+}
+
+func selectKey(name string) func(any) ([]any, error) {
+	return func(v any) ([]any, error) {
+		switch v := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case map[string]any:
+			return []any{v[name]}, nil
+		default:
+			return nil, fmt.Errorf("cannot select field %q of %s", name, jsonTypeName(v))
+		}
+	}
+}
+
+func selectIndex(index int) func(any) ([]any, error) {
+	return func(v any) ([]any, error) {
+		switch v := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case []any:
+			i := index
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return []any{nil}, nil
+			}
+			return []any{v[i]}, nil
+		default:
+			return nil, fmt.Errorf("cannot index %s", jsonTypeName(v))
+		}
+	}
+}
+
+func selectAll(v any) ([]any, error) {
+	switch v := v.(type) {
+	case []any:
+		return v, nil
+	case map[string]any:
+		res := make([]any, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			res = append(res, v[k])
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("cannot iterate over %s", jsonTypeName(v))
+	}
+}
+
+func jsonTypeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
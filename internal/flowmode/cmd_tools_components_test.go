@@ -0,0 +1,104 @@
+package flowmode
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testComponentsJSON = `[
+	{
+		"name": "prometheus.scrape",
+		"localID": "prometheus.scrape.default",
+		"moduleID": "",
+		"referencesTo": ["discovery.kubernetes.pods"],
+		"referencedBy": [],
+		"health": {"state": "healthy", "message": "started scrape manager", "updatedTime": "2024-01-01T00:00:00Z"}
+	},
+	{
+		"name": "discovery.kubernetes",
+		"localID": "discovery.kubernetes.pods",
+		"moduleID": "",
+		"referencesTo": [],
+		"referencedBy": ["prometheus.scrape.default"],
+		"health": {"state": "unhealthy", "message": "failed to list pods", "updatedTime": "2024-01-01T00:00:00Z"},
+		"exports": [
+			{"name": "targets", "type": "attr", "value": {"type": "array", "value": [
+				{"type": "object", "value": [{"key": "__address__", "value": {"type": "string", "value": "10.0.0.1:80"}}]},
+				{"type": "object", "value": [{"key": "__address__", "value": {"type": "string", "value": "10.0.0.2:80"}}]}
+			]}}
+		]
+	}
+]`
+
+func newTestComponents(t *testing.T) (*flowComponents, *bytes.Buffer) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v0/web/components" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testComponentsJSON))
+	}))
+	t.Cleanup(srv.Close)
+
+	var out bytes.Buffer
+	c := &flowComponents{client: newAgentClient(), out: &out, output: outputTable}
+	c.client.addr = srv.URL
+	return c, &out
+}
+
+func TestComponentsList(t *testing.T) {
+	c, out := newTestComponents(t)
+	require.NoError(t, c.List())
+	require.Equal(t, `ID                         NAME                  HEALTH     MESSAGE
+discovery.kubernetes.pods  discovery.kubernetes  unhealthy  failed to list pods
+prometheus.scrape.default  prometheus.scrape     healthy    started scrape manager
+`, out.String())
+
+	out.Reset()
+	c.field = ".[].exports.targets[].__address__"
+	require.NoError(t, c.List())
+	require.Equal(t, "10.0.0.1:80\n10.0.0.2:80\n", out.String())
+
+	c.moduleID = "missing"
+	require.EqualError(t, c.List(), `module "missing" not found`)
+}
+
+func TestSelectField(t *testing.T) {
+	v := map[string]any{
+		"health": map[string]any{"state": "healthy"},
+		"list":   []any{1.0, 2.0, 3.0},
+		"a.b":    "dotted",
+	}
+
+	tt := []struct {
+		field  string
+		expect []any
+		err    string
+	}{
+		{field: ".", expect: []any{v}},
+		{field: ".health.state", expect: []any{"healthy"}},
+		{field: ".list[1]", expect: []any{2.0}},
+		{field: ".list.[-1]", expect: []any{3.0}},
+		{field: ".list[]", expect: []any{1.0, 2.0, 3.0}},
+		{field: `.["a.b"]`, expect: []any{"dotted"}},
+		{field: ".missing.field", expect: []any{nil}},
+		{field: "health", err: `invalid field "health": must start with . or [`},
+		{field: ".list[", err: `invalid field ".list[": missing ]`},
+		{field: ".list.x", err: `field ".list.x": cannot select field "x" of array`},
+	}
+	for _, tc := range tt {
+		t.Run(tc.field, func(t *testing.T) {
+			actual, err := selectField(v, tc.field)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, actual)
+		})
+	}
+}
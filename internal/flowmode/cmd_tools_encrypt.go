@@ -0,0 +1,63 @@
+package flowmode
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func encryptConfigCommand() *cobra.Command {
+	e := &flowEncryptConfig{}
+
+	cmd := &cobra.Command{
+		Use:   "encrypt-config [flags] file",
+		Short: "Encrypt a config file",
+		Long: `The encrypt-config subcommand encrypts the specified config file and
+writes the encrypted config to stdout. Encrypted configs are decrypted in
+memory by the run command when --config.decryption-key is set.
+
+If the file argument is "-", the config is read from stdin.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return e.Run(args[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&e.key, "key", e.key, "Key to encrypt the config with, as file:PATH, awskms:KEY_ID, or age:PATH")
+	return cmd
+}
+
+type flowEncryptConfig struct {
+	key string
+}
+
+func (e *flowEncryptConfig) Run(path string) error {
+	k, err := parseConfigKey(e.key)
+	if err != nil {
+		return err
+	}
+
+	var bb []byte
+	if path == "-" {
+		bb, err = io.ReadAll(os.Stdin)
+	} else {
+		bb, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+	if isEncryptedConfig(bb) {
+		return fmt.Errorf("config is already encrypted")
+	}
+
+	encrypted, err := encryptConfig(k, bb)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(encrypted)
+	return err
+}
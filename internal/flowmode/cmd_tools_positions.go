@@ -0,0 +1,392 @@
+package flowmode
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grafana/agent/internal/component/common/loki/positions"
+	"github.com/spf13/cobra"
+)
+
+func positionsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "positions",
+		Short: "Inspect and edit the positions files of log sources",
+		Long: `The positions subcommands list, validate, and reset the positions stored
+by loki.source.file, loki.source.journal, and the other log sources which
+keep a positions file, without editing the file by hand.
+
+The file argument is a positions file, or the data directory of a component
+containing a positions.yml file.
+
+Offsets of loki.source.kafka are committed to Kafka by its consumer group
+rather than stored in a positions file. They're reset with the kafka-reset
+subcommand instead.`,
+	}
+
+	cmd.AddCommand(
+		positionsListCommand(),
+		positionsValidateCommand(),
+		positionsResetCommand(),
+		positionsKafkaResetCommand(),
+	)
+	return cmd
+}
+
+func positionsListCommand() *cobra.Command {
+	c := newFlowPositions()
+
+	return &cobra.Command{
+		Use:   "list file",
+		Short: "List the positions of a positions file",
+		Long: `The list subcommand lists the entries of a positions file with their
+position and status. The position of files is their byte offset, and the
+position of journals is their cursor.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return c.List(args[0])
+		},
+	}
+}
+
+func positionsValidateCommand() *cobra.Command {
+	c := newFlowPositions()
+
+	return &cobra.Command{
+		Use:   "validate file",
+		Short: "Validate a positions file",
+		Long: `The validate subcommand lists the entries of a positions file like the
+list subcommand, and exits with a non-zero status if the file can't be
+parsed or an entry has an invalid position.
+
+Entries of files which no longer exist, or whose offset is past the end of
+the file, are reported but aren't invalid: the former are removed by the
+component, and the latter are read from the start of the file again.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return c.Validate(args[0])
+		},
+	}
+}
+
+func positionsResetCommand() *cobra.Command {
+	c := newFlowPositions()
+
+	cmd := &cobra.Command{
+		Use:   "reset [flags] file",
+		Short: "Reset the positions of a positions file",
+		Long: `The reset subcommand changes the positions of the entries of a positions
+file matching --path, and --labels if set, so that their logs are read again
+from the new position once the component restarts.
+
+Exactly one of --offset, --since, and --remove must be set:
+
+* --offset sets the byte offset of files.
+* --since sets the offset of files to the start of the first line logged
+  within the given duration, such as 2h. The time of each line is the first
+  timestamp in a format like 2006-01-02T15:04:05Z07:00 found in it, and lines
+  without a timestamp are skipped. Journal cursors are set to the first entry
+  logged within the duration, unless it's longer than the max_age of the
+  component.
+* --remove removes the entries. Files are then read according to the
+  arguments of the component, such as tail_from_end, and journals are read
+  from the max_age of the component.
+
+The agent using the positions file must be stopped first, otherwise it
+overwrites the reset positions.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return c.Reset(args[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&c.path, "path", c.path, "Path of the entries to reset")
+	cmd.Flags().StringVar(&c.labels, "labels", c.labels, "Labels of the entries to reset, as stored in the positions file")
+	cmd.Flags().Int64Var(&c.offset, "offset", c.offset, "Byte offset to reset files to")
+	cmd.Flags().DurationVar(&c.since, "since", c.since, "Reset files to the first line logged within this duration")
+	cmd.Flags().BoolVar(&c.remove, "remove", c.remove, "Remove the entries")
+	return cmd
+}
+
+type flowPositions struct {
+	path   string
+	labels string
+	offset int64
+	since  time.Duration
+	remove bool
+
+	out io.Writer
+	now func() time.Time
+}
+
+func newFlowPositions() *flowPositions {
+	return &flowPositions{
+		offset: -1,
+		out:    os.Stdout,
+		now:    time.Now,
+	}
+}
+
+// positionStatus describes the position of an entry of a positions file.
+type positionStatus struct {
+	status  string
+	invalid bool
+}
+
+func (c *flowPositions) List(file string) error {
+	_, err := c.list(file)
+	return err
+}
+
+func (c *flowPositions) Validate(file string) error {
+	invalid, err := c.list(file)
+	if err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d entries have an invalid position", invalid)
+	}
+	return nil
+}
+
+// list prints the entries of file and returns how many are invalid.
+func (c *flowPositions) list(file string) (int, error) {
+	path, err := positionsFilePath(file)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := positions.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(c.out, "No positions found.")
+		return 0, nil
+	}
+
+	var invalid int
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tLABELS\tPOSITION\tSTATUS")
+	for _, e := range sortedEntries(entries) {
+		st := entryStatus(e, entries[e])
+		if st.invalid {
+			invalid++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Path, e.Labels, entries[e], st.status)
+	}
+	return invalid, w.Flush()
+}
+
+func (c *flowPositions) Reset(file string) error {
+	var actions int
+	for _, set := range []bool{c.offset >= 0, c.since > 0, c.remove} {
+		if set {
+			actions++
+		}
+	}
+	switch {
+	case c.path == "":
+		return fmt.Errorf("--path must be set")
+	case actions != 1:
+		return fmt.Errorf("exactly one of --offset, --since, and --remove must be set")
+	}
+
+	path, err := positionsFilePath(file)
+	if err != nil {
+		return err
+	}
+	entries, err := positions.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var matched []positions.Entry
+	for _, e := range sortedEntries(entries) {
+		if e.Path == c.path && (c.labels == "" || e.Labels == c.labels) {
+			matched = append(matched, e)
+		}
+	}
+	if len(matched) == 0 {
+		return fmt.Errorf("no entries match path %q", c.path)
+	}
+
+	for _, e := range matched {
+		if c.remove {
+			delete(entries, e)
+			fmt.Fprintf(c.out, "Removed %s %s\n", e.Path, e.Labels)
+			continue
+		}
+		if e.IsCursor() {
+			if c.since == 0 {
+				return fmt.Errorf("%s holds a journal cursor, which can only be reset with --since or --remove", e.Path)
+			}
+			cursor := journalCursorAt(c.now().Add(-c.since))
+			fmt.Fprintf(c.out, "Reset %s from %s to %s\n", e.Path, entries[e], cursor)
+			entries[e] = cursor
+			continue
+		}
+
+		offset := c.offset
+		if c.since > 0 {
+			if offset, err = offsetSince(e.Path, c.now().Add(-c.since)); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(c.out, "Reset %s %s from %s to %d\n", e.Path, e.Labels, entries[e], offset)
+		entries[e] = strconv.FormatInt(offset, 10)
+	}
+	return positions.WriteFile(path, entries)
+}
+
+// positionsFilePath returns the path of the positions file of arg, which is
+// either a positions file or a directory containing a positions.yml file.
+func positionsFilePath(arg string) (string, error) {
+	fi, err := os.Stat(arg)
+	if err != nil {
+		return "", err
+	}
+	if fi.IsDir() {
+		return filepath.Join(arg, "positions.yml"), nil
+	}
+	return arg, nil
+}
+
+func sortedEntries(entries map[positions.Entry]string) []positions.Entry {
+	res := make([]positions.Entry, 0, len(entries))
+	for e := range entries {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Path != res[j].Path {
+			return res[i].Path < res[j].Path
+		}
+		return res[i].Labels < res[j].Labels
+	})
+	return res
+}
+
+func entryStatus(e positions.Entry, pos string) positionStatus {
+	if e.IsCursor() {
+		if pos == "" {
+			return positionStatus{status: "empty journal cursor", invalid: true}
+		}
+		if t, ok := journalCursorTime(pos); ok {
+			return positionStatus{status: "journal cursor at " + t.UTC().Format(time.RFC3339)}
+		}
+		return positionStatus{status: "journal cursor"}
+	}
+
+	offset, err := strconv.ParseInt(pos, 10, 64)
+	if err != nil || offset < 0 {
+		return positionStatus{status: "invalid offset", invalid: true}
+	}
+	fi, err := os.Stat(e.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return positionStatus{status: "file no longer exists"}
+	case err != nil:
+		return positionStatus{status: err.Error()}
+	case offset > fi.Size():
+		return positionStatus{status: fmt.Sprintf("offset past end of file of %d bytes", fi.Size())}
+	default:
+		return positionStatus{status: fmt.Sprintf("ok, %d bytes left", fi.Size()-offset)}
+	}
+}
+
+// journalCursorTime returns the time of the entry a journal cursor points
+// to, from its t field.
+func journalCursorTime(cursor string) (time.Time, bool) {
+	for _, field := range strings.Split(cursor, ";") {
+		if v, ok := strings.CutPrefix(field, "t="); ok {
+			usec, err := strconv.ParseInt(v, 16, 64)
+			if err != nil {
+				return time.Time{}, false
+			}
+			return time.UnixMicro(usec), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// journalCursorAt returns a journal cursor pointing to the first entry
+// logged at or after t. The journal accepts cursors made of only the t
+// field, the realtime timestamp of the entry in hexadecimal microseconds.
+func journalCursorAt(t time.Time) string {
+	return "t=" + strconv.FormatInt(t.UnixMicro(), 16)
+}
+
+// lineTimestampRegexp matches timestamps like 2006-01-02T15:04:05.000Z07:00,
+// with an optional fraction and zone, and a space or a T between the date
+// and the time.
+var lineTimestampRegexp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+
+// offsetSince returns the offset of the first line of the file at path with
+// a timestamp which isn't before t, or the size of the file if there is
+// none.
+func offsetSince(path string, t time.Time) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var (
+		r          = bufio.NewReader(f)
+		offset     int64
+		timestamps bool
+	)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if ts, ok := lineTimestamp(line); ok {
+				timestamps = true
+				if !ts.Before(t) {
+					return offset, nil
+				}
+			}
+			offset += int64(len(line))
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if !timestamps {
+		return 0, fmt.Errorf("no timestamps found in %s", path)
+	}
+	return offset, nil
+}
+
+func lineTimestamp(line []byte) (time.Time, bool) {
+	match := lineTimestampRegexp.Find(line)
+	if match == nil {
+		return time.Time{}, false
+	}
+	s := strings.NewReplacer(" ", "T", ",", ".").Replace(string(match))
+
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700"} {
+		if ts, err := time.Parse(layout, s); err == nil {
+			return ts, true
+		}
+	}
+	// Timestamps without a zone are in local time.
+	ts, err := time.ParseInLocation("2006-01-02T15:04:05.999999999", s, time.Local)
+	return ts, err == nil
+}
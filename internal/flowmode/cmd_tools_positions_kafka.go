@@ -0,0 +1,285 @@
+package flowmode
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/spf13/cobra"
+)
+
+// kafkaSASLPasswordEnv is the environment variable holding the SASL
+// password of kafka-reset, so that it isn't passed on the command line.
+const kafkaSASLPasswordEnv = "KAFKA_SASL_PASSWORD"
+
+func positionsKafkaResetCommand() *cobra.Command {
+	c := newFlowKafkaReset()
+
+	cmd := &cobra.Command{
+		Use:   "kafka-reset [flags]",
+		Short: "Reset the offsets of the consumer group of loki.source.kafka",
+		Long: `The kafka-reset subcommand resets the offsets committed by the consumer
+group of loki.source.kafka, so that the logs of its topics are read again
+from the new offsets once the component restarts.
+
+--brokers and --topics must be set like the arguments of the component.
+Topics must be names rather than regular expressions. --group-id defaults
+to the default group_id of the component.
+
+Exactly one of --since, --earliest, and --latest must be set:
+
+* --since resets each partition to the first message produced within the
+  given duration, such as 2h, or to the end of the partition if there is
+  none.
+* --earliest resets each partition to its first message.
+* --latest resets each partition to its end, skipping the messages which
+  weren't read yet.
+
+The consumer group must have no active members, so the agents consuming
+with it must be stopped first.
+
+--tls connects to the brokers with TLS, and --sasl-user authenticates with
+SASL PLAIN, with the password read from the KAFKA_SASL_PASSWORD environment
+variable.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return c.Run()
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&c.brokers, "brokers", c.brokers, "Addresses of the Kafka brokers")
+	cmd.Flags().StringSliceVar(&c.topics, "topics", c.topics, "Topics to reset the offsets of")
+	cmd.Flags().StringVar(&c.groupID, "group-id", c.groupID, "Consumer group to reset the offsets of")
+	cmd.Flags().StringVar(&c.version, "version", c.version, "Kafka version to connect with")
+	cmd.Flags().DurationVar(&c.since, "since", c.since, "Reset partitions to the first message produced within this duration")
+	cmd.Flags().BoolVar(&c.earliest, "earliest", c.earliest, "Reset partitions to their first message")
+	cmd.Flags().BoolVar(&c.latest, "latest", c.latest, "Reset partitions to their end")
+	cmd.Flags().BoolVar(&c.tls, "tls", c.tls, "Connect to the brokers with TLS")
+	cmd.Flags().BoolVar(&c.insecureSkipVerify, "tls-insecure-skip-verify", c.insecureSkipVerify, "Don't verify the certificates of the brokers")
+	cmd.Flags().StringVar(&c.saslUser, "sasl-user", c.saslUser, "User to authenticate with SASL PLAIN")
+	return cmd
+}
+
+type flowKafkaReset struct {
+	brokers  []string
+	topics   []string
+	groupID  string
+	version  string
+	since    time.Duration
+	earliest bool
+	latest   bool
+
+	tls                bool
+	insecureSkipVerify bool
+	saslUser           string
+
+	out io.Writer
+	now func() time.Time
+}
+
+func newFlowKafkaReset() *flowKafkaReset {
+	return &flowKafkaReset{
+		// Defaults of loki.source.kafka.
+		groupID: "loki.source.kafka",
+		version: "2.2.1",
+
+		out: os.Stdout,
+		now: time.Now,
+	}
+}
+
+func (c *flowKafkaReset) Run() error {
+	var resets int
+	for _, set := range []bool{c.since > 0, c.earliest, c.latest} {
+		if set {
+			resets++
+		}
+	}
+	switch {
+	case len(c.brokers) == 0:
+		return fmt.Errorf("--brokers must be set")
+	case len(c.topics) == 0:
+		return fmt.Errorf("--topics must be set")
+	case c.groupID == "":
+		return fmt.Errorf("--group-id must be set")
+	case resets != 1:
+		return fmt.Errorf("exactly one of --since, --earliest, and --latest must be set")
+	}
+
+	cfg, err := c.config()
+	if err != nil {
+		return err
+	}
+
+	admin, err := sarama.NewClusterAdmin(c.brokers, cfg)
+	if err != nil {
+		return fmt.Errorf("connecting to the brokers: %w", err)
+	}
+	defer admin.Close()
+	if err := checkGroupInactive(admin, c.groupID); err != nil {
+		return err
+	}
+
+	client, err := sarama.NewClient(c.brokers, cfg)
+	if err != nil {
+		return fmt.Errorf("connecting to the brokers: %w", err)
+	}
+	defer client.Close()
+	return c.reset(client)
+}
+
+// config returns the config of the Kafka clients.
+func (c *flowKafkaReset) config() (*sarama.Config, error) {
+	version, err := sarama.ParseKafkaVersion(c.version)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := sarama.NewConfig()
+	cfg.ClientID = "agent-tools"
+	cfg.Version = version
+	cfg.Consumer.Return.Errors = true
+	cfg.Consumer.Offsets.AutoCommit.Enable = false
+
+	if c.tls {
+		cfg.Net.TLS.Enable = true
+		cfg.Net.TLS.Config = &tls.Config{InsecureSkipVerify: c.insecureSkipVerify}
+	}
+	if c.saslUser != "" {
+		cfg.Net.SASL.Enable = true
+		cfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		cfg.Net.SASL.User = c.saslUser
+		cfg.Net.SASL.Password = os.Getenv(kafkaSASLPasswordEnv)
+	}
+	return cfg, nil
+}
+
+// checkGroupInactive returns an error if the consumer group has active
+// members, which would overwrite the reset offsets.
+func checkGroupInactive(admin sarama.ClusterAdmin, groupID string) error {
+	groups, err := admin.DescribeConsumerGroups([]string{groupID})
+	if err != nil {
+		return fmt.Errorf("describing consumer group %q: %w", groupID, err)
+	}
+	for _, g := range groups {
+		if g.Err != sarama.ErrNoError {
+			return fmt.Errorf("describing consumer group %q: %w", groupID, g.Err)
+		}
+		if len(g.Members) > 0 {
+			return fmt.Errorf("consumer group %q has %d active members, which must be stopped first", groupID, len(g.Members))
+		}
+	}
+	return nil
+}
+
+// reset commits the new offsets of the partitions of the topics. Offsets
+// are only committed once all of them are looked up, so that a failure
+// doesn't leave the group partly reset.
+func (c *flowKafkaReset) reset(client sarama.Client) error {
+	// Time to look the new offsets up with, in milliseconds.
+	at := sarama.OffsetOldest
+	switch {
+	case c.latest:
+		at = sarama.OffsetNewest
+	case c.since > 0:
+		at = c.now().Add(-c.since).UnixMilli()
+	}
+
+	type partitionReset struct {
+		topic     string
+		partition int32
+		from, to  int64
+		pom       sarama.PartitionOffsetManager
+	}
+	var resets []*partitionReset
+
+	for _, topic := range c.topics {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return fmt.Errorf("listing the partitions of topic %q: %w", topic, err)
+		}
+		for _, partition := range partitions {
+			offset, err := client.GetOffset(topic, partition, at)
+			if err == nil && offset == -1 {
+				// No message was produced since the time, so the partition is
+				// reset to its end.
+				offset, err = client.GetOffset(topic, partition, sarama.OffsetNewest)
+			}
+			if err != nil {
+				return fmt.Errorf("looking up the offset of topic %q partition %d: %w", topic, partition, err)
+			}
+			resets = append(resets, &partitionReset{topic: topic, partition: partition, to: offset})
+		}
+	}
+
+	om, err := sarama.NewOffsetManagerFromClient(c.groupID, client)
+	if err != nil {
+		return err
+	}
+	closed := false
+	defer func() {
+		if closed {
+			return
+		}
+		// Partition offset managers must be closed before the offset manager,
+		// which flushes and releases them.
+		for _, r := range resets {
+			if r.pom != nil {
+				r.pom.AsyncClose()
+			}
+		}
+		_ = om.Close()
+	}()
+
+	for _, r := range resets {
+		if r.pom, err = om.ManagePartition(r.topic, r.partition); err != nil {
+			return fmt.Errorf("fetching the offset of topic %q partition %d: %w", r.topic, r.partition, err)
+		}
+		r.from, _ = r.pom.NextOffset()
+	}
+	for _, r := range resets {
+		// ResetOffset only moves offsets backwards, and MarkOffset forwards.
+		if r.to < r.from {
+			r.pom.ResetOffset(r.to, "")
+		} else {
+			r.pom.MarkOffset(r.to, "")
+		}
+		r.pom.AsyncClose()
+	}
+	om.Commit()
+	closed = true
+	if err := om.Close(); err != nil {
+		return fmt.Errorf("committing offsets: %w", err)
+	}
+
+	// Errors committing offsets are reported by the partition offset
+	// managers, whose error channels are closed once the offset manager is.
+	var errs []error
+	for _, r := range resets {
+		for err := range r.pom.Errors() {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("committing offsets: %w", errors.Join(errs...))
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TOPIC\tPARTITION\tFROM\tTO")
+	for _, r := range resets {
+		from := "none"
+		if r.from >= 0 {
+			from = strconv.FormatInt(r.from, 10)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\n", r.topic, r.partition, from, r.to)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,103 @@
+package flowmode
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaReset(t *testing.T) {
+	const group = "loki.source.kafka"
+	now := time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC)
+	since := now.Add(-2 * time.Hour).UnixMilli()
+
+	newBroker := func(t *testing.T, description *sarama.GroupDescription) *sarama.MockBroker {
+		broker := sarama.NewMockBroker(t, 1)
+		t.Cleanup(broker.Close)
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(t).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				SetController(broker.BrokerID()).
+				SetLeader("logs", 0, broker.BrokerID()).
+				SetLeader("logs", 1, broker.BrokerID()),
+			"OffsetRequest": sarama.NewMockOffsetResponse(t).
+				SetOffset("logs", 0, since, 42).
+				SetOffset("logs", 1, since, -1).
+				SetOffset("logs", 1, sarama.OffsetNewest, 100),
+			"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+				SetCoordinator(sarama.CoordinatorGroup, group, broker),
+			"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+				AddGroupDescription(group, description),
+			"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+				SetOffset(group, "logs", 0, 50, "", sarama.ErrNoError).
+				SetOffset(group, "logs", 1, 90, "", sarama.ErrNoError).
+				SetError(sarama.ErrNoError),
+			"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+		})
+		return broker
+	}
+	newKafkaReset := func(broker *sarama.MockBroker) (*flowKafkaReset, *bytes.Buffer) {
+		var out bytes.Buffer
+		c := newFlowKafkaReset()
+		c.out = &out
+		c.now = func() time.Time { return now }
+		c.brokers = []string{broker.Addr()}
+		c.topics = []string{"logs"}
+		c.since = 2 * time.Hour
+		return c, &out
+	}
+
+	t.Run("since", func(t *testing.T) {
+		broker := newBroker(t, &sarama.GroupDescription{GroupId: group, State: "Empty"})
+		c, out := newKafkaReset(broker)
+		require.NoError(t, c.Run())
+
+		// Offsets are moved both backwards and forwards, and partitions without
+		// messages since the time are reset to their end.
+		committed := map[int32]int64{}
+		for _, rr := range broker.History() {
+			req, ok := rr.Request.(*sarama.OffsetCommitRequest)
+			if !ok {
+				continue
+			}
+			for _, partition := range []int32{0, 1} {
+				if offset, _, err := req.Offset("logs", partition); err == nil {
+					committed[partition] = offset
+				}
+			}
+		}
+		require.Equal(t, map[int32]int64{0: 42, 1: 100}, committed)
+		require.Regexp(t, `logs\s+0\s+50\s+42`, out.String())
+		require.Regexp(t, `logs\s+1\s+90\s+100`, out.String())
+	})
+
+	t.Run("active group", func(t *testing.T) {
+		broker := newBroker(t, &sarama.GroupDescription{
+			GroupId: group,
+			State:   "Stable",
+			Members: map[string]*sarama.GroupMemberDescription{"agent-1": {ClientId: "agent-1"}},
+		})
+		c, _ := newKafkaReset(broker)
+		require.ErrorContains(t, c.Run(), "has 1 active members")
+	})
+}
+
+func TestKafkaReset_Flags(t *testing.T) {
+	c := newFlowKafkaReset()
+	require.ErrorContains(t, c.Run(), "--brokers must be set")
+
+	c.brokers = []string{"localhost:9092"}
+	require.ErrorContains(t, c.Run(), "--topics must be set")
+
+	c.topics = []string{"logs"}
+	require.ErrorContains(t, c.Run(), "exactly one of --since, --earliest, and --latest must be set")
+
+	c.since, c.latest = time.Hour, true
+	require.ErrorContains(t, c.Run(), "exactly one of --since, --earliest, and --latest must be set")
+
+	c.latest, c.version = false, "invalid"
+	require.Error(t, c.Run())
+}
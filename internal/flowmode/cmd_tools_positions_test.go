@@ -0,0 +1,106 @@
+package flowmode
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component/common/loki/positions"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositions(t *testing.T) {
+	dir := t.TempDir()
+
+	logFile := filepath.Join(dir, "app.log")
+	lines := "2024-03-01T10:00:00Z first\n" +
+		"continuation without timestamp\n" +
+		"2024-03-01T11:30:00Z second\n" +
+		"2024-03-01T12:00:00Z third\n"
+	require.NoError(t, os.WriteFile(logFile, []byte(lines), 0644))
+
+	var (
+		fileEntry    = positions.Entry{Path: logFile, Labels: `{job="app"}`}
+		missingEntry = positions.Entry{Path: filepath.Join(dir, "missing.log"), Labels: "{}"}
+		cursorEntry  = positions.Entry{Path: positions.CursorKey("loki.source.journal.default"), Labels: ""}
+	)
+	positionsFile := filepath.Join(dir, "positions.yml")
+	require.NoError(t, positions.WriteFile(positionsFile, map[positions.Entry]string{
+		fileEntry:    "10",
+		missingEntry: "5",
+		cursorEntry:  "s=abc;i=1;t=5e6f3b1a2c000;x=def",
+	}))
+
+	newPositions := func() (*flowPositions, *bytes.Buffer) {
+		var out bytes.Buffer
+		c := newFlowPositions()
+		c.out = &out
+		c.now = func() time.Time { return time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC) }
+		return c, &out
+	}
+
+	t.Run("validate", func(t *testing.T) {
+		c, out := newPositions()
+		require.NoError(t, c.Validate(dir))
+		require.Contains(t, out.String(), "ok, 103 bytes left")
+		require.Contains(t, out.String(), "file no longer exists")
+		require.Contains(t, out.String(), "journal cursor at")
+	})
+
+	t.Run("reset since", func(t *testing.T) {
+		c, _ := newPositions()
+		c.path = logFile
+		c.since = 2 * time.Hour
+		require.NoError(t, c.Reset(positionsFile))
+
+		entries, err := positions.ReadFile(positionsFile)
+		require.NoError(t, err)
+		require.Equal(t, "58", entries[fileEntry])
+		require.Equal(t, "5", entries[missingEntry])
+	})
+
+	t.Run("reset offset", func(t *testing.T) {
+		c, _ := newPositions()
+		c.path = logFile
+		c.offset = 0
+		require.NoError(t, c.Reset(positionsFile))
+
+		entries, err := positions.ReadFile(positionsFile)
+		require.NoError(t, err)
+		require.Equal(t, "0", entries[fileEntry])
+	})
+
+	t.Run("reset cursor", func(t *testing.T) {
+		c, _ := newPositions()
+		c.path = cursorEntry.Path
+		c.offset = 0
+		require.ErrorContains(t, c.Reset(positionsFile), "can only be reset with --since or --remove")
+
+		// The cursor is reset to the first entry logged within the duration.
+		c.offset, c.since = -1, 2*time.Hour
+		require.NoError(t, c.Reset(positionsFile))
+
+		entries, err := positions.ReadFile(positionsFile)
+		require.NoError(t, err)
+		at, ok := journalCursorTime(entries[cursorEntry])
+		require.True(t, ok)
+		require.Equal(t, time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), at.UTC())
+
+		c.since, c.remove = 0, true
+		require.NoError(t, c.Reset(positionsFile))
+
+		entries, err = positions.ReadFile(positionsFile)
+		require.NoError(t, err)
+		require.NotContains(t, entries, cursorEntry)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.NoError(t, positions.WriteFile(positionsFile, map[positions.Entry]string{fileEntry: "abc"}))
+
+		c, out := newPositions()
+		require.ErrorContains(t, c.Validate(positionsFile), "1 entries have an invalid position")
+		require.Contains(t, out.String(), "invalid offset")
+	})
+}
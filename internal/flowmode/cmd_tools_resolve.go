@@ -0,0 +1,54 @@
+package flowmode
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func resolveCommand() *cobra.Command {
+	r := &flowResolve{client: newAgentClient()}
+
+	cmd := &cobra.Command{
+		Use:   "resolve [flags] expression",
+		Short: "Resolve a River expression against a running agent",
+		Long: `The resolve subcommand evaluates a River expression against the
+components of a running agent and prints the resolved value.
+
+Expressions may reference the exports of components of the root module and
+call standard library functions, except for env and nonsensitive. Secrets in
+the resolved value are printed as (secret).
+
+If the expression argument is "-", the expression is read from stdin.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return r.Run(args[0])
+		},
+	}
+
+	r.client.registerFlags(cmd.Flags())
+	return cmd
+}
+
+type flowResolve struct {
+	client *agentClient
+}
+
+func (r *flowResolve) Run(expr string) error {
+	expr, err := readArgument(expr)
+	if err != nil {
+		return fmt.Errorf("reading expression from stdin: %w", err)
+	}
+
+	bb, err := r.client.do(http.MethodPost, "/resolve", "text/plain", strings.NewReader(expr))
+	if err != nil {
+		return fmt.Errorf("failed to resolve expression: %w", err)
+	}
+
+	fmt.Println(strings.TrimSpace(string(bb)))
+	return nil
+}
@@ -0,0 +1,133 @@
+package flowmode
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grafana/agent/internal/static/agentctl/waltools"
+	"github.com/spf13/cobra"
+)
+
+func walInspectCommand() *cobra.Command {
+	c := &flowWALInspect{
+		truncateBefore: -1,
+		out:            os.Stdout,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "wal-inspect [flags] directory",
+		Short: "Inspect, repair and truncate the WAL of prometheus.remote_write",
+		Long: `The wal-inspect subcommand inspects the WAL of a prometheus.remote_write
+component offline. The directory argument is the data directory of the
+component or the wal directory inside it.
+
+The latest checkpoint and every segment of the WAL are listed with their size
+on disk, number of series and samples, and the time range of their samples.
+Each segment is read independently so that corrupted segments are detected
+and reported without preventing the others from being inspected. The command
+exits with a non-zero status if a segment is corrupted and --repair isn't
+set.
+
+--repair discards the records of the first corrupted segment from the
+corruption onwards, and the segments after it. --truncate-before removes the
+segments before the given one to free disk space; the series of the removed
+segments are kept in a new checkpoint, but their samples are dropped.
+
+The agent using the WAL must be stopped before using --repair or
+--truncate-before.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+
+		RunE: func(_ *cobra.Command, args []string) error {
+			return c.Run(args[0])
+		},
+	}
+
+	cmd.Flags().BoolVar(&c.repair, "repair", c.repair, "Repair the first corrupted segment")
+	cmd.Flags().IntVar(&c.truncateBefore, "truncate-before", c.truncateBefore, "Remove the segments before the given segment")
+	return cmd
+}
+
+type flowWALInspect struct {
+	repair         bool
+	truncateBefore int
+	out            io.Writer
+}
+
+func (c *flowWALInspect) Run(directory string) error {
+	if _, err := os.Stat(directory); err != nil {
+		return fmt.Errorf("reading WAL: %w", err)
+	}
+	// Check if ./wal is a subdirectory, use that instead.
+	if _, err := os.Stat(filepath.Join(directory, "wal")); err == nil {
+		directory = filepath.Join(directory, "wal")
+	}
+
+	if c.repair {
+		repaired, err := waltools.Repair(directory)
+		if err != nil {
+			return err
+		}
+		if repaired != nil {
+			fmt.Fprintf(c.out, "Repaired segment %s: %v\n\n", repaired.Name, repaired.Corruption)
+		}
+	}
+	if c.truncateBefore >= 0 {
+		if err := waltools.TruncateBefore(directory, c.truncateBefore); err != nil {
+			return fmt.Errorf("truncating WAL: %w", err)
+		}
+		fmt.Fprintf(c.out, "Removed segments before %08d\n\n", c.truncateBefore)
+	}
+
+	ins, err := waltools.Inspect(directory)
+	if err != nil {
+		return fmt.Errorf("inspecting WAL: %w", err)
+	}
+
+	var series, samples int
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEGMENT\tSIZE\tSERIES\tSAMPLES\tFROM\tTO\tSTATUS")
+	for _, s := range ins.Segments {
+		status := "ok"
+		if s.Corruption != nil {
+			status = fmt.Sprintf("corrupted at offset %d: %v", s.Corruption.Offset, s.Corruption.Err)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\t%s\n", s.Name, formatBytes(s.Size), s.Series, s.Samples, formatTime(s.From), formatTime(s.To), status)
+
+		series += s.Series
+		samples += s.Samples
+	}
+	fmt.Fprintf(w, "TOTAL\t%s\t%d\t%d\t%s\t%s\t\n", formatBytes(ins.Size), series, samples, formatTime(ins.From()), formatTime(ins.To()))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if corrupted := ins.Corrupted(); corrupted != nil {
+		return fmt.Errorf("segment %s is corrupted, run with --repair to repair it", corrupted.Name)
+	}
+	return nil
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
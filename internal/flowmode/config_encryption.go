@@ -0,0 +1,339 @@
+package flowmode
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// encryptedConfigType is the PEM block type of encrypted config files.
+const encryptedConfigType = "GRAFANA AGENT ENCRYPTED CONFIG"
+
+// ageMagic starts the header of age-encrypted files which aren't armored.
+const ageMagic = "age-encryption.org/v1\n"
+
+// Kinds of keys which configs can be encrypted with.
+const (
+	configKeyFile   = "file"   // AES-256 key stored in a local file.
+	configKeyAWSKMS = "awskms" // Data key encrypted by an AWS KMS key.
+	configKeyAge    = "age"    // age recipients or identities stored in a local file.
+)
+
+// PEM headers of encrypted config files.
+const (
+	headerKeyKind = "Key-Kind"
+	headerDataKey = "Data-Key"
+	headerNonce   = "Nonce"
+)
+
+// configKey is a key used to encrypt or decrypt config files. Keys are given
+// as file:PATH, for a file holding a base64-encoded 256-bit AES key, as
+// awskms:KEY_ID, for a key managed by AWS KMS, or as age:PATH, for a file
+// holding age recipients to encrypt with or age identities to decrypt with.
+// The key ID of awskms keys may be omitted when decrypting.
+type configKey struct {
+	kind string
+	ref  string
+}
+
+func parseConfigKey(s string) (configKey, error) {
+	kind, ref, ok := strings.Cut(s, ":")
+	if !ok {
+		return configKey{}, fmt.Errorf("invalid key %q: expected file:PATH, awskms:KEY_ID, or age:PATH", s)
+	}
+
+	switch kind {
+	case configKeyFile, configKeyAge:
+		if ref == "" {
+			return configKey{}, fmt.Errorf("invalid key %q: missing path", s)
+		}
+	case configKeyAWSKMS:
+	default:
+		return configKey{}, fmt.Errorf("invalid key %q: unsupported key kind %q", s, kind)
+	}
+	return configKey{kind: kind, ref: ref}, nil
+}
+
+// newDataKey returns a key to encrypt a config with, along with the encrypted
+// form of the key to store with the config. The encrypted form is nil for
+// file keys.
+func (k configKey) newDataKey() (key, encrypted []byte, err error) {
+	switch k.kind {
+	case configKeyFile:
+		key, err := k.readFileKey()
+		return key, nil, err
+
+	case configKeyAWSKMS:
+		if k.ref == "" {
+			return nil, nil, fmt.Errorf("a KMS key ID is required to encrypt configs")
+		}
+		client, err := newKMSClient()
+		if err != nil {
+			return nil, nil, err
+		}
+		out, err := client.GenerateDataKey(&kms.GenerateDataKeyInput{
+			KeyId:   aws.String(k.ref),
+			KeySpec: aws.String(kms.DataKeySpecAes256),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("generating data key: %w", err)
+		}
+		return out.Plaintext, out.CiphertextBlob, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported key kind %q", k.kind)
+	}
+}
+
+// openDataKey returns the key to decrypt a config with, given the encrypted
+// data key stored with the config.
+func (k configKey) openDataKey(encrypted []byte) ([]byte, error) {
+	switch k.kind {
+	case configKeyFile:
+		return k.readFileKey()
+
+	case configKeyAWSKMS:
+		client, err := newKMSClient()
+		if err != nil {
+			return nil, err
+		}
+		in := &kms.DecryptInput{CiphertextBlob: encrypted}
+		if k.ref != "" {
+			in.KeyId = aws.String(k.ref)
+		}
+		out, err := client.Decrypt(in)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting data key: %w", err)
+		}
+		return out.Plaintext, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key kind %q", k.kind)
+	}
+}
+
+func (k configKey) readFileKey() ([]byte, error) {
+	bb, err := os.ReadFile(k.ref)
+	if err != nil {
+		return nil, fmt.Errorf("reading key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(bb)))
+	if err != nil {
+		return nil, fmt.Errorf("decoding key from %s: %w", k.ref, err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key in %s must be 32 bytes, got %d", k.ref, len(key))
+	}
+	return key, nil
+}
+
+func newKMSClient() (*kms.KMS, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating AWS session: %w", err)
+	}
+	return kms.New(sess), nil
+}
+
+// isEncryptedConfig reports whether bb holds an encrypted config.
+func isEncryptedConfig(bb []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(bb), []byte("-----BEGIN "+encryptedConfigType+"-----")) || isAgeConfig(bb)
+}
+
+// isAgeConfig reports whether bb holds an age-encrypted config, armored or
+// not.
+func isAgeConfig(bb []byte) bool {
+	return bytes.HasPrefix(bb, []byte(ageMagic)) || bytes.HasPrefix(bytes.TrimSpace(bb), []byte(armor.Header))
+}
+
+// encryptConfig encrypts the config bb with k.
+func encryptConfig(k configKey, bb []byte) ([]byte, error) {
+	if k.kind == configKeyAge {
+		return encryptAgeConfig(k, bb)
+	}
+
+	key, encryptedKey, err := k.newDataKey()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newConfigAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	block := &pem.Block{
+		Type: encryptedConfigType,
+		Headers: map[string]string{
+			headerKeyKind: k.kind,
+			headerNonce:   base64.StdEncoding.EncodeToString(nonce),
+		},
+	}
+	if encryptedKey != nil {
+		block.Headers[headerDataKey] = base64.StdEncoding.EncodeToString(encryptedKey)
+	}
+	block.Bytes = aead.Seal(nil, nonce, bb, additionalData(block))
+	return pem.EncodeToMemory(block), nil
+}
+
+// encryptAgeConfig encrypts the config bb to the age recipients of k, as an
+// armored age file.
+func encryptAgeConfig(k configKey, bb []byte) ([]byte, error) {
+	f, err := os.Open(k.ref)
+	if err != nil {
+		return nil, fmt.Errorf("reading age recipients: %w", err)
+	}
+	defer f.Close()
+	recipients, err := age.ParseRecipients(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing age recipients from %s: %w", k.ref, err)
+	}
+
+	var buf bytes.Buffer
+	armorWriter := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armorWriter, recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(bb); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := armorWriter.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// decryptConfig decrypts the encrypted config bb with the key given by
+// keyFlag. The decrypted config is only held in memory.
+func decryptConfig(keyFlag string, bb []byte) ([]byte, error) {
+	if keyFlag == "" {
+		return nil, fmt.Errorf("config is encrypted but --config.decryption-key isn't set")
+	}
+	k, err := parseConfigKey(keyFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	if isAgeConfig(bb) {
+		if k.kind != configKeyAge {
+			return nil, fmt.Errorf("config was encrypted with a key of kind %q, but the given key is of kind %q", configKeyAge, k.kind)
+		}
+		return decryptAgeConfig(k, bb)
+	}
+
+	block, _ := pem.Decode(bytes.TrimSpace(bb))
+	if block == nil || block.Type != encryptedConfigType {
+		return nil, fmt.Errorf("malformed encrypted config")
+	}
+	if kind := block.Headers[headerKeyKind]; kind != k.kind {
+		return nil, fmt.Errorf("config was encrypted with a key of kind %q, but the given key is of kind %q", kind, k.kind)
+	}
+
+	var encryptedKey []byte
+	if v, ok := block.Headers[headerDataKey]; ok {
+		if encryptedKey, err = base64.StdEncoding.DecodeString(v); err != nil {
+			return nil, fmt.Errorf("malformed data key: %w", err)
+		}
+	}
+	nonce, err := base64.StdEncoding.DecodeString(block.Headers[headerNonce])
+	if err != nil {
+		return nil, fmt.Errorf("malformed nonce: %w", err)
+	}
+
+	key, err := k.openDataKey(encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newConfigAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("malformed nonce: expected %d bytes, got %d", aead.NonceSize(), len(nonce))
+	}
+
+	plaintext, err := aead.Open(nil, nonce, block.Bytes, additionalData(block))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting config: %w", err)
+	}
+	return plaintext, nil
+}
+
+// decryptAgeConfig decrypts the age-encrypted config bb with the age
+// identities of k.
+func decryptAgeConfig(k configKey, bb []byte) ([]byte, error) {
+	f, err := os.Open(k.ref)
+	if err != nil {
+		return nil, fmt.Errorf("reading age identities: %w", err)
+	}
+	defer f.Close()
+	identities, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing age identities from %s: %w", k.ref, err)
+	}
+
+	var src io.Reader = bytes.NewReader(bb)
+	if !bytes.HasPrefix(bb, []byte(ageMagic)) {
+		src = armor.NewReader(bytes.NewReader(bytes.TrimSpace(bb)))
+	}
+	r, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting config: %w", err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting config: %w", err)
+	}
+	return plaintext, nil
+}
+
+// additionalData returns the type and headers of block as additional data
+// of its ciphertext, so that they can't be changed without failing
+// decryption.
+func additionalData(block *pem.Block) []byte {
+	names := make([]string, 0, len(block.Headers))
+	for name := range block.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString(block.Type)
+	for _, name := range names {
+		fmt.Fprintf(&buf, "\n%s: %s", name, block.Headers[name])
+	}
+	return buf.Bytes()
+}
+
+func newConfigAEAD(key []byte) (cipher.AEAD, error) {
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(c)
+}
@@ -0,0 +1,158 @@
+package flowmode
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigEncryption(t *testing.T) {
+	dir := t.TempDir()
+	writeKey := func(name string) string {
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		require.NoError(t, err)
+
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600))
+		return "file:" + path
+	}
+	key, otherKey := writeKey("key"), writeKey("other")
+
+	config := []byte(`logging { level = "debug" }`)
+
+	k, err := parseConfigKey(key)
+	require.NoError(t, err)
+	encrypted, err := encryptConfig(k, config)
+	require.NoError(t, err)
+	require.True(t, isEncryptedConfig(encrypted))
+	require.NotContains(t, string(encrypted), "logging")
+
+	configPath := filepath.Join(dir, "config.river.enc")
+	require.NoError(t, os.WriteFile(configPath, encrypted, 0600))
+
+	t.Run("decrypts with the key", func(t *testing.T) {
+		bb, err := readConfigFile(configPath, key)
+		require.NoError(t, err)
+		require.Equal(t, config, bb)
+	})
+
+	t.Run("fails with a different key", func(t *testing.T) {
+		_, err := readConfigFile(configPath, otherKey)
+		require.ErrorContains(t, err, "decrypting config")
+	})
+
+	t.Run("fails without a key", func(t *testing.T) {
+		_, err := readConfigFile(configPath, "")
+		require.ErrorContains(t, err, "--config.decryption-key isn't set")
+	})
+
+	t.Run("fails with a different kind of key", func(t *testing.T) {
+		_, err := readConfigFile(configPath, "awskms:alias/agent")
+		require.ErrorContains(t, err, `config was encrypted with a key of kind "file", but the given key is of kind "awskms"`)
+	})
+
+	t.Run("fails with changed headers", func(t *testing.T) {
+		changed := strings.Replace(string(encrypted), "Key-Kind: file", "Key-Kind: file\nComment: changed", 1)
+		path := filepath.Join(dir, "changed.river.enc")
+		require.NoError(t, os.WriteFile(path, []byte(changed), 0600))
+
+		_, err := readConfigFile(path, key)
+		require.ErrorContains(t, err, "decrypting config")
+	})
+
+	t.Run("unencrypted configs are rejected with a key", func(t *testing.T) {
+		path := filepath.Join(dir, "config.river")
+		require.NoError(t, os.WriteFile(path, config, 0600))
+
+		_, err := readConfigFile(path, key)
+		require.ErrorContains(t, err, "config isn't encrypted but --config.decryption-key is set")
+	})
+
+	t.Run("unencrypted configs are read as is without a key", func(t *testing.T) {
+		path := filepath.Join(dir, "config.river")
+		require.NoError(t, os.WriteFile(path, config, 0600))
+
+		bb, err := readConfigFile(path, "")
+		require.NoError(t, err)
+		require.Equal(t, config, bb)
+	})
+}
+
+func TestConfigEncryption_Age(t *testing.T) {
+	dir := t.TempDir()
+	writeIdentity := func(name string) (id *age.X25519Identity, recipients, identities string) {
+		id, err := age.GenerateX25519Identity()
+		require.NoError(t, err)
+
+		recipients = filepath.Join(dir, name+".pub")
+		require.NoError(t, os.WriteFile(recipients, []byte(id.Recipient().String()+"\n"), 0600))
+		identities = filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(identities, []byte("# created by a test\n"+id.String()+"\n"), 0600))
+		return id, "age:" + recipients, "age:" + identities
+	}
+	id, recipients, identities := writeIdentity("key")
+	_, _, otherIdentities := writeIdentity("other")
+
+	config := []byte(`logging { level = "debug" }`)
+
+	k, err := parseConfigKey(recipients)
+	require.NoError(t, err)
+	encrypted, err := encryptConfig(k, config)
+	require.NoError(t, err)
+	require.True(t, isEncryptedConfig(encrypted))
+	require.True(t, strings.HasPrefix(string(encrypted), "-----BEGIN AGE ENCRYPTED FILE-----"))
+
+	configPath := filepath.Join(dir, "config.river.enc")
+	require.NoError(t, os.WriteFile(configPath, encrypted, 0600))
+
+	t.Run("decrypts with the identities", func(t *testing.T) {
+		bb, err := readConfigFile(configPath, identities)
+		require.NoError(t, err)
+		require.Equal(t, config, bb)
+	})
+
+	t.Run("decrypts binary files", func(t *testing.T) {
+		var buf strings.Builder
+		w, err := age.Encrypt(&buf, id.Recipient())
+		require.NoError(t, err)
+		_, err = w.Write(config)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		path := filepath.Join(dir, "binary.river.enc")
+		require.NoError(t, os.WriteFile(path, []byte(buf.String()), 0600))
+
+		bb, err := readConfigFile(path, identities)
+		require.NoError(t, err)
+		require.Equal(t, config, bb)
+	})
+
+	t.Run("fails with other identities", func(t *testing.T) {
+		_, err := readConfigFile(configPath, otherIdentities)
+		require.ErrorContains(t, err, "decrypting config")
+	})
+
+	t.Run("fails with a different kind of key", func(t *testing.T) {
+		_, err := readConfigFile(configPath, "awskms:alias/agent")
+		require.ErrorContains(t, err, `config was encrypted with a key of kind "age", but the given key is of kind "awskms"`)
+	})
+}
+
+func TestParseConfigKey(t *testing.T) {
+	k, err := parseConfigKey("awskms:")
+	require.NoError(t, err)
+	require.Equal(t, configKey{kind: configKeyAWSKMS}, k)
+
+	_, err = parseConfigKey("/etc/agent/key")
+	require.EqualError(t, err, `invalid key "/etc/agent/key": expected file:PATH, awskms:KEY_ID, or age:PATH`)
+
+	_, err = parseConfigKey("gcpkms:key")
+	require.EqualError(t, err, `invalid key "gcpkms:key": unsupported key kind "gcpkms"`)
+}
@@ -2,6 +2,7 @@
 package flowmode
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -31,6 +32,10 @@ func Run() {
 	)
 
 	if err := cmd.Execute(); err != nil {
+		var exitErr exitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.code)
+		}
 		os.Exit(1)
 	}
 }
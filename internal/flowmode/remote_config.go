@@ -0,0 +1,401 @@
+package flowmode
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/flow"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// remoteConfigOptions configures fetching the config from a remote endpoint.
+type remoteConfigOptions struct {
+	// URL of the config, either http(s)://... or s3://BUCKET/KEY.
+	URL string
+	// Headers sent with HTTP requests.
+	Headers map[string]string
+	// HeadersFile is the path of a file holding more headers to send with
+	// HTTP requests, one "Name: value" per line. It's read on every fetch,
+	// so that credentials can be rotated without restarting.
+	HeadersFile string
+	// PollFrequency is how often the config is fetched.
+	PollFrequency time.Duration
+	// FallbackToLastGood loads the last config which was successfully loaded
+	// when the remote config can't be fetched or loaded.
+	FallbackToLastGood bool
+	// StoragePath is the directory the last good config is cached in.
+	StoragePath string
+}
+
+// maxRemoteConfigSize is the maximum size of a remote config.
+const maxRemoteConfigSize = 16 << 20 // 16 MiB
+
+// remoteConfigFetcher fetches the content of a remote config.
+type remoteConfigFetcher func(ctx context.Context) ([]byte, error)
+
+// remoteConfig polls the config from a remote endpoint. Configs are only
+// reloaded when their content changed since the last load.
+type remoteConfig struct {
+	log   log.Logger
+	opts  remoteConfigOptions
+	fetch remoteConfigFetcher
+
+	fetchErrors   prometheus.Counter
+	lastFetch     prometheus.Gauge
+	fallbackLoads prometheus.Counter
+
+	cachePath string // Where the last good config is cached on disk.
+
+	mut             sync.Mutex
+	lastGood        []byte // Content of the last config which was loaded successfully.
+	lastGoodChecked bool   // Whether the on-disk cache was read into lastGood.
+	candidate       []byte // Content of the config being loaded.
+	pending         []byte // Content fetched by the poller, used by the next load.
+}
+
+func newRemoteConfig(l log.Logger, reg prometheus.Registerer, opts remoteConfigOptions) (*remoteConfig, error) {
+	fetch, err := newRemoteConfigFetcher(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &remoteConfig{
+		log:   l,
+		opts:  opts,
+		fetch: fetch,
+
+		fetchErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_config_remote_fetch_errors_total",
+			Help: "Total number of failed fetches of the remote config.",
+		}),
+		lastFetch: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "agent_config_remote_last_fetch_success_timestamp_seconds",
+			Help: "Timestamp of the last successful fetch of the remote config.",
+		}),
+		fallbackLoads: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_config_remote_fallback_loads_total",
+			Help: "Total number of times the last good config was loaded instead of the remote config.",
+		}),
+		cachePath: filepath.Join(opts.StoragePath, "remote-config", "last-good.river"),
+	}
+	if reg != nil {
+		reg.MustRegister(rc.fetchErrors, rc.lastFetch, rc.fallbackLoads)
+	}
+	return rc, nil
+}
+
+func newRemoteConfigFetcher(opts remoteConfigOptions) (remoteConfigFetcher, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote config URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return httpConfigFetcher(opts.URL, opts.Headers, opts.HeadersFile), nil
+	case "s3":
+		if u.Host == "" || strings.TrimPrefix(u.Path, "/") == "" {
+			return nil, fmt.Errorf("invalid remote config URL %q: expected s3://BUCKET/KEY", opts.URL)
+		}
+		sess, err := session.NewSessionWithOptions(session.Options{
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating AWS session: %w", err)
+		}
+		return s3ConfigFetcher(s3.New(sess), u.Host, strings.TrimPrefix(u.Path, "/")), nil
+	default:
+		return nil, fmt.Errorf("unsupported remote config URL scheme %q: expected http, https, or s3", u.Scheme)
+	}
+}
+
+func httpConfigFetcher(url string, headers map[string]string, headersFile string) remoteConfigFetcher {
+	return func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		if headersFile != "" {
+			if err := readHeadersFile(headersFile, req.Header); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode/100 != 2 {
+			return nil, fmt.Errorf("unexpected status code %s", resp.Status)
+		}
+		return readRemoteConfig(resp.Body)
+	}
+}
+
+// readHeadersFile sets the headers of the file at path, which holds one
+// "Name: value" per line, in h. Empty lines and lines starting with # are
+// ignored.
+func readHeadersFile(path string, h http.Header) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("reading headers file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(text, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("headers file %s: line %d: expected Name: value", path, line)
+		}
+		h.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading headers file: %w", err)
+	}
+	return nil
+}
+
+func s3ConfigFetcher(client *s3.S3, bucket, key string) remoteConfigFetcher {
+	return func(ctx context.Context) ([]byte, error) {
+		out, err := client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer out.Body.Close()
+		return readRemoteConfig(out.Body)
+	}
+}
+
+// readRemoteConfig reads a remote config from r, failing if it's larger than
+// maxRemoteConfigSize.
+func readRemoteConfig(r io.Reader) ([]byte, error) {
+	bb, err := io.ReadAll(io.LimitReader(r, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(bb) > maxRemoteConfigSize {
+		return nil, fmt.Errorf("remote config is larger than %d bytes", maxRemoteConfigSize)
+	}
+	return bb, nil
+}
+
+// fetchContent fetches the remote config, recording the result in the metrics.
+func (rc *remoteConfig) fetchContent(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, rc.fetchTimeout())
+	defer cancel()
+
+	bb, err := rc.fetch(ctx)
+	if err != nil {
+		rc.fetchErrors.Inc()
+		return nil, fmt.Errorf("fetching remote config %s: %w", rc.opts.URL, err)
+	}
+	rc.lastFetch.SetToCurrentTime()
+	return bb, nil
+}
+
+// fetchTimeout returns the timeout for a single fetch, which is bounded by the
+// poll frequency so that polls don't pile up.
+func (rc *remoteConfig) fetchTimeout() time.Duration {
+	const maxTimeout = 30 * time.Second
+	if rc.opts.PollFrequency > 0 && rc.opts.PollFrequency < maxTimeout {
+		return rc.opts.PollFrequency
+	}
+	return maxTimeout
+}
+
+// Source returns the source of the remote config to load. The content
+// fetched by the last poll is used if it's available; otherwise the config is
+// fetched.
+//
+// If the config can't be fetched or parsed and FallbackToLastGood is set, the
+// last good config is returned instead.
+func (rc *remoteConfig) Source(ctx context.Context) (*flow.Source, error) {
+	rc.mut.Lock()
+	bb := rc.pending
+	rc.pending = nil
+	rc.mut.Unlock()
+
+	var err error
+	if bb == nil {
+		bb, err = rc.fetchContent(ctx)
+	}
+
+	var source *flow.Source
+	if err == nil {
+		source, err = flow.ParseSource(rc.opts.URL, bb)
+	}
+	if err != nil {
+		return rc.Fallback(err)
+	}
+
+	rc.mut.Lock()
+	rc.candidate = bb
+	rc.mut.Unlock()
+	return source, nil
+}
+
+// Peek fetches and parses the remote config without marking it as the config
+// being loaded. It's used to validate the remote config.
+func (rc *remoteConfig) Peek(ctx context.Context) (*flow.Source, error) {
+	bb, err := rc.fetchContent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return flow.ParseSource(rc.opts.URL, bb)
+}
+
+// Fallback returns the source of the last good config after loading the
+// remote config failed with err. err is returned if there is no last good
+// config or FallbackToLastGood isn't set.
+func (rc *remoteConfig) Fallback(err error) (*flow.Source, error) {
+	if !rc.opts.FallbackToLastGood {
+		return nil, err
+	}
+
+	bb := rc.loadLastGood()
+	if bb == nil {
+		return nil, err
+	}
+
+	source, parseErr := flow.ParseSource(rc.opts.URL, bb)
+	if parseErr != nil {
+		return nil, errors.Join(err, fmt.Errorf("parsing last good config: %w", parseErr))
+	}
+
+	level.Warn(rc.log).Log("msg", "falling back to the last good config", "url", rc.opts.URL, "err", err)
+	rc.fallbackLoads.Inc()
+
+	rc.mut.Lock()
+	rc.candidate = bb
+	rc.mut.Unlock()
+	return source, nil
+}
+
+// loadLastGood returns the last good config, reading it from the on-disk
+// cache if no config was loaded yet.
+func (rc *remoteConfig) loadLastGood() []byte {
+	rc.mut.Lock()
+	defer rc.mut.Unlock()
+
+	if rc.lastGood == nil && !rc.lastGoodChecked {
+		rc.lastGoodChecked = true
+		bb, err := os.ReadFile(rc.cachePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			level.Warn(rc.log).Log("msg", "failed to read last good config", "path", rc.cachePath, "err", err)
+		}
+		if err == nil {
+			rc.lastGood = bb
+		}
+	}
+	return rc.lastGood
+}
+
+// Loaded marks the config returned by the last call to Source as loaded
+// successfully, caching it as the last good config.
+func (rc *remoteConfig) Loaded() {
+	rc.mut.Lock()
+	defer rc.mut.Unlock()
+
+	if rc.candidate == nil || bytes.Equal(rc.candidate, rc.lastGood) {
+		return
+	}
+	rc.lastGood = rc.candidate
+
+	if err := os.MkdirAll(filepath.Dir(rc.cachePath), 0770); err != nil {
+		level.Warn(rc.log).Log("msg", "failed to cache last good config", "path", rc.cachePath, "err", err)
+		return
+	}
+	if err := os.WriteFile(rc.cachePath, rc.lastGood, 0660); err != nil {
+		level.Warn(rc.log).Log("msg", "failed to cache last good config", "path", rc.cachePath, "err", err)
+	}
+}
+
+// Run polls the remote config until ctx is canceled, calling reload whenever
+// the content of the config changed since the last good config.
+func (rc *remoteConfig) Run(ctx context.Context, reload func() error) {
+	if rc.opts.PollFrequency <= 0 {
+		return
+	}
+
+	t := time.NewTicker(rc.opts.PollFrequency)
+	defer t.Stop()
+
+	var lastFailed [sha256.Size]byte
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		bb, err := rc.fetchContent(ctx)
+		if err != nil {
+			level.Error(rc.log).Log("msg", "failed to poll remote config", "err", err)
+			continue
+		}
+
+		// Skip configs which are already loaded, and configs which failed
+		// to load and didn't change since.
+		rc.mut.Lock()
+		unchanged := bytes.Equal(bb, rc.lastGood)
+		rc.mut.Unlock()
+		sum := sha256.Sum256(bb)
+		if unchanged || sum == lastFailed {
+			continue
+		}
+
+		rc.mut.Lock()
+		rc.pending = bb
+		rc.mut.Unlock()
+
+		err = reload()
+
+		// The last good config may have been loaded instead of the fetched
+		// one, in which case the fetched config also failed.
+		rc.mut.Lock()
+		loaded := bytes.Equal(bb, rc.lastGood)
+		rc.mut.Unlock()
+
+		switch {
+		case err != nil:
+			lastFailed = sum
+			level.Error(rc.log).Log("msg", "failed to reload remote config", "err", err)
+		case !loaded:
+			lastFailed = sum
+			level.Error(rc.log).Log("msg", "failed to load remote config, kept the last good config")
+		default:
+			lastFailed = [sha256.Size]byte{}
+			level.Info(rc.log).Log("msg", "remote config reloaded")
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package flowmode
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteConfig(t *testing.T) {
+	var (
+		mut     sync.Mutex
+		content = `logging { level = "debug" }`
+		fail    bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+		if fail || r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	opts := remoteConfigOptions{
+		URL:                srv.URL,
+		Headers:            map[string]string{"Authorization": "Bearer token"},
+		FallbackToLastGood: true,
+		StoragePath:        t.TempDir(),
+	}
+	rc, err := newRemoteConfig(log.NewNopLogger(), nil, opts)
+	require.NoError(t, err)
+
+	source, err := rc.Source(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []byte(content), source.RawConfigs()[srv.URL])
+	rc.Loaded()
+
+	// A new poller falls back to the last good config cached on disk when
+	// the remote config can't be fetched.
+	mut.Lock()
+	fail = true
+	mut.Unlock()
+
+	rc, err = newRemoteConfig(log.NewNopLogger(), nil, opts)
+	require.NoError(t, err)
+	source, err = rc.Source(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []byte(content), source.RawConfigs()[srv.URL])
+
+	// Without the fallback, the error is returned.
+	opts.FallbackToLastGood = false
+	rc, err = newRemoteConfig(log.NewNopLogger(), nil, opts)
+	require.NoError(t, err)
+	_, err = rc.Source(context.Background())
+	require.ErrorContains(t, err, "503 Service Unavailable")
+}
+
+func TestRemoteConfig_InvalidURL(t *testing.T) {
+	_, err := newRemoteConfig(log.NewNopLogger(), nil, remoteConfigOptions{URL: "ftp://example.com/config.river"})
+	require.EqualError(t, err, `unsupported remote config URL scheme "ftp": expected http, https, or s3`)
+
+	_, err = newRemoteConfig(log.NewNopLogger(), nil, remoteConfigOptions{URL: "s3://bucket"})
+	require.EqualError(t, err, `invalid remote config URL "s3://bucket": expected s3://BUCKET/KEY`)
+}
+
+func TestRemoteConfig_Run(t *testing.T) {
+	var (
+		mut     sync.Mutex
+		content = `logging { level = "debug" }`
+	)
+	setContent := func(c string) {
+		mut.Lock()
+		defer mut.Unlock()
+		content = c
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+		_, _ = w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	rc, err := newRemoteConfig(log.NewNopLogger(), nil, remoteConfigOptions{
+		URL:                srv.URL,
+		PollFrequency:      10 * time.Millisecond,
+		FallbackToLastGood: true,
+		StoragePath:        t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	_, err = rc.Source(context.Background())
+	require.NoError(t, err)
+	rc.Loaded()
+
+	// reload loads the config like the run command does, recording which
+	// config was loaded.
+	var (
+		loadedMut sync.Mutex
+		loaded    []string
+	)
+	reload := func() error {
+		source, err := rc.Source(context.Background())
+		if err != nil {
+			return err
+		}
+		rc.Loaded()
+
+		loadedMut.Lock()
+		defer loadedMut.Unlock()
+		loaded = append(loaded, string(source.RawConfigs()[srv.URL]))
+		return nil
+	}
+	loadedConfigs := func() []string {
+		loadedMut.Lock()
+		defer loadedMut.Unlock()
+		return append([]string(nil), loaded...)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rc.Run(ctx, reload)
+
+	// Unchanged configs aren't reloaded.
+	time.Sleep(100 * time.Millisecond)
+	require.Empty(t, loadedConfigs())
+
+	// Changed configs are reloaded once.
+	setContent(`logging { level = "info" }`)
+	require.Eventually(t, func() bool {
+		return len(loadedConfigs()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{`logging { level = "info" }`}, loadedConfigs())
+
+	// Invalid configs fall back to the last good config, and aren't tried
+	// again until they change.
+	setContent(`logging {`)
+	require.Eventually(t, func() bool {
+		return len(loadedConfigs()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, []string{`logging { level = "info" }`, `logging { level = "info" }`}, loadedConfigs())
+}
+
+func TestRemoteConfig_HeadersFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-Scope-OrgID") != "tenant" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`logging { level = "debug" }`))
+	}))
+	defer srv.Close()
+
+	headersFile := filepath.Join(t.TempDir(), "headers")
+	require.NoError(t, os.WriteFile(headersFile, []byte("# Credentials\nAuthorization: Bearer token\n\n"), 0600))
+
+	rc, err := newRemoteConfig(log.NewNopLogger(), nil, remoteConfigOptions{
+		URL:         srv.URL,
+		Headers:     map[string]string{"X-Scope-OrgID": "tenant", "Authorization": "Bearer flag"},
+		HeadersFile: headersFile,
+		StoragePath: t.TempDir(),
+	})
+	require.NoError(t, err)
+	_, err = rc.Peek(context.Background())
+	require.NoError(t, err)
+
+	// The file is read again on every fetch.
+	require.NoError(t, os.WriteFile(headersFile, []byte("Authorization\n"), 0600))
+	_, err = rc.Peek(context.Background())
+	require.ErrorContains(t, err, "line 1: expected Name: value")
+}
+
+func TestRemoteConfig_TooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("#", maxRemoteConfigSize+1)))
+	}))
+	defer srv.Close()
+
+	rc, err := newRemoteConfig(log.NewNopLogger(), nil, remoteConfigOptions{URL: srv.URL, StoragePath: t.TempDir()})
+	require.NoError(t, err)
+	_, err = rc.Peek(context.Background())
+	require.ErrorContains(t, err, "remote config is larger than 16777216 bytes")
+}
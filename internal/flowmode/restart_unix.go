@@ -0,0 +1,73 @@
+//go:build !windows
+
+package flowmode
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// restartInto replaces the running executable with the binary at path and
+// re-executes the process with the same arguments and environment. If the
+// running executable can't be replaced, the binary at path is executed
+// directly.
+func restartInto(path string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return syscall.Exec(path, os.Args, os.Environ())
+	}
+	return restartExecutable(exe, path, syscall.Exec)
+}
+
+// restartExecutable replaces exe with the binary at path and runs it with
+// execFn. The previous binary is kept next to exe with a ".previous" suffix.
+// If execFn fails, the previous binary is restored so that the next start of
+// the process runs a working version.
+func restartExecutable(exe, path string, execFn func(argv0 string, argv []string, envv []string) error) error {
+	backup := exe + ".previous"
+	if err := replaceFile(exe, backup); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to back up %s, running %s directly: %s\n", exe, path, err)
+		return execFn(path, os.Args, os.Environ())
+	}
+	if err := replaceFile(path, exe); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to replace %s, running %s directly: %s\n", exe, path, err)
+		return execFn(path, os.Args, os.Environ())
+	}
+
+	// execFn only returns if the updated binary couldn't be run.
+	execErr := execFn(exe, os.Args, os.Environ())
+	if err := replaceFile(backup, exe); err != nil {
+		return fmt.Errorf("running updated binary: %w; restoring %s from %s failed: %s", execErr, exe, backup, err)
+	}
+	return fmt.Errorf("running updated binary: %w; restored the previous binary", execErr)
+}
+
+// replaceFile atomically replaces dst with a copy of src.
+func replaceFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.new")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
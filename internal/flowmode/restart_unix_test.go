@@ -0,0 +1,43 @@
+//go:build !windows
+
+package flowmode
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestartExecutable(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "grafana-agent")
+	staged := filepath.Join(dir, "staged")
+	require.NoError(t, os.WriteFile(exe, []byte("old"), 0755))
+	require.NoError(t, os.WriteFile(staged, []byte("new"), 0755))
+
+	var ran string
+	err := restartExecutable(exe, staged, func(argv0 string, _ []string, _ []string) error {
+		ran = argv0
+
+		// The updated binary is in place while it's being run.
+		contents, err := os.ReadFile(exe)
+		require.NoError(t, err)
+		require.Equal(t, "new", string(contents))
+		return syscall.ENOEXEC
+	})
+	require.ErrorIs(t, err, syscall.ENOEXEC)
+	require.Equal(t, exe, ran)
+
+	// The previous binary is restored after the failed exec, and kept as a
+	// backup.
+	contents, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	require.Equal(t, "old", string(contents))
+
+	contents, err = os.ReadFile(exe + ".previous")
+	require.NoError(t, err)
+	require.Equal(t, "old", string(contents))
+}
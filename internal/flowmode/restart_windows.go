@@ -0,0 +1,9 @@
+package flowmode
+
+import "fmt"
+
+// restartInto is not supported on Windows, where a running executable can't
+// be replaced.
+func restartInto(path string) error {
+	return fmt.Errorf("restarting into %s is not supported on Windows", path)
+}
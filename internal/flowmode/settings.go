@@ -0,0 +1,51 @@
+package flowmode
+
+import (
+	"strings"
+
+	"github.com/grafana/agent/internal/service/settings"
+)
+
+// loadSettings reads the agent_settings block from the config at configPath.
+// Errors loading the config itself are ignored here, as they're reported by
+// the initial load.
+func (fr *flowRun) loadSettings(configPath string) (settings.Arguments, error) {
+	var args settings.Arguments
+
+	source, err := loadFlowSource(configPath, fr.configFormat, fr.configBypassConversionErrors, fr.configExtraArgs, fr.configDecryptionKey)
+	if err != nil {
+		return args, nil
+	}
+	_, err = source.DecodeBlock(settings.ServiceName, &args)
+	return args, err
+}
+
+// applySettings overrides the settings of fr with those set in an
+// agent_settings block. Flags which were explicitly set on the command line
+// take precedence over the block.
+func (fr *flowRun) applySettings(args settings.Arguments) {
+	set := func(flag string, isSet bool, apply func()) {
+		if !isSet || (fr.flags != nil && fr.flags.Changed(flag)) {
+			return
+		}
+		apply()
+	}
+
+	set("server.http.memory-addr", args.MemoryAddr != "", func() { fr.inMemoryAddr = args.MemoryAddr })
+	set("server.http.ui-path-prefix", args.UIPathPrefix != "", func() { fr.uiPrefix = args.UIPathPrefix })
+	set("storage.path", args.StoragePath != "", func() { fr.storagePath = args.StoragePath })
+
+	c := args.Cluster
+	if c == nil {
+		return
+	}
+	set("cluster.enabled", true, func() { fr.clusterEnabled = c.Enabled })
+	set("cluster.node-name", c.NodeName != "", func() { fr.clusterNodeName = c.NodeName })
+	set("cluster.advertise-address", c.AdvertiseAddress != "", func() { fr.clusterAdvAddr = c.AdvertiseAddress })
+	set("cluster.advertise-interfaces", len(c.AdvertiseInterfaces) > 0, func() { fr.clusterAdvInterfaces = c.AdvertiseInterfaces })
+	set("cluster.join-addresses", len(c.JoinAddresses) > 0, func() { fr.clusterJoinAddr = strings.Join(c.JoinAddresses, ",") })
+	set("cluster.discover-peers", c.DiscoverPeers != "", func() { fr.clusterDiscoverPeers = c.DiscoverPeers })
+	set("cluster.rejoin-interval", c.RejoinInterval != 0, func() { fr.clusterRejoinInterval = c.RejoinInterval })
+	set("cluster.max-join-peers", c.MaxJoinPeers != 0, func() { fr.ClusterMaxJoinPeers = c.MaxJoinPeers })
+	set("cluster.name", c.Name != "", func() { fr.clusterName = c.Name })
+}
@@ -0,0 +1,41 @@
+package flowmode
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// tenantConfig is the config of an isolated tenant passed with the --config
+// flag.
+type tenantConfig struct {
+	Name string
+	Path string
+}
+
+// parseTenantConfigs parses values of the --config flag, which have the form
+// [NAME=]PATH. When NAME is omitted, the base name of PATH without its
+// extension is used.
+func parseTenantConfigs(values []string) ([]tenantConfig, error) {
+	var (
+		res  = make([]tenantConfig, 0, len(values))
+		seen = make(map[string]string, len(values))
+	)
+	for _, value := range values {
+		name, path, ok := strings.Cut(value, "=")
+		if !ok {
+			path = value
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if path == "" {
+			return nil, fmt.Errorf("--config %q: path must not be empty", value)
+		}
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("--config %q: tenant %q is already used by %q; set a name with NAME=PATH", value, name, other)
+		}
+		seen[name] = value
+
+		res = append(res, tenantConfig{Name: name, Path: path})
+	}
+	return res, nil
+}
@@ -0,0 +1,25 @@
+package flowmode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTenantConfigs(t *testing.T) {
+	configs, err := parseTenantConfigs([]string{
+		"/etc/agent/teamA.river",
+		"teamB=/etc/agent/b",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []tenantConfig{
+		{Name: "teamA", Path: "/etc/agent/teamA.river"},
+		{Name: "teamB", Path: "/etc/agent/b"},
+	}, configs)
+
+	_, err = parseTenantConfigs([]string{"/a/team.river", "/b/team.river"})
+	require.EqualError(t, err, `--config "/b/team.river": tenant "team" is already used by "/a/team.river"; set a name with NAME=PATH`)
+
+	_, err = parseTenantConfigs([]string{"team="})
+	require.EqualError(t, err, `--config "team=": path must not be empty`)
+}
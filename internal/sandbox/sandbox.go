@@ -0,0 +1,286 @@
+// Package sandbox implements an opt-in self-sandboxing mode which restricts
+// the agent process after it has started.
+//
+// On Linux, the sandbox is made of two layers:
+//
+//   - A Landlock ruleset restricting filesystem access to the data directory,
+//     the configuration path, and an explicit list of extra paths. On kernels
+//     supporting Landlock ABI version 4 or newer, the ruleset also restricts
+//     which TCP ports may be bound. Outbound connections aren't restricted,
+//     since components connect to targets on arbitrary ports.
+//   - A seccomp filter denying system calls the agent never needs, such as
+//     mount or kexec_load, and sockets of address families it never uses,
+//     such as raw packet sockets.
+//
+// Components which need extra filesystem access, system calls, or socket
+// families declare them in their registration through
+// [component.SandboxExceptions]. Exceptions are only granted for components
+// which are running at the time the sandbox is applied; use [Options.Missing]
+// to check whether components started afterwards are covered.
+//
+// The sandbox can not be lifted once applied; it applies to the whole process
+// and every thread created afterwards. Landlock rules are applied to every
+// thread through [syscall.AllThreadsSyscall], which is unavailable in
+// binaries built with cgo, so the sandbox is only supported in binaries built
+// with CGO_ENABLED=0.
+package sandbox
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component"
+)
+
+// Options configures the sandbox.
+type Options struct {
+	Logger log.Logger
+
+	// ReadPaths is the set of paths the agent may read from. Directories grant
+	// access to everything beneath them.
+	ReadPaths []string
+
+	// WritePaths is the set of paths the agent may read from and write to.
+	// Directories grant access to everything beneath them.
+	WritePaths []string
+
+	// AllowedSyscalls are system calls which would otherwise be denied by the
+	// seccomp filter.
+	AllowedSyscalls []string
+
+	// SocketFamilies are the socket address families, such as "inet" or
+	// "packet", the agent may create sockets for.
+	SocketFamilies []string
+
+	// BindPorts are the TCP ports the agent may bind to. Listeners which were
+	// bound before the sandbox was applied keep working. Connecting to TCP
+	// ports is never restricted.
+	BindPorts []uint16
+}
+
+// DefaultReadPaths are system paths which must stay readable for the agent
+// and most components to function, such as procfs, sysfs, and TLS
+// certificates.
+var DefaultReadPaths = []string{
+	"/proc",
+	"/sys",
+	"/etc",
+	"/usr/share/zoneinfo",
+	"/usr/share/ca-certificates",
+	// Credentials of the service account of the pod, used by every
+	// component talking to the Kubernetes API from within a cluster.
+	"/var/run/secrets/kubernetes.io/serviceaccount",
+	"/dev/urandom",
+}
+
+// DefaultWritePaths are paths which remain writable in addition to the
+// storage path.
+var DefaultWritePaths = []string{
+	"/tmp",
+	// os/exec opens /dev/null for writing for the standard streams of
+	// subprocesses which aren't redirected.
+	"/dev/null",
+}
+
+// DefaultSocketFamilies are the socket address families the agent may always
+// create sockets for.
+var DefaultSocketFamilies = []string{
+	"unix",
+	"inet",
+	"inet6",
+	"netlink",
+}
+
+// DeniedSyscalls are the system calls blocked by the seccomp filter unless a
+// component declares them in its exceptions. Denied calls fail with EPERM.
+var DeniedSyscalls = []string{
+	"add_key",
+	"bpf",
+	"chroot",
+	"delete_module",
+	"finit_module",
+	"init_module",
+	"kexec_file_load",
+	"kexec_load",
+	"keyctl",
+	"mount",
+	"perf_event_open",
+	"pivot_root",
+	"process_vm_readv",
+	"process_vm_writev",
+	"ptrace",
+	"reboot",
+	"request_key",
+	"setns",
+	"swapoff",
+	"swapon",
+	"umount2",
+	"unshare",
+}
+
+// WithExceptions returns a copy of opts extended with the sandbox exceptions
+// of the provided components. Paths which depend on the arguments of a
+// component, including the files of its arguments named like *_file, are
+// only added if the Arguments field of its info is set.
+func (opts Options) WithExceptions(components []*component.Info) Options {
+	// Copy the slices so that appending never modifies the caller's options.
+	opts.ReadPaths = append([]string(nil), opts.ReadPaths...)
+	opts.WritePaths = append([]string(nil), opts.WritePaths...)
+	opts.AllowedSyscalls = append([]string(nil), opts.AllowedSyscalls...)
+	opts.SocketFamilies = append([]string(nil), opts.SocketFamilies...)
+
+	seen := make(map[string]struct{})
+
+	for _, c := range components {
+		reg, ok := component.Get(c.ComponentName)
+		if !ok {
+			continue
+		}
+		if c.Arguments != nil {
+			opts.ReadPaths = append(opts.ReadPaths, argumentFiles(c.Arguments)...)
+			if reg.Sandbox.ArgumentReadPaths != nil {
+				opts.ReadPaths = append(opts.ReadPaths, reg.Sandbox.ArgumentReadPaths(c.Arguments)...)
+			}
+			if reg.Sandbox.ArgumentWritePaths != nil {
+				opts.WritePaths = append(opts.WritePaths, reg.Sandbox.ArgumentWritePaths(c.Arguments)...)
+			}
+		}
+
+		if _, ok := seen[c.ComponentName]; ok {
+			continue
+		}
+		seen[c.ComponentName] = struct{}{}
+
+		opts.ReadPaths = append(opts.ReadPaths, reg.Sandbox.ReadPaths...)
+		opts.WritePaths = append(opts.WritePaths, reg.Sandbox.WritePaths...)
+		opts.AllowedSyscalls = append(opts.AllowedSyscalls, reg.Sandbox.Syscalls...)
+		opts.SocketFamilies = append(opts.SocketFamilies, reg.Sandbox.SocketFamilies...)
+	}
+	return opts
+}
+
+// Missing returns a description of every path, system call, and socket family
+// in required which isn't granted by opts. A path is granted if it's equal to
+// or beneath a granted path. Missing returns nil when opts covers required.
+func (opts Options) Missing(required Options) []string {
+	var missing []string
+
+	for _, p := range required.ReadPaths {
+		if !beneathAny(p, opts.ReadPaths) && !beneathAny(p, opts.WritePaths) {
+			missing = append(missing, fmt.Sprintf("read access to %s", p))
+		}
+	}
+	for _, p := range required.WritePaths {
+		if !beneathAny(p, opts.WritePaths) {
+			missing = append(missing, fmt.Sprintf("write access to %s", p))
+		}
+	}
+	for _, name := range required.AllowedSyscalls {
+		if !contains(opts.AllowedSyscalls, name) && contains(DeniedSyscalls, name) {
+			missing = append(missing, fmt.Sprintf("system call %s", name))
+		}
+	}
+	for _, name := range required.SocketFamilies {
+		if !contains(opts.SocketFamilies, name) {
+			missing = append(missing, fmt.Sprintf("socket family %s", name))
+		}
+	}
+
+	sort.Strings(missing)
+	return dedupe(missing)
+}
+
+// argumentFiles returns the non-empty values of the string arguments named
+// like *_file in args, such as the ca_file of TLS settings or the
+// password_file of basic authentication, including those of nested blocks.
+func argumentFiles(args component.Arguments) []string {
+	var files []string
+
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Pointer:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < v.Len(); i++ {
+				walk(v.Index(i))
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				field := v.Type().Field(i)
+				tag, ok := field.Tag.Lookup("river")
+				if !ok || !field.IsExported() {
+					continue
+				}
+				name, _, _ := strings.Cut(tag, ",")
+				fv := v.Field(i)
+				if fv.Kind() == reflect.String && strings.HasSuffix(name, "_file") {
+					if path := fv.String(); path != "" {
+						files = append(files, path)
+					}
+					continue
+				}
+				walk(fv)
+			}
+		}
+	}
+	walk(reflect.ValueOf(args))
+	return files
+}
+
+func beneathAny(path string, parents []string) bool {
+	path = filepath.Clean(path)
+	for _, parent := range parents {
+		parent = filepath.Clean(parent)
+		if path == parent || parent == "/" || strings.HasPrefix(path, parent+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, name string) bool {
+	for _, v := range list {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupe removes consecutive duplicates from a sorted slice.
+func dedupe(list []string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	res := list[:1]
+	for _, v := range list[1:] {
+		if v != res[len(res)-1] {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// deniedSyscalls returns DeniedSyscalls minus the allowed ones.
+func deniedSyscalls(allowed []string) []string {
+	allowSet := make(map[string]struct{}, len(allowed))
+	for _, name := range allowed {
+		allowSet[name] = struct{}{}
+	}
+
+	res := make([]string, 0, len(DeniedSyscalls))
+	for _, name := range DeniedSyscalls {
+		if _, ok := allowSet[name]; ok {
+			continue
+		}
+		res = append(res, name)
+	}
+	return res
+}
@@ -0,0 +1,17 @@
+//go:build linux && (arm64 || amd64) && cgo
+
+package sandbox
+
+import "errors"
+
+// errCgo is returned in binaries built with cgo, where Landlock rules can't
+// be applied to every thread of the process.
+var errCgo = errors.New("sandboxing requires a binary built with CGO_ENABLED=0")
+
+// Supported returns an error if the sandbox can't be applied by the running
+// binary.
+func Supported() error { return errCgo }
+
+// Apply applies the sandbox to the current process. It always fails in
+// binaries built with cgo.
+func Apply(opts Options) error { return errCgo }
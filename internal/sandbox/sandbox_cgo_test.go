@@ -0,0 +1,14 @@
+//go:build linux && (arm64 || amd64) && cgo
+
+package sandbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply_Cgo(t *testing.T) {
+	require.ErrorContains(t, Supported(), "CGO_ENABLED=0")
+	require.ErrorIs(t, Apply(Options{}), errCgo)
+}
@@ -0,0 +1,327 @@
+//go:build linux && (arm64 || amd64) && !cgo
+
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"golang.org/x/sys/unix"
+)
+
+// seccomp constants from linux/seccomp.h.
+const (
+	seccompSetModeFilter   = 1
+	seccompFilterFlagTsync = 1
+
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetAllow       = 0x7fff0000
+
+	// Offsets of fields in struct seccomp_data. The offset of the first
+	// argument is the offset of its lower 32 bits on little-endian
+	// architectures.
+	seccompDataNrOffset   = 0
+	seccompDataArchOffset = 4
+	seccompDataArg0Offset = 16
+
+	// x32SyscallBit is set for x32 ABI system calls on amd64, which share the
+	// same audit architecture as native calls.
+	x32SyscallBit = 0x40000000
+)
+
+// syscallNumbers maps the names in DeniedSyscalls to their numbers.
+var syscallNumbers = map[string]uint32{
+	"add_key":           unix.SYS_ADD_KEY,
+	"bpf":               unix.SYS_BPF,
+	"chroot":            unix.SYS_CHROOT,
+	"delete_module":     unix.SYS_DELETE_MODULE,
+	"finit_module":      unix.SYS_FINIT_MODULE,
+	"init_module":       unix.SYS_INIT_MODULE,
+	"kexec_file_load":   unix.SYS_KEXEC_FILE_LOAD,
+	"kexec_load":        unix.SYS_KEXEC_LOAD,
+	"keyctl":            unix.SYS_KEYCTL,
+	"mount":             unix.SYS_MOUNT,
+	"perf_event_open":   unix.SYS_PERF_EVENT_OPEN,
+	"pivot_root":        unix.SYS_PIVOT_ROOT,
+	"process_vm_readv":  unix.SYS_PROCESS_VM_READV,
+	"process_vm_writev": unix.SYS_PROCESS_VM_WRITEV,
+	"ptrace":            unix.SYS_PTRACE,
+	"reboot":            unix.SYS_REBOOT,
+	"request_key":       unix.SYS_REQUEST_KEY,
+	"setns":             unix.SYS_SETNS,
+	"swapoff":           unix.SYS_SWAPOFF,
+	"swapon":            unix.SYS_SWAPON,
+	"umount2":           unix.SYS_UMOUNT2,
+	"unshare":           unix.SYS_UNSHARE,
+}
+
+// socketFamilies maps the names used in Options.SocketFamilies to their
+// address families.
+var socketFamilies = map[string]uint32{
+	"unix":    unix.AF_UNIX,
+	"inet":    unix.AF_INET,
+	"inet6":   unix.AF_INET6,
+	"netlink": unix.AF_NETLINK,
+	"packet":  unix.AF_PACKET,
+	"vsock":   unix.AF_VSOCK,
+}
+
+// Landlock network constants from linux/landlock.h, which are not yet
+// exposed by golang.org/x/sys.
+const (
+	landlockAccessNetBindTCP = 1 << 0
+	landlockRuleNetPort      = 2
+)
+
+// landlockNetPortAttr mirrors struct landlock_net_port_attr.
+type landlockNetPortAttr struct {
+	allowedAccess uint64
+	port          uint64
+}
+
+// Landlock access rights, grouped by the ABI version which introduced them.
+const (
+	landlockAccessFSv1 = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+	landlockAccessFSv2 = landlockAccessFSv1 | unix.LANDLOCK_ACCESS_FS_REFER
+	landlockAccessFSv3 = landlockAccessFSv2 | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+
+	landlockAccessRead = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR
+
+	// landlockAccessFile is the set of rights which may be granted on a
+	// regular file rather than a directory.
+	landlockAccessFile = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE
+)
+
+// Supported returns an error if the sandbox can't be applied by the running
+// binary.
+func Supported() error { return nil }
+
+// Apply applies the sandbox to the current process. The filesystem
+// restrictions are skipped with a warning if the running kernel does not
+// support Landlock, and TCP ports aren't restricted if it doesn't support
+// Landlock ABI version 4.
+func Apply(opts Options) error {
+	// Both Landlock and seccomp require no_new_privs to be set when the
+	// process is unprivileged.
+	if err := allThreads(unix.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0); err != nil {
+		return fmt.Errorf("setting no_new_privs: %w", err)
+	}
+
+	if err := applyLandlock(opts); err != nil {
+		return fmt.Errorf("applying landlock rules: %w", err)
+	}
+	if err := applySeccomp(opts); err != nil {
+		return fmt.Errorf("applying seccomp filter: %w", err)
+	}
+	return nil
+}
+
+func applyLandlock(opts Options) error {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		if errors.Is(errno, unix.ENOSYS) || errors.Is(errno, unix.EOPNOTSUPP) {
+			level.Warn(opts.Logger).Log("msg", "kernel does not support landlock; filesystem access will not be restricted")
+			return nil
+		}
+		return fmt.Errorf("querying landlock ABI version: %w", errno)
+	}
+
+	var handled uint64
+	switch {
+	case abi >= 3:
+		handled = landlockAccessFSv3
+	case abi == 2:
+		handled = landlockAccessFSv2
+	default:
+		handled = landlockAccessFSv1
+	}
+
+	// Only binding is handled, so connecting to any TCP port stays allowed:
+	// scrape targets and other endpoints may listen on any port.
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	if abi >= 4 {
+		attr.Access_net = landlockAccessNetBindTCP
+	} else {
+		level.Warn(opts.Logger).Log("msg", "kernel does not support landlock network rules; binding TCP ports will not be restricted", "abi", abi)
+	}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("creating ruleset: %w", errno)
+	}
+	defer unix.Close(int(fd))
+
+	for _, p := range opts.ReadPaths {
+		if err := addPathRule(opts, int(fd), p, landlockAccessRead&handled); err != nil {
+			return err
+		}
+	}
+	for _, p := range opts.WritePaths {
+		if err := addPathRule(opts, int(fd), p, handled); err != nil {
+			return err
+		}
+	}
+
+	if attr.Access_net != 0 {
+		for _, port := range opts.BindPorts {
+			rule := landlockNetPortAttr{
+				allowedAccess: landlockAccessNetBindTCP,
+				port:          uint64(port),
+			}
+			_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, fd, landlockRuleNetPort, uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+			if errno != 0 {
+				return fmt.Errorf("adding rule for port %d: %w", port, errno)
+			}
+		}
+	}
+
+	if err := allThreads(unix.SYS_LANDLOCK_RESTRICT_SELF, fd, 0, 0); err != nil {
+		return err
+	}
+
+	level.Info(opts.Logger).Log("msg", "landlock rules applied", "abi", abi)
+	return nil
+}
+
+func addPathRule(opts Options, rulesetFD int, path string, access uint64) error {
+	fi, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		level.Debug(opts.Logger).Log("msg", "skipping sandbox rule for missing path", "path", path)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("checking path %q: %w", path, err)
+	}
+	if !fi.IsDir() {
+		access &= landlockAccessFile
+	}
+
+	pathFD, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("opening path %q: %w", path, err)
+	}
+	defer unix.Close(pathFD)
+
+	rule := unix.LandlockPathBeneathAttr{
+		Allowed_access: access,
+		Parent_fd:      int32(pathFD),
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFD), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&rule)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("adding rule for path %q: %w", path, errno)
+	}
+	return nil
+}
+
+func applySeccomp(opts Options) error {
+	var arch uint32
+	switch runtime.GOARCH {
+	case "amd64":
+		arch = unix.AUDIT_ARCH_X86_64
+	case "arm64":
+		arch = unix.AUDIT_ARCH_AARCH64
+	}
+
+	denied := deniedSyscalls(opts.AllowedSyscalls)
+
+	var families []uint32
+	for _, name := range opts.SocketFamilies {
+		family, ok := socketFamilies[name]
+		if !ok {
+			return fmt.Errorf("unknown socket family %q", name)
+		}
+		families = append(families, family)
+	}
+
+	filter := []unix.SockFilter{
+		// Kill the process if a system call is made for a foreign architecture.
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArchOffset),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, arch, 1, 0),
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess),
+
+		// Load the system call number and deny x32 calls.
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataNrOffset),
+		jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32SyscallBit, 0, 1),
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.EPERM)),
+	}
+	for _, name := range denied {
+		nr, ok := syscallNumbers[name]
+		if !ok {
+			return fmt.Errorf("unknown system call %q", name)
+		}
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, nr, 0, 1),
+			stmt(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.EPERM)),
+		)
+	}
+
+	// Deny sockets of any family which wasn't allowed. The checks jump to the
+	// final instruction allowing the call when the family matches.
+	filter = append(filter,
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_SOCKET, 0, uint8(len(families)+2)),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArg0Offset),
+	)
+	for i, family := range families {
+		filter = append(filter, jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, family, uint8(len(families)-i), 0))
+	}
+	filter = append(filter,
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.EAFNOSUPPORT)),
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetAllow),
+	)
+
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	// SECCOMP_FILTER_FLAG_TSYNC installs the filter on every thread of the
+	// process rather than only the calling one.
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompSetModeFilter, seccompFilterFlagTsync, uintptr(unsafe.Pointer(&prog)))
+	runtime.KeepAlive(filter)
+	if errno != 0 {
+		return errno
+	}
+
+	level.Info(opts.Logger).Log("msg", "seccomp filter applied", "denied_syscalls", len(denied), "socket_families", len(families))
+	return nil
+}
+
+func stmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func jump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
+
+// allThreads invokes a system call on every thread of the process, which is
+// required for calls which only affect the calling thread. It's only
+// available in binaries built without cgo.
+func allThreads(trap, a1, a2, a3 uintptr) error {
+	_, _, errno := syscall.AllThreadsSyscall(trap, a1, a2, a3)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
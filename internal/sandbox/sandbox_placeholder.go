@@ -0,0 +1,20 @@
+//go:build !(linux && (arm64 || amd64))
+
+package sandbox
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Supported returns an error if the sandbox can't be applied by the running
+// binary.
+func Supported() error {
+	return fmt.Errorf("sandboxing is not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+// Apply applies the sandbox to the current process. Sandboxing is only
+// supported on Linux for the amd64 and arm64 architectures.
+func Apply(opts Options) error {
+	return Supported()
+}
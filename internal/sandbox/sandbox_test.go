@@ -0,0 +1,141 @@
+package sandbox
+
+import (
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/stretchr/testify/require"
+)
+
+type testArguments struct {
+	Filename string
+}
+
+type clientArguments struct {
+	CAFile   string `river:"ca_file,attr,optional"`
+	Password string `river:"password,attr,optional"`
+}
+
+type fileArguments struct {
+	Name         string            `river:"name,attr"`
+	PasswordFile string            `river:"password_file,attr,optional"`
+	Client       *clientArguments  `river:"client,block,optional"`
+	Endpoints    []clientArguments `river:"endpoint,block,optional"`
+	Output       string            `river:"output,attr,optional"`
+}
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "sandbox_test.ebpf",
+		Stability: featuregate.StabilityExperimental,
+		Args:      testArguments{},
+		Sandbox: component.SandboxExceptions{
+			WritePaths:     []string{"/sys/fs/bpf"},
+			Syscalls:       []string{"bpf"},
+			SocketFamilies: []string{"packet"},
+		},
+	})
+	component.Register(component.Registration{
+		Name:      "sandbox_test.file",
+		Stability: featuregate.StabilityExperimental,
+		Args:      testArguments{},
+		Sandbox: component.SandboxExceptions{
+			ArgumentReadPaths: func(args component.Arguments) []string {
+				return []string{args.(testArguments).Filename}
+			},
+		},
+	})
+	component.Register(component.Registration{
+		Name:      "sandbox_test.write",
+		Stability: featuregate.StabilityExperimental,
+		Args:      fileArguments{},
+		Sandbox: component.SandboxExceptions{
+			ArgumentWritePaths: func(args component.Arguments) []string {
+				return []string{args.(fileArguments).Output}
+			},
+		},
+	})
+}
+
+func TestDeniedSyscalls(t *testing.T) {
+	denied := deniedSyscalls([]string{"bpf", "perf_event_open", "not_a_syscall"})
+	require.NotContains(t, denied, "bpf")
+	require.NotContains(t, denied, "perf_event_open")
+	require.Contains(t, denied, "ptrace")
+	require.Len(t, denied, len(DeniedSyscalls)-2)
+}
+
+func TestWithExceptions(t *testing.T) {
+	base := Options{
+		ReadPaths:      []string{"/etc"},
+		WritePaths:     []string{"/data"},
+		SocketFamilies: DefaultSocketFamilies,
+	}
+
+	opts := base.WithExceptions([]*component.Info{
+		{ComponentName: "sandbox_test.ebpf"},
+		{ComponentName: "sandbox_test.ebpf"},
+		{ComponentName: "sandbox_test.file", Arguments: testArguments{Filename: "/srv/a.txt"}},
+		{ComponentName: "sandbox_test.file", Arguments: testArguments{Filename: "/srv/b.txt"}},
+		{ComponentName: "sandbox_test.file"},
+		{ComponentName: "not.registered"},
+	})
+
+	require.Equal(t, []string{"/etc", "/srv/a.txt", "/srv/b.txt"}, opts.ReadPaths)
+	require.Equal(t, []string{"/data", "/sys/fs/bpf"}, opts.WritePaths)
+	require.Equal(t, []string{"bpf"}, opts.AllowedSyscalls)
+	require.Equal(t, append(append([]string{}, DefaultSocketFamilies...), "packet"), opts.SocketFamilies)
+
+	// The base options must not be modified.
+	require.Equal(t, []string{"/etc"}, base.ReadPaths)
+}
+
+func TestWithExceptions_ArgumentFiles(t *testing.T) {
+	opts := Options{}.WithExceptions([]*component.Info{
+		{ComponentName: "sandbox_test.write", Arguments: fileArguments{
+			Name:         "not_a_file",
+			PasswordFile: "/etc/agent/password",
+			Client:       &clientArguments{CAFile: "/srv/ca.pem", Password: "secret"},
+			Endpoints:    []clientArguments{{CAFile: "/srv/a.pem"}, {}},
+			Output:       "/srv/out",
+		}},
+	})
+
+	require.Equal(t, []string{"/etc/agent/password", "/srv/ca.pem", "/srv/a.pem"}, opts.ReadPaths)
+	require.Equal(t, []string{"/srv/out"}, opts.WritePaths)
+}
+
+func TestMissing(t *testing.T) {
+	applied := Options{
+		ReadPaths:       []string{"/etc", "/var/log"},
+		WritePaths:      []string{"/data"},
+		AllowedSyscalls: []string{"bpf"},
+		SocketFamilies:  DefaultSocketFamilies,
+	}
+
+	t.Run("covered", func(t *testing.T) {
+		require.Empty(t, applied.Missing(Options{
+			ReadPaths:       []string{"/etc/hosts", "/var/log", "/data/wal"},
+			WritePaths:      []string{"/data/wal"},
+			AllowedSyscalls: []string{"bpf", "not_denied"},
+			SocketFamilies:  []string{"inet"},
+		}))
+	})
+
+	t.Run("not covered", func(t *testing.T) {
+		missing := applied.Missing(Options{
+			ReadPaths:       []string{"/var/logs/app.log", "/srv/a.txt", "/srv/a.txt"},
+			WritePaths:      []string{"/etc/agent"},
+			AllowedSyscalls: []string{"perf_event_open"},
+			SocketFamilies:  []string{"packet"},
+		})
+		require.Equal(t, []string{
+			"read access to /srv/a.txt",
+			"read access to /var/logs/app.log",
+			"socket family packet",
+			"system call perf_event_open",
+			"write access to /etc/agent",
+		}, missing)
+	})
+}
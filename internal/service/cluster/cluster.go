@@ -68,6 +68,10 @@ type Options struct {
 	// Function to discover peers to join. If this function is nil or returns an
 	// empty slice, no peers will be joined.
 	DiscoverPeers func() ([]string, error)
+
+	// ConfigHash returns the hash of the currently loaded configuration, which
+	// is reported to the other nodes of the cluster. May be nil.
+	ConfigHash func() string
 }
 
 // Service is the cluster service.
@@ -76,14 +80,21 @@ type Service struct {
 	tracer trace.TracerProvider
 	opts   Options
 
-	sharder shard.Sharder
-	node    *ckit.Node
-	randGen *rand.Rand
+	sharder    shard.Sharder
+	node       *ckit.Node
+	httpClient *http.Client
+
+	// discoveryMut guards the peer discovery settings of opts, which can be
+	// changed at runtime, and randGen.
+	discoveryMut sync.Mutex
+	randGen      *rand.Rand
 }
 
 var (
-	_ service.Service             = (*Service)(nil)
-	_ http_service.ServiceHandler = (*Service)(nil)
+	_ service.Service                  = (*Service)(nil)
+	_ http_service.ServiceHandler      = (*Service)(nil)
+	_ http_service.ServicePathsHandler = (*Service)(nil)
+	_ http_service.AuthExempter        = (*Service)(nil)
 )
 
 // New returns a new, unstarted instance of the cluster service.
@@ -141,9 +152,10 @@ func New(opts Options) (*Service, error) {
 		tracer: t,
 		opts:   opts,
 
-		sharder: ckitConfig.Sharder,
-		node:    node,
-		randGen: rand.New(rand.NewSource(time.Now().UnixNano())),
+		sharder:    ckitConfig.Sharder,
+		node:       node,
+		httpClient: httpClient,
+		randGen:    rand.New(rand.NewSource(time.Now().UnixNano())),
 	}, nil
 }
 
@@ -173,14 +185,40 @@ func (s *Service) ServiceHandler(host service.Host) (base string, handler http.H
 	base, handler = s.node.Handler()
 
 	if !s.opts.EnableClustering {
-		handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-			http.Error(w, "clustering is disabled", http.StatusNotFound)
-		})
+		handler = clusteringDisabledHandler()
 	}
-
 	return base, handler
 }
 
+// ServicePaths implements [http_service.ServicePathsHandler]. The clustering
+// service serves the status of the local node to the other nodes of the
+// cluster at statusPath. The handler always returns 404 when clustering is
+// disabled.
+func (s *Service) ServicePaths(host service.Host) map[string]http.Handler {
+	handler := clusteringDisabledHandler()
+	if s.opts.EnableClustering {
+		handler = s.statusHandler(host)
+	}
+	return map[string]http.Handler{statusPath: handler}
+}
+
+func clusteringDisabledHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "clustering is disabled", http.StatusNotFound)
+	})
+}
+
+// AuthExemptPaths implements [http_service.AuthExempter]. The clustering
+// transport and the status of the node are exempt from authentication since
+// peers don't send credentials.
+func (s *Service) AuthExemptPaths() []string {
+	if !s.opts.EnableClustering {
+		return nil
+	}
+	base, _ := s.node.Handler()
+	return []string{base, statusPath}
+}
+
 // ChangeState changes the state of the service. If clustering is enabled,
 // ChangeState will block until the state change has been propagated to another
 // node; cancel the current context to stop waiting. ChangeState fails if the
@@ -296,7 +334,20 @@ func (s *Service) Run(ctx context.Context, host service.Host) error {
 	return nil
 }
 
+// UpdatePeerDiscovery changes how peers are discovered. The new settings are
+// used the next time the node rejoins the cluster.
+func (s *Service) UpdatePeerDiscovery(discoverPeers func() ([]string, error), maxJoinPeers int) {
+	s.discoveryMut.Lock()
+	defer s.discoveryMut.Unlock()
+
+	s.opts.DiscoverPeers = discoverPeers
+	s.opts.ClusterMaxJoinPeers = maxJoinPeers
+}
+
 func (s *Service) getPeers() ([]string, error) {
+	s.discoveryMut.Lock()
+	defer s.discoveryMut.Unlock()
+
 	if !s.opts.EnableClustering || s.opts.DiscoverPeers == nil {
 		return nil, nil
 	}
@@ -0,0 +1,158 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/grafana/agent/internal/build"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/service"
+)
+
+// statusPath is the path where each node serves its own status to the other
+// nodes of the cluster.
+//
+// ckit doesn't support attaching metadata to gossip messages, so the status
+// of peers is retrieved over HTTP instead, using the same HTTP/2 client as
+// the clustering traffic.
+const statusPath = "/api/v1/cluster/status"
+
+// peerStatusTimeout is the maximum time spent retrieving the status of a
+// peer.
+const peerStatusTimeout = 5 * time.Second
+
+// NodeStatus is the status a node reports about itself.
+type NodeStatus struct {
+	// State of the node in the cluster as seen by the node itself.
+	State string `json:"state"`
+	// Version of the agent running on the node.
+	Version string `json:"version"`
+	// Hash of the configuration loaded by the node. Empty if no configuration
+	// was loaded yet.
+	ConfigHash string `json:"configHash"`
+	// Number of components of the node which haven't exited, including the
+	// components of nested modules.
+	RunningComponents int `json:"runningComponents"`
+}
+
+// PeerStatus is a peer of the cluster along with the status it reported.
+type PeerStatus struct {
+	Name  string `json:"name"`
+	Addr  string `json:"addr"`
+	Self  bool   `json:"isSelf"`
+	State string `json:"state"`
+
+	// Status reported by the peer. Nil if the status couldn't be retrieved, in
+	// which case Error is set.
+	Status *NodeStatus `json:"status,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Enabled returns whether clustering is enabled.
+func (s *Service) Enabled() bool {
+	return s.opts.EnableClustering
+}
+
+// PeerStatuses returns the peers of the cluster along with the status each
+// peer reports about itself. The status of remote peers is retrieved
+// concurrently; peers which fail to report their status have their Error
+// field set.
+func (s *Service) PeerStatuses(ctx context.Context, host service.Host) []PeerStatus {
+	peers := s.sharder.Peers()
+	statuses := make([]PeerStatus, len(peers))
+
+	var wg sync.WaitGroup
+	for i, p := range peers {
+		statuses[i] = PeerStatus{
+			Name:  p.Name,
+			Addr:  p.Addr,
+			Self:  p.Self,
+			State: p.State.String(),
+		}
+		if p.Self {
+			status := s.nodeStatus(host)
+			statuses[i].Status = &status
+			continue
+		}
+
+		wg.Add(1)
+		go func(ps *PeerStatus) {
+			defer wg.Done()
+
+			status, err := s.fetchNodeStatus(ctx, ps.Addr)
+			if err != nil {
+				ps.Error = err.Error()
+				return
+			}
+			ps.Status = status
+		}(&statuses[i])
+	}
+	wg.Wait()
+
+	return statuses
+}
+
+// nodeStatus returns the status of the local node.
+func (s *Service) nodeStatus(host service.Host) NodeStatus {
+	status := NodeStatus{Version: build.Version}
+	for _, p := range s.sharder.Peers() {
+		if p.Self {
+			status.State = p.State.String()
+			break
+		}
+	}
+	if s.opts.ConfigHash != nil {
+		status.ConfigHash = s.opts.ConfigHash()
+	}
+	for _, info := range component.GetAllComponents(host, component.InfoOptions{GetHealth: true}) {
+		if info.Health.Health != component.HealthTypeExited {
+			status.RunningComponents++
+		}
+	}
+	return status
+}
+
+// fetchNodeStatus retrieves the status of the peer listening at addr.
+func (s *Service) fetchNodeStatus(ctx context.Context, addr string) (*NodeStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, peerStatusTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+statusPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var status NodeStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("decoding status: %w", err)
+	}
+	return &status, nil
+}
+
+// statusHandler serves the status of the local node.
+func (s *Service) statusHandler(host service.Host) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		bb, err := json.Marshal(s.nodeStatus(host))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(bb)
+	})
+}
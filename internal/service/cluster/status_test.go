@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/agent/internal/build"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/ckit/peer"
+	"github.com/grafana/ckit/shard"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerStatuses(t *testing.T) {
+	// Every node serves its status over HTTP. The peers of each node are set
+	// once the addresses of all nodes are known.
+	type node struct {
+		name    string
+		svc     *Service
+		host    statusHost
+		srv     *httptest.Server
+		address string
+	}
+	nodes := []*node{
+		{name: "a", host: statusHost{healthy: 2, exited: 1}},
+		{name: "b", host: statusHost{healthy: 3}},
+		{name: "c", host: statusHost{exited: 2}},
+	}
+	for _, n := range nodes {
+		hash := "hash-" + n.name
+		n.svc = &Service{
+			opts:       Options{EnableClustering: true, ConfigHash: func() string { return hash }},
+			sharder:    shard.Ring(tokensPerNode),
+			httpClient: http.DefaultClient,
+		}
+		handler := n.svc.ServicePaths(n.host)[statusPath]
+		require.NotNil(t, handler)
+		n.srv = httptest.NewServer(handler)
+		t.Cleanup(n.srv.Close)
+		n.address = strings.TrimPrefix(n.srv.URL, "http://")
+	}
+	for _, n := range nodes {
+		peers := make([]peer.Peer, 0, len(nodes)+1)
+		for _, other := range nodes {
+			peers = append(peers, peer.Peer{Name: other.name, Addr: other.address, Self: other == n, State: peer.StateParticipant})
+		}
+		// A peer which left without the cluster noticing yet.
+		peers = append(peers, peer.Peer{Name: "gone", Addr: "127.0.0.1:1", State: peer.StateParticipant})
+		n.svc.sharder.SetPeers(peers)
+	}
+
+	statuses := nodes[0].svc.PeerStatuses(context.Background(), nodes[0].host)
+	require.Len(t, statuses, 4)
+
+	byName := make(map[string]PeerStatus, len(statuses))
+	for _, ps := range statuses {
+		byName[ps.Name] = ps
+	}
+	require.True(t, byName["a"].Self)
+	for name, running := range map[string]int{"a": 2, "b": 3, "c": 0} {
+		ps := byName[name]
+		require.Empty(t, ps.Error, "peer %s", name)
+		require.Equal(t, &NodeStatus{
+			State:             peer.StateParticipant.String(),
+			Version:           build.Version,
+			ConfigHash:        "hash-" + name,
+			RunningComponents: running,
+		}, ps.Status, "peer %s", name)
+	}
+	require.Nil(t, byName["gone"].Status)
+	require.NotEmpty(t, byName["gone"].Error)
+}
+
+func TestServicePaths_ClusteringDisabled(t *testing.T) {
+	svc := &Service{opts: Options{EnableClustering: false}}
+
+	paths := svc.ServicePaths(statusHost{})
+	require.Len(t, paths, 1)
+
+	rec := httptest.NewRecorder()
+	paths[statusPath].ServeHTTP(rec, httptest.NewRequest(http.MethodGet, statusPath, nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// statusHost is a service.Host with the given number of healthy and exited
+// components in its root module.
+type statusHost struct {
+	service.Host
+	healthy, exited int
+}
+
+func (h statusHost) ListComponents(moduleID string, _ component.InfoOptions) ([]*component.Info, error) {
+	if moduleID != "" {
+		return nil, component.ErrModuleNotFound
+	}
+	var infos []*component.Info
+	for i := 0; i < h.healthy; i++ {
+		infos = append(infos, &component.Info{Health: component.Health{Health: component.HealthTypeHealthy}})
+	}
+	for i := 0; i < h.exited; i++ {
+		infos = append(infos, &component.Info{Health: component.Health{Health: component.HealthTypeExited}})
+	}
+	return infos, nil
+}
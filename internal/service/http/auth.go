@@ -0,0 +1,36 @@
+package http
+
+import (
+	"crypto/tls"
+)
+
+// verifiesClientCertificates returns whether the TLS settings of args
+// verify client certificates, which is required to authenticate clients by
+// their certificate.
+func verifiesClientCertificates(args *TLSArguments) bool {
+	if args == nil {
+		return false
+	}
+	if args.WindowsFilter != nil {
+		return true
+	}
+	switch tls.ClientAuthType(args.ClientAuth) {
+	case tls.VerifyClientCertIfGiven, tls.RequireAndVerifyClientCert:
+		return true
+	default:
+		return false
+	}
+}
+
+// AuthExempter is implemented by services whose HTTP handlers must be
+// reachable without authentication, such as handlers used by other agents.
+type AuthExempter interface {
+	ServiceHandler
+
+	// AuthExemptPaths returns the paths of the requests to the service which
+	// are exempt from authentication. Paths ending with a slash exempt every
+	// request under them; other paths only exempt requests to exactly the
+	// path. Only the requests of the service which must be reachable without
+	// credentials should be covered.
+	AuthExemptPaths() []string
+}
@@ -0,0 +1,295 @@
+// Package auth authenticates requests to the HTTP servers of the agent and
+// authorizes them according to the role required by their route.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/grafana/agent/internal/converter/convertapi"
+	"github.com/grafana/agent/internal/flow/riverfmt"
+	"github.com/grafana/river/rivertypes"
+)
+
+// Roles which can be granted to clients of the HTTP server.
+const (
+	// RoleViewer grants read-only access, such as browsing the UI and reading
+	// metrics.
+	RoleViewer = "viewer"
+	// RoleAdmin grants access to every endpoint, including the endpoints
+	// which change the state of the agent like /-/reload.
+	RoleAdmin = "admin"
+)
+
+// Arguments configures the authentication of requests to the HTTP
+// server. Requests are only authenticated when at least one credential is
+// configured.
+type Arguments struct {
+	BasicAuth          []BasicAuthUser     `river:"basic_auth,block,optional"`
+	BearerTokens       []BearerToken       `river:"bearer_token,block,optional"`
+	ClientCertificates []ClientCertificate `river:"client_certificate,block,optional"`
+}
+
+// BasicAuthUser is a user allowed to authenticate with basic authentication.
+type BasicAuthUser struct {
+	Username string            `river:"username,attr"`
+	Password rivertypes.Secret `river:"password,attr"`
+	Role     string            `river:"role,attr"`
+}
+
+// BearerToken is a token allowed to authenticate with the Authorization
+// header.
+type BearerToken struct {
+	Token rivertypes.Secret `river:"token,attr"`
+	Role  string            `river:"role,attr"`
+}
+
+// ClientCertificate grants a role to clients authenticating with a verified
+// TLS client certificate.
+type ClientCertificate struct {
+	CommonNames []string `river:"common_names,attr"`
+	Role        string   `river:"role,attr"`
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if len(args.BasicAuth)+len(args.BearerTokens)+len(args.ClientCertificates) == 0 {
+		return fmt.Errorf("at least one basic_auth, bearer_token, or client_certificate block must be provided")
+	}
+
+	usernames := make(map[string]struct{}, len(args.BasicAuth))
+	for _, user := range args.BasicAuth {
+		if user.Username == "" {
+			return fmt.Errorf("basic_auth username must not be empty")
+		}
+		if _, exists := usernames[user.Username]; exists {
+			return fmt.Errorf("found multiple basic_auth blocks for username %q", user.Username)
+		}
+		usernames[user.Username] = struct{}{}
+		if err := validateRole(user.Role); err != nil {
+			return err
+		}
+	}
+	for _, token := range args.BearerTokens {
+		if token.Token == "" {
+			return fmt.Errorf("bearer_token token must not be empty")
+		}
+		if err := validateRole(token.Role); err != nil {
+			return err
+		}
+	}
+	for _, cert := range args.ClientCertificates {
+		if len(cert.CommonNames) == 0 {
+			return fmt.Errorf("client_certificate common_names must not be empty")
+		}
+		if err := validateRole(cert.Role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRole(role string) error {
+	switch role {
+	case RoleViewer, RoleAdmin:
+		return nil
+	default:
+		return fmt.Errorf("unsupported role %q, must be one of %q or %q", role, RoleViewer, RoleAdmin)
+	}
+}
+
+// Authenticator authenticates requests to an HTTP server and authorizes them
+// according to the role required by their route.
+type Authenticator struct {
+	Args *Arguments // Nil when authentication is disabled.
+
+	// ExemptPaths are the paths of the requests which are exempt from
+	// authentication. Paths ending with a slash exempt every request under
+	// them; other paths only exempt requests to exactly the path.
+	ExemptPaths []string
+}
+
+// isExempt returns whether path is covered by the exempt paths of a.
+func (a *Authenticator) isExempt(path string) bool {
+	for _, exempt := range a.ExemptPaths {
+		if strings.HasSuffix(exempt, "/") && strings.HasPrefix(path, exempt) {
+			return true
+		} else if path == exempt {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredRole returns the role required to serve r. An empty role means r
+// doesn't require authentication.
+func (a *Authenticator) requiredRole(r *http.Request) string {
+	switch r.URL.Path {
+	case "/-/ready", "/-/healthy":
+		// Probes from orchestrators don't send credentials.
+		return ""
+	case "/-/reload", "/-/support":
+		// Support bundles include profiles of the agent, like /debug/pprof.
+		return RoleAdmin
+	case convertapi.Path, riverfmt.APIPath:
+		// Converting and formatting configs doesn't change the state of the
+		// agent.
+		return RoleViewer
+	}
+	if a.isExempt(r.URL.Path) {
+		return ""
+	}
+	if strings.HasPrefix(r.URL.Path, "/debug/pprof") || isAdminUIPath(r.URL.Path) {
+		return RoleAdmin
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RoleViewer
+	default:
+		// Requests which aren't read-only may change the state of the agent.
+		return RoleAdmin
+	}
+}
+
+// isAdminUIPath returns whether path is a UI API endpoint which requires the
+// admin role even though it's read-only. The UI may be served under a path
+// prefix, so only the part of path after the UI API prefix is checked.
+func isAdminUIPath(path string) bool {
+	_, route, found := strings.Cut(path, "/api/v0/web/")
+	if !found {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(route, "modules/") && strings.HasSuffix(route, "/content"):
+		// The content of modules is the raw config, which may hold secrets
+		// which can't be scrubbed reliably.
+		return true
+	case strings.HasPrefix(route, "profiles/"):
+		// Profiles expose the same data as /debug/pprof.
+		return true
+	default:
+		return false
+	}
+}
+
+// Principal is the client of an authenticated request.
+type Principal struct {
+	// Name identifies the client: the username for basic authentication, or
+	// the common name of the client certificate. It's empty for bearer
+	// tokens, which don't carry a name.
+	Name string
+	// Role is the role granted to the client.
+	Role string
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the client of the authenticated request whose
+// context is ctx. It returns false if the request wasn't authenticated, for
+// example because authentication is disabled.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// principalOf returns the client of r, and whether the client authenticated
+// successfully. The highest role is granted when the client presents
+// multiple valid credentials.
+func (a *Authenticator) principalOf(r *http.Request) (Principal, bool) {
+	var principals []Principal
+
+	if username, password, found := r.BasicAuth(); found {
+		for _, user := range a.Args.BasicAuth {
+			if secureEqual(username, user.Username) && secureEqual(password, string(user.Password)) {
+				principals = append(principals, Principal{Name: user.Username, Role: user.Role})
+			}
+		}
+	}
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		for _, t := range a.Args.BearerTokens {
+			if secureEqual(token, string(t.Token)) {
+				principals = append(principals, Principal{Role: t.Role})
+			}
+		}
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, cert := range a.Args.ClientCertificates {
+			if slices.Contains(cert.CommonNames, commonName) {
+				principals = append(principals, Principal{Name: commonName, Role: cert.Role})
+			}
+		}
+	}
+
+	if len(principals) == 0 {
+		return Principal{}, false
+	}
+	for _, p := range principals {
+		if p.Role == RoleAdmin {
+			return p, true
+		}
+	}
+	return principals[0], true
+}
+
+// secureEqual compares a and b in constant time.
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// grants returns whether role grants the permissions of required.
+func grants(role, required string) bool {
+	return role == RoleAdmin || role == required
+}
+
+// NewHandler wraps next with authentication according to the settings
+// returned by getArgs, which is called for every request so that settings can
+// change at runtime. Authentication is disabled while getArgs returns nil.
+//
+// NewHandler is used by servers other than the HTTP service, such as the
+// server of static mode, so that their routes require the same roles as the
+// routes of the HTTP service.
+func NewHandler(getArgs func() *Arguments, next http.Handler) http.Handler {
+	return Handler(func() *Authenticator { return &Authenticator{Args: getArgs()} }, next)
+}
+
+// Handler wraps next with authentication according to the settings returned
+// by getAuth, which is called for every request so that settings can change
+// at runtime. The Principal of authenticated requests is stored in their
+// context.
+func Handler(getAuth func() *Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := getAuth()
+		if a == nil || a.Args == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, ok := a.principalOf(r)
+		if ok {
+			r = r.WithContext(context.WithValue(r.Context(), principalKey{}, principal))
+		}
+
+		required := a.requiredRole(r)
+		if required == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Grafana Agent"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		if !grants(principal.Role, required) {
+			http.Error(w, fmt.Sprintf("the %s role is required", required), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
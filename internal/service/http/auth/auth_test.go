@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	args := &Arguments{
+		BasicAuth: []BasicAuthUser{
+			{Username: "viewer", Password: "viewer-password", Role: RoleViewer},
+			{Username: "admin", Password: "admin-password", Role: RoleAdmin},
+		},
+		BearerTokens: []BearerToken{
+			{Token: "viewer-token", Role: RoleViewer},
+		},
+		ClientCertificates: []ClientCertificate{
+			{CommonNames: []string{"ops"}, Role: RoleAdmin},
+		},
+	}
+	require.NoError(t, args.Validate())
+
+	a := &Authenticator{Args: args, ExemptPaths: []string{"/api/v1/ckit/transport/", "/api/v1/cluster/status"}}
+	handler := Handler(func() *Authenticator { return a }, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		auth   func(r *http.Request)
+		expect int
+	}{
+		{name: "no credentials", method: http.MethodGet, path: "/", expect: http.StatusUnauthorized},
+		{name: "invalid password", method: http.MethodGet, path: "/", auth: basicAuth("viewer", "wrong"), expect: http.StatusUnauthorized},
+		{name: "viewer reads UI", method: http.MethodGet, path: "/api/v0/web/components", auth: basicAuth("viewer", "viewer-password"), expect: http.StatusOK},
+		{name: "viewer reloads", method: http.MethodGet, path: "/-/reload", auth: basicAuth("viewer", "viewer-password"), expect: http.StatusForbidden},
+		{name: "viewer posts", method: http.MethodPost, path: "/api/v0/web/resolve", auth: basicAuth("viewer", "viewer-password"), expect: http.StatusForbidden},
+		{name: "viewer converts config", method: http.MethodPost, path: "/agent/api/v1/convert", auth: basicAuth("viewer", "viewer-password"), expect: http.StatusOK},
+		{name: "viewer formats config", method: http.MethodPost, path: "/agent/api/v1/fmt", auth: bearerToken("viewer-token"), expect: http.StatusOK},
+		{name: "viewer reads module content", method: http.MethodGet, path: "/api/v0/web/modules/import.http.shared/content", auth: basicAuth("viewer", "viewer-password"), expect: http.StatusForbidden},
+		{name: "viewer reads module content under UI prefix", method: http.MethodGet, path: "/agent/api/v0/web/modules/module.file.a/import.http.b/content", auth: basicAuth("viewer", "viewer-password"), expect: http.StatusForbidden},
+		{name: "admin reads module content", method: http.MethodGet, path: "/api/v0/web/modules/import.http.shared/content", auth: basicAuth("admin", "admin-password"), expect: http.StatusOK},
+		{name: "viewer reads module components", method: http.MethodGet, path: "/api/v0/web/modules/module.file.a/components", auth: basicAuth("viewer", "viewer-password"), expect: http.StatusOK},
+		{name: "viewer reads CPU profile", method: http.MethodGet, path: "/api/v0/web/profiles/cpu", auth: basicAuth("viewer", "viewer-password"), expect: http.StatusForbidden},
+		{name: "admin reads CPU profile", method: http.MethodGet, path: "/api/v0/web/profiles/cpu", auth: basicAuth("admin", "admin-password"), expect: http.StatusOK},
+		{name: "viewer reads support bundle", method: http.MethodGet, path: "/-/support", auth: basicAuth("viewer", "viewer-password"), expect: http.StatusForbidden},
+		{name: "viewer reads pprof", method: http.MethodGet, path: "/debug/pprof/heap", auth: bearerToken("viewer-token"), expect: http.StatusForbidden},
+		{name: "viewer token reads metrics", method: http.MethodGet, path: "/metrics", auth: bearerToken("viewer-token"), expect: http.StatusOK},
+		{name: "invalid token", method: http.MethodGet, path: "/metrics", auth: bearerToken("wrong"), expect: http.StatusUnauthorized},
+		{name: "admin reloads", method: http.MethodPost, path: "/-/reload", auth: basicAuth("admin", "admin-password"), expect: http.StatusOK},
+		{name: "client certificate reloads", method: http.MethodPost, path: "/-/reload", auth: clientCertificate("ops"), expect: http.StatusOK},
+		{name: "unknown client certificate", method: http.MethodGet, path: "/", auth: clientCertificate("someone"), expect: http.StatusUnauthorized},
+		{name: "readiness probe", method: http.MethodGet, path: "/-/ready", expect: http.StatusOK},
+		{name: "health probe", method: http.MethodGet, path: "/-/healthy", expect: http.StatusOK},
+		{name: "exempt service route", method: http.MethodPost, path: "/api/v1/ckit/transport/message", expect: http.StatusOK},
+		{name: "cluster status without credentials", method: http.MethodGet, path: "/api/v1/cluster/status", expect: http.StatusOK},
+		{name: "path under an exact exempt path", method: http.MethodGet, path: "/api/v1/cluster/status/other", expect: http.StatusUnauthorized},
+		{name: "path sharing the exempt prefix", method: http.MethodGet, path: "/api/v1/ckit/other", expect: http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.auth != nil {
+				tc.auth(req)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, tc.expect, rec.Code)
+			if tc.expect == http.StatusUnauthorized {
+				require.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	var args *Arguments
+	handler := NewHandler(func() *Arguments { return args }, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	reload := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+		return rec.Code
+	}
+	require.Equal(t, http.StatusOK, reload(), "authentication must be disabled without arguments")
+
+	// Arguments are read for every request.
+	args = &Arguments{BearerTokens: []BearerToken{{Token: "token", Role: RoleAdmin}}}
+	require.Equal(t, http.StatusUnauthorized, reload())
+}
+
+func TestHandler_Disabled(t *testing.T) {
+	handler := Handler(func() *Authenticator { return &Authenticator{} }, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/-/reload", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	a := &Authenticator{Args: &Arguments{
+		BasicAuth:          []BasicAuthUser{{Username: "alice", Password: "password", Role: RoleViewer}},
+		BearerTokens:       []BearerToken{{Token: "token", Role: RoleAdmin}},
+		ClientCertificates: []ClientCertificate{{CommonNames: []string{"ops"}, Role: RoleAdmin}},
+	}}
+
+	var (
+		principal Principal
+		found     bool
+	)
+	handler := Handler(func() *Authenticator { return a }, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, found = PrincipalFromContext(r.Context())
+	}))
+	serve := func(path string, auth func(r *http.Request)) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != nil {
+			auth(req)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve("/", basicAuth("alice", "password"))
+	require.True(t, found)
+	require.Equal(t, Principal{Name: "alice", Role: RoleViewer}, principal)
+
+	serve("/", clientCertificate("ops"))
+	require.True(t, found)
+	require.Equal(t, Principal{Name: "ops", Role: RoleAdmin}, principal)
+
+	// The credential granting the highest role identifies the client.
+	serve("/", func(r *http.Request) {
+		basicAuth("alice", "password")(r)
+		clientCertificate("ops")(r)
+	})
+	require.Equal(t, Principal{Name: "ops", Role: RoleAdmin}, principal)
+
+	// Requests which don't require authentication have no principal unless
+	// they carry valid credentials.
+	serve("/-/ready", nil)
+	require.False(t, found)
+	serve("/-/ready", bearerToken("token"))
+	require.True(t, found)
+	require.Equal(t, Principal{Role: RoleAdmin}, principal)
+}
+
+func TestArguments_Validate(t *testing.T) {
+	require.ErrorContains(t, (&Arguments{}).Validate(), "at least one")
+	require.ErrorContains(t, (&Arguments{
+		BearerTokens: []BearerToken{{Token: "token", Role: "owner"}},
+	}).Validate(), `unsupported role "owner"`)
+	require.ErrorContains(t, (&Arguments{
+		BasicAuth: []BasicAuthUser{
+			{Username: "user", Password: "a", Role: RoleViewer},
+			{Username: "user", Password: "b", Role: RoleAdmin},
+		},
+	}).Validate(), "multiple basic_auth blocks")
+}
+
+func basicAuth(username, password string) func(r *http.Request) {
+	return func(r *http.Request) { r.SetBasicAuth(username, password) }
+}
+
+func bearerToken(token string) func(r *http.Request) {
+	return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+}
+
+func clientCertificate(commonName string) func(r *http.Request) {
+	return func(r *http.Request) {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+}
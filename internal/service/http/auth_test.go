@@ -0,0 +1,69 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/service/http/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetServiceRoutes_ExemptPaths(t *testing.T) {
+	host := consumersHost{consumers: []service.Consumer{
+		{Type: service.ConsumerTypeService, ID: "cluster", Value: exemptService{base: "/api/v1/", exemptPaths: []string{"/api/v1/ckit/transport/", "/api/v1/cluster/status"}}},
+		{Type: service.ConsumerTypeService, ID: "other", Value: exemptService{base: "/api/v2/"}},
+	}}
+
+	routes := (&Service{}).getServiceRoutes(host)
+	require.Len(t, routes, 2)
+
+	exempt := make(map[string][]string, len(routes))
+	for _, route := range routes {
+		exempt[route.Base] = route.ExemptPaths
+	}
+
+	// Only the paths returned by the service are exempt, not its whole base
+	// route.
+	require.Equal(t, map[string][]string{
+		"/api/v1/": {"/api/v1/ckit/transport/", "/api/v1/cluster/status"},
+		"/api/v2/": nil,
+	}, exempt)
+}
+
+// exemptService is a service whose handler is partly exempt from
+// authentication. Only the methods used for routing are implemented.
+type exemptService struct {
+	service.Service
+	base        string
+	exemptPaths []string
+}
+
+var _ AuthExempter = exemptService{}
+
+func (s exemptService) ServiceHandler(service.Host) (string, http.Handler) {
+	return s.base, http.NotFoundHandler()
+}
+
+func (s exemptService) AuthExemptPaths() []string { return s.exemptPaths }
+
+// consumersHost is a service.Host whose services depend on the HTTP service.
+type consumersHost struct {
+	fakeHost
+	consumers []service.Consumer
+}
+
+func (h consumersHost) GetServiceConsumers(string) []service.Consumer { return h.consumers }
+
+func TestArguments_ValidateClientCertificates(t *testing.T) {
+	args := Arguments{
+		Auth: &auth.Arguments{
+			ClientCertificates: []auth.ClientCertificate{{CommonNames: []string{"ops"}, Role: auth.RoleAdmin}},
+		},
+	}
+	require.ErrorContains(t, args.Validate(), "require the tls block")
+
+	args.TLS = &TLSArguments{ClientAuth: ClientAuth(tls.RequireAndVerifyClientCert)}
+	require.NoError(t, args.Validate())
+}
@@ -0,0 +1,166 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/service"
+)
+
+// Supported health aggregation modes.
+const (
+	// HealthModeAny reports unhealthy if any selected component is unhealthy.
+	HealthModeAny = "any"
+	// HealthModeAll reports unhealthy only if every selected component is
+	// unhealthy.
+	HealthModeAll = "all"
+)
+
+// Overall health statuses reported by the /-/healthy endpoint.
+const (
+	healthStatusHealthy   = "healthy"
+	healthStatusDegraded  = "degraded"
+	healthStatusUnhealthy = "unhealthy"
+)
+
+// HealthArguments configures how the /-/healthy endpoint aggregates the health
+// of components.
+type HealthArguments struct {
+	Components          []string `river:"components,attr,optional"`
+	Mode                string   `river:"mode,attr,optional"`
+	DegradedStatusCode  int      `river:"degraded_status_code,attr,optional"`
+	UnhealthyStatusCode int      `river:"unhealthy_status_code,attr,optional"`
+}
+
+// DefaultHealthArguments holds the default settings for the /-/healthy
+// endpoint, used when the health block isn't specified. Failures are only
+// reported in the response body until operators opt in to an error status
+// code, so that existing probes don't start failing.
+var DefaultHealthArguments = HealthArguments{
+	Mode:                HealthModeAny,
+	DegradedStatusCode:  http.StatusOK,
+	UnhealthyStatusCode: http.StatusOK,
+}
+
+// SetToDefault implements river.Defaulter.
+func (args *HealthArguments) SetToDefault() {
+	*args = DefaultHealthArguments
+}
+
+// Validate implements river.Validator.
+func (args *HealthArguments) Validate() error {
+	switch args.Mode {
+	case HealthModeAny, HealthModeAll:
+	default:
+		return fmt.Errorf("unsupported mode %q, must be one of %q or %q", args.Mode, HealthModeAny, HealthModeAll)
+	}
+	for _, pattern := range args.Components {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid component pattern %q: %w", pattern, err)
+		}
+	}
+	if args.DegradedStatusCode < 200 || args.DegradedStatusCode > 599 {
+		return fmt.Errorf("degraded_status_code must be a valid HTTP status code, got %d", args.DegradedStatusCode)
+	}
+	if args.UnhealthyStatusCode < 200 || args.UnhealthyStatusCode > 599 {
+		return fmt.Errorf("unhealthy_status_code must be a valid HTTP status code, got %d", args.UnhealthyStatusCode)
+	}
+	return nil
+}
+
+// selects returns true if the component with the given global ID is
+// considered when determining whether the agent is unhealthy.
+func (args *HealthArguments) selects(id string) bool {
+	if len(args.Components) == 0 {
+		return true
+	}
+	for _, pattern := range args.Components {
+		if ok, _ := path.Match(pattern, id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// healthResponse is the JSON body returned by the /-/healthy endpoint.
+type healthResponse struct {
+	Status            string            `json:"status"`
+	FailingComponents []failingResponse `json:"failing_components"`
+}
+
+type failingResponse struct {
+	ID       string `json:"id"`
+	Health   string `json:"health"`
+	Message  string `json:"message"`
+	Selected bool   `json:"selected"`
+}
+
+// healthHandler returns the handler for the /-/healthy endpoint.
+func (s *Service) healthHandler(host service.Host) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		s.healthMut.RLock()
+		args := s.health
+		s.healthMut.RUnlock()
+
+		infos := component.GetAllComponents(host, component.InfoOptions{GetHealth: true})
+		resp, code := aggregateHealth(args, infos)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// aggregateHealth determines the overall health of the agent from the health
+// of its components, returning the response body and status code.
+func aggregateHealth(args HealthArguments, infos []*component.Info) (healthResponse, int) {
+	resp := healthResponse{
+		Status:            healthStatusHealthy,
+		FailingComponents: []failingResponse{},
+	}
+
+	var selected, selectedFailing int
+	for _, info := range infos {
+		id := info.ID.String()
+		isSelected := args.selects(id)
+		if isSelected {
+			selected++
+		}
+
+		switch info.Health.Health {
+		case component.HealthTypeUnhealthy, component.HealthTypeExited:
+		default:
+			continue
+		}
+		if isSelected {
+			selectedFailing++
+		}
+		resp.FailingComponents = append(resp.FailingComponents, failingResponse{
+			ID:       id,
+			Health:   info.Health.Health.String(),
+			Message:  info.Health.Message,
+			Selected: isSelected,
+		})
+	}
+
+	var unhealthy bool
+	switch args.Mode {
+	case HealthModeAll:
+		unhealthy = selected > 0 && selectedFailing == selected
+	default:
+		unhealthy = selectedFailing > 0
+	}
+
+	switch {
+	case unhealthy:
+		resp.Status = healthStatusUnhealthy
+		return resp, args.UnhealthyStatusCode
+	case len(resp.FailingComponents) > 0:
+		resp.Status = healthStatusDegraded
+		return resp, args.DegradedStatusCode
+	default:
+		return resp, http.StatusOK
+	}
+}
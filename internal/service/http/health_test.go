@@ -0,0 +1,104 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateHealth(t *testing.T) {
+	info := func(id string, health component.HealthType) *component.Info {
+		return &component.Info{
+			ID:     component.ID{LocalID: id},
+			Health: component.Health{Health: health, Message: id + " message"},
+		}
+	}
+	infos := []*component.Info{
+		info("prometheus.remote_write.a", component.HealthTypeUnhealthy),
+		info("prometheus.remote_write.b", component.HealthTypeHealthy),
+		info("loki.source.file.logs", component.HealthTypeExited),
+	}
+
+	tests := []struct {
+		name       string
+		args       func(*HealthArguments)
+		infos      []*component.Info
+		wantStatus string
+		wantCode   int
+	}{
+		{
+			name:       "defaults only report failures in the body",
+			args:       func(a *HealthArguments) {},
+			infos:      infos,
+			wantStatus: healthStatusUnhealthy,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name: "all components, any mode",
+			args: func(a *HealthArguments) {
+				a.UnhealthyStatusCode = http.StatusServiceUnavailable
+			},
+			infos:      infos,
+			wantStatus: healthStatusUnhealthy,
+			wantCode:   http.StatusServiceUnavailable,
+		},
+		{
+			name:       "healthy",
+			args:       func(a *HealthArguments) {},
+			infos:      infos[1:2],
+			wantStatus: healthStatusHealthy,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name: "unselected failures are degraded",
+			args: func(a *HealthArguments) {
+				a.Components = []string{"prometheus.remote_write.b"}
+				a.DegradedStatusCode = 299
+			},
+			infos:      infos,
+			wantStatus: healthStatusDegraded,
+			wantCode:   299,
+		},
+		{
+			name: "all mode with some selected failing",
+			args: func(a *HealthArguments) {
+				a.Components = []string{"prometheus.remote_write.*"}
+				a.Mode = HealthModeAll
+			},
+			infos:      infos,
+			wantStatus: healthStatusDegraded,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name: "all mode with every selected failing",
+			args: func(a *HealthArguments) {
+				a.Components = []string{"prometheus.remote_write.a", "loki.*"}
+				a.Mode = HealthModeAll
+				a.UnhealthyStatusCode = http.StatusInternalServerError
+			},
+			infos:      infos,
+			wantStatus: healthStatusUnhealthy,
+			wantCode:   http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args := DefaultHealthArguments
+			tc.args(&args)
+			require.NoError(t, args.Validate())
+
+			resp, code := aggregateHealth(args, tc.infos)
+			require.Equal(t, tc.wantStatus, resp.Status)
+			require.Equal(t, tc.wantCode, code)
+		})
+	}
+
+	resp, _ := aggregateHealth(DefaultHealthArguments, infos)
+	require.Equal(t, []failingResponse{
+		{ID: "prometheus.remote_write.a", Health: "unhealthy", Message: "prometheus.remote_write.a message", Selected: true},
+		{ID: "loki.source.file.logs", Health: "exited", Message: "loki.source.file.logs message", Selected: true},
+	}, resp.FailingComponents)
+}
@@ -16,10 +16,13 @@ import (
 	"github.com/go-kit/log"
 	"github.com/gorilla/mux"
 	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/converter/convertapi"
 	"github.com/grafana/agent/internal/featuregate"
 	"github.com/grafana/agent/internal/flow"
 	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/flow/riverfmt"
 	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/service/http/auth"
 	"github.com/grafana/agent/internal/static/server"
 	"github.com/grafana/ckit/memconn"
 	_ "github.com/grafana/pyroscope-go/godeltaprof/http/pprof" // Register godeltaprof handler
@@ -45,6 +48,21 @@ type Options struct {
 	ReadyFunc  func() bool
 	ReloadFunc func() (*flow.Source, error)
 
+	// ValidateFunc loads and validates the config without applying it. It is
+	// used for dry-run reloads.
+	ValidateFunc func() (*flow.Source, error)
+
+	// ConvertHandler, if set, serves the config conversion API at
+	// convertapi.Path.
+	ConvertHandler http.Handler
+
+	// FmtHandler, if set, serves the formatting API at riverfmt.APIPath.
+	FmtHandler http.Handler
+
+	// ConfigHash returns the hash of the currently loaded configuration. May be
+	// nil.
+	ConfigHash func() string
+
 	HTTPListenAddr   string // Address to listen for HTTP traffic on.
 	MemoryListenAddr string // Address to accept in-memory traffic on.
 	EnablePProf      bool   // Whether pprof endpoints should be exposed.
@@ -52,7 +70,17 @@ type Options struct {
 
 // Arguments holds runtime settings for the HTTP service.
 type Arguments struct {
-	TLS *TLSArguments `river:"tls,block,optional"`
+	TLS    *TLSArguments    `river:"tls,block,optional"`
+	Health *HealthArguments `river:"health,block,optional"`
+	Auth   *auth.Arguments  `river:"auth,block,optional"`
+}
+
+// Validate implements river.Validator.
+func (args *Arguments) Validate() error {
+	if args.Auth != nil && len(args.Auth.ClientCertificates) > 0 && !verifiesClientCertificates(args.TLS) {
+		return fmt.Errorf("auth client_certificate blocks require the tls block to set client_auth_type to VerifyClientCertIfGiven or RequireAndVerifyClientCert")
+	}
+	return nil
 }
 
 type Service struct {
@@ -79,6 +107,16 @@ type Service struct {
 	memLis *memconn.Listener
 
 	componentHttpPathPrefix string
+
+	healthMut sync.RWMutex
+	health    HealthArguments
+
+	authMut     sync.RWMutex
+	auth        *auth.Arguments // Nil when authentication is disabled.
+	exemptPaths []string        // Service paths exempt from authentication.
+
+	hostMut sync.RWMutex
+	host    service.Host // Nil until the service runs.
 }
 
 var _ service.Service = (*Service)(nil)
@@ -120,6 +158,8 @@ func New(opts Options) *Service {
 		memLis:    memconn.NewListener(l),
 
 		componentHttpPathPrefix: "/api/v0/component/",
+
+		health: DefaultHealthArguments,
 	}
 }
 
@@ -136,6 +176,10 @@ func (s *Service) Definition() service.Definition {
 // Run starts the HTTP service. It will run until the provided context is
 // canceled or there is a fatal error.
 func (s *Service) Run(ctx context.Context, host service.Host) error {
+	s.hostMut.Lock()
+	s.host = host
+	s.hostMut.Unlock()
+
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
@@ -185,8 +229,15 @@ func (s *Service) Run(ctx context.Context, host service.Host) error {
 		})
 	}
 
+	r.HandleFunc("/-/healthy", s.healthHandler(host)).Methods(http.MethodGet)
+
 	if s.opts.ReloadFunc != nil {
-		r.HandleFunc("/-/reload", func(w http.ResponseWriter, _ *http.Request) {
+		r.HandleFunc("/-/reload", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("dry_run") == "true" {
+				s.validateHandler(w)
+				return
+			}
+
 			level.Info(s.log).Log("msg", "reload requested via /-/reload endpoint")
 
 			_, err := s.opts.ReloadFunc()
@@ -201,33 +252,61 @@ func (s *Service) Run(ctx context.Context, host service.Host) error {
 		}).Methods(http.MethodGet, http.MethodPost)
 	}
 
+	if s.opts.ConvertHandler != nil {
+		r.Handle(convertapi.Path, s.opts.ConvertHandler).Methods(http.MethodPost)
+	}
+	if s.opts.FmtHandler != nil {
+		r.Handle(riverfmt.APIPath, s.opts.FmtHandler).Methods(http.MethodPost)
+	}
+
 	// Wire custom service handlers for services which depend on the http
 	// service.
 	//
 	// NOTE(rfratto): keep this at the bottom of all other routes, otherwise a
 	// service with a colliding path takes precedence over a predefined route.
-	for _, route := range s.getServiceRoutes(host) {
+	var exemptPaths []string
+	routes := s.getServiceRoutes(host)
+	for _, route := range routes {
+		for path, handler := range route.Paths {
+			r.Path(path).Handler(handler)
+		}
+	}
+	for _, route := range routes {
 		r.PathPrefix(route.Base).Handler(route.Handler)
+		exemptPaths = append(exemptPaths, route.ExemptPaths...)
 	}
+	s.authMut.Lock()
+	s.exemptPaths = exemptPaths
+	s.authMut.Unlock()
 
-	srv := &http.Server{Handler: h2c.NewHandler(r, &http2.Server{})}
+	// Only public traffic is authenticated. In-memory traffic comes from the
+	// agent itself, such as scrapes of the exporter components, and can't be
+	// sent by other processes, so it bypasses authentication entirely.
+	var (
+		publicSrv = &http.Server{Handler: h2c.NewHandler(auth.Handler(s.authenticator, r), &http2.Server{})}
+		memSrv    = &http.Server{Handler: h2c.NewHandler(r, &http2.Server{})}
+	)
 
 	level.Info(s.log).Log("msg", "now listening for http traffic", "addr", s.opts.HTTPListenAddr)
 
-	listeners := []net.Listener{s.publicLis, s.memLis}
-	for _, lis := range listeners {
+	servers := map[net.Listener]*http.Server{s.publicLis: publicSrv, s.memLis: memSrv}
+	for lis, srv := range servers {
 		wg.Add(1)
-		go func(lis net.Listener) {
+		go func(lis net.Listener, srv *http.Server) {
 			defer wg.Done()
 			defer cancel()
 
 			if err := srv.Serve(lis); err != nil {
 				level.Info(s.log).Log("msg", "http server closed", "addr", lis.Addr(), "err", err)
 			}
-		}(lis)
+		}(lis, srv)
 	}
 
-	defer func() { _ = srv.Shutdown(ctx) }()
+	defer func() {
+		for _, srv := range servers {
+			_ = srv.Shutdown(ctx)
+		}
+	}()
 
 	<-ctx.Done()
 	return nil
@@ -252,9 +331,21 @@ func (s *Service) getServiceRoutes(host service.Host) []serviceRoute {
 		}
 		base, handler := sh.ServiceHandler(host)
 
+		var exemptPaths []string
+		if exempter, ok := sh.(AuthExempter); ok {
+			exemptPaths = exempter.AuthExemptPaths()
+		}
+
+		var paths map[string]http.Handler
+		if ph, ok := sh.(ServicePathsHandler); ok {
+			paths = ph.ServicePaths(host)
+		}
+
 		routes = append(routes, serviceRoute{
-			Base:    base,
-			Handler: handler,
+			Base:        base,
+			Handler:     handler,
+			Paths:       paths,
+			ExemptPaths: exemptPaths,
 		})
 	}
 
@@ -262,6 +353,13 @@ func (s *Service) getServiceRoutes(host service.Host) []serviceRoute {
 	return routes
 }
 
+// authenticator returns the current authentication settings.
+func (s *Service) authenticator() *auth.Authenticator {
+	s.authMut.RLock()
+	defer s.authMut.RUnlock()
+	return &auth.Authenticator{Args: s.auth, ExemptPaths: s.exemptPaths}
+}
+
 func (s *Service) componentHandler(host service.Host) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Trim the path prefix to get our full path.
@@ -302,6 +400,18 @@ func (s *Service) componentHandler(host service.Host) http.HandlerFunc {
 func (s *Service) Update(newConfig any) error {
 	newArgs := newConfig.(Arguments)
 
+	s.healthMut.Lock()
+	if newArgs.Health != nil {
+		s.health = *newArgs.Health
+	} else {
+		s.health = DefaultHealthArguments
+	}
+	s.healthMut.Unlock()
+
+	s.authMut.Lock()
+	s.auth = newArgs.Auth
+	s.authMut.Unlock()
+
 	if newArgs.TLS != nil {
 		var tlsConfig *tls.Config
 		var err error
@@ -353,7 +463,28 @@ func (s *Service) Data() any {
 				return (&net.Dialer{}).DialContext(ctx, network, address)
 			}
 		},
+
+		ComponentHealth: s.componentHealth,
+		ConfigHash: func() string {
+			if s.opts.ConfigHash == nil {
+				return ""
+			}
+			return s.opts.ConfigHash()
+		},
+	}
+}
+
+// componentHealth returns the health of every component of the host, or nil
+// if the service isn't running yet.
+func (s *Service) componentHealth() []*component.Info {
+	s.hostMut.RLock()
+	host := s.host
+	s.hostMut.RUnlock()
+
+	if host == nil {
+		return nil
 	}
+	return component.GetAllComponents(host, component.InfoOptions{GetHealth: true})
 }
 
 // Data includes information associated with the HTTP service.
@@ -372,6 +503,14 @@ type Data struct {
 	// address is MemoryListenAddr. If address is not MemoryListenAddr, DialFunc
 	// establishes an outbound network connection.
 	DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// ComponentHealth returns the health of every running component, including
+	// the components of modules. It returns nil until the HTTP service runs.
+	ComponentHealth func() []*component.Info
+
+	// ConfigHash returns the hash of the currently loaded configuration, or an
+	// empty string if it's unknown.
+	ConfigHash func() string
 }
 
 // HTTPPathForComponent returns the full HTTP path for a given global component
@@ -411,6 +550,17 @@ type ServiceHandler interface {
 	ServiceHandler(host service.Host) (base string, handler http.Handler)
 }
 
+// ServicePathsHandler is implemented by services which serve individual
+// paths outside of their base route.
+type ServicePathsHandler interface {
+	ServiceHandler
+
+	// ServicePaths returns the handlers of the paths served by the service
+	// in addition to its base route, by path. Each handler only serves
+	// requests to exactly its path.
+	ServicePaths(host service.Host) map[string]http.Handler
+}
+
 // lazyListener is a [net.Listener] which lazily initializes the underlying
 // listener.
 type lazyListener struct {
@@ -160,13 +160,15 @@ type testEnvironment struct {
 	addr string
 }
 
-func newTestEnvironment(t *testing.T) (*testEnvironment, error) {
+// newTestEnvironment creates a test environment. The options of the HTTP
+// service can be changed with configure.
+func newTestEnvironment(t *testing.T, configure ...func(*Options)) (*testEnvironment, error) {
 	port, err := freeport.GetFreePort()
 	if err != nil {
 		return nil, err
 	}
 
-	svc := New(Options{
+	opts := Options{
 		Logger:   util.TestLogger(t),
 		Tracer:   noop.NewTracerProvider(),
 		Gatherer: prometheus.NewRegistry(),
@@ -177,7 +179,11 @@ func newTestEnvironment(t *testing.T) (*testEnvironment, error) {
 		HTTPListenAddr:   fmt.Sprintf("127.0.0.1:%d", port),
 		MemoryListenAddr: "agent.internal:12345",
 		EnablePProf:      true,
-	})
+	}
+	for _, f := range configure {
+		f(&opts)
+	}
+	svc := New(opts)
 
 	return &testEnvironment{
 		svc:  svc,
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/river/diag"
+)
+
+// validateResponse is the response body of a dry-run reload.
+type validateResponse struct {
+	Valid       bool                 `json:"valid"`
+	Diagnostics []diagnosticResponse `json:"diagnostics"`
+}
+
+type diagnosticResponse struct {
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// validateHandler handles /-/reload?dry_run=true, which validates the config
+// without applying it. Invalid configs are reported with a 400 status code.
+// Configs with warnings but no errors are valid.
+func (s *Service) validateHandler(w http.ResponseWriter) {
+	if s.opts.ValidateFunc == nil {
+		http.Error(w, "dry-run reloads are not supported", http.StatusNotImplemented)
+		return
+	}
+
+	level.Info(s.log).Log("msg", "dry-run reload requested via /-/reload endpoint")
+
+	_, err := s.opts.ValidateFunc()
+	resp := validateResponse{
+		Valid:       !hasErrors(err),
+		Diagnostics: diagnosticsFromError(err),
+	}
+
+	code := http.StatusOK
+	if !resp.Valid {
+		level.Info(s.log).Log("msg", "dry-run reload found an invalid config", "err", err)
+		code = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// hasErrors reports whether err is an error other than River diagnostics
+// which are all warnings.
+func hasErrors(err error) bool {
+	var diags diag.Diagnostics
+	if errors.As(err, &diags) {
+		return diags.HasErrors()
+	}
+	return err != nil
+}
+
+// diagnosticsFromError converts err into a list of diagnostics. Errors which
+// don't hold River diagnostics are reported as a single error without a
+// position.
+func diagnosticsFromError(err error) []diagnosticResponse {
+	res := []diagnosticResponse{}
+	if err == nil {
+		return res
+	}
+
+	var (
+		diags diag.Diagnostics
+		d     diag.Diagnostic
+	)
+	switch {
+	case errors.As(err, &diags):
+	case errors.As(err, &d):
+		diags = diag.Diagnostics{d}
+	default:
+		return append(res, diagnosticResponse{Severity: "error", Message: err.Error()})
+	}
+
+	for _, d := range diags {
+		severity := "error"
+		if d.Severity == diag.SeverityLevelWarn {
+			severity = "warning"
+		}
+		res = append(res, diagnosticResponse{
+			Severity: severity,
+			File:     d.StartPos.Filename,
+			Line:     d.StartPos.Line,
+			Column:   d.StartPos.Column,
+			Message:  d.Message,
+		})
+	}
+	return res
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grafana/agent/internal/flow"
+	"github.com/grafana/agent/internal/flow/componenttest"
+	"github.com/grafana/agent/internal/util"
+	"github.com/grafana/river/diag"
+	"github.com/grafana/river/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnosticsFromError(t *testing.T) {
+	require.Equal(t, []diagnosticResponse{}, diagnosticsFromError(nil))
+
+	require.Equal(t, []diagnosticResponse{
+		{Severity: "error", Message: "reading config: file not found"},
+	}, diagnosticsFromError(errors.New("reading config: file not found")))
+
+	diags := diag.Diagnostics{
+		{
+			Severity: diag.SeverityLevelError,
+			StartPos: token.Position{Filename: "config.river", Line: 3, Column: 5},
+			Message:  `unrecognized attribute name "unknown"`,
+		},
+		{
+			Severity: diag.SeverityLevelWarn,
+			StartPos: token.Position{Filename: "config.river", Line: 7, Column: 1},
+			Message:  "deprecated block",
+		},
+	}
+	require.Equal(t, []diagnosticResponse{
+		{Severity: "error", File: "config.river", Line: 3, Column: 5, Message: `unrecognized attribute name "unknown"`},
+		{Severity: "warning", File: "config.river", Line: 7, Column: 1, Message: "deprecated block"},
+	}, diagnosticsFromError(fmt.Errorf("validating tenant %q: %w", "team_a", diags)))
+}
+
+func TestReload_DryRun(t *testing.T) {
+	var (
+		mut         sync.Mutex
+		validateErr error
+		reloaded    atomic.Bool
+	)
+	env, err := newTestEnvironment(t, func(o *Options) {
+		o.ReloadFunc = func() (*flow.Source, error) {
+			reloaded.Store(true)
+			return nil, nil
+		}
+		o.ValidateFunc = func() (*flow.Source, error) {
+			mut.Lock()
+			defer mut.Unlock()
+			return nil, validateErr
+		}
+	})
+	require.NoError(t, err)
+	require.NoError(t, env.ApplyConfig(`/* empty */`))
+
+	ctx := componenttest.TestContext(t)
+	go func() {
+		require.NoError(t, env.Run(ctx))
+	}()
+
+	dryRun := func(t require.TestingT) (int, validateResponse) {
+		resp, err := http.Post(fmt.Sprintf("http://%s/-/reload?dry_run=true", env.ListenAddr()), "", nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var body validateResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body
+	}
+
+	tt := []struct {
+		name       string
+		err        error
+		expectCode int
+		expect     validateResponse
+	}{
+		{
+			name:       "valid",
+			expectCode: http.StatusOK,
+			expect:     validateResponse{Valid: true, Diagnostics: []diagnosticResponse{}},
+		},
+		{
+			name: "warnings",
+			err: diag.Diagnostics{{
+				Severity: diag.SeverityLevelWarn,
+				StartPos: token.Position{Filename: "config.river", Line: 2, Column: 1},
+				Message:  "deprecated block",
+			}},
+			expectCode: http.StatusOK,
+			expect: validateResponse{Valid: true, Diagnostics: []diagnosticResponse{
+				{Severity: "warning", File: "config.river", Line: 2, Column: 1, Message: "deprecated block"},
+			}},
+		},
+		{
+			name: "errors",
+			err: diag.Diagnostics{{
+				Severity: diag.SeverityLevelError,
+				StartPos: token.Position{Filename: "config.river", Line: 3, Column: 5},
+				Message:  `unrecognized attribute name "unknown"`,
+			}},
+			expectCode: http.StatusBadRequest,
+			expect: validateResponse{Valid: false, Diagnostics: []diagnosticResponse{
+				{Severity: "error", File: "config.river", Line: 3, Column: 5, Message: `unrecognized attribute name "unknown"`},
+			}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mut.Lock()
+			validateErr = tc.err
+			mut.Unlock()
+
+			util.Eventually(t, func(t require.TestingT) {
+				code, body := dryRun(t)
+				require.Equal(t, tc.expectCode, code)
+				require.Equal(t, tc.expect, body)
+			})
+		})
+	}
+
+	// A dry run never reloads the config.
+	require.False(t, reloaded.Load())
+}
@@ -9,6 +9,12 @@ import (
 type serviceRoute struct {
 	Base    string
 	Handler http.Handler
+	// Paths are the handlers of the individual paths served by the service
+	// outside of Base, by path.
+	Paths map[string]http.Handler
+	// ExemptPaths are the paths of the requests to the service which are
+	// exempt from authentication, as returned by AuthExempter.
+	ExemptPaths []string
 }
 
 // serviceRoutes is a sortable collection of serviceRoute.
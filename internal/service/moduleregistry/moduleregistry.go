@@ -0,0 +1,518 @@
+// Package moduleregistry implements a service which resolves versioned
+// modules from named sources, so that import.registry blocks can pin modules
+// to semantic version constraints.
+package moduleregistry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/vcs"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ServiceName defines the name used for the module registry service.
+const ServiceName = "module_registry"
+
+// cacheExt is the file extension of module versions cached on disk.
+const cacheExt = ".json"
+
+// Options are used to configure the module registry service. Options are
+// constant for the lifetime of the service.
+type Options struct {
+	Logger      log.Logger            // Where to send logs.
+	Metrics     prometheus.Registerer // Where to register metrics.
+	StoragePath string                // Where to cache resolved modules on-disk.
+}
+
+// Arguments holds runtime settings for the module registry service.
+type Arguments struct {
+	ResolveInterval time.Duration     `river:"resolve_interval,attr,optional"`
+	Sources         []SourceArguments `river:"source,block,optional"`
+}
+
+// GetDefaultArguments populates the default values for the Arguments struct.
+func GetDefaultArguments() Arguments {
+	return Arguments{
+		ResolveInterval: 5 * time.Minute,
+	}
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = GetDefaultArguments()
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.ResolveInterval <= 0 {
+		return fmt.Errorf("resolve_interval must be greater than 0")
+	}
+	names := make(map[string]struct{}, len(a.Sources))
+	for _, src := range a.Sources {
+		if _, ok := names[src.Name]; ok {
+			return fmt.Errorf("source %q is defined more than once", src.Name)
+		}
+		names[src.Name] = struct{}{}
+	}
+	return nil
+}
+
+// SourceArguments defines a named source of module versions. Exactly one of
+// Git and HTTP must be set.
+type SourceArguments struct {
+	Name string         `river:",label"`
+	Git  *GitArguments  `river:"git,block,optional"`
+	HTTP *HTTPArguments `river:"http,block,optional"`
+}
+
+// Validate implements river.Validator.
+func (a *SourceArguments) Validate() error {
+	if a.Name == "" {
+		return fmt.Errorf("source must have a non-empty label")
+	}
+	if (a.Git == nil) == (a.HTTP == nil) {
+		return fmt.Errorf("source %q must have exactly one of the git and http blocks", a.Name)
+	}
+	return nil
+}
+
+// GitArguments resolves module versions from the tags of a Git repository.
+type GitArguments struct {
+	Repository    string            `river:"repository,attr"`
+	Path          string            `river:"path,attr"`
+	TagPrefix     string            `river:"tag_prefix,attr,optional"`
+	GitAuthConfig vcs.GitAuthConfig `river:",squash"`
+}
+
+// HTTPArguments resolves module versions from an index served over HTTP.
+type HTTPArguments struct {
+	URL              string                   `river:"url,attr"`
+	HTTPClientConfig *config.HTTPClientConfig `river:",squash"`
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *HTTPArguments) SetToDefault() {
+	*a = HTTPArguments{
+		HTTPClientConfig: config.CloneDefaultHTTPClientConfig(),
+	}
+}
+
+// Validate implements river.Validator.
+func (a *HTTPArguments) Validate() error {
+	// We must explicitly Validate because HTTPClientConfig is squashed and it
+	// won't run otherwise
+	if a.HTTPClientConfig != nil {
+		return a.HTTPClientConfig.Validate()
+	}
+	return nil
+}
+
+// Module is a resolved version of a module.
+type Module struct {
+	Version string
+	Content map[string]string
+}
+
+// Registry resolves modules from the sources of the module registry. It's
+// the Data of the module registry service.
+type Registry interface {
+	// Resolve returns the newest version of the module of the named source
+	// which matches the semantic version constraint.
+	Resolve(ctx context.Context, source, constraint string) (Module, error)
+
+	// Subscribe returns a channel which is notified whenever the available
+	// versions of any source change, until the returned function is called.
+	Subscribe() (<-chan struct{}, func())
+}
+
+// Service implements the module registry service.
+type Service struct {
+	opts    Options
+	updated chan struct{}
+
+	resolves        prometheus.Counter
+	resolveFailures prometheus.Counter
+
+	// fetchMut serializes fetching module versions so that a version is only
+	// downloaded once.
+	fetchMut sync.Mutex
+
+	mut             sync.RWMutex
+	resolveInterval time.Duration
+	sources         map[string]*source
+
+	subMut      sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+var (
+	_ service.Service = (*Service)(nil)
+	_ Registry        = (*Service)(nil)
+)
+
+// New returns a new instance of the module registry service.
+func New(opts Options) (*Service, error) {
+	if err := os.MkdirAll(filepath.Join(opts.StoragePath, ServiceName), 0750); err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		opts:            opts,
+		updated:         make(chan struct{}, 1),
+		resolveInterval: GetDefaultArguments().ResolveInterval,
+		sources:         make(map[string]*source),
+		subscribers:     make(map[chan struct{}]struct{}),
+
+		resolves: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_module_registry_resolves_total",
+			Help: "Total number of times the versions of module registry sources were resolved.",
+		}),
+		resolveFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_module_registry_resolve_failures_total",
+			Help: "Total number of times resolving the versions of a module registry source failed.",
+		}),
+	}
+
+	if opts.Metrics != nil {
+		for _, c := range []prometheus.Collector{s.resolves, s.resolveFailures} {
+			if err := opts.Metrics.Register(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// Data returns the [Registry] of the module registry service.
+func (s *Service) Data() any {
+	return s
+}
+
+// Definition returns the definition of the module registry service.
+func (s *Service) Definition() service.Definition {
+	return service.Definition{
+		Name:       ServiceName,
+		ConfigType: Arguments{},
+		DependsOn:  nil, // module_registry has no dependencies.
+		Stability:  featuregate.StabilityExperimental,
+	}
+}
+
+// Run implements [service.Service] and starts the module registry service.
+// The versions of every source are resolved again at the configured interval
+// until the provided context is canceled.
+func (s *Service) Run(ctx context.Context, host service.Host) error {
+	s.mut.RLock()
+	timer := time.NewTimer(s.resolveInterval)
+	s.mut.RUnlock()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-s.updated:
+			// Sources which changed are resolved when they're first used, so
+			// only the interval needs to be applied.
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			s.mut.RLock()
+			timer.Reset(s.resolveInterval)
+			s.mut.RUnlock()
+			s.notify()
+
+		case <-timer.C:
+			s.mut.RLock()
+			sources := make([]*source, 0, len(s.sources))
+			for _, src := range s.sources {
+				sources = append(sources, src)
+			}
+			interval := s.resolveInterval
+			s.mut.RUnlock()
+
+			var changed bool
+			for _, src := range sources {
+				if s.resolveVersions(ctx, src) {
+					changed = true
+				}
+			}
+			if changed {
+				s.notify()
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// Update implements [service.Service] and applies settings.
+func (s *Service) Update(newConfig any) error {
+	newArgs := newConfig.(Arguments)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	sources := make(map[string]*source, len(newArgs.Sources))
+	for _, args := range newArgs.Sources {
+		if existing, ok := s.sources[args.Name]; ok && reflect.DeepEqual(existing.args, args) {
+			sources[args.Name] = existing
+			continue
+		}
+		src, err := newSource(args, filepath.Join(s.opts.StoragePath, ServiceName))
+		if err != nil {
+			return fmt.Errorf("source %q: %w", args.Name, err)
+		}
+		sources[args.Name] = src
+	}
+	s.sources = sources
+	s.resolveInterval = newArgs.ResolveInterval
+
+	select {
+	case s.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Resolve implements [Registry].
+func (s *Service) Resolve(ctx context.Context, name, constraint string) (Module, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return Module{}, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+
+	s.mut.RLock()
+	src, ok := s.sources[name]
+	s.mut.RUnlock()
+	if !ok {
+		return Module{}, fmt.Errorf("module registry source %q isn't defined", name)
+	}
+
+	if !src.resolved() {
+		s.resolveVersions(ctx, src)
+	}
+	version, ref, ok := src.match(c)
+	if !ok {
+		return Module{}, fmt.Errorf("no version of source %q matches %q", name, constraint)
+	}
+
+	content, err := s.content(ctx, src, version, ref)
+	if err != nil {
+		return Module{}, fmt.Errorf("fetching version %s of source %q: %w", version, name, err)
+	}
+	return Module{Version: version.String(), Content: content}, nil
+}
+
+// Subscribe implements [Registry].
+func (s *Service) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	s.subMut.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.subMut.Unlock()
+
+	return ch, func() {
+		s.subMut.Lock()
+		defer s.subMut.Unlock()
+		delete(s.subscribers, ch)
+	}
+}
+
+// notify signals every subscriber without blocking.
+func (s *Service) notify() {
+	s.subMut.Lock()
+	defer s.subMut.Unlock()
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// resolveVersions lists the available versions of src and reports whether
+// they changed. If listing fails, the previously resolved versions are kept;
+// when there are none, the versions cached on disk are used so that modules
+// can still be loaded while the source is unavailable.
+func (s *Service) resolveVersions(ctx context.Context, src *source) bool {
+	s.resolves.Inc()
+	versions, err := src.list(ctx)
+	if err != nil {
+		s.resolveFailures.Inc()
+		level.Error(s.opts.Logger).Log("msg", "failed to resolve module versions", "source", src.args.Name, "err", err)
+
+		if src.resolved() {
+			return false
+		}
+		if versions, err = src.listCached(); err != nil {
+			level.Error(s.opts.Logger).Log("msg", "failed to read cached module versions", "source", src.args.Name, "err", err)
+			return false
+		}
+	}
+	return src.setVersions(versions)
+}
+
+// content returns the content of a version of src, fetching it if it isn't
+// cached on disk yet. Versions are immutable, so cached content never
+// expires.
+func (s *Service) content(ctx context.Context, src *source, version *semver.Version, ref string) (map[string]string, error) {
+	s.fetchMut.Lock()
+	defer s.fetchMut.Unlock()
+
+	path := filepath.Join(src.dir, version.String()+cacheExt)
+	if bb, err := os.ReadFile(path); err == nil {
+		var content map[string]string
+		if err := json.Unmarshal(bb, &content); err == nil {
+			return content, nil
+		}
+	}
+
+	if ref == "" {
+		return nil, fmt.Errorf("version is only known from the cache and its content is missing")
+	}
+	level.Info(s.opts.Logger).Log("msg", "fetching module version", "source", src.args.Name, "version", version.String())
+	content, err := src.fetch(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	bb, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path+".tmp", bb, 0640); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// source is a named source of module versions.
+type source struct {
+	args SourceArguments
+	dir  string // Where versions of the source are cached.
+
+	list  func(ctx context.Context) (map[string]string, error)
+	fetch func(ctx context.Context, ref string) (map[string]string, error)
+
+	mut      sync.RWMutex
+	versions []*semver.Version // Sorted from newest to oldest; nil until resolved.
+	refs     map[string]string // Tag or URL of each version.
+}
+
+func newSource(args SourceArguments, baseDir string) (*source, error) {
+	// Cached versions are stored by the location of the source rather than
+	// its name, so that they're discarded when the location changes.
+	var location string
+	if args.Git != nil {
+		location = strings.Join([]string{"git", args.Git.Repository, args.Git.Path, args.Git.TagPrefix}, "\x00")
+	} else {
+		location = strings.Join([]string{"http", args.HTTP.URL}, "\x00")
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(location))
+
+	src := &source{
+		args: args,
+		dir:  filepath.Join(baseDir, fmt.Sprintf("%s-%x", args.Name, h.Sum64())),
+	}
+	if err := os.MkdirAll(src.dir, 0750); err != nil {
+		return nil, err
+	}
+
+	if args.Git != nil {
+		g := &gitSource{args: *args.Git, dir: src.dir}
+		src.list, src.fetch = g.list, g.fetch
+	} else {
+		h, err := newHTTPSource(*args.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		src.list, src.fetch = h.list, h.fetch
+	}
+	return src, nil
+}
+
+func (src *source) resolved() bool {
+	src.mut.RLock()
+	defer src.mut.RUnlock()
+	return src.versions != nil
+}
+
+// setVersions replaces the versions of src with refs, which maps each
+// version to its tag or URL, and reports whether the versions changed.
+// Entries which aren't semantic versions are ignored.
+func (src *source) setVersions(refs map[string]string) bool {
+	versions := make([]*semver.Version, 0, len(refs))
+	byVersion := make(map[string]string, len(refs))
+	for raw, ref := range refs {
+		v, err := semver.NewVersion(raw)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+		byVersion[v.String()] = ref
+	}
+	sort.Sort(sort.Reverse(semver.Collection(versions)))
+
+	src.mut.Lock()
+	defer src.mut.Unlock()
+
+	changed := src.versions == nil || !reflect.DeepEqual(src.refs, byVersion)
+	src.versions = versions
+	src.refs = byVersion
+	return changed
+}
+
+// match returns the newest version of src matching c and its tag or URL.
+func (src *source) match(c *semver.Constraints) (*semver.Version, string, bool) {
+	src.mut.RLock()
+	defer src.mut.RUnlock()
+
+	for _, v := range src.versions {
+		if c.Check(v) {
+			return v, src.refs[v.String()], true
+		}
+	}
+	return nil, "", false
+}
+
+// listCached returns the versions of src which are cached on disk. Their
+// refs are empty, since only their cached content can be used.
+func (src *source) listCached() (map[string]string, error) {
+	entries, err := os.ReadDir(src.dir)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), cacheExt) {
+			continue
+		}
+		versions[strings.TrimSuffix(entry.Name(), cacheExt)] = ""
+	}
+	return versions, nil
+}
@@ -0,0 +1,184 @@
+package moduleregistry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/river"
+	"github.com/stretchr/testify/require"
+)
+
+// testIndex serves an HTTP index of module versions.
+type testIndex struct {
+	mut      sync.Mutex
+	versions map[string]string
+	modules  map[string]string
+	down     bool
+}
+
+func (ti *testIndex) set(versions map[string]string) {
+	ti.mut.Lock()
+	defer ti.mut.Unlock()
+	ti.versions = versions
+}
+
+func (ti *testIndex) setDown(down bool) {
+	ti.mut.Lock()
+	defer ti.mut.Unlock()
+	ti.down = down
+}
+
+func (ti *testIndex) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ti.mut.Lock()
+	defer ti.mut.Unlock()
+
+	if ti.down {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if r.URL.Path == "/index.json" {
+		_ = json.NewEncoder(w).Encode(httpIndex{Versions: ti.versions})
+		return
+	}
+	module, ok := ti.modules[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	_, _ = w.Write([]byte(module))
+}
+
+func newTestService(t *testing.T, storagePath, indexURL string) *Service {
+	t.Helper()
+	s, err := New(Options{Logger: log.NewNopLogger(), StoragePath: storagePath})
+	require.NoError(t, err)
+
+	args := GetDefaultArguments()
+	args.Sources = []SourceArguments{{
+		Name: "lib",
+		HTTP: &HTTPArguments{URL: indexURL, HTTPClientConfig: config.CloneDefaultHTTPClientConfig()},
+	}}
+	require.NoError(t, s.Update(args))
+	return s
+}
+
+func TestResolve(t *testing.T) {
+	index := &testIndex{
+		versions: map[string]string{
+			"1.1.0":  "v1.1.0/lib.river",
+			"1.2.0":  "v1.2.0/lib.river",
+			"2.0.0":  "/v2.0.0/lib.river",
+			"latest": "v2.0.0/lib.river", // Not a version; ignored.
+		},
+		modules: map[string]string{
+			"/v1.1.0/lib.river": `declare "v110" {}`,
+			"/v1.2.0/lib.river": `declare "v120" {}`,
+			"/v2.0.0/lib.river": `declare "v200" {}`,
+			"/v1.3.0/lib.river": `declare "v130" {}`,
+		},
+	}
+	srv := httptest.NewServer(index)
+	defer srv.Close()
+
+	storagePath := t.TempDir()
+	s := newTestService(t, storagePath, srv.URL+"/index.json")
+	ctx := context.Background()
+
+	module, err := s.Resolve(ctx, "lib", ">=1.2 <2")
+	require.NoError(t, err)
+	require.Equal(t, Module{Version: "1.2.0", Content: map[string]string{"lib.river": `declare "v120" {}`}}, module)
+
+	module, err = s.Resolve(ctx, "lib", "*")
+	require.NoError(t, err)
+	require.Equal(t, "2.0.0", module.Version)
+
+	_, err = s.Resolve(ctx, "lib", ">=3")
+	require.EqualError(t, err, `no version of source "lib" matches ">=3"`)
+	_, err = s.Resolve(ctx, "other", "*")
+	require.EqualError(t, err, `module registry source "other" isn't defined`)
+
+	// New versions are picked up when the source is resolved again, and
+	// subscribers are notified.
+	updates, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+	index.set(map[string]string{"1.2.0": "v1.2.0/lib.river", "1.3.0": "v1.3.0/lib.river"})
+	s.mut.RLock()
+	src := s.sources["lib"]
+	s.mut.RUnlock()
+	require.True(t, s.resolveVersions(ctx, src))
+	s.notify()
+	select {
+	case <-updates:
+	case <-time.After(time.Second):
+		t.Fatal("subscriber wasn't notified")
+	}
+	module, err = s.Resolve(ctx, "lib", ">=1.2 <2")
+	require.NoError(t, err)
+	require.Equal(t, "1.3.0", module.Version)
+
+	// Resolved versions are cached on disk and used while the source is
+	// unavailable.
+	index.setDown(true)
+	restarted := newTestService(t, storagePath, srv.URL+"/index.json")
+	module, err = restarted.Resolve(ctx, "lib", ">=1.2 <2")
+	require.NoError(t, err)
+	require.Equal(t, Module{Version: "1.3.0", Content: map[string]string{"lib.river": `declare "v130" {}`}}, module)
+}
+
+func TestArguments(t *testing.T) {
+	var args Arguments
+	err := river.Unmarshal([]byte(`
+		resolve_interval = "1m"
+
+		source "lib" {
+			http {
+				url = "https://example.com/index.json"
+			}
+		}
+
+		source "k8s" {
+			git {
+				repository = "https://github.com/example/modules.git"
+				path       = "k8s"
+				tag_prefix = "k8s/"
+			}
+		}
+	`), &args)
+	require.NoError(t, err)
+	require.Len(t, args.Sources, 2)
+	require.Equal(t, "k8s/", args.Sources[1].Git.TagPrefix)
+
+	err = river.Unmarshal([]byte(`
+		source "lib" {
+			http {
+				url = "https://example.com/index.json"
+			}
+			git {
+				repository = "https://github.com/example/modules.git"
+				path       = "lib"
+			}
+		}
+	`), &args)
+	require.ErrorContains(t, err, `source "lib" must have exactly one of the git and http blocks`)
+
+	err = river.Unmarshal([]byte(`
+		source "lib" {
+			http {
+				url = "https://example.com/index.json"
+			}
+		}
+		source "lib" {
+			http {
+				url = "https://example.com/other.json"
+			}
+		}
+	`), &args)
+	require.ErrorContains(t, err, `source "lib" is defined more than once`)
+}
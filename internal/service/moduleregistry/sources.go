@@ -0,0 +1,182 @@
+package moduleregistry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/agent/internal/vcs"
+	commonconfig "github.com/prometheus/common/config"
+)
+
+const (
+	// maxIndexSize is the maximum size of an HTTP index.
+	maxIndexSize = 1 << 20
+	// maxModuleSize is the maximum size of a module fetched over HTTP.
+	maxModuleSize = 10 << 20
+)
+
+// gitSource resolves versions from the tags of a Git repository. A tag is a
+// version if it starts with the tag prefix and the rest of the tag is a
+// semantic version, such as v1.2.3.
+type gitSource struct {
+	args GitArguments
+	dir  string
+}
+
+func (g *gitSource) list(ctx context.Context) (map[string]string, error) {
+	tags, err := vcs.ListTags(ctx, g.args.Repository, g.args.GitAuthConfig)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string)
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, g.args.TagPrefix) {
+			continue
+		}
+		versions[strings.TrimPrefix(tag, g.args.TagPrefix)] = tag
+	}
+	return versions, nil
+}
+
+// fetch clones the repository at the given tag into a temporary directory
+// and reads the module from it. If the path is a directory, every .river
+// file in it is read.
+func (g *gitSource) fetch(ctx context.Context, tag string) (map[string]string, error) {
+	dir, err := os.MkdirTemp(g.dir, "clone-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	repo, err := vcs.NewGitRepo(ctx, dir, vcs.GitRepoOptions{
+		Repository: g.args.Repository,
+		Revision:   tag,
+		Auth:       g.args.GitAuthConfig,
+		Submodules: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := repo.Stat(g.args.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		bb, err := repo.ReadFile(g.args.Path)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{g.args.Path: string(bb)}, nil
+	}
+
+	files, err := repo.ReadDir(g.args.Path)
+	if err != nil {
+		return nil, err
+	}
+	content := make(map[string]string)
+	for _, fi := range files {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".river") {
+			continue
+		}
+		bb, err := repo.ReadFile(filepath.Join(g.args.Path, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		content[fi.Name()] = string(bb)
+	}
+	return content, nil
+}
+
+// httpIndex is the index of the versions of a module served over HTTP. It
+// maps versions to the URL of the module, which may be relative to the
+// index.
+type httpIndex struct {
+	Versions map[string]string `json:"versions"`
+}
+
+// httpSource resolves versions from an index served over HTTP.
+type httpSource struct {
+	url    *url.URL
+	client *http.Client
+}
+
+func newHTTPSource(args HTTPArguments) (*httpSource, error) {
+	u, err := url.Parse(args.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	client, err := commonconfig.NewClientFromConfig(*args.HTTPClientConfig.Convert(), ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	return &httpSource{url: u, client: client}, nil
+}
+
+func (h *httpSource) list(ctx context.Context) (map[string]string, error) {
+	body, err := h.get(ctx, h.url.String(), maxIndexSize)
+	if err != nil {
+		return nil, err
+	}
+	var index httpIndex
+	if err := json.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("decoding index: %w", err)
+	}
+
+	versions := make(map[string]string, len(index.Versions))
+	for version, ref := range index.Versions {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL %q for version %s: %w", ref, version, err)
+		}
+		versions[version] = h.url.ResolveReference(u).String()
+	}
+	return versions, nil
+}
+
+// fetch downloads the module at u. The module is keyed by the name of the
+// file in the URL.
+func (h *httpSource) fetch(ctx context.Context, u string) (map[string]string, error) {
+	body, err := h.get(ctx, u, maxModuleSize)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{path.Base(parsed.Path): string(body)}, nil
+}
+
+func (h *httpSource) get(ctx context.Context, u string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unexpected status code %d fetching %s", resp.StatusCode, u)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("response from %s exceeds %d bytes", u, limit)
+	}
+	return body, nil
+}
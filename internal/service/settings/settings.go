@@ -0,0 +1,154 @@
+// Package settings implements the agent_settings service, which allows
+// settings of the run command to be provided in the config file.
+package settings
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/service"
+)
+
+// ServiceName defines the name used for the agent_settings service.
+const ServiceName = "agent_settings"
+
+// Options are used to configure the agent_settings service. Options are
+// constant for the lifetime of the agent_settings service.
+type Options struct {
+	// Initial holds the settings which were read from the config file when the
+	// process started. Changes to settings which can't be reloaded are
+	// detected by comparing against Initial.
+	Initial Arguments
+
+	// OnUpdate is invoked with the new settings whenever they change without
+	// changing a setting which can't be reloaded. May be nil.
+	OnUpdate func(Arguments) error
+}
+
+// Arguments holds the settings of the agent. Unset fields fall back to the
+// value of the matching command-line flag.
+type Arguments struct {
+	MemoryAddr   string            `river:"memory_addr,attr,optional"`
+	UIPathPrefix string            `river:"ui_path_prefix,attr,optional"`
+	StoragePath  string            `river:"storage_path,attr,optional"`
+	Cluster      *ClusterArguments `river:"cluster,block,optional"`
+}
+
+// ClusterArguments holds the clustering settings of the agent.
+type ClusterArguments struct {
+	Enabled             bool          `river:"enabled,attr,optional"`
+	NodeName            string        `river:"node_name,attr,optional"`
+	AdvertiseAddress    string        `river:"advertise_address,attr,optional"`
+	AdvertiseInterfaces []string      `river:"advertise_interfaces,attr,optional"`
+	JoinAddresses       []string      `river:"join_addresses,attr,optional"`
+	DiscoverPeers       string        `river:"discover_peers,attr,optional"`
+	RejoinInterval      time.Duration `river:"rejoin_interval,attr,optional"`
+	MaxJoinPeers        int           `river:"max_join_peers,attr,optional"`
+	Name                string        `river:"name,attr,optional"`
+}
+
+// SetToDefault implements river.Defaulter. Defining the cluster block enables
+// clustering unless enabled is set to false.
+func (args *ClusterArguments) SetToDefault() {
+	*args = ClusterArguments{Enabled: true}
+}
+
+// Validate implements river.Validator.
+func (args *ClusterArguments) Validate() error {
+	if len(args.JoinAddresses) > 0 && args.DiscoverPeers != "" {
+		return fmt.Errorf("at most one of join_addresses and discover_peers may be set")
+	}
+	if args.RejoinInterval < 0 {
+		return fmt.Errorf("rejoin_interval must not be negative")
+	}
+	if args.MaxJoinPeers < 0 {
+		return fmt.Errorf("max_join_peers must not be negative")
+	}
+	return nil
+}
+
+// NonReloadableChanges returns the names of the settings which differ between
+// a and b and can't be changed without restarting the process.
+func NonReloadableChanges(a, b Arguments) []string {
+	var changed []string
+	check := func(name string, x, y any) {
+		if !reflect.DeepEqual(x, y) {
+			changed = append(changed, name)
+		}
+	}
+
+	check("memory_addr", a.MemoryAddr, b.MemoryAddr)
+	check("ui_path_prefix", a.UIPathPrefix, b.UIPathPrefix)
+	check("storage_path", a.StoragePath, b.StoragePath)
+
+	var ac, bc ClusterArguments
+	if a.Cluster != nil {
+		ac = *a.Cluster
+	}
+	if b.Cluster != nil {
+		bc = *b.Cluster
+	}
+	check("cluster.enabled", ac.Enabled, bc.Enabled)
+	check("cluster.node_name", ac.NodeName, bc.NodeName)
+	check("cluster.advertise_address", ac.AdvertiseAddress, bc.AdvertiseAddress)
+	check("cluster.advertise_interfaces", ac.AdvertiseInterfaces, bc.AdvertiseInterfaces)
+	check("cluster.rejoin_interval", ac.RejoinInterval, bc.RejoinInterval)
+	check("cluster.name", ac.Name, bc.Name)
+
+	// cluster.join_addresses, cluster.discover_peers, and
+	// cluster.max_join_peers are reloadable; they're used the next time the
+	// node rejoins the cluster.
+	return changed
+}
+
+// Service implements the agent_settings service.
+type Service struct {
+	opts Options
+}
+
+var _ service.Service = (*Service)(nil)
+
+// New returns a new instance of the agent_settings service.
+func New(opts Options) *Service {
+	return &Service{opts: opts}
+}
+
+// Definition returns the definition of the agent_settings service.
+func (s *Service) Definition() service.Definition {
+	return service.Definition{
+		Name:       ServiceName,
+		ConfigType: Arguments{},
+		DependsOn:  nil, // agent_settings has no dependencies.
+		Stability:  featuregate.StabilityExperimental,
+	}
+}
+
+// Run implements [service.Service]. Settings are applied by the process
+// when it starts and through Update, so Run has nothing to do.
+func (s *Service) Run(ctx context.Context, host service.Host) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements [service.Service]. It returns an error if newConfig
+// changes a setting which can't be reloaded.
+func (s *Service) Update(newConfig any) error {
+	newArgs := newConfig.(Arguments)
+
+	if changed := NonReloadableChanges(s.opts.Initial, newArgs); len(changed) > 0 {
+		return fmt.Errorf("changing %s requires restarting the agent", strings.Join(changed, ", "))
+	}
+	if s.opts.OnUpdate != nil {
+		return s.opts.OnUpdate(newArgs)
+	}
+	return nil
+}
+
+// Data is a no-op for the agent_settings service.
+func (s *Service) Data() any {
+	return nil
+}
@@ -0,0 +1,70 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/river"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRiverConfig(t *testing.T) {
+	cfg := `
+		ui_path_prefix = "/agent/"
+
+		cluster {
+			join_addresses  = ["agent-0:12345", "agent-1:12345"]
+			rejoin_interval = "30s"
+		}
+	`
+	var args Arguments
+	require.NoError(t, river.Unmarshal([]byte(cfg), &args))
+	require.Equal(t, Arguments{
+		UIPathPrefix: "/agent/",
+		Cluster: &ClusterArguments{
+			Enabled:        true,
+			JoinAddresses:  []string{"agent-0:12345", "agent-1:12345"},
+			RejoinInterval: 30 * time.Second,
+		},
+	}, args)
+
+	cfg = `
+		cluster {
+			join_addresses = ["agent-0:12345"]
+			discover_peers = "provider=k8s"
+		}
+	`
+	require.ErrorContains(t, river.Unmarshal([]byte(cfg), &args), "at most one of join_addresses and discover_peers may be set")
+}
+
+func TestUpdate(t *testing.T) {
+	initial := Arguments{
+		StoragePath: "/var/lib/agent",
+		Cluster:     &ClusterArguments{Enabled: true, JoinAddresses: []string{"agent-0"}},
+	}
+
+	var updated []Arguments
+	svc := New(Options{
+		Initial: initial,
+		OnUpdate: func(a Arguments) error {
+			updated = append(updated, a)
+			return nil
+		},
+	})
+
+	// Reloadable settings are passed through.
+	reloadable := Arguments{
+		StoragePath: "/var/lib/agent",
+		Cluster:     &ClusterArguments{Enabled: true, JoinAddresses: []string{"agent-1"}, MaxJoinPeers: 3},
+	}
+	require.NoError(t, svc.Update(reloadable))
+	require.Equal(t, []Arguments{reloadable}, updated)
+
+	// Other settings are rejected.
+	err := svc.Update(Arguments{
+		StoragePath: "/data",
+		Cluster:     &ClusterArguments{Enabled: false},
+	})
+	require.EqualError(t, err, "changing storage_path, cluster.enabled requires restarting the agent")
+	require.Len(t, updated, 1)
+}
@@ -21,7 +21,8 @@ const ServiceName = "ui"
 // Options are used to configure the UI service. Options are constant for the
 // lifetime of the UI service.
 type Options struct {
-	UIPrefix string // Path prefix to host the UI at.
+	UIPrefix    string // Path prefix to host the UI at.
+	EnablePProf bool   // Whether CPU profiles can be collected through the API.
 }
 
 // Service implements the UI service.
@@ -75,7 +76,7 @@ func (s *Service) Data() any {
 func (s *Service) ServiceHandler(host service.Host) (base string, handler http.Handler) {
 	r := mux.NewRouter()
 
-	fa := api.NewFlowAPI(host)
+	fa := api.NewFlowAPI(host, s.opts.EnablePProf)
 	fa.RegisterRoutes(path.Join(s.opts.UIPrefix, "/api/v0/web"), r)
 	ui.RegisterRoutes(s.opts.UIPrefix, r)
 
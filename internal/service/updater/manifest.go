@@ -0,0 +1,176 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// maxArtifactSize is the largest artifact which will be downloaded.
+const maxArtifactSize = 512 << 20
+
+// Manifest describes the releases available from the update URL.
+type Manifest struct {
+	// Channels maps the name of a release channel to its current release.
+	Channels map[string]Release `json:"channels"`
+}
+
+// Release is a single version of the agent.
+type Release struct {
+	Version   string     `json:"version"`
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// Artifact is the binary of a release for a single platform.
+type Artifact struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// URL of the binary. Relative URLs are resolved against the URL of the
+	// manifest.
+	URL string `json:"url"`
+	// Hex-encoded SHA-256 checksum of the binary.
+	SHA256 string `json:"sha256"`
+	// Base64-encoded ed25519 signature of the message returned by
+	// signedMessage, which binds the checksum to the release version and
+	// platform.
+	Signature string `json:"signature"`
+}
+
+// signedMessage returns the message signed for an artifact of the given
+// release version. Signing the version and platform along with the checksum
+// prevents a validly signed binary from being announced as a different
+// version or for a different platform.
+func (a Artifact) signedMessage(version string) []byte {
+	return []byte(strings.Join([]string{
+		"grafana-agent-release-v1",
+		version,
+		a.OS,
+		a.Arch,
+		strings.ToLower(a.SHA256),
+	}, "\n"))
+}
+
+// artifactFor returns the artifact of the release for the given platform.
+func (r Release) artifactFor(goos, goarch string) (Artifact, bool) {
+	for _, a := range r.Artifacts {
+		if a.OS == goos && a.Arch == goarch {
+			return a, true
+		}
+	}
+	return Artifact{}, false
+}
+
+// parsePublicKey parses a PEM-encoded ed25519 public key.
+func parsePublicKey(in string) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode([]byte(in))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an ed25519 public key, got %T", key)
+	}
+	return edKey, nil
+}
+
+// verify checks that the contents of an artifact match its checksum and that
+// the checksum, platform, and release version were signed by key.
+func (a Artifact) verify(key ed25519.PublicKey, version string, contents []byte) error {
+	sum := sha256.Sum256(contents)
+	if expect, err := hex.DecodeString(a.SHA256); err != nil || len(expect) != sha256.Size {
+		return fmt.Errorf("invalid sha256 checksum %q", a.SHA256)
+	} else if string(expect) != string(sum[:]) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %x", a.SHA256, sum)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	if !ed25519.Verify(key, a.signedMessage(version), sig) {
+		return fmt.Errorf("signature verification failed")
+	}
+	return nil
+}
+
+// isNewer returns true if candidate is a strictly newer semantic version than
+// current. Both versions must be valid semantic versions.
+func isNewer(current, candidate string) (bool, error) {
+	cur, err := semver.NewVersion(current)
+	if err != nil {
+		return false, fmt.Errorf("running version %q is not a valid semantic version", current)
+	}
+	next, err := semver.NewVersion(candidate)
+	if err != nil {
+		return false, fmt.Errorf("release version %q is not a valid semantic version", candidate)
+	}
+	return next.GreaterThan(cur), nil
+}
+
+// fetchManifest downloads and decodes the manifest at manifestURL.
+func fetchManifest(ctx context.Context, client *http.Client, manifestURL string) (*Manifest, error) {
+	body, err := get(ctx, client, manifestURL, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("decoding manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// fetchArtifact downloads the artifact, resolving its URL against the URL of
+// the manifest.
+func fetchArtifact(ctx context.Context, client *http.Client, manifestURL string, a Artifact) ([]byte, error) {
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(a.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact URL %q: %w", a.URL, err)
+	}
+	return get(ctx, client, base.ResolveReference(ref).String(), maxArtifactSize)
+}
+
+func get(ctx context.Context, client *http.Client, u string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unexpected status code %d fetching %s", resp.StatusCode, u)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("response from %s exceeds %d bytes", u, limit)
+	}
+	return body, nil
+}
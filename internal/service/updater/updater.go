@@ -0,0 +1,344 @@
+// Package updater implements a service which keeps the agent binary up to
+// date with a release channel.
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/internal/build"
+	"github.com/grafana/agent/internal/component/common/config"
+	"github.com/grafana/agent/internal/featuregate"
+	"github.com/grafana/agent/internal/flow/logging/level"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/service/cluster"
+	"github.com/grafana/ckit/peer"
+	"github.com/prometheus/client_golang/prometheus"
+	commonconfig "github.com/prometheus/common/config"
+)
+
+// ServiceName defines the name used for the updater service.
+const ServiceName = "updater"
+
+// Options are used to configure the updater service. Options are constant for
+// the lifetime of the updater service.
+type Options struct {
+	Logger      log.Logger            // Where to send logs.
+	Metrics     prometheus.Registerer // Where to register metrics.
+	StoragePath string                // Where to stage downloaded binaries.
+
+	// Restart is invoked with the path of a verified, staged binary once the
+	// agent is ready to be restarted into it. Restart must shut down the
+	// process gracefully before running the new binary.
+	Restart func(binaryPath string)
+}
+
+// Arguments holds runtime settings for the updater service.
+type Arguments struct {
+	URL              string                   `river:"url,attr,optional"`
+	Channel          string                   `river:"channel,attr,optional"`
+	PublicKey        string                   `river:"public_key,attr,optional"`
+	CheckInterval    time.Duration            `river:"check_interval,attr,optional"`
+	DrainPeriod      time.Duration            `river:"drain_period,attr,optional"`
+	HTTPClientConfig *config.HTTPClientConfig `river:",squash"`
+}
+
+// GetDefaultArguments populates the default values for the Arguments struct.
+func GetDefaultArguments() Arguments {
+	return Arguments{
+		Channel:          "stable",
+		CheckInterval:    1 * time.Hour,
+		DrainPeriod:      30 * time.Second,
+		HTTPClientConfig: config.CloneDefaultHTTPClientConfig(),
+	}
+}
+
+// SetToDefault implements river.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = GetDefaultArguments()
+}
+
+// Validate implements river.Validator.
+func (a *Arguments) Validate() error {
+	if a.URL != "" {
+		if runtime.GOOS == "windows" {
+			return fmt.Errorf("the updater is not supported on Windows")
+		}
+		if a.PublicKey == "" {
+			return fmt.Errorf("public_key must be set when url is set")
+		}
+		if _, err := parsePublicKey(a.PublicKey); err != nil {
+			return fmt.Errorf("invalid public_key: %w", err)
+		}
+	}
+	if a.Channel == "" {
+		return fmt.Errorf("channel must not be empty")
+	}
+	if a.CheckInterval <= 0 {
+		return fmt.Errorf("check_interval must be greater than 0")
+	}
+	if a.DrainPeriod < 0 {
+		return fmt.Errorf("drain_period must not be negative")
+	}
+
+	// We must explicitly Validate because HTTPClientConfig is squashed and it
+	// won't run otherwise
+	if a.HTTPClientConfig != nil {
+		return a.HTTPClientConfig.Validate()
+	}
+	return nil
+}
+
+// Service implements the updater service.
+type Service struct {
+	opts    Options
+	updated chan struct{}
+
+	checks        prometheus.Counter
+	checkFailures prometheus.Counter
+
+	mut    sync.RWMutex
+	args   Arguments
+	key    ed25519.PublicKey
+	client *http.Client // nil when the service is disabled.
+}
+
+var _ service.Service = (*Service)(nil)
+
+// New returns a new instance of the updater service.
+func New(opts Options) (*Service, error) {
+	if err := os.MkdirAll(filepath.Join(opts.StoragePath, ServiceName), 0750); err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		opts:    opts,
+		updated: make(chan struct{}, 1),
+		args:    Arguments{HTTPClientConfig: config.CloneDefaultHTTPClientConfig()},
+
+		checks: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_updater_checks_total",
+			Help: "Total number of times the release channel was checked for updates.",
+		}),
+		checkFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "agent_updater_check_failures_total",
+			Help: "Total number of update checks which failed.",
+		}),
+	}
+
+	if opts.Metrics != nil {
+		for _, c := range []prometheus.Collector{s.checks, s.checkFailures} {
+			if err := opts.Metrics.Register(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+// Data is a no-op for the updater service.
+func (s *Service) Data() any {
+	return nil
+}
+
+// Definition returns the definition of the updater service.
+func (s *Service) Definition() service.Definition {
+	return service.Definition{
+		Name:       ServiceName,
+		ConfigType: Arguments{},
+		// The cluster service is used to drain the node before restarting.
+		DependsOn: []string{cluster.ServiceName},
+		Stability: featuregate.StabilityExperimental,
+	}
+}
+
+// Run implements [service.Service] and starts the updater service. It will
+// run until the provided context is canceled or the agent is restarted into a
+// new version.
+func (s *Service) Run(ctx context.Context, host service.Host) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-s.updated:
+			// Check straight away with the new settings.
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(0)
+
+		case <-timer.C:
+			s.mut.RLock()
+			args, key, client := s.args, s.key, s.client
+			s.mut.RUnlock()
+
+			if client == nil {
+				// Disabled; wait for the next update.
+				continue
+			}
+
+			s.checks.Inc()
+			staged, err := s.check(ctx, args, key, client)
+			if err != nil {
+				s.checkFailures.Inc()
+				level.Error(s.opts.Logger).Log("msg", "failed to check for updates", "err", err)
+			}
+			if staged == "" {
+				timer.Reset(args.CheckInterval)
+				continue
+			}
+
+			s.drain(ctx, host, args.DrainPeriod)
+			if ctx.Err() != nil {
+				return nil
+			}
+			level.Info(s.opts.Logger).Log("msg", "restarting into updated binary", "path", staged)
+			s.opts.Restart(staged)
+			return nil
+		}
+	}
+}
+
+// Update implements [service.Service] and applies settings.
+func (s *Service) Update(newConfig any) error {
+	newArgs := newConfig.(Arguments)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if newArgs.URL == "" {
+		// The block was never set or was removed; stop checking for updates.
+		s.client = nil
+		s.args = newArgs
+		return nil
+	}
+
+	key, err := parsePublicKey(newArgs.PublicKey)
+	if err != nil {
+		return err
+	}
+	if s.client == nil || !reflect.DeepEqual(s.args.HTTPClientConfig, newArgs.HTTPClientConfig) {
+		client, err := commonconfig.NewClientFromConfig(*newArgs.HTTPClientConfig.Convert(), ServiceName)
+		if err != nil {
+			return err
+		}
+		s.client = client
+	}
+	s.key = key
+	s.args = newArgs
+
+	select {
+	case s.updated <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// check looks for a new release on the configured channel. If one is found,
+// its binary is downloaded, verified, and staged on disk, and the path to the
+// staged binary is returned.
+func (s *Service) check(ctx context.Context, args Arguments, key ed25519.PublicKey, client *http.Client) (string, error) {
+	m, err := fetchManifest(ctx, client, args.URL)
+	if err != nil {
+		return "", err
+	}
+	release, ok := m.Channels[args.Channel]
+	if !ok {
+		return "", fmt.Errorf("channel %q not found in manifest", args.Channel)
+	}
+	// Only ever move forward, so that an old release can't be replayed to
+	// downgrade the agent.
+	newer, err := isNewer(build.Version, release.Version)
+	if err != nil {
+		return "", err
+	} else if !newer {
+		level.Debug(s.opts.Logger).Log("msg", "agent is up to date", "version", build.Version, "channel", args.Channel, "channel_version", release.Version)
+		return "", nil
+	}
+
+	artifact, ok := release.artifactFor(runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return "", fmt.Errorf("release %s has no artifact for %s/%s", release.Version, runtime.GOOS, runtime.GOARCH)
+	}
+
+	level.Info(s.opts.Logger).Log("msg", "downloading update", "version", release.Version, "current_version", build.Version)
+	contents, err := fetchArtifact(ctx, client, args.URL, artifact)
+	if err != nil {
+		return "", fmt.Errorf("downloading release %s: %w", release.Version, err)
+	}
+	if err := artifact.verify(key, release.Version, contents); err != nil {
+		return "", fmt.Errorf("verifying release %s: %w", release.Version, err)
+	}
+
+	return s.stage(release.Version, contents)
+}
+
+// stage writes a verified binary to the storage path and returns its path.
+func (s *Service) stage(version string, contents []byte) (string, error) {
+	dir := filepath.Join(s.opts.StoragePath, ServiceName)
+	f, err := os.CreateTemp(dir, "agent-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(contents); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(f.Name(), 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "agent-"+filepath.Base(version))
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// drain moves the node to the Terminating state so that its peers take over
+// its share of work, then waits for period before returning.
+func (s *Service) drain(ctx context.Context, host service.Host, period time.Duration) {
+	svc, ok := host.GetService(cluster.ServiceName)
+	if !ok {
+		return
+	}
+	node, ok := svc.(interface {
+		ChangeState(ctx context.Context, targetState peer.State) error
+	})
+	if !ok {
+		return
+	}
+
+	level.Info(s.opts.Logger).Log("msg", "draining cluster node before restart", "drain_period", period)
+	changeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := node.ChangeState(changeCtx, peer.StateTerminating); err != nil {
+		level.Warn(s.opts.Logger).Log("msg", "failed to change cluster state to Terminating", "err", err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(period):
+	}
+}
@@ -0,0 +1,235 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/build"
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/service"
+	"github.com/grafana/agent/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdater(t *testing.T) {
+	setVersion(t, "v1.0.0")
+	pub, priv := newKeyPair(t)
+	binary := []byte("#!/bin/sh\necho new agent\n")
+
+	srv := newReleaseServer(t, "v99.0.0", signedArtifact(priv, "v99.0.0", binary), binary)
+
+	restarted := make(chan string, 1)
+	svc, err := New(Options{
+		Logger:      util.TestLogger(t),
+		StoragePath: t.TempDir(),
+		Restart:     func(path string) { restarted <- path },
+	})
+	require.NoError(t, err)
+
+	args := GetDefaultArguments()
+	args.URL = srv.URL + "/manifest.json"
+	args.PublicKey = pub
+	args.DrainPeriod = 0
+	require.NoError(t, args.Validate())
+	require.NoError(t, svc.Update(args))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = svc.Run(ctx, fakeHost{}) }()
+
+	select {
+	case path := <-restarted:
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, binary, contents)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "agent was not restarted")
+	}
+}
+
+func TestUpdater_Check(t *testing.T) {
+	setVersion(t, "v1.0.0")
+	pub, priv := newKeyPair(t)
+	_, otherPriv := newKeyPair(t)
+	binary := []byte("agent")
+
+	tests := []struct {
+		name     string
+		version  string
+		artifact Artifact
+		err      string
+	}{
+		{
+			name:     "signed by another key",
+			version:  "v2.0.0",
+			artifact: signedArtifact(otherPriv, "v2.0.0", binary),
+			err:      "signature verification failed",
+		},
+		{
+			name:     "signature for another version",
+			version:  "v2.0.0",
+			artifact: signedArtifact(priv, "v1.5.0", binary),
+			err:      "signature verification failed",
+		},
+		{
+			name:     "downgrade",
+			version:  "v0.9.0",
+			artifact: signedArtifact(priv, "v0.9.0", binary),
+		},
+		{
+			name:     "same version",
+			version:  "v1.0.0",
+			artifact: signedArtifact(priv, "v1.0.0", binary),
+		},
+		{
+			name:     "invalid version",
+			version:  "latest",
+			artifact: signedArtifact(priv, "latest", binary),
+			err:      `release version "latest" is not a valid semantic version`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newReleaseServer(t, tc.version, tc.artifact, binary)
+
+			svc, err := New(Options{Logger: util.TestLogger(t), StoragePath: t.TempDir()})
+			require.NoError(t, err)
+
+			args := GetDefaultArguments()
+			args.URL = srv.URL + "/manifest.json"
+			args.PublicKey = pub
+			require.NoError(t, svc.Update(args))
+
+			key, err := parsePublicKey(pub)
+			require.NoError(t, err)
+			staged, err := svc.check(context.Background(), args, key, svc.client)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Empty(t, staged)
+		})
+	}
+}
+
+func TestArtifact_Verify(t *testing.T) {
+	pub, priv := newKeyPair(t)
+	key, err := parsePublicKey(pub)
+	require.NoError(t, err)
+
+	contents := []byte("agent")
+	a := signedArtifact(priv, "v1.0.0", contents)
+	require.NoError(t, a.verify(key, "v1.0.0", contents))
+	require.ErrorContains(t, a.verify(key, "v1.0.0", []byte("tampered")), "checksum mismatch")
+	require.ErrorContains(t, a.verify(key, "v2.0.0", contents), "signature verification failed")
+
+	a.Arch = "other"
+	require.ErrorContains(t, a.verify(key, "v1.0.0", contents), "signature verification failed")
+}
+
+func TestIsNewer(t *testing.T) {
+	newer, err := isNewer("v1.0.0", "v1.0.1")
+	require.NoError(t, err)
+	require.True(t, newer)
+
+	newer, err = isNewer("v1.0.0", "v1.0.0-rc.1")
+	require.NoError(t, err)
+	require.False(t, newer)
+
+	_, err = isNewer("", "v1.0.0")
+	require.ErrorContains(t, err, "running version")
+}
+
+func TestValidate(t *testing.T) {
+	args := GetDefaultArguments()
+	args.URL = "https://releases.example.com/manifest.json"
+	require.EqualError(t, args.Validate(), "public_key must be set when url is set")
+
+	args.PublicKey = "not a key"
+	require.EqualError(t, args.Validate(), "invalid public_key: no PEM data found")
+}
+
+func newKeyPair(t *testing.T) (string, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), priv
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// setVersion sets the version of the running agent for the duration of the
+// test.
+func setVersion(t *testing.T, version string) {
+	prev := build.Version
+	build.Version = version
+	t.Cleanup(func() { build.Version = prev })
+}
+
+// signedArtifact returns an artifact of binary for the running platform,
+// signed with priv for the given release version.
+func signedArtifact(priv ed25519.PrivateKey, version string, binary []byte) Artifact {
+	a := Artifact{
+		OS:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+		URL:    "artifacts/agent",
+		SHA256: checksum(binary),
+	}
+	a.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, a.signedMessage(version)))
+	return a
+}
+
+// newReleaseServer serves a manifest announcing version on the stable channel
+// with a single artifact.
+func newReleaseServer(t *testing.T, version string, a Artifact, binary []byte) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/manifest.json", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(Manifest{Channels: map[string]Release{
+			"stable": {Version: version, Artifacts: []Artifact{a}},
+		}})
+	})
+	mux.HandleFunc("/artifacts/agent", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(binary)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+type fakeHost struct{}
+
+var _ service.Host = (fakeHost{})
+
+func (fakeHost) GetComponent(id component.ID, opts component.InfoOptions) (*component.Info, error) {
+	return nil, fmt.Errorf("no such component %s", id)
+}
+
+func (fakeHost) ListComponents(moduleID string, opts component.InfoOptions) ([]*component.Info, error) {
+	return nil, fmt.Errorf("no such module %q", moduleID)
+}
+
+func (fakeHost) GetServiceConsumers(serviceName string) []service.Consumer { return nil }
+
+func (fakeHost) NewController(id string) service.Controller { return nil }
+
+func (fakeHost) GetService(_ string) (service.Service, bool) { return nil, false }
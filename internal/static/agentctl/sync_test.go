@@ -99,6 +99,16 @@ type mockFuncPromClient struct {
 	GetConfigurationFunc    func(ctx context.Context, name string) (*instance.Config, error)
 	PutConfigurationFunc    func(ctx context.Context, name string, cfg *instance.Config) error
 	DeleteConfigurationFunc func(ctx context.Context, name string) error
+
+	ListConfigsMatchingFunc          func(ctx context.Context, selectors ...string) (*configapi.ListConfigurationsResponse, error)
+	GetConfigurationWithRevisionFunc func(ctx context.Context, name string) (*instance.Config, string, error)
+	PutConfigurationIfMatchFunc      func(ctx context.Context, name string, cfg *instance.Config, revision string) error
+	DeleteConfigurationIfMatchFunc   func(ctx context.Context, name string, revision string) error
+
+	PutConfigurationsFunc    func(ctx context.Context, cfgs []*instance.Config) (*configapi.BatchConfigurationsResponse, error)
+	DeleteConfigurationsFunc func(ctx context.Context, names []string) (*configapi.BatchConfigurationsResponse, error)
+
+	ConfigurationHistoryFunc func(ctx context.Context, name string) ([]configapi.ConfigEvent, error)
 }
 
 func (m mockFuncPromClient) Instances(ctx context.Context) ([]string, error) {
@@ -135,3 +145,52 @@ func (m mockFuncPromClient) DeleteConfiguration(ctx context.Context, name string
 	}
 	return errors.New("not implemented")
 }
+
+func (m mockFuncPromClient) PutConfigurations(ctx context.Context, cfgs []*instance.Config) (*configapi.BatchConfigurationsResponse, error) {
+	if m.PutConfigurationsFunc != nil {
+		return m.PutConfigurationsFunc(ctx, cfgs)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m mockFuncPromClient) DeleteConfigurations(ctx context.Context, names []string) (*configapi.BatchConfigurationsResponse, error) {
+	if m.DeleteConfigurationsFunc != nil {
+		return m.DeleteConfigurationsFunc(ctx, names)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m mockFuncPromClient) ConfigurationHistory(ctx context.Context, name string) ([]configapi.ConfigEvent, error) {
+	if m.ConfigurationHistoryFunc != nil {
+		return m.ConfigurationHistoryFunc(ctx, name)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m mockFuncPromClient) GetConfigurationWithRevision(ctx context.Context, name string) (*instance.Config, string, error) {
+	if m.GetConfigurationWithRevisionFunc != nil {
+		return m.GetConfigurationWithRevisionFunc(ctx, name)
+	}
+	return nil, "", errors.New("not implemented")
+}
+
+func (m mockFuncPromClient) PutConfigurationIfMatch(ctx context.Context, name string, cfg *instance.Config, revision string) error {
+	if m.PutConfigurationIfMatchFunc != nil {
+		return m.PutConfigurationIfMatchFunc(ctx, name, cfg, revision)
+	}
+	return errors.New("not implemented")
+}
+
+func (m mockFuncPromClient) DeleteConfigurationIfMatch(ctx context.Context, name string, revision string) error {
+	if m.DeleteConfigurationIfMatchFunc != nil {
+		return m.DeleteConfigurationIfMatchFunc(ctx, name, revision)
+	}
+	return errors.New("not implemented")
+}
+
+func (m mockFuncPromClient) ListConfigsMatching(ctx context.Context, selectors ...string) (*configapi.ListConfigurationsResponse, error) {
+	if m.ListConfigsMatchingFunc != nil {
+		return m.ListConfigsMatchingFunc(ctx, selectors...)
+	}
+	return nil, errors.New("not implemented")
+}
@@ -0,0 +1,299 @@
+package waltools
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+)
+
+// SegmentInfo describes a segment or checkpoint of a WAL.
+type SegmentInfo struct {
+	// Name of the segment file or checkpoint directory.
+	Name string
+
+	// Index of the segment, or of the last segment covered by a checkpoint.
+	Index int
+
+	// Checkpoint is true if the segment is a checkpoint.
+	Checkpoint bool
+
+	// Size of the segment on disk in bytes.
+	Size int64
+
+	// Series is the number of series records in the segment.
+	Series int
+
+	// Samples is the number of float and histogram samples in the segment.
+	Samples int
+
+	// From and To hold the timestamps of the oldest and newest samples in the
+	// segment. They are zero if the segment has no samples.
+	From, To time.Time
+
+	// Corruption is set if the segment can't be read past some offset.
+	Corruption *wlog.CorruptionErr
+}
+
+// Inspection describes the segments and checkpoints of a WAL.
+type Inspection struct {
+	// Segments holds the latest checkpoint, if any, followed by the segments
+	// of the WAL in order. Segments covered by the checkpoint are included
+	// but aren't read when the WAL is replayed.
+	Segments []SegmentInfo
+
+	// Size is the total size of the segments and checkpoint on disk.
+	Size int64
+}
+
+// Corrupted returns the first corrupted segment, or nil if no segment is
+// corrupted.
+func (i Inspection) Corrupted() *SegmentInfo {
+	for n := range i.Segments {
+		if i.Segments[n].Corruption != nil {
+			return &i.Segments[n]
+		}
+	}
+	return nil
+}
+
+// From returns the timestamp of the oldest sample across all segments.
+func (i Inspection) From() time.Time {
+	var from time.Time
+	for _, s := range i.Segments {
+		if !s.From.IsZero() && (from.IsZero() || s.From.Before(from)) {
+			from = s.From
+		}
+	}
+	return from
+}
+
+// To returns the timestamp of the newest sample across all segments.
+func (i Inspection) To() time.Time {
+	var to time.Time
+	for _, s := range i.Segments {
+		if s.To.After(to) {
+			to = s.To
+		}
+	}
+	return to
+}
+
+// Inspect reads every segment and the latest checkpoint of the WAL in walDir
+// independently, so that a corrupted segment doesn't prevent inspecting the
+// others. walDir isn't modified.
+func Inspect(walDir string) (Inspection, error) {
+	var res Inspection
+
+	checkpoint, checkpointIdx, err := wlog.LastCheckpoint(walDir)
+	if err != nil && !errors.Is(err, record.ErrNotFound) {
+		return res, err
+	}
+	if checkpoint != "" {
+		info := SegmentInfo{Name: filepath.Base(checkpoint), Index: checkpointIdx, Checkpoint: true}
+		if info.Size, err = dirSize(checkpoint); err != nil {
+			return res, err
+		}
+		sr, err := wlog.NewSegmentsReader(checkpoint)
+		if err != nil {
+			return res, err
+		}
+		inspectSegment(wlog.NewReader(sr), &info)
+		_ = sr.Close()
+		res.Segments = append(res.Segments, info)
+	}
+
+	first, last, err := wlog.Segments(walDir)
+	if err != nil {
+		return res, err
+	}
+	for i := first; i <= last && last >= 0; i++ {
+		name := wlog.SegmentName(walDir, i)
+		info := SegmentInfo{Name: filepath.Base(name), Index: i}
+
+		fi, err := os.Stat(name)
+		if err != nil {
+			return res, err
+		}
+		info.Size = fi.Size()
+
+		s, err := wlog.OpenReadSegment(name)
+		if err != nil {
+			return res, err
+		}
+		sr := wlog.NewSegmentBufReader(s)
+		inspectSegment(wlog.NewReader(sr), &info)
+		_ = sr.Close()
+		res.Segments = append(res.Segments, info)
+	}
+
+	for _, s := range res.Segments {
+		res.Size += s.Size
+	}
+	return res, nil
+}
+
+// inspectSegment reads the records of r into info, stopping at the first
+// corrupted record.
+func inspectSegment(r *wlog.Reader, info *SegmentInfo) {
+	var (
+		dec      record.Decoder
+		min, max int64 = math.MaxInt64, math.MinInt64
+	)
+	observe := func(t int64) {
+		if t < min {
+			min = t
+		}
+		if t > max {
+			max = t
+		}
+	}
+
+	var err error
+	for err == nil && r.Next() {
+		rec := r.Record()
+		switch dec.Type(rec) {
+		case record.Series:
+			var series []record.RefSeries
+			if series, err = dec.Series(rec, nil); err == nil {
+				info.Series += len(series)
+			}
+		case record.Samples:
+			var samples []record.RefSample
+			if samples, err = dec.Samples(rec, nil); err == nil {
+				info.Samples += len(samples)
+				for _, s := range samples {
+					observe(s.T)
+				}
+			}
+		case record.HistogramSamples:
+			var samples []record.RefHistogramSample
+			if samples, err = dec.HistogramSamples(rec, nil); err == nil {
+				info.Samples += len(samples)
+				for _, s := range samples {
+					observe(s.T)
+				}
+			}
+		case record.FloatHistogramSamples:
+			var samples []record.RefFloatHistogramSample
+			if samples, err = dec.FloatHistogramSamples(rec, nil); err == nil {
+				info.Samples += len(samples)
+				for _, s := range samples {
+					observe(s.T)
+				}
+			}
+		}
+	}
+
+	if err != nil {
+		// The record was read but can't be decoded.
+		info.Corruption = &wlog.CorruptionErr{Segment: info.Index, Offset: r.Offset(), Err: err}
+	} else if err := r.Err(); err != nil {
+		var cerr *wlog.CorruptionErr
+		if !errors.As(err, &cerr) {
+			cerr = &wlog.CorruptionErr{Segment: info.Index, Offset: r.Offset(), Err: err}
+		}
+		info.Corruption = cerr
+	}
+
+	if min <= max {
+		info.From = timestamp.Time(min)
+		info.To = timestamp.Time(max)
+	}
+}
+
+// Repair repairs the first corrupted segment of the WAL in walDir by
+// discarding every record from the corruption onwards, including the
+// segments after it. It returns the repaired segment, or nil if no segment is
+// corrupted. The agent using walDir must be stopped.
+func Repair(walDir string) (*SegmentInfo, error) {
+	ins, err := Inspect(walDir)
+	if err != nil {
+		return nil, err
+	}
+	corrupted := ins.Corrupted()
+	if corrupted == nil {
+		return nil, nil
+	} else if corrupted.Checkpoint {
+		return nil, fmt.Errorf("checkpoint %s is corrupted and can't be repaired: %w", corrupted.Name, corrupted.Corruption)
+	}
+
+	w, err := openWAL(walDir)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	if err := w.Repair(corrupted.Corruption); err != nil {
+		return nil, fmt.Errorf("repairing segment %s: %w", corrupted.Name, err)
+	}
+	return corrupted, nil
+}
+
+// TruncateBefore removes the segments of the WAL in walDir before segment,
+// freeing their disk space. Series records of the removed segments are kept
+// in a new checkpoint so that the remaining samples can still be sent, but
+// their samples are dropped. The agent using walDir must be stopped.
+func TruncateBefore(walDir string, segment int) error {
+	first, last, err := wlog.Segments(walDir)
+	if err != nil {
+		return err
+	} else if last < 0 {
+		return fmt.Errorf("no segments in %s", walDir)
+	} else if segment <= first {
+		return nil
+	} else if segment > last {
+		return fmt.Errorf("segment %d is after the last segment %d", segment, last)
+	}
+
+	w, err := openWAL(walDir)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	// Segments already covered by the latest checkpoint don't need a new one.
+	_, checkpointIdx, err := wlog.LastCheckpoint(walDir)
+	if err != nil && !errors.Is(err, record.ErrNotFound) {
+		return err
+	}
+	if err != nil || checkpointIdx < segment-1 {
+		keep := func(chunks.HeadSeriesRef) bool { return true }
+		if _, err := wlog.Checkpoint(log.NewNopLogger(), w, first, segment-1, keep, math.MaxInt64); err != nil {
+			return fmt.Errorf("create checkpoint: %w", err)
+		}
+	}
+	if err := w.Truncate(segment); err != nil {
+		return fmt.Errorf("truncate segments: %w", err)
+	}
+	return wlog.DeleteCheckpoints(walDir, segment-1)
+}
+
+// openWAL opens the WAL in walDir for writing. Like when the agent starts, a
+// new empty segment is created.
+func openWAL(walDir string) (*wlog.WL, error) {
+	return wlog.NewSize(log.NewNopLogger(), prometheus.NewRegistry(), walDir, wlog.DefaultSegmentSize, wlog.CompressionSnappy)
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
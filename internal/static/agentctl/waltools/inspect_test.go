@@ -0,0 +1,76 @@
+package waltools
+
+import (
+	"os"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/timestamp"
+	"github.com/prometheus/prometheus/tsdb/wlog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	walDir := setupTestWAL(t)
+	ins, err := Inspect(walDir)
+	require.NoError(t, err)
+	require.Nil(t, ins.Corrupted())
+
+	var names []string
+	for _, s := range ins.Segments {
+		names = append(names, s.Name)
+	}
+	require.Equal(t, []string{"checkpoint.00000001", "00000000", "00000001", "00000002", "00000003"}, names)
+
+	require.True(t, ins.Segments[0].Checkpoint)
+	require.Equal(t, 21, ins.Segments[0].Series)
+	require.Equal(t, 21, ins.Segments[1].Series)
+	require.Equal(t, 21, ins.Segments[3].Samples)
+	require.Equal(t, int64(1), timestamp.FromTime(ins.From()))
+	require.Equal(t, int64(20), timestamp.FromTime(ins.To()))
+}
+
+func TestInspect_Corrupted(t *testing.T) {
+	walDir := setupTestWAL(t)
+	corruptSegment(t, walDir, 2)
+
+	ins, err := Inspect(walDir)
+	require.NoError(t, err)
+
+	corrupted := ins.Corrupted()
+	require.NotNil(t, corrupted)
+	require.Equal(t, "00000002", corrupted.Name)
+	require.Equal(t, 2, corrupted.Corruption.Segment)
+
+	repaired, err := Repair(walDir)
+	require.NoError(t, err)
+	require.Equal(t, "00000002", repaired.Name)
+
+	ins, err = Inspect(walDir)
+	require.NoError(t, err)
+	require.Nil(t, ins.Corrupted())
+	require.True(t, ins.From().IsZero())
+}
+
+func TestTruncateBefore(t *testing.T) {
+	walDir := setupTestWAL(t)
+	require.NoError(t, TruncateBefore(walDir, 3))
+
+	ins, err := Inspect(walDir)
+	require.NoError(t, err)
+	require.Equal(t, "checkpoint.00000002", ins.Segments[0].Name)
+	require.Equal(t, 21, ins.Segments[0].Series)
+	require.Equal(t, 0, ins.Segments[0].Samples)
+	require.Equal(t, 3, ins.Segments[1].Index)
+
+	require.Error(t, TruncateBefore(walDir, 10))
+}
+
+// corruptSegment overwrites part of the first record of a segment so that
+// its checksum doesn't match.
+func corruptSegment(t *testing.T, walDir string, segment int) {
+	f, err := os.OpenFile(wlog.SegmentName(walDir, segment), os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{0xde, 0xad, 0xbe, 0xef}, 10)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
@@ -6,28 +6,48 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
+	"github.com/grafana/agent/internal/converter/convertapi"
 	"github.com/grafana/agent/internal/static/metrics/cluster/configapi"
 	"github.com/grafana/agent/internal/static/metrics/instance"
 	"gopkg.in/yaml.v2"
 )
 
+// ErrRevisionMismatch is returned by conditional requests when the
+// configuration doesn't match the expected revision, usually because it was
+// modified since it was read.
+var ErrRevisionMismatch = errors.New("configuration revision mismatch")
+
 // Client is a collection of all subsystem clients.
 type Client struct {
 	PrometheusClient
+	ConverterClient
 }
 
 // New creates a new Client.
 func New(addr string) *Client {
 	return &Client{
 		PrometheusClient: &prometheusClient{addr: addr},
+		ConverterClient:  &converterClient{addr: addr},
 	}
 }
 
+// ConverterClient is the client interface to the config conversion API of
+// the Grafana Agent.
+type ConverterClient interface {
+	// Convert converts a config file from another format to a Grafana Agent
+	// Flow config. Conversions which fail because of the converted config
+	// don't return an error; the reasons of the failure are in the
+	// diagnostics of the response instead.
+	Convert(ctx context.Context, req convertapi.ConvertRequest) (*convertapi.ConvertResponse, error)
+}
+
 // PrometheusClient is the client interface to the API exposed by the
 // Prometheus subsystem of the Grafana Agent.
 type PrometheusClient interface {
@@ -41,6 +61,11 @@ type PrometheusClient interface {
 	// management KV store.
 	ListConfigs(ctx context.Context) (*configapi.ListConfigurationsResponse, error)
 
+	// ListConfigsMatching is like ListConfigs, but only lists the instance
+	// configs whose labels match any of the provided series selectors, such as
+	// `{team="a"}`.
+	ListConfigsMatching(ctx context.Context, selectors ...string) (*configapi.ListConfigurationsResponse, error)
+
 	// GetConfiguration returns a named configuration from the config
 	// management KV store.
 	GetConfiguration(ctx context.Context, name string) (*instance.Config, error)
@@ -52,6 +77,33 @@ type PrometheusClient interface {
 	// DeleteConfiguration removes a named configuration from the config
 	// management KV store.
 	DeleteConfiguration(ctx context.Context, name string) error
+
+	// GetConfigurationWithRevision is like GetConfiguration, but also returns
+	// the revision of the configuration to pass to PutConfigurationIfMatch and
+	// DeleteConfigurationIfMatch.
+	GetConfigurationWithRevision(ctx context.Context, name string) (*instance.Config, string, error)
+
+	// PutConfigurationIfMatch is like PutConfiguration, but fails with
+	// ErrRevisionMismatch if the stored configuration doesn't match revision.
+	PutConfigurationIfMatch(ctx context.Context, name string, cfg *instance.Config, revision string) error
+
+	// DeleteConfigurationIfMatch is like DeleteConfiguration, but fails with
+	// ErrRevisionMismatch if the stored configuration doesn't match revision.
+	DeleteConfigurationIfMatch(ctx context.Context, name string, revision string) error
+
+	// PutConfigurations adds or updates a batch of configurations, named by
+	// their Name field, into the config management KV store. Either all or
+	// none of the configurations are applied.
+	PutConfigurations(ctx context.Context, cfgs []*instance.Config) (*configapi.BatchConfigurationsResponse, error)
+
+	// DeleteConfigurations removes a batch of named configurations from the
+	// config management KV store. Either all or none of the configurations
+	// are removed.
+	DeleteConfigurations(ctx context.Context, names []string) (*configapi.BatchConfigurationsResponse, error)
+
+	// ConfigurationHistory returns the recent changes made to a named
+	// configuration through the agent, oldest first.
+	ConfigurationHistory(ctx context.Context, name string) ([]configapi.ConfigEvent, error)
 }
 
 type prometheusClient struct {
@@ -71,35 +123,80 @@ func (c *prometheusClient) Instances(ctx context.Context) ([]string, error) {
 	return data, err
 }
 
+// listConfigsPageSize is the number of configs requested per page when
+// listing configs.
+const listConfigsPageSize = 1000
+
 func (c *prometheusClient) ListConfigs(ctx context.Context) (*configapi.ListConfigurationsResponse, error) {
-	url := fmt.Sprintf("%s/agent/api/v1/configs", c.addr)
+	return c.ListConfigsMatching(ctx)
+}
+
+func (c *prometheusClient) ListConfigsMatching(ctx context.Context, selectors ...string) (*configapi.ListConfigurationsResponse, error) {
+	query := url.Values{}
+	query.Set("limit", fmt.Sprint(listConfigsPageSize))
+	for _, selector := range selectors {
+		query.Add("match", selector)
+	}
+
+	// Pages are requested until the response has no continue token. Agents
+	// which don't support pagination return all configs in the first page.
+	var result configapi.ListConfigurationsResponse
+	for {
+		url := fmt.Sprintf("%s/agent/api/v1/configs?%s", c.addr, query.Encode())
+
+		resp, err := c.doRequest(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var data configapi.ListConfigurationsResponse
+		if err := unmarshalPrometheusAPIResponse(resp.Body, &data); err != nil {
+			return &result, err
+		}
+		result.Configs = append(result.Configs, data.Configs...)
+
+		if data.ContinueToken == "" {
+			return &result, nil
+		}
+		query.Set("continue_token", data.ContinueToken)
+	}
+}
+
+func (c *prometheusClient) ConfigurationHistory(ctx context.Context, name string) ([]configapi.ConfigEvent, error) {
+	url := fmt.Sprintf("%s/agent/api/v1/configs/%s/history", c.addr, name)
 
 	resp, err := c.doRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	var data configapi.ListConfigurationsResponse
+	var data configapi.ConfigHistoryResponse
 	err = unmarshalPrometheusAPIResponse(resp.Body, &data)
-	return &data, err
+	return data.Events, err
 }
 
 func (c *prometheusClient) GetConfiguration(ctx context.Context, name string) (*instance.Config, error) {
+	cfg, _, err := c.GetConfigurationWithRevision(ctx, name)
+	return cfg, err
+}
+
+func (c *prometheusClient) GetConfigurationWithRevision(ctx context.Context, name string) (*instance.Config, string, error) {
 	url := fmt.Sprintf("%s/agent/api/v1/configs/%s", c.addr, name)
 
 	resp, err := c.doRequest(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	revision := strings.Trim(resp.Header.Get("ETag"), `"`)
 
 	var data configapi.GetConfigurationResponse
 	if err := unmarshalPrometheusAPIResponse(resp.Body, &data); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var config instance.Config
 	err = yaml.NewDecoder(strings.NewReader(data.Value)).Decode(&config)
-	return &config, err
+	return &config, revision, err
 }
 
 func (c *prometheusClient) PutConfiguration(ctx context.Context, name string, cfg *instance.Config) error {
@@ -129,6 +226,84 @@ func (c *prometheusClient) DeleteConfiguration(ctx context.Context, name string)
 	return unmarshalPrometheusAPIResponse(resp.Body, nil)
 }
 
+func (c *prometheusClient) PutConfigurationIfMatch(ctx context.Context, name string, cfg *instance.Config, revision string) error {
+	url := fmt.Sprintf("%s/agent/api/v1/config/%s", c.addr, name)
+
+	bb, err := instance.MarshalConfig(cfg, false)
+	if err != nil {
+		return err
+	}
+	return c.doConditionalRequest(ctx, "POST", url, revision, bytes.NewReader(bb))
+}
+
+func (c *prometheusClient) DeleteConfigurationIfMatch(ctx context.Context, name string, revision string) error {
+	url := fmt.Sprintf("%s/agent/api/v1/config/%s", c.addr, name)
+	return c.doConditionalRequest(ctx, "DELETE", url, revision, nil)
+}
+
+// doConditionalRequest performs a request with an If-Match header for
+// revision. It returns an error wrapping ErrRevisionMismatch if the server
+// rejects the precondition.
+func (c *prometheusClient) doConditionalRequest(ctx context.Context, method string, url string, revision string, body io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	if revision == "*" {
+		req.Header.Set("If-Match", revision)
+	} else {
+		req.Header.Set("If-Match", fmt.Sprintf("%q", revision))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	err = unmarshalPrometheusAPIResponse(resp.Body, nil)
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("%w: %w", ErrRevisionMismatch, err)
+	}
+	return err
+}
+
+func (c *prometheusClient) PutConfigurations(ctx context.Context, cfgs []*instance.Config) (*configapi.BatchConfigurationsResponse, error) {
+	url := fmt.Sprintf("%s/agent/api/v1/configs/batch", c.addr)
+
+	req := configapi.PutConfigurationsRequest{
+		Configs: make([]configapi.BatchConfiguration, 0, len(cfgs)),
+	}
+	for _, cfg := range cfgs {
+		bb, err := instance.MarshalConfig(cfg, false)
+		if err != nil {
+			return nil, err
+		}
+		req.Configs = append(req.Configs, configapi.BatchConfiguration{Name: cfg.Name, Value: string(bb)})
+	}
+	return c.doBatchRequest(ctx, "POST", url, req)
+}
+
+func (c *prometheusClient) DeleteConfigurations(ctx context.Context, names []string) (*configapi.BatchConfigurationsResponse, error) {
+	url := fmt.Sprintf("%s/agent/api/v1/configs/batch", c.addr)
+	return c.doBatchRequest(ctx, "DELETE", url, configapi.DeleteConfigurationsRequest{Configs: names})
+}
+
+func (c *prometheusClient) doBatchRequest(ctx context.Context, method string, url string, req interface{}) (*configapi.BatchConfigurationsResponse, error) {
+	bb, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(ctx, method, url, bytes.NewReader(bb))
+	if err != nil {
+		return nil, err
+	}
+
+	var data configapi.BatchConfigurationsResponse
+	err = unmarshalPrometheusAPIResponse(resp.Body, &data)
+	return &data, err
+}
+
 func (c *prometheusClient) doRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
@@ -137,6 +312,43 @@ func (c *prometheusClient) doRequest(ctx context.Context, method string, url str
 	return http.DefaultClient.Do(req)
 }
 
+type converterClient struct {
+	addr string
+}
+
+func (c *converterClient) Convert(ctx context.Context, req convertapi.ConvertRequest) (*convertapi.ConvertResponse, error) {
+	url := c.addr + convertapi.Path
+
+	bb, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(bb))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var data convertapi.ConvertResponse
+	err = unmarshalPrometheusAPIResponse(resp.Body, &data)
+	if resp.StatusCode != http.StatusOK {
+		// Responses which aren't from the conversion API, like authentication
+		// failures, don't have an API error to report.
+		if err == nil {
+			err = errors.New(http.StatusText(resp.StatusCode))
+		}
+		return nil, fmt.Errorf("unexpected status code %d: %w", resp.StatusCode, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
 // unmarshalPrometheusAPIResponse will unmarshal a response from the Prometheus
 // subsystem API.
 //
@@ -176,7 +176,7 @@ func (i *Instance) ApplyConfig(c *InstanceConfig, g GlobalConfig, dryRun bool) e
 	if !i.reg.UnregisterAll() {
 		// If UnregisterAll fails, we need to abort, otherwise the new promtail
 		// would try to re-register an existing metric and might panic.
-		return fmt.Errorf("failed to unregister all metrics from previous promtail. THIS IS A BUG")
+		return fmt.Errorf("failed to unregister all metrics from previous promtail, metrics still registered: %s. THIS IS A BUG", util.FormatLeaks(i.reg.LeakReport()))
 	}
 
 	if len(c.ClientConfigs) == 0 {
@@ -77,6 +77,9 @@ func New(
 	}
 	c.storeAPI = configstore.NewAPI(l, c.store, c.storeValidate, cfg.APIEnableGetConfiguration)
 	reg.MustRegister(c.storeAPI)
+	if err := c.storeAPI.ApplyAuditConfig(cfg.Audit); err != nil {
+		return nil, fmt.Errorf("failed to initialize config audit log: %w", err)
+	}
 
 	c.watcher, err = newConfigWatcher(l, cfg, c.store, im, c.node.Owns, validate)
 	if err != nil {
@@ -133,6 +136,10 @@ func (c *Cluster) ApplyConfig(cfg Config) error {
 		return fmt.Errorf("failed to apply config to config store: %w", err)
 	}
 
+	if err := c.storeAPI.ApplyAuditConfig(cfg.Audit); err != nil {
+		return fmt.Errorf("failed to apply config to config audit log: %w", err)
+	}
+
 	if err := c.watcher.ApplyConfig(cfg); err != nil {
 		return fmt.Errorf("failed to apply config to watcher: %w", err)
 	}
@@ -168,6 +175,7 @@ func (c *Cluster) Stop() {
 	}{
 		{"node", c.node.Stop},
 		{"config store", c.store.Close},
+		{"config audit log", c.storeAPI.Close},
 		{"config watcher", c.watcher.Stop},
 	}
 	for _, dep := range deps {
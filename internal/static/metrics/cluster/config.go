@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/grafana/agent/internal/static/metrics/cluster/client"
+	"github.com/grafana/agent/internal/static/metrics/instance/configstore"
 	flagutil "github.com/grafana/agent/internal/util"
 	util_log "github.com/grafana/agent/internal/util/log"
 	"github.com/grafana/dskit/kv"
@@ -45,6 +46,10 @@ type Config struct {
 
 	DangerousAllowReadingFiles bool `yaml:"dangerous_allow_reading_files,omitempty"`
 
+	// Audit configures where changes made through the config management API are
+	// recorded.
+	Audit configstore.AuditConfig `yaml:"audit,omitempty"`
+
 	// TODO(rfratto): deprecate scraping_service_client in Agent and replace with this.
 	Client                    client.Config `yaml:"-"`
 	APIEnableGetConfiguration bool          `yaml:"-"`
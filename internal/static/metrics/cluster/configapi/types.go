@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // APIResponse is the base object returned for any API call.
@@ -47,6 +48,10 @@ type ErrorResponse struct {
 type ListConfigurationsResponse struct {
 	// Configs is the list of configuration names.
 	Configs []string `json:"configs"`
+
+	// ContinueToken is set when more configurations are available. Pass it to
+	// the next request to retrieve the next page.
+	ContinueToken string `json:"continue_token,omitempty"`
 }
 
 // GetConfigurationResponse is contained inside an APIResponse
@@ -57,6 +62,62 @@ type GetConfigurationResponse struct {
 	Value string `json:"value"`
 }
 
+// BatchConfiguration is a single named configuration of a batch request.
+type BatchConfiguration struct {
+	// Name is the name of the configuration.
+	Name string `json:"name"`
+	// Value is the stringified YAML configuration.
+	Value string `json:"value"`
+}
+
+// PutConfigurationsRequest is the request body of PutConfigurations.
+type PutConfigurationsRequest struct {
+	// Configs is the list of configurations to create or update.
+	Configs []BatchConfiguration `json:"configs"`
+}
+
+// DeleteConfigurationsRequest is the request body of DeleteConfigurations.
+type DeleteConfigurationsRequest struct {
+	// Configs is the list of configuration names to delete.
+	Configs []string `json:"configs"`
+}
+
+// BatchConfigurationsResponse is contained inside an APIResponse and lists
+// the configurations changed by a batch request. Returned by
+// PutConfigurations and DeleteConfigurations.
+type BatchConfigurationsResponse struct {
+	// Created is the list of created configuration names.
+	Created []string `json:"created,omitempty"`
+	// Updated is the list of updated configuration names.
+	Updated []string `json:"updated,omitempty"`
+	// Deleted is the list of deleted configuration names.
+	Deleted []string `json:"deleted,omitempty"`
+}
+
+// ConfigEvent records a change made to a configuration through the API.
+type ConfigEvent struct {
+	// Time is when the change was made.
+	Time time.Time `json:"time"`
+	// Action is either "put" or "delete".
+	Action string `json:"action"`
+	// Name is the name of the changed configuration.
+	Name string `json:"name"`
+	// Revision is the revision of the configuration after the change. Empty
+	// for deletions.
+	Revision string `json:"revision,omitempty"`
+	// User is the user who made the change, if known.
+	User string `json:"user,omitempty"`
+	// RemoteAddr is the address of the client which made the change.
+	RemoteAddr string `json:"remote_addr,omitempty"`
+}
+
+// ConfigHistoryResponse is contained inside an APIResponse and lists the
+// recent changes made to a configuration, oldest first. Returned by
+// GetConfigurationHistory.
+type ConfigHistoryResponse struct {
+	Events []ConfigEvent `json:"events"`
+}
+
 // WriteResponse writes a response object to the provided ResponseWriter w and with a
 // status code of statusCode. resp is marshaled to JSON.
 func WriteResponse(w http.ResponseWriter, statusCode int, resp interface{}) error {
@@ -1,11 +1,16 @@
 package configstore
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -15,6 +20,15 @@ import (
 	"github.com/grafana/agent/internal/static/metrics/cluster/configapi"
 	"github.com/grafana/agent/internal/static/metrics/instance"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// Limits of batch requests. Consul allows at most 64 operations in a
+// transaction.
+const (
+	maxBatchConfigs     = 64
+	maxBatchRequestSize = 16 << 20 // 16 MiB
 )
 
 // API is an HTTP API to interact with a configstore.
@@ -27,6 +41,9 @@ type API struct {
 	totalCreatedConfigs prometheus.Counter
 	totalUpdatedConfigs prometheus.Counter
 	totalDeletedConfigs prometheus.Counter
+	totalAuditFailures  prometheus.Counter
+
+	auditor *auditor
 
 	enableGet bool
 }
@@ -37,6 +54,11 @@ type Validator = func(c *instance.Config) error
 
 // NewAPI creates a new API. Store can be applied later with SetStore.
 func NewAPI(l log.Logger, store Store, v Validator, enableGet bool) *API {
+	totalAuditFailures := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "agent_metrics_ha_configs_audit_failures_total",
+		Help: "Total number of scraping service config changes which failed to be sent to the audit sink",
+	})
+
 	return &API{
 		log:       l,
 		store:     store,
@@ -54,7 +76,9 @@ func NewAPI(l log.Logger, store Store, v Validator, enableGet bool) *API {
 			Name: "agent_metrics_ha_configs_deleted_total",
 			Help: "Total number of deleted scraping service configs",
 		}),
-		enableGet: enableGet,
+		totalAuditFailures: totalAuditFailures,
+		auditor:            newAuditor(l, totalAuditFailures),
+		enableGet:          enableGet,
 	}
 }
 
@@ -66,11 +90,14 @@ func (api *API) WireAPI(r *mux.Router) {
 	r = r.UseEncodedPath()
 
 	r.HandleFunc("/agent/api/v1/configs", api.ListConfigurations).Methods("GET")
+	r.HandleFunc("/agent/api/v1/configs/batch", api.PutConfigurations).Methods("PUT", "POST")
+	r.HandleFunc("/agent/api/v1/configs/batch", api.DeleteConfigurations).Methods("DELETE")
 	getConfigHandler := messageHandlerFunc(http.StatusNotFound, "404 - config endpoint is disabled")
 	if api.enableGet {
 		getConfigHandler = api.GetConfiguration
 	}
 	r.HandleFunc("/agent/api/v1/configs/{name}", getConfigHandler).Methods("GET")
+	r.HandleFunc("/agent/api/v1/configs/{name}/history", api.GetConfigurationHistory).Methods("GET")
 	r.HandleFunc("/agent/api/v1/config/{name}", api.PutConfiguration).Methods("PUT", "POST")
 	r.HandleFunc("/agent/api/v1/config/{name}", api.DeleteConfiguration).Methods("DELETE")
 }
@@ -80,6 +107,7 @@ func (api *API) Describe(ch chan<- *prometheus.Desc) {
 	ch <- api.totalCreatedConfigs.Desc()
 	ch <- api.totalUpdatedConfigs.Desc()
 	ch <- api.totalDeletedConfigs.Desc()
+	ch <- api.totalAuditFailures.Desc()
 }
 
 // Collect implements prometheus.Collector.
@@ -87,9 +115,29 @@ func (api *API) Collect(mm chan<- prometheus.Metric) {
 	mm <- api.totalCreatedConfigs
 	mm <- api.totalUpdatedConfigs
 	mm <- api.totalDeletedConfigs
+	mm <- api.totalAuditFailures
+}
+
+// ApplyAuditConfig changes where changes made to configs are recorded. The
+// recent history of changes is kept.
+func (api *API) ApplyAuditConfig(cfg AuditConfig) error {
+	return api.auditor.ApplyConfig(cfg)
 }
 
-// ListConfigurations returns a list of configurations.
+// Close closes the audit sink of the API.
+func (api *API) Close() error {
+	return api.auditor.Close()
+}
+
+// ListConfigurations returns a list of configurations, sorted by name.
+//
+// The list can be filtered by the labels of the configurations with match
+// query parameters, each holding a series selector. Configurations matching
+// any of the selectors are listed.
+//
+// The list is paginated if the limit query parameter is set. The
+// continue_token of the response must then be passed to the next request to
+// get the next page.
 func (api *API) ListConfigurations(rw http.ResponseWriter, r *http.Request) {
 	api.storeMut.Lock()
 	defer api.storeMut.Unlock()
@@ -98,7 +146,18 @@ func (api *API) ListConfigurations(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	keys, err := api.store.List(r.Context())
+	opts, err := parseListOptions(r)
+	if err != nil {
+		api.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	var keys []string
+	if len(opts.selectors) == 0 {
+		keys, err = api.store.List(r.Context())
+	} else {
+		keys, err = api.listMatching(r.Context(), opts.selectors)
+	}
 	if errors.Is(err, ErrNotConnected) {
 		api.writeError(rw, http.StatusNotFound, fmt.Errorf("no config store running"))
 		return
@@ -106,7 +165,91 @@ func (api *API) ListConfigurations(rw http.ResponseWriter, r *http.Request) {
 		api.writeError(rw, http.StatusInternalServerError, fmt.Errorf("failed to write config: %w", err))
 		return
 	}
-	api.writeResponse(rw, http.StatusOK, configapi.ListConfigurationsResponse{Configs: keys})
+
+	sort.Strings(keys)
+	if opts.after != "" {
+		keys = keys[sort.SearchStrings(keys, opts.after):]
+		if len(keys) > 0 && keys[0] == opts.after {
+			keys = keys[1:]
+		}
+	}
+
+	resp := configapi.ListConfigurationsResponse{Configs: keys}
+	if opts.limit > 0 && len(keys) > opts.limit {
+		resp.Configs = keys[:opts.limit]
+		resp.ContinueToken = base64.RawURLEncoding.EncodeToString([]byte(keys[opts.limit-1]))
+	}
+	api.writeResponse(rw, http.StatusOK, resp)
+}
+
+// listOptions holds the query parameters of ListConfigurations.
+type listOptions struct {
+	limit     int                 // Maximum number of configs to return; 0 for no limit.
+	after     string              // Name of the last config of the previous page.
+	selectors [][]*labels.Matcher // Selectors of which any must match the labels of a config.
+}
+
+func parseListOptions(r *http.Request) (listOptions, error) {
+	var (
+		opts  listOptions
+		query = r.URL.Query()
+	)
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return opts, fmt.Errorf("invalid limit %q: must be a non-negative integer", v)
+		}
+		opts.limit = limit
+	}
+
+	if v := query.Get("continue_token"); v != "" {
+		after, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid continue_token %q", v)
+		}
+		opts.after = string(after)
+	}
+
+	for _, v := range query["match"] {
+		matchers, err := parser.ParseMetricSelector(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid match %q: %w", v, err)
+		}
+		opts.selectors = append(opts.selectors, matchers)
+	}
+	return opts, nil
+}
+
+// listMatching returns the names of the configs whose labels match any of
+// selectors.
+func (api *API) listMatching(ctx context.Context, selectors [][]*labels.Matcher) ([]string, error) {
+	cfgCh, err := api.store.All(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for cfg := range cfgCh {
+		for _, matchers := range selectors {
+			if matchLabels(matchers, cfg.Labels) {
+				keys = append(keys, cfg.Name)
+				break
+			}
+		}
+	}
+	return keys, nil
+}
+
+// matchLabels reports whether all matchers match lbls. Missing labels are
+// matched as empty values.
+func matchLabels(matchers []*labels.Matcher, lbls map[string]string) bool {
+	for _, m := range matchers {
+		if !m.Matches(lbls[m.Name]) {
+			return false
+		}
+	}
+	return true
 }
 
 // GetConfiguration gets an individual configuration.
@@ -124,7 +267,7 @@ func (api *API) GetConfiguration(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg, err := api.store.Get(r.Context(), configKey)
+	cfg, revision, err := api.store.GetWithRevision(r.Context(), configKey)
 	switch {
 	case errors.Is(err, ErrNotConnected):
 		api.writeError(rw, http.StatusNotFound, err)
@@ -138,13 +281,17 @@ func (api *API) GetConfiguration(rw http.ResponseWriter, r *http.Request) {
 			api.writeError(rw, http.StatusInternalServerError, fmt.Errorf("could not marshal config for response: %w", err))
 			return
 		}
+		if revision != "" {
+			rw.Header().Set("ETag", fmt.Sprintf("%q", revision))
+		}
 		api.writeResponse(rw, http.StatusOK, &configapi.GetConfigurationResponse{
 			Value: string(bb),
 		})
 	}
 }
 
-// PutConfiguration creates or updates a configuration.
+// PutConfiguration creates or updates a configuration. If the request has an
+// If-Match header, the configuration is only updated if its revision matches.
 func (api *API) PutConfiguration(rw http.ResponseWriter, r *http.Request) {
 	api.storeMut.Lock()
 	defer api.storeMut.Unlock()
@@ -165,36 +312,35 @@ func (api *API) PutConfiguration(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg, err := instance.UnmarshalConfig(strings.NewReader(config.String()))
+	revision, err := getIfMatch(r)
 	if err != nil {
-		api.writeError(rw, http.StatusBadRequest, fmt.Errorf("could not unmarshal config: %w", err))
+		api.writeError(rw, http.StatusBadRequest, err)
 		return
 	}
-	cfg.Name = configName
 
-	if api.validator != nil {
-		validateCfg, err := instance.UnmarshalConfig(strings.NewReader(config.String()))
-		if err != nil {
-			api.writeError(rw, http.StatusBadRequest, fmt.Errorf("could not unmarshal config: %w", err))
-			return
-		}
-		validateCfg.Name = configName
-
-		if err := api.validator(validateCfg); err != nil {
-			api.writeError(rw, http.StatusBadRequest, fmt.Errorf("failed to validate config: %w", err))
-			return
-		}
+	cfg, err := api.parseConfig(configName, config.String())
+	if err != nil {
+		api.writeError(rw, http.StatusBadRequest, err)
+		return
 	}
 
-	created, err := api.store.Put(r.Context(), *cfg)
+	var created bool
+	if revision != "" {
+		created, err = api.store.PutIfMatch(r.Context(), *cfg, revision)
+	} else {
+		created, err = api.store.Put(r.Context(), *cfg)
+	}
 	switch {
 	case errors.Is(err, ErrNotConnected):
 		api.writeError(rw, http.StatusNotFound, err)
 	case errors.As(err, &NotUniqueError{}):
 		api.writeError(rw, http.StatusBadRequest, err)
+	case errors.As(err, &RevisionMismatchError{}):
+		api.writeError(rw, http.StatusPreconditionFailed, err)
 	case err != nil:
 		api.writeError(rw, http.StatusInternalServerError, err)
 	default:
+		api.audit(r, "put", cfg)
 		if created {
 			api.totalCreatedConfigs.Inc()
 			api.writeResponse(rw, http.StatusCreated, nil)
@@ -205,7 +351,8 @@ func (api *API) PutConfiguration(rw http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// DeleteConfiguration deletes a configuration.
+// DeleteConfiguration deletes a configuration. If the request has an If-Match
+// header, the configuration is only deleted if its revision matches.
 func (api *API) DeleteConfiguration(rw http.ResponseWriter, r *http.Request) {
 	api.storeMut.Lock()
 	defer api.storeMut.Unlock()
@@ -220,20 +367,241 @@ func (api *API) DeleteConfiguration(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = api.store.Delete(r.Context(), configKey)
+	revision, err := getIfMatch(r)
+	if err != nil {
+		api.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	if revision != "" {
+		err = api.store.DeleteIfMatch(r.Context(), configKey, revision)
+	} else {
+		err = api.store.Delete(r.Context(), configKey)
+	}
 	switch {
 	case errors.Is(err, ErrNotConnected):
 		api.writeError(rw, http.StatusNotFound, err)
 	case errors.As(err, &NotExistError{}):
 		api.writeError(rw, http.StatusNotFound, err)
+	case errors.As(err, &RevisionMismatchError{}):
+		api.writeError(rw, http.StatusPreconditionFailed, err)
 	case err != nil:
 		api.writeError(rw, http.StatusInternalServerError, err)
 	default:
+		api.audit(r, "delete", &instance.Config{Name: configKey})
 		api.totalDeletedConfigs.Inc()
 		api.writeResponse(rw, http.StatusOK, nil)
 	}
 }
 
+// GetConfigurationHistory returns the recent changes made to a configuration
+// through this agent.
+func (api *API) GetConfigurationHistory(rw http.ResponseWriter, r *http.Request) {
+	configKey, err := getConfigName(r)
+	if err != nil {
+		api.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	api.writeResponse(rw, http.StatusOK, &configapi.ConfigHistoryResponse{
+		Events: api.auditor.History(configKey),
+	})
+}
+
+// PutConfigurations creates or updates a batch of configurations. The whole
+// batch is validated, then applied in a single transaction, so either all or
+// none of the batch is applied.
+func (api *API) PutConfigurations(rw http.ResponseWriter, r *http.Request) {
+	api.storeMut.Lock()
+	defer api.storeMut.Unlock()
+	if api.store == nil {
+		api.writeError(rw, http.StatusNotFound, fmt.Errorf("no config store running"))
+		return
+	}
+
+	var req configapi.PutConfigurationsRequest
+	if !api.decodeBatchRequest(rw, r, &req) {
+		return
+	}
+	if len(req.Configs) > maxBatchConfigs {
+		api.writeError(rw, http.StatusBadRequest, fmt.Errorf("batch has %d configs, more than the maximum of %d", len(req.Configs), maxBatchConfigs))
+		return
+	}
+
+	var (
+		cfgs = make([]instance.Config, 0, len(req.Configs))
+		seen = make(map[string]struct{}, len(req.Configs))
+		jobs = make(map[string]struct{})
+	)
+	for _, bc := range req.Configs {
+		if bc.Name == "" {
+			api.writeError(rw, http.StatusBadRequest, fmt.Errorf("config name must not be empty"))
+			return
+		}
+		if _, exist := seen[bc.Name]; exist {
+			api.writeError(rw, http.StatusBadRequest, fmt.Errorf("config %s is specified more than once", bc.Name))
+			return
+		}
+		seen[bc.Name] = struct{}{}
+
+		cfg, err := api.parseConfig(bc.Name, bc.Value)
+		if err != nil {
+			api.writeError(rw, http.StatusBadRequest, fmt.Errorf("config %s: %w", bc.Name, err))
+			return
+		}
+		// The store only checks the uniqueness of job names against configs
+		// which aren't part of the batch, so configs of the batch are
+		// checked against each other here.
+		for _, sc := range cfg.ScrapeConfigs {
+			if _, exist := jobs[sc.JobName]; exist {
+				api.writeError(rw, http.StatusBadRequest, NotUniqueError{ScrapeJob: sc.JobName})
+				return
+			}
+			jobs[sc.JobName] = struct{}{}
+		}
+
+		cfgs = append(cfgs, *cfg)
+	}
+
+	created, err := api.store.ApplyBatch(r.Context(), cfgs, nil)
+	if err != nil {
+		api.writeStoreError(rw, fmt.Errorf("failed to put configs: %w", err))
+		return
+	}
+
+	var resp configapi.BatchConfigurationsResponse
+	for i := range cfgs {
+		if created[i] {
+			resp.Created = append(resp.Created, cfgs[i].Name)
+		} else {
+			resp.Updated = append(resp.Updated, cfgs[i].Name)
+		}
+		api.audit(r, "put", &cfgs[i])
+	}
+	api.totalCreatedConfigs.Add(float64(len(resp.Created)))
+	api.totalUpdatedConfigs.Add(float64(len(resp.Updated)))
+	api.writeResponse(rw, http.StatusOK, &resp)
+}
+
+// DeleteConfigurations deletes a batch of configurations in a single
+// transaction. All configurations must exist, and either all or none of the
+// batch is deleted.
+func (api *API) DeleteConfigurations(rw http.ResponseWriter, r *http.Request) {
+	api.storeMut.Lock()
+	defer api.storeMut.Unlock()
+	if api.store == nil {
+		api.writeError(rw, http.StatusNotFound, fmt.Errorf("no config store running"))
+		return
+	}
+
+	var req configapi.DeleteConfigurationsRequest
+	if !api.decodeBatchRequest(rw, r, &req) {
+		return
+	}
+	if len(req.Configs) > maxBatchConfigs {
+		api.writeError(rw, http.StatusBadRequest, fmt.Errorf("batch has %d configs, more than the maximum of %d", len(req.Configs), maxBatchConfigs))
+		return
+	}
+
+	seen := make(map[string]struct{}, len(req.Configs))
+	for _, name := range req.Configs {
+		if _, exist := seen[name]; exist {
+			api.writeError(rw, http.StatusBadRequest, fmt.Errorf("config %s is specified more than once", name))
+			return
+		}
+		seen[name] = struct{}{}
+	}
+
+	if _, err := api.store.ApplyBatch(r.Context(), nil, req.Configs); err != nil {
+		api.writeStoreError(rw, fmt.Errorf("failed to delete configs: %w", err))
+		return
+	}
+
+	resp := configapi.BatchConfigurationsResponse{Deleted: req.Configs}
+	for _, name := range resp.Deleted {
+		api.audit(r, "delete", &instance.Config{Name: name})
+	}
+	api.totalDeletedConfigs.Add(float64(len(resp.Deleted)))
+	api.writeResponse(rw, http.StatusOK, &resp)
+}
+
+// decodeBatchRequest decodes the JSON body of the batch request r into v,
+// writing an error and returning false if it can't. Bodies larger than
+// maxBatchRequestSize are rejected.
+func (api *API) decodeBatchRequest(rw http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(http.MaxBytesReader(rw, r.Body, maxBatchRequestSize)).Decode(v)
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		api.writeError(rw, http.StatusRequestEntityTooLarge, fmt.Errorf("request body is larger than %d bytes", maxBatchRequestSize))
+		return false
+	case err != nil:
+		api.writeError(rw, http.StatusBadRequest, fmt.Errorf("could not decode request: %w", err))
+		return false
+	}
+	return true
+}
+
+// parseConfig unmarshals and validates the config named name from its YAML
+// text.
+func (api *API) parseConfig(name, text string) (*instance.Config, error) {
+	cfg, err := instance.UnmarshalConfig(strings.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal config: %w", err)
+	}
+	cfg.Name = name
+
+	if api.validator != nil {
+		// The validator is allowed to mutate the config, so it's given a copy.
+		validateCfg, err := instance.UnmarshalConfig(strings.NewReader(text))
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal config: %w", err)
+		}
+		validateCfg.Name = name
+
+		if err := api.validator(validateCfg); err != nil {
+			return nil, fmt.Errorf("failed to validate config: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// audit records a change of cfg made by r. Only the name of cfg is used for
+// deletions.
+func (api *API) audit(r *http.Request, action string, cfg *instance.Config) {
+	var revision string
+	if action != "delete" {
+		bb, err := instance.MarshalConfig(cfg, false)
+		if err != nil {
+			level.Warn(api.log).Log("msg", "failed to compute revision of config for audit", "config", cfg.Name, "err", err)
+		} else {
+			revision = configRevision(string(bb))
+		}
+	}
+
+	if err := api.auditor.Record(newConfigEvent(r, action, cfg.Name, revision)); err != nil {
+		level.Error(api.log).Log("msg", "failed to record config change to audit sink", "action", action, "config", cfg.Name, "err", err)
+	}
+}
+
+// writeStoreError writes an error returned by the store with the matching
+// status code.
+func (api *API) writeStoreError(rw http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotConnected):
+		api.writeError(rw, http.StatusNotFound, err)
+	case errors.As(err, &NotExistError{}):
+		api.writeError(rw, http.StatusNotFound, err)
+	case errors.As(err, &NotUniqueError{}):
+		api.writeError(rw, http.StatusBadRequest, err)
+	case errors.Is(err, ErrBatchConflict):
+		api.writeError(rw, http.StatusConflict, err)
+	case errors.Is(err, ErrBatchUnsupported):
+		api.writeError(rw, http.StatusNotImplemented, err)
+	default:
+		api.writeError(rw, http.StatusInternalServerError, err)
+	}
+}
+
 func (api *API) writeError(rw http.ResponseWriter, statusCode int, writeErr error) {
 	err := configapi.WriteError(rw, statusCode, writeErr)
 	if err != nil {
@@ -260,6 +628,20 @@ func getConfigName(r *http.Request) (string, error) {
 	return name, nil
 }
 
+// getIfMatch returns the revision from the If-Match header of r, or an empty
+// string if the header isn't set. Only a single entity tag or "*" is
+// supported.
+func getIfMatch(r *http.Request) (string, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return v, nil
+	}
+	if len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' || strings.Contains(v[1:len(v)-1], `"`) {
+		return "", fmt.Errorf("invalid If-Match header %s: expected a single entity tag or *", v)
+	}
+	return v[1 : len(v)-1], nil
+}
+
 func messageHandlerFunc(statusCode int, msg string) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		rw.WriteHeader(statusCode)
@@ -8,15 +8,21 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/gorilla/mux"
+	"github.com/grafana/agent/internal/service/http/auth"
 	"github.com/grafana/agent/internal/static/client"
 	"github.com/grafana/agent/internal/static/metrics/cluster/configapi"
 	"github.com/grafana/agent/internal/static/metrics/instance"
+	"github.com/grafana/dskit/kv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -55,6 +61,98 @@ func TestAPI_ListConfigurations(t *testing.T) {
 	})
 }
 
+func TestAPI_ListConfigurations_Paginated(t *testing.T) {
+	names := make([]string, 0, 25)
+	for i := 0; i < 25; i++ {
+		names = append(names, fmt.Sprintf("config-%02d", i))
+	}
+	s := &Mock{
+		ListFunc: func(ctx context.Context) ([]string, error) {
+			// Return the names in reverse order to check sorting.
+			reversed := make([]string, 0, len(names))
+			for i := len(names) - 1; i >= 0; i-- {
+				reversed = append(reversed, names[i])
+			}
+			return reversed, nil
+		},
+	}
+
+	api := NewAPI(log.NewNopLogger(), s, nil, true)
+	env := newAPITestEnvironment(t, api)
+
+	var (
+		listed []string
+		token  string
+		pages  int
+	)
+	for {
+		resp, err := http.Get(env.srv.URL + "/agent/api/v1/configs?limit=10&continue_token=" + token)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var apiResp struct {
+			Data configapi.ListConfigurationsResponse `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiResp))
+		require.LessOrEqual(t, len(apiResp.Data.Configs), 10)
+		listed = append(listed, apiResp.Data.Configs...)
+		pages++
+
+		if apiResp.Data.ContinueToken == "" {
+			break
+		}
+		token = apiResp.Data.ContinueToken
+	}
+	require.Equal(t, 3, pages)
+	require.Equal(t, names, listed)
+
+	resp, err := http.Get(env.srv.URL + "/agent/api/v1/configs?limit=-1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAPI_ListConfigurations_Match(t *testing.T) {
+	cfgs := []instance.Config{
+		{Name: "a", Labels: map[string]string{"team": "infra", "env": "prod"}},
+		{Name: "b", Labels: map[string]string{"team": "infra", "env": "dev"}},
+		{Name: "c", Labels: map[string]string{"team": "web", "env": "prod"}},
+		{Name: "d"},
+	}
+	s := &Mock{
+		AllFunc: func(ctx context.Context, keep func(key string) bool) (<-chan instance.Config, error) {
+			ch := make(chan instance.Config, len(cfgs))
+			for _, cfg := range cfgs {
+				ch <- cfg
+			}
+			close(ch)
+			return ch, nil
+		},
+	}
+
+	api := NewAPI(log.NewNopLogger(), s, nil, true)
+	env := newAPITestEnvironment(t, api)
+	cli := client.New(env.srv.URL)
+
+	tt := []struct {
+		selectors []string
+		expect    []string
+	}{
+		{selectors: []string{`{team="infra"}`}, expect: []string{"a", "b"}},
+		{selectors: []string{`{team="infra", env!="dev"}`}, expect: []string{"a"}},
+		{selectors: []string{`{env=~"p.*"}`}, expect: []string{"a", "c"}},
+		{selectors: []string{`{team="web"}`, `{env="dev"}`}, expect: []string{"b", "c"}},
+		{selectors: []string{`{env!="dev"}`}, expect: []string{"a", "c", "d"}},
+	}
+	for _, tc := range tt {
+		resp, err := cli.ListConfigsMatching(context.Background(), tc.selectors...)
+		require.NoError(t, err)
+		require.Equal(t, tc.expect, resp.Configs, "selectors: %v", tc.selectors)
+	}
+
+	_, err := cli.ListConfigsMatching(context.Background(), `{team=`)
+	require.Error(t, err)
+}
+
 func TestAPI_GetConfiguration_Invalid(t *testing.T) {
 	s := &Mock{
 		GetFunc: func(ctx context.Context, key string) (instance.Config, error) {
@@ -390,6 +488,298 @@ func TestServer_URLEncoded(t *testing.T) {
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
+func TestServer_IfMatch(t *testing.T) {
+	remote, err := NewRemote(log.NewNopLogger(), prometheus.NewRegistry(), kv.Config{
+		Store:  "inmemory",
+		Prefix: "configs/",
+	}, true)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := remote.Close()
+		require.NoError(t, err)
+	})
+
+	api := NewAPI(log.NewNopLogger(), remote, nil, true)
+	env := newAPITestEnvironment(t, api)
+	cli := client.New(env.srv.URL)
+
+	cfg := instance.DefaultConfig
+	cfg.Name = "config"
+	require.NoError(t, cli.PutConfiguration(context.Background(), "config", &cfg))
+
+	resp, err := http.Get(env.srv.URL + "/agent/api/v1/configs/config")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotEmpty(t, resp.Header.Get("ETag"))
+
+	_, rev, err := cli.GetConfigurationWithRevision(context.Background(), "config")
+	require.NoError(t, err)
+	require.Equal(t, resp.Header.Get("ETag"), fmt.Sprintf("%q", rev))
+
+	// The first writer with the revision wins, the second one gets a 412.
+	cfg.HostFilter = true
+	require.NoError(t, cli.PutConfigurationIfMatch(context.Background(), "config", &cfg, rev))
+	err = cli.PutConfigurationIfMatch(context.Background(), "config", &cfg, rev)
+	require.ErrorIs(t, err, client.ErrRevisionMismatch)
+	err = cli.DeleteConfigurationIfMatch(context.Background(), "config", rev)
+	require.ErrorIs(t, err, client.ErrRevisionMismatch)
+
+	req, err := http.NewRequest(http.MethodDelete, env.srv.URL+"/agent/api/v1/config/config", nil)
+	require.NoError(t, err)
+	req.Header.Set("If-Match", "not-quoted")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	require.NoError(t, cli.DeleteConfigurationIfMatch(context.Background(), "config", "*"))
+	_, err = remote.Get(context.Background(), "config")
+	require.ErrorAs(t, err, &NotExistError{})
+}
+
+func TestServer_PutConfigurations(t *testing.T) {
+	store := newMapStore(map[string]instance.Config{"b": {Name: "b"}})
+	mock := store.Mock()
+
+	api := NewAPI(log.NewNopLogger(), mock, nil, true)
+	env := newAPITestEnvironment(t, api)
+
+	cli := client.New(env.srv.URL)
+	resp, err := cli.PutConfigurations(context.Background(), []*instance.Config{{Name: "a"}, {Name: "b"}})
+	require.NoError(t, err)
+	require.Equal(t, &configapi.BatchConfigurationsResponse{Created: []string{"a"}, Updated: []string{"b"}}, resp)
+	require.ElementsMatch(t, []string{"a", "b"}, store.Names())
+
+	t.Run("Invalid", func(t *testing.T) {
+		body := `{"configs": [{"name": "c", "value": "{}"}, {"name": "c", "value": "{}"}]}`
+		resp, err := http.Post(env.srv.URL+"/agent/api/v1/configs/batch", "", strings.NewReader(body))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		require.ElementsMatch(t, []string{"a", "b"}, store.Names())
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		mock.ApplyBatchFunc = func(context.Context, []instance.Config, []string) ([]bool, error) {
+			return nil, ErrBatchConflict
+		}
+		defer func() { mock.ApplyBatchFunc = store.Mock().ApplyBatchFunc }()
+
+		_, err := cli.PutConfigurations(context.Background(), []*instance.Config{{Name: "c"}})
+		require.EqualError(t, err, "failed to put configs: configs of the batch changed while it was applied")
+		require.ElementsMatch(t, []string{"a", "b"}, store.Names())
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		cfgs := make([]*instance.Config, maxBatchConfigs+1)
+		for i := range cfgs {
+			cfgs[i] = &instance.Config{Name: fmt.Sprintf("config-%d", i)}
+		}
+		_, err := cli.PutConfigurations(context.Background(), cfgs)
+		require.EqualError(t, err, "batch has 65 configs, more than the maximum of 64")
+
+		body := `{"configs": [{"name": "c", "value": "` + strings.Repeat(" ", maxBatchRequestSize) + `"}]}`
+		resp, err := http.Post(env.srv.URL+"/agent/api/v1/configs/batch", "", strings.NewReader(body))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		require.ElementsMatch(t, []string{"a", "b"}, store.Names())
+	})
+}
+
+func TestServer_DeleteConfigurations(t *testing.T) {
+	store := newMapStore(map[string]instance.Config{"a": {Name: "a"}, "b": {Name: "b"}})
+
+	api := NewAPI(log.NewNopLogger(), store.Mock(), nil, true)
+	env := newAPITestEnvironment(t, api)
+	cli := client.New(env.srv.URL)
+
+	_, err := cli.DeleteConfigurations(context.Background(), []string{"a", "does-not-exist"})
+	require.EqualError(t, err, "failed to delete configs: configuration does-not-exist does not exist")
+	require.ElementsMatch(t, []string{"a", "b"}, store.Names())
+
+	resp, err := cli.DeleteConfigurations(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+	require.Equal(t, &configapi.BatchConfigurationsResponse{Deleted: []string{"a", "b"}}, resp)
+	require.Empty(t, store.Names())
+}
+
+func TestServer_ConfigurationHistory(t *testing.T) {
+	store := newMapStore(map[string]instance.Config{})
+
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+	api := NewAPI(log.NewNopLogger(), store.Mock(), nil, true)
+	require.NoError(t, api.ApplyAuditConfig(AuditConfig{Sink: AuditSinkFile, File: auditFile}))
+	t.Cleanup(func() { require.NoError(t, api.Close()) })
+
+	env := newAPITestEnvironment(t, api)
+	cli := client.New(env.srv.URL)
+
+	cfg := instance.DefaultConfig
+	cfg.Name = "config"
+	require.NoError(t, cli.PutConfiguration(context.Background(), "config", &cfg))
+	require.NoError(t, cli.DeleteConfiguration(context.Background(), "config"))
+
+	events, err := cli.ConfigurationHistory(context.Background(), "config")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "put", events[0].Action)
+	require.NotEmpty(t, events[0].Revision)
+	require.Equal(t, "127.0.0.1", events[0].RemoteAddr)
+	require.Equal(t, "delete", events[1].Action)
+	require.Empty(t, events[1].Revision)
+
+	events, err = cli.ConfigurationHistory(context.Background(), "other")
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	// Every event is written to the sink as a line of JSON. Events are sent
+	// in the background; closing the API waits for them.
+	require.NoError(t, api.Close())
+	bb, err := os.ReadFile(auditFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(bb)), "\n")
+	require.Len(t, lines, 2)
+
+	var ev configapi.ConfigEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &ev))
+	require.Equal(t, events[0].Name, ev.Name)
+	require.Equal(t, events[0].Revision, ev.Revision)
+}
+
+func TestAuditor_HistorySize(t *testing.T) {
+	a := newAuditor(log.NewNopLogger(), prometheus.NewCounter(prometheus.CounterOpts{}))
+	t.Cleanup(func() { require.NoError(t, a.Close()) })
+	require.NoError(t, a.ApplyConfig(AuditConfig{HistorySize: 2}))
+
+	for _, action := range []string{"put", "put", "delete"} {
+		require.NoError(t, a.Record(configapi.ConfigEvent{Name: "config", Action: action}))
+	}
+
+	events := a.History("config")
+	require.Len(t, events, 2)
+	require.Equal(t, "put", events[0].Action)
+	require.Equal(t, "delete", events[1].Action)
+}
+
+func TestAuditor_HistoryConfigs(t *testing.T) {
+	a := newAuditor(log.NewNopLogger(), prometheus.NewCounter(prometheus.CounterOpts{}))
+	t.Cleanup(func() { require.NoError(t, a.Close()) })
+	require.NoError(t, a.ApplyConfig(AuditConfig{HistoryConfigs: 2}))
+
+	now := time.Now()
+	for i, name := range []string{"a", "b", "a", "c"} {
+		require.NoError(t, a.Record(configapi.ConfigEvent{Name: name, Action: "put", Time: now.Add(time.Duration(i) * time.Second)}))
+	}
+
+	// The history of b, changed least recently, was dropped to make room for
+	// c.
+	require.Len(t, a.History("a"), 2)
+	require.Empty(t, a.History("b"))
+	require.Len(t, a.History("c"), 1)
+}
+
+func TestAuditor_SlowSink(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(srv.Close)
+
+	failures := prometheus.NewCounter(prometheus.CounterOpts{})
+	a := newAuditor(log.NewNopLogger(), failures)
+	require.NoError(t, a.ApplyConfig(AuditConfig{Sink: AuditSinkHTTP, URL: srv.URL, HTTPTimeout: time.Minute}))
+
+	// Recording doesn't wait for the sink, even when it doesn't respond.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			_ = a.Record(configapi.ConfigEvent{Name: "config", Action: "put"})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Record blocked on the audit sink")
+	}
+
+	close(release)
+	require.NoError(t, a.Close())
+	require.Equal(t, 0.0, testutil.ToFloat64(failures))
+}
+
+func TestNewConfigEvent_User(t *testing.T) {
+	var ev configapi.ConfigEvent
+	handler := auth.NewHandler(func() *auth.Arguments {
+		return &auth.Arguments{BasicAuth: []auth.BasicAuthUser{{Username: "alice", Password: "password", Role: auth.RoleAdmin}}}
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ev = newConfigEvent(r, "put", "config", "")
+	}))
+
+	// The user is the authenticated client, not a header set by the client.
+	req := httptest.NewRequest(http.MethodPut, "/agent/api/v1/config/config", nil)
+	req.SetBasicAuth("alice", "password")
+	req.Header.Set("X-Remote-User", "mallory")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, "alice", ev.User)
+}
+
+// mapStore is an in-memory store for testing batch requests.
+type mapStore struct {
+	configs map[string]instance.Config
+}
+
+func newMapStore(configs map[string]instance.Config) *mapStore {
+	return &mapStore{configs: configs}
+}
+
+func (s *mapStore) Names() []string {
+	names := make([]string, 0, len(s.configs))
+	for name := range s.configs {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s *mapStore) Mock() *Mock {
+	return &Mock{
+		GetFunc: func(_ context.Context, key string) (instance.Config, error) {
+			cfg, ok := s.configs[key]
+			if !ok {
+				return instance.Config{}, NotExistError{Key: key}
+			}
+			return cfg, nil
+		},
+		PutFunc: func(_ context.Context, c instance.Config) (created bool, err error) {
+			_, exist := s.configs[c.Name]
+			s.configs[c.Name] = c
+			return !exist, nil
+		},
+		DeleteFunc: func(_ context.Context, key string) error {
+			if _, ok := s.configs[key]; !ok {
+				return NotExistError{Key: key}
+			}
+			delete(s.configs, key)
+			return nil
+		},
+		ApplyBatchFunc: func(_ context.Context, puts []instance.Config, deletes []string) ([]bool, error) {
+			for _, key := range deletes {
+				if _, ok := s.configs[key]; !ok {
+					return nil, NotExistError{Key: key}
+				}
+			}
+			created := make([]bool, len(puts))
+			for i, c := range puts {
+				_, exist := s.configs[c.Name]
+				created[i] = !exist
+				s.configs[c.Name] = c
+			}
+			for _, key := range deletes {
+				delete(s.configs, key)
+			}
+			return created, nil
+		},
+	}
+}
+
 type apiTestEnvironment struct {
 	srv    *httptest.Server
 	router *mux.Router
@@ -0,0 +1,394 @@
+package configstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/internal/service/http/auth"
+	"github.com/grafana/agent/internal/static/metrics/cluster/configapi"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Audit sink types.
+const (
+	AuditSinkNone = ""
+	AuditSinkLog  = "log"
+	AuditSinkFile = "file"
+	AuditSinkHTTP = "http"
+)
+
+// DefaultAuditConfig holds default settings for the audit log.
+var DefaultAuditConfig = AuditConfig{
+	HistorySize:    20,
+	HistoryConfigs: 1000,
+	HTTPTimeout:    10 * time.Second,
+}
+
+// auditQueueSize is the number of events which can wait to be sent to the
+// sink. Events are dropped when the queue is full.
+const auditQueueSize = 1000
+
+// AuditConfig configures where changes made to configs through the API are
+// recorded.
+type AuditConfig struct {
+	// Sink is where events are sent: "log", "file", "http", or empty to only
+	// keep the recent history in memory.
+	Sink string `yaml:"sink,omitempty"`
+	// File is the path of the file events are appended to when Sink is "file".
+	File string `yaml:"file,omitempty"`
+	// URL is the endpoint events are posted to when Sink is "http".
+	URL string `yaml:"url,omitempty"`
+	// HTTPTimeout is the timeout for posting an event when Sink is "http".
+	HTTPTimeout time.Duration `yaml:"http_timeout,omitempty"`
+	// HistorySize is the number of recent events kept in memory per config.
+	// Zero uses the default.
+	HistorySize int `yaml:"history_size,omitempty"`
+	// HistoryConfigs is the number of configs whose history is kept in
+	// memory. The history of the config changed least recently is dropped
+	// first. Zero uses the default.
+	HistoryConfigs int `yaml:"history_configs,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *AuditConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultAuditConfig
+
+	type plain AuditConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is invalid.
+func (c *AuditConfig) Validate() error {
+	switch c.Sink {
+	case AuditSinkNone, AuditSinkLog:
+	case AuditSinkFile:
+		if c.File == "" {
+			return fmt.Errorf("audit file must be set when the audit sink is %q", c.Sink)
+		}
+	case AuditSinkHTTP:
+		if c.URL == "" {
+			return fmt.Errorf("audit url must be set when the audit sink is %q", c.Sink)
+		}
+	default:
+		return fmt.Errorf("unknown audit sink %q", c.Sink)
+	}
+	if c.HistorySize < 0 {
+		return fmt.Errorf("audit history_size must not be negative")
+	}
+	if c.HistoryConfigs < 0 {
+		return fmt.Errorf("audit history_configs must not be negative")
+	}
+	return nil
+}
+
+// auditSink records config events.
+type auditSink interface {
+	Record(ev configapi.ConfigEvent) error
+	io.Closer
+}
+
+func newAuditSink(l log.Logger, cfg AuditConfig) (auditSink, error) {
+	switch cfg.Sink {
+	case AuditSinkNone:
+		return nil, nil
+	case AuditSinkLog:
+		return &logAuditSink{log: l}, nil
+	case AuditSinkFile:
+		f, err := os.OpenFile(cfg.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit file: %w", err)
+		}
+		return &fileAuditSink{f: f}, nil
+	case AuditSinkHTTP:
+		timeout := cfg.HTTPTimeout
+		if timeout <= 0 {
+			timeout = DefaultAuditConfig.HTTPTimeout
+		}
+		return &httpAuditSink{url: cfg.URL, cli: &http.Client{Timeout: timeout}}, nil
+	default:
+		return nil, fmt.Errorf("unknown audit sink %q", cfg.Sink)
+	}
+}
+
+// logAuditSink writes events to the log.
+type logAuditSink struct {
+	log log.Logger
+}
+
+func (s *logAuditSink) Record(ev configapi.ConfigEvent) error {
+	return level.Info(s.log).Log(
+		"msg", "config changed",
+		"action", ev.Action,
+		"name", ev.Name,
+		"revision", ev.Revision,
+		"user", ev.User,
+		"remote_addr", ev.RemoteAddr,
+	)
+}
+
+func (s *logAuditSink) Close() error { return nil }
+
+// fileAuditSink appends events to a file as JSON lines.
+type fileAuditSink struct {
+	mut sync.Mutex
+	f   *os.File
+}
+
+func (s *fileAuditSink) Record(ev configapi.ConfigEvent) error {
+	bb, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	_, err = s.f.Write(append(bb, '\n'))
+	return err
+}
+
+func (s *fileAuditSink) Close() error { return s.f.Close() }
+
+// httpAuditSink posts events as JSON to an HTTP endpoint.
+type httpAuditSink struct {
+	url string
+	cli *http.Client
+}
+
+func (s *httpAuditSink) Record(ev configapi.ConfigEvent) error {
+	bb, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, bytes.NewReader(bb))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *httpAuditSink) Close() error { return nil }
+
+// auditor records config events to a sink and keeps the recent events of
+// each config in memory. Events are sent to the sink in the background, so
+// that a slow sink doesn't block changes to configs.
+type auditor struct {
+	log      log.Logger
+	failures prometheus.Counter
+
+	queue     chan configapi.ConfigEvent
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// sinkMut is held while sending an event, so that the sink isn't closed
+	// while it's in use.
+	sinkMut sync.RWMutex
+	sink    auditSink
+
+	mut            sync.Mutex
+	closed         bool
+	historySize    int
+	historyConfigs int
+	history        map[string][]configapi.ConfigEvent
+}
+
+// newAuditor creates a new auditor. failures is incremented for every event
+// which couldn't be sent to the sink.
+func newAuditor(l log.Logger, failures prometheus.Counter) *auditor {
+	a := &auditor{
+		log:            l,
+		failures:       failures,
+		queue:          make(chan configapi.ConfigEvent, auditQueueSize),
+		done:           make(chan struct{}),
+		historySize:    DefaultAuditConfig.HistorySize,
+		historyConfigs: DefaultAuditConfig.HistoryConfigs,
+		history:        make(map[string][]configapi.ConfigEvent),
+	}
+	go a.run()
+	return a
+}
+
+// run sends queued events to the sink until the queue is closed.
+func (a *auditor) run() {
+	defer close(a.done)
+
+	for ev := range a.queue {
+		a.sinkMut.RLock()
+		var err error
+		if a.sink != nil {
+			err = a.sink.Record(ev)
+		}
+		a.sinkMut.RUnlock()
+
+		if err != nil {
+			a.failures.Inc()
+			level.Error(a.log).Log("msg", "failed to record config change to audit sink", "action", ev.Action, "config", ev.Name, "err", err)
+		}
+	}
+}
+
+// ApplyConfig replaces the sink of the auditor. The history is kept.
+func (a *auditor) ApplyConfig(cfg AuditConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	sink, err := newAuditSink(a.log, cfg)
+	if err != nil {
+		return err
+	}
+
+	a.sinkMut.Lock()
+	prev := a.sink
+	a.sink = sink
+	a.sinkMut.Unlock()
+	if prev != nil {
+		if err := prev.Close(); err != nil {
+			level.Warn(a.log).Log("msg", "failed to close audit sink", "err", err)
+		}
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.historySize = cfg.HistorySize
+	if a.historySize == 0 {
+		a.historySize = DefaultAuditConfig.HistorySize
+	}
+	a.historyConfigs = cfg.HistoryConfigs
+	if a.historyConfigs == 0 {
+		a.historyConfigs = DefaultAuditConfig.HistoryConfigs
+	}
+	for name, events := range a.history {
+		a.history[name] = trimEvents(events, a.historySize)
+	}
+	for len(a.history) > a.historyConfigs {
+		a.evictOldest()
+	}
+	return nil
+}
+
+// Record adds ev to the history and queues it to be sent to the sink. An
+// error is returned if the queue is full, in which case ev isn't sent.
+func (a *auditor) Record(ev configapi.ConfigEvent) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if _, ok := a.history[ev.Name]; !ok && len(a.history) >= a.historyConfigs {
+		a.evictOldest()
+	}
+	a.history[ev.Name] = trimEvents(append(a.history[ev.Name], ev), a.historySize)
+
+	if a.closed {
+		return nil
+	}
+	select {
+	case a.queue <- ev:
+		return nil
+	default:
+		a.failures.Inc()
+		return fmt.Errorf("audit queue is full")
+	}
+}
+
+// evictOldest drops the history of the config changed least recently. a.mut
+// must be held.
+func (a *auditor) evictOldest() {
+	var (
+		oldestName string
+		oldestTime time.Time
+	)
+	for name, events := range a.history {
+		last := events[len(events)-1].Time
+		if oldestName == "" || last.Before(oldestTime) {
+			oldestName, oldestTime = name, last
+		}
+	}
+	delete(a.history, oldestName)
+}
+
+// History returns the recent events of the config name, oldest first.
+func (a *auditor) History(name string) []configapi.ConfigEvent {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	events := make([]configapi.ConfigEvent, len(a.history[name]))
+	copy(events, a.history[name])
+	return events
+}
+
+// Close sends the queued events to the sink and closes it. Events recorded
+// afterwards are only kept in the history.
+func (a *auditor) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		a.mut.Lock()
+		a.closed = true
+		close(a.queue)
+		a.mut.Unlock()
+		<-a.done
+
+		a.sinkMut.Lock()
+		defer a.sinkMut.Unlock()
+		if a.sink != nil {
+			err = a.sink.Close()
+			a.sink = nil
+		}
+	})
+	return err
+}
+
+// trimEvents returns the last size events.
+func trimEvents(events []configapi.ConfigEvent, size int) []configapi.ConfigEvent {
+	if len(events) <= size {
+		return events
+	}
+	return append([]configapi.ConfigEvent(nil), events[len(events)-size:]...)
+}
+
+// newConfigEvent returns an event for a change of the config name made by r.
+// The user is the client authenticated by the HTTP server; it's empty when
+// authentication is disabled or the client authenticated with a bearer token.
+func newConfigEvent(r *http.Request, action, name, revision string) configapi.ConfigEvent {
+	var user string
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		user = principal.Name
+	}
+	remoteAddr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteAddr = r.RemoteAddr
+	}
+
+	return configapi.ConfigEvent{
+		Time:       time.Now().UTC(),
+		Action:     action,
+		Name:       name,
+		Revision:   revision,
+		User:       user,
+		RemoteAddr: remoteAddr,
+	}
+}
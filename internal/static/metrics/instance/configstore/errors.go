@@ -6,6 +6,14 @@ import "fmt"
 // to the store was active.
 var ErrNotConnected = fmt.Errorf("not connected to store")
 
+// ErrBatchUnsupported is used when a batch is applied to a store which
+// doesn't support transactions.
+var ErrBatchUnsupported = fmt.Errorf("store doesn't support batches")
+
+// ErrBatchConflict is used when a batch wasn't applied because a config of
+// the batch changed while it was being applied.
+var ErrBatchConflict = fmt.Errorf("configs of the batch changed while it was applied")
+
 // NotExistError is used when a config doesn't exist.
 type NotExistError struct {
 	Key string
@@ -16,6 +24,18 @@ func (e NotExistError) Error() string {
 	return fmt.Sprintf("configuration %s does not exist", e.Key)
 }
 
+// RevisionMismatchError is used when a config doesn't match the revision
+// expected by a conditional update.
+type RevisionMismatchError struct {
+	Key      string
+	Revision string
+}
+
+// Error implements error.
+func (e RevisionMismatchError) Error() string {
+	return fmt.Sprintf("configuration %s does not match revision %s", e.Key, e.Revision)
+}
+
 // NotUniqueError is used when two scrape jobs have the same name.
 type NotUniqueError struct {
 	ScrapeJob string
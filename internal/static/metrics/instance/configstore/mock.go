@@ -15,6 +15,11 @@ type Mock struct {
 	AllFunc    func(ctx context.Context, keep func(key string) bool) (<-chan instance.Config, error)
 	WatchFunc  func() <-chan WatchEvent
 	CloseFunc  func() error
+
+	GetWithRevisionFunc func(ctx context.Context, key string) (instance.Config, string, error)
+	PutIfMatchFunc      func(ctx context.Context, c instance.Config, revision string) (created bool, err error)
+	DeleteIfMatchFunc   func(ctx context.Context, key string, revision string) error
+	ApplyBatchFunc      func(ctx context.Context, puts []instance.Config, deletes []string) (created []bool, err error)
 }
 
 // List implements Store.
@@ -49,6 +54,40 @@ func (s *Mock) Delete(ctx context.Context, key string) error {
 	panic("Delete not implemented")
 }
 
+// GetWithRevision implements Store. If GetWithRevisionFunc isn't set,
+// GetFunc is used and an empty revision is returned.
+func (s *Mock) GetWithRevision(ctx context.Context, key string) (instance.Config, string, error) {
+	if s.GetWithRevisionFunc != nil {
+		return s.GetWithRevisionFunc(ctx, key)
+	}
+	cfg, err := s.Get(ctx, key)
+	return cfg, "", err
+}
+
+// PutIfMatch implements Store.
+func (s *Mock) PutIfMatch(ctx context.Context, c instance.Config, revision string) (created bool, err error) {
+	if s.PutIfMatchFunc != nil {
+		return s.PutIfMatchFunc(ctx, c, revision)
+	}
+	panic("PutIfMatch not implemented")
+}
+
+// DeleteIfMatch implements Store.
+func (s *Mock) DeleteIfMatch(ctx context.Context, key string, revision string) error {
+	if s.DeleteIfMatchFunc != nil {
+		return s.DeleteIfMatchFunc(ctx, key, revision)
+	}
+	panic("DeleteIfMatch not implemented")
+}
+
+// ApplyBatch implements Store.
+func (s *Mock) ApplyBatch(ctx context.Context, puts []instance.Config, deletes []string) (created []bool, err error) {
+	if s.ApplyBatchFunc != nil {
+		return s.ApplyBatchFunc(ctx, puts, deletes)
+	}
+	panic("ApplyBatch not implemented")
+}
+
 // All implements Store.
 func (s *Mock) All(ctx context.Context, keep func(key string) bool) (<-chan instance.Config, error) {
 	if s.AllFunc != nil {
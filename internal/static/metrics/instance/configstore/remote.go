@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -21,6 +23,7 @@ import (
 	"github.com/grafana/dskit/kv"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 /***********************************************************************************************************************
@@ -57,6 +60,7 @@ type Remote struct {
 type agentRemoteClient struct {
 	kv.Client
 	consul *api.Client
+	etcd   *clientv3.Client // Used for transactions, which kv.Client doesn't support.
 	config kv.Config
 }
 
@@ -99,7 +103,7 @@ func (r *Remote) ApplyConfig(cfg kv.Config, enable bool) error {
 	r.reg.UnregisterAll()
 
 	if !enable {
-		r.setClient(nil, nil, kv.Config{})
+		r.setClient(nil, nil, nil, kv.Config{})
 		return nil
 	}
 
@@ -126,19 +130,49 @@ func (r *Remote) ApplyConfig(cfg kv.Config, enable bool) error {
 		return fmt.Errorf("failed to create kv client: %w", err)
 	}
 
-	r.setClient(cli, consulClient, cfg)
+	// Like for consul, the etcd client of the kv client isn't exposed, so
+	// another one is created for transactions.
+	var etcdClient *clientv3.Client
+	if cfg.Store == "etcd" {
+		etcdClient, err = newEtcdClient(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to create etcd client: %w", err)
+		}
+	}
+
+	r.setClient(cli, consulClient, etcdClient, cfg)
 	return nil
 }
 
+// newEtcdClient creates an etcd client from the etcd settings of cfg.
+func newEtcdClient(cfg kv.Config) (*clientv3.Client, error) {
+	tlsConfig, err := cfg.Etcd.GetTLS()
+	if err != nil {
+		return nil, err
+	}
+	return clientv3.New(clientv3.Config{
+		Endpoints:   cfg.Etcd.Endpoints,
+		DialTimeout: cfg.Etcd.DialTimeout,
+		TLS:         tlsConfig,
+		Username:    cfg.Etcd.UserName,
+		Password:    cfg.Etcd.Password.String(),
+	})
+}
+
 // setClient sets the active client and notifies run to restart the
 // kv watcher.
-func (r *Remote) setClient(client kv.Client, consulClient *api.Client, config kv.Config) {
+func (r *Remote) setClient(client kv.Client, consulClient *api.Client, etcdClient *clientv3.Client, config kv.Config) {
+	if r.kv != nil && r.kv.etcd != nil {
+		_ = r.kv.etcd.Close()
+	}
+
 	if client == nil && consulClient == nil {
 		r.kv = nil
 	} else {
 		r.kv = &agentRemoteClient{
 			Client: client,
 			consul: consulClient,
+			etcd:   etcdClient,
 			config: config,
 		}
 	}
@@ -251,28 +285,50 @@ func (r *Remote) listConsul(ctx context.Context) (api.KVPairs, error) {
 
 // Get retrieves an individual config from the KV store.
 func (r *Remote) Get(ctx context.Context, key string) (instance.Config, error) {
+	cfg, _, err := r.GetWithRevision(ctx, key)
+	return cfg, err
+}
+
+// GetWithRevision retrieves an individual config from the KV store along with
+// its revision.
+func (r *Remote) GetWithRevision(ctx context.Context, key string) (instance.Config, string, error) {
 	r.kvMut.RLock()
 	defer r.kvMut.RUnlock()
 	if r.kv == nil {
-		return instance.Config{}, ErrNotConnected
+		return instance.Config{}, "", ErrNotConnected
 	}
 
 	v, err := r.kv.Get(ctx, key)
 	if err != nil {
-		return instance.Config{}, fmt.Errorf("failed to get config %s: %w", key, err)
+		return instance.Config{}, "", fmt.Errorf("failed to get config %s: %w", key, err)
 	} else if v == nil {
-		return instance.Config{}, NotExistError{Key: key}
+		return instance.Config{}, "", NotExistError{Key: key}
 	}
 
 	cfg, err := instance.UnmarshalConfig(strings.NewReader(v.(string)))
 	if err != nil {
-		return instance.Config{}, fmt.Errorf("failed to unmarshal config %s: %w", key, err)
+		return instance.Config{}, "", fmt.Errorf("failed to unmarshal config %s: %w", key, err)
 	}
-	return *cfg, nil
+	return *cfg, configRevision(v.(string)), nil
 }
 
 // Put adds or updates a config in the KV store.
 func (r *Remote) Put(ctx context.Context, c instance.Config) (bool, error) {
+	return r.put(ctx, c, nil)
+}
+
+// PutIfMatch adds or updates a config in the KV store if the stored config
+// matches revision.
+func (r *Remote) PutIfMatch(ctx context.Context, c instance.Config, revision string) (bool, error) {
+	return r.put(ctx, c, func(stored interface{}) error {
+		return checkRevision(c.Name, stored, revision)
+	})
+}
+
+// put adds or updates a config in the KV store. If check is not nil, it is
+// called with the stored value of the config as part of the update, which is
+// aborted if check returns an error.
+func (r *Remote) put(ctx context.Context, c instance.Config, check func(stored interface{}) error) (bool, error) {
 	// We need to use a write lock here since two Applies can't run concurrently
 	// (given the current need to perform a store-wide validation.)
 	r.kvMut.Lock()
@@ -296,6 +352,11 @@ func (r *Remote) Put(ctx context.Context, c instance.Config) (bool, error) {
 
 	var created bool
 	err = r.kv.CAS(ctx, c.Name, func(in interface{}) (out interface{}, retry bool, err error) {
+		if check != nil {
+			if err := check(in); err != nil {
+				return nil, false, err
+			}
+		}
 		// The configuration is new if there's no previous value from the CAS
 		created = (in == nil)
 		return string(bb), false, nil
@@ -306,6 +367,329 @@ func (r *Remote) Put(ctx context.Context, c instance.Config) (bool, error) {
 	return created, nil
 }
 
+// DeleteIfMatch deletes a config from the KV store if the stored config
+// matches revision. A revision of "*" matches any stored config, but not a
+// missing one.
+//
+// The consul and etcd stores delete the config only if it's unchanged since
+// its revision was checked, so a config updated concurrently by another agent
+// isn't lost. Other KV stores don't support conditional deletes, so the check
+// is only atomic with changes made through this agent.
+func (r *Remote) DeleteIfMatch(ctx context.Context, key string, revision string) error {
+	// Like put, a write lock keeps the config from being updated through this
+	// agent between the check and the deletion.
+	r.kvMut.Lock()
+	defer r.kvMut.Unlock()
+	if r.kv == nil {
+		return ErrNotConnected
+	}
+
+	switch {
+	case r.kv.consul != nil:
+		return deleteIfMatch(key, revision, func() (interface{}, func() (bool, error), error) {
+			return r.getForDeleteConsul(ctx, key)
+		})
+	case r.kv.etcd != nil:
+		return deleteIfMatch(key, revision, func() (interface{}, func() (bool, error), error) {
+			return r.getForDeleteEtcd(ctx, key)
+		})
+	}
+
+	v, err := r.kv.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to get config %s: %w", key, err)
+	}
+	if err := checkRevision(key, v, revision); err != nil {
+		return err
+	}
+
+	err = r.kv.Delete(ctx, key)
+	if err != nil {
+		return fmt.Errorf("error deleting configuration: %w", err)
+	}
+	return nil
+}
+
+// maxDeleteAttempts is the number of times deleteIfMatch tries to delete a
+// config which matches "*" but keeps changing.
+const maxDeleteAttempts = 10
+
+// deleteIfMatch deletes key if its stored value matches revision. get returns
+// the stored value along with a function deleting key only if it's unchanged
+// since it was read, which reports whether key was deleted.
+func deleteIfMatch(key, revision string, get func() (interface{}, func() (bool, error), error)) error {
+	for attempt := 0; attempt < maxDeleteAttempts; attempt++ {
+		stored, del, err := get()
+		if err != nil {
+			return err
+		}
+		if err := checkRevision(key, stored, revision); err != nil {
+			return err
+		}
+
+		deleted, err := del()
+		if err != nil {
+			return fmt.Errorf("error deleting configuration: %w", err)
+		} else if deleted {
+			return nil
+		}
+
+		// The config changed after its revision was checked. "*" matches any
+		// config which still exists, so the deletion is retried; any other
+		// revision no longer matches.
+		if revision != "*" {
+			return RevisionMismatchError{Key: key, Revision: revision}
+		}
+	}
+	return fmt.Errorf("error deleting configuration: %s changed during every attempt", key)
+}
+
+// getForDeleteConsul returns the stored value of key and a function deleting
+// key if its modify index is unchanged.
+func (r *Remote) getForDeleteConsul(ctx context.Context, key string) (interface{}, func() (bool, error), error) {
+	var (
+		prefixed = r.kv.config.Prefix + key
+		opts     = (&api.QueryOptions{RequireConsistent: true}).WithContext(ctx)
+	)
+
+	pair, _, err := r.kv.consul.KV().Get(prefixed, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get config %s: %w", key, err)
+	} else if pair == nil {
+		return nil, nil, nil
+	}
+	stored, err := GetCodec().Decode(pair.Value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode config %s: %w", key, err)
+	}
+
+	return stored, func() (bool, error) {
+		deleted, _, err := r.kv.consul.KV().DeleteCAS(&api.KVPair{Key: prefixed, ModifyIndex: pair.ModifyIndex}, (&api.WriteOptions{}).WithContext(ctx))
+		return deleted, err
+	}, nil
+}
+
+// getForDeleteEtcd returns the stored value of key and a function deleting
+// key in a transaction if its modification revision is unchanged.
+func (r *Remote) getForDeleteEtcd(ctx context.Context, key string) (interface{}, func() (bool, error), error) {
+	prefixed := r.kv.config.Prefix + key
+
+	resp, err := r.kv.etcd.Get(ctx, prefixed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get config %s: %w", key, err)
+	} else if len(resp.Kvs) == 0 {
+		return nil, nil, nil
+	}
+	stored, err := GetCodec().Decode(resp.Kvs[0].Value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode config %s: %w", key, err)
+	}
+
+	rev := resp.Kvs[0].ModRevision
+	return stored, func() (bool, error) {
+		resp, err := r.kv.etcd.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(prefixed), "=", rev)).
+			Then(clientv3.OpDelete(prefixed)).
+			Commit()
+		if err != nil {
+			return false, err
+		}
+		return resp.Succeeded, nil
+	}, nil
+}
+
+// ApplyBatch puts and deletes configs in a single transaction. Only the
+// consul and etcd stores support transactions.
+func (r *Remote) ApplyBatch(ctx context.Context, puts []instance.Config, deletes []string) ([]bool, error) {
+	r.kvMut.Lock()
+	defer r.kvMut.Unlock()
+	if r.kv == nil {
+		return nil, ErrNotConnected
+	}
+	if r.kv.consul == nil && r.kv.etcd == nil {
+		return nil, ErrBatchUnsupported
+	}
+
+	values := make([][]byte, len(puts))
+	for i := range puts {
+		bb, err := instance.MarshalConfig(&puts[i], false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal config %s: %w", puts[i].Name, err)
+		}
+		if values[i], err = GetCodec().Encode(string(bb)); err != nil {
+			return nil, fmt.Errorf("failed to encode config %s: %w", puts[i].Name, err)
+		}
+	}
+	if err := r.checkBatchUnique(ctx, puts, deletes); err != nil {
+		return nil, err
+	}
+
+	if r.kv.consul != nil {
+		return r.applyBatchConsul(ctx, puts, values, deletes)
+	}
+	return r.applyBatchEtcd(ctx, puts, values, deletes)
+}
+
+// checkBatchUnique validates that the job names of puts are unique from
+// those of the stored configs which aren't part of the batch. The configs
+// of the batch must be checked against each other by the caller.
+func (r *Remote) checkBatchUnique(ctx context.Context, puts []instance.Config, deletes []string) error {
+	batch := make(map[string]struct{}, len(puts)+len(deletes))
+	for _, c := range puts {
+		batch[c.Name] = struct{}{}
+	}
+	for _, key := range deletes {
+		batch[key] = struct{}{}
+	}
+
+	cfgCh, err := r.all(ctx, func(key string) bool {
+		_, inBatch := batch[key]
+		return !inBatch
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check validity of configs: %w", err)
+	}
+	jobs := make(map[string]struct{})
+	for cfg := range cfgCh {
+		for _, sc := range cfg.ScrapeConfigs {
+			jobs[sc.JobName] = struct{}{}
+		}
+	}
+
+	for _, c := range puts {
+		for _, sc := range c.ScrapeConfigs {
+			if _, exist := jobs[sc.JobName]; exist {
+				return fmt.Errorf("failed to check uniqueness of config %s: %w", c.Name, NotUniqueError{ScrapeJob: sc.JobName})
+			}
+		}
+	}
+	return nil
+}
+
+// applyBatchConsul applies a batch as a consul transaction. Every operation
+// checks the index of its key as it was read, so that the transaction fails
+// if a config changed in the meantime.
+func (r *Remote) applyBatchConsul(ctx context.Context, puts []instance.Config, values [][]byte, deletes []string) ([]bool, error) {
+	var (
+		prefix = r.kv.config.Prefix
+		opts   = (&api.QueryOptions{RequireConsistent: true}).WithContext(ctx)
+
+		ops     = make(api.TxnOps, 0, len(puts)+len(deletes))
+		created = make([]bool, len(puts))
+	)
+	index := func(key string) (uint64, bool, error) {
+		pair, _, err := r.kv.consul.KV().Get(prefix+key, opts)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to get config %s: %w", key, err)
+		} else if pair == nil {
+			return 0, false, nil
+		}
+		return pair.ModifyIndex, true, nil
+	}
+
+	for i, c := range puts {
+		idx, exist, err := index(c.Name)
+		if err != nil {
+			return nil, err
+		}
+		created[i] = !exist
+
+		// An index of 0 only sets keys which don't exist.
+		ops = append(ops, &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVCAS, Key: prefix + c.Name, Value: values[i], Index: idx}})
+	}
+	for _, key := range deletes {
+		idx, exist, err := index(key)
+		if err != nil {
+			return nil, err
+		} else if !exist {
+			return nil, NotExistError{Key: key}
+		}
+		ops = append(ops, &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVDeleteCAS, Key: prefix + key, Index: idx}})
+	}
+
+	ok, resp, _, err := r.kv.consul.Txn().Txn(ops, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply batch: %w", err)
+	}
+	if !ok {
+		errs := make([]error, 0, len(resp.Errors))
+		for _, txnErr := range resp.Errors {
+			errs = append(errs, errors.New(txnErr.What))
+		}
+		return nil, fmt.Errorf("%w: %w", ErrBatchConflict, errors.Join(errs...))
+	}
+	return created, nil
+}
+
+// applyBatchEtcd applies a batch as an etcd transaction. The transaction
+// compares the revision of every key as it was read, so that it fails if a
+// config changed in the meantime.
+func (r *Remote) applyBatchEtcd(ctx context.Context, puts []instance.Config, values [][]byte, deletes []string) ([]bool, error) {
+	var (
+		prefix = r.kv.config.Prefix
+
+		cmps    = make([]clientv3.Cmp, 0, len(puts)+len(deletes))
+		ops     = make([]clientv3.Op, 0, len(puts)+len(deletes))
+		created = make([]bool, len(puts))
+	)
+	revision := func(key string) (int64, bool, error) {
+		resp, err := r.kv.etcd.Get(ctx, prefix+key)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to get config %s: %w", key, err)
+		} else if len(resp.Kvs) == 0 {
+			return 0, false, nil
+		}
+		return resp.Kvs[0].ModRevision, true, nil
+	}
+
+	for i, c := range puts {
+		rev, exist, err := revision(c.Name)
+		if err != nil {
+			return nil, err
+		}
+		created[i] = !exist
+
+		// Keys which don't exist have a modification revision of 0.
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(prefix+c.Name), "=", rev))
+		ops = append(ops, clientv3.OpPut(prefix+c.Name, string(values[i])))
+	}
+	for _, key := range deletes {
+		rev, exist, err := revision(key)
+		if err != nil {
+			return nil, err
+		} else if !exist {
+			return nil, NotExistError{Key: key}
+		}
+		cmps = append(cmps, clientv3.Compare(clientv3.ModRevision(prefix+key), "=", rev))
+		ops = append(ops, clientv3.OpDelete(prefix+key))
+	}
+
+	resp, err := r.kv.etcd.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply batch: %w", err)
+	}
+	if !resp.Succeeded {
+		return nil, ErrBatchConflict
+	}
+	return created, nil
+}
+
+// configRevision returns the revision of a config from its stored text.
+func configRevision(text string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(text))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// checkRevision returns RevisionMismatchError if the stored value of the
+// config key doesn't match revision. A nil stored value never matches.
+func checkRevision(key string, stored interface{}, revision string) error {
+	if stored != nil && (revision == "*" || configRevision(stored.(string)) == revision) {
+		return nil
+	}
+	return RevisionMismatchError{Key: key, Revision: revision}
+}
+
 // Delete deletes a config from the KV store. It returns NotExistError if
 // the config doesn't exist.
 func (r *Remote) Delete(ctx context.Context, key string) error {
@@ -467,5 +851,8 @@ func (r *Remote) Close() error {
 	r.kvMut.Lock()
 	defer r.kvMut.Unlock()
 	r.cancelFunc()
+	if r.kv != nil && r.kv.etcd != nil {
+		return r.kv.etcd.Close()
+	}
 	return nil
 }
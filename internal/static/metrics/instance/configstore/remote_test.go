@@ -165,6 +165,89 @@ func TestRemote_Delete(t *testing.T) {
 	require.EqualError(t, err, "configuration deleteme does not exist")
 }
 
+func TestRemote_Revision(t *testing.T) {
+	remote, err := NewRemote(log.NewNopLogger(), prometheus.NewRegistry(), kv.Config{
+		Store:  "inmemory",
+		Prefix: "configs/",
+	}, true)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		err := remote.Close()
+		require.NoError(t, err)
+	})
+
+	cfg := instance.DefaultConfig
+	cfg.Name = "someconfig"
+
+	_, err = remote.PutIfMatch(context.Background(), cfg, "*")
+	require.ErrorAs(t, err, &RevisionMismatchError{}, "config must exist to match *")
+
+	_, err = remote.Put(context.Background(), cfg)
+	require.NoError(t, err)
+	_, rev, err := remote.GetWithRevision(context.Background(), "someconfig")
+	require.NoError(t, err)
+	require.NotEmpty(t, rev)
+
+	cfg.HostFilter = true
+	created, err := remote.PutIfMatch(context.Background(), cfg, rev)
+	require.NoError(t, err)
+	require.False(t, created)
+
+	// The revision changed with the update.
+	_, err = remote.PutIfMatch(context.Background(), cfg, rev)
+	require.ErrorAs(t, err, &RevisionMismatchError{})
+	err = remote.DeleteIfMatch(context.Background(), "someconfig", rev)
+	require.ErrorAs(t, err, &RevisionMismatchError{})
+
+	_, newRev, err := remote.GetWithRevision(context.Background(), "someconfig")
+	require.NoError(t, err)
+	require.NotEqual(t, rev, newRev)
+	require.NoError(t, remote.DeleteIfMatch(context.Background(), "someconfig", newRev))
+
+	_, err = remote.Get(context.Background(), "someconfig")
+	require.ErrorAs(t, err, &NotExistError{})
+}
+
+func TestDeleteIfMatch_Conflict(t *testing.T) {
+	// fakeKey returns a get function for deleteIfMatch which reads the
+	// values in turn. Deletes fail until the last value was read, as if the
+	// key changed after every read.
+	fakeKey := func(values ...interface{}) (get func() (interface{}, func() (bool, error), error), deleted *bool) {
+		deleted = new(bool)
+		reads := 0
+		get = func() (interface{}, func() (bool, error), error) {
+			v := values[reads]
+			reads++
+			last := reads == len(values)
+			return v, func() (bool, error) {
+				*deleted = last
+				return last, nil
+			}, nil
+		}
+		return get, deleted
+	}
+
+	t.Run("revision changed after check", func(t *testing.T) {
+		get, deleted := fakeKey("a", "b")
+		err := deleteIfMatch("someconfig", configRevision("a"), get)
+		require.ErrorAs(t, err, &RevisionMismatchError{})
+		require.False(t, *deleted)
+	})
+
+	t.Run("any revision is retried", func(t *testing.T) {
+		get, deleted := fakeKey("a", "b")
+		require.NoError(t, deleteIfMatch("someconfig", "*", get))
+		require.True(t, *deleted)
+	})
+
+	t.Run("any revision requires the config to exist", func(t *testing.T) {
+		get, deleted := fakeKey("a", nil)
+		err := deleteIfMatch("someconfig", "*", get)
+		require.ErrorAs(t, err, &RevisionMismatchError{})
+		require.False(t, *deleted)
+	})
+}
+
 func TestRemote_All(t *testing.T) {
 	remote, err := NewRemote(log.NewNopLogger(), prometheus.NewRegistry(), kv.Config{
 		Store:  "inmemory",
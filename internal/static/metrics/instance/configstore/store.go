@@ -24,6 +24,27 @@ type Store interface {
 	// Delete deletes a config from the store.
 	Delete(ctx context.Context, key string) error
 
+	// GetWithRevision gets an individual config by name along with its
+	// revision. The revision changes whenever the config is updated.
+	GetWithRevision(ctx context.Context, key string) (instance.Config, string, error)
+
+	// PutIfMatch is like Put, but only applies c if the revision of the stored
+	// config matches revision. A revision of "*" matches any stored config.
+	// It returns RevisionMismatchError if the revision doesn't match.
+	PutIfMatch(ctx context.Context, c instance.Config, revision string) (created bool, err error)
+
+	// DeleteIfMatch is like Delete, but only deletes the config if its
+	// revision matches revision. A revision of "*" matches any stored config.
+	// It returns RevisionMismatchError if the revision doesn't match.
+	DeleteIfMatch(ctx context.Context, key string, revision string) error
+
+	// ApplyBatch puts the configs of puts and deletes the configs named by
+	// deletes in a single transaction, so that either every change is
+	// applied or none is. It returns whether each config of puts was created.
+	// It returns NotExistError if a config of deletes doesn't exist, and
+	// ErrBatchUnsupported if the store doesn't support transactions.
+	ApplyBatch(ctx context.Context, puts []instance.Config, deletes []string) (created []bool, err error)
+
 	// All retrieves the entire list of instance configs currently
 	// in the store. A filtering "keep" function can be provided to ignore some
 	// configs, which can significantly speed up the operation in some cases.
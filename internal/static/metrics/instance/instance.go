@@ -63,6 +63,7 @@ var (
 // agent. It has its own set of scrape_configs and remote_write rules.
 type Config struct {
 	Name                     string                      `yaml:"name,omitempty"`
+	Labels                   map[string]string           `yaml:"labels,omitempty"`
 	HostFilter               bool                        `yaml:"host_filter,omitempty"`
 	HostFilterRelabelConfigs []*relabel.Config           `yaml:"host_filter_relabel_configs,omitempty"`
 	ScrapeConfigs            []*config.ScrapeConfig      `yaml:"scrape_configs,omitempty"`
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/grafana/agent/internal/service/http/auth"
+	"github.com/grafana/river/rivertypes"
+	"github.com/prometheus/common/config"
+)
+
+// AuthConfig configures the authentication of requests to the HTTP server.
+// Requests require the same roles as in Flow mode: the admin role is
+// required for requests which change the state of the agent, such as
+// /-/reload and the scraping service config API, and the viewer role for
+// read-only requests.
+type AuthConfig struct {
+	BasicAuth          []BasicAuthUser     `yaml:"basic_auth,omitempty"`
+	BearerTokens       []BearerToken       `yaml:"bearer_tokens,omitempty"`
+	ClientCertificates []ClientCertificate `yaml:"client_certificates,omitempty"`
+}
+
+// BasicAuthUser is a user allowed to authenticate with basic authentication.
+type BasicAuthUser struct {
+	Username string        `yaml:"username"`
+	Password config.Secret `yaml:"password"`
+	Role     string        `yaml:"role"`
+}
+
+// BearerToken is a token allowed to authenticate with the Authorization
+// header.
+type BearerToken struct {
+	Token config.Secret `yaml:"token"`
+	Role  string        `yaml:"role"`
+}
+
+// ClientCertificate grants a role to clients authenticating with a verified
+// TLS client certificate.
+type ClientCertificate struct {
+	CommonNames []string `yaml:"common_names"`
+	Role        string   `yaml:"role"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *AuthConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type authConfig AuthConfig
+	if err := unmarshal((*authConfig)(c)); err != nil {
+		return err
+	}
+	return c.arguments().Validate()
+}
+
+// arguments converts c to the arguments of the authentication of the Flow
+// HTTP service, which implements the authentication.
+func (c *AuthConfig) arguments() *auth.Arguments {
+	if c == nil {
+		return nil
+	}
+
+	var args auth.Arguments
+	for _, user := range c.BasicAuth {
+		args.BasicAuth = append(args.BasicAuth, auth.BasicAuthUser{
+			Username: user.Username,
+			Password: rivertypes.Secret(user.Password),
+			Role:     user.Role,
+		})
+	}
+	for _, token := range c.BearerTokens {
+		args.BearerTokens = append(args.BearerTokens, auth.BearerToken{
+			Token: rivertypes.Secret(token.Token),
+			Role:  token.Role,
+		})
+	}
+	for _, cert := range c.ClientCertificates {
+		args.ClientCertificates = append(args.ClientCertificates, auth.ClientCertificate{
+			CommonNames: cert.CommonNames,
+			Role:        cert.Role,
+		})
+	}
+	return &args
+}
+
+// validateAuth checks that the client certificates of cfg can be verified by
+// the HTTP server.
+func validateAuth(cfg HTTPConfig, useTLS bool) error {
+	if cfg.Auth == nil || len(cfg.Auth.ClientCertificates) == 0 {
+		return nil
+	}
+	if !useTLS {
+		return fmt.Errorf("http_auth client_certificates require TLS to be enabled for the HTTP server")
+	}
+	if cfg.TLSConfig.WindowsCertificateFilter != nil {
+		return nil
+	}
+	clientAuth, err := GetClientAuthFromString(cfg.TLSConfig.ClientAuth)
+	if err != nil {
+		return err
+	}
+	switch clientAuth {
+	case tls.VerifyClientCertIfGiven, tls.RequireAndVerifyClientCert:
+		return nil
+	default:
+		return fmt.Errorf("http_auth client_certificates require http_tls_config client_auth_type to be VerifyClientCertIfGiven or RequireAndVerifyClientCert")
+	}
+}
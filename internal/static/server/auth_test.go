@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestServer_Auth(t *testing.T) {
+	cfg := newTestConfig()
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+basic_auth:
+  - username: admin
+    password: admin-password
+    role: admin
+bearer_tokens:
+  - token: viewer-token
+    role: viewer
+`), &cfg.HTTP.Auth))
+	flags := newTestFlags()
+	srv := runExampleServer(t, cfg, flags)
+	srv.HTTP.HandleFunc("/agent/api/v1/config/{name}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := func(client *http.Client, addr, method string, auth func(r *http.Request)) int {
+		req, err := http.NewRequestWithContext(context.Background(), method, fmt.Sprintf("http://%s/agent/api/v1/config/example", addr), nil)
+		require.NoError(t, err)
+		if auth != nil {
+			auth(req)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	viewer := func(r *http.Request) { r.Header.Set("Authorization", "Bearer viewer-token") }
+	admin := func(r *http.Request) { r.SetBasicAuth("admin", "admin-password") }
+
+	addr := srv.HTTPAddress().String()
+	require.Equal(t, http.StatusUnauthorized, request(http.DefaultClient, addr, http.MethodGet, nil))
+	require.Equal(t, http.StatusOK, request(http.DefaultClient, addr, http.MethodGet, viewer))
+	require.Equal(t, http.StatusForbidden, request(http.DefaultClient, addr, http.MethodPost, viewer))
+	require.Equal(t, http.StatusOK, request(http.DefaultClient, addr, http.MethodPost, admin))
+	require.Equal(t, http.StatusOK, request(http.DefaultClient, addr, http.MethodDelete, admin))
+
+	// In-memory traffic comes from the agent itself and isn't authenticated.
+	memClient := &http.Client{Transport: &http.Transport{DialContext: srv.DialContext}}
+	require.Equal(t, http.StatusOK, request(memClient, flags.HTTP.InMemoryAddr, http.MethodPost, nil))
+
+	// Authentication can be disabled at runtime.
+	require.NoError(t, srv.ApplyConfig(newTestConfig()))
+	require.Equal(t, http.StatusOK, request(http.DefaultClient, addr, http.MethodPost, nil))
+}
+
+func TestAuthConfig_Validate(t *testing.T) {
+	var cfg AuthConfig
+	err := yaml.UnmarshalStrict([]byte(`
+bearer_tokens:
+  - token: token
+    role: owner
+`), &cfg)
+	require.ErrorContains(t, err, `unsupported role "owner"`)
+
+	httpCfg := HTTPConfig{Auth: &AuthConfig{
+		ClientCertificates: []ClientCertificate{{CommonNames: []string{"ops"}, Role: "admin"}},
+	}}
+	require.ErrorContains(t, validateAuth(httpCfg, false), "require TLS")
+	require.ErrorContains(t, validateAuth(httpCfg, true), "client_auth_type")
+
+	httpCfg.TLSConfig.ClientAuth = "RequireAndVerifyClientCert"
+	require.NoError(t, validateAuth(httpCfg, true))
+}
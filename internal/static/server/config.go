@@ -36,6 +36,9 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 // HTTPConfig holds dynamic configuration options for the HTTP server.
 type HTTPConfig struct {
 	TLSConfig TLSConfig `yaml:"http_tls_config,omitempty"`
+
+	// Auth enables authentication of HTTP requests when set.
+	Auth *AuthConfig `yaml:"http_auth,omitempty"`
 }
 
 // GRPCConfig holds dynamic configuration options for the gRPC server.
@@ -16,6 +16,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/gorilla/mux"
+	"github.com/grafana/agent/internal/service/http/auth"
 	"github.com/grafana/ckit/memconn"
 	"github.com/grafana/dskit/middleware"
 	_ "github.com/grafana/pyroscope-go/godeltaprof/http/pprof" // anonymous import to get the godeltaprof handler registered
@@ -54,6 +55,14 @@ type Server struct {
 	updateHTTPTLS func(TLSConfig) error
 	updateGRPCTLS func(TLSConfig) error
 
+	authMut sync.RWMutex
+	auth    *AuthConfig // Nil when authentication is disabled.
+
+	// memHTTPServer serves the in-memory HTTP listener. Unlike HTTPServer, it
+	// doesn't authenticate requests: in-memory traffic comes from the agent
+	// itself and can't be sent by other processes.
+	memHTTPServer *http.Server
+
 	HTTP       *mux.Router
 	HTTPServer *http.Server
 	GRPC       *grpc.Server
@@ -193,12 +202,22 @@ func New(l log.Logger, r prometheus.Registerer, g prometheus.Gatherer, cfg Confi
 		"http_tls_enabled", flags.HTTP.UseTLS, "grpc_tls_enabled", flags.GRPC.UseTLS,
 	)
 
+	if err := validateAuth(cfg.HTTP, flags.HTTP.UseTLS); err != nil {
+		return nil, err
+	}
+
 	// Build servers
 	grpcServer := newGRPCServer(l, &flags.GRPC, m)
 	httpServer, router, err := newHTTPServer(l, g, &flags, m)
 	if err != nil {
 		return nil, err
 	}
+	memHTTPServer := &http.Server{
+		ReadTimeout:  httpServer.ReadTimeout,
+		WriteTimeout: httpServer.WriteTimeout,
+		IdleTimeout:  httpServer.IdleTimeout,
+		Handler:      httpServer.Handler,
+	}
 
 	// Build in-memory listeners and dial function
 	var (
@@ -216,7 +235,7 @@ func New(l log.Logger, r prometheus.Registerer, g prometheus.Gatherer, cfg Confi
 		}
 	}
 
-	return &Server{
+	srv = &Server{
 		flags:           flags,
 		httpListener:    httpListener,
 		grpcListener:    grpcListener,
@@ -226,11 +245,23 @@ func New(l log.Logger, r prometheus.Registerer, g prometheus.Gatherer, cfg Confi
 		updateHTTPTLS: updateHTTPTLS,
 		updateGRPCTLS: updateGRPCTLS,
 
+		auth:          cfg.HTTP.Auth,
+		memHTTPServer: memHTTPServer,
+
 		HTTP:        router,
 		HTTPServer:  httpServer,
 		GRPC:        grpcServer,
 		DialContext: dialFunc,
-	}, nil
+	}
+	httpServer.Handler = auth.NewHandler(srv.authArguments, httpServer.Handler)
+	return srv, nil
+}
+
+// authArguments returns the current authentication settings.
+func (s *Server) authArguments() *auth.Arguments {
+	s.authMut.RLock()
+	defer s.authMut.RUnlock()
+	return s.auth.arguments()
 }
 
 func newHTTPListener(opts *HTTPFlags, m *metrics) (net.Listener, error) {
@@ -365,6 +396,10 @@ func (s *Server) ApplyConfig(cfg Config) error {
 	// N.B. LogLevel/LogFormat support dynamic updating but are never used in
 	// *Server, so they're ignored here.
 
+	if err := validateAuth(cfg.HTTP, s.flags.HTTP.UseTLS); err != nil {
+		return err
+	}
+
 	if s.updateHTTPTLS != nil {
 		if err := s.updateHTTPTLS(cfg.HTTP.TLSConfig); err != nil {
 			return fmt.Errorf("updating HTTP TLS settings: %w", err)
@@ -376,6 +411,10 @@ func (s *Server) ApplyConfig(cfg Config) error {
 		}
 	}
 
+	s.authMut.Lock()
+	s.auth = cfg.HTTP.Auth
+	s.authMut.Unlock()
+
 	return nil
 }
 
@@ -394,14 +433,14 @@ func (s *Server) Run(ctx context.Context) error {
 		cancel()
 	})
 
-	httpListeners := []net.Listener{
-		s.httpListener,
-		s.httpMemListener,
+	httpServers := map[net.Listener]*http.Server{
+		s.httpListener:    s.HTTPServer,
+		s.httpMemListener: s.memHTTPServer,
 	}
-	for i := range httpListeners {
-		listener := httpListeners[i]
+	for listener, httpServer := range httpServers {
+		listener, httpServer := listener, httpServer
 		g.Add(func() error {
-			err := s.HTTPServer.Serve(listener)
+			err := httpServer.Serve(listener)
 			if errors.Is(err, http.ErrServerClosed) {
 				err = nil
 			}
@@ -409,7 +448,7 @@ func (s *Server) Run(ctx context.Context) error {
 		}, func(_ error) {
 			ctx, cancel := context.WithTimeout(context.Background(), s.flags.GracefulShutdownTimeout)
 			defer cancel()
-			_ = s.HTTPServer.Shutdown(ctx)
+			_ = httpServer.Shutdown(ctx)
 		})
 	}
 
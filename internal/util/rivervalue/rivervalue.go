@@ -0,0 +1,46 @@
+// Package rivervalue converts decoded values into values River can represent.
+package rivervalue
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/river/rivertypes"
+)
+
+// FromYAML converts values decoded by the YAML decoder into values River can
+// represent. Values decoded by the JSON decoder are converted too, since
+// they're a subset of the values decoded by the YAML decoder.
+//
+// When isSecret is true, every string in the result is a secret.
+func FromYAML(in any, isSecret bool) any {
+	switch v := in.(type) {
+	case string:
+		if isSecret {
+			return rivertypes.Secret(v)
+		}
+		return v
+	case time.Time:
+		return FromYAML(v.Format(time.RFC3339Nano), isSecret)
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			out[i] = FromYAML(elem, isSecret)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, elem := range v {
+			out[key] = FromYAML(elem, isSecret)
+		}
+		return out
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for key, elem := range v {
+			out[fmt.Sprint(key)] = FromYAML(elem, isSecret)
+		}
+		return out
+	default:
+		return in
+	}
+}
@@ -1,12 +1,53 @@
 package util
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 // Unregisterer is a Prometheus Registerer that can unregister all collectors
-// passed to it.
+// passed to it. It's safe for concurrent use.
 type Unregisterer struct {
 	wrap prometheus.Registerer
-	cs   map[prometheus.Collector]struct{}
+
+	mut   sync.Mutex
+	debug bool
+	cs    map[prometheus.Collector]string // Collectors to their call site, if known.
+	leaks []CollectorLeak
+}
+
+// CollectorLeak describes a collector which was still registered after
+// UnregisterAll tried to unregister it.
+type CollectorLeak struct {
+	// Descriptions of the metrics of the collector.
+	Descs []string
+	// Where the collector was registered from. Only set when the Unregisterer
+	// was in debug mode at registration time.
+	CallSite string
+}
+
+// String returns a human-readable description of the leak.
+func (l CollectorLeak) String() string {
+	desc := strings.Join(l.Descs, ", ")
+	if l.CallSite == "" {
+		return desc
+	}
+	return fmt.Sprintf("%s (registered at %s)", desc, l.CallSite)
+}
+
+// FormatLeaks returns a human-readable list of leaks.
+func FormatLeaks(leaks []CollectorLeak) string {
+	strs := make([]string, 0, len(leaks))
+	for _, l := range leaks {
+		strs = append(strs, l.String())
+	}
+	return strings.Join(strs, "; ")
 }
 
 // WrapWithUnregisterer wraps a prometheus Registerer with capabilities to
@@ -14,12 +55,27 @@ type Unregisterer struct {
 func WrapWithUnregisterer(reg prometheus.Registerer) *Unregisterer {
 	return &Unregisterer{
 		wrap: reg,
-		cs:   make(map[prometheus.Collector]struct{}),
+		cs:   make(map[prometheus.Collector]string),
 	}
 }
 
+// SetDebug enables or disables debug mode. In debug mode, the call site of
+// each registration is recorded and included in LeakReport. Recording call
+// sites has a cost, so debug mode is disabled by default.
+func (u *Unregisterer) SetDebug(debug bool) {
+	u.mut.Lock()
+	defer u.mut.Unlock()
+	u.debug = debug
+}
+
 // Register implements prometheus.Registerer.
 func (u *Unregisterer) Register(c prometheus.Collector) error {
+	return u.register(c, 2)
+}
+
+// register registers c, skipping skip frames to find the call site of the
+// registration.
+func (u *Unregisterer) register(c prometheus.Collector, skip int) error {
 	if u.wrap == nil {
 		return nil
 	}
@@ -28,36 +84,143 @@ func (u *Unregisterer) Register(c prometheus.Collector) error {
 	if err != nil {
 		return err
 	}
-	u.cs[c] = struct{}{}
+
+	u.mut.Lock()
+	defer u.mut.Unlock()
+
+	var callSite string
+	if u.debug {
+		if _, file, line, ok := runtime.Caller(skip); ok {
+			callSite = fmt.Sprintf("%s:%d", file, line)
+		}
+	}
+	u.cs[c] = callSite
 	return nil
 }
 
 // MustRegister implements prometheus.Registerer.
 func (u *Unregisterer) MustRegister(cs ...prometheus.Collector) {
 	for _, c := range cs {
-		if err := u.Register(c); err != nil {
+		if err := u.register(c, 2); err != nil {
 			panic(err)
 		}
 	}
 }
 
+// TryRegister registers c like Register, but replaces an identical collector
+// which was already registered through the Registerer instead of failing.
+// This allows a component to register its collectors again after a reload
+// which didn't unregister them. Collectors registered by others are never
+// replaced.
+func (u *Unregisterer) TryRegister(c prometheus.Collector) error {
+	err := u.register(c, 2)
+
+	var are prometheus.AlreadyRegisteredError
+	if !errors.As(err, &are) {
+		return err
+	}
+
+	u.mut.Lock()
+	_, tracked := u.cs[are.ExistingCollector]
+	u.mut.Unlock()
+	if !tracked || !u.Unregister(are.ExistingCollector) {
+		return err
+	}
+	return u.register(c, 2)
+}
+
 // Unregister implements prometheus.Registerer.
 func (u *Unregisterer) Unregister(c prometheus.Collector) bool {
 	if u.wrap != nil && u.wrap.Unregister(c) {
+		u.mut.Lock()
 		delete(u.cs, c)
+		u.mut.Unlock()
 		return true
 	}
 	return false
 }
 
-// UnregisterAll unregisters all collectors that were registered through the
+// Count returns the number of collectors currently registered through the
 // Registerer.
+func (u *Unregisterer) Count() int {
+	u.mut.Lock()
+	defer u.mut.Unlock()
+	return len(u.cs)
+}
+
+// UnregisterAll unregisters all collectors that were registered through the
+// Registerer. It returns false if some of the collectors are still registered
+// afterwards; they are reported by LeakReport.
 func (u *Unregisterer) UnregisterAll() bool {
-	success := true
-	for c := range u.cs {
-		if !u.Unregister(c) {
-			success = false
+	u.mut.Lock()
+	cs := make(map[prometheus.Collector]string, len(u.cs))
+	for c, callSite := range u.cs {
+		cs[c] = callSite
+	}
+	u.mut.Unlock()
+
+	var leaks []CollectorLeak
+	for c, callSite := range cs {
+		if u.Unregister(c) || !u.stillRegistered(c) {
+			continue
 		}
+		leaks = append(leaks, CollectorLeak{Descs: describe(c), CallSite: callSite})
+	}
+	sort.Slice(leaks, func(i, j int) bool {
+		return leaks[i].String() < leaks[j].String()
+	})
+
+	u.mut.Lock()
+	u.leaks = leaks
+	u.mut.Unlock()
+	return len(leaks) == 0
+}
+
+// stillRegistered reports whether c, which couldn't be unregistered, is still
+// registered with the wrapped Registerer. Collectors which aren't registered
+// anymore, for example because they were unregistered without going through
+// u, are forgotten.
+func (u *Unregisterer) stillRegistered(c prometheus.Collector) bool {
+	if u.wrap == nil {
+		return false
+	}
+
+	// Registering the collector again is the only way to find out whether it's
+	// registered; it's unregistered right away if that succeeds.
+	err := u.wrap.Register(c)
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		return true
+	}
+	if err == nil {
+		u.wrap.Unregister(c)
+	}
+
+	u.mut.Lock()
+	delete(u.cs, c)
+	u.mut.Unlock()
+	return false
+}
+
+// LeakReport returns the collectors which were still registered after the
+// latest call to UnregisterAll.
+func (u *Unregisterer) LeakReport() []CollectorLeak {
+	u.mut.Lock()
+	defer u.mut.Unlock()
+	return append([]CollectorLeak(nil), u.leaks...)
+}
+
+// describe returns the descriptions of the metrics of c.
+func describe(c prometheus.Collector) []string {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		c.Describe(ch)
+		close(ch)
+	}()
+
+	var descs []string
+	for d := range ch {
+		descs = append(descs, d.String())
 	}
-	return success
+	return descs
 }
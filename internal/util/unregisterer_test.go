@@ -0,0 +1,141 @@
+package util
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnregisterer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	u := WrapWithUnregisterer(reg)
+
+	a := prometheus.NewCounter(prometheus.CounterOpts{Name: "a_total"})
+	b := prometheus.NewCounter(prometheus.CounterOpts{Name: "b_total"})
+	u.MustRegister(a, b)
+	require.Equal(t, 2, u.Count())
+
+	require.True(t, u.UnregisterAll())
+	require.Equal(t, 0, u.Count())
+	require.Empty(t, u.LeakReport())
+
+	// The collectors can be registered again once unregistered.
+	require.NoError(t, reg.Register(a))
+}
+
+func TestUnregisterer_LeakReport(t *testing.T) {
+	reg := &stickyRegisterer{Registerer: prometheus.NewRegistry()}
+	u := WrapWithUnregisterer(reg)
+	u.SetDebug(true)
+
+	a := prometheus.NewCounter(prometheus.CounterOpts{Name: "a_total"})
+	require.NoError(t, u.Register(a))
+
+	// A collector which the wrapped Registerer refuses to unregister is still
+	// registered after UnregisterAll.
+	require.False(t, u.UnregisterAll())
+	require.Equal(t, 1, u.Count())
+
+	leaks := u.LeakReport()
+	require.Len(t, leaks, 1)
+	require.Len(t, leaks[0].Descs, 1)
+	require.Contains(t, leaks[0].Descs[0], `"a_total"`)
+	require.Contains(t, leaks[0].CallSite, "unregisterer_test.go:")
+	require.Contains(t, leaks[0].String(), "registered at")
+
+	// Call sites aren't recorded outside of debug mode.
+	b := prometheus.NewCounter(prometheus.CounterOpts{Name: "b_total"})
+	u = WrapWithUnregisterer(reg)
+	require.NoError(t, u.Register(b))
+	require.False(t, u.UnregisterAll())
+	require.Equal(t, "", u.LeakReport()[0].CallSite)
+}
+
+func TestUnregisterer_UnregisteredElsewhere(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	u := WrapWithUnregisterer(reg)
+
+	a := prometheus.NewCounter(prometheus.CounterOpts{Name: "a_total"})
+	require.NoError(t, u.Register(a))
+
+	// A collector which was unregistered without going through the
+	// Unregisterer isn't registered anymore, so it isn't a leak.
+	require.True(t, reg.Unregister(a))
+	require.True(t, u.UnregisterAll())
+	require.Empty(t, u.LeakReport())
+	require.Equal(t, 0, u.Count())
+
+	// Checking whether the collector was still registered didn't leave it
+	// registered.
+	require.NoError(t, reg.Register(a))
+}
+
+// stickyRegisterer is a prometheus.Registerer which never unregisters
+// collectors.
+type stickyRegisterer struct {
+	prometheus.Registerer
+}
+
+func (r *stickyRegisterer) Unregister(prometheus.Collector) bool { return false }
+
+func TestUnregisterer_TryRegister(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	u := WrapWithUnregisterer(reg)
+
+	a := prometheus.NewCounter(prometheus.CounterOpts{Name: "a_total"})
+	require.NoError(t, u.Register(a))
+
+	// An identical collector replaces the registered one.
+	replacement := prometheus.NewCounter(prometheus.CounterOpts{Name: "a_total"})
+	require.Error(t, u.Register(replacement))
+	require.NoError(t, u.TryRegister(replacement))
+	require.Equal(t, 1, u.Count())
+	require.False(t, reg.Unregister(a))
+
+	// Collectors which conflict without being identical still fail.
+	conflicting := prometheus.NewGauge(prometheus.GaugeOpts{Name: "a_total", Help: "different"})
+	require.Error(t, u.TryRegister(conflicting))
+	require.Equal(t, 1, u.Count())
+
+	require.True(t, u.UnregisterAll())
+	require.Equal(t, 0, u.Count())
+
+	// Collectors which weren't registered through the Unregisterer are left
+	// alone.
+	other := prometheus.NewCounter(prometheus.CounterOpts{Name: "other_total"})
+	require.NoError(t, reg.Register(other))
+	require.Error(t, u.TryRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "other_total"})))
+	require.Equal(t, 0, u.Count())
+	require.True(t, reg.Unregister(other))
+}
+
+func TestUnregisterer_Concurrent(t *testing.T) {
+	u := WrapWithUnregisterer(prometheus.NewRegistry())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				name := fmt.Sprintf("metric_%d_%d_total", i, j)
+				u.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: name}))
+				_ = u.Count()
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				u.UnregisterAll()
+				_ = u.LeakReport()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.True(t, u.UnregisterAll())
+	require.Equal(t, 0, u.Count())
+}
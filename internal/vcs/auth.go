@@ -2,11 +2,13 @@ package vcs
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/grafana/river/rivertypes"
+	gossh "golang.org/x/crypto/ssh"
 )
 
 type GitAuthConfig struct {
@@ -53,6 +55,22 @@ type SSHKey struct {
 	Key        rivertypes.Secret `river:"key,attr,optional"`
 	Keyfile    string            `river:"key_file,attr,optional"`
 	Passphrase rivertypes.Secret `river:"passphrase,attr,optional"`
+
+	// Known host keys of the server, in the format of an OpenSSH known_hosts
+	// file. The default known_hosts files are used when neither is set.
+	KnownHosts     string `river:"known_hosts,attr,optional"`
+	KnownHostsFile string `river:"known_hosts_file,attr,optional"`
+}
+
+// Validate implements river.Validator.
+func (s *SSHKey) Validate() error {
+	if s.Key != "" && s.Keyfile != "" {
+		return fmt.Errorf("at most one of key and key_file may be set")
+	}
+	if s.KnownHosts != "" && s.KnownHostsFile != "" {
+		return fmt.Errorf("at most one of known_hosts and known_hosts_file may be set")
+	}
+	return nil
 }
 
 // Convert converts our type to the native prometheus type
@@ -61,21 +79,52 @@ func (s *SSHKey) Convert() (transport.AuthMethod, error) {
 		return nil, nil
 	}
 
-	if s.Key != "" {
-		publickeys, err := ssh.NewPublicKeys(s.Username, []byte(s.Key), string(s.Passphrase))
-		if err != nil {
-			return nil, fmt.Errorf("Loading SSH keys failed: %s", err.Error())
-		}
-		return publickeys, nil
+	var (
+		publickeys *ssh.PublicKeys
+		err        error
+	)
+	switch {
+	case s.Key != "":
+		publickeys, err = ssh.NewPublicKeys(s.Username, []byte(s.Key), string(s.Passphrase))
+	case s.Keyfile != "":
+		publickeys, err = ssh.NewPublicKeysFromFile(s.Username, s.Keyfile, string(s.Passphrase))
+	default:
+		return nil, nil
 	}
+	if err != nil {
+		return nil, fmt.Errorf("Loading SSH keys failed: %s", err.Error())
+	}
+
+	if publickeys.HostKeyCallback, err = s.hostKeyCallback(); err != nil {
+		return nil, fmt.Errorf("Loading SSH known hosts failed: %s", err.Error())
+	}
+	return publickeys, nil
+}
 
-	if s.Keyfile != "" {
-		publickeys, err := ssh.NewPublicKeysFromFile(s.Username, s.Keyfile, string(s.Passphrase))
+// hostKeyCallback returns the callback verifying the host key of the server.
+// It returns nil when no known hosts are set, so that the default known_hosts
+// files are used.
+func (s *SSHKey) hostKeyCallback() (gossh.HostKeyCallback, error) {
+	switch {
+	case s.KnownHostsFile != "":
+		return ssh.NewKnownHostsCallback(s.KnownHostsFile)
+
+	case s.KnownHosts != "":
+		// Known hosts can only be parsed from files; the file is read when the
+		// callback is created and can be removed afterwards.
+		f, err := os.CreateTemp("", "known_hosts")
 		if err != nil {
-			return nil, fmt.Errorf("Loading SSH keys failed: %s", err.Error())
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+		if _, err := f.WriteString(s.KnownHosts); err != nil {
+			f.Close()
+			return nil, err
 		}
-		return publickeys, nil
+		if err := f.Close(); err != nil {
+			return nil, err
+		}
+		return ssh.NewKnownHostsCallback(f.Name())
 	}
-
 	return nil, nil
 }
@@ -0,0 +1,80 @@
+package vcs_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/grafana/agent/internal/vcs"
+	"github.com/grafana/river/rivertypes"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestSSHKey_KnownHosts(t *testing.T) {
+	clientKey := newPrivateKey(t)
+	hostKey, otherKey := newPublicKey(t), newPublicKey(t)
+
+	knownHosts := knownhosts.Line([]string{"git.example.com"}, hostKey) + "\n"
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(knownHostsFile, []byte(knownHosts), 0600))
+
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 22}
+
+	tt := []struct {
+		name string
+		key  vcs.SSHKey
+	}{
+		{name: "known_hosts", key: vcs.SSHKey{Username: "git", Key: clientKey, KnownHosts: knownHosts}},
+		{name: "known_hosts_file", key: vcs.SSHKey{Username: "git", Key: clientKey, KnownHostsFile: knownHostsFile}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.key.Validate())
+
+			auth, err := tc.key.Convert()
+			require.NoError(t, err)
+			callback := auth.(*gitssh.PublicKeys).HostKeyCallback
+			require.NotNil(t, callback)
+
+			require.NoError(t, callback("git.example.com:22", addr, hostKey))
+			// A different key for a known host is rejected.
+			require.Error(t, callback("git.example.com:22", addr, otherKey))
+			// Unknown hosts are rejected.
+			require.Error(t, callback("other.example.com:22", addr, hostKey))
+		})
+	}
+}
+
+func TestSSHKey_Validate(t *testing.T) {
+	key := vcs.SSHKey{Username: "git", Key: "key", Keyfile: "key_file"}
+	require.EqualError(t, key.Validate(), "at most one of key and key_file may be set")
+
+	key = vcs.SSHKey{Username: "git", KnownHosts: "known_hosts", KnownHostsFile: "known_hosts_file"}
+	require.EqualError(t, key.Validate(), "at most one of known_hosts and known_hosts_file may be set")
+}
+
+// newPrivateKey returns a new SSH private key in the OpenSSH format.
+func newPrivateKey(t *testing.T) rivertypes.Secret {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	require.NoError(t, err)
+	return rivertypes.Secret(pem.EncodeToMemory(block))
+}
+
+func newPublicKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	return key
+}
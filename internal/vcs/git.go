@@ -3,19 +3,35 @@ package vcs
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
 )
 
 type GitRepoOptions struct {
 	Repository string
 	Revision   string
 	Auth       GitAuthConfig
+
+	// SparsePaths limits the checked out files to the given directories.
+	// The whole repository is checked out when empty. When set and Revision
+	// is a branch or a tag, only the latest commit of Revision is fetched.
+	SparsePaths []string
+
+	// Submodules initializes and updates the submodules of the repository
+	// after every checkout.
+	Submodules bool
 }
 
 // GitRepo manages a Git repository for the purposes of retrieving a file from
@@ -41,13 +57,30 @@ func NewGitRepo(ctx context.Context, storagePath string, opts GitRepoOptions) (*
 	)
 
 	if !isRepoCloned(storagePath) {
-		repo, err = git.PlainCloneContext(ctx, storagePath, false, &git.CloneOptions{
-			URL:               opts.Repository,
-			ReferenceName:     plumbing.HEAD,
-			Auth:              opts.Auth.Convert(),
-			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
-			Tags:              git.AllTags,
-		})
+		cloneOpts := &git.CloneOptions{
+			URL:           opts.Repository,
+			ReferenceName: plumbing.HEAD,
+			Auth:          opts.Auth.Convert(),
+			Tags:          git.AllTags,
+		}
+		if len(opts.SparsePaths) > 0 {
+			remoteRef, _, err := sparseRefs(ctx, opts)
+			if err != nil {
+				return nil, DownloadFailedError{
+					Repository: opts.Repository,
+					Inner:      err,
+				}
+			}
+			// Files are checked out below, limited to the sparse paths.
+			cloneOpts.NoCheckout = true
+			if remoteRef != "" {
+				cloneOpts.ReferenceName = remoteRef
+				cloneOpts.SingleBranch = true
+				cloneOpts.Depth = 1
+				cloneOpts.Tags = git.NoTags
+			}
+		}
+		repo, err = git.PlainCloneContext(ctx, storagePath, false, cloneOpts)
 	} else {
 		repo, err = git.PlainOpen(storagePath)
 	}
@@ -66,27 +99,19 @@ func NewGitRepo(ctx context.Context, storagePath string, opts GitRepoOptions) (*
 			Inner:      err,
 		}
 	}
-	pullRepoErr := wt.PullContext(ctx, &git.PullOptions{
-		RemoteName: "origin",
-		Force:      true,
-		Auth:       opts.Auth.Convert(),
-	})
-	if pullRepoErr != nil && !errors.Is(pullRepoErr, git.NoErrAlreadyUpToDate) {
-		workTree, err := repo.Worktree()
-		if err != nil {
-			return nil, err
-		}
-		return &GitRepo{
-				opts:     opts,
-				repo:     repo,
-				workTree: workTree,
-			}, UpdateFailedError{
-				Repository: opts.Repository,
-				Inner:      pullRepoErr,
-			}
+	gitRepo := &GitRepo{
+		opts:     opts,
+		repo:     repo,
+		workTree: wt,
+	}
+	if pullRepoErr := gitRepo.pull(ctx); pullRepoErr != nil {
+		return gitRepo, UpdateFailedError{
+			Repository: opts.Repository,
+			Inner:      pullRepoErr,
+		}
 	}
 
-	checkoutErr := checkout(opts.Revision, repo)
+	checkoutErr := gitRepo.checkout(ctx)
 	if checkoutErr != nil {
 		return nil, UpdateFailedError{
 			Repository: opts.Repository,
@@ -94,11 +119,7 @@ func NewGitRepo(ctx context.Context, storagePath string, opts GitRepoOptions) (*
 		}
 	}
 
-	return &GitRepo{
-		opts:     opts,
-		repo:     repo,
-		workTree: wt,
-	}, err
+	return gitRepo, err
 }
 
 func isRepoCloned(dir string) bool {
@@ -109,19 +130,14 @@ func isRepoCloned(dir string) bool {
 // Update updates the repository by pulling new content and re-checking out to
 // latest version of Revision.
 func (repo *GitRepo) Update(ctx context.Context) error {
-	pullRepoErr := repo.workTree.PullContext(ctx, &git.PullOptions{
-		RemoteName: "origin",
-		Force:      true,
-		Auth:       repo.opts.Auth.Convert(),
-	})
-	if pullRepoErr != nil && !errors.Is(pullRepoErr, git.NoErrAlreadyUpToDate) {
+	if pullRepoErr := repo.pull(ctx); pullRepoErr != nil {
 		return UpdateFailedError{
 			Repository: repo.opts.Repository,
 			Inner:      pullRepoErr,
 		}
 	}
 
-	checkoutErr := checkout(repo.opts.Revision, repo.repo)
+	checkoutErr := repo.checkout(ctx)
 	if checkoutErr != nil {
 		return UpdateFailedError{
 			Repository: repo.opts.Repository,
@@ -132,6 +148,11 @@ func (repo *GitRepo) Update(ctx context.Context) error {
 	return nil
 }
 
+// Open opens the file of the repository specified by path for reading.
+func (repo *GitRepo) Open(path string) (io.ReadCloser, error) {
+	return repo.workTree.Filesystem.Open(path)
+}
+
 // ReadFile returns a file from the repository specified by path.
 func (repo *GitRepo) ReadFile(path string) ([]byte, error) {
 	f, err := repo.workTree.Filesystem.Open(path)
@@ -170,11 +191,291 @@ func (repo *GitRepo) CurrentRevision() (string, error) {
 	return ref.Hash().String(), nil
 }
 
+// pull fetches the latest contents of the repository.
+//
+// Pulling resets the whole worktree, so a sparse checkout only fetches
+// instead, and the HEAD revision is resolved to its remote branch by
+// checkout. When the revision is a branch or a tag, only its latest commit is
+// fetched.
+func (repo *GitRepo) pull(ctx context.Context) error {
+	var err error
+	if len(repo.opts.SparsePaths) > 0 {
+		fetchOpts := &git.FetchOptions{
+			RemoteName: "origin",
+			Force:      true,
+			Tags:       git.AllTags,
+			Auth:       repo.opts.Auth.Convert(),
+		}
+
+		remoteRef, localRef, refsErr := sparseRefs(ctx, repo.opts)
+		if refsErr != nil {
+			return refsErr
+		}
+		if remoteRef != "" {
+			fetchOpts.RefSpecs = []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", remoteRef, localRef))}
+			fetchOpts.Depth = 1
+			fetchOpts.Tags = git.NoTags
+		}
+		err = repo.repo.FetchContext(ctx, fetchOpts)
+	} else {
+		err = repo.workTree.PullContext(ctx, &git.PullOptions{
+			RemoteName: "origin",
+			Force:      true,
+			Auth:       repo.opts.Auth.Convert(),
+		})
+	}
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+	return nil
+}
+
+// checkout checks out the configured revision and updates submodules if
+// they're enabled.
+func (repo *GitRepo) checkout(ctx context.Context) error {
+	sparse := len(repo.opts.SparsePaths) > 0
+
+	rev := repo.opts.Revision
+	if sparse && rev == plumbing.HEAD.String() {
+		// The local HEAD isn't updated by fetching, so the default branch of
+		// the remote is checked out instead.
+		branch, err := repo.remoteDefaultBranch(ctx)
+		if err != nil {
+			return err
+		}
+		rev = branch
+	}
+
+	// The files of a sparse checkout are written by sparseCheckout, so only
+	// HEAD is moved.
+	if err := checkout(rev, repo.repo, sparse); err != nil {
+		return err
+	}
+	if sparse {
+		if err := repo.sparseCheckout(); err != nil {
+			return err
+		}
+	}
+	if !repo.opts.Submodules {
+		return nil
+	}
+
+	submodules, err := repo.workTree.Submodules()
+	if err != nil {
+		return err
+	}
+	return submodules.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Auth:              repo.opts.Auth.Convert(),
+	})
+}
+
+// sparseCheckout writes the files of the HEAD commit which are inside the
+// sparse paths to the worktree, and removes every other file.
+//
+// go-git marks the index entries outside of the sparse paths to be skipped,
+// but still writes their files, so the worktree is written here instead.
+func (repo *GitRepo) sparseCheckout() error {
+	head, err := repo.repo.Head()
+	if err != nil {
+		return err
+	}
+	commit, err := repo.repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]struct{})
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !InSparsePaths(f.Name, repo.opts.SparsePaths) {
+			return nil
+		}
+		keep[f.Name] = struct{}{}
+		return repo.writeFile(f)
+	})
+	if err != nil {
+		return err
+	}
+	return repo.removeFiles("", keep)
+}
+
+// writeFile writes f to the worktree.
+func (repo *GitRepo) writeFile(f *object.File) error {
+	wfs := repo.workTree.Filesystem
+
+	if f.Mode == filemode.Symlink {
+		target, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		if err := wfs.Remove(f.Name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return wfs.Symlink(target, f.Name)
+	}
+
+	mode, err := f.Mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+	r, err := f.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := wfs.OpenFile(f.Name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// removeFiles removes the files in dir of the worktree which aren't in keep,
+// and the directories left empty.
+func (repo *GitRepo) removeFiles(dir string, keep map[string]struct{}) error {
+	wfs := repo.workTree.Filesystem
+
+	entries, err := wfs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := path.Join(dir, entry.Name())
+		switch {
+		case dir == "" && entry.Name() == git.GitDirName:
+			continue
+
+		case entry.IsDir():
+			if err := repo.removeFiles(name, keep); err != nil {
+				return err
+			}
+			if left, err := wfs.ReadDir(name); err == nil && len(left) == 0 {
+				if err := wfs.Remove(name); err != nil {
+					return err
+				}
+			}
+
+		default:
+			if _, ok := keep[name]; !ok {
+				if err := wfs.Remove(name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// InSparsePaths reports whether the file at path, relative to the root of the
+// repository, is checked out when only sparsePaths are checked out.
+func InSparsePaths(p string, sparsePaths []string) bool {
+	p = path.Clean(filepath.ToSlash(p))
+	for _, sp := range sparsePaths {
+		sp = path.Clean(filepath.ToSlash(sp))
+		if p == sp || strings.HasPrefix(p, sp+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sparseRefs returns the remote reference of the branch or tag named by the
+// revision of opts, and the local reference it's fetched to. It returns empty
+// names when the revision isn't a branch or a tag, such as a commit hash, in
+// which case the whole repository must be fetched.
+func sparseRefs(ctx context.Context, opts GitRepoOptions) (remoteRef, localRef plumbing.ReferenceName, err error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{opts.Repository},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: opts.Auth.Convert()})
+	if err != nil {
+		return "", "", err
+	}
+
+	rev := opts.Revision
+	names := make(map[plumbing.ReferenceName]struct{}, len(refs))
+	for _, ref := range refs {
+		names[ref.Name()] = struct{}{}
+		if rev == plumbing.HEAD.String() && ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			rev = ref.Target().Short()
+		}
+	}
+
+	if branch := plumbing.NewBranchReferenceName(rev); hasRef(names, branch) {
+		return branch, plumbing.NewRemoteReferenceName("origin", rev), nil
+	}
+	if tag := plumbing.NewTagReferenceName(rev); hasRef(names, tag) {
+		return tag, tag, nil
+	}
+	return "", "", nil
+}
+
+func hasRef(names map[plumbing.ReferenceName]struct{}, name plumbing.ReferenceName) bool {
+	_, ok := names[name]
+	return ok
+}
+
+// remoteDefaultBranch returns the name of the branch HEAD points to on the
+// origin remote.
+func (repo *GitRepo) remoteDefaultBranch(ctx context.Context) (string, error) {
+	remote, err := repo.repo.Remote("origin")
+	if err != nil {
+		return "", err
+	}
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: repo.opts.Auth.Convert()})
+	if err != nil {
+		return "", err
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.HEAD && ref.Type() == plumbing.SymbolicReference {
+			return ref.Target().Short(), nil
+		}
+	}
+	return "", fmt.Errorf("couldn't find the default branch of %s", repo.opts.Repository)
+}
+
+// ListTags returns the names of the tags of a repository without cloning it.
+func ListTags(ctx context.Context, repository string, auth GitAuthConfig) ([]string, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repository},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth.Convert()})
+	if err != nil {
+		return nil, DownloadFailedError{
+			Repository: repository,
+			Inner:      err,
+		}
+	}
+
+	var tags []string
+	for _, ref := range refs {
+		if ref.Name().IsTag() {
+			tags = append(tags, ref.Name().Short())
+		}
+	}
+	return tags, nil
+}
+
 // Depending on the type of revision we need to handle checkout differently.
 // Tags are checked out as branches
 // Branches as branches
 // Commits are commits
-func checkout(rev string, repo *git.Repository) error {
+//
+// When keepWorktree is true, only HEAD is moved and the worktree is left
+// untouched.
+func checkout(rev string, repo *git.Repository, keepWorktree bool) error {
 	// Try looking for the revision in the following order:
 	//
 	// 1. Search by tag name.
@@ -188,21 +489,24 @@ func checkout(rev string, repo *git.Repository) error {
 	if tagRef, err := repo.Tag(rev); err == nil {
 		return wt.Checkout(&git.CheckoutOptions{
 			Branch: tagRef.Name(),
-			Force:  true,
+			Force:  !keepWorktree,
+			Keep:   keepWorktree,
 		})
 	}
 
 	if remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName("origin", rev), true); err == nil {
 		return wt.Checkout(&git.CheckoutOptions{
 			Branch: remoteRef.Name(),
-			Force:  true,
+			Force:  !keepWorktree,
+			Keep:   keepWorktree,
 		})
 	}
 
 	if hash, err := repo.ResolveRevision(plumbing.Revision(rev)); err == nil {
 		return wt.Checkout(&git.CheckoutOptions{
 			Hash:  *hash,
-			Force: true,
+			Force: !keepWorktree,
+			Keep:  keepWorktree,
 		})
 	}
 
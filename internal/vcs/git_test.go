@@ -2,10 +2,15 @@ package vcs_test
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/grafana/agent/internal/vcs"
 	"github.com/stretchr/testify/require"
 )
@@ -59,6 +64,112 @@ func Test_GitRepo(t *testing.T) {
 	require.Equal(t, "See you later!", string(bb))
 }
 
+func Test_GitRepo_SparsePaths(t *testing.T) {
+	origRepo := initRepository(t)
+
+	commit := func(files map[string]string) {
+		for path, contents := range files {
+			require.NoError(t, origRepo.WriteFile(path, []byte(contents)))
+		}
+		_, err := origRepo.Worktree.Add(".")
+		require.NoError(t, err)
+		_, err = origRepo.Worktree.Commit("commit", &git.CommitOptions{})
+		require.NoError(t, err)
+	}
+	commit(map[string]string{
+		"modules/a.river": "a",
+		"other/b.river":   "b",
+	})
+	commit(map[string]string{
+		"modules/a.river": "a",
+		"other/b.river":   "b1",
+	})
+
+	origRef, err := origRepo.CurrentRef()
+	require.NoError(t, err)
+
+	newRepoDir := t.TempDir()
+	newRepo, err := vcs.NewGitRepo(context.Background(), newRepoDir, vcs.GitRepoOptions{
+		Repository:  origRepo.Directory,
+		Revision:    origRef,
+		SparsePaths: []string{"modules"},
+	})
+	require.NoError(t, err)
+
+	// Only the latest commit of the branch is fetched.
+	require.FileExists(t, filepath.Join(newRepoDir, ".git", "shallow"))
+	require.NoDirExists(t, filepath.Join(newRepoDir, "other"))
+
+	bb, err := newRepo.ReadFile("modules/a.river")
+	require.NoError(t, err)
+	require.Equal(t, "a", string(bb))
+	_, err = newRepo.Stat("other/b.river")
+	require.Error(t, err)
+
+	// Updates are checked out, still limited to the sparse paths.
+	commit(map[string]string{
+		"modules/a.river": "a2",
+		"other/b.river":   "b2",
+	})
+	require.NoError(t, newRepo.Update(context.Background()))
+
+	bb, err = newRepo.ReadFile("modules/a.river")
+	require.NoError(t, err)
+	require.Equal(t, "a2", string(bb))
+	_, err = newRepo.Stat("other/b.river")
+	require.Error(t, err)
+}
+
+func Test_GitRepo_Submodules(t *testing.T) {
+	subRepo := initRepository(t)
+	require.NoError(t, subRepo.WriteFile("b.river", []byte("b")))
+	_, err := subRepo.Worktree.Add(".")
+	require.NoError(t, err)
+	subCommit, err := subRepo.Worktree.Commit("initial commit", &git.CommitOptions{})
+	require.NoError(t, err)
+
+	// The submodule is added to the index as a gitlink, like git submodule add
+	// does.
+	origRepo := initRepository(t)
+	require.NoError(t, origRepo.WriteFile("a.river", []byte("a")))
+	require.NoError(t, origRepo.WriteFile(".gitmodules", []byte(fmt.Sprintf("[submodule \"sub\"]\n\tpath = sub\n\turl = %s\n", subRepo.Directory))))
+	_, err = origRepo.Worktree.Add(".")
+	require.NoError(t, err)
+	idx, err := origRepo.Repo.Storer.Index()
+	require.NoError(t, err)
+	idx.Entries = append(idx.Entries, &index.Entry{Name: "sub", Mode: filemode.Submodule, Hash: subCommit})
+	require.NoError(t, origRepo.Repo.Storer.SetIndex(idx))
+	_, err = origRepo.Worktree.Commit("add submodule", &git.CommitOptions{})
+	require.NoError(t, err)
+
+	origRef, err := origRepo.CurrentRef()
+	require.NoError(t, err)
+
+	t.Run("enabled", func(t *testing.T) {
+		newRepo, err := vcs.NewGitRepo(context.Background(), t.TempDir(), vcs.GitRepoOptions{
+			Repository: origRepo.Directory,
+			Revision:   origRef,
+			Submodules: true,
+		})
+		require.NoError(t, err)
+
+		bb, err := newRepo.ReadFile("sub/b.river")
+		require.NoError(t, err)
+		require.Equal(t, "b", string(bb))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		newRepo, err := vcs.NewGitRepo(context.Background(), t.TempDir(), vcs.GitRepoOptions{
+			Repository: origRepo.Directory,
+			Revision:   origRef,
+		})
+		require.NoError(t, err)
+
+		_, err = newRepo.ReadFile("sub/b.river")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
 type testRepository struct {
 	Directory string
 	Repo      *git.Repository
@@ -6,24 +6,41 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"path"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/grafana/agent/internal/component"
 	"github.com/grafana/agent/internal/service"
 	"github.com/grafana/agent/internal/service/cluster"
+	"github.com/grafana/river/token/builder"
 	"github.com/prometheus/prometheus/util/httputil"
 )
 
 // FlowAPI is a wrapper around the component API.
 type FlowAPI struct {
-	flow service.Host
+	flow        service.Host
+	enablePProf bool
 }
 
-// NewFlowAPI instantiates a new Flow API.
-func NewFlowAPI(flow service.Host) *FlowAPI {
-	return &FlowAPI{flow: flow}
+// expressionEvaluator is implemented by controllers which can evaluate River
+// expressions against the exports of their components.
+type expressionEvaluator interface {
+	EvaluateExpression(expr string) (any, error)
+}
+
+// maxExpressionSize is the largest expression accepted by the resolve
+// endpoint.
+const maxExpressionSize = 1 << 20
+
+// NewFlowAPI instantiates a new Flow API. CPU profiles can only be collected
+// when enablePProf is true.
+func NewFlowAPI(flow service.Host, enablePProf bool) *FlowAPI {
+	return &FlowAPI{flow: flow, enablePProf: enablePProf}
 }
 
 // RegisterRoutes registers all the API's routes.
@@ -33,9 +50,25 @@ func (f *FlowAPI) RegisterRoutes(urlPrefix string, r *mux.Router) {
 	// component IDs.
 
 	r.Handle(path.Join(urlPrefix, "/modules/{moduleID:.+}/components"), httputil.CompressionHandler{Handler: f.listComponentsHandler()})
+	r.Handle(path.Join(urlPrefix, "/modules/{moduleID:.+}/imports"), httputil.CompressionHandler{Handler: f.listImportsHandler()})
+	r.Handle(path.Join(urlPrefix, "/modules/{moduleID:.+}/content"), httputil.CompressionHandler{Handler: f.getModuleContentHandler()})
+	r.Handle(path.Join(urlPrefix, "/modules/{moduleID:.+}/graph"), httputil.CompressionHandler{Handler: f.getGraphHandler()})
 	r.Handle(path.Join(urlPrefix, "/components"), httputil.CompressionHandler{Handler: f.listComponentsHandler()})
+	// The stream isn't compressed, as events must be flushed to the client as
+	// soon as they're written.
+	r.Handle(path.Join(urlPrefix, "/stream/components"), f.streamComponentsHandler())
 	r.Handle(path.Join(urlPrefix, "/components/{id:.+}"), httputil.CompressionHandler{Handler: f.getComponentHandler()})
+	r.Handle(path.Join(urlPrefix, "/imports"), httputil.CompressionHandler{Handler: f.listImportsHandler()})
+	r.Handle(path.Join(urlPrefix, "/graph"), httputil.CompressionHandler{Handler: f.getGraphHandler()})
 	r.Handle(path.Join(urlPrefix, "/peers"), httputil.CompressionHandler{Handler: f.getClusteringPeersHandler()})
+	r.Handle(path.Join(urlPrefix, "/tenants"), httputil.CompressionHandler{Handler: f.listTenantsHandler()})
+	r.Handle(path.Join(urlPrefix, "/modules"), httputil.CompressionHandler{Handler: f.listModulesHandler()})
+	r.Handle(path.Join(urlPrefix, "/modules/{moduleID:.+}"), httputil.CompressionHandler{Handler: f.getModuleHandler()})
+	r.Handle(path.Join(urlPrefix, "/resolve"), f.resolveHandler()).Methods(http.MethodPost)
+	r.Handle(path.Join(urlPrefix, "/goroutines"), httputil.CompressionHandler{Handler: f.goroutinesHandler()})
+	r.Handle(path.Join(urlPrefix, "/scrape/costs"), httputil.CompressionHandler{Handler: f.scrapeCostsHandler()})
+	// Profiles in the pprof format are already compressed.
+	r.Handle(path.Join(urlPrefix, "/profiles/cpu"), f.cpuProfileHandler())
 }
 
 func (f *FlowAPI) listComponentsHandler() http.HandlerFunc {
@@ -70,10 +103,11 @@ func (f *FlowAPI) getComponentHandler() http.HandlerFunc {
 		requestedComponent := component.ParseID(vars["id"])
 
 		component, err := f.flow.GetComponent(requestedComponent, component.InfoOptions{
-			GetHealth:    true,
-			GetArguments: true,
-			GetExports:   true,
-			GetDebugInfo: true,
+			GetHealth:             true,
+			GetArguments:          true,
+			GetExports:            true,
+			GetDebugInfo:          true,
+			RedactSensitiveBlocks: true,
 		})
 		if err != nil {
 			http.NotFound(w, r)
@@ -89,16 +123,57 @@ func (f *FlowAPI) getComponentHandler() http.HandlerFunc {
 	}
 }
 
+func (f *FlowAPI) listImportsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip, ok := f.flow.(component.ImportInfoProvider)
+		if !ok {
+			http.Error(w, "import information not available", http.StatusNotImplemented)
+			return
+		}
+
+		// moduleID is set from the /modules/{moduleID:.+}/imports route above
+		// but not from the /imports route.
+		var moduleID string
+		if vars := mux.Vars(r); vars != nil {
+			moduleID = vars["moduleID"]
+		}
+
+		imports, err := ip.ListImports(moduleID)
+		if errors.Is(err, component.ErrModuleNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		bb, err := json.Marshal(imports)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
 func (f *FlowAPI) getClusteringPeersHandler() http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
-		// TODO(@tpaschalis) Detect if clustering is disabled and propagate to
-		// the Typescript code (eg. via the returned status code?).
+	return func(w http.ResponseWriter, r *http.Request) {
 		svc, found := f.flow.GetService(cluster.ServiceName)
 		if !found {
 			http.Error(w, "cluster service not running", http.StatusInternalServerError)
 			return
 		}
-		peers := svc.Data().(cluster.Cluster).Peers()
+		clusterSvc, ok := svc.(*cluster.Service)
+		if !ok {
+			http.Error(w, fmt.Sprintf("unexpected cluster service type %T", svc), http.StatusInternalServerError)
+			return
+		}
+		if !clusterSvc.Enabled() {
+			writeJSONError(w, http.StatusNotImplemented, "clustering is disabled")
+			return
+		}
+
+		peers := clusterSvc.PeerStatuses(r.Context(), f.flow)
 		bb, err := json.Marshal(peers)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -107,3 +182,322 @@ func (f *FlowAPI) getClusteringPeersHandler() http.HandlerFunc {
 		_, _ = w.Write(bb)
 	}
 }
+
+// writeJSONError writes an error with the given status code as a JSON object
+// with an error field.
+func writeJSONError(w http.ResponseWriter, statusCode int, msg string) {
+	bb, err := json.Marshal(struct {
+		Error string `json:"error"`
+	}{Error: msg})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(bb)
+}
+
+func (f *FlowAPI) listTenantsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		// Tenants are listed by the module ID their components are namespaced
+		// under.
+		moduleIDs := []string{}
+		if tp, ok := f.flow.(component.TenantProvider); ok {
+			moduleIDs = append(moduleIDs, tp.TenantModuleIDs()...)
+		}
+
+		bb, err := json.Marshal(moduleIDs)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+func (f *FlowAPI) listModulesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		mp, ok := f.flow.(component.ModuleInfoProvider)
+		if !ok {
+			http.Error(w, "module information not available", http.StatusNotImplemented)
+			return
+		}
+
+		// The root module is listed first, followed by every running module.
+		modules := []*component.ModuleInfo{}
+		for _, id := range append([]string{""}, mp.ModuleIDs()...) {
+			info, err := mp.GetModuleInfo(id)
+			if errors.Is(err, component.ErrModuleNotFound) {
+				// The module stopped after it was listed.
+				continue
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			modules = append(modules, info)
+		}
+
+		bb, err := json.Marshal(modules)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+func (f *FlowAPI) getModuleHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mp, ok := f.flow.(component.ModuleInfoProvider)
+		if !ok {
+			http.Error(w, "module information not available", http.StatusNotImplemented)
+			return
+		}
+
+		info, err := mp.GetModuleInfo(mux.Vars(r)["moduleID"])
+		if errors.Is(err, component.ErrModuleNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		bb, err := json.Marshal(info)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+// getGraphHandler responds with the dependency graph of a module, encoded as
+// JSON or, with ?format=dot, in the Graphviz DOT language.
+func (f *FlowAPI) getGraphHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gp, ok := f.flow.(component.GraphProvider)
+		if !ok {
+			http.Error(w, "graph not available", http.StatusNotImplemented)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		switch format {
+		case "":
+			format = graphFormatJSON
+		case graphFormatJSON, graphFormatDOT:
+		default:
+			http.Error(w, fmt.Sprintf("unsupported format %q, must be one of %q or %q", format, graphFormatJSON, graphFormatDOT), http.StatusBadRequest)
+			return
+		}
+
+		// moduleID is set from the /modules/{moduleID:.+}/graph route above but
+		// not from the /graph route.
+		var moduleID string
+		if vars := mux.Vars(r); vars != nil {
+			moduleID = vars["moduleID"]
+		}
+
+		graph, err := gp.GetGraph(moduleID)
+		if errors.Is(err, component.ErrModuleNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if format == graphFormatDOT {
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			_ = writeDOT(w, graph)
+			return
+		}
+
+		bb, err := json.Marshal(graph)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+// getModuleContentHandler responds with the content currently loaded by a
+// module or an import block, with secrets scrubbed.
+func (f *FlowAPI) getModuleContentHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cp, ok := f.flow.(component.ModuleContentProvider)
+		if !ok {
+			http.Error(w, "module content not available", http.StatusNotImplemented)
+			return
+		}
+
+		content, err := cp.GetModuleContent(mux.Vars(r)["moduleID"])
+		if errors.Is(err, component.ErrModuleNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		bb, err := json.Marshal(content)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+// goroutinesHandler responds with the number of goroutines running on behalf
+// of each component, which helps track down goroutine leaks.
+func (f *FlowAPI) goroutinesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		summary, err := summarizeGoroutines()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		bb, err := json.Marshal(summary)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+// scrapeCostsHandler responds with the cost of the samples produced by each
+// scrape component, with its most expensive targets. The number of targets
+// reported for each component is set by the targets query parameter.
+func (f *FlowAPI) scrapeCostsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		topTargets := defaultScrapeCostTopTargets
+		if targets := r.URL.Query().Get("targets"); targets != "" {
+			n, err := strconv.Atoi(targets)
+			if err != nil || n < 0 {
+				http.Error(w, fmt.Sprintf("invalid targets %q", targets), http.StatusBadRequest)
+				return
+			}
+			topTargets = n
+		}
+
+		bb, err := json.Marshal(reportScrapeCosts(f.flow, topTargets))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+// cpuProfileHandler collects a CPU profile and responds with the CPU time
+// spent on behalf of each component. The duration of the profile is set by the
+// seconds query parameter.
+//
+// The module and component query parameters only keep the CPU time spent on
+// behalf of the matching components. With format=pprof, the filtered profile
+// is sent in the pprof format, for use with go tool pprof.
+//
+// Like /debug/pprof, the handler responds with 404 Not Found when pprof is
+// disabled.
+func (f *FlowAPI) cpuProfileHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !f.enablePProf {
+			http.Error(w, "profiling is disabled", http.StatusNotFound)
+			return
+		}
+
+		query := r.URL.Query()
+
+		duration, err := parseCPUProfileDuration(query.Get("seconds"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		format := query.Get("format")
+		switch format {
+		case "", "json", "pprof":
+		default:
+			http.Error(w, fmt.Sprintf("unsupported format %q", format), http.StatusBadRequest)
+			return
+		}
+
+		var filter componentFilter
+		if query.Has("module") {
+			moduleID := query.Get("module")
+			filter.moduleID = &moduleID
+		}
+		if query.Has("component") {
+			componentID := query.Get("component")
+			filter.componentID = &componentID
+		}
+
+		p, err := collectCPUProfile(r.Context(), duration)
+		switch {
+		case errors.Is(err, errCPUProfileRunning):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if filter.moduleID != nil || filter.componentID != nil {
+			p = filterProfile(p, filter)
+		}
+
+		if format == "pprof" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", `attachment; filename="cpu.pprof"`)
+			_ = p.Write(w)
+			return
+		}
+
+		summary, err := summarizeCPU(p)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		bb, err := json.Marshal(summary)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+// resolveHandler evaluates the River expression in the request body against
+// the root module and responds with the resolved value encoded as River.
+func (f *FlowAPI) resolveHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ee, ok := f.flow.(expressionEvaluator)
+		if !ok {
+			http.Error(w, "resolving expressions isn't supported", http.StatusNotImplemented)
+			return
+		}
+
+		expr, err := io.ReadAll(io.LimitReader(r.Body, maxExpressionSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		v, err := ee.EvaluateExpression(string(expr))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Secrets are encoded as (secret) by the builder.
+		out := builder.NewExpr()
+		out.SetValue(v)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = out.WriteTo(w)
+	}
+}
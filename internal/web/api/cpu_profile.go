@@ -0,0 +1,173 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+const (
+	// defaultCPUProfileDuration is the duration of CPU profiles when the
+	// request doesn't set one, matching /debug/pprof/profile.
+	defaultCPUProfileDuration = 30 * time.Second
+
+	// maxCPUProfileDuration is the longest CPU profile which can be requested.
+	maxCPUProfileDuration = 5 * time.Minute
+)
+
+// errCPUProfileRunning is returned when a CPU profile is requested while
+// another one is being collected. The Go runtime only supports one CPU
+// profile at a time, including those collected from /debug/pprof/profile.
+var errCPUProfileRunning = errors.New("a CPU profile is already being collected")
+
+// componentCPU is the CPU time spent on behalf of a component.
+type componentCPU struct {
+	ModuleID       string `json:"moduleID"`
+	ComponentID    string `json:"componentID"`
+	CPUNanoseconds int64  `json:"cpuNanoseconds"`
+}
+
+// cpuSummary summarizes the CPU time of the process by the component it was
+// spent on behalf of.
+type cpuSummary struct {
+	DurationSeconds      float64        `json:"durationSeconds"`
+	TotalNanoseconds     int64          `json:"totalNanoseconds"`
+	UnlabeledNanoseconds int64          `json:"unlabeledNanoseconds"` // CPU time which doesn't belong to a component.
+	Components           []componentCPU `json:"components"`
+}
+
+// parseCPUProfileDuration parses the seconds query parameter of a CPU profile
+// request. The default duration is returned if seconds is empty.
+func parseCPUProfileDuration(seconds string) (time.Duration, error) {
+	if seconds == "" {
+		return defaultCPUProfileDuration, nil
+	}
+
+	// The bounds are checked before converting to a duration, which could
+	// otherwise overflow.
+	n, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil || n < 1 || n > int64(maxCPUProfileDuration/time.Second) {
+		return 0, fmt.Errorf("seconds must be a whole number between 1 and %d, got %q", int64(maxCPUProfileDuration/time.Second), seconds)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// collectCPUProfile collects a CPU profile of the process for d, or until ctx
+// is canceled.
+func collectCPUProfile(ctx context.Context, d time.Duration) (*profile.Profile, error) {
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, errCPUProfileRunning
+	}
+
+	t := time.NewTimer(d)
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+	t.Stop()
+	pprof.StopCPUProfile()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return profile.Parse(&buf)
+}
+
+// cpuSampleIndex returns the index of the CPU time in the values of the
+// samples of p.
+func cpuSampleIndex(p *profile.Profile) (int, error) {
+	for i, st := range p.SampleType {
+		if st.Type == "cpu" {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("profile has no cpu sample type")
+}
+
+// summarizeCPU sums the CPU time of each component in p using the profiling
+// labels of its samples. Components are sorted by their CPU time in
+// descending order.
+func summarizeCPU(p *profile.Profile) (*cpuSummary, error) {
+	idx, err := cpuSampleIndex(p)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ moduleID, componentID string }
+
+	var (
+		summary = cpuSummary{DurationSeconds: time.Duration(p.DurationNanos).Seconds()}
+		totals  = make(map[key]int64)
+	)
+	for _, s := range p.Sample {
+		n := s.Value[idx]
+		summary.TotalNanoseconds += n
+
+		moduleID, componentID, ok := sampleComponent(s)
+		if !ok {
+			summary.UnlabeledNanoseconds += n
+			continue
+		}
+		totals[key{moduleID, componentID}] += n
+	}
+
+	summary.Components = make([]componentCPU, 0, len(totals))
+	for k, n := range totals {
+		summary.Components = append(summary.Components, componentCPU{
+			ModuleID:       k.moduleID,
+			ComponentID:    k.componentID,
+			CPUNanoseconds: n,
+		})
+	}
+	sort.Slice(summary.Components, func(i, j int) bool {
+		a, b := summary.Components[i], summary.Components[j]
+		switch {
+		case a.CPUNanoseconds != b.CPUNanoseconds:
+			return a.CPUNanoseconds > b.CPUNanoseconds
+		case a.ModuleID != b.ModuleID:
+			return a.ModuleID < b.ModuleID
+		default:
+			return a.ComponentID < b.ComponentID
+		}
+	})
+	return &summary, nil
+}
+
+// componentFilter selects the samples recorded on behalf of components. An
+// unset field matches any value.
+type componentFilter struct {
+	moduleID    *string
+	componentID *string
+}
+
+func (f componentFilter) matches(s *profile.Sample) bool {
+	moduleID, componentID, ok := sampleComponent(s)
+	if !ok {
+		return false
+	}
+	if f.moduleID != nil && *f.moduleID != moduleID {
+		return false
+	}
+	return f.componentID == nil || *f.componentID == componentID
+}
+
+// filterProfile returns a copy of p which only holds the samples matching f.
+func filterProfile(p *profile.Profile, f componentFilter) *profile.Profile {
+	filtered := p.Copy()
+	samples := filtered.Sample[:0]
+	for _, s := range filtered.Sample {
+		if f.matches(s) {
+			samples = append(samples, s)
+		}
+	}
+	filtered.Sample = samples
+	return filtered.Compact()
+}
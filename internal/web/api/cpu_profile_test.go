@@ -0,0 +1,177 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/grafana/agent/internal/component"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCPUProfileDuration(t *testing.T) {
+	tt := []struct {
+		seconds string
+		expect  time.Duration
+		err     bool
+	}{
+		{seconds: "", expect: defaultCPUProfileDuration},
+		{seconds: "1", expect: time.Second},
+		{seconds: "300", expect: maxCPUProfileDuration},
+		{seconds: "0", err: true},
+		{seconds: "-1", err: true},
+		{seconds: "301", err: true},
+		{seconds: "1.5", err: true},
+		{seconds: "ten", err: true},
+		// Large enough to overflow when converted to a duration.
+		{seconds: "9223372037", err: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.seconds, func(t *testing.T) {
+			d, err := parseCPUProfileDuration(tc.seconds)
+			if tc.err {
+				require.EqualError(t, err, `seconds must be a whole number between 1 and 300, got "`+tc.seconds+`"`)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, d)
+		})
+	}
+}
+
+func TestCPUProfileHandler_InvalidSeconds(t *testing.T) {
+	for _, seconds := range []string{"0", "301", "9223372037"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v0/web/profiles/cpu?seconds="+seconds, nil)
+		(&FlowAPI{enablePProf: true}).cpuProfileHandler().ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code, "seconds=%s", seconds)
+		require.Contains(t, rec.Body.String(), "seconds must be a whole number between 1 and 300")
+	}
+}
+
+func TestCPUProfileHandler_PProfDisabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v0/web/profiles/cpu?seconds=1", nil)
+	(&FlowAPI{}).cpuProfileHandler().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSummarizeCPU(t *testing.T) {
+	summary, err := summarizeCPU(testCPUProfile())
+	require.NoError(t, err)
+	require.Equal(t, &cpuSummary{
+		DurationSeconds:      10,
+		TotalNanoseconds:     1000,
+		UnlabeledNanoseconds: 100,
+		Components: []componentCPU{
+			{ModuleID: "", ComponentID: "prometheus.scrape.default", CPUNanoseconds: 500},
+			{ModuleID: "", ComponentID: "loki.process.default", CPUNanoseconds: 200},
+			{ModuleID: "import.file.lib", ComponentID: "loki.process.default", CPUNanoseconds: 200},
+		},
+	}, summary)
+}
+
+func TestSummarizeCPU_NoCPUSampleType(t *testing.T) {
+	p := testCPUProfile()
+	p.SampleType = []*profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "wall", Unit: "nanoseconds"}}
+
+	_, err := summarizeCPU(p)
+	require.EqualError(t, err, "profile has no cpu sample type")
+}
+
+func TestFilterProfile(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tt := []struct {
+		name   string
+		filter componentFilter
+		expect []componentCPU
+	}{
+		{
+			name:   "component in any module",
+			filter: componentFilter{componentID: str("loki.process.default")},
+			expect: []componentCPU{
+				{ModuleID: "", ComponentID: "loki.process.default", CPUNanoseconds: 200},
+				{ModuleID: "import.file.lib", ComponentID: "loki.process.default", CPUNanoseconds: 200},
+			},
+		},
+		{
+			name:   "module",
+			filter: componentFilter{moduleID: str("import.file.lib")},
+			expect: []componentCPU{
+				{ModuleID: "import.file.lib", ComponentID: "loki.process.default", CPUNanoseconds: 200},
+			},
+		},
+		{
+			name:   "root module",
+			filter: componentFilter{moduleID: str(""), componentID: str("prometheus.scrape.default")},
+			expect: []componentCPU{
+				{ModuleID: "", ComponentID: "prometheus.scrape.default", CPUNanoseconds: 500},
+			},
+		},
+		{
+			name:   "no match",
+			filter: componentFilter{componentID: str("prometheus.remote_write.default")},
+			expect: []componentCPU{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p := testCPUProfile()
+			filtered := filterProfile(p, tc.filter)
+
+			summary, err := summarizeCPU(filtered)
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, summary.Components)
+			// Unlabeled samples never match a filter.
+			require.Zero(t, summary.UnlabeledNanoseconds)
+
+			// The original profile must be left untouched.
+			require.Len(t, p.Sample, 5)
+		})
+	}
+}
+
+// testCPUProfile returns a CPU profile with samples labeled with the
+// components they were recorded on behalf of.
+func testCPUProfile() *profile.Profile {
+	fn := &profile.Function{ID: 1, Name: "main"}
+	loc := &profile.Location{ID: 1, Line: []profile.Line{{Function: fn}}}
+
+	sample := func(cpu int64, moduleID, componentID string) *profile.Sample {
+		s := &profile.Sample{
+			Location: []*profile.Location{loc},
+			Value:    []int64{1, cpu},
+		}
+		if componentID != "" {
+			s.Label = map[string][]string{component.ProfileLabelComponentID: {componentID}}
+			if moduleID != "" {
+				s.Label[component.ProfileLabelModule] = []string{moduleID}
+			}
+		}
+		return s
+	}
+
+	return &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		DurationNanos: int64(10 * time.Second),
+		Sample: []*profile.Sample{
+			sample(300, "", "prometheus.scrape.default"),
+			sample(200, "", "prometheus.scrape.default"),
+			sample(200, "", "loki.process.default"),
+			sample(200, "import.file.lib", "loki.process.default"),
+			sample(100, "", ""),
+		},
+		Location: []*profile.Location{loc},
+		Function: []*profile.Function{fn},
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"sort"
+
+	"github.com/google/pprof/profile"
+	"github.com/grafana/agent/internal/component"
+)
+
+// componentGoroutines is the number of goroutines running on behalf of a
+// component.
+type componentGoroutines struct {
+	ModuleID    string `json:"moduleID"`
+	ComponentID string `json:"componentID"`
+	Goroutines  int64  `json:"goroutines"`
+}
+
+// goroutineSummary summarizes the goroutines of the process by the component
+// which started them.
+type goroutineSummary struct {
+	Total      int64                 `json:"total"`
+	Unlabeled  int64                 `json:"unlabeled"` // Goroutines which don't belong to a component.
+	Components []componentGoroutines `json:"components"`
+}
+
+// summarizeGoroutines takes a goroutine profile and counts the goroutines of
+// each component using their profiling labels. Components are sorted by
+// their number of goroutines in descending order.
+func summarizeGoroutines() (*goroutineSummary, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 0); err != nil {
+		return nil, err
+	}
+	p, err := profile.Parse(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ moduleID, componentID string }
+
+	var (
+		summary goroutineSummary
+		counts  = make(map[key]int64)
+	)
+	for _, s := range p.Sample {
+		if len(s.Value) == 0 {
+			continue
+		}
+		n := s.Value[0]
+		summary.Total += n
+
+		moduleID, componentID, ok := sampleComponent(s)
+		if !ok {
+			summary.Unlabeled += n
+			continue
+		}
+		counts[key{moduleID, componentID}] += n
+	}
+
+	summary.Components = make([]componentGoroutines, 0, len(counts))
+	for k, n := range counts {
+		summary.Components = append(summary.Components, componentGoroutines{
+			ModuleID:    k.moduleID,
+			ComponentID: k.componentID,
+			Goroutines:  n,
+		})
+	}
+	sort.Slice(summary.Components, func(i, j int) bool {
+		a, b := summary.Components[i], summary.Components[j]
+		switch {
+		case a.Goroutines != b.Goroutines:
+			return a.Goroutines > b.Goroutines
+		case a.ModuleID != b.ModuleID:
+			return a.ModuleID < b.ModuleID
+		default:
+			return a.ComponentID < b.ComponentID
+		}
+	})
+	return &summary, nil
+}
+
+// sampleComponent returns the module and component IDs held by the profiling
+// labels of s. It returns false if s wasn't recorded on behalf of a
+// component.
+func sampleComponent(s *profile.Sample) (moduleID, componentID string, ok bool) {
+	componentIDs := s.Label[component.ProfileLabelComponentID]
+	if len(componentIDs) == 0 {
+		return "", "", false
+	}
+	if ids := s.Label[component.ProfileLabelModule]; len(ids) > 0 {
+		moduleID = ids[0]
+	}
+	return moduleID, componentIDs[0], true
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"sync"
+	"testing"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoroutinesHandler(t *testing.T) {
+	stop := make(chan struct{})
+	var started, stopped sync.WaitGroup
+	defer func() {
+		close(stop)
+		stopped.Wait()
+	}()
+
+	// startGoroutines starts n goroutines labeled like the goroutines of a
+	// running component.
+	startGoroutines := func(moduleID, componentID string, n int) {
+		labels := pprof.Labels(
+			component.ProfileLabelComponentID, componentID,
+			component.ProfileLabelModule, moduleID,
+		)
+		pprof.Do(context.Background(), labels, func(context.Context) {
+			for i := 0; i < n; i++ {
+				started.Add(1)
+				stopped.Add(1)
+				go func() {
+					defer stopped.Done()
+					started.Done()
+					<-stop
+				}()
+			}
+		})
+	}
+	startGoroutines("", "prometheus.scrape.default", 3)
+	startGoroutines("module.file.logs", "loki.source.file.default", 2)
+	started.Wait()
+
+	rec := httptest.NewRecorder()
+	(&FlowAPI{}).goroutinesHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v0/web/goroutines", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var raw struct {
+		Total      *int64           `json:"total"`
+		Unlabeled  *int64           `json:"unlabeled"`
+		Components []map[string]any `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &raw))
+	require.NotNil(t, raw.Total)
+	require.NotNil(t, raw.Unlabeled)
+	require.Len(t, raw.Components, 2)
+
+	// Components are sorted by their number of goroutines, most first.
+	require.Equal(t, []map[string]any{
+		{"moduleID": "", "componentID": "prometheus.scrape.default", "goroutines": float64(3)},
+		{"moduleID": "module.file.logs", "componentID": "loki.source.file.default", "goroutines": float64(2)},
+	}, raw.Components)
+
+	// The test itself runs in unlabeled goroutines.
+	require.Positive(t, *raw.Unlabeled)
+	require.Equal(t, *raw.Total, *raw.Unlabeled+5)
+}
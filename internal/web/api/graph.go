@@ -0,0 +1,54 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/grafana/agent/internal/component"
+)
+
+// Formats supported by the graph endpoint.
+const (
+	graphFormatJSON = "json"
+	graphFormatDOT  = "dot"
+)
+
+// graphNodeShapes holds the Graphviz shape used for each kind of node.
+var graphNodeShapes = map[string]string{
+	component.GraphNodeComponent:       "box",
+	component.GraphNodeCustomComponent: "box3d",
+	component.GraphNodeImport:          "folder",
+	component.GraphNodeDeclare:         "note",
+	component.GraphNodeService:         "hexagon",
+	component.GraphNodeConfig:          "ellipse",
+}
+
+// writeDOT writes g to w in the Graphviz DOT language. Edges point from a
+// node to the nodes it references.
+func writeDOT(w io.Writer, g *component.Graph) error {
+	name := g.ModuleID
+	if name == "" {
+		name = "root"
+	}
+
+	if _, err := fmt.Fprintf(w, "digraph %s {\n", strconv.Quote(name)); err != nil {
+		return err
+	}
+	for _, n := range g.Nodes {
+		shape, ok := graphNodeShapes[n.Kind]
+		if !ok {
+			shape = "ellipse"
+		}
+		if _, err := fmt.Fprintf(w, "\t%s [shape=%s, kind=%s];\n", strconv.Quote(n.ID), shape, strconv.Quote(n.Kind)); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		if _, err := fmt.Fprintf(w, "\t%s -> %s;\n", strconv.Quote(e.From), strconv.Quote(e.To)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"sort"
+
+	"github.com/grafana/agent/internal/component"
+)
+
+// defaultScrapeCostTopTargets is the number of targets reported for each
+// component when the number isn't set by the request.
+const defaultScrapeCostTopTargets = 10
+
+// componentScrapeCost is the cost of the samples produced by a scrape
+// component, with its most expensive targets.
+type componentScrapeCost struct {
+	ModuleID      string `json:"moduleID"`
+	ComponentID   string `json:"componentID"`
+	ComponentName string `json:"componentName"`
+	component.ScrapeCost
+}
+
+// scrapeCostReport reports the cost of the samples produced by each scrape
+// component.
+type scrapeCostReport struct {
+	ActiveSeries     int                   `json:"activeSeries"`
+	SamplesPerSecond float64               `json:"samplesPerSecond"`
+	BytesPerSecond   float64               `json:"bytesPerSecond"`
+	Components       []componentScrapeCost `json:"components"`
+}
+
+// reportScrapeCosts collects the cost of every component which implements
+// component.ScrapeCostComponent. Components are sorted by their active series
+// in descending order, and only their topTargets most expensive targets are
+// kept.
+func reportScrapeCosts(p component.Provider, topTargets int) *scrapeCostReport {
+	report := &scrapeCostReport{Components: []componentScrapeCost{}}
+
+	for _, info := range component.GetAllComponents(p, component.InfoOptions{}) {
+		sc, ok := info.Component.(component.ScrapeCostComponent)
+		if !ok {
+			continue
+		}
+
+		cost := sc.ScrapeCost()
+		if len(cost.Targets) > topTargets {
+			cost.Targets = cost.Targets[:topTargets]
+		}
+		report.ActiveSeries += cost.ActiveSeries
+		report.SamplesPerSecond += cost.SamplesPerSecond
+		report.BytesPerSecond += cost.BytesPerSecond
+		report.Components = append(report.Components, componentScrapeCost{
+			ModuleID:      info.ID.ModuleID,
+			ComponentID:   info.ID.LocalID,
+			ComponentName: info.ComponentName,
+			ScrapeCost:    cost,
+		})
+	}
+
+	sort.SliceStable(report.Components, func(i, j int) bool {
+		return report.Components[i].ActiveSeries > report.Components[j].ActiveSeries
+	})
+	return report
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/river/encoding/riverjson"
+)
+
+const (
+	// streamInterval is how often components are checked for changes to
+	// stream.
+	streamInterval = time.Second
+
+	// streamKeepaliveInterval is how often a comment is sent to clients when
+	// no component changes, so idle connections aren't closed by proxies.
+	streamKeepaliveInterval = 15 * time.Second
+)
+
+// componentRemoved is the data of events for components which stopped
+// running.
+type componentRemoved struct {
+	ID string `json:"id"`
+}
+
+// streamComponentsHandler streams changes to the health and exports of
+// components as Server-Sent Events. When a client connects, every component
+// is sent. Afterwards, a "component" event is sent whenever the health or
+// exports of a component change, and a "remove" event is sent when a
+// component goes away.
+func (f *FlowAPI) streamComponentsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming isn't supported", http.StatusNotImplemented)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		var (
+			ticker        = time.NewTicker(streamInterval)
+			sent          = make(map[string][]byte) // Fingerprint of the last sent state of each component
+			lastWriteTime = time.Now()
+		)
+		defer ticker.Stop()
+
+		for {
+			n, err := f.streamComponentChanges(w, sent)
+			if err != nil {
+				// The client went away.
+				return
+			}
+			if n == 0 && time.Since(lastWriteTime) >= streamKeepaliveInterval {
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+				n++
+			}
+			if n > 0 {
+				flusher.Flush()
+				lastWriteTime = time.Now()
+			}
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// streamComponentChanges writes an event for every component which changed
+// since its state was recorded in sent, and for every component in sent
+// which went away. It returns the number of events written.
+func (f *FlowAPI) streamComponentChanges(w http.ResponseWriter, sent map[string][]byte) (int, error) {
+	var (
+		events int
+		seen   = make(map[string]struct{})
+	)
+
+	components := component.GetAllComponents(f.flow, component.InfoOptions{
+		GetHealth:  true,
+		GetExports: true,
+	})
+	for _, info := range components {
+		id := info.ID.String()
+		seen[id] = struct{}{}
+
+		fingerprint, err := componentFingerprint(info)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(sent[id], fingerprint) {
+			continue
+		}
+
+		bb, err := json.Marshal(info)
+		if err != nil {
+			continue
+		}
+		if err := writeEvent(w, "component", bb); err != nil {
+			return events, err
+		}
+		sent[id] = fingerprint
+		events++
+	}
+
+	for id := range sent {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+
+		bb, err := json.Marshal(componentRemoved{ID: id})
+		if err != nil {
+			return events, err
+		}
+		if err := writeEvent(w, "remove", bb); err != nil {
+			return events, err
+		}
+		delete(sent, id)
+		events++
+	}
+	return events, nil
+}
+
+// componentFingerprint returns the state of info which is streamed to
+// clients. The update time of the health is left out, as some components
+// refresh it without their health changing.
+func componentFingerprint(info *component.Info) ([]byte, error) {
+	exports, err := riverjson.MarshalBody(info.Exports)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Health  string
+		Message string
+		Exports json.RawMessage
+	}{info.Health.Health.String(), info.Health.Message, exports})
+}
+
+// writeEvent writes a Server-Sent Event. data must not contain newlines,
+// which is the case for JSON produced by json.Marshal.
+func writeEvent(w http.ResponseWriter, event string, data []byte) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
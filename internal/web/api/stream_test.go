@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/internal/component"
+	"github.com/grafana/agent/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamComponentsHandler(t *testing.T) {
+	host := &fakeHost{components: []*component.Info{
+		{ID: component.ID{LocalID: "local.file.a"}, ComponentName: "local.file", Health: component.Health{Health: component.HealthTypeHealthy}},
+		{ID: component.ID{LocalID: "local.file.b"}, ComponentName: "local.file", Health: component.Health{Health: component.HealthTypeHealthy}},
+	}}
+
+	handlerDone := make(chan struct{})
+	handler := (&FlowAPI{flow: host}).streamComponentsHandler()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(handlerDone)
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+	events := readEvents(resp)
+	nextEvent := func() streamEvent {
+		t.Helper()
+		select {
+		case ev, ok := <-events:
+			require.True(t, ok, "stream closed")
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+			return streamEvent{}
+		}
+	}
+	componentID := func(ev streamEvent) string {
+		t.Helper()
+		var data struct {
+			LocalID string `json:"localID"`
+			ID      string `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(ev.data), &data))
+		return data.LocalID + data.ID
+	}
+
+	// Every component is sent when the client connects.
+	for _, id := range []string{"local.file.a", "local.file.b"} {
+		ev := nextEvent()
+		require.Equal(t, "component", ev.event)
+		require.Equal(t, id, componentID(ev))
+	}
+
+	// Changed components are sent again, and removed components are reported.
+	host.setComponents([]*component.Info{
+		{ID: component.ID{LocalID: "local.file.a"}, ComponentName: "local.file", Health: component.Health{Health: component.HealthTypeUnhealthy, Message: "file not found"}},
+	})
+
+	ev := nextEvent()
+	require.Equal(t, "component", ev.event)
+	require.Equal(t, "local.file.a", componentID(ev))
+	require.Contains(t, ev.data, `"message":"file not found"`)
+
+	ev = nextEvent()
+	require.Equal(t, "remove", ev.event)
+	require.JSONEq(t, `{"id":"local.file.b"}`, ev.data)
+
+	// The handler returns once the client goes away.
+	cancel()
+	select {
+	case <-handlerDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler didn't return after the client disconnected")
+	}
+}
+
+func TestStreamComponentsHandler_CanceledRequest(t *testing.T) {
+	host := &fakeHost{components: []*component.Info{
+		{ID: component.ID{LocalID: "local.file.a"}, ComponentName: "local.file"},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A request whose context is already canceled gets the current state of
+	// the components before the handler returns.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v0/web/stream/components", nil).WithContext(ctx)
+	(&FlowAPI{flow: host}).streamComponentsHandler().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.True(t, rec.Flushed)
+
+	body := rec.Body.String()
+	require.True(t, strings.HasPrefix(body, "event: component\ndata: {"), "unexpected stream %q", body)
+	require.True(t, strings.HasSuffix(body, "}\n\n"), "unexpected stream %q", body)
+	require.Equal(t, 1, strings.Count(body, "event: "))
+}
+
+// streamEvent is a Server-Sent Event read from a stream.
+type streamEvent struct {
+	event, data string
+}
+
+// readEvents reads the Server-Sent Events of resp until the stream ends.
+// Comments are skipped.
+func readEvents(resp *http.Response) <-chan streamEvent {
+	events := make(chan streamEvent, 10)
+	go func() {
+		defer close(events)
+
+		var (
+			scanner = bufio.NewScanner(resp.Body)
+			ev      streamEvent
+		)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if ev.event != "" {
+					events <- ev
+				}
+				ev = streamEvent{}
+			case strings.HasPrefix(line, "event: "):
+				ev.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events
+}
+
+// fakeHost is a service.Host which lists the components it was given in the
+// root module.
+type fakeHost struct {
+	service.Host
+
+	mut        sync.Mutex
+	components []*component.Info
+}
+
+func (h *fakeHost) setComponents(components []*component.Info) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.components = components
+}
+
+func (h *fakeHost) ListComponents(moduleID string, _ component.InfoOptions) ([]*component.Info, error) {
+	if moduleID != "" {
+		return nil, component.ErrModuleNotFound
+	}
+
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.components, nil
+}
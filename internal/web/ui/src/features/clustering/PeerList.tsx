@@ -8,7 +8,15 @@ interface PeerListProps {
   peers: PeerInfo[];
 }
 
-const TABLEHEADERS = ['Node Name', 'Advertised Address', 'Current State', 'Local Node'];
+const TABLEHEADERS = [
+  'Node Name',
+  'Advertised Address',
+  'Current State',
+  'Version',
+  'Config Hash',
+  'Running Components',
+  'Local Node',
+];
 
 const PeerList = ({ peers }: PeerListProps) => {
   const tableStyles = { width: '130px' };
@@ -17,7 +25,7 @@ const PeerList = ({ peers }: PeerListProps) => {
    * Custom renderer for table data
    */
   const renderTableData = () => {
-    return peers.map(({ name, addr, state, isSelf }) => (
+    return peers.map(({ name, addr, state, isSelf, status, error }) => (
       <tr key={name} style={{ lineHeight: '2.5' }}>
         <td>
           <span className={styles.idName}>{name}</span>
@@ -28,6 +36,15 @@ const PeerList = ({ peers }: PeerListProps) => {
         <td>
           <span className={styles.idName}>{state}</span>
         </td>
+        <td>
+          <span className={styles.idName}>{status?.version ?? error}</span>
+        </td>
+        <td>
+          <span className={styles.idName}>{status?.configHash.substring(0, 12)}</span>
+        </td>
+        <td>
+          <span className={styles.idName}>{status?.runningComponents}</span>
+        </td>
         <td>
           <span> {isSelf ? '✅' : ' '}</span>
         </td>
@@ -6,4 +6,20 @@ export interface PeerInfo {
   state: string;
 
   isSelf: boolean;
+
+  // Status reported by the peer itself. Unset if the status couldn't be
+  // retrieved, in which case error is set.
+  status?: PeerStatus;
+
+  error?: string;
+}
+
+export interface PeerStatus {
+  state: string;
+
+  version: string;
+
+  configHash: string;
+
+  runningComponents: number;
 }
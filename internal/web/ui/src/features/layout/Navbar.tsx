@@ -23,6 +23,11 @@ function Navbar() {
             Clustering
           </NavLink>
         </li>
+        <li>
+          <NavLink to="/tenants" className="nav-link">
+            Tenants
+          </NavLink>
+        </li>
         <li>
           <a href="https://grafana.com/docs/agent/latest">Help</a>
         </li>
@@ -20,6 +20,12 @@ export const usePeerInfo = (fromPeer?: string): PeerInfo[] => {
           cache: 'no-cache',
           credentials: 'same-origin',
         });
+        if (!resp.ok) {
+          // The API responds with a JSON error, for example when clustering
+          // is disabled.
+          const { error } = await resp.json();
+          throw new Error(`failed to retrieve peers: ${error}`);
+        }
         setPeers(await resp.json());
       };
 
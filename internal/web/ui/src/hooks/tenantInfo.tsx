@@ -0,0 +1,25 @@
+import { useEffect, useState } from 'react';
+
+/**
+ * useTenantInfo retrieves the module IDs of the running tenants from the API.
+ */
+export const useTenantInfo = (): string[] => {
+  const [tenants, setTenants] = useState<string[]>([]);
+
+  useEffect(function () {
+    const worker = async () => {
+      const infoPath = './api/v0/web/tenants';
+
+      // Request is relative to the <base> tag inside of <head>.
+      const resp = await fetch(infoPath, {
+        cache: 'no-cache',
+        credentials: 'same-origin',
+      });
+      setTenants(await resp.json());
+    };
+
+    worker().catch(console.error);
+  }, []);
+
+  return tenants;
+};
@@ -0,0 +1,37 @@
+import { faUsers } from '@fortawesome/free-solid-svg-icons';
+
+import ComponentList from '../features/component/ComponentList';
+import Page from '../features/layout/Page';
+import { useComponentInfo } from '../hooks/componentInfo';
+import { useTenantInfo } from '../hooks/tenantInfo';
+
+interface TenantComponentsProps {
+  moduleID: string;
+}
+
+const TenantComponents = ({ moduleID }: TenantComponentsProps) => {
+  const [components] = useComponentInfo(moduleID);
+
+  return (
+    <section>
+      <h2>{moduleID}</h2>
+      <ComponentList components={components} moduleID={moduleID} />
+    </section>
+  );
+};
+
+function PageTenants() {
+  const tenants = useTenantInfo();
+
+  return (
+    <Page name="Tenants" desc="Components of the tenants running in this process" icon={faUsers}>
+      {tenants.length === 0 ? (
+        <p>No tenants are running.</p>
+      ) : (
+        tenants.map((moduleID) => <TenantComponents key={moduleID} moduleID={moduleID} />)
+      )}
+    </Page>
+  );
+}
+
+export default PageTenants;